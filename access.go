@@ -0,0 +1,703 @@
+package gitdb
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bouggo/log"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//Permission is what a role may do with a dataset. Combine permissions with |
+type Permission int
+
+const (
+	//PermRead allows records to be read and searched
+	PermRead Permission = 1 << iota
+	//PermWrite allows records to be inserted, updated, locked and reverted
+	PermWrite
+	//PermDelete allows records to be deleted
+	PermDelete
+	//PermAll allows everything
+	PermAll = PermRead | PermWrite | PermDelete
+)
+
+func (p Permission) String() string {
+	var names []string
+	for _, perm := range []struct {
+		p    Permission
+		name string
+	}{{PermRead, "read"}, {PermWrite, "write"}, {PermDelete, "delete"}} {
+		if p&perm.p != 0 {
+			names = append(names, perm.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+//Role maps datasets to the permissions a role has on them. The key "*" applies to
+//datasets the role has no entry for e.g Role{"Bookings": PermRead, "*": 0}
+type Role map[string]Permission
+
+//can reports whether the role has permission p on dataset
+func (r Role) can(dataset string, p Permission) bool {
+	perm, ok := r[dataset]
+	if !ok {
+		perm = r["*"]
+	}
+	return perm&p == p
+}
+
+//admin reports whether the role has every permission on every dataset
+func (r Role) admin() bool {
+	if r["*"] != PermAll {
+		return false
+	}
+	for _, perm := range r {
+		if perm != PermAll {
+			return false
+		}
+	}
+	return true
+}
+
+//ErrAccessDenied is returned when a connection made with WithRole lacks the permission for a dataset
+type ErrAccessDenied struct {
+	Role       string
+	Dataset    string
+	Permission Permission
+}
+
+func (e *ErrAccessDenied) Error() string {
+	return fmt.Sprintf("role %s does not have %s permission on %s", e.Role, e.Permission, e.Dataset)
+}
+
+//access returns *ErrAccessDenied unless role has permission p on dataset. Unknown roles have no permissions
+func (c Config) access(role string, dataset string, p Permission) error {
+	if !c.Roles[role].can(dataset, p) {
+		return &ErrAccessDenied{Role: role, Dataset: dataset, Permission: p}
+	}
+	return nil
+}
+
+//roleSession is a view of a gitdb connection limited to the permissions of role in Config.Roles. It
+//doesn't embed *gitdb so that every method of GitDb has to check the role, operations on the whole
+//repository e.g branches and history need a role with PermAll on every dataset
+type roleSession struct {
+	gitdb *gitdb
+	role  string
+	user  *User
+}
+
+//WithRole returns a view of the connection which can only use datasets as allowed by
+//the role in Config.Roles e.g db.WithRole("reporting").Fetch("Bookings")
+func (g *gitdb) WithRole(role string) GitDb {
+	return &roleSession{gitdb: g, role: role}
+}
+
+//WithRole keeps the user of the session
+func (s *session) WithRole(role string) GitDb {
+	return &roleSession{gitdb: s.gitdb, role: role, user: s.user}
+}
+
+//WithUser keeps the role of the session
+func (s *roleSession) WithUser(name string, email string) GitDb {
+	return &roleSession{gitdb: s.gitdb, role: s.role, user: NewUser(name, email)}
+}
+
+func (s *roleSession) WithRole(role string) GitDb {
+	return &roleSession{gitdb: s.gitdb, role: role, user: s.user}
+}
+
+func (s *roleSession) access(dataset string, p Permission) error {
+	return s.gitdb.config.access(s.role, dataset, p)
+}
+
+//admin returns *ErrAccessDenied unless the role has every permission on every dataset
+func (s *roleSession) admin() error {
+	if !s.gitdb.config.Roles[s.role].admin() {
+		return &ErrAccessDenied{Role: s.role, Dataset: "*", Permission: PermAll}
+	}
+	return nil
+}
+
+//accessID is access for the dataset of record id
+func (s *roleSession) accessID(id string, p Permission) error {
+	dataset, _, _, err := ParseID(id)
+	if err != nil {
+		return err
+	}
+	return s.access(dataset, p)
+}
+
+func (s *roleSession) Insert(m Model) error {
+	if err := s.access(m.GetSchema().name(), PermWrite); err != nil {
+		return err
+	}
+	return s.gitdb.insert(m, s.user)
+}
+
+func (s *roleSession) InsertRevision(m Model, revision int) error {
+	if err := s.access(m.GetSchema().name(), PermWrite); err != nil {
+		return err
+	}
+	return s.gitdb.insertRevision(m, revision, s.user)
+}
+
+func (s *roleSession) Update(m Model) error {
+	if err := s.access(m.GetSchema().name(), PermWrite); err != nil {
+		return err
+	}
+	return s.gitdb.update(m, s.user)
+}
+
+func (s *roleSession) Revision(id string) (int, error) {
+	if err := s.accessID(id, PermRead); err != nil {
+		return 0, err
+	}
+	return s.gitdb.Revision(id)
+}
+
+func (s *roleSession) InsertMany(models []Model) error {
+	for _, m := range models {
+		if err := s.access(m.GetSchema().name(), PermWrite); err != nil {
+			return err
+		}
+	}
+	return s.gitdb.insertMany(models, s.user)
+}
+
+func (s *roleSession) InsertAsync(m Model, done func(err error)) {
+	if err := s.access(m.GetSchema().name(), PermWrite); err != nil {
+		if done != nil {
+			done(err)
+		}
+		return
+	}
+	s.gitdb.insertAsync(m, done, s.user)
+}
+
+func (s *roleSession) Get(id string, m Model) error {
+	if err := s.accessID(id, PermRead); err != nil {
+		return err
+	}
+	return s.gitdb.Get(id, m)
+}
+
+func (s *roleSession) GetModel(id string) (Model, error) {
+	if err := s.accessID(id, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.GetModel(id)
+}
+
+func (s *roleSession) Exists(id string) error {
+	if err := s.accessID(id, PermRead); err != nil {
+		return err
+	}
+	return s.gitdb.Exists(id)
+}
+
+func (s *roleSession) Fetch(dataset string) ([]*db.Record, error) {
+	if err := s.access(dataset, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.Fetch(dataset)
+}
+
+//Stats is Stats of the datasets the role can read
+func (s *roleSession) Stats() (*Stats, error) {
+	datasets, err := s.gitdb.datasetNames()
+	if err != nil {
+		return nil, err
+	}
+
+	var readable []string
+	for _, dataset := range datasets {
+		if s.access(dataset, PermRead) == nil {
+			readable = append(readable, dataset)
+		}
+	}
+	return s.gitdb.stats(readable)
+}
+
+func (s *roleSession) FetchStrict(dataset string, m Model) ([]*db.Record, error) {
+	if err := s.access(dataset, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.FetchStrict(dataset, m)
+}
+
+func (s *roleSession) FetchModels(dataset string) ([]Model, error) {
+	if err := s.access(dataset, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.FetchModels(dataset)
+}
+
+func (s *roleSession) SchemaDiff(dataset string, m Model) (*SchemaDrift, error) {
+	if err := s.access(dataset, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.SchemaDiff(dataset, m)
+}
+
+func (s *roleSession) Search(dataset string, searchParams []*SearchParam, searchMode SearchMode) ([]*db.Record, error) {
+	if err := s.access(dataset, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.Search(dataset, searchParams, searchMode)
+}
+
+func (s *roleSession) SearchRange(dataset string, index string, from string, to string) ([]*db.Record, error) {
+	if err := s.access(dataset, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.SearchRange(dataset, index, from, to)
+}
+
+func (s *roleSession) SearchText(dataset string, query string) ([]*db.Record, error) {
+	if err := s.access(dataset, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.SearchText(dataset, query)
+}
+
+func (s *roleSession) SearchWhere(dataset string, index string, conds ...Condition) ([]*db.Record, error) {
+	if err := s.access(dataset, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.SearchWhere(dataset, index, conds...)
+}
+
+func (s *roleSession) Explain(q *Query) (*Plan, error) {
+	if err := s.access(q.Dataset, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.Explain(q)
+}
+
+func (s *roleSession) SearchNear(dataset string, lat float64, lng float64, radiusKm float64) ([]*db.Record, error) {
+	if err := s.access(dataset, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.SearchNear(dataset, lat, lng, radiusKm)
+}
+
+func (s *roleSession) SearchIDs(dataset string, index string, value string) ([]string, error) {
+	if err := s.access(dataset, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.SearchIDs(dataset, index, value)
+}
+
+func (s *roleSession) IndexValues(dataset string, index string, ids ...string) (map[string]interface{}, error) {
+	if err := s.access(dataset, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.IndexValues(dataset, index, ids...)
+}
+
+func (s *roleSession) Delete(id string) error {
+	if err := s.accessID(id, PermDelete); err != nil {
+		return err
+	}
+	return s.gitdb.dodelete(id, false, s.user)
+}
+
+func (s *roleSession) DeleteOrFail(id string) error {
+	if err := s.accessID(id, PermDelete); err != nil {
+		return err
+	}
+	return s.gitdb.dodelete(id, true, s.user)
+}
+
+//Shred needs an admin role like SquashHistory as it rewrites the history of the whole repository, audit log included
+func (s *roleSession) Shred(id string) error {
+	if err := s.admin(); err != nil {
+		return err
+	}
+	return s.gitdb.shred(id, s.user)
+}
+
+func (s *roleSession) Lock(m Model) error {
+	if err := s.access(m.GetSchema().name(), PermWrite); err != nil {
+		return err
+	}
+	return s.gitdb.lock(m, s.user)
+}
+
+func (s *roleSession) TryLock(m Model) (bool, error) {
+	if err := s.access(m.GetSchema().name(), PermWrite); err != nil {
+		return false, err
+	}
+	return s.gitdb.tryLock(m, s.user)
+}
+
+func (s *roleSession) LockWait(m Model, timeout time.Duration) error {
+	if err := s.access(m.GetSchema().name(), PermWrite); err != nil {
+		return err
+	}
+	return s.gitdb.lockWait(m, timeout, s.user)
+}
+
+func (s *roleSession) Unlock(m Model) error {
+	if err := s.access(m.GetSchema().name(), PermWrite); err != nil {
+		return err
+	}
+	return s.gitdb.unlock(m, s.user)
+}
+
+func (s *roleSession) Migrate(from Model, to Model) error {
+	if err := s.access(from.GetSchema().name(), PermRead|PermDelete); err != nil {
+		return err
+	}
+	if err := s.access(to.GetSchema().name(), PermWrite); err != nil {
+		return err
+	}
+	return s.gitdb.Migrate(from, to)
+}
+
+func (s *roleSession) StartTransaction(name string) Transaction {
+	return s.gitdb.startTransaction(name, s.user)
+}
+
+func (s *roleSession) RevertRecord(id string, commit string) error {
+	if err := s.accessID(id, PermWrite); err != nil {
+		return err
+	}
+	return s.gitdb.revertRecord(id, commit, s.user)
+}
+
+func (s *roleSession) History(id string) ([]*Change, error) {
+	if err := s.accessID(id, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.History(id)
+}
+
+func (s *roleSession) Diff(dataset string, from string, to string) ([]*RecordDiff, error) {
+	if err := s.access(dataset, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.Diff(dataset, from, to)
+}
+
+func (s *roleSession) VerifyHistory(dataset string) ([]UnverifiedCommit, error) {
+	if err := s.access(dataset, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.VerifyHistory(dataset)
+}
+
+func (s *roleSession) RebuildIndex(dataset string) error {
+	if err := s.access(dataset, PermWrite); err != nil {
+		return err
+	}
+	return s.gitdb.RebuildIndex(dataset)
+}
+
+func (s *roleSession) FetchAtTag(dataset string, tag string) ([]*db.Record, error) {
+	if err := s.access(dataset, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.FetchAtTag(dataset, tag)
+}
+
+func (s *roleSession) CreateView(name string, q *Query) error {
+	if err := s.access(q.Dataset, PermRead); err != nil {
+		return err
+	}
+	if err := s.access(name, PermWrite); err != nil {
+		return err
+	}
+	return s.gitdb.CreateView(name, q)
+}
+
+func (s *roleSession) RunMigrations(opts ...MigrationOption) error {
+	for dataset := range s.gitdb.config.Migrations {
+		if err := s.access(dataset, PermWrite); err != nil {
+			return err
+		}
+	}
+	return s.gitdb.RunMigrations(opts...)
+}
+
+func (s *roleSession) UpgradeFormat() error {
+	datasets, err := s.gitdb.datasetNames()
+	if err != nil {
+		return err
+	}
+	for _, dataset := range datasets {
+		if err := s.access(dataset, PermWrite); err != nil {
+			return err
+		}
+	}
+	return s.gitdb.UpgradeFormat()
+}
+
+func (s *roleSession) Compact(dryRun bool, datasets ...string) ([]*CompactReport, error) {
+	all := datasets
+	if len(all) == 0 {
+		var err error
+		if all, err = s.gitdb.datasetNames(); err != nil {
+			return nil, err
+		}
+	}
+	for _, dataset := range all {
+		if err := s.access(dataset, PermWrite); err != nil {
+			return nil, err
+		}
+	}
+	return s.gitdb.Compact(dryRun, datasets...)
+}
+
+func (s *roleSession) RotateKey(dataset string, oldKey string, newKey string) error {
+	if err := s.access(dataset, PermWrite); err != nil {
+		return err
+	}
+	return s.gitdb.RotateKey(dataset, oldKey, newKey)
+}
+
+func (s *roleSession) SetDatasetMeta(dataset string, meta *DatasetMeta) error {
+	if err := s.access(dataset, PermWrite); err != nil {
+		return err
+	}
+	return s.gitdb.setDatasetMeta(dataset, meta, s.user)
+}
+
+func (s *roleSession) GetDatasetMeta(dataset string) (*DatasetMeta, error) {
+	if err := s.access(dataset, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.GetDatasetMeta(dataset)
+}
+
+func (s *roleSession) ExportJSONSchema(dataset string) ([]byte, error) {
+	if err := s.access(dataset, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.ExportJSONSchema(dataset)
+}
+
+//Subscribe only passes on changes to datasets the role can read
+func (s *roleSession) Subscribe(kinds EventKind, handler EventHandler) {
+	s.gitdb.Subscribe(kinds, func(e Event) {
+		if s.access(e.Dataset, PermRead) == nil {
+			handler(e)
+		}
+	})
+}
+
+func (s *roleSession) ChangesSince(dataset string, seq int64) ([]*ChangeEntry, error) {
+	if err := s.access(dataset, PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.ChangesSince(dataset, seq)
+}
+
+func (s *roleSession) Close() error {
+	if err := s.admin(); err != nil {
+		return err
+	}
+	return s.gitdb.Close()
+}
+
+func (s *roleSession) Flush() error {
+	return s.gitdb.Flush()
+}
+
+func (s *roleSession) Decode(record *db.Record) (Model, error) {
+	if err := s.accessID(record.ID(), PermRead); err != nil {
+		return nil, err
+	}
+	return s.gitdb.Decode(record)
+}
+
+//CheckIntegrity is CheckIntegrity of the records the role can read
+func (s *roleSession) CheckIntegrity() ([]*DanglingRef, error) {
+	refs, err := s.gitdb.CheckIntegrity()
+	if err != nil {
+		return nil, err
+	}
+
+	var readable []*DanglingRef
+	for _, ref := range refs {
+		if s.accessID(ref.ID, PermRead) == nil {
+			readable = append(readable, ref)
+		}
+	}
+	return readable, nil
+}
+
+//DiagnoseLocks is empty unless the role is an admin as locks span datasets
+func (s *roleSession) DiagnoseLocks() LockDiagnostics {
+	if s.admin() != nil {
+		return LockDiagnostics{}
+	}
+	return s.gitdb.DiagnoseLocks()
+}
+
+//Upload needs PermWrite on the dataset of UploadModel to upload files
+func (s *roleSession) Upload() *Upload {
+	return &Upload{db: s.gitdb, denied: s.access(uploadModelDataset, PermWrite)}
+}
+
+//GetMails is empty unless the role is an admin
+func (s *roleSession) GetMails() []*mail {
+	if s.admin() != nil {
+		return nil
+	}
+	return s.gitdb.GetMails()
+}
+
+func (s *roleSession) GetLastCommitTime() (time.Time, error) {
+	return s.gitdb.GetLastCommitTime()
+}
+
+func (s *roleSession) SetUser(user *User) error {
+	if err := s.admin(); err != nil {
+		return err
+	}
+	return s.gitdb.SetUser(user)
+}
+
+//Config leaves out the keys of the connection unless the role is an admin
+func (s *roleSession) Config() Config {
+	cfg := s.gitdb.Config()
+	if s.admin() != nil {
+		cfg.EncryptionKey = ""
+		cfg.PreviousKeys = nil
+		cfg.KeyProvider = nil
+		cfg.Cipher = nil
+		cfg.IndexKey = ""
+		cfg.IntegrityKey = ""
+		cfg.ShareKey = ""
+	}
+	return cfg
+}
+
+func (s *roleSession) Branch(name string) error {
+	if err := s.admin(); err != nil {
+		return err
+	}
+	return s.gitdb.Branch(name)
+}
+
+func (s *roleSession) SwitchBranch(name string) error {
+	if err := s.admin(); err != nil {
+		return err
+	}
+	return s.gitdb.SwitchBranch(name)
+}
+
+func (s *roleSession) MergeBranch(name string) error {
+	if err := s.admin(); err != nil {
+		return err
+	}
+	return s.gitdb.MergeBranch(name)
+}
+
+func (s *roleSession) CurrentBranch() string {
+	return s.gitdb.CurrentBranch()
+}
+
+func (s *roleSession) Sync(opts ...SyncOption) error {
+	if err := s.admin(); err != nil {
+		return err
+	}
+	return s.gitdb.Sync(opts...)
+}
+
+//Remotes is empty unless the role is an admin as remote urls may hold credentials
+func (s *roleSession) Remotes() []RemoteStatus {
+	if s.admin() != nil {
+		return nil
+	}
+	return s.gitdb.Remotes()
+}
+
+func (s *roleSession) PendingPushes() int {
+	return s.gitdb.PendingPushes()
+}
+
+//PendingWrites is PendingWrites of the datasets the role can read
+func (s *roleSession) PendingWrites() map[string]int {
+	pending := map[string]int{}
+	for dataset, n := range s.gitdb.PendingWrites() {
+		if s.access(dataset, PermRead) == nil {
+			pending[dataset] = n
+		}
+	}
+	return pending
+}
+
+func (s *roleSession) Verify() ([]Tampering, error) {
+	if err := s.admin(); err != nil {
+		return nil, err
+	}
+	return s.gitdb.Verify()
+}
+
+func (s *roleSession) TrustBlocks() error {
+	if err := s.admin(); err != nil {
+		return err
+	}
+	return s.gitdb.TrustBlocks()
+}
+
+func (s *roleSession) Fsck(fix bool) (*FsckReport, error) {
+	if err := s.admin(); err != nil {
+		return nil, err
+	}
+	return s.gitdb.Fsck(fix)
+}
+
+//OnChange doesn't register handler unless the role can read dataset
+func (s *roleSession) OnChange(dataset string, handler ChangeHandler) {
+	if err := s.access(dataset, PermRead); err != nil {
+		log.Error(err.Error())
+		return
+	}
+	s.gitdb.OnChange(dataset, handler)
+}
+
+func (s *roleSession) Maintain() (*MaintenanceReport, error) {
+	if err := s.admin(); err != nil {
+		return nil, err
+	}
+	return s.gitdb.Maintain()
+}
+
+func (s *roleSession) RepoObjects() (*RepoObjects, error) {
+	if err := s.admin(); err != nil {
+		return nil, err
+	}
+	return s.gitdb.RepoObjects()
+}
+
+func (s *roleSession) SquashHistory(before time.Time) error {
+	if err := s.admin(); err != nil {
+		return err
+	}
+	return s.gitdb.SquashHistory(before)
+}
+
+func (s *roleSession) TagRelease(name string) error {
+	if err := s.admin(); err != nil {
+		return err
+	}
+	return s.gitdb.TagRelease(name)
+}
+
+func (s *roleSession) ListReleases() ([]*Release, error) {
+	return s.gitdb.ListReleases()
+}
+
+func (s *roleSession) ShareLink(dataset string, commit string, ttl time.Duration) (string, error) {
+	if err := s.access(dataset, PermRead); err != nil {
+		return "", err
+	}
+	return s.gitdb.ShareLink(dataset, commit, ttl)
+}
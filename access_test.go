@@ -0,0 +1,97 @@
+package gitdb_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestWithRole(t *testing.T) {
+	cfg := getConfig()
+	cfg.Roles = map[string]gitdb.Role{
+		"reporting": {"Message": gitdb.PermRead},
+		"editor":    {"*": gitdb.PermRead | gitdb.PermWrite},
+	}
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	m := getTestMessage()
+	editor := testDb.WithRole("editor").WithUser("Jane", "jane@example.com")
+	if err := editor.Insert(m); err != nil {
+		t.Fatalf("editor.Insert failed: %s", err)
+	}
+
+	var denied *gitdb.ErrAccessDenied
+	if err := editor.Delete(gitdb.ID(m)); !errors.As(err, &denied) || denied.Permission != gitdb.PermDelete {
+		t.Errorf("want: %T for delete, got: %v", denied, err)
+	}
+
+	reporting := testDb.WithRole("reporting")
+	if records, err := reporting.Fetch("Message"); err != nil || len(records) != 1 {
+		t.Errorf("want: 1 record, got: %d (%v)", len(records), err)
+	}
+	if err := reporting.Insert(getTestMessage()); !errors.As(err, &denied) {
+		t.Errorf("want: %T for insert, got: %v", denied, err)
+	}
+	if _, err := reporting.Fetch("Booking"); !errors.As(err, &denied) || denied.Dataset != "Booking" {
+		t.Errorf("want: %T for Booking, got: %v", denied, err)
+	}
+
+	if _, err := testDb.WithRole("unknown").Fetch("Message"); !errors.As(err, &denied) {
+		t.Errorf("want: %T for unknown role, got: %v", denied, err)
+	}
+}
+
+func TestWithRoleRepositoryOperations(t *testing.T) {
+	cfg := getConfig()
+	cfg.ShareKey = "share-key"
+	cfg.Roles = map[string]gitdb.Role{
+		"reporting": {"Message": gitdb.PermRead},
+		"cleanup":   {"Message": gitdb.PermAll},
+		"admin":     {"*": gitdb.PermAll},
+	}
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	insert(getTestMessageWithId(1), true)
+
+	var denied *gitdb.ErrAccessDenied
+	reporting := testDb.WithRole("reporting")
+	if _, err := reporting.ShareLink("Booking", "HEAD", time.Hour); !errors.As(err, &denied) || denied.Dataset != "Booking" {
+		t.Errorf("want: %T for a share link of Booking, got: %v", denied, err)
+	}
+	if _, err := reporting.ShareLink("Message", "HEAD", time.Hour); err != nil {
+		t.Errorf("want: a share link of Message, got: %s", err)
+	}
+	for name, op := range map[string]func(gitdb.GitDb) error{
+		"Branch":        func(conn gitdb.GitDb) error { return conn.Branch("reports") },
+		"SwitchBranch":  func(conn gitdb.GitDb) error { return conn.SwitchBranch("master") },
+		"MergeBranch":   func(conn gitdb.GitDb) error { return conn.MergeBranch("master") },
+		"SquashHistory": func(conn gitdb.GitDb) error { return conn.SquashHistory(time.Now()) },
+		"TagRelease":    func(conn gitdb.GitDb) error { return conn.TagRelease("v1") },
+		"Sync":          func(conn gitdb.GitDb) error { return conn.Sync() },
+		"Fsck":          func(conn gitdb.GitDb) error { _, err := conn.Fsck(true); return err },
+		"Close":         func(conn gitdb.GitDb) error { return conn.Close() },
+	} {
+		if err := op(reporting); !errors.As(err, &denied) || denied.Dataset != "*" {
+			t.Errorf("want: %T for %s, got: %v", denied, name, err)
+		}
+	}
+	//deleting records of a dataset doesn't allow rewriting the history and audit log of the repository
+	if err := testDb.WithRole("cleanup").Shred("Message/b0/1"); !errors.As(err, &denied) || denied.Dataset != "*" {
+		t.Errorf("want: %T for Shred, got: %v", denied, err)
+	}
+	if cfg := reporting.Config(); len(cfg.EncryptionKey) > 0 || len(cfg.ShareKey) > 0 {
+		t.Errorf("want: keys left out of the config of a role")
+	}
+
+	admin := testDb.WithRole("admin")
+	if err := admin.TagRelease("v1"); err != nil {
+		t.Errorf("want: admin to tag a release, got: %s", err)
+	}
+	if cfg := admin.Config(); cfg.EncryptionKey != testDb.Config().EncryptionKey {
+		t.Errorf("want: keys in the config of an admin")
+	}
+}
@@ -183,7 +183,7 @@ func (m *Message) GetLockFileNames() []string {
 // func (m *Message) BeforeInsert() error { return nil }
 
 type MessageV2 struct {
-	gitdb.TimeStampedModel
+	testModel
 	MessageId int
 	From      string
 	To        string
@@ -203,11 +203,6 @@ func (m *MessageV2) GetSchema() *gitdb.Schema {
 	return gitdb.NewSchema(name, block, record, indexes)
 }
 
-func (m *MessageV2) Validate() error            { return nil }
-func (m *MessageV2) IsLockable() bool           { return false }
-func (m *MessageV2) ShouldEncrypt() bool        { return false }
-func (m *MessageV2) GetLockFileNames() []string { return []string{} }
-
 // func (m *MessageV2) BeforeInsert() error { return nil }
 
 //count the number of records in fetched block
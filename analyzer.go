@@ -0,0 +1,80 @@
+package gitdb
+
+import (
+	"strings"
+	"unicode"
+)
+
+//Analyzer turns the text of a Config.FullText field, and the queries passed to SearchText,
+//into search terms. Each token of Tokenize is passed to Normalize and then Stem and empty
+//terms are dropped
+type Analyzer interface {
+	Tokenize(text string) []string
+	Normalize(token string) string
+	Stem(token string) string
+}
+
+//DefaultAnalyzer splits text into words of letters and numbers and lowercases them.
+//It is used by fields without an analyzer in Config.Analyzers
+type DefaultAnalyzer struct{}
+
+//Tokenize splits text on anything that is not a letter or a number
+func (DefaultAnalyzer) Tokenize(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+//Normalize lowercases token
+func (DefaultAnalyzer) Normalize(token string) string {
+	return strings.ToLower(token)
+}
+
+//Stem returns token unchanged
+func (DefaultAnalyzer) Stem(token string) string {
+	return token
+}
+
+//KeywordAnalyzer treats the whole text as a single term with everything but letters and numbers
+//removed so codes such as SKUs and phone numbers match however they are formatted
+//e.g "+44 (0)20 7946-0018" and "4402079460018"
+type KeywordAnalyzer struct{}
+
+//Tokenize returns text as the only token
+func (KeywordAnalyzer) Tokenize(text string) []string {
+	return []string{text}
+}
+
+//Normalize lowercases token and drops anything that is not a letter or a number
+func (KeywordAnalyzer) Normalize(token string) string {
+	return strings.Map(func(r rune) rune {
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, token)
+}
+
+//Stem returns token unchanged
+func (KeywordAnalyzer) Stem(token string) string {
+	return token
+}
+
+//analyze counts the terms a produces from text
+func analyze(a Analyzer, text string) map[string]int {
+	terms := map[string]int{}
+	for _, token := range a.Tokenize(text) {
+		if term := a.Stem(a.Normalize(token)); len(term) > 0 {
+			terms[term]++
+		}
+	}
+	return terms
+}
+
+//analyzer returns the Analyzer configured for field of dataset
+func (g *gitdb) analyzer(dataset string, field string) Analyzer {
+	if a, ok := g.config.Analyzers[dataset+"."+field]; ok && a != nil {
+		return a
+	}
+	return DefaultAnalyzer{}
+}
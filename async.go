@@ -0,0 +1,218 @@
+package gitdb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bouggo/log"
+)
+
+const defaultAsyncBatchSize = 100
+const defaultAsyncFlushInterval = time.Second
+
+//asyncWrite is a write queued by InsertAsync or, when flushed is set, a call to Flush
+type asyncWrite struct {
+	m       *model
+	user    *User
+	done    func(error)
+	flushed chan bool
+}
+
+//InsertAsync queues m to be written in the background and returns straight away. Queued writes
+//are made in batches of Config.AsyncBatchSize, or every Config.AsyncFlushInterval, with one commit
+//per batch. done is called with the result once m is committed, or straight away if m is not valid.
+//m must not be changed until done is called. done may queue writes with InsertAsync but must not call Flush.
+//Use Flush to wait for every queued write
+func (g *gitdb) InsertAsync(m Model, done func(err error)) {
+	g.insertAsync(m, done, nil)
+}
+
+func (g *gitdb) insertAsync(mo Model, done func(err error), user *User) {
+	if done == nil {
+		done = func(error) {}
+	}
+
+	if err := g.writable(); err != nil {
+		done(err)
+		return
+	}
+
+	//hooks and validation run now so they see m as it was passed in
+	m, err := g.prepareInsert(mo)
+	if err != nil {
+		done(err)
+		return
+	}
+
+	if err := g.enqueue(&asyncWrite{m: m, user: user, done: done}); err != nil {
+		done(err)
+	}
+}
+
+//Flush blocks until every write queued by InsertAsync before it is committed
+func (g *gitdb) Flush() error {
+	g.asyncMu.Lock()
+	started := g.async != nil
+	g.asyncMu.Unlock()
+	if !started {
+		return nil
+	}
+
+	flushed := make(chan bool)
+	if err := g.enqueue(&asyncWrite{flushed: flushed}); err != nil {
+		return err
+	}
+	<-flushed
+	return nil
+}
+
+//enqueue adds w to the queue of the background writer, starting it on first use. w is sent outside asyncMu
+//so a producer waiting on a full queue doesn't keep others, or stopAsyncWriter, out
+func (g *gitdb) enqueue(w *asyncWrite) error {
+	g.asyncMu.Lock()
+	if g.asyncStopped {
+		g.asyncMu.Unlock()
+		return errConnectionClosed
+	}
+
+	if g.async == nil {
+		g.async = make(chan *asyncWrite, g.config.AsyncBatchSize)
+		go g.asyncWriter(g.async)
+	}
+	queue := g.async
+	g.asyncSending.Add(1)
+	g.asyncMu.Unlock()
+
+	queue <- w
+	g.asyncSending.Done()
+	return nil
+}
+
+//stopAsyncWriter writes what is left in the queue of the background writer and stops taking writes
+func (g *gitdb) stopAsyncWriter() {
+	g.asyncMu.Lock()
+	g.asyncStopped = true
+	queue := g.async
+	g.asyncMu.Unlock()
+	if queue == nil {
+		return
+	}
+
+	//writes already on their way to the queue are flushed along with the rest
+	g.asyncSending.Wait()
+	flushed := make(chan bool)
+	queue <- &asyncWrite{flushed: flushed}
+	<-flushed
+}
+
+//asyncWriter writes the batches of queue until the connection is closed
+func (g *gitdb) asyncWriter(queue chan *asyncWrite) {
+	log.Test("starting async writer")
+
+	var batch []*asyncWrite
+	var flushAt <-chan time.Time
+	//closed once the done callbacks of the last batch have been called
+	notified := make(chan struct{})
+	close(notified)
+	write := func() {
+		notified = g.writeBatch(batch, notified)
+		batch = nil
+		flushAt = nil
+	}
+
+	for {
+		select {
+		case <-g.shutdown:
+			log.Test("shutting down async writer")
+			return
+		case <-flushAt:
+			write()
+		case w := <-queue:
+			if w.flushed != nil {
+				write()
+				go func(notified chan struct{}, flushed chan bool) {
+					<-notified
+					close(flushed)
+				}(notified, w.flushed)
+				continue
+			}
+
+			//a commit is attributed to one user so a write as someone else starts a new batch
+			if len(batch) > 0 && batch[0].user != w.user {
+				write()
+			}
+
+			batch = append(batch, w)
+			if len(batch) == 1 {
+				flushAt = time.After(g.config.AsyncFlushInterval)
+			}
+			if len(batch) >= g.config.AsyncBatchSize {
+				write()
+			}
+		}
+	}
+}
+
+//writeBatch writes every record of batch and commits them together. The done callbacks of batch are called in
+//the background, once those of the batch before it closes after, so they can queue writes of their own. The
+//returned channel is closed once they have been called
+func (g *gitdb) writeBatch(batch []*asyncWrite, after chan struct{}) chan struct{} {
+	if len(batch) == 0 {
+		return after
+	}
+
+	type change struct {
+		op     Op
+		before string
+		stored string
+	}
+
+	errs := make([]error, len(batch))
+	changes := make([]change, len(batch))
+	written := 0
+
+	//the records are staged without being committed, leaving autoCommit to transactions
+	g.commitMu.Lock()
+	for i, w := range batch {
+		w.m.batched = true
+		op, before, stored, err := g.writeRecord(w.m, w.user)
+		errs[i] = err
+		changes[i] = change{op, before, stored}
+		if err == nil {
+			written++
+		}
+	}
+
+	if written > 0 {
+		//the blocks staged by the batch are committed, see startEventLoop
+		committed := make(chan error, 1)
+		e := newWriteEvent(fmt.Sprintf("Writing %d records", written), "", true, batch[0].user, "batch")
+		e.Committed = committed
+		g.commit.Add(1)
+		g.events <- e
+		if err := <-committed; err != nil {
+			for i := range errs {
+				if errs[i] == nil {
+					errs[i] = fmt.Errorf("failed to commit %s: %w", ID(batch[i].m), err)
+				}
+			}
+		}
+	}
+	g.commitMu.Unlock()
+
+	notified := make(chan struct{})
+	go func() {
+		<-after
+		for i, w := range batch {
+			if errs[i] == nil {
+				c := changes[i]
+				g.audit(c.op, ID(w.m), c.before, modelData(w.m), storedEncrypted(c.stored), w.user)
+				g.publishWrite(c.op, ID(w.m))
+				errs[i] = afterWrite(c.op, w.m)
+			}
+			w.done(errs[i])
+		}
+		close(notified)
+	}()
+	return notified
+}
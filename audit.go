@@ -0,0 +1,168 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bouggo/log"
+	"github.com/gogitdb/gitdb/v2/internal/crypto"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+const auditDataset = "_audit"
+
+//ErrAuditReadOnly is returned when a record of the _audit dataset is written or deleted other than by the audit log
+var ErrAuditReadOnly = errors.New("the _audit dataset can only be written by the audit log")
+
+//AuditEntry is a change made to a record, written to the _audit dataset when Config.Audit is set.
+//Entries are stored in a block per month and can be read like any other dataset
+//e.g db.Search("_audit", []*SearchParam{{Index: "Record", Value: id}}, SearchEquals)
+type AuditEntry struct {
+	//Entry is the record ID of the entry in the _audit dataset
+	Entry     string
+	Actor     string
+	Operation Op
+	Dataset   string
+	//Record is the ID of the record that was changed
+	Record string
+	//Before is the data of the record as JSON before the change. It is empty for inserts
+	Before string
+	//After is the data of the record as JSON after the change. It is empty for deletes
+	After string
+	//Changed lists the fields of the record that were added, modified or removed
+	Changed []string
+	TimeStampedModel
+	encrypt bool
+}
+
+//GetSchema implements Model.GetSchema
+func (e *AuditEntry) GetSchema() *Schema {
+	return newSchema(
+		auditDataset,
+		e.CreatedAt.Format("200601"),
+		e.Entry,
+		map[string]interface{}{
+			"Actor":     e.Actor,
+			"Operation": string(e.Operation),
+			"Dataset":   e.Dataset,
+			"Record":    e.Record,
+		},
+	)
+}
+
+//Validate implements Model.Validate
+func (e *AuditEntry) Validate() error { return nil }
+
+//IsLockable informs GitDb if a Model support locking
+func (e *AuditEntry) IsLockable() bool { return false }
+
+//GetLockFileNames informs GitDb of files a Models using for locking
+func (e *AuditEntry) GetLockFileNames() []string { return nil }
+
+//ShouldEncrypt is true when the audited record is stored encrypted
+func (e *AuditEntry) ShouldEncrypt() bool { return e.encrypt }
+
+//audit writes an AuditEntry of the change op made by user to record id. before and after are
+//the data of the record as JSON. Failing to audit is logged since the change is already committed
+func (g *gitdb) audit(op Op, id string, before string, after string, encrypt bool, user *User) {
+	if !g.config.Audit {
+		return
+	}
+
+	dataset, _, _, err := ParseID(id)
+	if err != nil || dataset == auditDataset {
+		return
+	}
+
+	if user == nil {
+		user = g.config.User
+	}
+
+	changed := changedFields(before, after)
+	g.readCache(func() error {
+		before = g.meta().redactJSON(dataset, before)
+		after = g.meta().redactJSON(dataset, after)
+		return nil
+	})
+
+	now := time.Now()
+	entry := &AuditEntry{
+		Entry:     fmt.Sprintf("%d", now.UnixNano()),
+		Actor:     user.String(),
+		Operation: op,
+		Dataset:   dataset,
+		Record:    id,
+		Before:    before,
+		After:     after,
+		Changed:   changed,
+		encrypt:   encrypt,
+	}
+	entry.CreatedAt = now
+
+	//entries are written in the turn of the write being audited so they don't queue behind it
+	m, err := g.prepareInsert(entry)
+	if err == nil {
+		m.audit = true
+		err = g.write(m, user)
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to audit %s of %s: %s", op, id, err))
+	}
+}
+
+//recordData returns the decrypted data of record as JSON
+func recordData(record *db.Record) string {
+	var data map[string]interface{}
+	if err := record.Hydrate(&data); err != nil {
+		log.Error(err.Error())
+		return ""
+	}
+	b, _ := json.Marshal(data)
+	return string(b)
+}
+
+//modelData returns the data of m as JSON as it is stored, without its transient fields
+func modelData(m Model) string {
+	if wrapped, ok := m.(*model); ok {
+		m = wrapped.Data
+	}
+	b, err := json.Marshal(m)
+	if err == nil {
+		b, err = stripTransient(b, transientPaths(reflect.TypeOf(m)))
+	}
+	if err != nil {
+		log.Error(err.Error())
+	}
+	return string(b)
+}
+
+//storedEncrypted reports whether raw, a record as stored in a block, is encrypted in whole or in part
+func storedEncrypted(raw string) bool {
+	return !json.Valid([]byte(raw)) || strings.Contains(raw, crypto.FieldKey)
+}
+
+//changedFields returns the top level fields that differ between the JSON objects before and after
+func changedFields(before string, after string) []string {
+	var b, a map[string]json.RawMessage
+	json.Unmarshal([]byte(before), &b)
+	json.Unmarshal([]byte(after), &a)
+
+	var changed []string
+	for field, v := range a {
+		if old, ok := b[field]; !ok || string(old) != string(v) {
+			changed = append(changed, field)
+		}
+	}
+	for field := range b {
+		if _, ok := a[field]; !ok {
+			changed = append(changed, field)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
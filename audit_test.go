@@ -0,0 +1,64 @@
+package gitdb_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestAudit(t *testing.T) {
+	cfg := getConfig()
+	cfg.Audit = true
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	m := getTestMessage()
+	jane := testDb.WithUser("Jane", "jane@example.com")
+	if err := jane.Insert(m); err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+	m.Body = "edited"
+	if err := jane.Insert(m); err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+	if err := testDb.Delete(gitdb.ID(m)); err != nil {
+		t.Fatalf("Delete failed: %s", err)
+	}
+
+	records, err := testDb.Search("_audit", []*gitdb.SearchParam{{Index: "Record", Value: gitdb.ID(m)}}, gitdb.SearchEquals)
+	if err != nil || len(records) != 3 {
+		t.Fatalf("want: 3 audit entries, got: %d (%v)", len(records), err)
+	}
+
+	entries := map[gitdb.Op]*gitdb.AuditEntry{}
+	for _, r := range records {
+		e := &gitdb.AuditEntry{}
+		if err := r.Hydrate(e); err != nil {
+			t.Fatal(err)
+		}
+		entries[e.Operation] = e
+	}
+
+	if e := entries[gitdb.OpInsert]; e == nil || e.Actor != "Jane <jane@example.com>" || len(e.Before) != 0 {
+		t.Errorf("want: insert by Jane, got: %+v", e)
+	}
+	if e := entries[gitdb.OpUpdate]; e == nil || len(e.Changed) == 0 || e.Changed[0] != "Body" {
+		t.Errorf("want: update of Body, got: %+v", e)
+	}
+	if e := entries[gitdb.OpDelete]; e == nil || len(e.Before) == 0 || len(e.After) != 0 {
+		t.Errorf("want: delete with record before, got: %+v", e)
+	}
+
+	e := entries[gitdb.OpInsert]
+	e.Actor = "Mallory"
+	if err := testDb.Insert(e); !errors.Is(err, gitdb.ErrAuditReadOnly) {
+		t.Errorf("want: %v for an edited entry, got: %v", gitdb.ErrAuditReadOnly, err)
+	}
+	if err := testDb.Delete(records[0].ID()); !errors.Is(err, gitdb.ErrAuditReadOnly) {
+		t.Errorf("want: %v for a deleted entry, got: %v", gitdb.ErrAuditReadOnly, err)
+	}
+	if records, _ := testDb.Fetch("_audit"); len(records) != 3 {
+		t.Errorf("want: 3 audit entries left as written, got: %d", len(records))
+	}
+}
@@ -0,0 +1,140 @@
+package gitdb
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bouggo/log"
+)
+
+//backfillIndexes finds indexes that were added to a Schema after records were
+//written to its dataset and adds them to the existing records and the index
+func (g *gitdb) backfillIndexes() {
+	if !g.config.hasFactory() || g.config.readOnly {
+		return
+	}
+
+	//the index must be fully built to tell which indexes are missing
+	g.indexing.Wait()
+
+	datasets, err := g.datasetNames()
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+
+	for _, dataset := range datasets {
+		missing := g.missingIndexes(dataset)
+		if len(missing) == 0 {
+			continue
+		}
+
+		if err := g.backfill(dataset, missing); err != nil {
+			log.Error(fmt.Sprintf("Failed to backfill indexes %v of %s: %s", missing, dataset, err))
+		}
+	}
+}
+
+//missingIndexes returns the indexes of dataset's Schema that have no index file
+func (g *gitdb) missingIndexes(dataset string) []string {
+	m := g.config.factory(dataset)
+	if m == nil || len(g.index(dataset, "id")) == 0 {
+		return nil
+	}
+
+	var missing []string
+	for name := range m.GetSchema().indexes {
+		if _, err := os.Stat(g.indexFile(dataset, name)); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+
+	return missing
+}
+
+//backfill rewrites the records of dataset with the indexes of its current Schema and commits them
+func (g *gitdb) backfill(dataset string, indexes []string) error {
+	g.commitMu.Lock()
+	defer g.commitMu.Unlock()
+
+	blockFiles, err := g.blockFiles(dataset)
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+	for i, blockFile := range blockFiles {
+		log.Info(fmt.Sprintf("Backfilling indexes %v of %s: block %d of %d", indexes, dataset, i+1, len(blockFiles)))
+
+		dataBlock, err := g.loadBlock(blockFile)
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		for _, record := range dataBlock.Records() {
+			//v1 records are indexed with the Config.Factory or registered model so are never missing an index
+			if record.Version() != RecVersion {
+				continue
+			}
+
+			if !hasIndexes(record.Indexes(), indexes) {
+				m := g.newModel(dataset, record)
+				if m == nil {
+					continue
+				}
+				if err := record.Hydrate(m); err != nil {
+					return err
+				}
+
+				schema := schemaOf(m)
+				if err := g.blindIndexes(schema, schema.indexes); err != nil {
+					return err
+				}
+
+				recordStr, err := g.encodeRecord(&model{Version: RecVersion, Type: record.Type(), Indexes: schema.indexes, Data: m})
+				if err != nil {
+					return err
+				}
+
+				dataBlock.Add(record.ID(), recordStr)
+				ids = append(ids, record.ID())
+				changed = true
+			}
+		}
+
+		if changed {
+			if err := g.writeBlock(blockFile, dataBlock); err != nil {
+				return err
+			}
+		}
+		g.updateIndexes(dataBlock)
+	}
+
+	if err := g.flushIndex(); err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	g.commit.Add(1)
+	g.events <- newWriteEvent("Backfilling indexes "+strings.Join(indexes, ", ")+" of "+dataset, g.datasetPath(dataset), true, nil, "backfill", ids...)
+	g.waitForCommit()
+	log.Info(fmt.Sprintf("Backfilled indexes %v of %d records in %s", indexes, len(ids), dataset))
+
+	return nil
+}
+
+func hasIndexes(recordIndexes map[string]interface{}, names []string) bool {
+	for _, name := range names {
+		if _, ok := recordIndexes[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
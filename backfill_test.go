@@ -0,0 +1,67 @@
+package gitdb_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+//guestIndexesCity adds the City index to the Guest schema
+var guestIndexesCity bool
+
+type Guest struct {
+	testModel
+	GuestId int
+	Name    string
+	City    string
+}
+
+func (g *Guest) GetSchema() *gitdb.Schema {
+	indexes := map[string]interface{}{"Name": g.Name}
+	if guestIndexesCity {
+		indexes["City"] = g.City
+	}
+	return gitdb.NewSchema("Guest", "b0", fmt.Sprintf("%d", g.GuestId), indexes)
+}
+
+func (g *Guest) ShouldEncrypt() bool { return true }
+
+func TestBackfillIndexes(t *testing.T) {
+	guestIndexesCity = false
+	defer func() { guestIndexesCity = false }()
+
+	cfg := getConfig()
+	cfg.Factory = func(dataset string) gitdb.Model {
+		if dataset == "Guest" {
+			return &Guest{}
+		}
+		return nil
+	}
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	for i, city := range []string{"Lagos", "Accra", "Lagos"} {
+		if err := testDb.Insert(&Guest{GuestId: i, Name: fmt.Sprintf("guest %d", i), City: city}); err != nil {
+			t.Fatalf("testDb.Insert failed: %s", err)
+		}
+	}
+	testDb.Close()
+
+	guestIndexesCity = true
+	testDb = getDbConn(t, cfg)
+
+	sp := &gitdb.SearchParam{Index: "City", Value: "Lagos"}
+	results, err := testDb.Search("Guest", []*gitdb.SearchParam{sp}, gitdb.SearchEquals)
+	if err != nil {
+		t.Fatalf("testDb.Search failed: %s", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("want: 2 guests in Lagos after backfill, got: %d", len(results))
+	}
+
+	if msg := headCommitMessage(t); !strings.HasPrefix(msg, "Backfilling indexes City of Guest") {
+		t.Errorf("want: backfill commit, got: %s", msg)
+	}
+}
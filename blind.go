@@ -0,0 +1,78 @@
+package gitdb
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bouggo/log"
+)
+
+//BlindIndex stores the values of indexes as HMACs keyed with Config.IndexKey so records, e.g with
+//fields encrypted by EncryptFields, can be found by equality searches without their values being
+//readable in the record or index files. Search, SearchIDs and SearchEquals only find exact matches of
+//blind indexes. Values are compared ignoring case and surrounding white space, or by their collation
+func (a *Schema) BlindIndex(indexes ...string) *Schema {
+	if a.blind == nil {
+		a.blind = map[string]bool{}
+	}
+	for _, index := range indexes {
+		a.blind[index] = true
+	}
+	return a
+}
+
+func (a *Schema) validateBlindIndexes() error {
+	for index := range a.blind {
+		if _, ok := a.indexes[index]; !ok {
+			return fmt.Errorf("blind index %s is not an index of %s", index, a.dataset)
+		}
+
+		//composite and geo indexes would store the value in the clear
+		for _, fields := range a.composites {
+			for _, field := range fields {
+				if field == index {
+					return fmt.Errorf("blind index %s can not be part of a composite index", index)
+				}
+			}
+		}
+		for _, field := range a.geo {
+			if field == index {
+				return fmt.Errorf("blind index %s can not be part of a geo index", index)
+			}
+		}
+	}
+	return nil
+}
+
+//blindIndexes replaces the values of the blind indexes of schema in indexes with their HMAC
+func (g *gitdb) blindIndexes(schema *Schema, indexes map[string]interface{}) error {
+	if len(schema.blind) == 0 {
+		return nil
+	}
+
+	if len(g.config.IndexKey) == 0 {
+		return errors.New("BlindIndex requires Config.IndexKey")
+	}
+
+	for index := range schema.blind {
+		if v, ok := indexes[index]; ok {
+			indexes[index] = g.blindValue(fmt.Sprint(v))
+		}
+	}
+	return nil
+}
+
+//blindValue returns the HMAC of s keyed with Config.IndexKey
+func (g *gitdb) blindValue(s string) string {
+	if len(g.config.IndexKey) == 0 {
+		log.Error("BlindIndex requires Config.IndexKey")
+	}
+
+	mac := hmac.New(sha256.New, []byte(g.config.IndexKey))
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(s))))
+	return hex.EncodeToString(mac.Sum(nil))
+}
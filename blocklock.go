@@ -0,0 +1,58 @@
+package gitdb
+
+import "sync"
+
+//blockLocks holds a read-write lock for each block file so a block file is never read while it is
+//being written. Readers share the lock of a block and see either its old or its new content.
+//Git operations that rewrite the working tree e.g pull take every block with lockAll
+type blockLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.RWMutex
+	//tree is shared by everyone holding the lock of a block and taken by lockAll
+	tree sync.RWMutex
+}
+
+//get returns the lock of blockFile
+func (b *blockLocks) get(blockFile string) *sync.RWMutex {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.locks == nil {
+		b.locks = map[string]*sync.RWMutex{}
+	}
+
+	lock, ok := b.locks[blockFile]
+	if !ok {
+		lock = &sync.RWMutex{}
+		b.locks[blockFile] = lock
+	}
+	return lock
+}
+
+//lock takes the lock of blockFile to write it and returns the func that releases it
+func (b *blockLocks) lock(blockFile string) func() {
+	b.tree.RLock()
+	lock := b.get(blockFile)
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		b.tree.RUnlock()
+	}
+}
+
+//rlock takes the lock of blockFile to read it and returns the func that releases it
+func (b *blockLocks) rlock(blockFile string) func() {
+	b.tree.RLock()
+	lock := b.get(blockFile)
+	lock.RLock()
+	return func() {
+		lock.RUnlock()
+		b.tree.RUnlock()
+	}
+}
+
+//lockAll takes the lock of every block, waiting for those held to be released, and returns the func
+//that releases them
+func (b *blockLocks) lockAll() func() {
+	b.tree.Lock()
+	return b.tree.Unlock
+}
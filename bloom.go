@@ -0,0 +1,140 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bouggo/log"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//bloomBitsPerRecord gives a false positive rate of about 1% with bloomHashes hashes
+const bloomBitsPerRecord = 10
+const bloomHashes = 7
+const bloomMinBits = 64
+
+//bloomFilter records which ids a block holds so lookups can skip blocks that don't have an id
+type bloomFilter struct {
+	Bits []uint64 `json:"bits"`
+}
+
+func newBloomFilter(n int) *bloomFilter {
+	bits := n * bloomBitsPerRecord
+	if bits < bloomMinBits {
+		bits = bloomMinBits
+	}
+	return &bloomFilter{Bits: make([]uint64, (bits+63)/64)}
+}
+
+//positions returns the bits of id using double hashing
+func (b *bloomFilter) positions(id string) []uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	h1 := h.Sum64()
+	h2 := h1>>33 | h1<<31
+
+	m := uint64(len(b.Bits) * 64)
+	positions := make([]uint64, bloomHashes)
+	for i := range positions {
+		positions[i] = (h1 + uint64(i)*h2) % m
+	}
+	return positions
+}
+
+func (b *bloomFilter) add(id string) {
+	for _, p := range b.positions(id) {
+		b.Bits[p/64] |= 1 << (p % 64)
+	}
+}
+
+//mightContain returns false if id is definitely not in the block
+func (b *bloomFilter) mightContain(id string) bool {
+	if len(b.Bits) == 0 {
+		return true
+	}
+
+	for _, p := range b.positions(id) {
+		if b.Bits[p/64]&(1<<(p%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *gitdb) bloomFile(dataset string, block string) string {
+	return filepath.Join(g.bloomDir(), dataset, block+".json")
+}
+
+//readBloom returns the bloom filter in file or nil if there is none
+func readBloom(file string) *bloomFilter {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+
+	b := &bloomFilter{}
+	if err := json.Unmarshal(data, b); err != nil {
+		log.Error(err.Error())
+		return nil
+	}
+	return b
+}
+
+//updateBloom rebuilds the bloom filter of dataBlock from its records
+func (g *gitdb) updateBloom(dataBlock *db.Block) {
+	records := dataBlock.Records()
+	b := newBloomFilter(len(records))
+	for _, record := range records {
+		b.add(record.ID())
+	}
+
+	dataset := dataBlock.Dataset().Name()
+	file := g.bloomFile(dataset, dataBlock.Name())
+	if g.blooms == nil {
+		g.blooms = map[string]*bloomFilter{}
+	}
+	g.blooms[file] = b
+
+	data, err := json.Marshal(b)
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(file), 0755); err == nil {
+			err = ioutil.WriteFile(file, data, 0744)
+		}
+	}
+	if err != nil {
+		log.Error("Failed to write bloom filter: " + err.Error())
+	}
+}
+
+//mightContain returns false if the block of id definitely does not hold it
+func (g *gitdb) mightContain(id string) bool {
+	dataset, block, _, err := ParseID(id)
+	if err != nil {
+		return false
+	}
+
+	file := g.bloomFile(dataset, block)
+	b, ok := g.blooms[file]
+	if !ok {
+		b = readBloom(file)
+		if b == nil {
+			return true
+		}
+		if g.blooms == nil {
+			g.blooms = map[string]*bloomFilter{}
+		}
+		g.blooms[file] = b
+	}
+
+	return b.mightContain(id)
+}
+
+//resetBlooms discards the bloom filters of all blocks. It must be called when blocks change outside of
+//gitdb's write path e.g by a pull, as a stale filter would rule out records blocks hold
+func (g *gitdb) resetBlooms() error {
+	g.blooms = map[string]*bloomFilter{}
+	return os.RemoveAll(g.bloomDir())
+}
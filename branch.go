@@ -0,0 +1,82 @@
+package gitdb
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bouggo/log"
+)
+
+//Branch creates a new branch called name from the current branch
+func (g *gitdb) Branch(name string) error {
+	if err := g.checkBranchName(name); err != nil {
+		return err
+	}
+
+	return g.gitDriver.createBranch(name)
+}
+
+//SwitchBranch points the connection at branch name. All subsequent reads
+//and writes will operate on this branch
+func (g *gitdb) SwitchBranch(name string) error {
+	if err := g.checkBranchName(name); err != nil {
+		return err
+	}
+
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
+
+	g.cacheMu.Lock()
+	err := g.flushIndex()
+	g.cacheMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	before, _ := g.gitDriver.head()
+	if err := g.gitCheckout(name); err != nil {
+		return err
+	}
+	g.trustChanges(before)
+
+	log.Info("switched to branch " + name)
+	return g.reindex()
+}
+
+//MergeBranch merges branch name into the current branch
+func (g *gitdb) MergeBranch(name string) error {
+	if err := g.writable(); err != nil {
+		return err
+	}
+	if err := g.checkBranchName(name); err != nil {
+		return err
+	}
+
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
+
+	before, _ := g.gitDriver.head()
+	if err := g.gitMerge(name, g.config.User); err != nil {
+		return err
+	}
+	g.trustChanges(before)
+
+	return g.reindex()
+}
+
+//CurrentBranch returns the branch the connection is operating on
+func (g *gitdb) CurrentBranch() string {
+	branch, err := g.gitDriver.currentBranch()
+	if err != nil {
+		log.Error(err.Error())
+	}
+	return branch
+}
+
+//checkBranchName rejects names git would take for an option and names that aren't valid branch names
+func (g *gitdb) checkBranchName(name string) error {
+	if len(name) == 0 || strings.HasPrefix(name, "-") {
+		return errors.New("Invalid branch name: " + name)
+	}
+	return g.gitDriver.checkBranchName(name)
+}
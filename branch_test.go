@@ -0,0 +1,62 @@
+package gitdb_test
+
+import (
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestBranches(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	if err := insert(getTestMessageWithId(0), false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	main := testDb.CurrentBranch()
+	for _, name := range []string{"", "--orphan", "staging..prod", "staging lock"} {
+		if err := testDb.Branch(name); err == nil {
+			t.Errorf("testDb.Branch(%q) should fail", name)
+		}
+		if err := testDb.SwitchBranch(name); err == nil {
+			t.Errorf("testDb.SwitchBranch(%q) should fail", name)
+		}
+		if err := testDb.MergeBranch(name); err == nil {
+			t.Errorf("testDb.MergeBranch(%q) should fail", name)
+		}
+	}
+
+	if err := testDb.Branch("staging"); err != nil {
+		t.Fatalf("testDb.Branch failed: %s", err)
+	}
+
+	if err := testDb.SwitchBranch("staging"); err != nil {
+		t.Fatalf("testDb.SwitchBranch failed: %s", err)
+	}
+
+	if got := testDb.CurrentBranch(); got != "staging" {
+		t.Errorf("want: staging, got: %s", got)
+	}
+
+	m := getTestMessageWithId(1)
+	if err := insert(m, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	if err := testDb.SwitchBranch(main); err != nil {
+		t.Fatalf("testDb.SwitchBranch failed: %s", err)
+	}
+
+	if err := testDb.Exists(gitdb.ID(m)); err == nil {
+		t.Errorf("%s should not exist on %s", gitdb.ID(m), main)
+	}
+
+	if err := testDb.MergeBranch("staging"); err != nil {
+		t.Fatalf("testDb.MergeBranch failed: %s", err)
+	}
+
+	if err := testDb.Exists(gitdb.ID(m)); err != nil {
+		t.Errorf("%s should exist after merge: %s", gitdb.ID(m), err)
+	}
+}
@@ -0,0 +1,122 @@
+package gitdb
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/bouggo/log"
+)
+
+//ChangeEntry is an entry of the change feed of a dataset
+type ChangeEntry struct {
+	//Seq numbers the changes of a dataset from 1 in the order they were made
+	Seq int64  `json:"seq"`
+	Op  Op     `json:"op"`
+	ID  string `json:"id"`
+	//SHA is the commit the change is in. It is empty for changes made in a transaction as they are committed later
+	SHA string `json:"sha"`
+}
+
+//changeFeed holds the last sequence number of the change feed of each dataset
+type changeFeed struct {
+	mu   sync.Mutex
+	last map[string]int64
+}
+
+func (g *gitdb) changesDir() string {
+	return filepath.Join(g.absDbPath(), g.internalDirName(), "_changes")
+}
+
+func (g *gitdb) changesFile(dataset string) string {
+	return filepath.Join(g.changesDir(), dataset)
+}
+
+//appendChange adds op on record id in commit sha to the change feed of dataset if Config.ChangeFeed is set
+func (g *gitdb) appendChange(dataset string, op Op, id string, sha string) {
+	if !g.config.ChangeFeed {
+		return
+	}
+
+	g.changes.mu.Lock()
+	defer g.changes.mu.Unlock()
+
+	if g.changes.last == nil {
+		g.changes.last = map[string]int64{}
+	}
+	last, ok := g.changes.last[dataset]
+	if !ok {
+		changes, err := g.readChanges(dataset, 0)
+		if err != nil {
+			log.Error("failed to read change feed of " + dataset + ": " + err.Error())
+			return
+		}
+		if len(changes) > 0 {
+			last = changes[len(changes)-1].Seq
+		}
+	}
+
+	change := &ChangeEntry{Seq: last + 1, Op: op, ID: id, SHA: sha}
+	if err := g.writeChange(dataset, change); err != nil {
+		log.Error("failed to append to change feed of " + dataset + ": " + err.Error())
+		return
+	}
+	g.changes.last[dataset] = change.Seq
+}
+
+func (g *gitdb) writeChange(dataset string, change *ChangeEntry) error {
+	if err := os.MkdirAll(g.changesDir(), 0755); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(g.changesFile(dataset), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//ChangesSince returns the changes to dataset after seq from its change feed, oldest first. Pass 0 for
+//every change, then the Seq of the last change read to tail the feed. The feed is kept by each node in
+//its .gitdb directory when Config.ChangeFeed is set and holds the changes the node made or pulled in by a sync
+func (g *gitdb) ChangesSince(dataset string, seq int64) ([]*ChangeEntry, error) {
+	g.changes.mu.Lock()
+	defer g.changes.mu.Unlock()
+
+	return g.readChanges(dataset, seq)
+}
+
+func (g *gitdb) readChanges(dataset string, seq int64) ([]*ChangeEntry, error) {
+	f, err := os.Open(g.changesFile(dataset))
+	if os.IsNotExist(err) {
+		return []*ChangeEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	changes := []*ChangeEntry{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		change := &ChangeEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), change); err != nil {
+			return nil, err
+		}
+		if change.Seq > seq {
+			changes = append(changes, change)
+		}
+	}
+	return changes, scanner.Err()
+}
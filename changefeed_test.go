@@ -0,0 +1,91 @@
+package gitdb_test
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestChangeFeed(t *testing.T) {
+	cfg := getConfig()
+	cfg.ChangeFeed = true
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	m := getTestMessageWithId(1)
+	id := gitdb.ID(m)
+	if err := testDb.Insert(m); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+	if err := testDb.Insert(m); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+	if err := testDb.Delete(id); err != nil {
+		t.Fatalf("testDb.Delete failed: %s", err)
+	}
+
+	changes, err := testDb.ChangesSince("Message", 0)
+	if err != nil {
+		t.Fatalf("testDb.ChangesSince failed: %s", err)
+	}
+	wantOps := []gitdb.Op{gitdb.OpInsert, gitdb.OpUpdate, gitdb.OpDelete}
+	if len(changes) != len(wantOps) {
+		t.Fatalf("want: %d changes, got: %d", len(wantOps), len(changes))
+	}
+	for i, c := range changes {
+		if c.Seq != int64(i+1) || c.Op != wantOps[i] || c.ID != id || c.SHA == "" {
+			t.Errorf("want: %d %s %s, got: %+v", i+1, wantOps[i], id, c)
+		}
+	}
+
+	head, err := exec.Command("git", "-C", filepath.Join(dbPath, "data"), "rev-parse", "HEAD").Output()
+	if err != nil {
+		t.Fatalf("git rev-parse failed: %s", err)
+	}
+	if last := changes[len(changes)-1]; last.SHA != strings.TrimSpace(string(head)) {
+		t.Errorf("want: %s, got: %s", head, last.SHA)
+	}
+
+	//tailing the feed returns the changes after the last one read
+	changes, err = testDb.ChangesSince("Message", 2)
+	if err != nil {
+		t.Fatalf("testDb.ChangesSince failed: %s", err)
+	}
+	if len(changes) != 1 || changes[0].Seq != 3 {
+		t.Errorf("want: change 3, got: %v", changes)
+	}
+
+	//the feed outlives the connection and keeps counting
+	testDb.Close()
+	testDb = getDbConn(t, cfg)
+	if err := testDb.Insert(m); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+	changes, err = testDb.ChangesSince("Message", 3)
+	if err != nil {
+		t.Fatalf("testDb.ChangesSince failed: %s", err)
+	}
+	if len(changes) != 1 || changes[0].Seq != 4 || changes[0].Op != gitdb.OpInsert {
+		t.Errorf("want: change 4, got: %v", changes)
+	}
+}
+
+func TestChangeFeedDisabled(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	if err := testDb.Insert(getTestMessage()); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+
+	changes, err := testDb.ChangesSince("Message", 0)
+	if err != nil {
+		t.Fatalf("testDb.ChangesSince failed: %s", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("want: no changes, got: %d", len(changes))
+	}
+}
@@ -0,0 +1,73 @@
+package gitdb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/gogitdb/gitdb/v2/internal/crypto"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//Cipher encrypts and decrypts records, and fields listed with EncryptFields, in place of the built-in
+//AES-GCM encryption e.g to use a FIPS validated module, libsodium or envelope encryption with a KMS
+type Cipher interface {
+	Encrypt(dataset string, plaintext []byte) ([]byte, error)
+	Decrypt(dataset string, ciphertext []byte) ([]byte, error)
+}
+
+//cipherHeader marks values encrypted with Config.Cipher
+const cipherHeader = "cipher:"
+
+//encrypt encrypts plaintext for dataset with Config.Cipher or the encryption key of dataset
+func (g *gitdb) encrypt(dataset string, key string, plaintext string) (string, error) {
+	if g.config.Cipher == nil {
+		return crypto.Encrypt(key, plaintext)
+	}
+
+	ciphertext, err := g.config.Cipher.Encrypt(dataset, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return cipherHeader + base64.RawURLEncoding.EncodeToString(ciphertext), nil
+}
+
+//cipherDecrypter decrypts values of dataset encrypted with Config.Cipher and, with keyring,
+//those encrypted by GitDB before the Cipher was configured
+type cipherDecrypter struct {
+	cipher  Cipher
+	dataset string
+	keyring db.Keyring
+}
+
+//Decrypt implements db.Decrypter
+func (d cipherDecrypter) Decrypt(secureMessage string) (string, error) {
+	if !strings.HasPrefix(secureMessage, cipherHeader) {
+		return d.keyring.Decrypt(secureMessage)
+	}
+
+	ciphertext, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(secureMessage, cipherHeader))
+	if err != nil {
+		return "", crypto.ErrMalformed
+	}
+
+	plaintext, err := d.cipher.Decrypt(d.dataset, ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if !json.Valid(plaintext) {
+		return "", crypto.ErrAuthFailed
+	}
+	return string(plaintext), nil
+}
+
+//decrypter returns what records of dataset are decrypted with given the keys in keyring
+func (c Config) decrypter(dataset string, keyring string) db.Decrypter {
+	if c.Cipher != nil {
+		return cipherDecrypter{cipher: c.Cipher, dataset: dataset, keyring: db.Keyring(keyring)}
+	}
+	if len(keyring) == 0 {
+		return nil
+	}
+	return db.Keyring(keyring)
+}
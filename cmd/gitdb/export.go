@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+//idField is the field gitdb export writes the id of each record in and gitdb import reads it from
+const idField = "_id"
+
+//transferFormats are the formats gitdb export writes and gitdb import reads
+var transferFormats = []string{"csv", "json", "ndjson"}
+
+//transferFormat returns format or, when it isn't given, the format of file going by its extension
+func transferFormat(format string, file string) (string, error) {
+	if len(format) == 0 {
+		format = strings.TrimPrefix(filepath.Ext(file), ".")
+		switch format {
+		case "":
+			format = "json"
+		case "jsonl":
+			format = "ndjson"
+		}
+	}
+	for _, f := range transferFormats {
+		if f == format {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %s; use csv, json or ndjson", format)
+}
+
+//parseArgs parses args, taking the dataset from the first argument that isn't a flag whether it comes
+//before or after the flags
+func parseArgs(flags *flag.FlagSet, args []string) (string, error) {
+	positional, err := parsePositional(flags, args)
+	if err != nil || len(positional) == 0 {
+		return "", err
+	}
+	return positional[0], nil
+}
+
+//parsePositional parses args and returns the arguments that aren't flags, whether they come before or after the flags
+func parsePositional(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		positional, args = append(positional, args[0]), args[1:]
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	return append(positional, flags.Args()...), nil
+}
+
+//exportOptions are the flags of gitdb export
+type exportOptions struct {
+	path    string
+	dataset string
+	format  string
+	out     string
+	noID    bool
+}
+
+//parseExport parses the flags of gitdb export Booking --format ndjson --out bookings.ndjson
+func parseExport(args []string) (*exportOptions, error) {
+	opts := &exportOptions{}
+	exportCommand := flag.NewFlagSet("export", flag.ContinueOnError)
+	exportCommand.StringVar(&opts.path, "path", ".", "path of the database i.e Config.DbPath")
+	exportCommand.StringVar(&opts.format, "format", "", "csv, json or ndjson; defaults to the extension of --out or json")
+	exportCommand.StringVar(&opts.out, "out", "", "file to write the records to; defaults to stdout")
+	exportCommand.BoolVar(&opts.noID, "no-id", false, "leave out the "+idField+" field holding the id of each record")
+	dataset, err := parseArgs(exportCommand, args)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.dataset = dataset
+	if len(opts.dataset) == 0 {
+		return nil, errors.New("usage: gitdb export <dataset> [--path .] [--format csv|json|ndjson] [--out file] [--no-id]")
+	}
+	if opts.format, err = transferFormat(opts.format, opts.out); err != nil {
+		return nil, err
+	}
+	return opts, checkDatabase(opts.path)
+}
+
+//export runs gitdb export Booking --format ndjson --out bookings.ndjson, writing every record of a dataset
+func export(args []string) error {
+	opts, err := parseExport(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	cfg := gitdb.NewConfig(opts.path)
+	cfg.EncryptionKey = os.Getenv("GITDB_ENCRYPTION_KEY")
+	db, err := openDatabase(cfg, false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	records, err := db.Fetch(opts.dataset)
+	if err != nil {
+		return err
+	}
+	rows := make([]map[string]json.RawMessage, len(records))
+	for i, record := range records {
+		//fields are kept as stored so numbers and times are written as they are
+		if err := record.Hydrate(&rows[i]); err != nil {
+			return fmt.Errorf("%s: %s", record.ID(), err)
+		}
+		if rows[i] == nil {
+			rows[i] = map[string]json.RawMessage{}
+		}
+		if !opts.noID {
+			rows[i][idField], _ = json.Marshal(record.ID())
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if len(opts.out) > 0 {
+		f, err := os.Create(opts.out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	buf := bufio.NewWriter(out)
+	if err := writeRows(buf, opts.format, rows); err != nil {
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+
+	if len(opts.out) > 0 {
+		fmt.Printf("Exported %d records of %s to %s\n", len(rows), opts.dataset, opts.out)
+	}
+	return nil
+}
+
+//writeRows writes rows to w in format
+func writeRows(w io.Writer, format string, rows []map[string]json.RawMessage) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		for _, row := range rows {
+			if err := enc.Encode(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	//CSV has a column for every field of any record, the id first, and writes nested fields as JSON
+	seen := map[string]bool{}
+	var columns []string
+	for _, row := range rows {
+		for field := range row {
+			if !seen[field] && field != idField {
+				seen[field] = true
+				columns = append(columns, field)
+			}
+		}
+	}
+	sort.Strings(columns)
+	if len(rows) > 0 {
+		if _, ok := rows[0][idField]; ok {
+			columns = append([]string{idField}, columns...)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	line := make([]string, len(columns))
+	for _, row := range rows {
+		for i, column := range columns {
+			line[i] = csvValue(row[column])
+		}
+		if err := cw.Write(line); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+//csvValue returns the text of a field in a CSV cell: strings unquoted, nothing for null and JSON otherwise
+func csvValue(value json.RawMessage) string {
+	if len(value) == 0 || string(value) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(value, &s) == nil {
+		return s
+	}
+	return string(value)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+//fsckOptions are the flags of gitdb fsck
+type fsckOptions struct {
+	path string
+	fix  bool
+}
+
+//parseFsck parses the flags of gitdb fsck --path /data/db --fix
+func parseFsck(args []string) (*fsckOptions, error) {
+	opts := &fsckOptions{}
+	fsckCommand := flag.NewFlagSet("fsck", flag.ContinueOnError)
+	fsckCommand.StringVar(&opts.path, "path", ".", "path of the database i.e Config.DbPath")
+	fsckCommand.BoolVar(&opts.fix, "fix", false, "restore bad blocks to their last good commit and rebuild out of date indexes")
+	if err := fsckCommand.Parse(args); err != nil {
+		return nil, err
+	}
+	return opts, checkDatabase(opts.path)
+}
+
+//fsck runs gitdb fsck --path /data/db, writing the problems found to out. It returns an error if any
+//problems are left so CI jobs fail
+func fsck(args []string, out io.Writer) error {
+	opts, err := parseFsck(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	cfg := gitdb.NewConfig(opts.path)
+	cfg.EncryptionKey = os.Getenv("GITDB_ENCRYPTION_KEY")
+	cfg.IntegrityKey = os.Getenv("GITDB_INTEGRITY_KEY")
+	db, err := openDatabase(cfg, opts.fix)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report, err := db.Fsck(opts.fix)
+	if err != nil {
+		return err
+	}
+	for _, p := range report.Problems {
+		fmt.Fprintln(out, p)
+	}
+
+	unfixed := len(report.Unfixed())
+	fmt.Fprintf(out, "Checked %d datasets, %d blocks and %d records: %d problems, %d fixed\n",
+		report.Datasets, report.Blocks, report.Records, len(report.Problems), len(report.Problems)-unfixed)
+	if unfixed > 0 {
+		return fmt.Errorf("%d problems need fixing", unfixed)
+	}
+	return nil
+}
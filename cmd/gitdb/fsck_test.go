@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func Test_fsck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := gitdb.Open(gitdb.NewConfig(dir))
+	if err != nil {
+		t.Fatalf("gitdb.Open failed: %s", err)
+	}
+	db.Close()
+
+	bookings := filepath.Join(dir, "bookings.ndjson")
+	ioutil.WriteFile(bookings, []byte(`{"RoomId":"101","GuestName":"Ada"}`+"\n"), 0644)
+	if err := importData([]string{"Booking", "--path", dir, "--in", bookings, "--key", "RoomId", "--index", "GuestName"}); err != nil {
+		t.Fatalf("importData() failed: %s", err)
+	}
+
+	var out bytes.Buffer
+	if err := fsck([]string{"--path", dir}, &out); err != nil || !strings.Contains(out.String(), "Checked 1 datasets, 1 blocks and 1 records: 0 problems") {
+		t.Errorf("want: no problems, got: %v %s", err, out.String())
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "data", "Booking", "b0.json"), []byte("{"), 0744)
+	out.Reset()
+	if err := fsck([]string{"--path", dir}, &out); err == nil || !strings.Contains(out.String(), "Booking: bad block Booking/b0") {
+		t.Errorf("want: error for a bad block, got: %v %s", err, out.String())
+	}
+
+	out.Reset()
+	if err := fsck([]string{"--path", dir, "--fix"}, &out); err != nil || !strings.Contains(out.String(), "(fixed)") {
+		t.Errorf("want: bad block fixed, got: %v %s", err, out.String())
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+//modelField is a field of a generated model
+type modelField struct {
+	Name string
+	Type string
+	//Value is a Go literal of Type the generated test sets the field to
+	Value string
+	//Compare is how the generated test compares the field after reading it back: eq, time or empty to skip it
+	Compare string
+	Index   bool
+}
+
+type modelSpec struct {
+	Package string
+	Name    string
+	Dataset string
+	Fields  []modelField
+	Time    bool
+}
+
+//fieldTypes maps the short type names accepted by --fields to Go types, test values and comparisons
+var fieldTypes = map[string]modelField{
+	"string":  {Type: "string", Compare: "eq"},
+	"int":     {Type: "int", Value: "1", Compare: "eq"},
+	"int64":   {Type: "int64", Value: "1", Compare: "eq"},
+	"float":   {Type: "float64", Value: "1.5", Compare: "eq"},
+	"float64": {Type: "float64", Value: "1.5", Compare: "eq"},
+	"bool":    {Type: "bool", Value: "true", Compare: "eq"},
+	"time":    {Type: "time.Time", Value: "time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)", Compare: "time"},
+}
+
+//genModel runs gitdb gen model <Name> --fields "RoomId:string,CheckInDate:time" and writes the model
+//and its test to the output directory
+func genModel(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return errors.New(`usage: gitdb gen model <Name> --fields "RoomId:string,CheckInDate:time" [--index RoomId] [--package models] [-o dir]`)
+	}
+	name := args[0]
+
+	genCommand := flag.NewFlagSet("gen model", flag.ExitOnError)
+	fields := genCommand.String("fields", "", "fields of the model as Name:type separated by commas; types are string, int, int64, float, bool, time or any Go type")
+	index := genCommand.String("index", "", "fields to index separated by commas; default all fields of the short types")
+	pkg := genCommand.String("package", "", "package of the generated files; default the name of the output directory")
+	dir := genCommand.String("o", ".", "output directory")
+	genCommand.Parse(args[1:])
+
+	spec, err := newModelSpec(name, *fields, *index, *pkg, *dir)
+	if err != nil {
+		return err
+	}
+
+	base := filepath.Join(*dir, strings.ToLower(name))
+	for file, tmpl := range map[string]*template.Template{base + ".go": modelTmpl, base + "_test.go": modelTestTmpl} {
+		if _, err := os.Stat(file); err == nil {
+			return fmt.Errorf("%s already exists", file)
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, spec); err != nil {
+			return err
+		}
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(file, src, 0644); err != nil {
+			return err
+		}
+		fmt.Println(file)
+	}
+	return nil
+}
+
+func newModelSpec(name, fields, index, pkg, dir string) (*modelSpec, error) {
+	if !token.IsIdentifier(name) || !token.IsExported(name) {
+		return nil, fmt.Errorf("%s is not an exported Go name", name)
+	}
+
+	if pkg == "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		pkg = strings.ToLower(strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+				return r
+			}
+			return -1
+		}, filepath.Base(abs)))
+	}
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("%s is not a valid package name; use --package", pkg)
+	}
+
+	spec := &modelSpec{Package: pkg, Name: name, Dataset: name}
+	seen := map[string]bool{"ID": true}
+	for _, f := range strings.Split(fields, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+
+		parts := strings.SplitN(f, ":", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("field %s has no type; write it as Name:type", f)
+		}
+		fieldName, typ := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if !token.IsIdentifier(fieldName) || !token.IsExported(fieldName) {
+			return nil, fmt.Errorf("%s is not an exported Go name", fieldName)
+		}
+		if seen[fieldName] {
+			return nil, fmt.Errorf("field %s is declared twice or clashes with the ID field", fieldName)
+		}
+		seen[fieldName] = true
+
+		field, ok := fieldTypes[typ]
+		if ok {
+			//fields of the short types are indexed unless --index says otherwise
+			field.Index = index == ""
+		} else {
+			field = modelField{Type: typ}
+		}
+		field.Name = fieldName
+		if field.Type == "string" {
+			field.Value = fmt.Sprintf("%q", fieldName)
+		}
+		spec.Time = spec.Time || field.Type == "time.Time"
+		spec.Fields = append(spec.Fields, field)
+	}
+	if len(spec.Fields) == 0 {
+		return nil, errors.New("--fields is required")
+	}
+
+	for _, i := range strings.Split(index, ",") {
+		i = strings.TrimSpace(i)
+		if i == "" {
+			continue
+		}
+		found := false
+		for n := range spec.Fields {
+			if spec.Fields[n].Name == i {
+				spec.Fields[n].Index = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("index %s is not a field of %s", i, name)
+		}
+	}
+	return spec, nil
+}
+
+var modelTmpl = template.Must(template.New("model").Parse(`package {{.Package}}
+
+import (
+	{{if .Time}}"time"
+{{end}}
+	"github.com/gogitdb/gitdb/v2"
+)
+
+//{{.Name}} is a record of the {{.Dataset}} dataset
+type {{.Name}} struct {
+	gitdb.TimeStampedModel
+	ID string
+	{{range .Fields}}{{.Name}} {{.Type}}
+	{{end}}
+}
+
+//GetSchema stores {{.Name}} in a block per month under a ULID generated on insert
+func (m *{{.Name}}) GetSchema() *gitdb.Schema {
+	indexes := map[string]interface{}{
+		{{range .Fields}}{{if .Index}}"{{.Name}}": m.{{.Name}},
+		{{end}}{{end}}
+	}
+	return gitdb.NewSchema("{{.Dataset}}", m.CreatedAt.Format("200601"), m.ID, indexes).AutoID("ID", gitdb.AutoULID())
+}
+
+//Validate checks {{.Name}} before it is inserted
+func (m *{{.Name}}) Validate() error            { return nil }
+func (m *{{.Name}}) IsLockable() bool           { return false }
+func (m *{{.Name}}) ShouldEncrypt() bool        { return false }
+func (m *{{.Name}}) GetLockFileNames() []string { return []string{} }
+`))
+
+var modelTestTmpl = template.Must(template.New("modeltest").Parse(`package {{.Package}}
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	{{if .Time}}"time"
+{{end}}
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func Test{{.Name}}(t *testing.T) {
+	dbPath, err := ioutil.TempDir("", "{{.Dataset}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dbPath)
+
+	db, err := gitdb.Open(gitdb.NewConfig(dbPath))
+	if err != nil {
+		t.Fatalf("gitdb.Open failed: %s", err)
+	}
+	defer db.Close()
+
+	m := &{{.Name}}{
+		{{range .Fields}}{{if .Value}}{{.Name}}: {{.Value}},
+		{{end}}{{end}}
+	}
+	if err := db.Insert(m); err != nil {
+		t.Fatalf("db.Insert failed: %s", err)
+	}
+	if m.ID == "" {
+		t.Fatal("want: an ID generated on insert")
+	}
+
+	got := &{{.Name}}{}
+	if err := db.Get(gitdb.ID(m), got); err != nil {
+		t.Fatalf("db.Get failed: %s", err)
+	}
+	{{range .Fields}}{{if eq .Compare "eq"}}if got.{{.Name}} != m.{{.Name}} {
+		t.Errorf("{{.Name}} want: %v, got: %v", m.{{.Name}}, got.{{.Name}})
+	}
+	{{else if eq .Compare "time"}}if !got.{{.Name}}.Equal(m.{{.Name}}) {
+		t.Errorf("{{.Name}} want: %v, got: %v", m.{{.Name}}, got.{{.Name}})
+	}
+	{{end}}{{end -}}
+}
+`))
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_genModel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	args := []string{"Booking", "--fields", "RoomId:string,CheckInDate:time,Guests:int,Tags:[]string", "--index", "RoomId,CheckInDate", "--package", "models", "-o", dir}
+	if err := genModel(args); err != nil {
+		t.Fatalf("genModel() failed: %s", err)
+	}
+
+	for _, file := range []string{"booking.go", "booking_test.go"} {
+		if _, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, file), nil, 0); err != nil {
+			t.Errorf("%s is not valid Go: %s", file, err)
+		}
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "booking.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := string(b)
+	for _, want := range []string{"package models", "CheckInDate time.Time", "Tags        []string", `"RoomId":      m.RoomId`, `"CheckInDate": m.CheckInDate`, `AutoID("ID", gitdb.AutoULID())`} {
+		if !strings.Contains(src, want) {
+			t.Errorf("booking.go should contain %s", want)
+		}
+	}
+	if strings.Contains(src, `"Guests"`) {
+		t.Error("Guests should not be indexed")
+	}
+
+	//generated files are never overwritten
+	if err := genModel(args); err == nil {
+		t.Error("genModel() should fail when the files exist")
+	}
+}
+
+func Test_genModelInvalid(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"booking", "--fields", "RoomId:string"},
+		{"Booking"},
+		{"Booking", "--fields", "RoomId"},
+		{"Booking", "--fields", "roomId:string"},
+		{"Booking", "--fields", "RoomId:string,RoomId:int"},
+		{"Booking", "--fields", "RoomId:string", "--index", "Guests"},
+	}
+	for _, args := range tests {
+		if err := genModel(append(args, "-o", os.TempDir())); err == nil {
+			t.Errorf("genModel(%v) should fail", args)
+		}
+	}
+}
@@ -1,43 +1,140 @@
 package main
 
 import (
-	"bytes"
-	"encoding/base64"
-	"flag"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"path"
 	"path/filepath"
-	"runtime"
-	"strings"
-	"time"
-)
-
-var (
-	packageRoot = "../../"
-
-	embedCommand = flag.NewFlagSet("embed", flag.ExitOnError)
-	output       = embedCommand.String("o", "./ui_static.go", "output file name; default ./ui_static.go")
 
-	// dbpath      = flag.String("p", "", "path do gitdb")
+	"github.com/gogitdb/gitdb/v2"
 )
 
 func main() {
 
 	command := os.Args[1]
 	switch command {
-	case "embed-ui":
-		embedCommand.Parse(os.Args[2:])
-		err := embedUI()
+	case "merge-blocks":
+		//invoked by git as a merge driver: gitdb merge-blocks %P %O %A %B
+		if len(os.Args) != 6 {
+			fmt.Println("usage: gitdb merge-blocks <path> <base> <ours> <theirs>")
+			os.Exit(1)
+		}
+
+		err := gitdb.MergeBlocks(os.Args[2], os.Args[3], os.Args[4], os.Args[5], os.Getenv("GITDB_ENCRYPTION_KEY"))
 		if err != nil {
 			fmt.Println(err.Error())
+			//a non-zero exit code tells git the merge failed
+			os.Exit(1)
+		}
+	case "upgrade-format":
+		//rewrites records in the v1 layout: gitdb upgrade-format <db path>
+		if len(os.Args) != 3 {
+			fmt.Println("usage: gitdb upgrade-format <db path>")
+			os.Exit(1)
+		}
+
+		if err := upgradeFormat(os.Args[2], os.Getenv("GITDB_ENCRYPTION_KEY")); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	case "gen":
+		//generates the boilerplate of a new model: gitdb gen model Booking --fields "RoomId:string,CheckInDate:time"
+		if len(os.Args) < 3 || os.Args[2] != "model" {
+			fmt.Println("usage: gitdb gen model <Name> --fields <Name:type,...>")
+			os.Exit(1)
+		}
+
+		if err := genModel(os.Args[3:]); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	case "serve":
+		//serves the web UI and REST API of a database: gitdb serve --path /data/db --port 4120
+		if err := serve(os.Args[2:]); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	case "export":
+		//writes the records of a dataset to a file: gitdb export Booking --format ndjson --out bookings.ndjson
+		if err := export(os.Args[2:]); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	case "import":
+		//reads records into a dataset from a file: gitdb import Booking --in bookings.csv --key Id
+		if err := importData(os.Args[2:]); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	case "fsck":
+		//checks the blocks, records and indexes of a database: gitdb fsck --path /data/db [--fix]
+		if err := fsck(os.Args[2:], os.Stdout); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	case "migrate":
+		//runs data migrations on every record that is behind: gitdb migrate --path /data/db --dir migrations --to v3
+		if err := migrate(os.Args[2:], os.Stdout); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	case "stats":
+		//prints statistics of the datasets and repository of a database: gitdb stats --path /data/db [--json]
+		if err := stats(os.Args[2:], os.Stdout); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	case "get":
+		//prints the data of a record: gitdb get Booking b1234
+		if err := getRecord(os.Args[2:], os.Stdout); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	case "put":
+		//writes the data of a record read from stdin: gitdb put Booking b1234 < record.json
+		if err := putRecord(os.Args[2:], os.Stdin, os.Stdout); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	case "delete":
+		//deletes a record: gitdb delete Booking b1234
+		if err := deleteRecord(os.Args[2:], os.Stdout); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	case "query":
+		//prints the records matching a filter: gitdb query Booking --where 'RoomId=="r1" && Status!="cancelled"'
+		if err := query(os.Args[2:], os.Stdout); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	case "history":
+		//prints the versions of a record: gitdb history Booking/b1234
+		if err := history(os.Args[2:], os.Stdout); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	case "diff":
+		//prints the records that changed between commits: gitdb diff HEAD~5 HEAD --dataset Booking
+		if err := diffCommits(os.Args[2:], os.Stdout); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	case "compact":
+		//removes empty blocks and rewrites blocks edited by hand: gitdb compact Booking --dry-run
+		if err := compact(os.Args[2:], os.Stdout); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	case "gc":
+		//garbage collects and repacks the repository: gitdb gc --dry-run
+		if err := gc(os.Args[2:], os.Stdout); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
 		}
 	default:
-		fmt.Println("invalid command; try gitdb embed-ui")
+		fmt.Println("invalid command; try gitdb merge-blocks, upgrade-format, gen, serve, export, import, fsck, migrate, stats, get, put, delete, query, history, diff, compact or gc")
 		//future commands
-		//clean-db i.e git gc
-		//repair
 		//dataset
 		//dataset <name> blocks
 		//dataset <name> records
@@ -45,71 +142,35 @@ func main() {
 	}
 }
 
-type staticFile struct {
-	Name    string
-	Content string
-}
-
-func embedUI() error {
-	_, filename, _, ok := runtime.Caller(0)
-	if ok {
-		packageRoot = path.Dir(path.Dir(path.Dir(filename))) + "/"
-	}
-
-	var files []staticFile
-	if err := readAllStaticFiles(filepath.Join(packageRoot, "static"), &files); err != nil {
-		return err
-	}
-
-	_, err := os.Stat(path.Dir(*output))
+func upgradeFormat(dbPath string, encryptionKey string) error {
+	cfg := gitdb.NewConfig(dbPath)
+	cfg.EncryptionKey = encryptionKey
+	db, err := gitdb.Open(cfg)
 	if err != nil {
 		return err
 	}
+	defer db.Close()
 
-	w, err := os.Create(*output)
-	if err != nil {
-		fmt.Println(err)
-		return err
-	}
-
-	return packageTmpl.Execute(w, struct {
-		Files []staticFile
-		Date  string
-	}{
-		Files: files,
-		Date:  time.Now().Format(time.RFC1123),
-	})
+	return db.UpgradeFormat()
 }
 
-func readAllStaticFiles(path string, files *[]staticFile) error {
-
-	dirs, err := ioutil.ReadDir(path)
-	if err != nil {
-		return err
+//checkDatabase returns an error if there is no gitdb database at dbPath
+func checkDatabase(dbPath string) error {
+	if _, err := os.Stat(filepath.Join(dbPath, "data", ".git")); err != nil {
+		return fmt.Errorf("%s is not a gitdb database", dbPath)
 	}
+	return nil
+}
 
-	for _, dir := range dirs {
-		fileName := filepath.Join(path, dir.Name())
-		fmt.Println(fileName)
-		if !dir.IsDir() {
-			b, err := ioutil.ReadFile(fileName)
-			if err != nil {
-				return err
-			}
-
-			b = bytes.Replace(b, []byte("  "), []byte(""), -1)
-			b = bytes.Replace(b, []byte("\n"), []byte(""), -1)
-
-			content := base64.StdEncoding.EncodeToString(b)
-
-			fileKey := strings.Replace(fileName, packageRoot, "", 1)
-			*files = append(*files, staticFile{fileKey, content})
-		}
-
-		if !strings.HasPrefix(dir.Name(), ".") && dir.IsDir() {
-			readAllStaticFiles(fileName, files)
-		}
+//openDatabase opens the database of cfg, read-only unless writable
+func openDatabase(cfg *gitdb.Config, writable bool) (gitdb.GitDb, error) {
+	open := gitdb.OpenReadOnly
+	if writable {
+		open = gitdb.Open
 	}
-
-	return nil
+	db, err := open(cfg)
+	if errors.Is(err, gitdb.ErrDatabaseLocked) {
+		return nil, fmt.Errorf("%s is open in another process; use a clone of it instead", cfg.DbPath)
+	}
+	return db, err
 }
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+//historyOptions are the arguments and flags of gitdb history
+type historyOptions struct {
+	recordOptions
+	limit int
+}
+
+//parseHistory parses the arguments and flags of gitdb history Booking/b1234 --limit 5
+func parseHistory(args []string) (*historyOptions, error) {
+	opts := &historyOptions{}
+	historyCommand := flag.NewFlagSet("history", flag.ContinueOnError)
+	historyCommand.StringVar(&opts.path, "path", ".", "path of the database i.e Config.DbPath")
+	historyCommand.IntVar(&opts.limit, "limit", 0, "most versions to print, latest first; 0 prints every version")
+	id, err := parseArgs(historyCommand, args)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.SplitN(id, "/", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return nil, errors.New("usage: gitdb history <dataset>/<key or block/key> [--path .] [--limit n]")
+	}
+	if opts.limit < 0 {
+		return nil, fmt.Errorf("--limit can't be negative, got: %d", opts.limit)
+	}
+	opts.dataset, opts.key = parts[0], parts[1]
+	return opts, checkDatabase(opts.path)
+}
+
+//history runs gitdb history Booking/b1234, writing each version of a record to out, latest first, as a
+//diff of its JSON with the version before it
+func history(args []string, out io.Writer) error {
+	opts, err := parseHistory(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	conn, err := opts.open(false)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	//deleted records are only found by their full id
+	id := opts.dataset + "/" + opts.key
+	record, err := opts.find(conn)
+	if err == nil {
+		id = record.ID()
+	} else if !strings.Contains(opts.key, "/") {
+		return err
+	}
+
+	changes, err := conn.History(id)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return fmt.Errorf("Record %s has no history", id)
+	}
+	shown := len(changes)
+	if opts.limit > 0 && shown > opts.limit {
+		shown = opts.limit
+	}
+
+	for i, change := range changes[:shown] {
+		//the version before the oldest change is the record at the start of the history, which is none
+		before := ""
+		if i+1 < len(changes) {
+			before = changes[i+1].Commit
+		}
+		d, err := recordDiff(conn, id, before, change.Commit)
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "commit %s %s by %s at %s\n", change.Commit, change.Operation, change.Author, change.Time.Format("2006-01-02 15:04:05 -0700"))
+		if len(change.Message) > 0 {
+			fmt.Fprintf(out, "  %s\n", change.Message)
+		}
+		if d == nil {
+			fmt.Fprintln(out, "  (no change to the data of the record)")
+			continue
+		}
+		writeJSONDiff(out, d.Before, d.After)
+	}
+	return nil
+}
+
+//recordDiff returns the change made to record id between commits from and to, or nil if it didn't change
+func recordDiff(conn gitdb.GitDb, id string, from string, to string) (*gitdb.RecordDiff, error) {
+	dataset, _, _, err := gitdb.ParseID(id)
+	if err != nil {
+		return nil, err
+	}
+	diffs, err := conn.Diff(dataset, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range diffs {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+	return nil, nil
+}
+
+//diffOptions are the arguments and flags of gitdb diff
+type diffOptions struct {
+	path    string
+	dataset string
+	from    string
+	to      string
+}
+
+//parseDiff parses the arguments and flags of gitdb diff HEAD~5 HEAD --dataset Booking
+func parseDiff(args []string) (*diffOptions, error) {
+	opts := &diffOptions{}
+	diffCommand := flag.NewFlagSet("diff", flag.ContinueOnError)
+	diffCommand.StringVar(&opts.path, "path", ".", "path of the database i.e Config.DbPath")
+	diffCommand.StringVar(&opts.dataset, "dataset", "", "dataset to diff; defaults to every dataset")
+	args, err := parsePositional(diffCommand, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.New("usage: gitdb diff <from> [to] [--path .] [--dataset name]; from and to are commits, branches or tags and to defaults to HEAD")
+	}
+	opts.from, opts.to = args[0], "HEAD"
+	if len(args) == 2 {
+		opts.to = args[1]
+	}
+	return opts, checkDatabase(opts.path)
+}
+
+//diffCommits runs gitdb diff HEAD~5 HEAD --dataset Booking, writing each record that was inserted, updated
+//or deleted between two commits to out as a diff of its JSON
+func diffCommits(args []string, out io.Writer) error {
+	opts, err := parseDiff(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	cfg := gitdb.NewConfig(opts.path)
+	cfg.EncryptionKey = os.Getenv("GITDB_ENCRYPTION_KEY")
+	conn, err := openDatabase(cfg, false)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	//Diff limits the files it compares to the path of the dataset so . compares every dataset
+	dataset := opts.dataset
+	if len(dataset) == 0 {
+		dataset = "."
+	}
+	diffs, err := conn.Diff(dataset, opts.from, opts.to)
+	if err != nil {
+		return fmt.Errorf("failed to diff %s and %s: %s", opts.from, opts.to, strings.TrimSpace(err.Error()))
+	}
+
+	for _, d := range diffs {
+		fmt.Fprintf(out, "%s %s\n", d.Op, d.ID)
+		writeJSONDiff(out, d.Before, d.After)
+		fmt.Fprintln(out)
+	}
+	fmt.Fprintf(out, "%d records changed between %s and %s\n", len(diffs), opts.from, opts.to)
+	return nil
+}
+
+//writeJSONDiff writes the lines of the indented JSON of the data of before and after to out, prefixing lines that were
+//removed with - and lines that were added with +
+func writeJSONDiff(out io.Writer, before string, after string) {
+	a, b := jsonLines(before), jsonLines(after)
+
+	//lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(out, "  %s\n", a[i])
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(out, "- %s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(out, "+ %s\n", b[j])
+			j++
+		}
+	}
+}
+
+//jsonLines returns the lines of the data of a record as JSON from RecordDiff indented with two spaces, or of
+//the record as it is if it isn't JSON
+func jsonLines(data string) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	var record struct {
+		Data json.RawMessage
+	}
+	if json.Unmarshal([]byte(data), &record) == nil && len(record.Data) > 0 {
+		data = string(record.Data)
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(data), "", "  "); err == nil {
+		data = buf.String()
+	}
+	return strings.Split(data, "\n")
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func Test_historyAndDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := gitdb.Open(gitdb.NewConfig(dir))
+	if err != nil {
+		t.Fatalf("gitdb.Open failed: %s", err)
+	}
+	db.Close()
+
+	bookings := filepath.Join(dir, "bookings.ndjson")
+	ioutil.WriteFile(bookings, []byte(`{"Ref":"b1","Status":"paid"}`+"\n"+`{"Ref":"b2","Status":"paid"}`+"\n"), 0644)
+	if err := importData([]string{"Booking", "--path", dir, "--in", bookings, "--key", "Ref"}); err != nil {
+		t.Fatalf("importData() failed: %s", err)
+	}
+	var out bytes.Buffer
+	if err := putRecord([]string{"Booking", "b1", "--path", dir}, strings.NewReader(`{"Ref":"b1","Status":"cancelled"}`), &out); err != nil {
+		t.Fatalf("putRecord() failed: %s", err)
+	}
+	if err := deleteRecord([]string{"Booking", "b2", "--path", dir}, &out); err != nil {
+		t.Fatalf("deleteRecord() failed: %s", err)
+	}
+
+	out.Reset()
+	if err := history([]string{"Booking/b1", "--path", dir}, &out); err != nil {
+		t.Fatalf("history() failed: %s", err)
+	}
+	if got := out.String(); strings.Count(got, "commit ") != 2 || !strings.Contains(got, `-   "Status": "paid"`) || !strings.Contains(got, `+   "Status": "cancelled"`) {
+		t.Errorf("want: 2 versions of Booking/b0/b1 with Status changed, got: %s", got)
+	}
+
+	out.Reset()
+	if err := history([]string{"Booking/b0/b2", "--path", dir, "--limit", "1"}, &out); err != nil {
+		t.Fatalf("history() failed: %s", err)
+	}
+	if got := out.String(); strings.Count(got, "commit ") != 1 || !strings.Contains(got, `-   "Ref": "b2"`) {
+		t.Errorf("want: the delete of Booking/b0/b2, got: %s", got)
+	}
+
+	out.Reset()
+	if err := diffCommits([]string{"HEAD~2", "HEAD", "--path", dir, "--dataset", "Booking"}, &out); err != nil {
+		t.Fatalf("diffCommits() failed: %s", err)
+	}
+	got := out.String()
+	if !strings.Contains(got, "update Booking/b0/b1\n") || !strings.Contains(got, "delete Booking/b0/b2\n") || !strings.HasSuffix(got, "2 records changed between HEAD~2 and HEAD\n") {
+		t.Errorf("want: b1 updated and b2 deleted, got: %s", got)
+	}
+
+	out.Reset()
+	if err := diffCommits([]string{"HEAD~1", "--path", dir}, &out); err != nil {
+		t.Fatalf("diffCommits() failed: %s", err)
+	}
+	if got := out.String(); !strings.Contains(got, "delete Booking/b0/b2\n") || !strings.HasSuffix(got, "1 records changed between HEAD~1 and HEAD\n") {
+		t.Errorf("want: b2 deleted, got: %s", got)
+	}
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+//maxImportErrors is how many invalid records gitdb import lists before giving up
+const maxImportErrors = 20
+
+//importTypes are the types --types converts the text of fields to
+var importTypes = map[string]bool{"string": true, "int": true, "float": true, "bool": true, "time": true, "json": true}
+
+//importOptions are the flags of gitdb import
+type importOptions struct {
+	path    string
+	dataset string
+	format  string
+	in      string
+	key     string
+	block   string
+	indexes []string
+	//fields maps the columns or fields of the file to the fields of the records they are imported as
+	fields   map[string]string
+	types    map[string]string
+	required []string
+	batch    int
+	encrypt  bool
+	dryRun   bool
+	//allowSecrets imports records matching gitdb.DefaultSecretPatterns, only warning about them
+	allowSecrets bool
+}
+
+//parseImport parses the flags of gitdb import Booking --in bookings.csv --key Id --map "Room No:RoomId"
+func parseImport(args []string) (*importOptions, error) {
+	opts := &importOptions{}
+	var indexes, fields, types, required string
+	importCommand := flag.NewFlagSet("import", flag.ContinueOnError)
+	importCommand.StringVar(&opts.path, "path", ".", "path of the database i.e Config.DbPath")
+	importCommand.StringVar(&opts.format, "format", "", "csv, json or ndjson; defaults to the extension of --in")
+	importCommand.StringVar(&opts.in, "in", "", "file to read the records from; defaults to stdin")
+	importCommand.StringVar(&opts.key, "key", "", "field the key of each record is read from when it has no "+idField+" field")
+	importCommand.StringVar(&opts.block, "block", "b0", "block records without an "+idField+" field go in; {Field} is replaced by the value of Field")
+	importCommand.StringVar(&indexes, "index", "", "fields to index e.g RoomId,CheckInDate; defaults to the indexes the dataset has")
+	importCommand.StringVar(&fields, "map", "", "columns or fields of the file to import as other fields e.g \"Room No:RoomId,Guest:GuestName\"")
+	importCommand.StringVar(&types, "types", "", "types to convert the text of fields to e.g Nights:int,Paid:bool,CheckInDate:time; types are int, float, bool, time and json")
+	importCommand.StringVar(&required, "require", "", "fields every record must have e.g RoomId,GuestName")
+	importCommand.IntVar(&opts.batch, "batch", 500, "number of records committed at a time")
+	importCommand.BoolVar(&opts.encrypt, "encrypt", false, "encrypt the records with GITDB_ENCRYPTION_KEY")
+	importCommand.BoolVar(&opts.dryRun, "dry-run", false, "only validate the records")
+	importCommand.BoolVar(&opts.allowSecrets, "allow-secrets", false, "import records that look like they contain API keys, private keys or card numbers, only warning about them")
+	dataset, err := parseArgs(importCommand, args)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.dataset = dataset
+	if len(opts.dataset) == 0 {
+		return nil, errors.New("usage: gitdb import <dataset> [--path .] [--format csv|json|ndjson] [--in file] [--key Field] [--block b0] [--index a,b] [--map Column:Field,...] [--types Field:type,...] [--require a,b] [--batch 500] [--encrypt] [--allow-secrets] [--dry-run]")
+	}
+	if len(opts.in) == 0 && len(opts.format) == 0 {
+		return nil, errors.New("--format is required when reading from stdin")
+	}
+	if opts.format, err = transferFormat(opts.format, opts.in); err != nil {
+		return nil, err
+	}
+	if opts.batch < 1 {
+		return nil, errors.New("--batch must be at least 1")
+	}
+	if opts.fields, err = fieldPairs("--map", fields); err != nil {
+		return nil, err
+	}
+	if opts.types, err = fieldPairs("--types", types); err != nil {
+		return nil, err
+	}
+	for field, t := range opts.types {
+		if !importTypes[t] {
+			return nil, fmt.Errorf("unknown type %s of %s; use string, int, float, bool, time or json", t, field)
+		}
+	}
+	opts.indexes = fieldList(indexes)
+	opts.required = fieldList(required)
+	return opts, checkDatabase(opts.path)
+}
+
+//fieldList splits a comma separated list of fields
+func fieldList(value string) []string {
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); len(field) > 0 {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+//fieldPairs parses the From:To pairs of flag
+func fieldPairs(flag string, value string) (map[string]string, error) {
+	pairs := map[string]string{}
+	for _, pair := range fieldList(value) {
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 || len(strings.TrimSpace(parts[0])) == 0 || len(strings.TrimSpace(parts[1])) == 0 {
+			return nil, fmt.Errorf("%s takes a list of From:To pairs, got: %s", flag, pair)
+		}
+		pairs[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return pairs, nil
+}
+
+//importRecord is a record read by gitdb import. It is stored as its fields
+type importRecord struct {
+	schema  *gitdb.Schema
+	encrypt bool
+	fields  map[string]interface{}
+}
+
+func (r *importRecord) GetSchema() *gitdb.Schema {
+	return r.schema
+}
+
+func (r *importRecord) Validate() error {
+	return nil
+}
+
+func (r *importRecord) IsLockable() bool {
+	return false
+}
+
+func (r *importRecord) GetLockFileNames() []string {
+	return nil
+}
+
+func (r *importRecord) ShouldEncrypt() bool {
+	return r.encrypt
+}
+
+func (r *importRecord) BeforeInsert() error {
+	return nil
+}
+
+//MarshalJSON implements json.Marshaler
+func (r *importRecord) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.fields)
+}
+
+//importData runs gitdb import Booking --in bookings.csv --key Id, validating every record of a file before
+//inserting them into a dataset, or updating the records with their ids, --batch records to a commit
+func importData(args []string) error {
+	opts, err := parseImport(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	if len(opts.in) > 0 {
+		f, err := os.Open(opts.in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	rows, err := readRows(in, opts.format)
+	if err != nil {
+		return err
+	}
+
+	if len(opts.indexes) == 0 {
+		opts.indexes = datasetIndexes(opts.path, opts.dataset)
+	}
+	cfg := gitdb.NewConfig(opts.path)
+	cfg.EncryptionKey = os.Getenv("GITDB_ENCRYPTION_KEY")
+	cfg.SecretPatterns = gitdb.DefaultSecretPatterns
+	cfg.WarnOnSecrets = opts.allowSecrets
+	if opts.encrypt && len(cfg.EncryptionKey) == 0 {
+		return errors.New("--encrypt requires GITDB_ENCRYPTION_KEY")
+	}
+
+	records, err := opts.records(rows)
+	if err != nil {
+		return err
+	}
+	if opts.dryRun {
+		fmt.Printf("%d records of %s are valid\n", len(records), opts.dataset)
+		return nil
+	}
+
+	db, err := openDatabase(cfg, true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for start := 0; start < len(records); start += opts.batch {
+		end := start + opts.batch
+		if end > len(records) {
+			end = len(records)
+		}
+		if err := db.InsertMany(records[start:end]); err != nil {
+			return fmt.Errorf("import stopped after %d of %d records: %s", start, len(records), err)
+		}
+	}
+	fmt.Printf("Imported %d records into %s\n", len(records), opts.dataset)
+	return nil
+}
+
+//datasetIndexes returns the names of the indexes dataset has, leaving out the id index every dataset has
+func datasetIndexes(dbPath string, dataset string) []string {
+	files, _ := filepath.Glob(filepath.Join(dbPath, ".gitdb", "index", dataset, "*.json"))
+	var indexes []string
+	for _, file := range files {
+		if name := strings.TrimSuffix(filepath.Base(file), ".json"); name != "id" {
+			indexes = append(indexes, name)
+		}
+	}
+	return indexes
+}
+
+//readRows reads the records of a file in format. Numbers are kept as they are written
+func readRows(in io.Reader, format string) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	switch format {
+	case "json":
+		dec := json.NewDecoder(in)
+		dec.UseNumber()
+		if err := dec.Decode(&rows); err != nil {
+			return nil, fmt.Errorf("file is not a JSON array of objects: %s", err)
+		}
+	case "ndjson":
+		data, err := ioutil.ReadAll(in)
+		if err != nil {
+			return nil, err
+		}
+		for i, line := range bytes.Split(data, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			var row map[string]interface{}
+			dec := json.NewDecoder(bytes.NewReader(line))
+			dec.UseNumber()
+			if err := dec.Decode(&row); err != nil || row == nil {
+				return nil, fmt.Errorf("line %d is not a JSON object", i+1)
+			}
+			rows = append(rows, row)
+		}
+	case "csv":
+		lines, err := csv.NewReader(in).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		if len(lines) == 0 {
+			return nil, errors.New("file has no header row")
+		}
+		for _, line := range lines[1:] {
+			//empty cells are left out so the fields keep their zero values
+			row := map[string]interface{}{}
+			for i, column := range lines[0] {
+				if i < len(line) && len(line[i]) > 0 {
+					row[column] = line[i]
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+//records maps, converts and validates rows, returning them as records of the dataset or every problem found
+func (opts *importOptions) records(rows []map[string]interface{}) ([]gitdb.Model, error) {
+	var records []gitdb.Model
+	var problems []string
+	ids := map[string]int{}
+	for i, row := range rows {
+		record, err := opts.record(row)
+		if err == nil {
+			id := gitdb.ID(record)
+			if first, ok := ids[id]; ok {
+				err = fmt.Errorf("%s is also the id of record %d", id, first)
+			}
+			ids[id] = i + 1
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("record %d: %s", i+1, err))
+			continue
+		}
+		records = append(records, record)
+	}
+
+	if len(problems) > 0 {
+		more := ""
+		if len(problems) > maxImportErrors {
+			more = fmt.Sprintf("\nand %d more", len(problems)-maxImportErrors)
+			problems = problems[:maxImportErrors]
+		}
+		return nil, fmt.Errorf("%d of %d records are invalid, nothing was imported:\n%s%s", len(problems), len(rows), strings.Join(problems, "\n"), more)
+	}
+	return records, nil
+}
+
+//record returns row as a record of the dataset
+func (opts *importOptions) record(row map[string]interface{}) (*importRecord, error) {
+	fields := map[string]interface{}{}
+	for name, value := range row {
+		if to, ok := opts.fields[name]; ok {
+			name = to
+		}
+		fields[name] = value
+	}
+	for name, t := range opts.types {
+		if text, ok := fields[name].(string); ok {
+			value, err := convertField(t, text)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", name, err)
+			}
+			fields[name] = value
+		}
+	}
+	for _, name := range opts.required {
+		if value, ok := fields[name]; !ok || value == nil || value == "" {
+			return nil, fmt.Errorf("%s is required", name)
+		}
+	}
+
+	block, key, err := opts.recordID(fields)
+	if err != nil {
+		return nil, err
+	}
+	indexes := map[string]interface{}{}
+	for _, name := range opts.indexes {
+		value, ok := fields[name]
+		if !ok || value == nil {
+			return nil, fmt.Errorf("index %s is missing", name)
+		}
+		indexes[name] = value
+	}
+
+	return &importRecord{
+		schema:  gitdb.NewSchema(opts.dataset, block, key, indexes),
+		encrypt: opts.encrypt,
+		fields:  fields,
+	}, nil
+}
+
+//recordID returns the block and key of a record from its id field, removing it, or from --block and --key
+func (opts *importOptions) recordID(fields map[string]interface{}) (string, string, error) {
+	if id, ok := fields[idField].(string); ok {
+		delete(fields, idField)
+		dataset, block, key, err := gitdb.ParseID(id)
+		if err != nil {
+			return "", "", err
+		}
+		if dataset != opts.dataset {
+			return "", "", fmt.Errorf("%s is not a record of %s", id, opts.dataset)
+		}
+		return block, key, nil
+	}
+
+	if len(opts.key) == 0 {
+		return "", "", errors.New("record has no " + idField + " field; give the field its key is in with --key")
+	}
+	key := fieldText(fields[opts.key])
+	if len(key) == 0 {
+		return "", "", fmt.Errorf("key %s is missing", opts.key)
+	}
+	block := opts.block
+	for name, value := range fields {
+		block = strings.Replace(block, "{"+name+"}", fieldText(value), -1)
+	}
+	if strings.ContainsAny(block, "{}") {
+		return "", "", fmt.Errorf("block %s is missing a field", block)
+	}
+	if strings.Contains(key, "/") || strings.Contains(block, "/") || len(block) == 0 {
+		return "", "", fmt.Errorf("%s/%s is not a valid block and key", block, key)
+	}
+	return block, key, nil
+}
+
+//fieldText returns the text of a field value used in a record id
+func fieldText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format("2006-01-02")
+	}
+	return fmt.Sprint(value)
+}
+
+//convertField converts text to type t of importTypes
+func convertField(t string, text string) (interface{}, error) {
+	switch t {
+	case "string":
+		return text, nil
+	case "int":
+		if _, err := strconv.ParseInt(text, 10, 64); err != nil {
+			return nil, fmt.Errorf("%q is not an int", text)
+		}
+		return json.Number(text), nil
+	case "float":
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return nil, fmt.Errorf("%q is not a float", text)
+		}
+		return json.Number(text), nil
+	case "bool":
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a bool", text)
+		}
+		return b, nil
+	case "time":
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+			if tm, err := time.Parse(layout, text); err == nil {
+				return tm, nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not a time such as 2006-01-02 or 2006-01-02T15:04:05Z", text)
+	case "json":
+		var value interface{}
+		dec := json.NewDecoder(strings.NewReader(text))
+		dec.UseNumber()
+		if err := dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("%q is not JSON", text)
+		}
+		return value, nil
+	}
+	return nil, fmt.Errorf("unknown type %s", t)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gogitdb/gitdb/v2"
+	"github.com/gogitdb/gitdb/v2/internal/digital"
+)
+
+//maintenanceOptions are the arguments and flags of gitdb compact and gc
+type maintenanceOptions struct {
+	path     string
+	datasets []string
+	dryRun   bool
+}
+
+//parseMaintenance parses the arguments and flags of gitdb compact Booking --dry-run and gitdb gc --dry-run
+func parseMaintenance(command string, args []string) (*maintenanceOptions, error) {
+	opts := &maintenanceOptions{}
+	maintenanceCommand := flag.NewFlagSet(command, flag.ContinueOnError)
+	maintenanceCommand.StringVar(&opts.path, "path", ".", "path of the database i.e Config.DbPath")
+	maintenanceCommand.BoolVar(&opts.dryRun, "dry-run", false, "print the space expected to be saved without changing anything")
+	datasets, err := parsePositional(maintenanceCommand, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if command == "gc" && len(datasets) > 0 {
+		return nil, errors.New("usage: gitdb gc [--path .] [--dry-run]")
+	}
+	if len(datasets) > 1 {
+		return nil, errors.New("usage: gitdb compact [dataset] [--path .] [--dry-run]")
+	}
+	opts.datasets = datasets
+	return opts, checkDatabase(opts.path)
+}
+
+//open opens the database of opts, read-only for dry runs
+func (opts *maintenanceOptions) open() (gitdb.GitDb, error) {
+	cfg := gitdb.NewConfig(opts.path)
+	cfg.EncryptionKey = os.Getenv("GITDB_ENCRYPTION_KEY")
+	return openDatabase(cfg, !opts.dryRun)
+}
+
+//compact runs gitdb compact Booking, removing the blocks of a dataset, or every dataset, left without
+//records and rewriting those not laid out the way GitDB writes them. The space expected to be saved is
+//written to out first
+func compact(args []string, out io.Writer) error {
+	opts, err := parseMaintenance("compact", args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	conn, err := opts.open()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	reports, err := conn.Compact(true, opts.datasets...)
+	if err != nil {
+		return err
+	}
+
+	changed := 0
+	var savings int64
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "DATASET\tBLOCKS\tEMPTY\tTO REWRITE\tSIZE\tEXPECTED SAVINGS")
+	for _, r := range reports {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%s\n", r.Dataset, r.Blocks, r.Removed, r.Rewritten, digital.FormatBytes(uint64(r.SizeBefore)), formatSaving(r.Reclaimed()))
+		changed += r.Removed + r.Rewritten
+		savings += r.Reclaimed()
+	}
+	w.Flush()
+
+	if changed == 0 {
+		fmt.Fprintln(out, "\nNothing to compact")
+		return nil
+	}
+	fmt.Fprintf(out, "\n%d blocks to compact, expected to save %s\n", changed, formatSaving(savings))
+	if opts.dryRun {
+		return nil
+	}
+
+	if reports, err = conn.Compact(false, opts.datasets...); err != nil {
+		return err
+	}
+	savings = 0
+	for _, r := range reports {
+		savings += r.Reclaimed()
+	}
+	fmt.Fprintf(out, "Compacted %d blocks, saved %s\n", changed, formatSaving(savings))
+	return nil
+}
+
+//gc runs gitdb gc, garbage collecting, repacking and pruning the repository of the database. The space
+//expected to be saved is written to out first
+func gc(args []string, out io.Writer) error {
+	opts, err := parseMaintenance("gc", args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	conn, err := opts.open()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	objects, err := conn.RepoObjects()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%d loose objects (%s), %d packs (%s), %d garbage files (%s)\n",
+		objects.Loose, digital.FormatBytes(uint64(objects.LooseSize)),
+		objects.Packs, digital.FormatBytes(uint64(objects.PackSize)),
+		objects.Garbage, digital.FormatBytes(uint64(objects.GarbageSize)))
+	fmt.Fprintf(out, "Expected to save up to %s\n", digital.FormatBytes(uint64(objects.Reclaimable())))
+	if opts.dryRun {
+		return nil
+	}
+
+	report, err := conn.Maintain()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Repository %s -> %s, saved %s in %s\n", digital.FormatBytes(uint64(report.SizeBefore)),
+		digital.FormatBytes(uint64(report.SizeAfter)), formatSaving(report.Reclaimed()), report.Duration.Round(time.Millisecond))
+	return nil
+}
+
+//formatSaving formats a number of bytes saved, which is negative when files grow e.g blocks edited by hand
+//without indentation
+func formatSaving(n int64) string {
+	if n < 0 {
+		return "-" + digital.FormatBytes(uint64(-n))
+	}
+	return digital.FormatBytes(uint64(n))
+}
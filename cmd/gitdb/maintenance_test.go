@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func Test_compactAndGC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "maintenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := gitdb.Open(gitdb.NewConfig(dir))
+	if err != nil {
+		t.Fatalf("gitdb.Open failed: %s", err)
+	}
+	db.Close()
+
+	bookings := filepath.Join(dir, "bookings.ndjson")
+	ioutil.WriteFile(bookings, []byte(`{"Ref":"b1","Month":"jan"}`+"\n"+`{"Ref":"b2","Month":"feb"}`+"\n"), 0644)
+	if err := importData([]string{"Booking", "--path", dir, "--in", bookings, "--key", "Ref", "--block", "{Month}"}); err != nil {
+		t.Fatalf("importData() failed: %s", err)
+	}
+	var out bytes.Buffer
+	if err := deleteRecord([]string{"Booking", "b1", "--path", dir}, &out); err != nil {
+		t.Fatalf("deleteRecord() failed: %s", err)
+	}
+
+	blockFile := filepath.Join(dir, "data", "Booking", "jan.json")
+	out.Reset()
+	if err := compact([]string{"Booking", "--path", dir, "--dry-run"}, &out); err != nil {
+		t.Fatalf("compact() failed: %s", err)
+	}
+	if fields := strings.Fields(strings.Split(out.String(), "\n")[1]); len(fields) < 4 || fields[0] != "Booking" || fields[1] != "2" || fields[2] != "1" || !strings.Contains(out.String(), "1 blocks to compact") {
+		t.Errorf("want: 1 of 2 blocks of Booking empty, got: %s", out.String())
+	}
+	if _, err := os.Stat(blockFile); err != nil {
+		t.Errorf("want: block kept by a dry run, got: %s", err)
+	}
+
+	out.Reset()
+	if err := compact([]string{"--path", dir}, &out); err != nil {
+		t.Fatalf("compact() failed: %s", err)
+	}
+	if _, err := os.Stat(blockFile); !os.IsNotExist(err) || !strings.Contains(out.String(), "Compacted 1 blocks") {
+		t.Errorf("want: empty block removed, got: %v %s", err, out.String())
+	}
+
+	out.Reset()
+	if err := gc([]string{"--path", dir, "--dry-run"}, &out); err != nil {
+		t.Fatalf("gc() failed: %s", err)
+	}
+	if !strings.Contains(out.String(), "Expected to save up to") || strings.Contains(out.String(), "Repository") {
+		t.Errorf("want: expected savings only, got: %s", out.String())
+	}
+
+	out.Reset()
+	if err := gc([]string{"--path", dir}, &out); err != nil {
+		t.Fatalf("gc() failed: %s", err)
+	}
+	if !strings.Contains(out.String(), "Repository ") {
+		t.Errorf("want: repository maintained, got: %s", out.String())
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"plugin"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+//migrateOptions are the flags of gitdb migrate
+type migrateOptions struct {
+	path   string
+	to     int
+	dir    string
+	plugin string
+	tag    string
+	dryRun bool
+}
+
+//parseMigrate parses the flags of gitdb migrate --path /data/db --dir migrations --to v3
+func parseMigrate(args []string) (*migrateOptions, error) {
+	opts := &migrateOptions{}
+	var to string
+	migrateCommand := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	migrateCommand.StringVar(&opts.path, "path", ".", "path of the database i.e Config.DbPath")
+	migrateCommand.StringVar(&to, "to", "", "version to migrate to e.g v3; defaults to the last version of each dataset")
+	migrateCommand.StringVar(&opts.dir, "dir", "", "directory of migrations as <dataset>/<version>.json files")
+	migrateCommand.StringVar(&opts.plugin, "plugin", "", "Go plugin exporting Migrations, a map[string][]gitdb.Migration")
+	migrateCommand.StringVar(&opts.tag, "tag", "", "release the database is tagged as before it is migrated; defaults to before-migrate-<time>")
+	migrateCommand.BoolVar(&opts.dryRun, "dry-run", false, "run the migrations without writing the records")
+	if err := migrateCommand.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if len(opts.dir) == 0 && len(opts.plugin) == 0 {
+		return nil, errors.New("usage: gitdb migrate [--path .] --dir <migrations dir> | --plugin <migrations.so> [--to v3] [--tag name] [--dry-run]")
+	}
+	if len(to) > 0 {
+		version, err := strconv.Atoi(strings.TrimPrefix(to, "v"))
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("--to takes a version such as v3, got: %s", to)
+		}
+		opts.to = version
+	}
+	return opts, checkDatabase(opts.path)
+}
+
+//migrations returns the migrations of the directory and plugin of opts up to --to
+func (opts *migrateOptions) migrations() (map[string][]gitdb.Migration, error) {
+	migrations := map[string][]gitdb.Migration{}
+	if len(opts.dir) > 0 {
+		dirMigrations, err := loadDirMigrations(opts.dir)
+		if err != nil {
+			return nil, err
+		}
+		for dataset, list := range dirMigrations {
+			migrations[dataset] = append(migrations[dataset], list...)
+		}
+	}
+	if len(opts.plugin) > 0 {
+		pluginMigrations, err := loadPluginMigrations(opts.plugin)
+		if err != nil {
+			return nil, err
+		}
+		for dataset, list := range pluginMigrations {
+			migrations[dataset] = append(migrations[dataset], list...)
+		}
+	}
+
+	for dataset, list := range migrations {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+		if opts.to > 0 {
+			n := sort.Search(len(list), func(i int) bool { return list[i].Version > opts.to })
+			list = list[:n]
+		}
+		if len(list) == 0 {
+			delete(migrations, dataset)
+			continue
+		}
+		migrations[dataset] = list
+	}
+	return migrations, nil
+}
+
+//loadPluginMigrations returns the Migrations exported by a Go plugin built against the same version of gitdb
+func loadPluginMigrations(path string) (map[string][]gitdb.Migration, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	symbol, err := p.Lookup("Migrations")
+	if err != nil {
+		return nil, err
+	}
+	migrations, ok := symbol.(*map[string][]gitdb.Migration)
+	if !ok {
+		return nil, fmt.Errorf("Migrations of %s must be a map[string][]gitdb.Migration", path)
+	}
+	return *migrations, nil
+}
+
+//fileMigration is a migration read from a <dataset>/<version>.json file of a migrations directory.
+//Its steps are applied in the order of its fields
+type fileMigration struct {
+	//Rename renames fields, from old name to new name
+	Rename map[string]string `json:"rename"`
+	//Set sets fields to values
+	Set map[string]interface{} `json:"set"`
+	//Default sets fields that are missing or null to values
+	Default map[string]interface{} `json:"default"`
+	//Remove removes fields
+	Remove []string `json:"remove"`
+}
+
+//up applies the steps of m to the data of a record
+func (m *fileMigration) up(old map[string]interface{}) (map[string]interface{}, error) {
+	for from, to := range m.Rename {
+		if value, ok := old[from]; ok {
+			old[to] = value
+			delete(old, from)
+		}
+	}
+	for field, value := range m.Set {
+		old[field] = value
+	}
+	for field, value := range m.Default {
+		if old[field] == nil {
+			old[field] = value
+		}
+	}
+	for _, field := range m.Remove {
+		delete(old, field)
+	}
+	return old, nil
+}
+
+//loadDirMigrations returns the migrations of the <dataset>/<version>.json files of dir e.g Booking/v3.json
+func loadDirMigrations(dir string) (map[string][]gitdb.Migration, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s has no <dataset>/<version>.json migrations", dir)
+	}
+
+	migrations := map[string][]gitdb.Migration{}
+	for _, file := range files {
+		dataset := filepath.Base(filepath.Dir(file))
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		version, err := strconv.Atoi(strings.TrimPrefix(name, "v"))
+		if err != nil {
+			return nil, fmt.Errorf("%s is not named after a version such as v3.json", file)
+		}
+
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		m := &fileMigration{}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(m); err != nil {
+			return nil, fmt.Errorf("%s is not a valid migration: %s", file, err)
+		}
+		migrations[dataset] = append(migrations[dataset], gitdb.Migration{Version: version, Up: m.up})
+	}
+	return migrations, nil
+}
+
+//migrate runs gitdb migrate --path /data/db --dir migrations --to v3, running the migrations on every record
+//that is behind after checking they succeed with a dry run and tagging the database as a release
+func migrate(args []string, out io.Writer) error {
+	opts, err := parseMigrate(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	migrations, err := opts.migrations()
+	if err != nil {
+		return err
+	}
+
+	cfg := gitdb.NewConfig(opts.path)
+	cfg.EncryptionKey = os.Getenv("GITDB_ENCRYPTION_KEY")
+	cfg.Migrations = migrations
+	db, err := openDatabase(cfg, !opts.dryRun)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	pending := 0
+	count := gitdb.MigrateProgress(func(p *gitdb.MigrationProgress) {
+		if p.Done == p.Blocks {
+			pending += p.Records
+			fmt.Fprintf(out, "%s: %d records to migrate to version %d\n", p.Dataset, p.Records, p.Version)
+		}
+	})
+	if err := db.RunMigrations(gitdb.MigrateDryRun(), count); err != nil {
+		return err
+	}
+	if pending == 0 || opts.dryRun {
+		fmt.Fprintf(out, "%d records to migrate\n", pending)
+		return nil
+	}
+
+	if len(opts.tag) == 0 {
+		opts.tag = "before-migrate-" + time.Now().Format("20060102150405")
+	}
+	if err := db.TagRelease(opts.tag); err != nil {
+		return fmt.Errorf("failed to tag the database before migrating it: %s", err)
+	}
+	fmt.Fprintf(out, "Tagged the database as %s\n", opts.tag)
+
+	progress := gitdb.MigrateProgress(func(p *gitdb.MigrationProgress) {
+		fmt.Fprintf(out, "%s: %d/%d blocks, %d records migrated\n", p.Dataset, p.Done, p.Blocks, p.Records)
+	})
+	if err := db.RunMigrations(progress); err != nil {
+		return fmt.Errorf("%s; release %s holds the database as it was before the migration", err, opts.tag)
+	}
+	fmt.Fprintf(out, "Migrated %d records\n", pending)
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func Test_migrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := gitdb.Open(gitdb.NewConfig(dir))
+	if err != nil {
+		t.Fatalf("gitdb.Open failed: %s", err)
+	}
+	db.Close()
+
+	bookings := filepath.Join(dir, "bookings.ndjson")
+	ioutil.WriteFile(bookings, []byte(`{"RoomId":"101","Name":"Ada"}`+"\n"+`{"RoomId":"102","Name":"Grace"}`+"\n"), 0644)
+	if err := importData([]string{"Booking", "--path", dir, "--in", bookings, "--key", "RoomId"}); err != nil {
+		t.Fatalf("importData() failed: %s", err)
+	}
+
+	migrations := filepath.Join(dir, "migrations")
+	os.MkdirAll(filepath.Join(migrations, "Booking"), 0755)
+	ioutil.WriteFile(filepath.Join(migrations, "Booking", "v1.json"), []byte(`{"rename": {"Name": "GuestName"}}`), 0644)
+	ioutil.WriteFile(filepath.Join(migrations, "Booking", "v2.json"), []byte(`{"default": {"Status": "open"}}`), 0644)
+
+	if _, err := parseMigrate([]string{"--path", dir}); err == nil {
+		t.Error("want: error without migrations")
+	}
+	if _, err := parseMigrate([]string{"--path", dir, "--dir", migrations, "--to", "latest"}); err == nil {
+		t.Error("want: error for an invalid version")
+	}
+
+	fetch := func() []map[string]interface{} {
+		db, err := gitdb.OpenReadOnly(gitdb.NewConfig(dir))
+		if err != nil {
+			t.Fatalf("gitdb.OpenReadOnly failed: %s", err)
+		}
+		defer db.Close()
+		records, err := db.Fetch("Booking")
+		if err != nil {
+			t.Fatalf("Fetch failed: %s", err)
+		}
+		bookings := make([]map[string]interface{}, len(records))
+		for i, record := range records {
+			record.Hydrate(&bookings[i])
+		}
+		return bookings
+	}
+
+	var out bytes.Buffer
+	if err := migrate([]string{"--path", dir, "--dir", migrations, "--to", "v1", "--dry-run"}, &out); err != nil || !strings.Contains(out.String(), "Booking: 2 records to migrate to version 1") {
+		t.Errorf("want: 2 records to migrate, got: %v %s", err, out.String())
+	}
+	if got := fetch(); got[0]["Name"] != "Ada" {
+		t.Errorf("want: nothing migrated by a dry run, got: %v", got)
+	}
+
+	out.Reset()
+	if err := migrate([]string{"--path", dir, "--dir", migrations, "--to", "v1", "--tag", "before-v1"}, &out); err != nil || !strings.Contains(out.String(), "Booking: 1/1 blocks, 2 records migrated") {
+		t.Fatalf("want: 2 records migrated, got: %v %s", err, out.String())
+	}
+	if got := fetch(); got[0]["GuestName"] != "Ada" || got[0]["Status"] != nil {
+		t.Errorf("want: bookings at version 1, got: %v", got)
+	}
+
+	out.Reset()
+	if err := migrate([]string{"--path", dir, "--dir", migrations}, &out); err != nil || !strings.Contains(out.String(), "Tagged the database as before-migrate-") {
+		t.Fatalf("want: bookings migrated to version 2, got: %v %s", err, out.String())
+	}
+	if got := fetch(); got[1]["GuestName"] != "Grace" || got[1]["Status"] != "open" {
+		t.Errorf("want: bookings at version 2, got: %v", got)
+	}
+
+	db, err = gitdb.OpenReadOnly(gitdb.NewConfig(dir))
+	if err != nil {
+		t.Fatalf("gitdb.OpenReadOnly failed: %s", err)
+	}
+	defer db.Close()
+	releases, err := db.ListReleases()
+	if err != nil || len(releases) != 2 || (releases[0].Name != "before-v1" && releases[1].Name != "before-v1") {
+		t.Errorf("want: a release before each migration, got: %d %v", len(releases), err)
+	}
+}
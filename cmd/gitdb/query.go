@@ -0,0 +1,405 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	"github.com/gogitdb/gitdb/v2"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//queryOptions are the arguments and flags of gitdb query
+type queryOptions struct {
+	path    string
+	dataset string
+	where   []*clause
+	limit   int
+	format  string
+}
+
+//parseQuery parses the arguments and flags of gitdb query Booking --where 'RoomId=="r1"' --limit 20 --format json
+func parseQuery(args []string) (*queryOptions, error) {
+	opts := &queryOptions{}
+	var where string
+	queryCommand := flag.NewFlagSet("query", flag.ContinueOnError)
+	queryCommand.StringVar(&opts.path, "path", ".", "path of the database i.e Config.DbPath")
+	queryCommand.StringVar(&where, "where", "", `conditions joined by && e.g 'RoomId=="r1" && Amount>=100'; using ==, !=, <, <=, > or >=`)
+	queryCommand.IntVar(&opts.limit, "limit", 0, "most records to print; 0 prints every record")
+	queryCommand.StringVar(&opts.format, "format", "table", "table or json")
+	dataset, err := parseArgs(queryCommand, args)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.dataset = dataset
+	if len(opts.dataset) == 0 {
+		return nil, errors.New(`usage: gitdb query <dataset> [--path .] [--where 'Field=="value" && ...'] [--limit n] [--format table|json]`)
+	}
+	if opts.format != "table" && opts.format != "json" {
+		return nil, fmt.Errorf("unknown format %s; use table or json", opts.format)
+	}
+	if opts.limit < 0 {
+		return nil, fmt.Errorf("--limit can't be negative, got: %d", opts.limit)
+	}
+	if opts.where, err = parseWhere(where); err != nil {
+		return nil, err
+	}
+	return opts, checkDatabase(opts.path)
+}
+
+//clause is a comparison of a field with a value in the --where of gitdb query e.g Status!="cancelled"
+type clause struct {
+	field string
+	op    string
+	//value is a string, float64, bool or nil
+	value interface{}
+}
+
+//queryOperators are the operators of a clause, longest first so <= isn't read as <
+var queryOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+//parseWhere parses conditions joined by && e.g RoomId=="r1" && Status!="cancelled" && Amount>=100.
+//Values are double quoted strings, numbers, true, false or null
+func parseWhere(where string) ([]*clause, error) {
+	var clauses []*clause
+	rest := strings.TrimSpace(where)
+	for len(rest) > 0 {
+		c := &clause{}
+		n := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' })
+		if n < 0 {
+			n = len(rest)
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("--where expects a field at: %s", rest)
+		}
+		c.field, rest = rest[:n], strings.TrimSpace(rest[n:])
+
+		for _, op := range queryOperators {
+			if strings.HasPrefix(rest, op) {
+				c.op, rest = op, strings.TrimSpace(rest[len(op):])
+				break
+			}
+		}
+		if len(c.op) == 0 {
+			return nil, fmt.Errorf("--where expects one of %s after %s", strings.Join(queryOperators, " "), c.field)
+		}
+
+		var err error
+		if c.value, rest, err = parseValue(rest); err != nil {
+			return nil, fmt.Errorf("--where has a bad value for %s: %s", c.field, err)
+		}
+		if (c.value == nil || isBool(c.value)) && c.op != "==" && c.op != "!=" {
+			return nil, fmt.Errorf("--where can only compare %s with true, false or null using == or !=", c.field)
+		}
+		clauses = append(clauses, c)
+
+		rest = strings.TrimSpace(rest)
+		if len(rest) > 0 {
+			if !strings.HasPrefix(rest, "&&") {
+				return nil, fmt.Errorf("--where expects && between conditions at: %s", rest)
+			}
+			if rest = strings.TrimSpace(rest[2:]); len(rest) == 0 {
+				return nil, errors.New("--where expects a condition after &&")
+			}
+		}
+	}
+	return clauses, nil
+}
+
+//parseValue parses the value at the start of s and returns it with the rest of s
+func parseValue(s string) (interface{}, string, error) {
+	if strings.HasPrefix(s, `"`) {
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				value, err := strconv.Unquote(s[:i+1])
+				return value, s[i+1:], err
+			}
+		}
+		return nil, "", fmt.Errorf("%s is missing its closing quote", s)
+	}
+
+	n := strings.IndexAny(s, " \t&")
+	if n < 0 {
+		n = len(s)
+	}
+	word := s[:n]
+	switch word {
+	case "true", "false":
+		return word == "true", s[n:], nil
+	case "null":
+		return nil, s[n:], nil
+	}
+	value, err := strconv.ParseFloat(word, 64)
+	if err != nil {
+		return nil, "", fmt.Errorf("%q is not a quoted string, number, true, false or null", word)
+	}
+	return value, s[n:], nil
+}
+
+//isBool reports whether v is true or false
+func isBool(v interface{}) bool {
+	_, ok := v.(bool)
+	return ok
+}
+
+//condition returns the gitdb.Condition of c for SearchWhere. != has none as SearchWhere only matches ranges
+func (c *clause) condition() (gitdb.Condition, bool) {
+	switch c.op {
+	case "==":
+		return gitdb.Eq(c.value), true
+	case "<":
+		return gitdb.Lt(c.value), true
+	case "<=":
+		return gitdb.Lte(c.value), true
+	case ">":
+		return gitdb.Gt(c.value), true
+	case ">=":
+		return gitdb.Gte(c.value), true
+	}
+	return gitdb.Condition{}, false
+}
+
+//match reports whether the field of c in a record satisfies c. Numbers are compared as numbers, strings in
+//RFC3339 format as times and other strings lexically; values of different types are never equal
+func (c *clause) match(row map[string]interface{}) bool {
+	cmp, ok := compareValues(row[c.field], c.value)
+	switch c.op {
+	case "==":
+		return ok && cmp == 0
+	case "!=":
+		return !ok || cmp != 0
+	case "<":
+		return ok && cmp < 0
+	case "<=":
+		return ok && cmp <= 0
+	case ">":
+		return ok && cmp > 0
+	}
+	return ok && cmp >= 0
+}
+
+//compareValues compares the value of a field with the value of a clause, returning false if they can't be compared
+func compareValues(field interface{}, value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case float64:
+		n, ok := field.(json.Number)
+		if !ok {
+			return 0, false
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return 0, false
+		}
+		if f < v {
+			return -1, true
+		} else if f > v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		s, ok := field.(string)
+		if !ok {
+			return 0, false
+		}
+		t1, err1 := time.Parse(time.RFC3339Nano, s)
+		t2, err2 := time.Parse(time.RFC3339Nano, v)
+		if err1 == nil && err2 == nil {
+			if t1.Before(t2) {
+				return -1, true
+			} else if t1.After(t2) {
+				return 1, true
+			}
+			return 0, true
+		}
+		return strings.Compare(s, v), true
+	case bool:
+		b, ok := field.(bool)
+		if !ok || b == v {
+			return 0, ok
+		}
+		return 1, true
+	}
+	return 0, field == nil
+}
+
+//query runs gitdb query Booking --where 'RoomId=="r1" && Status!="cancelled"' --limit 20, writing the matching
+//records to out. Conditions on indexes are answered by SearchWhere and the rest are checked against each record
+func query(args []string, out io.Writer) error {
+	opts, err := parseQuery(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	cfg := gitdb.NewConfig(opts.path)
+	cfg.EncryptionKey = os.Getenv("GITDB_ENCRYPTION_KEY")
+	conn, err := openDatabase(cfg, false)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	records, filters, err := opts.search(conn)
+	if err != nil {
+		return err
+	}
+
+	var rows []map[string]json.RawMessage
+	for _, record := range records {
+		var fields map[string]interface{}
+		if err := hydrateNumbers(record, &fields); err != nil {
+			return fmt.Errorf("%s can't be read: %s", record.ID(), err)
+		}
+		matched := true
+		for _, c := range filters {
+			if matched = c.match(fields); !matched {
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		var row map[string]json.RawMessage
+		if err := record.Hydrate(&row); err != nil {
+			return fmt.Errorf("%s can't be read: %s", record.ID(), err)
+		}
+		if row == nil {
+			row = map[string]json.RawMessage{}
+		}
+		row[idField], _ = json.Marshal(record.ID())
+		rows = append(rows, row)
+		if opts.limit > 0 && len(rows) == opts.limit {
+			break
+		}
+	}
+
+	if opts.format == "json" {
+		if rows == nil {
+			rows = []map[string]json.RawMessage{}
+		}
+		return writeRows(out, "json", rows)
+	}
+	return writeTable(out, rows)
+}
+
+//search returns the records of the dataset of opts matching its conditions on indexes, and the conditions
+//left to check against each record. Records are in the order of the first index searched, otherwise by id
+func (opts *queryOptions) search(conn gitdb.GitDb) ([]*db.Record, []*clause, error) {
+	indexed := map[string]bool{}
+	for _, index := range datasetIndexes(opts.path, opts.dataset) {
+		indexed[index] = true
+	}
+
+	var indexes []string
+	conds := map[string][]gitdb.Condition{}
+	var filters []*clause
+	for _, c := range opts.where {
+		cond, ok := c.condition()
+		if !ok || !indexed[c.field] {
+			filters = append(filters, c)
+			continue
+		}
+		if _, ok := conds[c.field]; !ok {
+			indexes = append(indexes, c.field)
+		}
+		conds[c.field] = append(conds[c.field], cond)
+	}
+
+	if len(indexes) == 0 {
+		records, err := conn.Fetch(opts.dataset)
+		if err != nil {
+			return nil, nil, err
+		}
+		sort.SliceStable(records, func(i, j int) bool { return records[i].ID() < records[j].ID() })
+		return records, filters, nil
+	}
+
+	var records []*db.Record
+	for i, index := range indexes {
+		found, err := conn.SearchWhere(opts.dataset, index, conds[index]...)
+		if err != nil {
+			return nil, nil, err
+		}
+		if i == 0 {
+			records = found
+			continue
+		}
+		//keep the records found by every index
+		ids := map[string]bool{}
+		for _, record := range found {
+			ids[record.ID()] = true
+		}
+		kept := records[:0]
+		for _, record := range records {
+			if ids[record.ID()] {
+				kept = append(kept, record)
+			}
+		}
+		records = kept
+	}
+	return records, filters, nil
+}
+
+//hydrateNumbers decodes the data of record into v keeping numbers as json.Number
+func hydrateNumbers(record *db.Record, v interface{}) error {
+	var data json.RawMessage
+	if err := record.Hydrate(&data); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+//writeTable writes rows to w as a table with a column for every field of any row, the id first
+func writeTable(w io.Writer, rows []map[string]json.RawMessage) error {
+	if len(rows) == 0 {
+		_, err := fmt.Fprintln(w, "No records found")
+		return err
+	}
+
+	seen := map[string]bool{}
+	var columns []string
+	for _, row := range rows {
+		for field := range row {
+			if !seen[field] && field != idField {
+				seen[field] = true
+				columns = append(columns, field)
+			}
+		}
+	}
+	sort.Strings(columns)
+	columns = append([]string{idField}, columns...)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(columns, "\t"))
+	cells := make([]string, len(columns))
+	for _, row := range rows {
+		for i, column := range columns {
+			//tabs and newlines would break the columns of the table
+			cells[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(csvValue(row[column]))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d records\n", len(rows))
+	return err
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func Test_parseWhere(t *testing.T) {
+	clauses, err := parseWhere(`RoomId=="r1" && Status != "say \"hi\"" && Amount>=100.5 && Paid==true && Note==null`)
+	if err != nil {
+		t.Fatalf("parseWhere() failed: %s", err)
+	}
+	want := []clause{{"RoomId", "==", "r1"}, {"Status", "!=", `say "hi"`}, {"Amount", ">=", 100.5}, {"Paid", "==", true}, {"Note", "==", nil}}
+	if len(clauses) != len(want) {
+		t.Fatalf("want: %d clauses, got: %d", len(want), len(clauses))
+	}
+	for i, c := range clauses {
+		if *c != want[i] {
+			t.Errorf("want: %v, got: %v", want[i], *c)
+		}
+	}
+
+	for _, where := range []string{`RoomId`, `RoomId="r1"`, `RoomId=="r1`, `RoomId==r1`, `RoomId=="r1" Status=="a"`, `RoomId=="r1" &&`, `Paid>true`} {
+		if _, err := parseWhere(where); err == nil {
+			t.Errorf("want: error parsing %s", where)
+		}
+	}
+}
+
+func Test_query(t *testing.T) {
+	dir, err := ioutil.TempDir("", "query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := gitdb.Open(gitdb.NewConfig(dir))
+	if err != nil {
+		t.Fatalf("gitdb.Open failed: %s", err)
+	}
+	db.Close()
+
+	bookings := filepath.Join(dir, "bookings.ndjson")
+	ioutil.WriteFile(bookings, []byte(
+		`{"Ref":"b1","RoomId":"r1","Status":"paid","Amount":"120"}`+"\n"+
+			`{"Ref":"b2","RoomId":"r1","Status":"cancelled","Amount":"80"}`+"\n"+
+			`{"Ref":"b3","RoomId":"r2","Status":"paid","Amount":"300"}`+"\n"+
+			`{"Ref":"b4","RoomId":"r1","Status":"pending","Amount":"450"}`+"\n"), 0644)
+	if err := importData([]string{"Booking", "--path", dir, "--in", bookings, "--key", "Ref", "--index", "RoomId", "--types", "Amount:int"}); err != nil {
+		t.Fatalf("importData() failed: %s", err)
+	}
+
+	var out bytes.Buffer
+	if err := query([]string{"Booking", "--path", dir, "--where", `RoomId=="r1" && Status!="cancelled"`}, &out); err != nil {
+		t.Fatalf("query() failed: %s", err)
+	}
+	if !strings.Contains(out.String(), "Booking/b0/b1") || strings.Contains(out.String(), "Booking/b0/b2") || !strings.Contains(out.String(), "\n2 records\n") {
+		t.Errorf("want: b1 and b4 in a table, got: %s", out.String())
+	}
+
+	out.Reset()
+	if err := query([]string{"Booking", "--path", dir, "--where", `Amount>100 && Amount<=450`, "--limit", "2", "--format", "json"}, &out); err != nil {
+		t.Fatalf("query() failed: %s", err)
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &rows); err != nil || len(rows) != 2 || rows[0][idField] != "Booking/b0/b1" || rows[1][idField] != "Booking/b0/b3" {
+		t.Errorf("want: b1 and b3 as JSON, got: %v %s", err, out.String())
+	}
+
+	out.Reset()
+	if err := query([]string{"Booking", "--path", dir, "--where", `RoomId=="r9"`}, &out); err != nil || !strings.Contains(out.String(), "No records found") {
+		t.Errorf("want: no records found, got: %v %s", err, out.String())
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gogitdb/gitdb/v2"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//recordOptions are the arguments and flags of gitdb get, put and delete
+type recordOptions struct {
+	path    string
+	dataset string
+	//key is the key of the record, or its block and key as <block>/<key>
+	key     string
+	indexes string
+	encrypt bool
+}
+
+//parseRecord parses the arguments and flags of gitdb get|put|delete Booking b1234 --path /data/db
+func parseRecord(command string, args []string) (*recordOptions, error) {
+	opts := &recordOptions{}
+	recordCommand := flag.NewFlagSet(command, flag.ContinueOnError)
+	recordCommand.StringVar(&opts.path, "path", ".", "path of the database i.e Config.DbPath")
+	if command == "put" {
+		recordCommand.StringVar(&opts.indexes, "index", "", "fields to index e.g RoomId,CheckInDate; defaults to the indexes of the record or the dataset")
+		recordCommand.BoolVar(&opts.encrypt, "encrypt", false, "encrypt a new record with GITDB_ENCRYPTION_KEY; records that are encrypted stay encrypted")
+	}
+	args, err := parsePositional(recordCommand, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) != 2 {
+		return nil, fmt.Errorf("usage: gitdb %s <dataset> <key or block/key> [--path .]", command)
+	}
+	opts.dataset, opts.key = args[0], args[1]
+	return opts, checkDatabase(opts.path)
+}
+
+//open opens the database of opts, read-only unless writable
+func (opts *recordOptions) open(writable bool) (gitdb.GitDb, error) {
+	cfg := gitdb.NewConfig(opts.path)
+	cfg.EncryptionKey = os.Getenv("GITDB_ENCRYPTION_KEY")
+	return openDatabase(cfg, writable)
+}
+
+//find returns the record of opts. A key given as <block>/<key> is read from its block through the id index,
+//a key without a block is looked for in every block of the dataset
+func (opts *recordOptions) find(conn gitdb.GitDb) (*db.Record, error) {
+	if strings.Contains(opts.key, "/") {
+		id := opts.dataset + "/" + opts.key
+		if err := conn.Exists(id); err != nil {
+			return nil, err
+		}
+		records, err := conn.Search(opts.dataset, []*gitdb.SearchParam{{Index: "id", Value: id}}, gitdb.SearchEquals)
+		if err != nil {
+			return nil, err
+		}
+		//search values are matched regardless of case
+		for _, record := range records {
+			if record.ID() == id {
+				return record, nil
+			}
+		}
+		return nil, fmt.Errorf("Record %s not found in %s", opts.key, opts.dataset)
+	}
+
+	records, err := conn.Fetch(opts.dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []*db.Record
+	for _, record := range records {
+		id := record.ID()
+		if strings.HasSuffix(id, "/"+opts.key) {
+			found = append(found, record)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("Record %s not found in %s", opts.key, opts.dataset)
+	case 1:
+		return found[0], nil
+	}
+
+	ids := make([]string, len(found))
+	for i, record := range found {
+		ids[i] = record.ID()
+	}
+	return nil, fmt.Errorf("%s is in more than one block of %s: %s; give it as <block>/<key>", opts.key, opts.dataset, strings.Join(ids, ", "))
+}
+
+//getRecord runs gitdb get Booking b1234, writing the data of the record to out as indented JSON
+func getRecord(args []string, out io.Writer) error {
+	opts, err := parseRecord("get", args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	conn, err := opts.open(false)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	record, err := opts.find(conn)
+	if err != nil {
+		return err
+	}
+	var data json.RawMessage
+	if err := record.Hydrate(&data); err != nil {
+		return fmt.Errorf("%s can't be read: %s", record.ID(), err)
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return err
+	}
+	buf.WriteString("\n")
+	_, err = buf.WriteTo(out)
+	return err
+}
+
+//putRecord runs gitdb put Booking b1234 < record.json, writing the JSON object read from in as the data of
+//the record, which is inserted in the block given as <block>/<key> if it doesn't exist. Its indexes are
+//taken from the fields of the same name
+func putRecord(args []string, in io.Reader, out io.Writer) error {
+	opts, err := parseRecord("put", args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	var fields map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil || fields == nil {
+		return errors.New("the record must be a JSON object")
+	}
+
+	conn, err := opts.open(true)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	put := &importOptions{dataset: opts.dataset, indexes: fieldList(opts.indexes), encrypt: opts.encrypt}
+	id := opts.dataset + "/" + opts.key
+	var encryptedFields []string
+	record, err := opts.find(conn)
+	if err == nil {
+		id = record.ID()
+		//records that were stored encrypted, as a whole or by field, stay encrypted and keep their indexes.
+		//A record that starts like a JSON object without being one is damaged rather than encrypted
+		stored := record.Data()
+		encrypted := !json.Valid([]byte(stored)) && !strings.HasPrefix(strings.TrimSpace(stored), "{")
+		encryptedFields = record.EncryptedFields()
+		if (encrypted || len(encryptedFields) > 0) && len(conn.Config().EncryptionKey) == 0 {
+			return fmt.Errorf("%s is encrypted; set GITDB_ENCRYPTION_KEY to update it", id)
+		}
+		put.encrypt = put.encrypt || encrypted
+		if len(put.indexes) == 0 {
+			for name := range record.Indexes() {
+				put.indexes = append(put.indexes, name)
+			}
+			sort.Strings(put.indexes)
+		}
+	} else if !strings.Contains(opts.key, "/") {
+		return fmt.Errorf("%s; give the block of a new record as <block>/<key>", err)
+	}
+	if len(put.indexes) == 0 {
+		put.indexes = datasetIndexes(opts.path, opts.dataset)
+	}
+
+	fields[idField] = id
+	m, err := put.record(fields)
+	if err != nil {
+		return err
+	}
+	m.schema.EncryptFields(encryptedFields...)
+	if err := conn.Insert(m); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Saved %s\n", id)
+	return nil
+}
+
+//deleteRecord runs gitdb delete Booking b1234
+func deleteRecord(args []string, out io.Writer) error {
+	opts, err := parseRecord("delete", args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	conn, err := opts.open(true)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	record, err := opts.find(conn)
+	if err != nil {
+		return err
+	}
+	if err := conn.DeleteOrFail(record.ID()); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Deleted %s\n", record.ID())
+	return nil
+}
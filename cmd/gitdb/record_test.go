@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func Test_recordCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := gitdb.Open(gitdb.NewConfig(dir))
+	if err != nil {
+		t.Fatalf("gitdb.Open failed: %s", err)
+	}
+	db.Close()
+
+	bookings := filepath.Join(dir, "bookings.ndjson")
+	ioutil.WriteFile(bookings, []byte(`{"RoomId":"101","GuestName":"Ada"}`+"\n"+`{"RoomId":"102","GuestName":"Grace"}`+"\n"), 0644)
+	if err := importData([]string{"Booking", "--path", dir, "--in", bookings, "--key", "RoomId", "--index", "GuestName"}); err != nil {
+		t.Fatalf("importData() failed: %s", err)
+	}
+
+	var out bytes.Buffer
+	if err := getRecord([]string{"Booking", "101", "--path", dir}, &out); err != nil || !strings.Contains(out.String(), `"GuestName": "Ada"`) {
+		t.Errorf("want: Booking/b0/101, got: %v %s", err, out.String())
+	}
+
+	//a record that can't be read is patched in place
+	blockFile := filepath.Join(dir, "data", "Booking", "b0.json")
+	data, _ := ioutil.ReadFile(blockFile)
+	var records map[string]string
+	json.Unmarshal(data, &records)
+	records["Booking/b0/101"] = `{"RoomId":`
+	data, _ = json.Marshal(records)
+	ioutil.WriteFile(blockFile, data, 0744)
+	if err := getRecord([]string{"Booking", "101", "--path", dir}, &out); err == nil {
+		t.Error("want: error getting a bad record")
+	}
+	if err := putRecord([]string{"Booking", "101", "--path", dir}, strings.NewReader(`{"RoomId":"101","GuestName":"Ada L"}`), &out); err != nil {
+		t.Fatalf("putRecord() failed: %s", err)
+	}
+	out.Reset()
+	if err := getRecord([]string{"--path", dir, "Booking", "b0/101"}, &out); err != nil || !strings.Contains(out.String(), `"GuestName": "Ada L"`) {
+		t.Errorf("want: Booking/b0/101 patched, got: %v %s", err, out.String())
+	}
+
+	if err := putRecord([]string{"Booking", "103", "--path", dir}, strings.NewReader(`{"RoomId":"103"}`), &out); err == nil {
+		t.Error("want: error putting a new record without its block")
+	}
+	if err := putRecord([]string{"Booking", "b1/103", "--path", dir}, strings.NewReader(`{"RoomId":"103"}`), &out); err == nil || !strings.Contains(err.Error(), "index GuestName is missing") {
+		t.Errorf("want: error for a record without the indexes of the dataset, got: %v", err)
+	}
+	if err := putRecord([]string{"Booking", "b1/103", "--path", dir}, strings.NewReader(`{"RoomId":"103","GuestName":"Linus"}`), &out); err != nil {
+		t.Fatalf("putRecord() of a new record failed: %s", err)
+	}
+
+	//a key given with its block is read from its block alone
+	brokenBlock := filepath.Join(dir, "data", "Booking", "b9.json")
+	ioutil.WriteFile(brokenBlock, []byte("not a block"), 0744)
+	out.Reset()
+	if err := getRecord([]string{"Booking", "b1/103", "--path", dir}, &out); err != nil || !strings.Contains(out.String(), `"GuestName": "Linus"`) {
+		t.Errorf("want: Booking/b1/103 read from b1, got: %v %s", err, out.String())
+	}
+	os.Remove(brokenBlock)
+
+	if err := deleteRecord([]string{"Booking", "102", "--path", dir}, &out); err != nil {
+		t.Fatalf("deleteRecord() failed: %s", err)
+	}
+	if err := getRecord([]string{"Booking", "102", "--path", dir}, &out); err == nil {
+		t.Error("want: Booking/b0/102 deleted")
+	}
+
+	db, err = gitdb.OpenReadOnly(gitdb.NewConfig(dir))
+	if err != nil {
+		t.Fatalf("gitdb.OpenReadOnly failed: %s", err)
+	}
+	defer db.Close()
+	if ids, err := db.SearchIDs("Booking", "GuestName", "Linus"); err != nil || len(ids) != 1 || ids[0] != "Booking/b1/103" {
+		t.Errorf("want: Booking/b1/103 indexed, got: %v %v", ids, err)
+	}
+}
+
+//patient is a record with a field encrypted with Schema.EncryptFields
+type patient struct {
+	gitdb.TimeStampedModel
+	ID  string
+	SSN string
+}
+
+func (p *patient) GetSchema() *gitdb.Schema {
+	return gitdb.NewSchema("Patient", "b0", p.ID, map[string]interface{}{}).EncryptFields("SSN")
+}
+func (p *patient) Validate() error            { return nil }
+func (p *patient) IsLockable() bool           { return false }
+func (p *patient) ShouldEncrypt() bool        { return false }
+func (p *patient) GetLockFileNames() []string { return nil }
+
+func Test_putEncryptedRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key := "0123456789abcdef0123456789abcdef"
+	cfg := gitdb.NewConfig(dir)
+	cfg.EncryptionKey = key
+	db, err := gitdb.Open(cfg)
+	if err != nil {
+		t.Fatalf("gitdb.Open failed: %s", err)
+	}
+	err = db.Insert(&patient{ID: "p1", SSN: "078-05-1120"})
+	db.Close()
+	if err != nil {
+		t.Fatalf("db.Insert failed: %s", err)
+	}
+
+	os.Setenv("GITDB_ENCRYPTION_KEY", key)
+	defer os.Unsetenv("GITDB_ENCRYPTION_KEY")
+	var out bytes.Buffer
+	if err := putRecord([]string{"Booking", "b0/101", "--path", dir, "--encrypt"}, strings.NewReader(`{"RoomId":"101"}`), &out); err != nil {
+		t.Fatalf("putRecord() failed: %s", err)
+	}
+
+	//encrypted records are not rewritten without the key
+	os.Unsetenv("GITDB_ENCRYPTION_KEY")
+	blockFile := filepath.Join(dir, "data", "Booking", "b0.json")
+	before, _ := ioutil.ReadFile(blockFile)
+	if err := putRecord([]string{"Booking", "101", "--path", dir}, strings.NewReader(`{"RoomId":"101","Note":"plain"}`), &out); err == nil || !strings.Contains(err.Error(), "GITDB_ENCRYPTION_KEY") {
+		t.Errorf("want: error putting an encrypted record without the key, got: %v", err)
+	}
+	if after, _ := ioutil.ReadFile(blockFile); !bytes.Equal(before, after) {
+		t.Errorf("want: Booking/b0/101 untouched, got: %s", after)
+	}
+	if err := putRecord([]string{"Patient", "p1", "--path", dir}, strings.NewReader(`{"ID":"p1","SSN":"078-05-1120"}`), &out); err == nil {
+		t.Error("want: error putting a record with encrypted fields without the key")
+	}
+	if err := putRecord([]string{"Booking", "b0/102", "--path", dir, "--encrypt"}, strings.NewReader(`{"RoomId":"102"}`), &out); err == nil {
+		t.Error("want: error putting a record to encrypt without the key")
+	}
+	if after, _ := ioutil.ReadFile(blockFile); strings.Contains(string(after), "Booking/b0/102") {
+		t.Errorf("want: Booking/b0/102 not stored, got: %s", after)
+	}
+
+	//fields encrypted with Schema.EncryptFields stay encrypted
+	os.Setenv("GITDB_ENCRYPTION_KEY", key)
+	if err := putRecord([]string{"Patient", "p1", "--path", dir}, strings.NewReader(`{"ID":"p1","SSN":"219-09-9999"}`), &out); err != nil {
+		t.Fatalf("putRecord() failed: %s", err)
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "data", "Patient", "b0.json"))
+	if strings.Contains(string(data), "219-09-9999") || !strings.Contains(string(data), `$enc`) {
+		t.Errorf("want: SSN encrypted, got: %s", data)
+	}
+	out.Reset()
+	if err := getRecord([]string{"Patient", "p1", "--path", dir}, &out); err != nil || !strings.Contains(out.String(), `"SSN": "219-09-9999"`) {
+		t.Errorf("want: SSN readable with the key, got: %v %s", err, out.String())
+	}
+}
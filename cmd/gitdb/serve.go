@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+//serveOptions are the flags of gitdb serve
+type serveOptions struct {
+	path      string
+	host      string
+	port      int
+	base      string
+	writes    bool
+	usersFile string
+	users     []gitdb.UIUser
+}
+
+//parseUsers parses users given one per line as name:password[:email], skipping blank lines and # comments
+func parseUsers(text string) ([]gitdb.UIUser, error) {
+	var users []gitdb.UIUser
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) < 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return nil, errors.New("users are given as name:password[:email], one per line")
+		}
+		user := gitdb.UIUser{Name: parts[0], Password: parts[1]}
+		if len(parts) == 3 {
+			user.Email = parts[2]
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+//isLoopback reports whether host only accepts connections from the machine it runs on
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+//parseServe parses the flags of gitdb serve --path /data/db --port 4120. Users are read from the file of
+//--users or from GITDB_USERS rather than flags, which other users of the machine can see
+func parseServe(args []string) (*serveOptions, error) {
+	opts := &serveOptions{}
+	serveCommand := flag.NewFlagSet("serve", flag.ContinueOnError)
+	serveCommand.StringVar(&opts.path, "path", "", "path of the database i.e Config.DbPath")
+	serveCommand.StringVar(&opts.host, "host", "localhost", "host to listen on; 0.0.0.0 for every interface")
+	serveCommand.IntVar(&opts.port, "port", 4120, "port to listen on")
+	serveCommand.StringVar(&opts.base, "base", "", "path the web UI is served under e.g /gitdb")
+	serveCommand.BoolVar(&opts.writes, "writes", false, "allow changes through the web UI and REST API; the database is opened read-only otherwise")
+	serveCommand.StringVar(&opts.usersFile, "users", "", "file of users who can sign in, one per line as name:password[:email], password being the password or its bcrypt hash; GITDB_USERS is read otherwise")
+	if err := serveCommand.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if len(opts.path) == 0 {
+		return nil, errors.New("usage: gitdb serve --path <db path> [--port 4120] [--host localhost] [--base /gitdb] [--users users.txt] [--writes]")
+	}
+
+	users := os.Getenv("GITDB_USERS")
+	if len(opts.usersFile) > 0 {
+		data, err := ioutil.ReadFile(opts.usersFile)
+		if err != nil {
+			return nil, err
+		}
+		users = string(data)
+	}
+	var err error
+	if opts.users, err = parseUsers(users); err != nil {
+		return nil, err
+	}
+
+	if opts.writes && len(opts.users) == 0 && !isLoopback(opts.host) {
+		return nil, fmt.Errorf("refusing to serve writes to anyone who can reach %s; add users with --users or GITDB_USERS", opts.host)
+	}
+	return opts, checkDatabase(opts.path)
+}
+
+//open opens the database of opts, read-only unless --writes is given, and returns the handler of its web UI
+//and REST API
+func (opts *serveOptions) open() (gitdb.GitDb, http.Handler, error) {
+	cfg := gitdb.NewConfig(opts.path)
+	cfg.EncryptionKey = os.Getenv("GITDB_ENCRYPTION_KEY")
+	cfg.UIBasePath = opts.base
+	cfg.UIUsers = opts.users
+	cfg.UIWrites = opts.writes
+	cfg.APIWrites = opts.writes
+
+	db, err := openDatabase(cfg, opts.writes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	handler, err := gitdb.UIHandler(db)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return db, handler, nil
+}
+
+//serve runs gitdb serve --path /data/db --port 4120, serving the web UI and REST API of an existing
+//database until it is interrupted
+func serve(args []string) error {
+	opts, err := parseServe(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	db, handler, err := opts.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	server := &http.Server{Addr: fmt.Sprintf("%s:%d", opts.host, opts.port), Handler: handler}
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+		server.Shutdown(context.Background())
+	}()
+
+	fmt.Printf("Serving %s at http://%s%s/\n", opts.path, server.Addr, strings.TrimSuffix(opts.base, "/"))
+	if len(opts.users) == 0 {
+		fmt.Println("Warning: anyone who can reach the server can read the database; add users with --users or GITDB_USERS")
+	}
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
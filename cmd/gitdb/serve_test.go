@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func Test_serve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := parseServe([]string{"--path", dir}); err == nil {
+		t.Error("want: error serving a directory without a database")
+	}
+	os.Setenv("GITDB_USERS", "ada")
+	_, err = parseServe([]string{"--path", dir})
+	os.Unsetenv("GITDB_USERS")
+	if err == nil {
+		t.Error("want: error for a user without a password")
+	}
+
+	db, err := gitdb.Open(gitdb.NewConfig(dir))
+	if err != nil {
+		t.Fatalf("gitdb.Open failed: %s", err)
+	}
+	db.Close()
+
+	if _, err := parseServe([]string{"--path", dir, "--host", "0.0.0.0", "--writes"}); err == nil {
+		t.Error("want: error serving writes to every interface without users")
+	}
+	if _, err := parseServe([]string{"--path", dir, "--host", "127.0.0.1", "--writes"}); err != nil {
+		t.Errorf("want: writes served to localhost without users, got: %s", err)
+	}
+
+	os.Setenv("GITDB_USERS", "bob:hunter2")
+	defer os.Unsetenv("GITDB_USERS")
+	if opts, err := parseServe([]string{"--path", dir, "--host", "0.0.0.0", "--writes"}); err != nil || len(opts.users) != 1 || opts.users[0].Name != "bob" {
+		t.Errorf("want: user bob read from GITDB_USERS, got: %+v (%v)", opts, err)
+	}
+
+	usersFile := filepath.Join(dir, "users.txt")
+	ioutil.WriteFile(usersFile, []byte("# who can sign in\nada:s3cret:ada@example.com\n\n"), 0600)
+	opts, err := parseServe([]string{"--path", dir, "--port", "4200", "--users", usersFile})
+	if err != nil {
+		t.Fatalf("parseServe() failed: %s", err)
+	}
+	if opts.port != 4200 || len(opts.users) != 1 || opts.users[0].Email != "ada@example.com" || opts.writes {
+		t.Errorf("want: port 4200, user ada from --users and no writes, got: %+v", opts)
+	}
+
+	conn, handler, err := opts.open()
+	if err != nil {
+		t.Fatalf("open() failed: %s", err)
+	}
+	defer conn.Close()
+	if err := conn.Delete("Booking/b0/1"); err != gitdb.ErrReadOnly {
+		t.Errorf("want: database opened read-only, got: %v", err)
+	}
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	get := func(user string, password string) (int, string) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/datasets", nil)
+		req.Header.Set("Accept", "application/json")
+		if len(user) > 0 {
+			req.SetBasicAuth(user, password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %s", err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if status, _ := get("", ""); status != http.StatusUnauthorized {
+		t.Errorf("want: 401 without signing in, got: %d", status)
+	}
+	if status, body := get("ada", "s3cret"); status != http.StatusOK || !strings.Contains(body, `"datasets"`) {
+		t.Errorf("want: datasets, got: %d %s", status, body)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/gogitdb/gitdb/v2"
+	"github.com/gogitdb/gitdb/v2/internal/digital"
+)
+
+//statsOptions are the flags of gitdb stats
+type statsOptions struct {
+	path string
+	json bool
+}
+
+//parseStats parses the flags of gitdb stats --path /data/db --json
+func parseStats(args []string) (*statsOptions, error) {
+	opts := &statsOptions{}
+	statsCommand := flag.NewFlagSet("stats", flag.ContinueOnError)
+	statsCommand.StringVar(&opts.path, "path", ".", "path of the database i.e Config.DbPath")
+	statsCommand.BoolVar(&opts.json, "json", false, "print the statistics as JSON")
+	if err := statsCommand.Parse(args); err != nil {
+		return nil, err
+	}
+	return opts, checkDatabase(opts.path)
+}
+
+//stats runs gitdb stats --path /data/db, writing the statistics of each dataset and the repository to out
+func stats(args []string, out io.Writer) error {
+	opts, err := parseStats(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	cfg := gitdb.NewConfig(opts.path)
+	cfg.EncryptionKey = os.Getenv("GITDB_ENCRYPTION_KEY")
+	db, err := openDatabase(cfg, false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	s, err := db.Stats()
+	if err != nil {
+		return err
+	}
+	if opts.json {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "DATASET\tRECORDS\tBLOCKS\tSIZE\tBAD BLOCKS\tBAD RECORDS")
+	for _, ds := range s.Datasets {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%d\t%d\n", ds.Name, ds.Records, ds.Blocks, digital.FormatBytes(uint64(ds.Size)), ds.BadBlocks, ds.BadRecords)
+	}
+	w.Flush()
+
+	fmt.Fprintf(out, "\nData: %s, repository objects: %s\n", digital.FormatBytes(uint64(s.Size)), digital.FormatBytes(uint64(s.RepoSize)))
+	for _, ds := range s.Datasets {
+		if len(ds.Largest) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "\nLargest records of %s:\n", ds.Name)
+		for _, r := range ds.Largest {
+			fmt.Fprintf(out, "  %s\t%s\n", r.ID, digital.FormatBytes(uint64(r.Size)))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func Test_stats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := gitdb.Open(gitdb.NewConfig(dir))
+	if err != nil {
+		t.Fatalf("gitdb.Open failed: %s", err)
+	}
+	db.Close()
+
+	bookings := filepath.Join(dir, "bookings.ndjson")
+	ioutil.WriteFile(bookings, []byte(`{"RoomId":"101","GuestName":"Ada"}`+"\n"+`{"RoomId":"102","GuestName":"Grace Hopper"}`+"\n"), 0644)
+	if err := importData([]string{"Booking", "--path", dir, "--in", bookings, "--key", "RoomId"}); err != nil {
+		t.Fatalf("importData() failed: %s", err)
+	}
+
+	var out bytes.Buffer
+	if err := stats([]string{"--path", dir}, &out); err != nil {
+		t.Fatalf("stats() failed: %s", err)
+	}
+	if fields := strings.Fields(strings.Split(out.String(), "\n")[1]); len(fields) < 3 || fields[0] != "Booking" || fields[1] != "2" || fields[2] != "1" || !strings.Contains(out.String(), "Largest records of Booking:\n  Booking/b0/102") {
+		t.Errorf("want: 2 records of Booking in 1 block, got: %s", out.String())
+	}
+
+	out.Reset()
+	if err := stats([]string{"--path", dir, "--json"}, &out); err != nil {
+		t.Fatalf("stats() failed: %s", err)
+	}
+	var s gitdb.Stats
+	if err := json.Unmarshal(out.Bytes(), &s); err != nil || len(s.Datasets) != 1 || s.Datasets[0].Records != 2 {
+		t.Errorf("want: stats as JSON, got: %v %s", err, out.String())
+	}
+}
@@ -0,0 +1,121 @@
+package gitdb
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+//Collation controls how the values of an index are compared. Combine options with |
+type Collation int
+
+const (
+	//CollateFoldCase compares values ignoring case
+	CollateFoldCase Collation = 1 << iota
+	//CollateTrim ignores leading and trailing white space
+	CollateTrim
+	//CollateNormalize ignores accents so composed and decomposed forms of
+	//a character match each other and the plain letter e.g é, e\u0301 and e
+	CollateNormalize
+)
+
+//accented and unaccented map Latin letters with diacritics to their base letter
+const accented = "ÀÁÂÃÄÅÇÈÉÊËÌÍÎÏÑÒÓÔÕÖÙÚÛÜÝàáâãäåçèéêëìíîïñòóôõöùúûüýÿĀāĂăĄąĆćĈĉĊċČčĎďĒēĔĕĖėĘęĚěĜĝĞğĠġĢģĤĥĨĩĪīĬĭĮįİĴĵĶķĹĺĻļĽľŃńŅņŇňŌōŎŏŐőŔŕŖŗŘřŚśŜŝŞşŠšŢţŤťŨũŪūŬŭŮůŰűŲųŴŵŶŷŸŹźŻżŽžſƠơƯưǍǎǏǐǑǒǓǔǕǖǗǘǙǚǛǜǞǟǠǡǦǧǨǩǪǫǬǭǰǴǵǸǹǺǻȀȁȂȃȄȅȆȇȈȉȊȋȌȍȎȏȐȑȒȓȔȕȖȗȘșȚțȞȟȦȧȨȩȪȫȬȭȮȯȰȱȲȳ"
+const unaccented = "AAAAAACEEEEIIIINOOOOOUUUUYaaaaaaceeeeiiiinooooouuuuyyAaAaAaCcCcCcCcDdEeEeEeEeEeGgGgGgGgHhIiIiIiIiIJjKkLlLlLlNnNnNnOoOoOoRrRrRrSsSsSsSsTtTtUuUuUuUuUuUuWwYyYZzZzZzsOoUuAaIiOoUuUuUuUuUuAaAaGgKkOoOojGgNnAaAaAaEeEeIiIiOoOoRrRrUuUuSsTtHhAaEeOoOoOoOoYy"
+
+var unaccent = map[rune]rune{}
+
+func init() {
+	base := []rune(unaccented)
+	for i, r := range []rune(accented) {
+		unaccent[r] = base[i]
+	}
+}
+
+//Collate sets the collation of index. Values are stored collated and search values
+//are collated the same way so callers don't need to normalize them
+func (a *Schema) Collate(index string, c Collation) *Schema {
+	if a.collations == nil {
+		a.collations = map[string]Collation{}
+	}
+	a.collations[index] = c
+
+	if v, ok := a.indexes[index]; ok {
+		a.indexes[index] = collateValue(v, c)
+	}
+	return a
+}
+
+func (a *Schema) validateCollations() error {
+	for index := range a.collations {
+		if _, ok := a.indexes[index]; !ok {
+			return fmt.Errorf("collation of %s is not an index of %s", index, a.dataset)
+		}
+	}
+	return nil
+}
+
+//collate applies c to s
+func collate(s string, c Collation) string {
+	if c&CollateTrim != 0 {
+		s = strings.TrimSpace(s)
+	}
+
+	if c&CollateNormalize != 0 {
+		s = strings.Map(func(r rune) rune {
+			if unicode.Is(unicode.Mn, r) {
+				return -1
+			}
+			if base, ok := unaccent[r]; ok {
+				return base
+			}
+			return r
+		}, s)
+	}
+
+	if c&CollateFoldCase != 0 {
+		s = strings.ToLower(s)
+	}
+
+	return s
+}
+
+func collateValue(v interface{}, c Collation) interface{} {
+	if s, ok := v.(string); ok {
+		return collate(s, c)
+	}
+	return v
+}
+
+//collateQuery applies the collation of a dataset's index to a search value and
+//replaces it with its HMAC if the index is a BlindIndex
+func (g *gitdb) collateQuery(dataset string, index string, s string) string {
+	if c, ok := g.meta().Collations[dataset+"."+index]; ok {
+		s = collate(s, c)
+	}
+	if g.meta().Blind[dataset+"."+index] {
+		s = g.blindValue(s)
+	}
+	return s
+}
+
+//collateConds applies the collation of a dataset's index to the string values of conds
+func (g *gitdb) collateConds(dataset string, index string, conds []Condition) []Condition {
+	c, ok := g.meta().Collations[dataset+"."+index]
+	if !ok {
+		return conds
+	}
+
+	collated := make([]Condition, len(conds))
+	for i, cond := range conds {
+		collated[i] = cond
+		for _, bound := range []**orderedValue{&collated[i].lower, &collated[i].upper} {
+			if *bound != nil && (*bound).kind == orderString {
+				v := **bound
+				v.str = collate(v.str, c)
+				*bound = &v
+			}
+		}
+	}
+	return collated
+}
@@ -0,0 +1,121 @@
+package gitdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/bouggo/log"
+)
+
+//commitTrailer prefixes the machine-readable metadata line of every commit message
+const commitTrailer = "Gitdb-Change: "
+
+//CommitInfo describes a change committed to GitDB. It is the data passed to
+//Config.CommitTemplate and is embedded as a JSON trailer in every commit message
+type CommitInfo struct {
+	Operation string   `json:"operation"`
+	Datasets  []string `json:"datasets"`
+	IDs       []string `json:"ids"`
+	Host      string   `json:"host"`
+	//Fields are the fields changed by Update. It is empty for other writes
+	Fields []string `json:"fields,omitempty"`
+	//Message is the commit message GitDB would use without a template
+	Message string `json:"-"`
+}
+
+//Dataset returns the datasets affected by the commit separated by commas
+func (c *CommitInfo) Dataset() string {
+	return strings.Join(c.Datasets, ",")
+}
+
+func (c *CommitInfo) has(id string) bool {
+	for _, i := range c.IDs {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+//ParseCommitInfo extracts the CommitInfo embedded in a commit message
+func ParseCommitInfo(message string) (*CommitInfo, error) {
+	i := strings.LastIndex(message, commitTrailer)
+	if i < 0 {
+		return nil, errors.New("commit message has no " + strings.TrimSpace(commitTrailer) + " trailer")
+	}
+
+	trailer := message[i+len(commitTrailer):]
+	if j := strings.Index(trailer, "\n"); j >= 0 {
+		trailer = trailer[:j]
+	}
+
+	info := &CommitInfo{}
+	if err := json.Unmarshal([]byte(trailer), info); err != nil {
+		return nil, err
+	}
+	info.Message = strings.TrimSpace(message[:i])
+
+	return info, nil
+}
+
+func newCommitInfo(operation string, message string, ids []string) *CommitInfo {
+	info := &CommitInfo{Operation: operation, Message: message, IDs: []string{}, Datasets: []string{}}
+	info.Host, _ = os.Hostname()
+
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		info.IDs = append(info.IDs, id)
+
+		dataset := strings.Split(id, "/")[0]
+		if !seen[dataset+"/"] {
+			seen[dataset+"/"] = true
+			info.Datasets = append(info.Datasets, dataset)
+		}
+	}
+
+	return info
+}
+
+//setFields sets the Fields of the commit to fields in order without duplicates
+func (c *CommitInfo) setFields(fields []string) {
+	seen := map[string]bool{}
+	for _, field := range fields {
+		if !seen[field] {
+			seen[field] = true
+			c.Fields = append(c.Fields, field)
+		}
+	}
+}
+
+func parseCommitTemplate(tmpl string) (*template.Template, error) {
+	return template.New("commit").Parse(tmpl)
+}
+
+//commitMessage renders info using Config.CommitTemplate and appends the JSON trailer
+func (g *gitdb) commitMessage(info *CommitInfo) string {
+	msg := info.Message
+	if g.commitTemplate != nil {
+		var buf bytes.Buffer
+		if err := g.commitTemplate.Execute(&buf, info); err != nil {
+			log.Error("Config.CommitTemplate failed: " + err.Error())
+		} else {
+			msg = strings.TrimSpace(buf.String())
+		}
+	}
+
+	trailer, err := json.Marshal(info)
+	if err != nil {
+		log.Error(err.Error())
+		return msg
+	}
+
+	return msg + "\n\n" + commitTrailer + string(trailer)
+}
@@ -0,0 +1,76 @@
+package gitdb_test
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func headCommitMessage(t *testing.T) string {
+	out, err := exec.Command("git", "-C", filepath.Join(dbPath, "data"), "log", "-1", "--format=%B").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git log failed: %s", out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestCommitTemplate(t *testing.T) {
+	cfg := getConfig()
+	cfg.CommitTemplate = "{{.Operation}} {{.Dataset}}: {{.Message}}"
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	m := getTestMessageWithId(0)
+	if err := insert(m, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	msg := headCommitMessage(t)
+	want := "insert Message: Inserting " + gitdb.ID(m)
+	if !strings.HasPrefix(msg, want) {
+		t.Errorf("want: %s, got: %s", want, msg)
+	}
+
+	info, err := gitdb.ParseCommitInfo(msg)
+	if err != nil {
+		t.Fatalf("gitdb.ParseCommitInfo failed: %s", err)
+	}
+
+	if info.Operation != "insert" || info.Dataset() != "Message" {
+		t.Errorf("want: insert Message, got: %s %s", info.Operation, info.Dataset())
+	}
+
+	if len(info.IDs) != 1 || info.IDs[0] != gitdb.ID(m) {
+		t.Errorf("want: [%s], got: %v", gitdb.ID(m), info.IDs)
+	}
+}
+
+func TestCommitInfoTransaction(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	messages := []gitdb.Model{getTestMessage(), getTestMessage()}
+	if err := testDb.InsertMany(messages); err != nil {
+		t.Fatalf("testDb.InsertMany failed: %s", err)
+	}
+
+	info, err := gitdb.ParseCommitInfo(headCommitMessage(t))
+	if err != nil {
+		t.Fatalf("gitdb.ParseCommitInfo failed: %s", err)
+	}
+
+	if info.Operation != "transaction" || len(info.IDs) != 2 {
+		t.Errorf("want: transaction of 2 records, got: %s of %v", info.Operation, info.IDs)
+	}
+}
+
+func TestInvalidCommitTemplate(t *testing.T) {
+	cfg := getConfig()
+	cfg.CommitTemplate = "{{.Operation"
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("cfg.Validate should fail for invalid CommitTemplate")
+	}
+}
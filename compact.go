@@ -0,0 +1,146 @@
+package gitdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/bouggo/log"
+)
+
+//CompactReport describes the blocks of a dataset Compact removes or rewrites
+type CompactReport struct {
+	Dataset string
+	Blocks  int
+	//Removed is the number of blocks left without records by deletes
+	Removed int
+	//Rewritten is the number of blocks not laid out the way GitDB writes them e.g edited by hand
+	Rewritten int
+	//SizeBefore and SizeAfter are the sizes of the block files of the dataset in bytes
+	SizeBefore int64
+	SizeAfter  int64
+}
+
+//Reclaimed returns the number of bytes freed by compacting the dataset
+func (r *CompactReport) Reclaimed() int64 {
+	return r.SizeBefore - r.SizeAfter
+}
+
+//Compact removes the blocks of datasets that have no records left and rewrites the blocks that aren't laid
+//out the way GitDB writes them, committing the changes at once. Blocks that can't be read are left for Fsck.
+//Every dataset is compacted when none are given. With dryRun nothing is written and the reports describe
+//what Compact would do
+func (g *gitdb) Compact(dryRun bool, datasets ...string) ([]*CompactReport, error) {
+	if !dryRun {
+		if err := g.writable(); err != nil {
+			return nil, err
+		}
+		g.commitMu.Lock()
+		defer g.commitMu.Unlock()
+	}
+
+	if len(datasets) == 0 {
+		var err error
+		if datasets, err = g.datasetNames(); err != nil {
+			return nil, err
+		}
+	}
+
+	var reports []*CompactReport
+	changed := 0
+	for _, dataset := range datasets {
+		report, err := g.compactDataset(dataset, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+		changed += report.Removed + report.Rewritten
+	}
+	if dryRun || changed == 0 {
+		return reports, nil
+	}
+
+	if err := g.flushIndex(); err != nil {
+		return nil, err
+	}
+
+	g.commit.Add(1)
+	g.events <- newWriteEvent(fmt.Sprintf("Compacting %d blocks", changed), ".", true, nil, "compact")
+	g.waitForCommit()
+	log.Info(fmt.Sprintf("Compacted %d blocks", changed))
+
+	return reports, nil
+}
+
+//compactDataset removes the empty blocks of dataset and rewrites the blocks that aren't laid out the way
+//writeBlock writes them unless dryRun
+func (g *gitdb) compactDataset(dataset string, dryRun bool) (*CompactReport, error) {
+	report := &CompactReport{Dataset: dataset}
+	blockFiles, err := g.blockFiles(dataset)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return report, nil
+		}
+		return nil, err
+	}
+
+	for _, blockFile := range blockFiles {
+		report.Blocks++
+		data, err := g.readBlockFile(blockFile)
+		if err != nil {
+			return nil, err
+		}
+		report.SizeBefore += int64(len(data))
+
+		var raw map[string]string
+		if json.Unmarshal(data, &raw) != nil {
+			report.SizeAfter += int64(len(data))
+			continue
+		}
+
+		if len(raw) == 0 {
+			report.Removed++
+			if dryRun {
+				continue
+			}
+
+			release := g.blockLocks.lock(blockFile)
+			err := os.Remove(blockFile)
+			release()
+			if err != nil {
+				return nil, err
+			}
+			delete(g.loadedBlocks, blockFile)
+			g.refreshFiles([]string{g.relPath(blockFile)})
+			continue
+		}
+
+		//the layout writeBlock writes
+		compacted, err := json.MarshalIndent(raw, "", "\t")
+		if err != nil {
+			return nil, err
+		}
+		report.SizeAfter += int64(len(compacted))
+		if bytes.Equal(data, compacted) {
+			continue
+		}
+
+		report.Rewritten++
+		if dryRun {
+			continue
+		}
+
+		//read the block afresh as the cached one may predate an edit to the file
+		dataBlock := g.readBlock(blockFile)
+		if g.loadedBlocks != nil {
+			g.loadedBlocks[blockFile] = dataBlock
+		}
+		if err := g.writeBlock(blockFile, dataBlock); err != nil {
+			return nil, err
+		}
+		g.updateIndexes(dataBlock)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,104 @@
+package gitdb_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompact(t *testing.T) {
+	cfg := getConfig()
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	insert(getTestMessageWithId(1), true)
+	insert(getTestMessageWithId(2), true)
+
+	reports, err := testDb.Compact(true)
+	if err != nil {
+		t.Fatalf("Compact failed: %s", err)
+	}
+	if len(reports) != 1 || reports[0].Blocks != 1 || reports[0].Removed+reports[0].Rewritten != 0 || reports[0].Reclaimed() != 0 {
+		t.Fatalf("want: nothing to compact, got: %+v", reports)
+	}
+
+	//a block edited by hand without indentation
+	blockFile := filepath.Join(cfg.DbPath, "data", "Message", "b0.json")
+	data, _ := ioutil.ReadFile(blockFile)
+	var compacted bytes.Buffer
+	json.Compact(&compacted, data)
+	padded := append(compacted.Bytes(), bytes.Repeat([]byte("\n"), 4096)...)
+	ioutil.WriteFile(blockFile, padded, 0744)
+	gitOutput(t, "commit", "-qam", "Edit Message/b0 by hand")
+
+	reports, err = testDb.Compact(true, "Message")
+	if err != nil {
+		t.Fatalf("Compact failed: %s", err)
+	}
+	if len(reports) != 1 || reports[0].Rewritten != 1 || reports[0].Reclaimed() <= 0 {
+		t.Fatalf("want: 1 block to rewrite, got: %+v", reports)
+	}
+	if got, _ := ioutil.ReadFile(blockFile); !bytes.Equal(got, padded) {
+		t.Errorf("want: block untouched by a dry run")
+	}
+
+	if _, err := testDb.Compact(false, "Message"); err != nil {
+		t.Fatalf("Compact failed: %s", err)
+	}
+	if got, _ := ioutil.ReadFile(blockFile); !bytes.Equal(got, data) {
+		t.Errorf("want: block rewritten as GitDB writes it, got: %s", got)
+	}
+	m := &Message{}
+	if err := testDb.Get("Message/b0/2", m); err != nil || m.MessageId != 2 {
+		t.Errorf("want: Message/b0/2 readable after compacting, got: %v", err)
+	}
+	if got := gitOutput(t, "log", "-1", "--format=%s"); !strings.Contains(got, "Compacting 1 blocks") {
+		t.Errorf("want: compaction committed, got: %s", got)
+	}
+
+	testDb.Delete("Message/b0/1")
+	testDb.Delete("Message/b0/2")
+	reports, err = testDb.Compact(false)
+	if err != nil {
+		t.Fatalf("Compact failed: %s", err)
+	}
+	if len(reports) != 1 || reports[0].Removed != 1 {
+		t.Fatalf("want: 1 empty block removed, got: %+v", reports)
+	}
+	if _, err := os.Stat(blockFile); !os.IsNotExist(err) {
+		t.Errorf("want: empty block removed, got: %v", err)
+	}
+	if got := gitOutput(t, "status", "--porcelain"); len(got) > 0 {
+		t.Errorf("want: removal committed, got: %s", got)
+	}
+}
+
+func TestRepoObjects(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	insert(getTestMessageWithId(1), true)
+
+	objects, err := testDb.RepoObjects()
+	if err != nil {
+		t.Fatalf("RepoObjects failed: %s", err)
+	}
+	if objects.Loose == 0 || objects.Reclaimable() <= 0 {
+		t.Errorf("want: loose objects to pack, got: %+v", objects)
+	}
+
+	if _, err := testDb.Maintain(); err != nil {
+		t.Fatalf("Maintain failed: %s", err)
+	}
+	objects, err = testDb.RepoObjects()
+	if err != nil {
+		t.Fatalf("RepoObjects failed: %s", err)
+	}
+	if objects.Loose != 0 || objects.Packs == 0 {
+		t.Errorf("want: objects packed, got: %+v", objects)
+	}
+}
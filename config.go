@@ -2,7 +2,12 @@ package gitdb
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
+
+	"github.com/gogitdb/gitdb/v2/internal/crypto"
 )
 
 //Config represents configuration options for GitDB
@@ -11,17 +16,120 @@ type Config struct {
 	DbPath         string
 	OnlineRemote   string
 	EncryptionKey  string
-	SyncInterval   time.Duration
-	User           *User
-	Factory        func(string) Model
+	//PreviousKeys are keys records may still be encrypted with e.g while RotateKey is run
+	//dataset by dataset. Records are read with EncryptionKey and then each of these in turn
+	PreviousKeys []string
+	//KeyProvider supplies the encryption key of each dataset in place of EncryptionKey
+	KeyProvider KeyProvider
+	//Cipher encrypts records in place of the built-in encryption with EncryptionKey or KeyProvider
+	Cipher Cipher
+	//EncryptAtRest encrypts every record written, including those of models that don't implement ShouldEncrypt,
+	//and the index files kept beside the repository
+	EncryptAtRest bool
+	//IndexKey is the key values of blind indexes are hashed with. Keep it apart from EncryptionKey
+	IndexKey string
+	//IntegrityKey is the key of the HMACs of block files checked by Verify. Keep it apart from EncryptionKey
+	IntegrityKey string
+	//Roles holds the permissions of the roles connections made with WithRole are limited to
+	Roles map[string]Role
+	//UIRole limits the web UI to the datasets the role can read
+	UIRole string
+	//UIUsers are the users who can sign in to the web UI. The UI is open to anyone when empty
+	UIUsers []UIUser
+	//APIWrites lets the REST API of the web UI insert and delete records. Reads are served without it
+	APIWrites bool
+	//UIWrites lets users of the web UI create, edit and delete records with forms
+	UIWrites bool
+	//GraphQL serves a GraphQL endpoint at /graphql of the web UI over the models of RegisterModel and Config.Factory
+	GraphQL bool
+	//SecretPatterns are checked against every record before it is committed e.g DefaultSecretPatterns.
+	//A record that matches is not written and ErrSecretDetected is returned
+	SecretPatterns map[string]*regexp.Regexp
+	//WarnOnSecrets logs records that match SecretPatterns and writes them anyway
+	WarnOnSecrets bool
+	//Audit writes an AuditEntry to the _audit dataset for every insert, update and delete
+	Audit bool
+	//ChangeFeed appends every change to a dataset, made locally or pulled in by a sync, to a feed read with ChangesSince
+	ChangeFeed bool
+	//SigningKey is the path to an SSH private key or a GPG key ID used to sign every commit
+	SigningKey   string
+	SyncInterval time.Duration
+	SyncMode     SyncMode
+	//CloneDepth limits how many commits are fetched when cloning from OnlineRemote.
+	//Set to a negative value to clone the full history
+	CloneDepth int
+	//SparseDatasets limits the clone to the listed datasets. Writes to other datasets fail
+	SparseDatasets []string
+	//ObjectReads makes read-only connections read blocks straight from git objects
+	//so the database is cloned without a working tree
+	ObjectReads bool
+	//Mirrors are remotes pushed to on every sync in addition to OnlineRemote
+	Mirrors []Remote
+	//Webhooks are URLs POSTed a signed JSON payload for every insert, update, delete and sync
+	Webhooks []*Webhook
+	//LockBackend holds the locks taken with Lock in place of lock files committed to each dataset
+	LockBackend LockBackend
+	//SharedLocks holds locks in the _locks dataset and syncs with OnlineRemote on every Lock and
+	//Unlock so writers on other nodes see them. Ignored when LockBackend is set
+	SharedLocks bool
+	//LockTTL is how long a lock taken with Lock is held before it expires. Zero never expires
+	LockTTL time.Duration
+	//RenewLocks renews locks taken with Lock every half of LockTTL until Unlock or Close,
+	//so locks only expire when the process holding them dies. Not supported with SharedLocks
+	RenewLocks bool
+	//AsyncBatchSize is the most writes queued by InsertAsync that are committed together
+	AsyncBatchSize int
+	//AsyncFlushInterval is how long a write queued by InsertAsync waits for others to batch with
+	AsyncFlushInterval time.Duration
+	//Migrations holds the migrations of each dataset in order of version. Records are migrated as they are
+	//read and written, or all at once by RunMigrations
+	Migrations map[string][]Migration
+	//WriteConcurrency is how many datasets can be written at once. Writes to a dataset are always
+	//made one at a time. Zero means no limit
+	WriteConcurrency int
+	//LockStallTimeout logs locks taken with Lock that are held for longer than it, along with the stack
+	//that took them, and owners deadlocked on locks. Zero turns the watchdog and DiagnoseLocks off
+	LockStallTimeout time.Duration
+	//LockTimeout is how long Open waits for another process that has the database open to close it
+	//before failing with ErrDatabaseLocked. Zero fails straight away and a negative value waits forever
+	LockTimeout time.Duration
+	//AllowForcePush allows SquashHistory and Shred to force push rewritten branches and tags to OnlineRemote and Mirrors
+	AllowForcePush bool
+	//MaintenanceEvery schedules git gc to run when the database is idle. Zero disables it
+	MaintenanceEvery time.Duration
+	//FullText lists the string fields of each dataset to index for SearchText
+	//e.g map[string][]string{"Bookings": {"GuestName", "City"}}
+	FullText map[string][]string
+	//Analyzers sets the Analyzer of a FullText field keyed by dataset and field
+	//e.g map[string]Analyzer{"Products.SKU": KeywordAnalyzer{}}. Other fields use DefaultAnalyzer
+	Analyzers map[string]Analyzer
+	User      *User
+	Factory   func(string) Model
+	//Types holds the concrete models of polymorphic datasets, keyed by dataset then by the type name
+	//records are stored with e.g map[string]map[string]func() Model{"Payments": {"card": func() Model { return &CardPayment{} }}}
+	Types map[string]map[string]func() Model
+	//CommitTemplate is a text/template used to build commit messages from a CommitInfo
+	//e.g "{{.Operation}} {{.Dataset}} on {{.Host}}"
+	CommitTemplate string
 	EnableUI       bool
 	UIPort         int
+	//UIBasePath is the path the web UI is served under e.g /gitdb when UIHandler is mounted on a router of your own
+	UIBasePath string
+	//UIDir is a directory laid out like static/ whose files the web UI reads on every request in place of
+	//the embedded ones, so pages can be worked on without rebuilding. Files it doesn't have are embedded
+	UIDir string
+	//ShareKey is the key share links of ShareLink and the web UI are signed with. Share links are off without it
+	ShareKey string
 	//Mock is a hook for testing apps. If true will return a Mock DB connection
 	Mock bool
+
+	//readOnly is set by OpenReadOnly
+	readOnly bool
 }
 
 const defaultConnectionName = "default"
 const defaultSyncInterval = time.Second * 5
+const defaultCloneDepth = 10
 const defaultUserName = "ghost"
 const defaultUserEmail = "ghost@gitdb.local"
 const defaultUIPort = 4120
@@ -31,6 +139,7 @@ func NewConfig(dbPath string) *Config {
 	return &Config{
 		DbPath:         dbPath,
 		SyncInterval:   defaultSyncInterval,
+		CloneDepth:     defaultCloneDepth,
 		User:           NewUser(defaultUserName, defaultUserEmail),
 		ConnectionName: defaultConnectionName,
 		UIPort:         defaultUIPort,
@@ -43,5 +152,78 @@ func (c *Config) Validate() error {
 		return errors.New("Config.DbPath must be set")
 	}
 
+	if c.SyncInterval < 0 || c.SyncMode.interval < 0 {
+		return errors.New("Config.SyncInterval must be a positive duration")
+	}
+
+	if c.ObjectReads && !c.readOnly {
+		return errors.New("Config.ObjectReads is only supported by connections opened with OpenReadOnly")
+	}
+
+	if c.EncryptAtRest && len(c.EncryptionKey) <= 0 && c.KeyProvider == nil && c.Cipher == nil {
+		return errors.New("Config.EncryptAtRest requires Config.EncryptionKey, Config.KeyProvider or Config.Cipher")
+	}
+
+	//renewing locks in the _locks dataset would commit from a goroutine of its own
+	if c.RenewLocks && c.SharedLocks && c.LockBackend == nil {
+		return errors.New("Config.RenewLocks can not be used with Config.SharedLocks")
+	}
+
+	names := map[string]bool{onlineRemote: true}
+	for _, mirror := range c.Mirrors {
+		if len(c.OnlineRemote) <= 0 {
+			return errors.New("Config.Mirrors requires Config.OnlineRemote to be set")
+		}
+		if len(mirror.Name) <= 0 || len(mirror.URL) <= 0 {
+			return errors.New("Config.Mirrors must have a Name and URL")
+		}
+		if names[mirror.Name] {
+			return fmt.Errorf("Config.Mirrors has a duplicate remote name: %s", mirror.Name)
+		}
+		names[mirror.Name] = true
+	}
+
+	for _, hook := range c.Webhooks {
+		if hook == nil || !(strings.HasPrefix(hook.URL, "http://") || strings.HasPrefix(hook.URL, "https://")) {
+			return errors.New("Config.Webhooks must have an http or https URL")
+		}
+	}
+
+	if _, ok := c.Roles[c.UIRole]; len(c.UIRole) > 0 && !ok {
+		return fmt.Errorf("Config.UIRole %s is not one of Config.Roles", c.UIRole)
+	}
+
+	for _, user := range c.UIUsers {
+		if len(user.Name) <= 0 || len(user.Password) <= 0 {
+			return errors.New("Config.UIUsers must have a Name and Password")
+		}
+		if _, ok := c.Roles[user.Role]; len(user.Role) > 0 && !ok {
+			return fmt.Errorf("Config.UIUsers role %s is not one of Config.Roles", user.Role)
+		}
+	}
+
+	if len(c.CommitTemplate) > 0 {
+		if _, err := parseCommitTemplate(c.CommitTemplate); err != nil {
+			return fmt.Errorf("Config.CommitTemplate is invalid: %s", err)
+		}
+	}
+
+	for dataset, types := range c.Types {
+		for name, factory := range types {
+			if len(name) == 0 || factory == nil || factory() == nil {
+				return fmt.Errorf("Config.Types of %s must have a name and a func returning a Model", dataset)
+			}
+		}
+	}
+
+	if err := validateMigrations(c.Migrations); err != nil {
+		return fmt.Errorf("Config.Migrations is invalid: %s", err)
+	}
+
 	return nil
 }
+
+//keyring returns EncryptionKey and PreviousKeys as a single key for reading records
+func (c Config) keyring() string {
+	return crypto.Keyring(append([]string{c.EncryptionKey}, c.PreviousKeys...)...)
+}
@@ -0,0 +1,86 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//datasetMetaFile is the name of the metadata file kept in the directory of a dataset
+const datasetMetaFile = ".meta.json"
+
+//DatasetMeta describes a dataset. It is stored in the dataset's directory as .meta.json
+//and committed with its data so it is versioned and synced like records
+type DatasetMeta struct {
+	Description string `json:"description,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+	//Retention is how long records of the dataset are kept e.g "7 years". It is informational
+	Retention  string            `json:"retention,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+//SetDatasetMeta replaces the metadata of dataset with meta and commits it
+func (g *gitdb) SetDatasetMeta(dataset string, meta *DatasetMeta) error {
+	return g.setDatasetMeta(dataset, meta, nil)
+}
+
+func (g *gitdb) setDatasetMeta(dataset string, meta *DatasetMeta, user *User) error {
+	if err := g.writable(); err != nil {
+		return err
+	}
+
+	if len(dataset) == 0 || strings.Contains(dataset, "/") {
+		return errors.New("Invalid dataset name: " + dataset)
+	}
+
+	if meta == nil {
+		meta = &DatasetMeta{}
+	}
+
+	data, err := json.MarshalIndent(meta, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	g.commitMu.Lock()
+	defer g.commitMu.Unlock()
+
+	if err := os.MkdirAll(g.datasetPath(dataset), 0755); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(g.datasetPath(dataset), datasetMetaFile), data, 0644); err != nil {
+		return err
+	}
+
+	g.commit.Add(1)
+	g.events <- newWriteEvent("Updating metadata of "+dataset, g.datasetPath(dataset), g.autoCommit, user, "meta")
+	g.waitForCommit()
+
+	return nil
+}
+
+//GetDatasetMeta returns the metadata of dataset. A dataset without metadata has an empty DatasetMeta
+func (g *gitdb) GetDatasetMeta(dataset string) (*DatasetMeta, error) {
+	return readDatasetMeta(g.datasetPath(dataset))
+}
+
+//readDatasetMeta reads the metadata file in datasetDir
+func readDatasetMeta(datasetDir string) (*DatasetMeta, error) {
+	meta := &DatasetMeta{}
+	data, err := ioutil.ReadFile(filepath.Join(datasetDir, datasetMetaFile))
+	if os.IsNotExist(err) {
+		return meta, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
@@ -0,0 +1,67 @@
+package gitdb_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestDatasetMeta(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	meta, err := testDb.GetDatasetMeta("Message")
+	if err != nil {
+		t.Fatalf("testDb.GetDatasetMeta failed: %s", err)
+	}
+	if meta.Description != "" || meta.Owner != "" || len(meta.Properties) > 0 {
+		t.Errorf("want: empty metadata, got: %+v", meta)
+	}
+
+	meta = &gitdb.DatasetMeta{
+		Description: "Messages sent between guests and staff",
+		Owner:       "guest-services",
+		Retention:   "2 years",
+		Properties:  map[string]string{"pii": "true"},
+	}
+	if err := testDb.WithUser("Jane", "jane@example.com").SetDatasetMeta("Message", meta); err != nil {
+		t.Fatalf("testDb.SetDatasetMeta failed: %s", err)
+	}
+
+	if msg := headCommitMessage(t); !strings.HasPrefix(msg, "Updating metadata of Message") {
+		t.Errorf("want: commit Updating metadata of Message, got: %s", msg)
+	}
+
+	got, err := testDb.GetDatasetMeta("Message")
+	if err != nil {
+		t.Fatalf("testDb.GetDatasetMeta failed: %s", err)
+	}
+	if got.Description != meta.Description || got.Owner != meta.Owner || got.Retention != meta.Retention || got.Properties["pii"] != "true" {
+		t.Errorf("want: %+v, got: %+v", meta, got)
+	}
+
+	//the metadata file is not a block
+	m := getTestMessage()
+	if block := gitdb.AutoBlock(dbPath, m, gitdb.BlockByCount, 10); block != "b0" {
+		t.Errorf("want: b0, got: %s", block)
+	}
+
+	generateInserts(t, 3)
+	records, err := testDb.Fetch("Message")
+	if err != nil {
+		t.Fatalf("testDb.Fetch failed: %s", err)
+	}
+	if len(records) != 3 {
+		t.Errorf("want: 3 records, got: %d", len(records))
+	}
+}
+
+func TestDatasetMetaInvalidName(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	if err := testDb.SetDatasetMeta("Message/b0", &gitdb.DatasetMeta{}); err == nil {
+		t.Error("SetDatasetMeta should fail for a name with /")
+	}
+}
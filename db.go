@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/bouggo/log"
@@ -37,15 +38,36 @@ type SearchParam struct {
 type GitDb interface {
 	Close() error
 	Insert(m Model) error
+	InsertRevision(m Model, revision int) error
+	Update(m Model) error
+	Revision(id string) (int, error)
 	InsertMany(m []Model) error
+	InsertAsync(m Model, done func(err error))
+	Flush() error
 	Get(id string, m Model) error
+	GetModel(id string) (Model, error)
 	Exists(id string) error
 	Fetch(dataset string) ([]*db.Record, error)
+	FetchStrict(dataset string, m Model) ([]*db.Record, error)
+	FetchModels(dataset string) ([]Model, error)
+	Decode(record *db.Record) (Model, error)
+	SchemaDiff(dataset string, m Model) (*SchemaDrift, error)
 	Search(dataDir string, searchParams []*SearchParam, searchMode SearchMode) ([]*db.Record, error)
+	SearchRange(dataset string, index string, from string, to string) ([]*db.Record, error)
+	SearchText(dataset string, query string) ([]*db.Record, error)
+	SearchWhere(dataset string, index string, conds ...Condition) ([]*db.Record, error)
+	Explain(q *Query) (*Plan, error)
+	SearchNear(dataset string, lat float64, lng float64, radiusKm float64) ([]*db.Record, error)
+	CheckIntegrity() ([]*DanglingRef, error)
+	SearchIDs(dataset string, index string, value string) ([]string, error)
+	IndexValues(dataset string, index string, ids ...string) (map[string]interface{}, error)
 	Delete(id string) error
 	DeleteOrFail(id string) error
 	Lock(m Model) error
+	TryLock(m Model) (bool, error)
+	LockWait(m Model, timeout time.Duration) error
 	Unlock(m Model) error
+	DiagnoseLocks() LockDiagnostics
 	Upload() *Upload
 	Migrate(from Model, to Model) error
 	GetMails() []*mail
@@ -53,40 +75,133 @@ type GitDb interface {
 	GetLastCommitTime() (time.Time, error)
 	SetUser(user *User) error
 	Config() Config
+	RevertRecord(id string, commit string) error
+	Branch(name string) error
+	SwitchBranch(name string) error
+	MergeBranch(name string) error
+	CurrentBranch() string
+	Sync(opts ...SyncOption) error
+	Remotes() []RemoteStatus
+	PendingPushes() int
+	PendingWrites() map[string]int
+	VerifyHistory(dataset string) ([]UnverifiedCommit, error)
+	Verify() ([]Tampering, error)
+	TrustBlocks() error
+	Fsck(fix bool) (*FsckReport, error)
+	Stats() (*Stats, error)
+	WithUser(name string, email string) GitDb
+	WithRole(role string) GitDb
+	History(id string) ([]*Change, error)
+	OnChange(dataset string, handler ChangeHandler)
+	Subscribe(kinds EventKind, handler EventHandler)
+	ChangesSince(dataset string, seq int64) ([]*ChangeEntry, error)
+	Maintain() (*MaintenanceReport, error)
+	RepoObjects() (*RepoObjects, error)
+	Compact(dryRun bool, datasets ...string) ([]*CompactReport, error)
+	SquashHistory(before time.Time) error
+	Shred(id string) error
+	Diff(dataset string, from string, to string) ([]*RecordDiff, error)
+	RebuildIndex(dataset string) error
+	TagRelease(name string) error
+	ListReleases() ([]*Release, error)
+	FetchAtTag(dataset string, tag string) ([]*db.Record, error)
+	ShareLink(dataset string, commit string, ttl time.Duration) (string, error)
+	CreateView(name string, q *Query) error
+	RotateKey(dataset string, oldKey string, newKey string) error
+	RunMigrations(opts ...MigrationOption) error
+	UpgradeFormat() error
+	SetDatasetMeta(dataset string, meta *DatasetMeta) error
+	GetDatasetMeta(dataset string) (*DatasetMeta, error)
+	ExportJSONSchema(dataset string) ([]byte, error)
 }
 
 type gitdb struct {
 	mu       sync.Mutex
 	writeMu  sync.Mutex
-	commit   sync.WaitGroup
-	locked   chan bool
-	shutdown chan bool
-	events   chan *dbEvent
-
-	config    Config
-	gitDriver dbDriver
+	uniqueMu sync.Mutex
+	//commitMu keeps record writes, which hold it for reading, out while bulk changes rewrite blocks
+	commitMu sync.RWMutex
+	//cacheMu serializes changes to the cached blocks and indexes shared by the writes to every dataset
+	cacheMu sync.Mutex
+	keysMu  sync.Mutex
+	//manifestMu guards manifest
+	manifestMu sync.Mutex
+	commit     sync.WaitGroup
+	indexing   sync.WaitGroup
+	locked     chan bool
+	shutdown   chan bool
+	events     chan *dbEvent
+
+	config         Config
+	gitDriver      dbDriver
+	commitTemplate *template.Template
+
+	//lastActive is the unix nano time of the last commit
+	lastActive int64
 
 	autoCommit   bool
-	indexUpdated bool
-	loopStarted  bool
-	closed       bool
+	dirtyIndexes map[string]bool
+	fts          map[string]*ftsIndex
+	ordered      map[string][]orderedEntry
+	schemaMeta   *schemaMeta
+	blooms       map[string]*bloomFilter
+	//keys caches the keys of Config.KeyProvider by dataset
+	keys map[string]string
+	//manifest holds the HMACs of block files checked by Verify
+	manifest map[string]string
+	//dbLock is the open lock file that keeps other processes from opening the database
+	dbLock      *os.File
+	loopStarted bool
+	closed      bool
 
 	indexCache   gdbIndexCache
 	loadedBlocks map[string]*db.Block
 
+	pushQueue    pushQueue
+	writeQueue   writeQueue
+	blockLocks   blockLocks
+	reads        readFlight
+	sequences    sequences
+	remoteStatus remoteStatuses
+
+	//async is the queue of the background writer of InsertAsync
+	asyncMu      sync.Mutex
+	async        chan *asyncWrite
+	asyncStopped bool
+	//asyncSending counts the writes on their way to async, see stopAsyncWriter
+	asyncSending sync.WaitGroup
+
+	//lockMu makes checking and taking locks one step, lockWatch tracks them for DiagnoseLocks
+	lockMu    sync.Mutex
+	lockWatch lockWatch
+
+	//leases holds the renewal of each set of locks renewed with Config.RenewLocks
+	leaseMu sync.Mutex
+	leases  map[string]*lease
+	//lockToken tells apart the locks of this connection from those of other connections of the same user
+	lockToken string
+
+	watchMu  sync.RWMutex
+	watchers map[string][]ChangeHandler
+	//subscriptions are the handlers registered with Subscribe
+	subscriptions []subscription
+	changes       changeFeed
+
 	mails []*mail
+
+	//typeNames caches the name of each model type in Config.Types by dataset
+	typeNames sync.Map
 }
 
 func newConnection() *gitdb {
 	//autocommit defaults to true
-	db := &gitdb{autoCommit: true, indexCache: make(gdbIndexCache)}
+	db := &gitdb{autoCommit: true, indexCache: make(gdbIndexCache), lockToken: newLockToken()}
 	//initialize channels
 	db.events = make(chan *dbEvent, 1)
 	db.locked = make(chan bool, 1)
-	//initialize shutdown channel with capacity 3
-	//to represent the event loop, sync clock, UI server
-	//goroutines
-	db.shutdown = make(chan bool, 3)
+	//shutdown is closed to stop the event loop, sync clock,
+	//maintenance clock and UI server goroutines
+	db.shutdown = make(chan bool)
 
 	return db
 }
@@ -105,19 +220,22 @@ func (g *gitdb) Close() error {
 		return nil
 	}
 
-	//flush index to disk
+	g.stopAsyncWriter()
+
+	//wait for index to finish building before flushing it to disk
+	g.indexing.Wait()
 	if err := g.flushIndex(); err != nil {
 		return err
 	}
 
-	//send shutdown event to event loop and sync clock
-	g.shutdown <- true
-	g.shutdown <- true
+	//send shutdown event to all goroutines
+	close(g.shutdown)
 	g.waitForCommit()
 
 	//remove cached connection
-	delete(conns, g.config.ConnectionName)
+	setConn(g.config.ConnectionName, nil)
 	g.closed = true
+	g.releaseDbLock()
 
 	return nil
 }
@@ -132,14 +250,35 @@ func (g *gitdb) configure(cfg Config) {
 		cfg.SyncInterval = defaultSyncInterval
 	}
 
+	//SyncImmediate still pulls on SyncInterval
+	if int64(cfg.SyncMode.interval) == 0 {
+		cfg.SyncMode.interval = cfg.SyncInterval
+	}
+
+	if cfg.CloneDepth == 0 {
+		cfg.CloneDepth = defaultCloneDepth
+	}
+
 	if cfg.UIPort == 0 {
 		cfg.UIPort = defaultUIPort
 	}
 
+	if cfg.AsyncBatchSize <= 0 {
+		cfg.AsyncBatchSize = defaultAsyncBatchSize
+	}
+
+	if cfg.AsyncFlushInterval <= 0 {
+		cfg.AsyncFlushInterval = defaultAsyncFlushInterval
+	}
+
 	if g.gitDriver == nil {
 		g.gitDriver = &gitBinary{}
 	}
 
+	if len(cfg.CommitTemplate) > 0 {
+		g.commitTemplate, _ = parseCommitTemplate(cfg.CommitTemplate)
+	}
+
 	g.config = cfg
 
 	g.gitDriver.configure(g)
@@ -147,6 +286,12 @@ func (g *gitdb) configure(cfg Config) {
 
 //Migrate model from one schema to another
 func (g *gitdb) Migrate(from Model, to Model) error {
+	if err := g.writable(); err != nil {
+		return err
+	}
+	if from.GetSchema().name() == auditDataset {
+		return ErrAuditReadOnly
+	}
 
 	//TODO add test case for this
 	//schema has not changed
@@ -154,7 +299,7 @@ func (g *gitdb) Migrate(from Model, to Model) error {
 		return errors.New("Invalid migration - no change found in schema")
 	}*/
 
-	block := db.NewEmptyBlock(g.config.EncryptionKey)
+	block := db.NewEmptyBlock(g.keyring(from.GetSchema().name()))
 	if err := g.dofetch(from.GetSchema().name(), block); err != nil {
 		return err
 	}
@@ -184,10 +329,13 @@ func (g *gitdb) Migrate(from Model, to Model) error {
 	//remove all old block files
 	for _, blockFilePath := range oldBlocks {
 		log.Info("Removing old block: " + blockFilePath)
+		release := g.blockLocks.lock(blockFilePath)
 		err := os.Remove(blockFilePath)
+		release()
 		if err != nil {
 			return err
 		}
+		g.refreshFiles([]string{g.relPath(blockFilePath)})
 	}
 
 	return nil
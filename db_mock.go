@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
@@ -17,6 +18,7 @@ type mockdb struct {
 	data   map[string]Model
 	index  map[string]map[string]interface{}
 	locks  map[string]bool
+	branch string
 }
 
 type mocktransaction struct {
@@ -41,9 +43,10 @@ func (t *mocktransaction) AddOperation(o operation) {
 
 func newMockConnection() *mockdb {
 	db := &mockdb{
-		data:  make(map[string]Model),
-		index: make(map[string]map[string]interface{}),
-		locks: make(map[string]bool),
+		data:   make(map[string]Model),
+		index:  make(map[string]map[string]interface{}),
+		locks:  make(map[string]bool),
+		branch: "master",
 	}
 	return db
 }
@@ -53,10 +56,28 @@ func (g *mockdb) Close() error {
 	return nil
 }
 
+func (g *mockdb) InsertRevision(m Model, revision int) error {
+	//todo
+	return g.Insert(m)
+}
+
+func (g *mockdb) Update(m Model) error {
+	if _, exists := g.data[ID(m)]; !exists {
+		dataset, _, _, _ := ParseID(ID(m))
+		return fmt.Errorf("Record %s not found in %s", ID(m), dataset)
+	}
+	return g.Insert(m)
+}
+
+func (g *mockdb) Revision(id string) (int, error) {
+	//todo
+	return 0, nil
+}
+
 func (g *mockdb) Insert(m Model) error {
 	g.data[ID(m)] = m
 
-	for name, value := range m.GetSchema().indexes {
+	for name, value := range schemaOf(m).indexes {
 		key := m.GetSchema().dataset + "." + name
 		if _, ok := g.index[key]; !ok {
 			g.index[key] = make(map[string]interface{})
@@ -74,6 +95,17 @@ func (g *mockdb) InsertMany(m []Model) error {
 	return nil
 }
 
+func (g *mockdb) InsertAsync(m Model, done func(err error)) {
+	err := g.Insert(m)
+	if done != nil {
+		done(err)
+	}
+}
+
+func (g *mockdb) Flush() error {
+	return nil
+}
+
 func (g *mockdb) Get(id string, result Model) error {
 
 	if reflect.ValueOf(result).Kind() != reflect.Ptr || reflect.ValueOf(result).IsNil() {
@@ -95,6 +127,11 @@ func (g *mockdb) Get(id string, result Model) error {
 		}
 		json.Unmarshal(b, result)
 
+		if hook, ok := result.(AfterFinder); ok {
+			if err := hook.AfterFind(); err != nil {
+				return fmt.Errorf("Model.AfterFind failed: %s", err)
+			}
+		}
 		return nil
 	}
 
@@ -129,6 +166,44 @@ func (g *mockdb) Fetch(dataset string) ([]*db.Record, error) {
 	return result, nil
 }
 
+//FetchStrict never reports drift as the mock stores models as they are
+func (g *mockdb) FetchStrict(dataset string, m Model) ([]*db.Record, error) {
+	return g.Fetch(dataset)
+}
+
+func (g *mockdb) FetchModels(dataset string) ([]Model, error) {
+	models := []Model{}
+	for id, model := range g.data {
+		if ds, _, _, err := ParseID(id); err == nil && ds == dataset {
+			models = append(models, model)
+		}
+	}
+	return models, nil
+}
+
+func (g *mockdb) GetModel(id string) (Model, error) {
+	if model, ok := g.data[id]; ok {
+		return model, nil
+	}
+	dataset, _, _, _ := ParseID(id)
+	return nil, fmt.Errorf("Record %s not found in %s", id, dataset)
+}
+
+func (g *mockdb) Decode(record *db.Record) (Model, error) {
+	if model, ok := g.data[record.ID()]; ok {
+		return model, nil
+	}
+	return nil, fmt.Errorf("Record %s not found", record.ID())
+}
+
+func (g *mockdb) SchemaDiff(dataset string, m Model) (*SchemaDrift, error) {
+	records, err := g.Fetch(dataset)
+	if err != nil {
+		return nil, err
+	}
+	return &SchemaDrift{Dataset: dataset, Records: len(records), Unknown: map[string]int{}, Missing: map[string]int{}}, nil
+}
+
 func (g *mockdb) Search(dataset string, searchParams []*SearchParam, searchMode SearchMode) ([]*db.Record, error) {
 	result := []*db.Record{}
 	for _, searchParam := range searchParams {
@@ -158,6 +233,89 @@ func (g *mockdb) Search(dataset string, searchParams []*SearchParam, searchMode
 	return result, nil
 }
 
+func (g *mockdb) SearchRange(dataset string, index string, from string, to string) ([]*db.Record, error) {
+	result := []*db.Record{}
+	key := dataset + "." + index
+	for recordID, value := range g.index[key] {
+		dbValue := fmt.Sprint(value)
+		if dbValue >= from && dbValue <= to {
+			result = append(result, db.ConvertModel(recordID, g.data[recordID]))
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return fmt.Sprint(g.index[key][result[i].ID()]) < fmt.Sprint(g.index[key][result[j].ID()])
+	})
+
+	return result, nil
+}
+
+func (g *mockdb) SearchWhere(dataset string, index string, conds ...Condition) ([]*db.Record, error) {
+	result := []*db.Record{}
+	key := dataset + "." + index
+	for recordID, value := range g.index[key] {
+		if matches(toOrdered(value), conds) {
+			result = append(result, db.ConvertModel(recordID, g.data[recordID]))
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return toOrdered(g.index[key][result[i].ID()]).compare(toOrdered(g.index[key][result[j].ID()])) < 0
+	})
+
+	return result, nil
+}
+
+func (g *mockdb) Explain(q *Query) (*Plan, error) {
+	//todo
+	return &Plan{Dataset: q.Dataset}, nil
+}
+
+func (g *mockdb) SearchNear(dataset string, lat float64, lng float64, radiusKm float64) ([]*db.Record, error) {
+	//todo
+	return []*db.Record{}, nil
+}
+
+func (g *mockdb) CheckIntegrity() ([]*DanglingRef, error) {
+	//todo
+	return nil, nil
+}
+
+func (g *mockdb) SearchIDs(dataset string, index string, value string) ([]string, error) {
+	ids := []string{}
+	for recordID, v := range g.index[dataset+"."+index] {
+		if strings.ToLower(fmt.Sprint(v)) == strings.ToLower(value) {
+			ids = append(ids, recordID)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (g *mockdb) IndexValues(dataset string, index string, ids ...string) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	for recordID, v := range g.index[dataset+"."+index] {
+		values[recordID] = v
+	}
+
+	if len(ids) == 0 {
+		return values, nil
+	}
+
+	selected := map[string]interface{}{}
+	for _, id := range ids {
+		if v, ok := values[id]; ok {
+			selected[id] = v
+		}
+	}
+	return selected, nil
+}
+
+func (g *mockdb) SearchText(dataset string, query string) ([]*db.Record, error) {
+	//todo
+	return []*db.Record{}, nil
+}
+
 func (g *mockdb) Delete(id string) error {
 	delete(g.data, id)
 	return nil
@@ -190,6 +348,16 @@ func (g *mockdb) Lock(m Model) error {
 	return nil
 }
 
+func (g *mockdb) TryLock(m Model) (bool, error) {
+	//todo
+	return true, g.Lock(m)
+}
+
+func (g *mockdb) LockWait(m Model, timeout time.Duration) error {
+	//todo
+	return g.Lock(m)
+}
+
 func (g *mockdb) Unlock(m Model) error {
 	if !m.IsLockable() {
 		return errors.New("Model is not lockable")
@@ -202,6 +370,10 @@ func (g *mockdb) Unlock(m Model) error {
 	return nil
 }
 
+func (g *mockdb) DiagnoseLocks() LockDiagnostics {
+	return LockDiagnostics{}
+}
+
 func (g *mockdb) Upload() *Upload {
 	//todo
 	return nil
@@ -248,6 +420,183 @@ func (g *mockdb) Migrate(from Model, to Model) error {
 	return nil
 }
 
+func (g *mockdb) RevertRecord(id string, commit string) error {
+	//mockdb does not keep history so there's nothing to revert to
+	return g.Exists(id)
+}
+
+func (g *mockdb) Branch(name string) error {
+	if len(name) == 0 {
+		return errors.New("Invalid branch name")
+	}
+	return nil
+}
+
+func (g *mockdb) SwitchBranch(name string) error {
+	g.branch = name
+	return nil
+}
+
+func (g *mockdb) MergeBranch(name string) error {
+	//todo
+	return nil
+}
+
+func (g *mockdb) CurrentBranch() string {
+	return g.branch
+}
+
+func (g *mockdb) Remotes() []RemoteStatus {
+	//todo
+	return nil
+}
+
+func (g *mockdb) Sync(opts ...SyncOption) error {
+	return nil
+}
+
+func (g *mockdb) PendingPushes() int {
+	return 0
+}
+
+func (g *mockdb) PendingWrites() map[string]int {
+	return map[string]int{}
+}
+
+func (g *mockdb) VerifyHistory(dataset string) ([]UnverifiedCommit, error) {
+	return nil, nil
+}
+
+func (g *mockdb) Verify() ([]Tampering, error) {
+	return nil, nil
+}
+
+func (g *mockdb) TrustBlocks() error {
+	return nil
+}
+
+func (g *mockdb) Fsck(fix bool) (*FsckReport, error) {
+	return &FsckReport{}, nil
+}
+
+func (g *mockdb) Stats() (*Stats, error) {
+	return &Stats{}, nil
+}
+
+func (g *mockdb) WithUser(name string, email string) GitDb {
+	//todo
+	return g
+}
+
+func (g *mockdb) WithRole(role string) GitDb {
+	//todo
+	return g
+}
+
+func (g *mockdb) History(id string) ([]*Change, error) {
+	//todo
+	return nil, nil
+}
+
+func (g *mockdb) Subscribe(kinds EventKind, handler EventHandler) {
+	//todo
+}
+
+func (g *mockdb) ChangesSince(dataset string, seq int64) ([]*ChangeEntry, error) {
+	//todo
+	return []*ChangeEntry{}, nil
+}
+
+func (g *mockdb) OnChange(dataset string, handler ChangeHandler) {
+	//todo
+}
+
+func (g *mockdb) Maintain() (*MaintenanceReport, error) {
+	return &MaintenanceReport{}, nil
+}
+
+func (g *mockdb) RepoObjects() (*RepoObjects, error) {
+	return &RepoObjects{}, nil
+}
+
+func (g *mockdb) Compact(dryRun bool, datasets ...string) ([]*CompactReport, error) {
+	return nil, nil
+}
+
+func (g *mockdb) SquashHistory(before time.Time) error {
+	//todo
+	return nil
+}
+
+func (g *mockdb) Shred(id string) error {
+	//todo
+	return nil
+}
+
+func (g *mockdb) Diff(dataset string, from string, to string) ([]*RecordDiff, error) {
+	//todo
+	return nil, nil
+}
+
+func (g *mockdb) RebuildIndex(dataset string) error {
+	//todo
+	return nil
+}
+
+func (g *mockdb) TagRelease(name string) error {
+	//todo
+	return nil
+}
+
+func (g *mockdb) ListReleases() ([]*Release, error) {
+	//todo
+	return nil, nil
+}
+
+func (g *mockdb) FetchAtTag(dataset string, tag string) ([]*db.Record, error) {
+	//todo
+	return nil, nil
+}
+
+func (g *mockdb) ShareLink(dataset string, commit string, ttl time.Duration) (string, error) {
+	//todo
+	return "", nil
+}
+
+func (g *mockdb) CreateView(name string, q *Query) error {
+	//todo
+	return nil
+}
+
+func (g *mockdb) RotateKey(dataset string, oldKey string, newKey string) error {
+	//todo
+	return nil
+}
+
+func (g *mockdb) RunMigrations(opts ...MigrationOption) error {
+	//todo
+	return nil
+}
+
+func (g *mockdb) UpgradeFormat() error {
+	//todo
+	return nil
+}
+
+func (g *mockdb) SetDatasetMeta(dataset string, meta *DatasetMeta) error {
+	//todo
+	return nil
+}
+
+func (g *mockdb) GetDatasetMeta(dataset string) (*DatasetMeta, error) {
+	//todo
+	return &DatasetMeta{}, nil
+}
+
+func (g *mockdb) ExportJSONSchema(dataset string) ([]byte, error) {
+	return g.config.jsonSchema(dataset)
+}
+
 func (g *mockdb) Config() Config {
 	return g.config
 }
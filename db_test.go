@@ -3,6 +3,7 @@ package gitdb_test
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/gogitdb/gitdb/v2"
 )
@@ -27,8 +28,9 @@ func TestNewConfig(t *testing.T) {
 	cfg := gitdb.NewConfig(dbPath)
 	db, err := gitdb.Open(cfg)
 	if err != nil {
-		t.Errorf("gitdb.Open failed: %s", err)
+		t.Fatalf("gitdb.Open failed: %s", err)
 	}
+	defer db.Close()
 
 	if reflect.DeepEqual(db.Config(), cfg) {
 		t.Errorf("Config does not match. want: %v, got: %v", cfg, db.Config())
@@ -40,4 +42,16 @@ func TestConfigValidate(t *testing.T) {
 	if err := cfg.Validate(); err == nil {
 		t.Errorf("cfg.Validate should fail if DbPath is %s", cfg.DbPath)
 	}
+
+	cfg = gitdb.NewConfig(dbPath)
+	cfg.SyncInterval = -time.Second
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("cfg.Validate should fail if SyncInterval is %s", cfg.SyncInterval)
+	}
+
+	cfg = gitdb.NewConfig(dbPath)
+	cfg.SyncMode = gitdb.SyncInterval(-time.Second)
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("cfg.Validate should fail for a negative SyncMode interval")
+	}
 }
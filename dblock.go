@@ -0,0 +1,85 @@
+package gitdb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bouggo/log"
+)
+
+//ErrDatabaseLocked is returned by Open when another process has the database open
+var ErrDatabaseLocked = errors.New("Database is locked by another process")
+
+//errLockHeld is returned by openLockFile when the lock is held by another open file
+var errLockHeld = errors.New("lock held")
+
+const dbLockRetryInterval = time.Millisecond * 50
+const worktreeLockRetryInterval = time.Millisecond * 5
+
+func (g *gitdb) dbLockFilePath() string {
+	return filepath.Join(g.absDbPath(), g.internalDirName(), "db.lock")
+}
+
+func (g *gitdb) worktreeLockFilePath() string {
+	return filepath.Join(g.absDbPath(), g.internalDirName(), "worktree.lock")
+}
+
+//acquireDbLock takes the OS advisory lock that keeps other processes from opening the database for
+//writing, waiting up to Config.LockTimeout for a process that has it open to close it. Connections opened
+//with OpenReadOnly don't take it so they can be opened alongside the writer, see lockWorktree
+func (g *gitdb) acquireDbLock() error {
+	if err := os.MkdirAll(filepath.Dir(g.dbLockFilePath()), 0755); err != nil {
+		return err
+	}
+	if g.config.readOnly {
+		return nil
+	}
+
+	deadline := time.Now().Add(g.config.LockTimeout)
+	for {
+		f, err := openLockFile(g.dbLockFilePath(), false)
+		if err == nil {
+			g.dbLock = f
+			return nil
+		}
+		if err != errLockHeld {
+			return err
+		}
+		if g.config.LockTimeout >= 0 && !time.Now().Before(deadline) {
+			return ErrDatabaseLocked
+		}
+		time.Sleep(dbLockRetryInterval)
+	}
+}
+
+//lockWorktree waits for the OS advisory lock on the working tree of the database and returns the func
+//releasing it. Writers take it exclusively while they write block files or git rewrites them, read-only
+//connections take it shared while they read block files so they never see a block half written
+func (g *gitdb) lockWorktree(shared bool) func() {
+	for {
+		f, err := openLockFile(g.worktreeLockFilePath(), shared)
+		if err == nil {
+			return func() { f.Close() }
+		}
+		if err != errLockHeld {
+			//e.g the database is on a read-only file system, which no one can be writing to
+			log.Error("Failed to lock the working tree: " + err.Error())
+			return func() {}
+		}
+		time.Sleep(worktreeLockRetryInterval)
+	}
+}
+
+//releaseDbLock lets other processes open the database
+func (g *gitdb) releaseDbLock() {
+	if g.dbLock == nil {
+		return
+	}
+
+	//remove the lock file while it is still locked so nothing is left behind
+	os.Remove(g.dbLockFilePath())
+	g.dbLock.Close()
+	g.dbLock = nil
+}
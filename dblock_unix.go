@@ -0,0 +1,44 @@
+//go:build !windows
+// +build !windows
+
+package gitdb
+
+import (
+	"os"
+	"syscall"
+)
+
+//openLockFile opens path and takes an exclusive flock on it, or a shared one if shared. The lock is released
+//when the file is closed or the process exits. Returns errLockHeld if another open file holds a conflicting lock
+func openLockFile(path string, shared bool) (*os.File, error) {
+	how := syscall.LOCK_EX
+	if shared {
+		how = syscall.LOCK_SH
+	}
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB); err != nil {
+			f.Close()
+			if err == syscall.EWOULDBLOCK {
+				return nil, errLockHeld
+			}
+			return nil, err
+		}
+
+		//the holder removes the file before releasing the lock so
+		//try again if the file locked is no longer the one at path
+		locked, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if current, err := os.Stat(path); err == nil && os.SameFile(locked, current) {
+			return f, nil
+		}
+		f.Close()
+	}
+}
@@ -0,0 +1,34 @@
+//go:build windows
+// +build windows
+
+package gitdb
+
+import (
+	"os"
+	"syscall"
+)
+
+const errorSharingViolation syscall.Errno = 32
+
+//openLockFile opens path without sharing it so no other process can open it until the file is closed
+//or the process exits. If shared, path is opened for reading and shared with other readers only.
+//Returns errLockHeld if path is already open in a way that conflicts
+func openLockFile(path string, shared bool) (*os.File, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	access, mode := uint32(syscall.GENERIC_READ|syscall.GENERIC_WRITE), uint32(0)
+	if shared {
+		access, mode = syscall.GENERIC_READ, syscall.FILE_SHARE_READ
+	}
+	h, err := syscall.CreateFile(name, access, mode, nil, syscall.OPEN_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if err == errorSharingViolation {
+		return nil, errLockHeld
+	}
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(h), path), nil
+}
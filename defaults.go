@@ -0,0 +1,108 @@
+package gitdb
+
+import (
+	"fmt"
+	"reflect"
+)
+
+//fieldValue is a default or computed value of a field of a model
+type fieldValue struct {
+	field   string
+	value   interface{}
+	compute func() interface{}
+}
+
+//Default sets field of the model to value on insert when it is empty. The value is stored with the
+//record so indexes and searches see it
+func (a *Schema) Default(field string, value interface{}) *Schema {
+	a.defaults = append(a.defaults, fieldValue{field: field, value: value})
+	return a
+}
+
+//Computed sets field of the model to what compute returns on every insert, after defaults are set.
+//compute is usually a closure over the model e.g
+//
+//	Computed("NumberOfNights", func() interface{} { return int(b.CheckOut.Sub(b.CheckIn).Hours() / 24) })
+func (a *Schema) Computed(field string, compute func() interface{}) *Schema {
+	a.computed = append(a.computed, fieldValue{field: field, compute: compute})
+	return a
+}
+
+//applyDefaults sets the default and computed fields of schema on m. Computed fields are evaluated
+//one at a time so a computed field can use the ones before it
+func applyDefaults(schema *Schema, m Model) error {
+	if len(schema.defaults) == 0 && len(schema.computed) == 0 {
+		return nil
+	}
+
+	v, err := modelStruct(schema, m)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range schema.defaults {
+		field, err := modelField(schema, v, d.field)
+		if err != nil {
+			return err
+		}
+		if field.IsZero() {
+			if err := setField(schema, field, d.field, d.value); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, c := range schema.computed {
+		field, err := modelField(schema, v, c.field)
+		if err != nil {
+			return err
+		}
+		if err := setField(schema, field, c.field, c.compute()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//modelStruct returns the struct m points to so its fields can be set
+func modelStruct(schema *Schema, m Model) (reflect.Value, error) {
+	v := reflect.ValueOf(m)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || !v.CanAddr() {
+		return v, fmt.Errorf("fields of %s can only be set through a pointer to a struct", schema.name())
+	}
+	return v, nil
+}
+
+func modelField(schema *Schema, v reflect.Value, name string) (reflect.Value, error) {
+	field := v.FieldByName(name)
+	if !field.IsValid() || !field.CanSet() {
+		return field, fmt.Errorf("%s is not a field of %s", name, schema.name())
+	}
+	return field, nil
+}
+
+//setField sets field to value, converting between number types
+func setField(schema *Schema, field reflect.Value, name string, value interface{}) error {
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch {
+	case rv.Type().AssignableTo(field.Type()):
+		field.Set(rv)
+	case isNumber(rv.Kind()) && isNumber(field.Kind()):
+		field.Set(rv.Convert(field.Type()))
+	default:
+		return fmt.Errorf("%s.%s is %s and can't be set to %v", schema.name(), name, field.Type(), value)
+	}
+	return nil
+}
+
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
@@ -0,0 +1,57 @@
+package gitdb_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+type Reservation struct {
+	testModel
+	ReservationId  int
+	Status         string
+	CheckIn        time.Time
+	CheckOut       time.Time
+	NumberOfNights int
+}
+
+func (r *Reservation) GetSchema() *gitdb.Schema {
+	indexes := map[string]interface{}{"Status": r.Status, "NumberOfNights": r.NumberOfNights}
+	return gitdb.NewSchema("Reservation", "b0", fmt.Sprintf("%d", r.ReservationId), indexes).
+		Default("Status", "pending").
+		Computed("NumberOfNights", func() interface{} { return r.CheckOut.Sub(r.CheckIn).Hours() / 24 })
+}
+
+func TestDefaultAndComputedFields(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	checkIn := time.Date(2024, 6, 1, 14, 0, 0, 0, time.UTC)
+	r1 := &Reservation{ReservationId: 1, CheckIn: checkIn, CheckOut: checkIn.AddDate(0, 0, 3)}
+	r2 := &Reservation{ReservationId: 2, Status: "confirmed", CheckIn: checkIn, CheckOut: checkIn.AddDate(0, 0, 1)}
+	for _, r := range []*Reservation{r1, r2} {
+		if err := testDb.Insert(r); err != nil {
+			t.Fatalf("testDb.Insert failed: %s", err)
+		}
+	}
+
+	result := &Reservation{}
+	if err := testDb.Get(gitdb.ID(r1), result); err != nil {
+		t.Fatalf("testDb.Get failed: %s", err)
+	}
+	if result.Status != "pending" || result.NumberOfNights != 3 {
+		t.Errorf("want: pending for 3 nights, got: %s for %d nights", result.Status, result.NumberOfNights)
+	}
+
+	//defaults and computed fields are indexed
+	results, err := testDb.Search("Reservation", []*gitdb.SearchParam{{Index: "Status", Value: "pending"}}, gitdb.SearchEquals)
+	if err != nil || len(results) != 1 || results[0].ID() != gitdb.ID(r1) {
+		t.Errorf("want: [%s], got: %d result(s) (%v)", gitdb.ID(r1), len(results), err)
+	}
+	results, err = testDb.Search("Reservation", []*gitdb.SearchParam{{Index: "NumberOfNights", Value: "1"}}, gitdb.SearchEquals)
+	if err != nil || len(results) != 1 || results[0].ID() != gitdb.ID(r2) {
+		t.Errorf("want: [%s], got: %d result(s) (%v)", gitdb.ID(r2), len(results), err)
+	}
+}
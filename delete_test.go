@@ -0,0 +1,32 @@
+package gitdb_test
+
+import (
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestDeleteNotRestoredByLaterWrites(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	m1, m2, m3 := getTestMessageWithId(1), getTestMessageWithId(2), getTestMessageWithId(3)
+	insert(m1, false)
+	insert(m2, false)
+	if err := testDb.Delete(gitdb.ID(m1)); err != nil {
+		t.Fatalf("testDb.Delete failed: %s", err)
+	}
+
+	//a later write to the same block must not bring back the deleted record
+	insert(m3, false)
+	if err := testDb.Get(gitdb.ID(m1), &Message{}); err == nil {
+		t.Errorf("want: %s deleted, got: record", gitdb.ID(m1))
+	}
+	records, err := testDb.Fetch("Message")
+	if err != nil {
+		t.Fatalf("testDb.Fetch failed: %s", err)
+	}
+	if len(records) != 2 {
+		t.Errorf("want: 2 records, got: %d", len(records))
+	}
+}
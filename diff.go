@@ -0,0 +1,114 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+
+	"github.com/bouggo/log"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//RecordDiff is a change made to a record between two commits
+type RecordDiff struct {
+	ID string
+	Op Op
+	//Before is the record as JSON before the change. It is empty for inserts
+	Before string
+	//After is the record as JSON after the change. It is empty for deletes
+	After string
+}
+
+//recordChange is a change made to a single record between two commits
+type recordChange struct {
+	dataset string
+	id      string
+	op      Op
+	before  *db.Record
+	after   *db.Record
+}
+
+//Diff returns the records in dataset that were added, modified or deleted between
+//from and to, which can be commits, branches or tags
+func (g *gitdb) Diff(dataset string, from string, to string) ([]*RecordDiff, error) {
+	changes, err := g.diff(from, to, dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := make([]*RecordDiff, 0, len(changes))
+	for _, c := range changes {
+		d := &RecordDiff{ID: c.id, Op: c.op}
+		if c.before != nil {
+			d.Before = c.before.JSON()
+		}
+		if c.after != nil {
+			d.After = c.after.JSON()
+		}
+		diffs = append(diffs, d)
+	}
+
+	return diffs, nil
+}
+
+//diff returns the records that changed between commits from and to limited to paths if given
+func (g *gitdb) diff(from string, to string, paths ...string) ([]recordChange, error) {
+	files, err := g.gitDriver.diffFiles(from, to, paths...)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []recordChange
+	for _, f := range files {
+		//only block files i.e. <dataset>/<block>.json hold records
+		dataset, file := path.Split(f.file)
+		dataset = strings.TrimSuffix(dataset, "/")
+		if len(dataset) == 0 || strings.Contains(dataset, "/") || !isBlockFile(file) {
+			continue
+		}
+
+		oldBlock, newBlock := db.NewEmptyBlock(g.keyring(dataset)), db.NewEmptyBlock(g.keyring(dataset))
+		if f.status != "A" {
+			if err := g.blockAt(from, f.file, oldBlock); err != nil {
+				return nil, err
+			}
+		}
+		if f.status != "D" {
+			if err := g.blockAt(to, f.file, newBlock); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, record := range newBlock.Records() {
+			old, err := oldBlock.Get(record.ID())
+			if err != nil {
+				changes = append(changes, recordChange{dataset: dataset, id: record.ID(), op: OpInsert, after: record})
+			} else if old.Data() != record.Data() {
+				changes = append(changes, recordChange{dataset: dataset, id: record.ID(), op: OpUpdate, before: old, after: record})
+			}
+		}
+
+		for _, record := range oldBlock.Records() {
+			if _, err := newBlock.Get(record.ID()); err != nil {
+				changes = append(changes, recordChange{dataset: dataset, id: record.ID(), op: OpDelete, before: record})
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+//blockAt loads the block in file as it was at commit into block
+func (g *gitdb) blockAt(commit string, file string, block *db.EmptyBlock) error {
+	data, err := g.gitDriver.show(commit, file)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, block); err != nil {
+		log.Error("Failed to read " + file + " at " + commit)
+		return errBadBlock
+	}
+
+	return nil
+}
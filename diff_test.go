@@ -0,0 +1,64 @@
+package gitdb_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestDiff(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	m1, m2, m3 := getTestMessageWithId(1), getTestMessageWithId(2), getTestMessageWithId(3)
+	for _, m := range []*Message{m1, m2} {
+		if err := insert(m, false); err != nil {
+			t.Fatalf("insert failed: %s", err)
+		}
+	}
+	from := headCommit(t)
+
+	m1.Body = "Changed"
+	if err := insert(m1, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+	if err := testDb.Delete(gitdb.ID(m2)); err != nil {
+		t.Fatalf("testDb.Delete failed: %s", err)
+	}
+	if err := insert(m3, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+	to := headCommit(t)
+
+	diffs, err := testDb.Diff("Message", from, to)
+	if err != nil {
+		t.Fatalf("testDb.Diff failed: %s", err)
+	}
+
+	got := map[string]*gitdb.RecordDiff{}
+	for _, d := range diffs {
+		got[d.ID] = d
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("want: 3 changed records, got: %d", len(got))
+	}
+
+	if d := got[gitdb.ID(m1)]; d.Op != gitdb.OpUpdate || !strings.Contains(d.After, "Changed") || strings.Contains(d.Before, "Changed") {
+		t.Errorf("want: %s updated, got: %+v", gitdb.ID(m1), d)
+	}
+
+	if d := got[gitdb.ID(m2)]; d.Op != gitdb.OpDelete || len(d.Before) == 0 || len(d.After) != 0 {
+		t.Errorf("want: %s deleted, got: %+v", gitdb.ID(m2), d)
+	}
+
+	if d := got[gitdb.ID(m3)]; d.Op != gitdb.OpInsert || len(d.Before) != 0 || len(d.After) == 0 {
+		t.Errorf("want: %s inserted, got: %+v", gitdb.ID(m3), d)
+	}
+
+	diffs, err = testDb.Diff("MessageV2", from, to)
+	if err != nil || len(diffs) != 0 {
+		t.Errorf("want: no changes in MessageV2, got: %d %v", len(diffs), err)
+	}
+}
@@ -0,0 +1,180 @@
+package gitdb
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/bouggo/log"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//RecordDrift lists the fields of a record that don't match the model it is read into
+type RecordDrift struct {
+	ID string
+	//Unknown are fields of the record the model doesn't have. Their values are dropped by Hydrate
+	Unknown []string
+	//Missing are fields of the model the record doesn't have. Hydrate leaves them at their zero value
+	Missing []string
+}
+
+//ErrSchemaDrift is returned by FetchStrict when records don't match the model they are read into
+type ErrSchemaDrift struct {
+	Dataset string
+	Records []RecordDrift
+}
+
+func (e *ErrSchemaDrift) Error() string {
+	return fmt.Sprintf("%d records of %s don't match the model: %s", len(e.Records), e.Dataset, e.Records[0].String())
+}
+
+func (d RecordDrift) String() string {
+	var parts []string
+	if len(d.Unknown) > 0 {
+		parts = append(parts, "unknown fields "+strings.Join(d.Unknown, ", "))
+	}
+	if len(d.Missing) > 0 {
+		parts = append(parts, "missing fields "+strings.Join(d.Missing, ", "))
+	}
+	return d.ID + " has " + strings.Join(parts, " and ")
+}
+
+//SchemaDrift sums up how the records of a dataset differ from a model
+type SchemaDrift struct {
+	Dataset string
+	//Records is the number of records compared
+	Records int
+	//Unknown and Missing hold the number of records with each unknown or missing field
+	Unknown map[string]int
+	Missing map[string]int
+}
+
+//Drifted reports whether any record differs from the model
+func (s *SchemaDrift) Drifted() bool {
+	return len(s.Unknown) > 0 || len(s.Missing) > 0
+}
+
+//FetchStrict fetches the records of dataset like Fetch and checks each one against the fields of m.
+//If any record has fields m doesn't or lacks fields m has, the records are returned with *ErrSchemaDrift
+func (g *gitdb) FetchStrict(dataset string, m Model) ([]*db.Record, error) {
+	records, err := g.Fetch(dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	drifts, err := recordDrifts(records, m)
+	if err != nil {
+		return nil, err
+	}
+	if len(drifts) > 0 {
+		return records, &ErrSchemaDrift{Dataset: dataset, Records: drifts}
+	}
+	return records, nil
+}
+
+//SchemaDiff compares every record of dataset with the fields of m. Use it to find the fields
+//that a migration needs to add, rename or remove
+func (g *gitdb) SchemaDiff(dataset string, m Model) (*SchemaDrift, error) {
+	records, err := g.Fetch(dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	drifts, err := recordDrifts(records, m)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SchemaDrift{Dataset: dataset, Records: len(records), Unknown: map[string]int{}, Missing: map[string]int{}}
+	for _, d := range drifts {
+		for _, field := range d.Unknown {
+			report.Unknown[field]++
+		}
+		for _, field := range d.Missing {
+			report.Missing[field]++
+		}
+	}
+	return report, nil
+}
+
+//recordDrifts returns the records that don't match the fields of m
+func recordDrifts(records []*db.Record, m Model) ([]RecordDrift, error) {
+	t := reflect.TypeOf(m)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s is not a struct", t)
+	}
+
+	//fields maps the JSON name of each field of m to whether it can be left out i.e omitempty
+	fields := map[string]bool{}
+	jsonFields(t, fields)
+
+	var drifts []RecordDrift
+	for _, record := range records {
+		var data map[string]interface{}
+		if err := record.Hydrate(&data); err != nil {
+			log.Error(fmt.Sprintf("%s: %s", record.ID(), err))
+			continue
+		}
+
+		d := RecordDrift{ID: record.ID()}
+		for field := range data {
+			if _, ok := fields[field]; !ok {
+				d.Unknown = append(d.Unknown, field)
+			}
+		}
+		for field, optional := range fields {
+			if _, ok := data[field]; !ok && !optional {
+				d.Missing = append(d.Missing, field)
+			}
+		}
+
+		if len(d.Unknown) > 0 || len(d.Missing) > 0 {
+			sort.Strings(d.Unknown)
+			sort.Strings(d.Missing)
+			drifts = append(drifts, d)
+		}
+	}
+	return drifts, nil
+}
+
+//jsonFields adds the names encoding/json gives the fields of struct type t to fields,
+//flattening embedded structs the way it does
+func jsonFields(t reflect.Type, fields map[string]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" || isTransient(sf) {
+			continue
+		}
+
+		name := sf.Name
+		opts := strings.Split(tag, ",")
+		if opts[0] != "" {
+			name = opts[0]
+		}
+
+		ft := sf.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && opts[0] == "" && ft.Kind() == reflect.Struct {
+			jsonFields(ft, fields)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		optional := false
+		for _, opt := range opts[1:] {
+			if opt == "omitempty" {
+				optional = true
+			}
+		}
+		fields[name] = optional
+	}
+}
@@ -0,0 +1,66 @@
+package gitdb_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestFetchStrict(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	for i := 0; i < 3; i++ {
+		if err := insert(getTestMessage(), false); err != nil {
+			t.Fatalf("insert failed: %s", err)
+		}
+	}
+
+	records, err := testDb.FetchStrict("Message", &Message{})
+	if err != nil || len(records) != 3 {
+		t.Errorf("want: 3 records, got: %d (%v)", len(records), err)
+	}
+
+	//Account has none of the fields of Message other than its timestamps
+	records, err = testDb.FetchStrict("Message", &Account{})
+	var drift *gitdb.ErrSchemaDrift
+	if !errors.As(err, &drift) {
+		t.Fatalf("want: *gitdb.ErrSchemaDrift, got: %v", err)
+	}
+	if len(records) != 3 || len(drift.Records) != 3 {
+		t.Fatalf("want: 3 records that drifted, got: %d of %d", len(drift.Records), len(records))
+	}
+	d := drift.Records[0]
+	if fmt.Sprint(d.Unknown) != "[Body From MessageId To]" || fmt.Sprint(d.Missing) != "[AccountId Email]" {
+		t.Errorf("want: unknown [Body From MessageId To] and missing [AccountId Email], got: %s", d)
+	}
+}
+
+func TestSchemaDiff(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	for i := 0; i < 2; i++ {
+		if err := insert(getTestMessage(), false); err != nil {
+			t.Fatalf("insert failed: %s", err)
+		}
+	}
+
+	report, err := testDb.SchemaDiff("Message", &Message{})
+	if err != nil {
+		t.Fatalf("testDb.SchemaDiff failed: %s", err)
+	}
+	if report.Records != 2 || report.Drifted() {
+		t.Errorf("want: 2 records that match, got: %+v", report)
+	}
+
+	report, err = testDb.SchemaDiff("Message", &Account{})
+	if err != nil {
+		t.Fatalf("testDb.SchemaDiff failed: %s", err)
+	}
+	if report.Unknown["Body"] != 2 || report.Missing["Email"] != 2 || report.Missing["CreatedAt"] != 0 {
+		t.Errorf("want: Body unknown and Email missing in 2 records, got: %+v", report)
+	}
+}
@@ -1,12 +1,7 @@
 package gitdb
 
 import (
-	"fmt"
-	"time"
-
 	"github.com/bouggo/log"
-	"github.com/distatus/battery"
-	"github.com/gogitdb/gitdb/v2/internal/db"
 )
 
 type eventType string
@@ -16,6 +11,7 @@ var (
 	wBefore eventType = "writeBefore" //writeBefore
 	d       eventType = "delete"      //delete
 	r       eventType = "read"        //read
+	u       eventType = "undo"        //undo
 )
 
 type dbEvent struct {
@@ -23,10 +19,18 @@ type dbEvent struct {
 	Dataset     string
 	Description string
 	Commit      bool
+	Operation   string
+	IDs         []string
+	//Fields are the fields of the record changed by an Update
+	Fields []string
+	//User is who the commit is attributed to. If nil, Config.User is used
+	User *User
+	//Committed, if set, is sent the result of the commit
+	Committed chan error
 }
 
-func newWriteEvent(description string, dataset string, commit bool) *dbEvent {
-	return &dbEvent{Type: w, Description: description, Dataset: dataset, Commit: commit}
+func newWriteEvent(description string, dataset string, commit bool, user *User, operation string, ids ...string) *dbEvent {
+	return &dbEvent{Type: w, Description: description, Dataset: dataset, Commit: commit, User: user, Operation: operation, IDs: ids}
 }
 
 func newWriteBeforeEvent(description string, dataset string) *dbEvent {
@@ -37,14 +41,20 @@ func newReadEvent(description string, dataset string) *dbEvent {
 	return &dbEvent{Type: r, Description: description, Dataset: dataset}
 }
 
-func newDeleteEvent(description string, dataset string, commit bool) *dbEvent {
-	return &dbEvent{Type: w, Description: description, Dataset: dataset, Commit: commit}
+func newDeleteEvent(description string, dataset string, commit bool, user *User, ids ...string) *dbEvent {
+	return &dbEvent{Type: w, Description: description, Dataset: dataset, Commit: commit, User: user, Operation: string(OpDelete), IDs: ids}
+}
+
+func newUndoEvent() *dbEvent {
+	return &dbEvent{Type: u}
 }
 
 func (g *gitdb) startEventLoop() {
 	go func(g *gitdb) {
 		log.Test("starting event loop")
 
+		//ids, fields and paths changed by uncommitted writes e.g within a transaction
+		var staged, stagedFields, stagedPaths []string
 		for {
 			select {
 			case <-g.shutdown:
@@ -54,11 +64,38 @@ func (g *gitdb) startEventLoop() {
 			case e := <-g.events:
 				switch e.Type {
 				case w, d:
+					staged = append(staged, e.IDs...)
+					stagedFields = append(stagedFields, e.Fields...)
+					if len(e.Dataset) > 0 {
+						stagedPaths = append(stagedPaths, e.Dataset)
+					}
 					if e.Commit {
-						g.gitCommit(e.Dataset, e.Description, g.config.User)
+						info := newCommitInfo(e.Operation, e.Description, staged)
+						info.setFields(stagedFields)
+						msg := g.commitMessage(info)
+						paths := uniqueSorted(stagedPaths)
+						staged, stagedFields, stagedPaths = nil, nil, nil
+						user := e.User
+						if user == nil {
+							user = g.config.User
+						}
+						err := g.gitCommit(paths, msg, user)
+						if e.Committed != nil {
+							e.Committed <- err
+						}
+						g.markActive()
 						log.Test("handled write event for " + e.Description)
+						if len(g.config.OnlineRemote) > 0 {
+							g.pushQueue.add()
+							//when backing off, the scheduled retry will push this commit
+							if g.config.SyncMode.kind == syncImmediate && !g.pushQueue.backingOff() {
+								g.push()
+							}
+						}
 					}
 					g.commit.Done()
+				case u:
+					staged, stagedFields, stagedPaths = nil, nil, nil
 				default:
 					log.Info("No handler found for " + string(e.Type) + " event")
 				}
@@ -67,59 +104,3 @@ func (g *gitdb) startEventLoop() {
 	}(g)
 
 }
-
-func (g *gitdb) startSyncClock() {
-
-	go func(g *gitdb) {
-		if len(g.config.OnlineRemote) <= 0 {
-			log.Info("Syncing disabled: online remote is not set")
-			return
-		}
-
-		log.Test(fmt.Sprintf("starting sync clock @ interval %s", g.config.SyncInterval))
-		ticker := time.NewTicker(g.config.SyncInterval)
-		for {
-			select {
-			case <-g.shutdown:
-				log.Test("shutting down sync clock")
-				return
-			case <-ticker.C:
-				g.writeMu.Lock()
-				//if client PC has at least 20% battery life
-				if !hasSufficientBatteryPower(20) {
-					log.Info("Syncing disabled: insufficient battery power")
-					return
-				}
-
-				log.Info("Syncing database...")
-				changedFiles := g.gitChangedFiles()
-				err1 := g.gitPull()
-				err2 := g.gitPush()
-				if err1 != nil || err2 != nil {
-					log.Info("Database sync failed")
-				}
-
-				//reset loaded blocks
-				g.loadedBlocks = map[string]*db.Block{}
-
-				g.buildIndexSmart(changedFiles)
-				g.writeMu.Unlock()
-			}
-		}
-	}(g)
-}
-
-func hasSufficientBatteryPower(threshold float64) bool {
-	batt, err := battery.Get(0)
-	if err != nil {
-		//device is probably running on direct power
-		return true
-	}
-
-	percentageCharge := batt.Current / batt.Full * 100
-
-	log.Info(fmt.Sprintf("Battery Level: %6.2f%%", percentageCharge))
-
-	//return true if battery life is above threshold
-	return percentageCharge >= threshold
-}
@@ -0,0 +1,128 @@
+package gitdb
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+//Query describes a Search for Explain and CreateView
+type Query struct {
+	Dataset string
+	Params  []*SearchParam
+	Mode    SearchMode
+}
+
+//Plan explains how GitDB runs a Query
+type Plan struct {
+	Dataset string
+	//Records is the number of records in the dataset
+	Records int
+	//Blocks is the number of blocks in the dataset
+	Blocks int
+	//BlocksToScan is the number of blocks matching records are read from
+	BlocksToScan int
+	//Matches is the number of records the query returns
+	Matches int
+	Indexes []*IndexPlan
+}
+
+//IndexPlan explains how a SearchParam of a Query uses its index
+type IndexPlan struct {
+	Index string
+	//Exists is false when no record in the dataset has the index. Add it to the Schema
+	Exists bool
+	//Records is the number of records in the index
+	Records int
+	//Matches is the number of records matched by the SearchParam
+	Matches int
+	//KeyCounts holds the number of records for each value of the index
+	KeyCounts map[string]int
+}
+
+//Explain reports which indexes a Query uses, how many records each index key has and how many
+//blocks would be read to answer it, so slow queries and missing indexes can be spotted
+func (g *gitdb) Explain(q *Query) (*Plan, error) {
+	blockFiles, err := g.blockFiles(q.Dataset)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	plan := &Plan{
+		Dataset: q.Dataset,
+		Blocks:  len(blockFiles),
+	}
+
+	matched := map[string]bool{}
+	blocks := map[string]bool{}
+	g.cacheMu.Lock()
+	defer g.cacheMu.Unlock()
+	plan.Records = len(g.index(q.Dataset, "id"))
+	for _, param := range q.Params {
+		index := g.index(q.Dataset, param.Index)
+		ip := &IndexPlan{
+			Index:     param.Index,
+			Exists:    len(index) > 0,
+			Records:   len(index),
+			KeyCounts: map[string]int{},
+		}
+
+		queryValue := strings.ToLower(g.collateQuery(q.Dataset, param.Index, param.Value))
+		for recordID, iv := range index {
+			ip.KeyCounts[fmt.Sprint(iv.Value)]++
+			if !searchMatch(iv.Value, queryValue, q.Mode) {
+				continue
+			}
+
+			ip.Matches++
+			matched[recordID] = true
+			if _, block, _, err := ParseID(recordID); err == nil {
+				blocks[block] = true
+			}
+		}
+
+		plan.Indexes = append(plan.Indexes, ip)
+	}
+
+	plan.Matches = len(matched)
+	plan.BlocksToScan = len(blocks)
+
+	return plan, nil
+}
+
+//String formats the plan for printing
+func (p *Plan) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "dataset %s: %d records in %d blocks\n", p.Dataset, p.Records, p.Blocks)
+	for _, ip := range p.Indexes {
+		if !ip.Exists {
+			fmt.Fprintf(&buf, "  index %s: missing\n", ip.Index)
+			continue
+		}
+
+		fmt.Fprintf(&buf, "  index %s: %d records, %d keys, %d matches\n", ip.Index, ip.Records, len(ip.KeyCounts), ip.Matches)
+
+		//show the most common keys first as they are the least selective
+		keys := make([]string, 0, len(ip.KeyCounts))
+		for key := range ip.KeyCounts {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if ip.KeyCounts[keys[i]] != ip.KeyCounts[keys[j]] {
+				return ip.KeyCounts[keys[i]] > ip.KeyCounts[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+		if len(keys) > 5 {
+			keys = keys[:5]
+		}
+		for _, key := range keys {
+			fmt.Fprintf(&buf, "    %q: %d\n", key, ip.KeyCounts[key])
+		}
+	}
+	fmt.Fprintf(&buf, "  scan %d of %d blocks for %d matches\n", p.BlocksToScan, p.Blocks, p.Matches)
+
+	return buf.String()
+}
@@ -0,0 +1,52 @@
+package gitdb_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestExplain(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	for i := 1; i <= 3; i++ {
+		m := getTestMessageWithId(i)
+		if i == 3 {
+			m.From = "carol@example.com"
+		}
+		if err := insert(m, false); err != nil {
+			t.Fatalf("insert failed: %s", err)
+		}
+	}
+
+	plan, err := testDb.Explain(&gitdb.Query{
+		Dataset: "Message",
+		Params: []*gitdb.SearchParam{
+			{Index: "From", Value: "alice@example.com"},
+			{Index: "To", Value: "bob@example.com"},
+		},
+		Mode: gitdb.SearchEquals,
+	})
+	if err != nil {
+		t.Fatalf("testDb.Explain failed: %s", err)
+	}
+
+	if plan.Records != 3 || plan.Blocks != 1 || plan.Matches != 2 || plan.BlocksToScan != 1 {
+		t.Errorf("want: 3 records, 1 block, 2 matches in 1 block, got: %+v", plan)
+	}
+
+	from := plan.Indexes[0]
+	if !from.Exists || from.Matches != 2 || from.KeyCounts["alice@example.com"] != 2 || from.KeyCounts["carol@example.com"] != 1 {
+		t.Errorf("want: From index with 2 alice and 1 carol, got: %+v", from)
+	}
+
+	if plan.Indexes[1].Exists {
+		t.Error("want: To index reported missing")
+	}
+
+	if out := plan.String(); !strings.Contains(out, "index To: missing") {
+		t.Errorf("want: missing index in plan output, got: %s", out)
+	}
+}
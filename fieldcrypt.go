@@ -0,0 +1,108 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gogitdb/gitdb/v2/internal/crypto"
+)
+
+//EncryptFields encrypts fields of the model inside the stored record with the dataset's key
+//while the rest of the record and its indexes stay in plaintext. fields are the names the model's
+//fields are marshalled to JSON with. Fields are decrypted as records are read
+func (a *Schema) EncryptFields(fields ...string) *Schema {
+	a.encrypted = append(a.encrypted, fields...)
+	return a
+}
+
+func (a *Schema) validateEncryptedFields() error {
+	for _, field := range a.encrypted {
+		if _, ok := a.indexes[field]; ok && !a.blind[field] {
+			return fmt.Errorf("encrypted field %s can only be a blind index of %s", field, a.dataset)
+		}
+	}
+	return nil
+}
+
+//encodeRecord marshals the wrapped model m for storage, without its transient fields, encrypting either the whole record
+//or the fields of Schema.EncryptFields as the model asks
+func (g *gitdb) encodeRecord(m Model) (string, error) {
+	data, err := marshalRecord(m)
+	if err != nil {
+		return "", err
+	}
+
+	fields := m.GetSchema().encrypted
+	whole := m.ShouldEncrypt() || g.config.EncryptAtRest
+	if !whole && len(fields) == 0 {
+		return string(data), nil
+	}
+
+	dataset := m.GetSchema().name()
+	var key string
+	if g.config.Cipher == nil {
+		if key, err = g.encryptionKey(dataset); err != nil {
+			return "", err
+		}
+	}
+	if g.config.Cipher == nil {
+		switch {
+		case len(key) == 0 && whole:
+			return "", fmt.Errorf("encrypting %s requires Config.EncryptionKey, Config.KeyProvider or Config.Cipher", dataset)
+		case len(key) == 0:
+			return "", errors.New("EncryptFields requires Config.EncryptionKey, Config.KeyProvider or Config.Cipher")
+		case !validKeyLength(key):
+			return "", errors.New("Invalid encryption key of " + dataset + ": must be 16, 24 or 32 bytes long")
+		}
+	}
+	encrypt := func(plaintext string) (string, error) { return g.encrypt(dataset, key, plaintext) }
+
+	if whole {
+		return encrypt(string(data))
+	}
+
+	if data, err = encryptFields(encrypt, data, fields); err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+//storedRecord is a record as stored with the fields of its Data left as they are
+type storedRecord struct {
+	Version       string
+	Revision      int
+	SchemaVersion int    `json:",omitempty"`
+	Type          string `json:",omitempty"`
+	Indexes       json.RawMessage
+	Data          map[string]json.RawMessage
+}
+
+//encryptFields replaces fields of the Data of record with {crypto.FieldKey: encrypted value}
+func encryptFields(encrypt func(string) (string, error), record []byte, fields []string) ([]byte, error) {
+	var rec storedRecord
+	if err := json.Unmarshal(record, &rec); err != nil {
+		return nil, err
+	}
+
+	for _, field := range fields {
+		value, ok := rec.Data[field]
+		if !ok {
+			continue
+		}
+
+		secureValue, err := encrypt(string(value))
+		if err != nil {
+			return nil, err
+		}
+
+		enc, err := json.Marshal(map[string]string{crypto.FieldKey: secureValue})
+		if err != nil {
+			return nil, err
+		}
+		rec.Data[field] = enc
+	}
+
+	return json.Marshal(rec)
+}
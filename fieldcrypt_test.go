@@ -0,0 +1,110 @@
+package gitdb_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+type Patient struct {
+	testModel
+	PatientId int
+	Name      string
+	SSN       string
+	indexSSN  bool
+	blindSSN  bool
+}
+
+func (p *Patient) GetSchema() *gitdb.Schema {
+	indexes := map[string]interface{}{"Name": p.Name}
+	if p.indexSSN || p.blindSSN {
+		indexes["SSN"] = p.SSN
+	}
+	schema := gitdb.NewSchema("Patient", "b0", strconv.Itoa(p.PatientId), indexes).EncryptFields("SSN")
+	if p.blindSSN {
+		schema.BlindIndex("SSN")
+	}
+	return schema
+}
+
+func TestEncryptFields(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	p := &Patient{PatientId: 1, Name: "Ada Obi", SSN: "078-05-1120"}
+	if err := testDb.Insert(p); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dbPath, "data", "Patient", "b0.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), p.SSN) || !strings.Contains(string(data), p.Name) {
+		t.Errorf("want: SSN encrypted and Name in plaintext, got: %s", data)
+	}
+
+	result := &Patient{}
+	if err := testDb.Get(gitdb.ID(p), result); err != nil || result.SSN != p.SSN {
+		t.Errorf("want: %s, got: %s (%v)", p.SSN, result.SSN, err)
+	}
+
+	records, err := testDb.Search("Patient", []*gitdb.SearchParam{{Index: "Name", Value: "ada obi"}}, gitdb.SearchEquals)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("want: 1 result, got: %d (%v)", len(records), err)
+	}
+	result = &Patient{}
+	if err := records[0].Hydrate(result); err != nil || result.SSN != p.SSN {
+		t.Errorf("want: %s, got: %s (%v)", p.SSN, result.SSN, err)
+	}
+
+	if err := testDb.Insert(&Patient{PatientId: 2, SSN: "219-09-9999", indexSSN: true}); err == nil {
+		t.Error("an encrypted field should not be an index")
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	cfg := getConfig()
+	cfg.IndexKey = "a-separate-key-for-blind-indexes"
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	p := &Patient{PatientId: 1, Name: "Ada Obi", SSN: "078-05-1120", blindSSN: true}
+	if err := testDb.Insert(p); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+
+	for _, file := range []string{
+		filepath.Join(dbPath, "data", "Patient", "b0.json"),
+		filepath.Join(dbPath, ".gitdb", "index", "Patient", "SSN.json"),
+	} {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), p.SSN) {
+			t.Errorf("want: SSN hidden in %s, got: %s", file, data)
+		}
+	}
+
+	ids, err := testDb.SearchIDs("Patient", "SSN", " 078-05-1120 ")
+	if err != nil || len(ids) != 1 || ids[0] != gitdb.ID(p) {
+		t.Errorf("want: [%s], got: %v (%v)", gitdb.ID(p), ids, err)
+	}
+
+	records, err := testDb.Search("Patient", []*gitdb.SearchParam{{Index: "SSN", Value: "078"}}, gitdb.SearchStartsWith)
+	if err != nil || len(records) != 0 {
+		t.Errorf("want: no partial matches of a blind index, got: %d (%v)", len(records), err)
+	}
+
+	testDb.Close()
+	cfg.IndexKey = ""
+	testDb = getDbConn(t, cfg)
+	if err := testDb.Insert(&Patient{PatientId: 2, SSN: "219-09-9999", blindSSN: true}); err == nil {
+		t.Error("a blind index should need Config.IndexKey")
+	}
+}
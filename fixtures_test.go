@@ -0,0 +1,16 @@
+package gitdb_test
+
+import (
+	"github.com/gogitdb/gitdb/v2"
+)
+
+//testModel is embedded by the models of the tests for the Model methods they don't customise.
+//Records of models embedding it are stamped and stored like those of gitdb.TimeStampedModel
+type testModel struct {
+	gitdb.TimeStampedModel
+}
+
+func (m *testModel) Validate() error            { return nil }
+func (m *testModel) IsLockable() bool           { return false }
+func (m *testModel) ShouldEncrypt() bool        { return false }
+func (m *testModel) GetLockFileNames() []string { return []string{} }
@@ -0,0 +1,272 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//FsckKind is the kind of a problem found by Fsck
+type FsckKind string
+
+const (
+	//FsckBadBlock is a block file that isn't a JSON object of records
+	FsckBadBlock FsckKind = "bad block"
+	//FsckBadRecord is a record that can't be decrypted or isn't valid JSON
+	FsckBadRecord FsckKind = "bad record"
+	//FsckChecksum is a block file that doesn't match the HMAC of it GitDB keeps, see Verify
+	FsckChecksum FsckKind = "checksum mismatch"
+	//FsckDanglingIndex is an index entry of a record that doesn't exist
+	FsckDanglingIndex FsckKind = "dangling index entry"
+	//FsckIndexDrift is a record whose index entries are missing or don't match the record
+	FsckIndexDrift FsckKind = "index drift"
+)
+
+//FsckProblem is a problem found by Fsck
+type FsckProblem struct {
+	Kind    FsckKind
+	Dataset string
+	//Target is the block, record or index entry with the problem
+	Target string
+	Reason string
+	//Fixed is set if Fsck was asked to fix problems and fixed this one
+	Fixed bool
+}
+
+func (p *FsckProblem) String() string {
+	s := fmt.Sprintf("%s: %s %s: %s", p.Dataset, p.Kind, p.Target, p.Reason)
+	if p.Fixed {
+		s += " (fixed)"
+	}
+	return s
+}
+
+//FsckReport is what Fsck checked and the problems it found
+type FsckReport struct {
+	Datasets int
+	Blocks   int
+	Records  int
+	Problems []*FsckProblem
+}
+
+//Unfixed returns the problems of the report that weren't fixed
+func (r *FsckReport) Unfixed() []*FsckProblem {
+	var unfixed []*FsckProblem
+	for _, p := range r.Problems {
+		if !p.Fixed {
+			unfixed = append(unfixed, p)
+		}
+	}
+	return unfixed
+}
+
+//Fsck checks every dataset for blocks that can't be parsed, records that can't be read, index entries
+//of records that don't exist and records whose index entries are missing or out of date, and, with
+//Config.IntegrityKey, block files changed outside GitDB. With fix, bad blocks are restored to the last
+//commit they could be parsed at and the indexes of datasets with index problems are rebuilt. Bad records
+//and checksum mismatches need a person to look at them, e.g with the repair page of the web UI
+func (g *gitdb) Fsck(fix bool) (*FsckReport, error) {
+	if fix {
+		if err := g.writable(); err != nil {
+			return nil, err
+		}
+	}
+
+	datasets, err := g.datasetNames()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FsckReport{}
+	for _, dataset := range datasets {
+		problems, err := g.fsckDataset(dataset, report)
+		if err != nil {
+			return nil, err
+		}
+		if fix {
+			g.fsckFix(dataset, problems)
+		}
+		report.Datasets++
+		report.Problems = append(report.Problems, problems...)
+	}
+
+	if len(g.config.IntegrityKey) > 0 {
+		tampered, err := g.Verify()
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tampered {
+			dataset := strings.SplitN(t.File, "/", 2)[0]
+			report.Problems = append(report.Problems, &FsckProblem{Kind: FsckChecksum, Dataset: dataset, Target: t.File, Reason: t.Reason})
+		}
+	}
+	return report, nil
+}
+
+//fsckDataset returns the problems of the blocks, records and indexes of dataset, counting what it checks in report
+func (g *gitdb) fsckDataset(dataset string, report *FsckReport) ([]*FsckProblem, error) {
+	g.commitMu.Lock()
+	defer g.commitMu.Unlock()
+
+	blockFiles, err := g.blockFiles(dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []*FsckProblem
+	problem := func(kind FsckKind, target string, reason string) {
+		problems = append(problems, &FsckProblem{Kind: kind, Dataset: dataset, Target: target, Reason: reason})
+	}
+
+	//positions and indexes of the records that can be read, by id
+	positions := map[string][]int{}
+	indexes := map[string]map[string]interface{}{}
+	badBlocks := map[string]bool{}
+	for _, blockFile := range blockFiles {
+		report.Blocks++
+		block := strings.TrimSuffix(filepath.Base(blockFile), ".json")
+		data, err := g.readBlockFile(blockFile)
+		if err == nil {
+			err = json.Unmarshal(data, &map[string]string{})
+		}
+		if err != nil {
+			badBlocks[block] = true
+			problem(FsckBadBlock, dataset+"/"+block, err.Error())
+			continue
+		}
+
+		dataBlock := db.ParseBlock(blockFile, g.keyring(dataset), data)
+		for id, pos := range extractPositions(dataBlock) {
+			positions[id] = pos
+		}
+		for _, record := range dataBlock.Records() {
+			report.Records++
+			if err := record.Check(); err != nil {
+				problem(FsckBadRecord, record.ID(), err.Error())
+				continue
+			}
+			if record.Version() != "v1" {
+				indexes[record.ID()] = record.Indexes()
+			}
+		}
+	}
+
+	//indexes are checked as persisted
+	if err := g.flushIndex(); err != nil {
+		return nil, err
+	}
+	indexFiles := g.indexFiles(dataset)
+	sort.Strings(indexFiles)
+	indexed := map[string]map[string]bool{}
+	for _, indexFile := range indexFiles {
+		name := strings.TrimSuffix(filepath.Base(indexFile), ".json")
+		index := g.readIndex(indexFile)
+		ids := make([]string, 0, len(index))
+		for id := range index {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			entry := index[id]
+			_, block, _, _ := ParseID(id)
+			pos, ok := positions[id]
+			switch {
+			case badBlocks[block]:
+				continue
+			case !ok:
+				problem(FsckDanglingIndex, name+"["+id+"]", "record does not exist")
+				continue
+			case entry.Offset != pos[0] || entry.Len != pos[1]:
+				problem(FsckIndexDrift, name+"["+id+"]", "position does not match the block")
+			case name == "id" || indexes[id] == nil:
+				//the id index has no value to check and v1 records no indexes of their own
+			case !hasKey(indexes[id], name):
+				problem(FsckIndexDrift, name+"["+id+"]", "record is not indexed by "+name)
+			case !sameIndexValue(entry.Value, indexes[id][name]):
+				problem(FsckIndexDrift, name+"["+id+"]", "value does not match the record")
+			}
+			if indexed[id] == nil {
+				indexed[id] = map[string]bool{}
+			}
+			indexed[id][name] = true
+		}
+	}
+
+	ids := make([]string, 0, len(positions))
+	for id := range positions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		names := []string{"id"}
+		for name := range indexes[id] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !indexed[id][name] {
+				problem(FsckIndexDrift, name+"["+id+"]", "record is missing from the index")
+			}
+		}
+	}
+	return problems, nil
+}
+
+//hasKey reports whether m has key
+func hasKey(m map[string]interface{}, key string) bool {
+	_, ok := m[key]
+	return ok
+}
+
+//sameIndexValue reports whether the value of an index entry read from disk is the value of the index of a record
+func sameIndexValue(entry interface{}, value interface{}) bool {
+	a, err := json.Marshal(entry)
+	if err != nil {
+		return false
+	}
+	b, err := json.Marshal(value)
+	return err == nil && string(a) == string(b)
+}
+
+//fsckFix fixes the problems of dataset it can, restoring bad blocks and rebuilding the indexes
+func (g *gitdb) fsckFix(dataset string, problems []*FsckProblem) {
+	var rebuild []*FsckProblem
+	for _, p := range problems {
+		switch p.Kind {
+		case FsckBadBlock:
+			block := strings.TrimPrefix(p.Target, dataset+"/")
+			if commit := g.lastGoodBlock(dataset, block); len(commit) > 0 && g.restoreBlock(dataset, block, commit, nil) == nil {
+				p.Fixed = true
+				p.Reason += "; restored to " + commit
+			}
+		case FsckDanglingIndex, FsckIndexDrift:
+			rebuild = append(rebuild, p)
+		}
+	}
+
+	if len(rebuild) > 0 && g.RebuildIndex(dataset) == nil {
+		for _, p := range rebuild {
+			p.Fixed = true
+		}
+	}
+}
+
+//lastGoodBlock returns the most recent commit block of dataset can be parsed at
+func (g *gitdb) lastGoodBlock(dataset string, block string) string {
+	history, err := g.blockHistory(dataset, block)
+	if err != nil {
+		return ""
+	}
+	for _, change := range history {
+		data, err := g.gitDriver.show(change.Commit, dataset+"/"+block+".json")
+		if err == nil && json.Unmarshal(data, &map[string]string{}) == nil {
+			return change.Commit
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package gitdb_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestFsck(t *testing.T) {
+	cfg := getConfig()
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	insert(getTestMessageWithId(1), true)
+	insert(getTestMessageWithId(2), true)
+
+	report, err := testDb.Fsck(false)
+	if err != nil {
+		t.Fatalf("Fsck failed: %s", err)
+	}
+	if report.Datasets != 1 || report.Blocks != 1 || report.Records != 2 || len(report.Problems) != 0 {
+		t.Fatalf("want: 1 dataset, 1 block and 2 records without problems, got: %+v %v", report, report.Problems)
+	}
+
+	//a record left in the indexes after its block lost it, and an index entry that's out of date
+	indexFile := filepath.Join(cfg.DbPath, ".gitdb", "index", "Message", "From.json")
+	var index map[string]map[string]interface{}
+	data, _ := ioutil.ReadFile(indexFile)
+	json.Unmarshal(data, &index)
+	index["Message/b0/9"] = index["Message/b0/1"]
+	index["Message/b0/2"]["v"] = "mallory@example.com"
+	data, _ = json.Marshal(index)
+	ioutil.WriteFile(indexFile, data, 0744)
+
+	report, err = testDb.Fsck(false)
+	if err != nil {
+		t.Fatalf("Fsck failed: %s", err)
+	}
+	problems := fsckProblems(report.Problems)
+	if !strings.Contains(problems, "Message: dangling index entry From[Message/b0/9]: record does not exist") ||
+		!strings.Contains(problems, "Message: index drift From[Message/b0/2]: value does not match the record") {
+		t.Errorf("want: dangling and out of date index entries, got: %s", problems)
+	}
+
+	blockFile := filepath.Join(cfg.DbPath, "data", "Message", "b0.json")
+	ioutil.WriteFile(blockFile, []byte(`{"Message/b0/1": `), 0744)
+	report, err = testDb.Fsck(true)
+	if err != nil {
+		t.Fatalf("Fsck failed: %s", err)
+	}
+	problems = fsckProblems(report.Problems)
+	if !strings.Contains(problems, "Message: bad block Message/b0: unexpected end of JSON input; restored to ") || len(report.Unfixed()) > 0 {
+		t.Errorf("want: bad block restored and the index rebuilt, got: %s", problems)
+	}
+
+	report, err = testDb.Fsck(false)
+	if err != nil || len(report.Problems) > 0 {
+		t.Errorf("want: no problems after fixing them, got: %v %s", err, fsckProblems(report.Problems))
+	}
+	m := &Message{}
+	if err := testDb.Get("Message/b0/2", m); err != nil || m.MessageId != 2 {
+		t.Errorf("want: Message/b0/2 readable after the fix, got: %v", err)
+	}
+}
+
+func fsckProblems(problems []*gitdb.FsckProblem) string {
+	lines := make([]string, len(problems))
+	for i, p := range problems {
+		lines[i] = p.String()
+	}
+	return strings.Join(lines, "\n")
+}
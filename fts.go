@@ -0,0 +1,280 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bouggo/log"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//ftsIndex is the inverted index of a dataset's Config.FullText fields
+type ftsIndex struct {
+	//Docs holds the number of times each token appears in a record
+	Docs map[string]map[string]int `json:"docs"`
+	//terms maps tokens to the records they appear in and is built from Docs
+	terms map[string]map[string]int
+}
+
+func newFtsIndex() *ftsIndex {
+	return &ftsIndex{Docs: map[string]map[string]int{}, terms: map[string]map[string]int{}}
+}
+
+func (f *ftsIndex) add(id string, tokens map[string]int) {
+	if len(tokens) == 0 {
+		return
+	}
+
+	f.Docs[id] = tokens
+	for token, n := range tokens {
+		if f.terms[token] == nil {
+			f.terms[token] = map[string]int{}
+		}
+		f.terms[token][id] = n
+	}
+}
+
+func (f *ftsIndex) remove(id string) {
+	for token := range f.Docs[id] {
+		delete(f.terms[token], id)
+		if len(f.terms[token]) == 0 {
+			delete(f.terms, token)
+		}
+	}
+	delete(f.Docs, id)
+}
+
+//rank scores records containing any of tokens by tf-idf, best match first
+func (f *ftsIndex) rank(tokens map[string]int) []string {
+	scores := map[string]float64{}
+	for token := range tokens {
+		docs := f.terms[token]
+		if len(docs) == 0 {
+			continue
+		}
+
+		idf := math.Log(1 + float64(len(f.Docs))/float64(len(docs)))
+		for id, n := range docs {
+			scores[id] += float64(n) * idf
+		}
+	}
+
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	return ids
+}
+
+//recordTokens analyzes the string fields of record from dataset. The tokens of fields stored encrypted are kept
+//as HMACs, like blind indexes, so the index files don't reveal them, or left out without Config.IndexKey
+func (g *gitdb) recordTokens(dataset string, record *db.Record, fields []string) map[string]int {
+	var data map[string]interface{}
+	if err := record.Hydrate(&data); err != nil {
+		log.Error(err.Error())
+		return nil
+	}
+
+	whole := !json.Valid([]byte(record.Data()))
+	encrypted := map[string]bool{}
+	for _, field := range record.EncryptedFields() {
+		encrypted[field] = true
+	}
+
+	tokens := map[string]int{}
+	for _, field := range fields {
+		s, ok := data[field].(string)
+		if !ok {
+			continue
+		}
+		blind := whole || encrypted[field]
+		if blind && len(g.config.IndexKey) == 0 {
+			continue
+		}
+		for token, n := range analyze(g.analyzer(dataset, field), s) {
+			if blind {
+				token = g.blindValue(token)
+			}
+			tokens[token] += n
+		}
+	}
+
+	return tokens
+}
+
+//queryTokens analyzes query with the analyzer of each Config.FullText field of dataset. With Config.IndexKey
+//the HMACs of the tokens are included to match the tokens of encrypted fields
+func (g *gitdb) queryTokens(dataset string, query string) map[string]int {
+	tokens := map[string]int{}
+	for _, field := range g.config.FullText[dataset] {
+		for token := range analyze(g.analyzer(dataset, field), query) {
+			tokens[token] = 1
+			if len(g.config.IndexKey) > 0 {
+				tokens[g.blindValue(token)] = 1
+			}
+		}
+	}
+	return tokens
+}
+
+func (g *gitdb) ftsFile(dataset string) string {
+	return filepath.Join(g.ftsDir(), dataset+".json")
+}
+
+//fullText returns the full-text index of dataset, building it if it is not persisted
+func (g *gitdb) fullText(dataset string) *ftsIndex {
+	ftsFile := g.ftsFile(dataset)
+	if fts, ok := g.fts[ftsFile]; ok {
+		return fts
+	}
+
+	if g.fts == nil {
+		g.fts = map[string]*ftsIndex{}
+	}
+
+	fts := newFtsIndex()
+	g.fts[ftsFile] = fts
+
+	data, err := ioutil.ReadFile(ftsFile)
+	if err == nil {
+		if data, err = g.openIndex(dataset, data); err == nil {
+			err = json.Unmarshal(data, fts)
+		}
+		if err != nil {
+			log.Error(err.Error())
+		}
+
+		docs := fts.Docs
+		fts.Docs = map[string]map[string]int{}
+		for id, tokens := range docs {
+			fts.add(id, tokens)
+		}
+		return fts
+	}
+
+	blockFiles, err := g.blockFiles(dataset)
+	if err != nil {
+		log.Error(err.Error())
+	}
+
+	for _, blockFile := range blockFiles {
+		for _, record := range g.readBlock(blockFile).Records() {
+			fts.add(record.ID(), g.recordTokens(dataset, record, g.config.FullText[dataset]))
+		}
+	}
+	g.markIndexDirty(ftsFile)
+
+	return fts
+}
+
+//updateFullText brings the full-text index of dataBlock's dataset in line with the records in it
+func (g *gitdb) updateFullText(dataBlock *db.Block) {
+	dataset := dataBlock.Dataset().Name()
+	fields, ok := g.config.FullText[dataset]
+	if !ok {
+		return
+	}
+
+	fts := g.fullText(dataset)
+	blockPrefix := dataset + "/" + dataBlock.Name() + "/"
+	for id := range fts.Docs {
+		if strings.HasPrefix(id, blockPrefix) {
+			fts.remove(id)
+		}
+	}
+
+	for _, record := range dataBlock.Records() {
+		fts.add(record.ID(), g.recordTokens(dataset, record, fields))
+	}
+	g.markIndexDirty(g.ftsFile(dataset))
+}
+
+//SearchText returns records in dataset whose Config.FullText fields contain any word
+//in query. Records containing more, and rarer, words of the query are returned first
+func (g *gitdb) SearchText(dataset string, query string) ([]*db.Record, error) {
+	if _, ok := g.config.FullText[dataset]; !ok {
+		return nil, errors.New("Full-text search is not enabled for " + dataset)
+	}
+
+	g.events <- newReadEvent("...", g.ftsFile(dataset))
+
+	var ids []string
+	searchBlocks := map[string][][]int{}
+	err := g.readCache(func() error {
+		ids = g.fullText(dataset).rank(g.queryTokens(dataset, query))
+		idIndex := g.index(dataset, "id")
+		for _, id := range ids {
+			iv, ok := idIndex[id]
+			if !ok {
+				continue
+			}
+
+			_, block, _, err := ParseID(id)
+			if err != nil {
+				return err
+			}
+			searchBlocks[block] = append(searchBlocks[block], []int{iv.Offset, iv.Len})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []*db.Record{}, nil
+	}
+
+	records, err := g.hydrateSearchBlocks(dataset, searchBlocks)
+	if err != nil {
+		return nil, err
+	}
+
+	rank := map[string]int{}
+	for i, id := range ids {
+		rank[id] = i
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return rank[records[i].ID()] < rank[records[j].ID()]
+	})
+
+	return records, nil
+}
+
+//resetFullText discards the full-text index of dataset, or of all datasets if dataset is empty
+func (g *gitdb) resetFullText(dataset string) error {
+	if len(dataset) == 0 {
+		g.fts = map[string]*ftsIndex{}
+		return os.RemoveAll(g.ftsDir())
+	}
+
+	ftsFile := g.ftsFile(dataset)
+	delete(g.dirtyIndexes, ftsFile)
+	if _, ok := g.config.FullText[dataset]; ok {
+		if g.fts == nil {
+			g.fts = map[string]*ftsIndex{}
+		}
+		//the caller re-adds every block so start from an empty index
+		g.fts[ftsFile] = newFtsIndex()
+	} else {
+		delete(g.fts, ftsFile)
+	}
+
+	if err := os.Remove(ftsFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package gitdb_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestSearchText(t *testing.T) {
+	cfg := getConfig()
+	cfg.FullText = map[string][]string{"Message": {"Body", "From"}}
+	cfg.IndexKey = "fts-index-key"
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	m1, m2, m3 := getTestMessageWithId(1), getTestMessageWithId(2), getTestMessageWithId(3)
+	m1.Body = "Adewale is flying to Lagos"
+	m2.Body = "Greetings from Lagos"
+	m3.Body = "Nothing to see here"
+	for _, m := range []*Message{m1, m2, m3} {
+		if err := insert(m, false); err != nil {
+			t.Fatalf("insert failed: %s", err)
+		}
+	}
+
+	results, err := testDb.SearchText("Message", "adewale LAGOS")
+	if err != nil {
+		t.Fatalf("testDb.SearchText failed: %s", err)
+	}
+	if len(results) != 2 || results[0].ID() != gitdb.ID(m1) || results[1].ID() != gitdb.ID(m2) {
+		t.Errorf("want: [%s %s], got: %d result(s)", gitdb.ID(m1), gitdb.ID(m2), len(results))
+	}
+
+	if err := testDb.Delete(gitdb.ID(m1)); err != nil {
+		t.Fatalf("testDb.Delete failed: %s", err)
+	}
+
+	results, err = testDb.SearchText("Message", "adewale")
+	if err != nil {
+		t.Fatalf("testDb.SearchText failed: %s", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("want: no results for deleted record, got: %d", len(results))
+	}
+
+	if _, err := testDb.SearchText("MessageV2", "lagos"); err == nil {
+		t.Error("testDb.SearchText should fail for a dataset without full-text fields")
+	}
+
+	//Message is stored encrypted so its words are only kept as HMACs
+	data, err := ioutil.ReadFile(filepath.Join(dbPath, ".gitdb", "fts", "Message.json"))
+	if err != nil {
+		t.Fatalf("read full-text index failed: %s", err)
+	}
+	if strings.Contains(string(data), "lagos") {
+		t.Errorf("want: words of encrypted records hidden, got: %s", data)
+	}
+}
+
+func TestSearchTextEncryptedWithoutIndexKey(t *testing.T) {
+	cfg := getConfig()
+	cfg.FullText = map[string][]string{"Message": {"Body"}}
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	m := getTestMessageWithId(1)
+	m.Body = "Greetings from Lagos"
+	if err := insert(m, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	//the words of encrypted records aren't indexed without Config.IndexKey
+	results, err := testDb.SearchText("Message", "lagos")
+	if err != nil {
+		t.Fatalf("testDb.SearchText failed: %s", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("want: no results, got: %d", len(results))
+	}
+}
+
+//pluralAnalyzer stems plurals so "flights" matches "flight"
+type pluralAnalyzer struct{ gitdb.DefaultAnalyzer }
+
+func (pluralAnalyzer) Stem(token string) string { return strings.TrimSuffix(token, "s") }
+
+func TestSearchTextAnalyzers(t *testing.T) {
+	cfg := getConfig()
+	cfg.FullText = map[string][]string{"Message": {"Body", "From"}}
+	cfg.Analyzers = map[string]gitdb.Analyzer{
+		"Message.Body": pluralAnalyzer{},
+		"Message.From": gitdb.KeywordAnalyzer{},
+	}
+	cfg.IndexKey = "fts-index-key"
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	m1, m2 := getTestMessageWithId(1), getTestMessageWithId(2)
+	m1.Body, m1.From = "Booked two flights", "+44 (0)20 7946-0018"
+	m2.Body, m2.From = "Nothing to see here", "+44 20 1234"
+	for _, m := range []*Message{m1, m2} {
+		if err := insert(m, false); err != nil {
+			t.Fatalf("insert failed: %s", err)
+		}
+	}
+
+	for _, query := range []string{"flight", "4402079460018"} {
+		results, err := testDb.SearchText("Message", query)
+		if err != nil {
+			t.Fatalf("testDb.SearchText failed: %s", err)
+		}
+		if len(results) != 1 || results[0].ID() != gitdb.ID(m1) {
+			t.Errorf("%s: want: [%s], got: %d result(s)", query, gitdb.ID(m1), len(results))
+		}
+	}
+
+	//keyword fields are not split into words
+	results, err := testDb.SearchText("Message", "44")
+	if err != nil {
+		t.Fatalf("testDb.SearchText failed: %s", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("want: no results, got: %d", len(results))
+	}
+}
@@ -0,0 +1,242 @@
+package gitdb
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//geoIndex is the name of the index added by Schema.GeoIndex
+const geoIndex = "_geo"
+
+//geohashPrecision is the length of geohashes stored in the geo index, about 4cm across
+const geohashPrecision = 12
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+const earthRadiusKm = 6371.0
+
+//GeoIndex indexes the location held in the lat and lng indexes, which must be numbers,
+//so records can be found with SearchNear. A schema can have one geo index
+func (a *Schema) GeoIndex(lat string, lng string) *Schema {
+	a.geo = []string{lat, lng}
+	if a.indexes == nil {
+		a.indexes = map[string]interface{}{}
+	}
+
+	latValue, lngValue := toOrdered(a.indexes[lat]), toOrdered(a.indexes[lng])
+	if latValue.kind == orderNumber && lngValue.kind == orderNumber {
+		a.indexes[geoIndex] = encodeGeohash(latValue.num, lngValue.num, geohashPrecision)
+	}
+	return a
+}
+
+//validateGeo ensures the fields of a geo index are numeric indexes
+func (a *Schema) validateGeo() error {
+	if len(a.geo) == 0 {
+		if _, ok := a.indexes[geoIndex]; ok && !a.internal {
+			return fmt.Errorf("%s is a reserved index name", geoIndex)
+		}
+		return nil
+	}
+
+	for _, field := range a.geo {
+		v, ok := a.indexes[field]
+		if !ok {
+			return fmt.Errorf("geo index field %s is not an index of %s", field, a.dataset)
+		}
+		if toOrdered(v).kind != orderNumber {
+			return fmt.Errorf("geo index field %s must be a number", field)
+		}
+	}
+	return nil
+}
+
+func encodeGeohash(lat float64, lng float64, precision int) string {
+	latRange := [2]float64{-90, 90}
+	lngRange := [2]float64{-180, 180}
+
+	var hash strings.Builder
+	bit, ch, even := 0, 0, true
+	for hash.Len() < precision {
+		r, v := &latRange, lat
+		if even {
+			r, v = &lngRange, lng
+		}
+
+		mid := (r[0] + r[1]) / 2
+		ch <<= 1
+		if v >= mid {
+			ch |= 1
+			r[0] = mid
+		} else {
+			r[1] = mid
+		}
+		even = !even
+
+		if bit++; bit == 5 {
+			hash.WriteByte(geohashAlphabet[ch])
+			bit, ch = 0, 0
+		}
+	}
+
+	return hash.String()
+}
+
+//decodeGeohash returns the centre of the cell of hash
+func decodeGeohash(hash string) (lat float64, lng float64) {
+	latRange := [2]float64{-90, 90}
+	lngRange := [2]float64{-180, 180}
+
+	even := true
+	for i := 0; i < len(hash); i++ {
+		ch := strings.IndexByte(geohashAlphabet, hash[i])
+		for mask := 16; mask > 0; mask >>= 1 {
+			r := &latRange
+			if even {
+				r = &lngRange
+			}
+
+			mid := (r[0] + r[1]) / 2
+			if ch&mask != 0 {
+				r[0] = mid
+			} else {
+				r[1] = mid
+			}
+			even = !even
+		}
+	}
+
+	return (latRange[0] + latRange[1]) / 2, (lngRange[0] + lngRange[1]) / 2
+}
+
+//geohashCell returns the height and width in degrees of a geohash cell of precision
+func geohashCell(precision int) (lat float64, lng float64) {
+	bits := 5 * precision
+	lngBits := (bits + 1) / 2
+	latBits := bits / 2
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lngBits))
+}
+
+//haversine returns the great circle distance between two points in km
+func haversine(lat1 float64, lng1 float64, lat2 float64, lng2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+//geohashCover returns geohash prefixes whose cells cover the circle of radiusKm around lat, lng
+func geohashCover(lat float64, lng float64, radiusKm float64) []string {
+	dLat := radiusKm / earthRadiusKm * 180 / math.Pi
+	minLat, maxLat := math.Max(-90, lat-dLat), math.Min(90, lat+dLat)
+
+	//longitude degrees shrink towards the poles
+	dLng := 180.0
+	if cos := math.Cos(math.Max(math.Abs(minLat), math.Abs(maxLat)) * math.Pi / 180); cos > 0 {
+		dLng = math.Min(180, dLat/cos)
+	}
+
+	//use the smallest cells that are still as large as the bounding box of the circle
+	precision := 0
+	for p := geohashPrecision; p > 0; p-- {
+		cellLat, cellLng := geohashCell(p)
+		if cellLat >= maxLat-minLat && cellLng >= 2*dLng {
+			precision = p
+			break
+		}
+	}
+	if precision == 0 {
+		return []string{""}
+	}
+
+	cellLat, cellLng := geohashCell(precision)
+	seen := map[string]bool{}
+	var cover []string
+	for y := minLat; ; y += cellLat {
+		y = math.Min(y, maxLat)
+		for x := lng - dLng; ; x += cellLng {
+			x = math.Min(x, lng+dLng)
+			hash := encodeGeohash(y, normalizeLng(x), precision)
+			if !seen[hash] {
+				seen[hash] = true
+				cover = append(cover, hash)
+			}
+			if x >= lng+dLng {
+				break
+			}
+		}
+		if y >= maxLat {
+			break
+		}
+	}
+
+	return cover
+}
+
+func normalizeLng(lng float64) float64 {
+	for lng < -180 {
+		lng += 360
+	}
+	for lng >= 180 {
+		lng -= 360
+	}
+	return lng
+}
+
+//SearchNear returns records in dataset within radiusKm of lat, lng, nearest first.
+//The dataset's Schema must have a GeoIndex
+func (g *gitdb) SearchNear(dataset string, lat float64, lng float64, radiusKm float64) ([]*db.Record, error) {
+	g.events <- newReadEvent("...", g.indexFile(dataset, geoIndex))
+
+	var entries []orderedEntry
+	g.readCache(func() error {
+		entries = g.orderedIndex(dataset, geoIndex)
+		return nil
+	})
+	searchBlocks := map[string][][]int{}
+	distances := map[string]float64{}
+	for _, prefix := range geohashCover(lat, lng, radiusKm) {
+		//the ordered index is sorted by geohash so a cell's records are contiguous
+		start := sort.Search(len(entries), func(i int) bool {
+			return entries[i].value.kind != orderNumber && entries[i].value.str >= prefix
+		})
+
+		for _, entry := range entries[start:] {
+			if !strings.HasPrefix(entry.value.str, prefix) {
+				break
+			}
+			if _, ok := distances[entry.id]; ok {
+				continue
+			}
+
+			pLat, pLng := decodeGeohash(entry.value.str)
+			d := haversine(lat, lng, pLat, pLng)
+			if d > radiusKm {
+				continue
+			}
+
+			_, block, _, err := ParseID(entry.id)
+			if err != nil {
+				return nil, err
+			}
+			searchBlocks[block] = append(searchBlocks[block], []int{entry.iv.Offset, entry.iv.Len})
+			distances[entry.id] = d
+		}
+	}
+
+	records, err := g.hydrateSearchBlocks(dataset, searchBlocks)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return distances[records[i].ID()] < distances[records[j].ID()]
+	})
+
+	return records, nil
+}
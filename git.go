@@ -19,10 +19,53 @@ type dbDriver interface {
 	clone() error
 	addRemote() error
 	pull() error
-	push() error
-	commit(filePath string, msg string, user *User) error
+	push(remote string) error
+	commit(paths []string, msg string, user *User) error
 	undo() error
 	changedFiles() []string
+	show(commit string, file string) ([]byte, error)
+	currentBranch() (string, error)
+	createBranch(name string) error
+	checkBranchName(name string) error
+	checkTagName(name string) error
+	checkout(name string) error
+	merge(name string, user *User) error
+	registerMergeDriver(command string) error
+	isDirty(path string) bool
+	configureSigning(format string, key string, allowedSigners string) error
+	signatures(path string) ([]commitSignature, error)
+	log(file string) ([]commitEntry, error)
+	head() (string, error)
+	revParse(rev string) (string, error)
+	diffFiles(from string, to string, paths ...string) ([]fileChange, error)
+	gc() error
+	countObjects() (*RepoObjects, error)
+	squash(before time.Time, msg string, user *User) (int, error)
+	forcePush(remote string) error
+	setRemote(name string, url string) error
+	lsTree(rev string, path string, dirsOnly bool) ([]string, error)
+	tag(name string, msg string, user *User) error
+	tags() ([]*Release, error)
+	fastForward() error
+	rewriteHistory(files []string, rewrite func(file string, data []byte) ([]byte, error)) (int, error)
+	prune() error
+}
+
+//emptyTree is the hash of git's empty tree, used to diff against a repository without commits
+const emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+//fileChange is a file added (A), modified (M) or deleted (D) between two commits
+type fileChange struct {
+	status string
+	file   string
+}
+
+//commitEntry is a commit as recorded in git log
+type commitEntry struct {
+	hash    string
+	author  *User
+	time    time.Time
+	message string
 }
 
 type baseGitDriver struct {
@@ -93,24 +136,41 @@ func (g *gitdb) gitAddRemote() error {
 //fails silently, logs error message and determine if we need to put the
 //application in an error state
 func (g *gitdb) gitPull() error {
+	//the pull rewrites block files so readers are kept out until it's done
+	defer g.blockLocks.lockAll()()
 	return g.gitDriver.pull()
 }
 
 func (g *gitdb) gitPush() error {
-	return g.gitDriver.push()
+	return g.gitDriver.push(onlineRemote)
 }
 
-func (g *gitdb) gitCommit(filePath string, msg string, user *User) {
+//gitCheckout switches the working tree to branch name
+func (g *gitdb) gitCheckout(name string) error {
+	defer g.blockLocks.lockAll()()
+	return g.gitDriver.checkout(name)
+}
+
+//gitMerge merges branch name into the working tree as user
+func (g *gitdb) gitMerge(name string, user *User) error {
+	defer g.blockLocks.lockAll()()
+	return g.gitDriver.merge(name, user)
+}
+
+//gitCommit commits the changes to paths, leaving changes to other files e.g staged by writes yet to be committed.
+//Errors are logged as well as returned as most writes don't wait for the result of their commit
+func (g *gitdb) gitCommit(paths []string, msg string, user *User) error {
 	mu.Lock()
 	defer mu.Unlock()
-	err := g.gitDriver.commit(filePath, msg, user)
+	err := g.gitDriver.commit(paths, msg, user)
 	if err != nil {
-		// todo: update to return this error but for now at least log it
 		log.Error(err.Error())
 	}
+	return err
 }
 
 func (g *gitdb) gitUndo() error {
+	defer g.blockLocks.lockAll()()
 	return g.gitDriver.undo()
 }
 
@@ -1,9 +1,16 @@
 package gitdb
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bouggo/log"
 )
@@ -30,13 +37,40 @@ func (g *gitBinary) init() error {
 
 func (g *gitBinary) clone() error {
 
-	cmd := exec.Command("git", "clone", "--depth", "10", g.config.OnlineRemote, g.absDbPath)
+	args := []string{"clone"}
+	//a negative depth clones the full history
+	if g.config.CloneDepth > 0 {
+		args = append(args, "--depth", strconv.Itoa(g.config.CloneDepth))
+	}
+
+	sparse := len(g.config.SparseDatasets) > 0
+	if sparse {
+		args = append(args, "--filter=blob:none", "--sparse")
+	}
+
+	//blocks are read from git objects so there is no need for a working tree
+	if g.config.ObjectReads {
+		args = append(args, "--no-checkout")
+	}
+
+	args = append(args, g.config.OnlineRemote, g.absDbPath)
+	cmd := exec.Command("git", args...)
 	//log(fmt.Sprintf("%s", cmd))
 	if out, err := cmd.CombinedOutput(); err != nil {
 		log.Info(string(out))
 		return errors.New(string(out))
 	}
 
+	if sparse {
+		//the datasets GitDB keeps itself are written whatever the sparse datasets
+		args = append([]string{"-C", g.absDbPath, "sparse-checkout", "set", auditDataset, locksDataset, conflictsDataset}, g.config.SparseDatasets...)
+		cmd = exec.Command("git", args...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			log.Info(string(out))
+			return errors.New(string(out))
+		}
+	}
+
 	return nil
 }
 
@@ -76,9 +110,21 @@ func (g *gitBinary) addRemote() error {
 }
 
 func (g *gitBinary) pull() error {
-	cmd := exec.Command("git", "-C", g.absDbPath, "pull", "online", "master")
+	branch, err := g.currentBranch()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("git", "-C", g.absDbPath, "pull", "online", branch)
+	//the merge driver needs the key to read timestamps of encrypted records
+	cmd.Env = append(os.Environ(), mergeKeyEnv+"="+g.config.keyring())
 	//log(utils.CmdToString(cmd))
 	if out, err := cmd.CombinedOutput(); err != nil {
+		//branch has not been pushed to the online remote yet so there's nothing to pull
+		if strings.Contains(string(out), "couldn't find remote ref") {
+			return nil
+		}
+
 		log.Error("Failed to pull data from online remote.")
 		log.Error(string(out))
 
@@ -88,8 +134,14 @@ func (g *gitBinary) pull() error {
 	return nil
 }
 
-func (g *gitBinary) push() error {
-	cmd := exec.Command("git", "-C", g.absDbPath, "push", "online", "master")
+func (g *gitBinary) push(remote string) error {
+	branch, err := g.currentBranch()
+	if err != nil {
+		return err
+	}
+
+	//annotated tags i.e. releases are pushed along with the commits they point to
+	cmd := exec.Command("git", "-C", g.absDbPath, "push", "--follow-tags", remote, branch)
 	//log(utils.CmdToString(cmd))
 	if out, err := cmd.CombinedOutput(); err != nil {
 		log.Error("Failed to push data to online remotes.")
@@ -100,7 +152,7 @@ func (g *gitBinary) push() error {
 	return nil
 }
 
-func (g *gitBinary) commit(filePath string, msg string, user *User) error {
+func (g *gitBinary) commit(paths []string, msg string, user *User) error {
 	cmd := exec.Command("git", "-C", g.absDbPath, "config", "user.email", user.Email)
 	//log(utils.CmdToString(cmd))
 	if out, err := cmd.CombinedOutput(); err != nil {
@@ -115,14 +167,14 @@ func (g *gitBinary) commit(filePath string, msg string, user *User) error {
 		return err
 	}
 
-	cmd = exec.Command("git", "-C", g.absDbPath, "add", filePath)
+	cmd = exec.Command("git", append([]string{"-C", g.absDbPath, "add", "--"}, paths...)...)
 	//log(utils.CmdToString(cmd))
 	if out, err := cmd.CombinedOutput(); err != nil {
 		log.Error(string(out))
 		return err
 	}
 
-	cmd = exec.Command("git", "-C", g.absDbPath, "commit", "-am", msg)
+	cmd = exec.Command("git", append([]string{"-C", g.absDbPath, "commit", "-m", msg, "--"}, paths...)...)
 	//log(utils.CmdToString(cmd))
 	if out, err := cmd.CombinedOutput(); err != nil {
 		log.Error(string(out))
@@ -145,20 +197,43 @@ func (g *gitBinary) undo() error {
 	return nil
 }
 
+func (g *gitBinary) show(commit string, file string) ([]byte, error) {
+	//resolve commit first so a value such as --output=file isn't taken for an option of git show
+	hash, err := g.revParse(commit + "^{commit}")
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("git", "-C", g.absDbPath, "show", hash+":"+file)
+	out, err := cmd.Output()
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	return out, nil
+}
+
 func (g *gitBinary) changedFiles() []string {
 
 	files := []string{}
 	if len(g.config.OnlineRemote) > 0 {
 		log.Test("getting list of changed files...")
+		branch, err := g.currentBranch()
+		if err != nil {
+			log.Error(err.Error())
+			return files
+		}
+
 		//git fetch
-		cmd := exec.Command("git", "-C", g.absDbPath, "fetch", "online", "master")
+		cmd := exec.Command("git", "-C", g.absDbPath, "fetch", "online", branch)
 		if out, err := cmd.CombinedOutput(); err != nil {
 			log.Error(string(out))
 			return files
 		}
 
-		//git diff --name-only ..online/master
-		cmd = exec.Command("git", "-C", g.absDbPath, "diff", "--name-only", "..online/master")
+		//git diff --name-only ..online/<branch>
+		cmd = exec.Command("git", "-C", g.absDbPath, "diff", "--name-only", "..online/"+branch)
 		out, err := cmd.CombinedOutput()
 		if err != nil {
 			log.Error(string(out))
@@ -169,7 +244,7 @@ func (g *gitBinary) changedFiles() []string {
 		if len(output) > 0 {
 			//strip out lock files
 			for _, file := range strings.Split(output, "\n") {
-				if strings.HasSuffix(file, ".json") {
+				if isBlockFile(file) {
 					files = append(files, file)
 				}
 			}
@@ -180,3 +255,609 @@ func (g *gitBinary) changedFiles() []string {
 
 	return files
 }
+
+func (g *gitBinary) currentBranch() (string, error) {
+	//symbolic-ref works on an unborn branch i.e before the first commit
+	cmd := exec.Command("git", "-C", g.absDbPath, "symbolic-ref", "--short", "HEAD")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		log.Error(string(out))
+		return "", err
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (g *gitBinary) createBranch(name string) error {
+	cmd := exec.Command("git", "-C", g.absDbPath, "branch", name)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		log.Error(string(out))
+		return errors.New(strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+func (g *gitBinary) checkBranchName(name string) error {
+	if _, err := g.git("check-ref-format", "--branch", name); err != nil {
+		return errors.New("Invalid branch name: " + name)
+	}
+	return nil
+}
+
+func (g *gitBinary) checkTagName(name string) error {
+	if _, err := g.git("check-ref-format", "refs/tags/"+name); err != nil {
+		return errors.New("Invalid release name: " + name)
+	}
+	return nil
+}
+
+func (g *gitBinary) checkout(name string) error {
+	cmd := exec.Command("git", "-C", g.absDbPath, "checkout", name)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		log.Error(string(out))
+		return errors.New(strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+func (g *gitBinary) merge(name string, user *User) error {
+	cmd := exec.Command("git", "-C", g.absDbPath, "-c", "user.name="+user.Name, "-c", "user.email="+user.Email, "merge", "--no-edit", name)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		log.Error(string(out))
+		//leave the repo in a usable state
+		exec.Command("git", "-C", g.absDbPath, "merge", "--abort").Run()
+		return errors.New(strings.TrimSpace(string(out)))
+	}
+
+	log.Info("merged branch " + name)
+	return nil
+}
+
+func (g *gitBinary) registerMergeDriver(command string) error {
+	cmd := exec.Command("git", "-C", g.absDbPath, "config", "merge.gitdb.name", "gitdb record level merge")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		log.Error(string(out))
+		return err
+	}
+
+	cmd = exec.Command("git", "-C", g.absDbPath, "config", "merge.gitdb.driver", command+" merge-blocks %P %O %A %B")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		log.Error(string(out))
+		return err
+	}
+
+	//use info/attributes rather than .gitattributes so the
+	//repository content is not affected by the local setup
+	infoDir := filepath.Join(g.absDbPath, ".git", "info")
+	if err := os.MkdirAll(infoDir, 0755); err != nil {
+		return err
+	}
+
+	//only blocks, the JSON files directly inside dataset directories that aren't hidden, are merged record by record
+	attributes := []string{"/*/*.json merge=gitdb", "/*/.*.json merge=text"}
+	attributesFile := filepath.Join(infoDir, "attributes")
+	data, err := ioutil.ReadFile(attributesFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	//entries of the user are kept. Ours go last, in order, as later lines take precedence
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	}
+	existing := map[string]bool{}
+	for _, line := range lines {
+		existing[strings.TrimSpace(line)] = true
+	}
+	if existing[attributes[0]] && existing[attributes[1]] {
+		return nil
+	}
+
+	var kept []string
+	for _, line := range lines {
+		if trimmed := strings.TrimSpace(line); trimmed != attributes[0] && trimmed != attributes[1] {
+			kept = append(kept, line)
+		}
+	}
+	kept = append(kept, attributes...)
+	return ioutil.WriteFile(attributesFile, []byte(strings.Join(kept, "\n")+"\n"), 0644)
+}
+
+func (g *gitBinary) isDirty(path string) bool {
+	cmd := exec.Command("git", "-C", g.absDbPath, "status", "--porcelain", "--", path)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		log.Error(string(out))
+		return false
+	}
+
+	return len(strings.TrimSpace(string(out))) > 0
+}
+
+func (g *gitBinary) configureSigning(format string, key string, allowedSigners string) error {
+	settings := [][]string{
+		{"gpg.format", format},
+		{"user.signingkey", key},
+		{"commit.gpgsign", "true"},
+	}
+
+	if len(allowedSigners) > 0 {
+		settings = append(settings, []string{"gpg.ssh.allowedSignersFile", allowedSigners})
+	}
+
+	for _, setting := range settings {
+		cmd := exec.Command("git", "-C", g.absDbPath, "config", setting[0], setting[1])
+		if out, err := cmd.CombinedOutput(); err != nil {
+			log.Error(string(out))
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *gitBinary) signatures(path string) ([]commitSignature, error) {
+	cmd := exec.Command("git", "-C", g.absDbPath, "log", "--format=%H%x09%G?%x09%s", "--", path)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		//repository without commits has no history to verify
+		if strings.Contains(string(out), "does not have any commits") {
+			return nil, nil
+		}
+		return nil, errors.New(string(out))
+	}
+
+	var sigs []commitSignature
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.SplitN(line, "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		sigs = append(sigs, commitSignature{hash: parts[0], status: parts[1], subject: parts[2]})
+	}
+
+	return sigs, nil
+}
+
+func (g *gitBinary) log(file string) ([]commitEntry, error) {
+	cmd := exec.Command("git", "-C", g.absDbPath, "log", "--format=%H%x1f%an%x1f%ae%x1f%at%x1f%B%x1e", "--", file)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(out), "does not have any commits") {
+			return nil, nil
+		}
+		return nil, errors.New(string(out))
+	}
+
+	var entries []commitEntry
+	for _, record := range strings.Split(string(out), "\x1e") {
+		fields := strings.SplitN(strings.TrimSpace(record), "\x1f", 5)
+		if len(fields) != 5 {
+			continue
+		}
+
+		unix, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, commitEntry{
+			hash:    fields[0],
+			author:  NewUser(fields[1], fields[2]),
+			time:    time.Unix(unix, 0),
+			message: fields[4],
+		})
+	}
+
+	return entries, nil
+}
+
+func (g *gitBinary) head() (string, error) {
+	cmd := exec.Command("git", "-C", g.absDbPath, "rev-parse", "--verify", "-q", "HEAD")
+	out, err := cmd.Output()
+	if err != nil {
+		//repository has no commits yet
+		return "", nil
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (g *gitBinary) diffFiles(from string, to string, paths ...string) ([]fileChange, error) {
+	if len(from) == 0 {
+		from = emptyTree
+	}
+
+	args := append([]string{"-C", g.absDbPath, "diff", "--name-status", "--no-renames", "--end-of-options", from, to, "--"}, paths...)
+	cmd := exec.Command("git", args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, errors.New(string(out))
+	}
+
+	var changes []fileChange
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		changes = append(changes, fileChange{status: parts[0], file: parts[1]})
+	}
+
+	return changes, nil
+}
+
+func (g *gitBinary) gc() error {
+	cmd := exec.Command("git", "-C", g.absDbPath, "gc", "--prune=now", "--quiet")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		log.Error(string(out))
+		return errors.New(string(out))
+	}
+
+	return nil
+}
+
+//countObjects returns the loose objects, packs and garbage of the repository as git count-objects reports them
+func (g *gitBinary) countObjects() (*RepoObjects, error) {
+	out, err := g.git("count-objects", "-v")
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int64{}
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected git count-objects output: %s", line)
+		}
+		counts[parts[0]] = n
+	}
+
+	//sizes are reported in KiB
+	return &RepoObjects{
+		Loose:       int(counts["count"]),
+		LooseSize:   counts["size"] * 1024,
+		Packs:       int(counts["packs"]),
+		PackSize:    counts["size-pack"] * 1024,
+		Garbage:     int(counts["garbage"]),
+		GarbageSize: counts["size-garbage"] * 1024,
+	}, nil
+}
+
+//squash rewrites the commits before t into a single commit with message msg and replays the
+//rest of the history on top of it. It returns the number of commits squashed
+func (g *gitBinary) squash(t time.Time, msg string, user *User) (int, error) {
+	branch, err := g.currentBranch()
+	if err != nil {
+		return 0, err
+	}
+
+	head, err := g.revParse("HEAD")
+	if err != nil {
+		return 0, err
+	}
+
+	base, err := g.git("rev-list", "-1", "--first-parent", "--before="+strconv.FormatInt(t.Unix(), 10), "HEAD")
+	if err != nil || len(base) == 0 {
+		//no commits before t
+		return 0, err
+	}
+
+	count, err := g.git("rev-list", "--count", base)
+	if err != nil {
+		return 0, err
+	}
+
+	squashed, _ := strconv.Atoi(count)
+	if squashed <= 1 {
+		return 0, nil
+	}
+
+	//build the new history on the side so the branch is untouched if anything fails
+	newHead, err := g.commitTree(base, "", msg, []string{
+		"GIT_AUTHOR_NAME=" + user.Name, "GIT_AUTHOR_EMAIL=" + user.Email,
+		"GIT_COMMITTER_NAME=" + user.Name, "GIT_COMMITTER_EMAIL=" + user.Email,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	later, err := g.git("rev-list", "--reverse", "--first-parent", base+"..HEAD")
+	if err != nil {
+		return 0, err
+	}
+
+	for _, commit := range strings.Fields(later) {
+		meta, err := g.git("log", "-1", "--date=raw", "--format=%an%x00%ae%x00%ad%x00%cn%x00%ce%x00%cd", commit)
+		if err != nil {
+			return 0, err
+		}
+		f := strings.Split(meta, "\x00")
+		if len(f) != 6 {
+			return 0, errors.New("unexpected commit metadata for " + commit)
+		}
+
+		body, err := g.git("log", "-1", "--format=%B", commit)
+		if err != nil {
+			return 0, err
+		}
+
+		newHead, err = g.commitTree(commit, newHead, body, []string{
+			"GIT_AUTHOR_NAME=" + f[0], "GIT_AUTHOR_EMAIL=" + f[1], "GIT_AUTHOR_DATE=" + f[2],
+			"GIT_COMMITTER_NAME=" + f[3], "GIT_COMMITTER_EMAIL=" + f[4], "GIT_COMMITTER_DATE=" + f[5],
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	//the rewritten history must hold exactly the same data
+	oldTree, err := g.revParse(head + "^{tree}")
+	if err != nil {
+		return 0, err
+	}
+	newTree, err := g.revParse(newHead + "^{tree}")
+	if err != nil {
+		return 0, err
+	}
+	if oldTree != newTree {
+		return 0, errors.New("squashed history does not match current data")
+	}
+
+	//swap the branch only if it has not moved while the history was rewritten
+	if _, err := g.git("update-ref", "-m", "gitdb: squash history", "refs/heads/"+branch, newHead, head); err != nil {
+		return 0, err
+	}
+
+	return squashed, nil
+}
+
+//rewriteHistory replaces every version of files in the history of all branches and tags with
+//its rewrite and returns the number of versions rewritten. Commits are rewritten with their
+//original authors, dates and messages. Old commits stay in the repository until prune is called
+func (g *gitBinary) rewriteHistory(files []string, rewrite func(file string, data []byte) ([]byte, error)) (int, error) {
+	//map each version of a file to its rewritten blob in an index filter run on every commit
+	var script strings.Builder
+	rewritten := 0
+	for _, file := range files {
+		out, err := g.git("log", "--branches", "--tags", "-m", "--format=", "--raw", "--no-abbrev", "--no-renames", "--", file)
+		if err != nil {
+			return 0, err
+		}
+
+		blobs := map[string]bool{}
+		for _, line := range strings.Split(out, "\n") {
+			//:<old mode> <new mode> <old blob> <new blob> <status>\t<path>
+			if fields := strings.Fields(line); len(fields) >= 5 {
+				blobs[fields[2]], blobs[fields[3]] = true, true
+			}
+		}
+
+		path := shellQuote(file)
+		script.WriteString("set -- $(git ls-files -s -- " + path + ")\ncase \"$2\" in\n")
+		for blob := range blobs {
+			if strings.Trim(blob, "0") == "" {
+				continue
+			}
+
+			data, err := g.gitBytes("cat-file", "blob", blob)
+			if err != nil {
+				return 0, err
+			}
+			newData, err := rewrite(file, data)
+			if err != nil {
+				return 0, err
+			}
+			if string(newData) == string(data) {
+				continue
+			}
+
+			newBlob, err := g.hashObject(newData)
+			if err != nil {
+				return 0, err
+			}
+			script.WriteString(blob + ") git update-index --cacheinfo \"$1\"," + newBlob + "," + path + " ;;\n")
+			rewritten++
+		}
+		script.WriteString("esac\n")
+	}
+
+	if rewritten == 0 {
+		return 0, nil
+	}
+
+	cmd := exec.Command("git", "-C", g.absDbPath, "filter-branch", "-f", "--index-filter", script.String(), "--tag-name-filter", "cat", "--", "--branches", "--tags")
+	cmd.Env = append(os.Environ(), "FILTER_BRANCH_SQUELCH_WARNING=1")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		log.Error(string(out))
+		return 0, errors.New(string(out))
+	}
+
+	//drop the backups filter-branch keeps of the old history
+	backups, err := g.git("for-each-ref", "--format=%(refname)", "refs/original/")
+	if err != nil {
+		return 0, err
+	}
+	for _, ref := range strings.Fields(backups) {
+		if _, err := g.git("update-ref", "-d", ref); err != nil {
+			return 0, err
+		}
+	}
+
+	return rewritten, nil
+}
+
+//prune removes commits and files no longer reachable from any ref, including those in reflogs
+func (g *gitBinary) prune() error {
+	if _, err := g.git("reflog", "expire", "--expire=now", "--all"); err != nil {
+		return err
+	}
+	return g.gc()
+}
+
+//gitBytes runs a git command against the database repository and returns its output as is
+func (g *gitBinary) gitBytes(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", g.absDbPath}, args...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return out, nil
+}
+
+//hashObject writes data to the repository as a blob and returns its hash
+func (g *gitBinary) hashObject(data []byte) (string, error) {
+	cmd := exec.Command("git", "-C", g.absDbPath, "hash-object", "-w", "--stdin")
+	cmd.Stdin = bytes.NewReader(data)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		log.Error(string(out))
+		return "", errors.New(string(out))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+//shellQuote quotes s for use as a single word in a shell script
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+//commitTree creates a commit with the tree of commit, the given parent and message. Unlike git commit,
+//commit-tree ignores commit.gpgsign so the commit is signed explicitly when signing is configured
+func (g *gitBinary) commitTree(commit string, parent string, msg string, env []string) (string, error) {
+	args := []string{"-C", g.absDbPath, "commit-tree", commit + "^{tree}", "-F", "-"}
+	if len(parent) > 0 {
+		args = append(args, "-p", parent)
+	}
+	//git config exits with an error when the setting is missing
+	if sign, _ := exec.Command("git", "-C", g.absDbPath, "config", "--bool", "commit.gpgsign").Output(); strings.TrimSpace(string(sign)) == "true" {
+		args = append(args, "-S")
+	}
+
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = strings.NewReader(msg)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		log.Error(string(out))
+		return "", errors.New(string(out))
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (g *gitBinary) revParse(rev string) (string, error) {
+	return g.git("rev-parse", "--verify", "--end-of-options", rev)
+}
+
+//git runs a git command against the database repository and returns its trimmed output
+func (g *gitBinary) git(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", g.absDbPath}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		log.Error(string(out))
+		return "", errors.New(string(out))
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+//forcePush replaces every branch and tag on remote with the local ones, e.g after their history is rewritten
+func (g *gitBinary) forcePush(remote string) error {
+	_, err := g.git("push", "--force", remote, "refs/heads/*:refs/heads/*", "refs/tags/*:refs/tags/*")
+	return err
+}
+
+func (g *gitBinary) setRemote(name string, url string) error {
+	remotes, err := g.git("remote")
+	if err != nil {
+		return err
+	}
+
+	for _, remote := range strings.Fields(remotes) {
+		if remote == name {
+			_, err := g.git("remote", "set-url", name, url)
+			return err
+		}
+	}
+
+	_, err = g.git("remote", "add", name, url)
+	return err
+}
+
+//lsTree lists the files, or directories if dirsOnly, in path at rev
+func (g *gitBinary) lsTree(rev string, path string, dirsOnly bool) ([]string, error) {
+	args := []string{"-C", g.absDbPath, "ls-tree", "--name-only"}
+	if dirsOnly {
+		args = append(args, "-d")
+	}
+	args = append(args, "--end-of-options", rev)
+	if len(path) > 0 {
+		args = append(args, "--", path)
+	}
+
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		//repository has no commits yet
+		if strings.Contains(string(out), "Not a valid object name") {
+			return nil, nil
+		}
+		return nil, errors.New(string(out))
+	}
+
+	return strings.Fields(string(out)), nil
+}
+
+//fastForward moves the current branch to the online remote without touching the working tree
+func (g *gitBinary) fastForward() error {
+	branch, err := g.currentBranch()
+	if err != nil {
+		return err
+	}
+
+	if _, err := g.git("fetch", onlineRemote, branch); err != nil {
+		return err
+	}
+
+	_, err = g.git("update-ref", "refs/heads/"+branch, "FETCH_HEAD")
+	return err
+}
+
+func (g *gitBinary) tag(name string, msg string, user *User) error {
+	_, err := g.git("-c", "user.name="+user.Name, "-c", "user.email="+user.Email, "tag", "-a", name, "-m", msg)
+	return err
+}
+
+func (g *gitBinary) tags() ([]*Release, error) {
+	out, err := g.git("for-each-ref", "refs/tags", "--sort=creatordate",
+		"--format=%(refname:short)%1f%(objectname)%1f%(*objectname)%1f%(creatordate:unix)%1f%(contents:subject)")
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []*Release
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Split(line, "\x1f")
+		if len(f) != 5 {
+			continue
+		}
+
+		//annotated tags point to a tag object, lightweight tags point straight to a commit
+		commit := f[2]
+		if len(commit) == 0 {
+			commit = f[1]
+		}
+
+		unix, _ := strconv.ParseInt(f[3], 10, 64)
+		releases = append(releases, &Release{Name: f[0], Commit: commit, Time: time.Unix(unix, 0), Message: f[4]})
+	}
+
+	return releases, nil
+}
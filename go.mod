@@ -1,6 +1,6 @@
 module github.com/gogitdb/gitdb/v2
 
-go 1.14
+go 1.16
 
 require (
 	github.com/bouggo/log v0.0.1
@@ -0,0 +1,622 @@
+package gitdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+const defaultGraphQLFirst = 50
+
+var graphQLName = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+//gqlSchema is the GraphQL schema derived from the models of the datasets a request can read
+type gqlSchema struct {
+	datasets []*gqlDataset
+	byName   map[string]*gqlDataset
+}
+
+//gqlDataset is the GraphQL type of the records of a dataset
+type gqlDataset struct {
+	name   string
+	fields []*gqlField
+	byName map[string]*gqlField
+}
+
+//gqlField is a field of a record. Fields of refs resolve the record the index ref holds the id of
+type gqlField struct {
+	name string
+	typ  string
+	ref  *ref
+}
+
+//gqlError is an error of a GraphQL response
+type gqlError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+//gqlObject is a result object which keeps its fields in the order they were selected
+type gqlObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *gqlObject) set(key string, value interface{}) {
+	if o.values == nil {
+		o.values = map[string]interface{}{}
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+//MarshalJSON marshals the fields of o in order
+func (o *gqlObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+//gqlRequest is the body of a GraphQL request
+type gqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+//graphQLEndpoints maps the paths of the GraphQL endpoint to their handlers
+func (u *router) graphQLEndpoints() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/graphql":        u.graphQL,
+		"/graphql/schema": u.graphQLSchema,
+	}
+}
+
+//graphQL executes the GraphQL request of r
+func (u *router) graphQL(w http.ResponseWriter, r *http.Request) {
+	if !u.apiAccepts(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	req := &gqlRequest{}
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		req.Query, req.OperationName = query.Get("query"), query.Get("operationName")
+		if v := query.Get("variables"); len(v) > 0 {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				apiFail(w, &apiErr{http.StatusBadRequest, "variables must be a JSON object"})
+				return
+			}
+		}
+	default:
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "application/json":
+			if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+				apiFail(w, &apiErr{http.StatusBadRequest, "invalid JSON: " + err.Error()})
+				return
+			}
+		case "application/graphql":
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				apiFail(w, &apiErr{http.StatusBadRequest, err.Error()})
+				return
+			}
+			req.Query = string(body)
+		default:
+			apiFail(w, &apiErr{http.StatusUnsupportedMediaType, "GraphQL requests must be posted as application/json or application/graphql"})
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, u.executeGraphQL(r, req))
+}
+
+//graphQLSchema serves the schema the request can query in the GraphQL schema language
+func (u *router) graphQLSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(u.graphQLTypes(r).sdl()))
+}
+
+//graphQLTypes derives the GraphQL schema from the models of the datasets the request can read, found with
+//RegisterModel or Config.Factory. Polymorphic datasets of Config.Types and datasets whose names can't be
+//GraphQL names are left out
+func (u *router) graphQLTypes(r *http.Request) *gqlSchema {
+	names, _ := u.db.datasetNames()
+	registryMu.RLock()
+	for name := range registry {
+		names = append(names, name)
+	}
+	registryMu.RUnlock()
+	sort.Strings(names)
+
+	schema := &gqlSchema{byName: map[string]*gqlDataset{}}
+	models := map[string]Model{}
+	for _, name := range names {
+		if _, ok := schema.byName[name]; ok || !graphQLName.MatchString(name) || strings.HasPrefix(name, "__") {
+			continue
+		}
+		if _, polymorphic := u.cfg.Types[name]; polymorphic || u.can(r, name, PermRead) != nil {
+			continue
+		}
+		m := u.cfg.factory(name)
+		if m == nil {
+			continue
+		}
+
+		properties := map[string]interface{}{}
+		if s, err := modelJSONSchema(m); err == nil {
+			properties, _ = s["properties"].(map[string]interface{})
+		}
+
+		dataset := &gqlDataset{name: name, byName: map[string]*gqlField{}}
+		dataset.add(&gqlField{name: "id", typ: "ID!"})
+		dataset.add(&gqlField{name: "revision", typ: "Int!"})
+		fieldNames := make([]string, 0, len(properties))
+		for fieldName := range properties {
+			fieldNames = append(fieldNames, fieldName)
+		}
+		sort.Strings(fieldNames)
+		for _, fieldName := range fieldNames {
+			property, _ := properties[fieldName].(map[string]interface{})
+			if graphQLName.MatchString(fieldName) && !strings.HasPrefix(fieldName, "__") {
+				dataset.add(&gqlField{name: fieldName, typ: graphQLScalar(property)})
+			}
+		}
+
+		schema.datasets = append(schema.datasets, dataset)
+		schema.byName[name] = dataset
+		models[name] = m
+	}
+
+	//fields of refs are added once every dataset is known so only refs to datasets of the schema are
+	for _, dataset := range schema.datasets {
+		for _, rf := range schemaOf(models[dataset.name]).refs {
+			rf := rf
+			if _, ok := schema.byName[rf.Target]; ok {
+				dataset.add(&gqlField{name: rf.Field + "Ref", typ: rf.Target, ref: &rf})
+			}
+		}
+	}
+	return schema
+}
+
+//add adds f to d unless d has a field of the same name
+func (d *gqlDataset) add(f *gqlField) {
+	if _, ok := d.byName[f.name]; ok {
+		return
+	}
+	d.fields = append(d.fields, f)
+	d.byName[f.name] = f
+}
+
+//graphQLScalar returns the GraphQL type of values described by the JSON Schema property
+func graphQLScalar(property map[string]interface{}) string {
+	typ, _ := property["type"].(string)
+	if types, ok := property["type"].([]string); ok {
+		typ = types[0]
+	}
+	switch typ {
+	case "string":
+		return "String"
+	case "integer":
+		return "Int"
+	case "number":
+		return "Float"
+	case "boolean":
+		return "Boolean"
+	}
+	return "JSON"
+}
+
+//sdl returns s in the GraphQL schema language
+func (s *gqlSchema) sdl() string {
+	var b strings.Builder
+	b.WriteString("\"\"\"Any JSON value\"\"\"\nscalar JSON\n")
+	for _, d := range s.datasets {
+		fmt.Fprintf(&b, "\ntype %s {\n", d.name)
+		for _, f := range d.fields {
+			fmt.Fprintf(&b, "  %s: %s\n", f.name, f.typ)
+		}
+		fmt.Fprintf(&b, "}\n\ntype %sPage {\n  total: Int!\n  records: [%s!]!\n}\n", d.name, d.name)
+	}
+
+	b.WriteString("\ntype Query {\n")
+	for _, d := range s.datasets {
+		fmt.Fprintf(&b, "  %s(id: ID!): %s\n", d.name, d.name)
+		fmt.Fprintf(&b, "  all%s(where: JSON, search: String, orderBy: String, desc: Boolean, first: Int = %d, offset: Int = 0): %sPage!\n", d.name, defaultGraphQLFirst, d.name)
+	}
+	b.WriteString("}\n")
+
+	if len(s.datasets) > 0 {
+		b.WriteString("\ntype Mutation {\n")
+		for _, d := range s.datasets {
+			fmt.Fprintf(&b, "  insert%s(record: JSON!): %s\n", d.name, d.name)
+			fmt.Fprintf(&b, "  delete%s(id: ID!): Boolean!\n", d.name)
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+//gqlExecution is the execution of a GraphQL request
+type gqlExecution struct {
+	u      *router
+	r      *http.Request
+	schema *gqlSchema
+	doc    *gqlDocument
+	vars   map[string]interface{}
+	errors []*gqlError
+}
+
+//executeGraphQL executes req returning the data it selects and the errors raised, as the response is made of
+func (u *router) executeGraphQL(r *http.Request, req *gqlRequest) map[string]interface{} {
+	fail := func(err error) map[string]interface{} {
+		return map[string]interface{}{"errors": []*gqlError{{Message: err.Error()}}}
+	}
+
+	doc, err := parseGraphQL(req.Query)
+	if err != nil {
+		return fail(err)
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return fail(err)
+	}
+	if op.kind == "subscription" {
+		return fail(fmt.Errorf("subscriptions are not supported"))
+	}
+	if op.kind == "mutation" && r.Method != http.MethodPost {
+		return fail(fmt.Errorf("mutations must be posted"))
+	}
+
+	e := &gqlExecution{u: u, r: r, schema: u.graphQLTypes(r), doc: doc, vars: map[string]interface{}{}}
+	for _, v := range op.variables {
+		value, ok := req.Variables[v.name]
+		if !ok {
+			value = v.defaultVal
+		}
+		if value == nil && v.nonNull {
+			return fail(fmt.Errorf("variable $%s is required", v.name))
+		}
+		e.vars[v.name] = value
+	}
+
+	var data *gqlObject
+	if op.kind == "mutation" {
+		data = e.selectRoot(op.selections, "Mutation", e.mutationField)
+	} else {
+		data = e.selectRoot(op.selections, "Query", e.queryField)
+	}
+
+	resp := map[string]interface{}{"data": data}
+	if len(e.errors) > 0 {
+		resp["errors"] = e.errors
+	}
+	return resp
+}
+
+//operation returns the operation of d named name, which can be empty when d has one operation
+func (d *gqlDocument) operation(name string) (*gqlOperation, error) {
+	if len(name) == 0 {
+		if len(d.operations) > 1 {
+			return nil, fmt.Errorf("operationName is required for documents with more than one operation")
+		}
+		return d.operations[0], nil
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %s", name)
+}
+
+//fail records err as the error of the field at path
+func (e *gqlExecution) fail(path []interface{}, err error) interface{} {
+	e.errors = append(e.errors, &gqlError{Message: err.Error(), Path: append([]interface{}{}, path...)})
+	return nil
+}
+
+//collect returns the fields selected on typeName by selections, following fragments and
+//dropping fields skipped by @skip and @include, with the fields of the same name merged
+func (e *gqlExecution) collect(selections []*gqlSelection, typeName string) []*gqlSelection {
+	var fields []*gqlSelection
+	byKey := map[string]*gqlSelection{}
+	var walk func(selections []*gqlSelection, depth int)
+	walk = func(selections []*gqlSelection, depth int) {
+		for _, s := range selections {
+			if !e.included(s) {
+				continue
+			}
+			switch {
+			case len(s.spread) > 0:
+				f, ok := e.doc.fragments[s.spread]
+				if ok && (f.on == typeName) && depth < 32 {
+					walk(f.selections, depth+1)
+				}
+			case s.inline:
+				if len(s.on) == 0 || s.on == typeName {
+					walk(s.selections, depth)
+				}
+			default:
+				if merged, ok := byKey[s.key()]; ok {
+					merged.selections = append(merged.selections, s.selections...)
+					continue
+				}
+				field := *s
+				field.selections = append([]*gqlSelection{}, s.selections...)
+				byKey[s.key()] = &field
+				fields = append(fields, &field)
+			}
+		}
+	}
+	walk(selections, 0)
+	return fields
+}
+
+//included applies the @skip and @include directives of s
+func (e *gqlExecution) included(s *gqlSelection) bool {
+	for _, d := range s.directives {
+		cond, _ := e.resolve(d.args["if"]).(bool)
+		if (d.name == "skip" && cond) || (d.name == "include" && !cond) {
+			return false
+		}
+	}
+	return true
+}
+
+//resolve replaces the variables of the argument value v with their values
+func (e *gqlExecution) resolve(v interface{}) interface{} {
+	switch value := v.(type) {
+	case gqlVariable:
+		return e.vars[string(value)]
+	case gqlEnum:
+		return string(value)
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i, item := range value {
+			list[i] = e.resolve(item)
+		}
+		return list
+	case map[string]interface{}:
+		obj := map[string]interface{}{}
+		for k, item := range value {
+			obj[k] = e.resolve(item)
+		}
+		return obj
+	}
+	return v
+}
+
+func (e *gqlExecution) arg(s *gqlSelection, name string) interface{} {
+	return e.resolve(s.args[name])
+}
+
+func (e *gqlExecution) selectRoot(selections []*gqlSelection, typeName string, resolve func(s *gqlSelection, path []interface{}) interface{}) *gqlObject {
+	result := &gqlObject{}
+	for _, s := range e.collect(selections, typeName) {
+		path := []interface{}{s.key()}
+		if s.name == "__typename" {
+			result.set(s.key(), typeName)
+			continue
+		}
+		result.set(s.key(), resolve(s, path))
+	}
+	return result
+}
+
+//queryField resolves a field of Query
+func (e *gqlExecution) queryField(s *gqlSelection, path []interface{}) interface{} {
+	if d, ok := e.schema.byName[s.name]; ok {
+		id := fmt.Sprint(e.arg(s, "id"))
+		if dataset, _, _, err := ParseID(id); err != nil || dataset != d.name {
+			return e.fail(path, fmt.Errorf("%s is not the id of a record of %s", id, d.name))
+		}
+		record, err := e.u.db.doget(id)
+		if err != nil {
+			return nil
+		}
+		return e.record(d, record, s.selections, path)
+	}
+
+	if d, ok := e.schema.byName[strings.TrimPrefix(s.name, "all")]; ok && strings.HasPrefix(s.name, "all") {
+		return e.page(d, s, path)
+	}
+	return e.fail(path, fmt.Errorf("Query has no field %s", s.name))
+}
+
+//page resolves the page of the records of d the arguments of s ask for
+func (e *gqlExecution) page(d *gqlDataset, s *gqlSelection, path []interface{}) interface{} {
+	q := pageQuery{dataset: d.name, where: map[string]string{}, limit: defaultGraphQLFirst}
+	q.hidden = func(index string) bool { return e.u.isRedacted(d.name, index) }
+	if where, ok := e.arg(s, "where").(map[string]interface{}); ok {
+		for index, value := range where {
+			q.where[index] = fmt.Sprint(value)
+		}
+	}
+	q.search, _ = e.arg(s, "search").(string)
+	q.sortBy, _ = e.arg(s, "orderBy").(string)
+	q.desc, _ = e.arg(s, "desc").(bool)
+	if first, ok := graphQLInt(e.arg(s, "first")); ok {
+		q.limit = first
+	}
+	if offset, ok := graphQLInt(e.arg(s, "offset")); ok {
+		q.offset = offset
+	}
+	if q.limit < 0 || q.limit > maxAPILimit || q.offset < 0 {
+		return e.fail(path, fmt.Errorf("first must be from 0 to %d and offset can't be negative", maxAPILimit))
+	}
+
+	records, total, err := e.u.db.pageRecords(q)
+	if err != nil {
+		return e.fail(path, err)
+	}
+
+	result := &gqlObject{}
+	for _, f := range e.collect(s.selections, d.name+"Page") {
+		fieldPath := append(append([]interface{}{}, path...), f.key())
+		switch f.name {
+		case "__typename":
+			result.set(f.key(), d.name+"Page")
+		case "total":
+			result.set(f.key(), total)
+		case "records":
+			list := make([]interface{}, len(records))
+			for i, record := range records {
+				list[i] = e.record(d, record, f.selections, append(fieldPath, i))
+			}
+			result.set(f.key(), list)
+		default:
+			result.set(f.key(), e.fail(fieldPath, fmt.Errorf("%sPage has no field %s", d.name, f.name)))
+		}
+	}
+	return result
+}
+
+//record resolves the fields of record selected by selections
+func (e *gqlExecution) record(d *gqlDataset, record *db.Record, selections []*gqlSelection, path []interface{}) interface{} {
+	if len(selections) == 0 {
+		return e.fail(path, fmt.Errorf("%s must have a selection of subfields", d.name))
+	}
+
+	var data map[string]interface{}
+	if err := record.Hydrate(&data); err != nil {
+		return e.fail(path, err)
+	}
+	e.u.redact(d.name, data)
+
+	result := &gqlObject{}
+	for _, s := range e.collect(selections, d.name) {
+		fieldPath := append(append([]interface{}{}, path...), s.key())
+		f, ok := d.byName[s.name]
+		switch {
+		case s.name == "__typename":
+			result.set(s.key(), d.name)
+		case !ok:
+			result.set(s.key(), e.fail(fieldPath, fmt.Errorf("%s has no field %s", d.name, s.name)))
+		case f.ref != nil:
+			result.set(s.key(), e.ref(f, record, s, fieldPath))
+		case len(s.selections) > 0:
+			result.set(s.key(), e.fail(fieldPath, fmt.Errorf("%s.%s is a %s which has no subfields", d.name, f.name, f.typ)))
+		case f.name == "id" && f.typ == "ID!":
+			result.set(s.key(), record.ID())
+		case f.name == "revision" && f.typ == "Int!":
+			result.set(s.key(), record.Revision())
+		default:
+			result.set(s.key(), data[f.name])
+		}
+	}
+	return result
+}
+
+//ref resolves the record the index of the ref field f of record holds the id of
+func (e *gqlExecution) ref(f *gqlField, record *db.Record, s *gqlSelection, path []interface{}) interface{} {
+	value, ok := record.Indexes()[f.ref.Field]
+	if !ok || value == nil || fmt.Sprint(value) == "" {
+		return nil
+	}
+	id, ok := e.u.db.refTarget(f.ref.Target, value)
+	if !ok {
+		return nil
+	}
+	target, err := e.u.db.doget(id)
+	if err != nil {
+		return nil
+	}
+	return e.record(e.schema.byName[f.ref.Target], target, s.selections, path)
+}
+
+//mutationField resolves a field of Mutation
+func (e *gqlExecution) mutationField(s *gqlSelection, path []interface{}) interface{} {
+	switch {
+	case strings.HasPrefix(s.name, "insert"):
+		if d, ok := e.schema.byName[strings.TrimPrefix(s.name, "insert")]; ok {
+			return e.insert(d, s, path)
+		}
+	case strings.HasPrefix(s.name, "delete"):
+		if d, ok := e.schema.byName[strings.TrimPrefix(s.name, "delete")]; ok {
+			return e.delete(d, s, path)
+		}
+	}
+	return e.fail(path, fmt.Errorf("Mutation has no field %s", s.name))
+}
+
+//insert decodes the record argument of s into the model of d and inserts it
+func (e *gqlExecution) insert(d *gqlDataset, s *gqlSelection, path []interface{}) interface{} {
+	if err := e.u.canWrite(e.r, d.name, PermWrite); err != nil {
+		return e.fail(path, err)
+	}
+
+	data, err := json.Marshal(e.arg(s, "record"))
+	if err != nil {
+		return e.fail(path, err)
+	}
+	m := e.u.cfg.factory(d.name)
+	if err := json.Unmarshal(data, m); err != nil {
+		return e.fail(path, fmt.Errorf("record is not a %s: %s", d.name, err))
+	}
+	if err := e.u.apiConn(e.r).Insert(m); err != nil {
+		return e.fail(path, err)
+	}
+
+	record, err := e.u.db.doget(ID(m))
+	if err != nil {
+		return e.fail(path, err)
+	}
+	return e.record(d, record, s.selections, path)
+}
+
+//delete deletes the record of d with the id argument of s
+func (e *gqlExecution) delete(d *gqlDataset, s *gqlSelection, path []interface{}) interface{} {
+	if err := e.u.canWrite(e.r, d.name, PermDelete); err != nil {
+		return e.fail(path, err)
+	}
+
+	id := fmt.Sprint(e.arg(s, "id"))
+	if dataset, _, _, err := ParseID(id); err != nil || dataset != d.name {
+		return e.fail(path, fmt.Errorf("%s is not the id of a record of %s", id, d.name))
+	}
+	if err := e.u.apiConn(e.r).DeleteOrFail(id); err != nil {
+		return e.fail(path, err)
+	}
+	return true
+}
+
+//graphQLInt returns v as an int if it is a whole number
+func graphQLInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int64:
+		return int(n), true
+	case float64:
+		if n == float64(int(n)) {
+			return int(n), true
+		}
+	}
+	return 0, false
+}
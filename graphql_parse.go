@@ -0,0 +1,586 @@
+package gitdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	gqlPunct = iota
+	gqlName
+	gqlInt
+	gqlFloat
+	gqlString
+	gqlEOF
+)
+
+type gqlToken struct {
+	kind  int
+	value string
+	pos   int
+}
+
+//gqlDocument is a parsed GraphQL request document
+type gqlDocument struct {
+	operations []*gqlOperation
+	fragments  map[string]*gqlFragment
+}
+
+//gqlOperation is a query or mutation of a document
+type gqlOperation struct {
+	kind       string
+	name       string
+	variables  []*gqlVariableDef
+	selections []*gqlSelection
+}
+
+type gqlVariableDef struct {
+	name       string
+	nonNull    bool
+	defaultVal interface{}
+}
+
+type gqlFragment struct {
+	name       string
+	on         string
+	selections []*gqlSelection
+}
+
+//gqlSelection is a field, a fragment spread when spread is set or an inline fragment when inline is set
+type gqlSelection struct {
+	alias      string
+	name       string
+	args       map[string]interface{}
+	directives []*gqlDirective
+	selections []*gqlSelection
+
+	spread string
+	inline bool
+	on     string
+}
+
+type gqlDirective struct {
+	name string
+	args map[string]interface{}
+}
+
+//gqlVariable is a reference to a variable in a value
+type gqlVariable string
+
+//gqlEnum is an enum value, which is resolved as its name
+type gqlEnum string
+
+//key returns the name the field is returned as
+func (s *gqlSelection) key() string {
+	if len(s.alias) > 0 {
+		return s.alias
+	}
+	return s.name
+}
+
+type gqlParser struct {
+	tokens []gqlToken
+	i      int
+}
+
+//parseGraphQL parses the executable definitions of a GraphQL document
+func parseGraphQL(src string) (*gqlDocument, error) {
+	tokens, err := lexGraphQL(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &gqlParser{tokens: tokens}
+	doc := &gqlDocument{fragments: map[string]*gqlFragment{}}
+	for p.peek().kind != gqlEOF {
+		switch {
+		case p.peekPunct("{"):
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &gqlOperation{kind: "query", selections: selections})
+		case p.peekName("query"), p.peekName("mutation"), p.peekName("subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.peekName("fragment"):
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments[f.name] = f
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("the document has no operation")
+	}
+	return doc, nil
+}
+
+func (p *gqlParser) peek() gqlToken {
+	return p.tokens[p.i]
+}
+
+func (p *gqlParser) next() gqlToken {
+	t := p.tokens[p.i]
+	if t.kind != gqlEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *gqlParser) peekPunct(punct string) bool {
+	t := p.peek()
+	return t.kind == gqlPunct && t.value == punct
+}
+
+func (p *gqlParser) peekName(name string) bool {
+	t := p.peek()
+	return t.kind == gqlName && t.value == name
+}
+
+func (p *gqlParser) unexpected() error {
+	t := p.peek()
+	if t.kind == gqlEOF {
+		return fmt.Errorf("syntax error: unexpected end of document")
+	}
+	return fmt.Errorf("syntax error: unexpected %q at %d", t.value, t.pos)
+}
+
+func (p *gqlParser) expectPunct(punct string) error {
+	if !p.peekPunct(punct) {
+		return p.unexpected()
+	}
+	p.next()
+	return nil
+}
+
+func (p *gqlParser) name() (string, error) {
+	if p.peek().kind != gqlName {
+		return "", p.unexpected()
+	}
+	return p.next().value, nil
+}
+
+func (p *gqlParser) operation() (*gqlOperation, error) {
+	op := &gqlOperation{kind: p.next().value}
+	if p.peek().kind == gqlName {
+		op.name = p.next().value
+	}
+
+	if p.peekPunct("(") {
+		p.next()
+		for !p.peekPunct(")") {
+			if err := p.expectPunct("$"); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct(":"); err != nil {
+				return nil, err
+			}
+			nonNull, err := p.typeRef()
+			if err != nil {
+				return nil, err
+			}
+
+			def := &gqlVariableDef{name: name, nonNull: nonNull}
+			if p.peekPunct("=") {
+				p.next()
+				if def.defaultVal, err = p.value(true); err != nil {
+					return nil, err
+				}
+			}
+			op.variables = append(op.variables, def)
+		}
+		p.next()
+	}
+
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	selections, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selections = selections
+	return op, nil
+}
+
+//typeRef skips the type of a variable and reports whether it is non null
+func (p *gqlParser) typeRef() (bool, error) {
+	if p.peekPunct("[") {
+		p.next()
+		if _, err := p.typeRef(); err != nil {
+			return false, err
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return false, err
+		}
+	} else if _, err := p.name(); err != nil {
+		return false, err
+	}
+
+	if p.peekPunct("!") {
+		p.next()
+		return true, nil
+	}
+	return false, nil
+}
+
+func (p *gqlParser) fragment() (*gqlFragment, error) {
+	p.next()
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if !p.peekName("on") {
+		return nil, p.unexpected()
+	}
+	p.next()
+	on, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	selections, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return &gqlFragment{name: name, on: on, selections: selections}, nil
+}
+
+func (p *gqlParser) selectionSet() ([]*gqlSelection, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+
+	var selections []*gqlSelection
+	for !p.peekPunct("}") {
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, s)
+	}
+	p.next()
+
+	if len(selections) == 0 {
+		return nil, p.unexpected()
+	}
+	return selections, nil
+}
+
+func (p *gqlParser) selection() (*gqlSelection, error) {
+	var err error
+	s := &gqlSelection{}
+	if p.peekPunct("...") {
+		p.next()
+		switch {
+		case p.peekName("on"):
+			p.next()
+			if s.on, err = p.name(); err != nil {
+				return nil, err
+			}
+			s.inline = true
+		case p.peek().kind == gqlName:
+			s.spread = p.next().value
+		default:
+			s.inline = true
+		}
+		if s.directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		if s.inline {
+			if s.selections, err = p.selectionSet(); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	}
+
+	if s.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if p.peekPunct(":") {
+		p.next()
+		s.alias = s.name
+		if s.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if s.args, err = p.arguments(); err != nil {
+		return nil, err
+	}
+	if s.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peekPunct("{") {
+		if s.selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (p *gqlParser) arguments() (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	if !p.peekPunct("(") {
+		return args, nil
+	}
+
+	p.next()
+	for !p.peekPunct(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		if args[name], err = p.value(false); err != nil {
+			return nil, err
+		}
+	}
+	p.next()
+	return args, nil
+}
+
+func (p *gqlParser) directives() ([]*gqlDirective, error) {
+	var directives []*gqlDirective
+	for p.peekPunct("@") {
+		p.next()
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.arguments()
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, &gqlDirective{name: name, args: args})
+	}
+	return directives, nil
+}
+
+//value parses a value. Constant values, such as the defaults of variables, can't hold variables
+func (p *gqlParser) value(constant bool) (interface{}, error) {
+	t := p.peek()
+	switch t.kind {
+	case gqlInt:
+		p.next()
+		return strconv.ParseInt(t.value, 10, 64)
+	case gqlFloat:
+		p.next()
+		return strconv.ParseFloat(t.value, 64)
+	case gqlString:
+		p.next()
+		return t.value, nil
+	case gqlName:
+		p.next()
+		switch t.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return gqlEnum(t.value), nil
+	}
+
+	switch {
+	case p.peekPunct("$") && !constant:
+		p.next()
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		return gqlVariable(name), nil
+	case p.peekPunct("["):
+		p.next()
+		list := []interface{}{}
+		for !p.peekPunct("]") {
+			v, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		p.next()
+		return list, nil
+	case p.peekPunct("{"):
+		p.next()
+		obj := map[string]interface{}{}
+		for !p.peekPunct("}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct(":"); err != nil {
+				return nil, err
+			}
+			if obj[name], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		p.next()
+		return obj, nil
+	}
+	return nil, p.unexpected()
+}
+
+//lexGraphQL splits src into tokens, dropping whitespace, commas and comments
+func lexGraphQL(src string) ([]gqlToken, error) {
+	var tokens []gqlToken
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' && src[i] != '\r' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "..."):
+			tokens = append(tokens, gqlToken{kind: gqlPunct, value: "...", pos: i})
+			i += 3
+		case strings.IndexByte("!$()-:=@[]{}|&", c) >= 0 && !(c == '-' && i+1 < len(src) && isDigit(src[i+1])):
+			tokens = append(tokens, gqlToken{kind: gqlPunct, value: string(c), pos: i})
+			i++
+		case c == '_' || isLetter(c):
+			start := i
+			for i < len(src) && (src[i] == '_' || isLetter(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			tokens = append(tokens, gqlToken{kind: gqlName, value: src[start:i], pos: start})
+		case c == '-' || isDigit(c):
+			start := i
+			kind := gqlInt
+			i++
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+			if i < len(src) && src[i] == '.' {
+				kind = gqlFloat
+				i++
+				for i < len(src) && isDigit(src[i]) {
+					i++
+				}
+			}
+			if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+				kind = gqlFloat
+				i++
+				if i < len(src) && (src[i] == '+' || src[i] == '-') {
+					i++
+				}
+				for i < len(src) && isDigit(src[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, gqlToken{kind: kind, value: src[start:i], pos: start})
+		case strings.HasPrefix(src[i:], `"""`):
+			end := strings.Index(src[i+3:], `"""`)
+			if end < 0 {
+				return nil, fmt.Errorf("syntax error: unterminated string at %d", i)
+			}
+			tokens = append(tokens, gqlToken{kind: gqlString, value: blockString(src[i+3 : i+3+end]), pos: i})
+			i += end + 6
+		case c == '"':
+			s, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("syntax error: %s at %d", err, i)
+			}
+			tokens = append(tokens, gqlToken{kind: gqlString, value: s, pos: i})
+			i += n
+		default:
+			r, _ := utf8.DecodeRuneInString(src[i:])
+			return nil, fmt.Errorf("syntax error: unexpected character %q at %d", r, i)
+		}
+	}
+	return append(tokens, gqlToken{kind: gqlEOF, pos: len(src)}), nil
+}
+
+//lexString reads the quoted string at the start of src and returns it unescaped with its length
+func lexString(src string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		switch c := src[i]; c {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\n', '\r':
+			return "", 0, fmt.Errorf("unterminated string")
+		case '\\':
+			i++
+			if i >= len(src) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			switch e := src[i]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'u':
+				if i+4 >= len(src) {
+					return "", 0, fmt.Errorf("invalid unicode escape")
+				}
+				r, err := strconv.ParseUint(src[i+1:i+5], 16, 32)
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid unicode escape")
+				}
+				b.WriteRune(rune(r))
+				i += 4
+			default:
+				b.WriteByte(e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+//blockString returns the value of a block string with its common indentation removed
+func blockString(raw string) string {
+	lines := strings.Split(strings.Replace(raw, `\"""`, `"""`, -1), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if len(trimmed) > 0 && (indent < 0 || len(line)-len(trimmed) < indent) {
+			indent = len(line) - len(trimmed)
+		}
+	}
+	for i := 1; i < len(lines) && indent > 0; i++ {
+		if len(lines[i]) >= indent {
+			lines[i] = lines[i][indent:]
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
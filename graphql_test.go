@@ -0,0 +1,119 @@
+package gitdb_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestGraphQL(t *testing.T) {
+	cfg := getConfig()
+	cfg.EnableUI = true
+	cfg.UIPort = 4134
+	cfg.GraphQL = true
+	cfg.APIWrites = true
+	cfg.Factory = func(dataset string) gitdb.Model {
+		if dataset == "Message" {
+			return &Message{}
+		}
+		return nil
+	}
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	for i := 1; i <= 5; i++ {
+		m := getTestMessageWithId(i)
+		if i%2 == 0 {
+			m.From = "carol@example.com"
+		}
+		insert(m, false)
+	}
+
+	query := func(q string, variables map[string]interface{}) string {
+		body, _ := json.Marshal(map[string]interface{}{"query": q, "variables": variables})
+		resp, err := http.Post("http://localhost:4134/graphql", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("GraphQL request failed: %s", err)
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return string(b)
+	}
+
+	got := query(`{ Message(id: "Message/b0/1") { id revision Body } }`, nil)
+	if want := `{"data":{"Message":{"id":"Message/b0/1","revision":1,"Body":"Hello"}}}`; strings.TrimSpace(got) != want {
+		t.Errorf("want: %s, got: %s", want, got)
+	}
+
+	got = query(`query Carol($from: String!, $first: Int = 1) {
+		page: allMessage(where: {From: $from}, orderBy: "id", desc: true, first: $first) {
+			total
+			records { ...message }
+		}
+	}
+	fragment message on Message { id From }`, map[string]interface{}{"from": "carol@example.com"})
+	if want := `{"data":{"page":{"total":2,"records":[{"id":"Message/b0/4","From":"carol@example.com"}]}}}`; strings.TrimSpace(got) != want {
+		t.Errorf("want: %s, got: %s", want, got)
+	}
+
+	if got := query(`{ Message(id: "Message/b0/1") { Subject } }`, nil); !strings.Contains(got, `"message":"Message has no field Subject"`) {
+		t.Errorf("want: error for unknown field, got: %s", got)
+	}
+	if got := query(`{ Message(id: "Message/b0/1") { id `, nil); !strings.Contains(got, "syntax error") {
+		t.Errorf("want: syntax error, got: %s", got)
+	}
+
+	got = query(`mutation { insertMessage(record: {MessageId: 6, From: "dave@example.com", Body: "Hi"}) { id From } }`, nil)
+	if want := `{"data":{"insertMessage":{"id":"Message/b0/6","From":"dave@example.com"}}}`; strings.TrimSpace(got) != want {
+		t.Errorf("want: %s, got: %s", want, got)
+	}
+	if got := query(`mutation { deleteMessage(id: "Message/b0/6") }`, nil); !strings.Contains(got, `"deleteMessage":true`) {
+		t.Errorf("want: Message/b0/6 deleted, got: %s", got)
+	}
+	if err := testDb.Exists("Message/b0/6"); err == nil {
+		t.Error("want: Message/b0/6 deleted")
+	}
+
+	resp, err := http.Get("http://localhost:4134/graphql/schema")
+	if err != nil {
+		t.Fatalf("GraphQL schema request failed: %s", err)
+	}
+	schema, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{"type Message {", "  From: String\n", "  allMessage(", "  insertMessage(record: JSON!): Message\n"} {
+		if !strings.Contains(string(schema), want) {
+			t.Errorf("want: %q in schema, got: %s", want, schema)
+		}
+	}
+}
+
+func TestGraphQLReadOnly(t *testing.T) {
+	cfg := getConfig()
+	cfg.EnableUI = true
+	cfg.UIPort = 4136
+	cfg.GraphQL = true
+	cfg.Factory = func(dataset string) gitdb.Model { return &Message{} }
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	insert(getTestMessageWithId(1), false)
+
+	body := `{"query": "mutation { deleteMessage(id: \"Message/b0/1\") }"}`
+	resp, err := http.Post("http://localhost:4136/graphql", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("GraphQL request failed: %s", err)
+	}
+	got, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(got), "Config.APIWrites") {
+		t.Errorf("want: mutations refused without Config.APIWrites, got: %s", got)
+	}
+	if err := testDb.Exists("Message/b0/1"); err != nil {
+		t.Errorf("want: Message/b0/1 kept, got: %s", err)
+	}
+}
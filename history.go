@@ -0,0 +1,176 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bouggo/log"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//Change describes a commit that changed a record
+type Change struct {
+	Commit    string
+	Author    *User
+	Time      time.Time
+	Operation string
+	Message   string
+}
+
+//History returns the commits that changed the record with the given id, most recent first
+func (g *gitdb) History(id string) ([]*Change, error) {
+
+	dataset, block, _, err := ParseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := g.gitDriver.log(dataset + "/" + block + ".json")
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []*Change
+	for _, entry := range entries {
+		info, err := ParseCommitInfo(entry.message)
+		if err != nil {
+			//commits made before metadata trailers only mention the id in their message
+			if !strings.Contains(entry.message+" ", id+" ") {
+				continue
+			}
+			info = &CommitInfo{Message: strings.TrimSpace(entry.message)}
+		} else if !info.has(id) {
+			continue
+		}
+
+		changes = append(changes, &Change{
+			Commit:    entry.hash,
+			Author:    entry.author,
+			Time:      entry.time,
+			Operation: info.Operation,
+			Message:   info.Message,
+		})
+	}
+
+	return changes, nil
+}
+
+//RevertRecord restores the record with the given id to its state at commit
+func (g *gitdb) RevertRecord(id string, commit string) error {
+	return g.revertRecord(id, commit, nil)
+}
+
+func (g *gitdb) revertRecord(id string, commit string, user *User) error {
+	if err := g.writable(); err != nil {
+		return err
+	}
+
+	dataset, block, key, err := ParseID(id)
+	if err != nil {
+		return err
+	}
+
+	data, err := g.gitDriver.show(commit, dataset+"/"+block+".json")
+	if err != nil {
+		return fmt.Errorf("Record %s not found at %s", id, commit)
+	}
+
+	oldBlock := db.NewEmptyBlock(g.keyring(dataset))
+	if err := json.Unmarshal(data, oldBlock); err != nil {
+		return errBadBlock
+	}
+
+	record, err := oldBlock.Get(id)
+	if err != nil {
+		return fmt.Errorf("Record %s not found at %s", id, commit)
+	}
+
+	reverted := &revertedRecord{dataset: dataset, block: block, key: key, whole: !json.Valid([]byte(record.Data()))}
+	if err := record.Hydrate(&reverted.data); err != nil {
+		return err
+	}
+	reverted.indexes = record.Indexes()
+	reverted.encrypted = record.EncryptedFields()
+
+	//the record is written like any other so it is queued, scanned, audited and published
+	m := wrap(reverted)
+	m.schema = reverted.GetSchema()
+	m.Indexes = m.schema.indexes
+	m.Type = record.Type()
+	m.revert = commit
+
+	defer g.writeQueue.enter(g.config.WriteConcurrency, dataset)()
+	return g.write(m, user)
+}
+
+//revertedRecord is the data of a record at an earlier commit, written back with the indexes it had
+//and encrypted the way it was
+type revertedRecord struct {
+	dataset string
+	block   string
+	key     string
+	indexes map[string]interface{}
+	data    json.RawMessage
+	//encrypted holds the fields that were encrypted inside the record and whole if the record was encrypted
+	encrypted []string
+	whole     bool
+}
+
+func (r *revertedRecord) GetSchema() *Schema {
+	indexes := make(map[string]interface{}, len(r.indexes))
+	for name, value := range r.indexes {
+		indexes[name] = value
+	}
+	return NewSchema(r.dataset, r.block, r.key, indexes).EncryptFields(r.encrypted...)
+}
+
+func (r *revertedRecord) Validate() error              { return nil }
+func (r *revertedRecord) IsLockable() bool             { return false }
+func (r *revertedRecord) GetLockFileNames() []string   { return nil }
+func (r *revertedRecord) ShouldEncrypt() bool          { return r.whole }
+func (r *revertedRecord) BeforeInsert() error          { return nil }
+func (r *revertedRecord) MarshalJSON() ([]byte, error) { return r.data, nil }
+
+//SquashHistory rewrites all commits made before the given time into a single baseline commit
+//to keep the repository small. If an online remote is set the rewritten history is force pushed,
+//which must be allowed with Config.AllowForcePush, and other nodes will have to clone afresh
+func (g *gitdb) SquashHistory(before time.Time) error {
+	if err := g.writable(); err != nil {
+		return err
+	}
+
+	hasRemote := len(g.config.OnlineRemote) > 0
+	if hasRemote && !g.config.AllowForcePush {
+		return errors.New("SquashHistory requires Config.AllowForcePush when an online remote is set")
+	}
+
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	info := newCommitInfo("squash", "Squashing history before "+before.Format(time.RFC3339), nil)
+	squashed, err := g.gitDriver.squash(before, g.commitMessage(info), g.config.User)
+	if err != nil {
+		return err
+	}
+
+	if squashed == 0 {
+		log.Info("No history to squash before " + before.Format(time.RFC3339))
+		return nil
+	}
+
+	log.Info(fmt.Sprintf("Squashed %d commits before %s", squashed, before.Format(time.RFC3339)))
+
+	if hasRemote {
+		if err := g.forcePushAll(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,276 @@
+package gitdb_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func headCommit(t *testing.T) string {
+	out, err := exec.Command("git", "-C", filepath.Join(dbPath, "data"), "rev-parse", "HEAD").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git rev-parse failed: %s", out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestRevertRecord(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	m := getTestMessageWithId(0)
+	if err := insert(m, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+	commit := headCommit(t)
+
+	m.Body = "Changed"
+	if err := insert(m, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	if err := testDb.RevertRecord(gitdb.ID(m), commit); err != nil {
+		t.Fatalf("testDb.RevertRecord failed: %s", err)
+	}
+
+	result := &Message{}
+	if err := testDb.Get(gitdb.ID(m), result); err != nil {
+		t.Fatalf("testDb.Get failed: %s", err)
+	}
+
+	if result.Body != "Hello" {
+		t.Errorf("want: Hello, got: %s", result.Body)
+	}
+
+	//reverts are written like any other write
+	if revision, err := testDb.Revision(gitdb.ID(m)); err != nil || revision != 3 {
+		t.Errorf("want: revision 3, got: %d %v", revision, err)
+	}
+	if changes, err := testDb.History(gitdb.ID(m)); err != nil || changes[0].Operation != "revert" {
+		t.Errorf("want: revert recorded in history, got: %v", err)
+	}
+
+	if err := testDb.RevertRecord("Message/b0/99", commit); err == nil {
+		t.Errorf("testDb.RevertRecord should fail for record not in commit")
+	}
+
+	//a commit that git would read as an option to write Message/b0.json to output:Message/b0.json
+	output, _ := filepath.Abs(filepath.Join(dbPath, "output"))
+	os.MkdirAll(output+":Message", 0755)
+	if err := testDb.RevertRecord(gitdb.ID(m), "--output="+output); err == nil {
+		t.Errorf("testDb.RevertRecord should fail for an option given as commit")
+	}
+	if _, err := os.Stat(output + ":Message/b0.json"); !os.IsNotExist(err) {
+		t.Errorf("want: no file written by git, got: %v", err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	m := getTestMessageWithId(1)
+	if err := insert(m, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	//a record in the same block whose id shares a prefix
+	if err := insert(getTestMessageWithId(10), false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	m.Body = "Changed"
+	if err := testDb.WithUser("Jane", "jane@gitdb.io").Insert(m); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	changes, err := testDb.History(gitdb.ID(m))
+	if err != nil {
+		t.Fatalf("testDb.History failed: %s", err)
+	}
+
+	if len(changes) != 2 {
+		t.Fatalf("want: 2 changes, got: %d", len(changes))
+	}
+
+	if changes[0].Author.AuthorName() != "Jane <jane@gitdb.io>" || changes[0].Operation != "update" {
+		t.Errorf("want: update by Jane <jane@gitdb.io>, got: %s by %s", changes[0].Operation, changes[0].Author)
+	}
+
+	if changes[1].Author.AuthorName() != "Tester <tester@io>" || changes[1].Operation != "insert" {
+		t.Errorf("want: insert by Tester <tester@io>, got: %s by %s", changes[1].Operation, changes[1].Author)
+	}
+}
+
+func localCommitCount(t *testing.T) int {
+	out, err := exec.Command("git", "-C", filepath.Join(dbPath, "data"), "rev-list", "--count", "HEAD").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git rev-list failed: %s", out)
+	}
+	count, _ := strconv.Atoi(strings.TrimSpace(string(out)))
+	return count
+}
+
+func TestSquashHistory(t *testing.T) {
+	backup := testData + "/backup"
+	cfg := getConfig()
+	cfg.SyncMode = gitdb.SyncManual
+	cfg.AllowForcePush = true
+	cfg.Mirrors = []gitdb.Remote{{Name: "backup", URL: backup}}
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	if out, err := exec.Command("git", "init", "--bare", backup).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %s", out)
+	}
+
+	old := []gitdb.Model{getTestMessage(), getTestMessage(), getTestMessage()}
+	for _, m := range old {
+		if err := insert(m, false); err != nil {
+			t.Fatalf("insert failed: %s", err)
+		}
+	}
+	if err := testDb.Sync(); err != nil {
+		t.Fatalf("testDb.Sync failed: %s", err)
+	}
+
+	//commit times have a resolution of a second
+	time.Sleep(time.Second)
+	before := time.Now()
+	time.Sleep(time.Second)
+
+	recent := getTestMessage()
+	if err := insert(recent, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	if err := testDb.SquashHistory(before); err != nil {
+		t.Fatalf("testDb.SquashHistory failed: %s", err)
+	}
+
+	if got := localCommitCount(t); got != 2 {
+		t.Errorf("want: 2 commits after squash, got: %d", got)
+	}
+
+	if got := remoteCommitCount(t); got != 2 {
+		t.Errorf("want: 2 commits on remote after squash, got: %d", got)
+	}
+	if out, _ := exec.Command("git", "-C", backup, "log", "--all", "--oneline").CombinedOutput(); len(strings.Split(strings.TrimSpace(string(out)), "\n")) != 2 {
+		t.Errorf("want: 2 commits on mirror after squash, got: %s", out)
+	}
+
+	for _, m := range append(old, recent) {
+		if err := testDb.Get(gitdb.ID(m), &Message{}); err != nil {
+			t.Errorf("testDb.Get(%s) failed after squash: %s", gitdb.ID(m), err)
+		}
+	}
+
+	changes, err := testDb.History(gitdb.ID(recent))
+	if err != nil || len(changes) != 1 || changes[0].Author.Name != "Tester" {
+		t.Errorf("history of %s should survive squash, got: %v %v", gitdb.ID(recent), changes, err)
+	}
+}
+
+func TestSquashHistoryRequiresForcePush(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	if err := testDb.SquashHistory(time.Now()); err == nil {
+		t.Errorf("testDb.SquashHistory should fail without Config.AllowForcePush")
+	}
+}
+
+func TestShred(t *testing.T) {
+	backup := testData + "/backup"
+	cfg := getConfig()
+	cfg.Audit = true
+	cfg.AllowForcePush = true
+	cfg.Mirrors = []gitdb.Remote{{Name: "backup", URL: backup}}
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	if out, err := exec.Command("git", "init", "--bare", backup).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %s", out)
+	}
+
+	keep := &Credential{Username: "bob", Password: "keep-pass", Token: "tok-bob"}
+	if err := testDb.Insert(keep); err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+
+	a := &Credential{Username: "ada", Password: "first-pass", Token: "tok-ada"}
+	if err := testDb.Insert(a); err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+	a.Password = "second-pass"
+	if err := testDb.Insert(a); err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+	oldBlob := gitOutput(t, "rev-parse", "HEAD:Credential/b0.json")
+
+	//a release and another branch pushed to the online remote and the mirror hold the record too
+	if err := testDb.TagRelease("v1"); err != nil {
+		t.Fatalf("testDb.TagRelease failed: %s", err)
+	}
+	if err := testDb.Branch("staging"); err != nil {
+		t.Fatalf("testDb.Branch failed: %s", err)
+	}
+	if err := testDb.Sync(); err != nil {
+		t.Fatalf("testDb.Sync failed: %s", err)
+	}
+	for _, remote := range []string{"online", "backup"} {
+		gitOutput(t, "push", remote, "staging")
+	}
+
+	if err := testDb.Shred(gitdb.ID(a)); err != nil {
+		t.Fatalf("testDb.Shred failed: %s", err)
+	}
+
+	for _, dir := range []string{filepath.Join(dbPath, "data"), fakeRemote, backup} {
+		out, err := exec.Command("git", "-C", dir, "log", "--all", "-p").CombinedOutput()
+		if err != nil {
+			t.Fatalf("git log failed: %s", out)
+		}
+		for _, secret := range []string{"first-pass", "second-pass", "tok-ada"} {
+			if strings.Contains(string(out), secret) {
+				t.Errorf("%s: history should not contain %s", dir, secret)
+			}
+		}
+		if !strings.Contains(string(out), keep.Token) {
+			t.Errorf("%s: history should still contain %s", dir, gitdb.ID(keep))
+		}
+		if refs, _ := exec.Command("git", "-C", dir, "show-ref").CombinedOutput(); !strings.Contains(string(refs), "refs/tags/v1") || !strings.Contains(string(refs), "refs/heads/staging") {
+			t.Errorf("%s: want release v1 and branch staging kept, got: %s", dir, refs)
+		}
+	}
+
+	if err := exec.Command("git", "-C", filepath.Join(dbPath, "data"), "cat-file", "-e", oldBlob).Run(); err == nil {
+		t.Errorf("blob %s of the shredded record should be pruned", oldBlob)
+	}
+
+	if err := testDb.Get(gitdb.ID(a), &Credential{}); err == nil {
+		t.Errorf("%s should be deleted", gitdb.ID(a))
+	}
+	if err := testDb.Get(gitdb.ID(keep), &Credential{}); err != nil {
+		t.Errorf("testDb.Get(%s) failed after shred: %s", gitdb.ID(keep), err)
+	}
+
+	entries, err := testDb.Search("_audit", []*gitdb.SearchParam{{Index: "Record", Value: gitdb.ID(a)}}, gitdb.SearchEquals)
+	if err != nil || len(entries) != 1 || !strings.Contains(entries[0].JSON(), string(gitdb.OpShred)) {
+		t.Errorf("want: 1 shred audit entry, got: %d (%v)", len(entries), err)
+	}
+}
+
+func gitOutput(t *testing.T, args ...string) string {
+	out, err := exec.Command("git", append([]string{"-C", filepath.Join(dbPath, "data")}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %s", args[0], out)
+	}
+	return strings.TrimSpace(string(out))
+}
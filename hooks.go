@@ -0,0 +1,118 @@
+package gitdb
+
+import (
+	"fmt"
+)
+
+//AfterInserter is implemented by models that run code after they are inserted as a new record
+type AfterInserter interface {
+	AfterInsert() error
+}
+
+//BeforeUpdater is implemented by models that run code before they replace a stored record. It is
+//called after BeforeInsert, which runs on every write, and an error aborts the update
+type BeforeUpdater interface {
+	BeforeUpdate() error
+}
+
+//AfterUpdater is implemented by models that run code after they replace a stored record
+type AfterUpdater interface {
+	AfterUpdate() error
+}
+
+//BeforeDeleter is implemented by models that run code before they are deleted. An error aborts the delete.
+//Delete only has the id of the record so delete hooks are called on the stored record hydrated into
+//a model from Config.Types, Config.Factory or RegisterModel, and not at all for datasets with none of them
+type BeforeDeleter interface {
+	BeforeDelete() error
+}
+
+//AfterDeleter is implemented by models that run code after they are deleted. See BeforeDeleter
+type AfterDeleter interface {
+	AfterDelete() error
+}
+
+//AfterFinder is implemented by models that run code after a record is read into them by Get, Hydrate on
+//a record returned by Fetch, Decode or FetchModels e.g to recompute derived fields or decode custom
+//encodings before the application sees them. An error fails the read
+type AfterFinder interface {
+	AfterFind() error
+}
+
+//beforeUpdate calls BeforeUpdate on m if it implements it and replaces a stored record
+func (g *gitdb) beforeUpdate(m Model) error {
+	hook, ok := m.(BeforeUpdater)
+	if !ok || g.Exists(ID(m)) != nil {
+		return nil
+	}
+
+	if err := hook.BeforeUpdate(); err != nil {
+		return fmt.Errorf("Model.BeforeUpdate failed: %s", err)
+	}
+	return nil
+}
+
+//afterWrite calls the After hook of m for op. m is already written so an error is only reported
+func afterWrite(op Op, m Model) error {
+	if wrapped, ok := m.(*model); ok {
+		m = wrapped.Data
+	}
+
+	var err error
+	switch op {
+	case OpInsert:
+		if hook, ok := m.(AfterInserter); ok {
+			if err = hook.AfterInsert(); err != nil {
+				err = fmt.Errorf("Model.AfterInsert failed: %s", err)
+			}
+		}
+	case OpUpdate:
+		if hook, ok := m.(AfterUpdater); ok {
+			if err = hook.AfterUpdate(); err != nil {
+				err = fmt.Errorf("Model.AfterUpdate failed: %s", err)
+			}
+		}
+	case OpDelete:
+		if hook, ok := m.(AfterDeleter); ok {
+			if err = hook.AfterDelete(); err != nil {
+				err = fmt.Errorf("Model.AfterDelete failed: %s", err)
+			}
+		}
+	}
+	return err
+}
+
+//beforeDelete returns record id hydrated into its model, having called BeforeDelete on it, when the
+//model has delete hooks. It returns nil if the record doesn't exist or the dataset has no model
+func (g *gitdb) beforeDelete(id string) (Model, error) {
+	dataset, _, _, err := ParseID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !g.config.hasFactory() && len(g.config.Types[dataset]) == 0 {
+		return nil, nil
+	}
+
+	record, err := g.doget(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	m := g.newModel(dataset, record)
+	_, before := m.(BeforeDeleter)
+	_, after := m.(AfterDeleter)
+	if m == nil || (!before && !after) {
+		return nil, nil
+	}
+
+	if err := record.Hydrate(m); err != nil {
+		return nil, err
+	}
+
+	if before {
+		if err := m.(BeforeDeleter).BeforeDelete(); err != nil {
+			return nil, fmt.Errorf("Model.BeforeDelete failed: %s", err)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,178 @@
+package gitdb_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+//hookCalls records the hooks of Hooked in the order they are called
+var hookCalls []string
+
+type Hooked struct {
+	testModel
+	HookedId int
+	Name     string
+	Locked   bool
+}
+
+func (h *Hooked) GetSchema() *gitdb.Schema {
+	return gitdb.NewSchema("Hooked", "b0", fmt.Sprintf("%d", h.HookedId), map[string]interface{}{})
+}
+
+func (h *Hooked) BeforeInsert() error {
+	hookCalls = append(hookCalls, "BeforeInsert")
+	return h.testModel.BeforeInsert()
+}
+
+func (h *Hooked) AfterInsert() error {
+	hookCalls = append(hookCalls, "AfterInsert")
+	return nil
+}
+
+func (h *Hooked) BeforeUpdate() error {
+	hookCalls = append(hookCalls, "BeforeUpdate")
+	if h.Name == "" {
+		return errors.New("Name can't be cleared")
+	}
+	return nil
+}
+
+func (h *Hooked) AfterUpdate() error {
+	hookCalls = append(hookCalls, "AfterUpdate")
+	return nil
+}
+
+func (h *Hooked) BeforeDelete() error {
+	hookCalls = append(hookCalls, "BeforeDelete "+h.Name)
+	if h.Locked {
+		return errors.New("record is locked")
+	}
+	return nil
+}
+
+func (h *Hooked) AfterDelete() error {
+	hookCalls = append(hookCalls, "AfterDelete "+h.Name)
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	cfg := getConfig()
+	cfg.Factory = func(dataset string) gitdb.Model { return &Hooked{} }
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	hookCalls = nil
+	h := &Hooked{HookedId: 1, Name: "first"}
+	if err := testDb.Insert(h); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+	h.Name = "second"
+	if err := testDb.Insert(h); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+	if err := testDb.Delete(gitdb.ID(h)); err != nil {
+		t.Fatalf("testDb.Delete failed: %s", err)
+	}
+
+	want := "BeforeInsert AfterInsert BeforeInsert BeforeUpdate AfterUpdate BeforeDelete second AfterDelete second"
+	if got := strings.Join(hookCalls, " "); got != want {
+		t.Errorf("want: %s, got: %s", want, got)
+	}
+}
+
+func TestHooksAbort(t *testing.T) {
+	cfg := getConfig()
+	cfg.Factory = func(dataset string) gitdb.Model { return &Hooked{} }
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	h := &Hooked{HookedId: 1, Name: "first", Locked: true}
+	if err := testDb.Insert(h); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+
+	//BeforeUpdate errors abort the update
+	if err := testDb.Insert(&Hooked{HookedId: 1}); err == nil || !strings.Contains(err.Error(), "BeforeUpdate") {
+		t.Errorf("want: BeforeUpdate error, got: %v", err)
+	}
+	got := &Hooked{}
+	if err := testDb.Get(gitdb.ID(h), got); err != nil || got.Name != "first" {
+		t.Errorf("want: first, got: %s (%v)", got.Name, err)
+	}
+
+	//BeforeDelete errors abort the delete
+	if err := testDb.Delete(gitdb.ID(h)); err == nil || !strings.Contains(err.Error(), "BeforeDelete") {
+		t.Errorf("want: BeforeDelete error, got: %v", err)
+	}
+	if err := testDb.Exists(gitdb.ID(h)); err != nil {
+		t.Errorf("record should not be deleted: %s", err)
+	}
+}
+
+type Derived struct {
+	testModel
+	DerivedId int
+	First     string
+	Last      string
+	FullName  string `json:"-"`
+}
+
+func (d *Derived) GetSchema() *gitdb.Schema {
+	return gitdb.NewSchema("Derived", "b0", fmt.Sprintf("%d", d.DerivedId), map[string]interface{}{})
+}
+
+func (d *Derived) AfterFind() error {
+	if d.Last == "" {
+		return errors.New("Last is missing")
+	}
+	d.FullName = d.First + " " + d.Last
+	return nil
+}
+
+func TestAfterFind(t *testing.T) {
+	cfg := getConfig()
+	cfg.Factory = func(dataset string) gitdb.Model { return &Derived{} }
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	if err := testDb.Insert(&Derived{DerivedId: 1, First: "Ada", Last: "Lovelace"}); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+
+	got := &Derived{}
+	if err := testDb.Get("Derived/b0/1", got); err != nil {
+		t.Fatalf("testDb.Get failed: %s", err)
+	}
+	if got.FullName != "Ada Lovelace" {
+		t.Errorf("want: Ada Lovelace, got: %s", got.FullName)
+	}
+
+	records, err := testDb.Fetch("Derived")
+	if err != nil {
+		t.Fatalf("testDb.Fetch failed: %s", err)
+	}
+	fetched := &Derived{}
+	if err := records[0].Hydrate(fetched); err != nil || fetched.FullName != "Ada Lovelace" {
+		t.Errorf("want: Ada Lovelace, got: %s (%v)", fetched.FullName, err)
+	}
+
+	models, err := testDb.FetchModels("Derived")
+	if err != nil {
+		t.Fatalf("testDb.FetchModels failed: %s", err)
+	}
+	if name := models[0].(*Derived).FullName; name != "Ada Lovelace" {
+		t.Errorf("want: Ada Lovelace, got: %s", name)
+	}
+
+	//AfterFind errors fail the read
+	if err := testDb.Insert(&Derived{DerivedId: 2, First: "Grace"}); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+	if err := testDb.Get("Derived/b0/2", &Derived{}); err == nil || !strings.Contains(err.Error(), "AfterFind") {
+		t.Errorf("want: AfterFind error, got: %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+package gitdb
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//IDStrategy generates the ids of new records. Use AutoULID, AutoUUID or Sequence
+type IDStrategy interface {
+	newID(g *gitdb, dataset string) (string, error)
+}
+
+//autoID is a field of a model filled in by an IDStrategy
+type autoID struct {
+	field    string
+	strategy IDStrategy
+}
+
+//AutoID sets field of the model to an id made by strategy on insert when the field is empty. Build the
+//schema's record id from the field so the record is stored under the generated id e.g
+//
+//	gitdb.NewSchema("Order", "b0", o.ID, indexes).AutoID("ID", gitdb.AutoULID())
+func (a *Schema) AutoID(field string, strategy IDStrategy) *Schema {
+	a.autoID = &autoID{field: field, strategy: strategy}
+	return a
+}
+
+//assignID fills in the auto id field of m if it is empty and reports whether it did
+func (g *gitdb) assignID(schema *Schema, m Model) (bool, error) {
+	if schema.autoID == nil {
+		return false, nil
+	}
+	if schema.autoID.strategy == nil {
+		return false, fmt.Errorf("AutoID of %s has no IDStrategy", schema.name())
+	}
+
+	v, err := modelStruct(schema, m)
+	if err != nil {
+		return false, err
+	}
+	name := schema.autoID.field
+	field, err := modelField(schema, v, name)
+	if err != nil {
+		return false, err
+	}
+	if !field.IsZero() {
+		return false, nil
+	}
+
+	id, err := schema.autoID.strategy.newID(g, schema.name())
+	if err != nil {
+		return false, fmt.Errorf("failed to generate an id for %s: %w", schema.name(), err)
+	}
+
+	if isNumber(field.Kind()) {
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("%s.%s is %s and can't hold id %s", schema.name(), name, field.Type(), id)
+		}
+		return true, setField(schema, field, name, n)
+	}
+	return true, setField(schema, field, name, id)
+}
+
+type uuidStrategy struct{}
+
+//AutoUUID generates random (version 4) UUIDs e.g 0b4ec8a7-4e4c-4d5a-9b1e-3c4f2a6d8e10
+func AutoUUID() IDStrategy {
+	return uuidStrategy{}
+}
+
+func (uuidStrategy) newID(g *gitdb, dataset string) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+//crockford is the alphabet ULIDs are written in
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+type ulidStrategy struct {
+	mu   sync.Mutex
+	ms   uint64
+	rand [10]byte
+}
+
+//ulids is shared so ids made in the same millisecond by any schema keep increasing
+var ulids = &ulidStrategy{}
+
+//AutoULID generates ULIDs: 26 character ids that sort in the order they were made e.g 01HZY3R8N5K2Q7T9W4XJ6M1B0C
+func AutoULID() IDStrategy {
+	return ulids
+}
+
+func (u *ulidStrategy) newID(g *gitdb, dataset string) (string, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	if ms > u.ms {
+		u.ms = ms
+		if _, err := rand.Read(u.rand[:]); err != nil {
+			return "", err
+		}
+	} else {
+		//ids made within the same millisecond increment the random part so they stay in order
+		for i := len(u.rand) - 1; i >= 0; i-- {
+			u.rand[i]++
+			if u.rand[i] != 0 {
+				break
+			}
+		}
+	}
+
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], u.ms<<16)
+	copy(b[6:], u.rand[:])
+
+	//the 128 bits are written 5 at a time from the bottom, leaving the top 3 for the first character
+	id := make([]byte, 26)
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	for i := 25; i >= 0; i-- {
+		id[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(id), nil
+}
+
+type sequenceStrategy struct{}
+
+//Sequence numbers the records of a dataset 1, 2, 3... The last number used is kept in the database
+//directory so numbers are not reused across restarts, and is handed out under a lock so concurrent
+//inserts never get the same number. As the directory isn't shared e.g with a fresh clone or another node,
+//the sequence carries on from the largest record key of the dataset that is a number when it is first used,
+//and a record is never overwritten by an insert with a generated id: it fails with *ErrStaleRecord instead
+func Sequence() IDStrategy {
+	return sequenceStrategy{}
+}
+
+func (sequenceStrategy) newID(g *gitdb, dataset string) (string, error) {
+	n, err := g.nextSequence(dataset)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(n, 10), nil
+}
+
+//sequences holds the last number handed out by Sequence for each dataset
+type sequences struct {
+	mu   sync.Mutex
+	last map[string]int64
+	//seeded holds the datasets whose sequence has been moved past their largest record key
+	seeded map[string]bool
+}
+
+//nextSequence increments the sequence of dataset and saves it before returning it
+func (g *gitdb) nextSequence(dataset string) (int64, error) {
+	g.sequences.mu.Lock()
+	defer g.sequences.mu.Unlock()
+
+	if g.sequences.last == nil {
+		last := map[string]int64{}
+		data, err := ioutil.ReadFile(g.sequenceFile())
+		if err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &last); err != nil {
+				return 0, err
+			}
+		}
+		g.sequences.last = last
+		g.sequences.seeded = map[string]bool{}
+	}
+
+	if !g.sequences.seeded[dataset] {
+		largest, err := g.largestKey(dataset)
+		if err != nil {
+			return 0, err
+		}
+		if largest > g.sequences.last[dataset] {
+			g.sequences.last[dataset] = largest
+		}
+		g.sequences.seeded[dataset] = true
+	}
+
+	next := g.sequences.last[dataset] + 1
+	g.sequences.last[dataset] = next
+	data, err := json.Marshal(g.sequences.last)
+	if err == nil {
+		err = ioutil.WriteFile(g.sequenceFile(), data, 0744)
+	}
+	if err != nil {
+		//the number is not handed out so the saved sequence stays in step
+		g.sequences.last[dataset] = next - 1
+		return 0, err
+	}
+	return next, nil
+}
+
+//largestKey returns the largest record key of dataset that is a number. Blocks that can't be read are left for Fsck
+func (g *gitdb) largestKey(dataset string) (int64, error) {
+	blockFiles, err := g.blockFiles(dataset)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	var largest int64
+	for _, blockFile := range blockFiles {
+		data, err := g.readBlockFile(blockFile)
+		if err != nil {
+			return 0, err
+		}
+		var raw map[string]json.RawMessage
+		if json.Unmarshal(data, &raw) != nil {
+			continue
+		}
+		for id := range raw {
+			key := id[strings.LastIndex(id, "/")+1:]
+			if n, err := strconv.ParseInt(key, 10, 64); err == nil && n > largest {
+				largest = n
+			}
+		}
+	}
+	return largest, nil
+}
+
+func (g *gitdb) sequenceFile() string {
+	return filepath.Join(g.absDbPath(), g.internalDirName(), "sequences.json")
+}
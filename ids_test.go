@@ -0,0 +1,133 @@
+package gitdb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+type Ticket struct {
+	testModel
+	ID       string
+	Number   int
+	Strategy string
+}
+
+func (tk *Ticket) GetSchema() *gitdb.Schema {
+	var strategy gitdb.IDStrategy
+	switch tk.Strategy {
+	case "uuid":
+		strategy = gitdb.AutoUUID()
+	case "ulid":
+		strategy = gitdb.AutoULID()
+	}
+	return gitdb.NewSchema("Ticket", "b0", tk.ID, map[string]interface{}{}).AutoID("ID", strategy)
+}
+
+type Invoice struct {
+	testModel
+	Number int
+}
+
+func (i *Invoice) GetSchema() *gitdb.Schema {
+	return gitdb.NewSchema("Invoice", "b0", gitdb.Composite(i.Number), map[string]interface{}{}).AutoID("Number", gitdb.Sequence())
+}
+
+func TestAutoID(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	formats := map[string]*regexp.Regexp{
+		"uuid": regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		"ulid": regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`),
+	}
+	for strategy, format := range formats {
+		var ids []string
+		for i := 0; i < 3; i++ {
+			tk := &Ticket{Strategy: strategy}
+			if err := testDb.Insert(tk); err != nil {
+				t.Fatalf("testDb.Insert failed: %s", err)
+			}
+			if !format.MatchString(tk.ID) {
+				t.Errorf("want: %s id, got: %s", strategy, tk.ID)
+			}
+			if err := testDb.Exists(gitdb.ID(tk)); err != nil {
+				t.Errorf("testDb.Exists failed: %s", err)
+			}
+			ids = append(ids, tk.ID)
+		}
+
+		//ULIDs sort in the order they were made
+		if strategy == "ulid" && !sort.StringsAreSorted(ids) {
+			t.Errorf("want: sorted ids, got: %v", ids)
+		}
+	}
+
+	//an id that is set is kept
+	tk := &Ticket{ID: "mine", Strategy: "uuid"}
+	if err := testDb.Insert(tk); err != nil || tk.ID != "mine" {
+		t.Errorf("want: mine, got: %s (%v)", tk.ID, err)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	cfg := getConfig()
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	//concurrent inserts never share a number
+	var wg sync.WaitGroup
+	invoices := make([]*Invoice, 10)
+	for i := range invoices {
+		invoices[i] = &Invoice{}
+		wg.Add(1)
+		go func(inv *Invoice) {
+			defer wg.Done()
+			if err := testDb.Insert(inv); err != nil {
+				t.Errorf("testDb.Insert failed: %s", err)
+			}
+		}(invoices[i])
+	}
+	wg.Wait()
+
+	seen := map[int]bool{}
+	for _, inv := range invoices {
+		if inv.Number < 1 || inv.Number > len(invoices) || seen[inv.Number] {
+			t.Errorf("want: a number from 1 to %d used once, got: %d", len(invoices), inv.Number)
+		}
+		seen[inv.Number] = true
+	}
+
+	//numbers carry on after a restart
+	testDb.Close()
+	testDb = getDbConn(t, cfg)
+	inv := &Invoice{}
+	if err := testDb.Insert(inv); err != nil || inv.Number != len(invoices)+1 {
+		t.Errorf("want: %d, got: %d (%v)", len(invoices)+1, inv.Number, err)
+	}
+
+	//numbers carry on from the records of the dataset without the saved sequence e.g on a fresh clone
+	testDb.Close()
+	os.Remove(filepath.Join(cfg.DbPath, ".gitdb", "sequences.json"))
+	testDb = getDbConn(t, cfg)
+	inv = &Invoice{}
+	if err := testDb.Insert(inv); err != nil || inv.Number != len(invoices)+2 {
+		t.Errorf("want: %d, got: %d (%v)", len(invoices)+2, inv.Number, err)
+	}
+
+	//a record written with the next number elsewhere e.g by another node is not overwritten
+	taken := &Invoice{Number: len(invoices) + 3}
+	if err := testDb.Insert(taken); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+	var stale *gitdb.ErrStaleRecord
+	if err := testDb.Insert(&Invoice{}); !errors.As(err, &stale) {
+		t.Errorf("want: %T, got: %v", stale, err)
+	}
+}
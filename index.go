@@ -2,6 +2,8 @@ package gitdb
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -19,24 +21,35 @@ type gdbIndexValue struct {
 	Value  interface{} `json:"v"`
 }
 
-//TODO handle deletes
+//updateIndexes brings the indexes of dataBlock's dataset in line with the records in it
 func (g *gitdb) updateIndexes(dataBlock *db.Block) {
-	g.indexUpdated = true
 	dataset := dataBlock.Dataset().Name()
 	indexPath := g.indexPath(dataset)
 	log.Info("updating in-memory index")
 	//get line position of each record in the block
 	p := extractPositions(dataBlock)
 
+	//drop records that are no longer in the block
+	blockPrefix := dataset + "/" + dataBlock.Name() + "/"
+	for _, indexFile := range g.indexFiles(dataset) {
+		index := g.cachedIndex(indexFile)
+		for recordID := range index {
+			if _, ok := p[recordID]; !ok && strings.HasPrefix(recordID, blockPrefix) {
+				delete(index, recordID)
+				g.markIndexDirty(indexFile)
+			}
+		}
+	}
+
 	var model Model
 	var indexes map[string]interface{}
 	for _, record := range dataBlock.Records() {
-		if record.Version() == "v1" && g.config.Factory != nil {
-			if model == nil {
-				model = g.config.Factory(dataset)
-			}
+		if record.Version() == "v1" && model == nil {
+			model = g.config.factory(dataset)
+		}
+		if record.Version() == "v1" && model != nil {
 			record.Hydrate(model)
-			indexes = model.GetSchema().indexes
+			indexes = schemaOf(model).indexes
 		} else {
 			indexes = record.Indexes()
 		}
@@ -47,22 +60,76 @@ func (g *gitdb) updateIndexes(dataBlock *db.Block) {
 
 		for name, value := range indexes {
 			indexFile := filepath.Join(indexPath, name+".json")
-			if _, ok := g.indexCache[indexFile]; !ok {
-				g.indexCache[indexFile] = g.readIndex(indexFile)
-			}
-			g.indexCache[indexFile][recordID] = gdbIndexValue{
+			g.cachedIndex(indexFile)[recordID] = gdbIndexValue{
 				Offset: p[recordID][0],
 				Len:    p[recordID][1],
 				Value:  value,
 			}
+			g.markIndexDirty(indexFile)
 		}
 	}
+
+	g.updateFullText(dataBlock)
+	g.updateBloom(dataBlock)
 }
 
+//cachedIndex returns indexFile from the cache, reading it from disk if need be
+func (g *gitdb) cachedIndex(indexFile string) gdbIndex {
+	if _, ok := g.indexCache[indexFile]; !ok {
+		g.indexCache[indexFile] = g.readIndex(indexFile)
+	}
+	return g.indexCache[indexFile]
+}
+
+func (g *gitdb) indexFile(dataset string, name string) string {
+	return filepath.Join(g.indexPath(dataset), name+".json")
+}
+
+//index returns the named index of dataset, building the dataset's indexes if it is not persisted
+func (g *gitdb) index(dataset string, name string) gdbIndex {
+	indexFile := g.indexFile(dataset, name)
+	if _, ok := g.indexCache[indexFile]; !ok {
+		if _, err := os.Stat(indexFile); err != nil {
+			g.buildIndexTargeted(dataset)
+		}
+	}
+	return g.cachedIndex(indexFile)
+}
+
+//indexFiles returns the index files of dataset that are cached or on disk
+func (g *gitdb) indexFiles(dataset string) []string {
+	indexPath := g.indexPath(dataset)
+	files, _ := filepath.Glob(filepath.Join(indexPath, "*.json"))
+	seen := map[string]bool{}
+	for _, file := range files {
+		seen[file] = true
+	}
+
+	for indexFile := range g.indexCache {
+		if filepath.Dir(indexFile) == indexPath && !seen[indexFile] {
+			files = append(files, indexFile)
+		}
+	}
+	return files
+}
+
+func (g *gitdb) markIndexDirty(indexFile string) {
+	if g.dirtyIndexes == nil {
+		g.dirtyIndexes = map[string]bool{}
+	}
+	g.dirtyIndexes[indexFile] = true
+	delete(g.ordered, indexFile)
+}
+
+//flushIndex writes index files changed since the last flush to disk
 func (g *gitdb) flushIndex() error {
-	if g.indexUpdated {
+	if len(g.dirtyIndexes) > 0 {
 		log.Test("flushing index")
-		for indexFile, data := range g.indexCache {
+		for indexFile := range g.dirtyIndexes {
+			var data interface{} = g.indexCache[indexFile]
+			if fts, ok := g.fts[indexFile]; ok {
+				data = fts
+			}
 
 			indexPath := filepath.Dir(indexFile)
 			if _, err := os.Stat(indexPath); err != nil {
@@ -75,18 +142,21 @@ func (g *gitdb) flushIndex() error {
 
 			// indexBytes, err := json.MarshalIndent(data, "", "\t")
 			indexBytes, err := json.Marshal(data)
+			if err == nil {
+				indexBytes, err = g.sealIndex(g.indexDataset(indexFile), indexBytes)
+			}
 			if err != nil {
 				log.Error("Failed to write to index [" + indexFile + "]: " + err.Error())
 				return err
 			}
 
-			err = ioutil.WriteFile(indexFile, indexBytes, 0744)
+			err = g.writeFileAtomic(indexFile, indexBytes, 0744)
 			if err != nil {
 				log.Error("Failed to write to index: " + indexFile)
 				return err
 			}
+			delete(g.dirtyIndexes, indexFile)
 		}
-		g.indexUpdated = false
 	}
 
 	return nil
@@ -96,6 +166,9 @@ func (g *gitdb) readIndex(indexFile string) gdbIndex {
 	rMap := make(gdbIndex)
 	if _, err := os.Stat(indexFile); err == nil {
 		data, err := ioutil.ReadFile(indexFile)
+		if err == nil {
+			data, err = g.openIndex(g.indexDataset(indexFile), data)
+		}
 		if err == nil {
 			err = json.Unmarshal(data, &rMap)
 		}
@@ -107,33 +180,146 @@ func (g *gitdb) readIndex(indexFile string) gdbIndex {
 	return rMap
 }
 
+//indexDataset returns the dataset of an index or full-text index file
+func (g *gitdb) indexDataset(indexFile string) string {
+	if filepath.Dir(indexFile) == g.ftsDir() {
+		return strings.TrimSuffix(filepath.Base(indexFile), ".json")
+	}
+	return filepath.Base(filepath.Dir(indexFile))
+}
+
+//sealIndex encrypts data, an index or full-text index file of dataset, with Config.EncryptAtRest so the
+//values and tokens of records encrypted in the repository aren't kept in plaintext beside it
+func (g *gitdb) sealIndex(dataset string, data []byte) ([]byte, error) {
+	if !g.config.EncryptAtRest {
+		return data, nil
+	}
+
+	var key string
+	if g.config.Cipher == nil {
+		var err error
+		if key, err = g.encryptionKey(dataset); err != nil {
+			return nil, err
+		}
+	}
+	sealed, err := g.encrypt(dataset, key, string(data))
+	return []byte(sealed), err
+}
+
+//openIndex returns data, an index or full-text index file of dataset, decrypted if sealIndex encrypted it
+func (g *gitdb) openIndex(dataset string, data []byte) ([]byte, error) {
+	if json.Valid(data) {
+		return data, nil
+	}
+
+	keyring := g.keyring(dataset)
+	if keyring == nil {
+		return nil, errors.New("Index of " + dataset + " is encrypted and no key is configured")
+	}
+	dec, err := keyring.Decrypt(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("Index of %s could not be decrypted: %w", dataset, err)
+	}
+	return []byte(dec), nil
+}
+
 func (g *gitdb) buildIndexSmart(changedFiles []string) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
 	for _, blockFile := range changedFiles {
 		log.Info("Building index for block: " + blockFile)
-		block := db.LoadBlock(filepath.Join(g.dbDir(), blockFile), g.config.EncryptionKey)
+		block := g.readBlock(filepath.Join(g.dbDir(), blockFile))
 		g.updateIndexes(block)
 	}
 	log.Info("Building index complete")
+	if err := g.flushIndex(); err != nil {
+		log.Error(err.Error())
+	}
 }
 
 func (g *gitdb) buildIndexTargeted(target string) {
-	ds := db.LoadDataset(filepath.Join(g.dbDir(), target), g.config.EncryptionKey)
-	for _, block := range ds.Blocks() {
-		g.updateIndexes(block)
+	blockFiles, err := g.blockFiles(target)
+	if err != nil {
+		log.Error(err.Error())
+	}
+
+	for _, blockFile := range blockFiles {
+		g.updateIndexes(g.readBlock(blockFile))
 	}
 }
 
 func (g *gitdb) buildIndexFull() {
-	datasets := db.LoadDatasets(g.dbDir(), g.config.EncryptionKey)
-	for _, ds := range datasets {
-		g.buildIndexTargeted(ds.Name())
+	datasets, err := g.datasetNames()
+	if err != nil {
+		log.Error(err.Error())
+	}
+
+	for _, dataset := range datasets {
+		g.buildIndexTargeted(dataset)
 	}
 	g.flushIndex()
 }
 
+//reindex discards all cached blocks and indexes and rebuilds them from disk.
+//It must be called whenever the working tree changes outside of gitdb's write path
+func (g *gitdb) reindex() error {
+	g.cacheMu.Lock()
+	defer g.cacheMu.Unlock()
+
+	g.loadedBlocks = map[string]*db.Block{}
+	g.indexCache = make(gdbIndexCache)
+	g.dirtyIndexes = map[string]bool{}
+	g.ordered = map[string][]orderedEntry{}
+	if err := os.RemoveAll(g.indexDir()); err != nil {
+		return err
+	}
+	if err := g.resetBlooms(); err != nil {
+		return err
+	}
+	if err := g.resetFullText(""); err != nil {
+		return err
+	}
+
+	g.buildIndexFull()
+	return nil
+}
+
+//RebuildIndex discards the persisted indexes of dataset and rebuilds them from its block files
+func (g *gitdb) RebuildIndex(dataset string) error {
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
+
+	blockFiles, err := g.blockFiles(dataset)
+	if err != nil {
+		return err
+	}
+
+	g.cacheMu.Lock()
+	defer g.cacheMu.Unlock()
+	indexPath := g.indexPath(dataset)
+	for indexFile := range g.indexCache {
+		if filepath.Dir(indexFile) == indexPath {
+			delete(g.indexCache, indexFile)
+			delete(g.dirtyIndexes, indexFile)
+			delete(g.ordered, indexFile)
+		}
+	}
+
+	if err := os.RemoveAll(indexPath); err != nil {
+		return err
+	}
+	if err := g.resetFullText(dataset); err != nil {
+		return err
+	}
+
+	for _, blockFile := range blockFiles {
+		g.updateIndexes(g.readBlock(blockFile))
+	}
+
+	return g.flushIndex()
+}
+
 //extractPositions returns the position of all records in a block
 //as they would appear in the physical block file
 func extractPositions(b *db.Block) map[string][]int {
@@ -146,11 +332,11 @@ func extractPositions(b *db.Block) map[string][]int {
 	offset := 2
 	length := 0
 	for i, record := range records {
-		recordStr := record.Data()
-		recordStr = strings.Replace(recordStr, `'`, `\'`, -1)
-		recordStr = strings.Replace(recordStr, `"`, `\"`, -1)
+		//escape id and data the way json.MarshalIndent does when writing the block
+		id, _ := json.Marshal(record.ID())
+		data, _ := json.Marshal(record.Data())
 		//stop line just after the comma
-		recordLine := "\t" + `"` + record.ID() + `": "` + recordStr + `",`
+		recordLine := "\t" + string(id) + ": " + string(data) + ","
 
 		if i > 0 {
 			offset = length + offset
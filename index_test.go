@@ -0,0 +1,319 @@
+package gitdb_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gogitdb/gitdb/v2"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+func persistedIndex(t *testing.T, dataset string, name string) map[string]interface{} {
+	data, err := ioutil.ReadFile(filepath.Join(dbPath, ".gitdb", "index", dataset, name+".json"))
+	if err != nil {
+		t.Fatalf("failed to read %s index: %s", name, err)
+	}
+
+	index := map[string]interface{}{}
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatalf("failed to parse %s index: %s", name, err)
+	}
+	return index
+}
+
+func TestIndexUpdatedIncrementally(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	m1, m2 := getTestMessageWithId(1), getTestMessageWithId(2)
+	for _, m := range []*Message{m1, m2} {
+		if err := insert(m, false); err != nil {
+			t.Fatalf("insert failed: %s", err)
+		}
+	}
+
+	index := persistedIndex(t, "Message", "From")
+	if _, ok := index[gitdb.ID(m1)]; !ok || len(index) != 2 {
+		t.Errorf("want: 2 records in persisted index, got: %d", len(index))
+	}
+
+	if err := testDb.Delete(gitdb.ID(m1)); err != nil {
+		t.Fatalf("testDb.Delete failed: %s", err)
+	}
+
+	index = persistedIndex(t, "Message", "From")
+	if _, ok := index[gitdb.ID(m1)]; ok || len(index) != 1 {
+		t.Errorf("want: %s removed from persisted index, got: %d record(s)", gitdb.ID(m1), len(index))
+	}
+
+	sp := &gitdb.SearchParam{Index: "From", Value: m2.From}
+	results, err := testDb.Search("Message", []*gitdb.SearchParam{sp}, gitdb.SearchEquals)
+	if err != nil {
+		t.Fatalf("testDb.Search failed: %s", err)
+	}
+	if len(results) != 1 || results[0].ID() != gitdb.ID(m2) {
+		t.Errorf("want: [%s], got: %d result(s)", gitdb.ID(m2), len(results))
+	}
+}
+
+func TestRebuildIndex(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	m := getTestMessageWithId(1)
+	if err := insert(m, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	indexDir := filepath.Join(dbPath, ".gitdb", "index", "Message")
+	if err := os.RemoveAll(indexDir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := testDb.RebuildIndex("Message"); err != nil {
+		t.Fatalf("testDb.RebuildIndex failed: %s", err)
+	}
+
+	if _, ok := persistedIndex(t, "Message", "From")[gitdb.ID(m)]; !ok {
+		t.Errorf("want: %s in rebuilt index", gitdb.ID(m))
+	}
+}
+
+type Booking struct {
+	testModel
+	BookingId   int
+	RoomId      string
+	CheckInDate string
+}
+
+func (b *Booking) GetSchema() *gitdb.Schema {
+	indexes := map[string]interface{}{"RoomId": b.RoomId, "CheckInDate": b.CheckInDate}
+	return gitdb.NewSchema("Booking", "b0", fmt.Sprintf("%d", b.BookingId), indexes).Index("RoomId", "CheckInDate")
+}
+
+func TestCompositeIndex(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	bookings := []*Booking{
+		{BookingId: 1, RoomId: "101", CheckInDate: "2024-06-03"},
+		{BookingId: 2, RoomId: "101", CheckInDate: "2024-06-01"},
+		{BookingId: 3, RoomId: "102", CheckInDate: "2024-06-02"},
+		{BookingId: 4, RoomId: "101", CheckInDate: "2024-07-01"},
+	}
+	for _, b := range bookings {
+		if err := testDb.Insert(b); err != nil {
+			t.Fatalf("testDb.Insert failed: %s", err)
+		}
+	}
+
+	sp := &gitdb.SearchParam{Index: "RoomId+CheckInDate", Value: gitdb.Composite("101", "2024-06-01")}
+	results, err := testDb.Search("Booking", []*gitdb.SearchParam{sp}, gitdb.SearchEquals)
+	if err != nil {
+		t.Fatalf("testDb.Search failed: %s", err)
+	}
+	if len(results) != 1 || results[0].ID() != gitdb.ID(bookings[1]) {
+		t.Errorf("want: [%s], got: %d result(s)", gitdb.ID(bookings[1]), len(results))
+	}
+
+	results, err = testDb.SearchRange("Booking", "RoomId+CheckInDate", gitdb.Composite("101", "2024-06-01"), gitdb.Composite("101", "2024-06-30"))
+	if err != nil {
+		t.Fatalf("testDb.SearchRange failed: %s", err)
+	}
+
+	var got []string
+	for _, r := range results {
+		got = append(got, r.ID())
+	}
+	want := []string{gitdb.ID(bookings[1]), gitdb.ID(bookings[0])}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("want: %v, got: %v", want, got)
+	}
+}
+
+func TestCompositeIndexFieldsMustBeIndexed(t *testing.T) {
+	schema := gitdb.NewSchema("Booking", "b0", "1", map[string]interface{}{"RoomId": "101"}).Index("RoomId", "CheckInDate")
+	if err := schema.Validate(); err == nil {
+		t.Error("schema.Validate should fail for a composite of fields that are not indexed")
+	}
+}
+
+type Payment struct {
+	testModel
+	PaymentId int
+	Amount    float64
+	PaidAt    time.Time
+}
+
+func (p *Payment) GetSchema() *gitdb.Schema {
+	indexes := map[string]interface{}{"Amount": p.Amount, "PaidAt": p.PaidAt}
+	return gitdb.NewSchema("Payment", "b0", fmt.Sprintf("%d", p.PaymentId), indexes)
+}
+
+func TestSearchWhere(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	june := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	payments := []*Payment{
+		{PaymentId: 1, Amount: 12000, PaidAt: june.AddDate(0, 0, 20)},
+		{PaymentId: 2, Amount: 900, PaidAt: june.AddDate(0, 0, 2)},
+		{PaymentId: 3, Amount: 5000, PaidAt: june.AddDate(0, 1, 0)},
+		{PaymentId: 4, Amount: 70000, PaidAt: june.AddDate(0, -1, 0)},
+	}
+	for _, p := range payments {
+		if err := testDb.Insert(p); err != nil {
+			t.Fatalf("testDb.Insert failed: %s", err)
+		}
+	}
+
+	ids := func(records []*db.Record) string {
+		var got []string
+		for _, r := range records {
+			got = append(got, r.ID())
+		}
+		return fmt.Sprint(got)
+	}
+
+	testCases := []struct {
+		index string
+		conds []gitdb.Condition
+		want  []*Payment
+	}{
+		//5000 < 12000 numerically but not as strings
+		{"Amount", []gitdb.Condition{gitdb.Gte(5000)}, []*Payment{payments[2], payments[0], payments[3]}},
+		{"Amount", []gitdb.Condition{gitdb.Gt(5000), gitdb.Lt(70000)}, []*Payment{payments[0]}},
+		{"Amount", []gitdb.Condition{gitdb.Eq(900)}, []*Payment{payments[1]}},
+		{"PaidAt", []gitdb.Condition{gitdb.Between(june, june.AddDate(0, 1, -1))}, []*Payment{payments[1], payments[0]}},
+		{"PaidAt", []gitdb.Condition{gitdb.Lt(june)}, []*Payment{payments[3]}},
+	}
+
+	for _, tc := range testCases {
+		results, err := testDb.SearchWhere("Payment", tc.index, tc.conds...)
+		if err != nil {
+			t.Fatalf("testDb.SearchWhere failed: %s", err)
+		}
+
+		var want []string
+		for _, p := range tc.want {
+			want = append(want, gitdb.ID(p))
+		}
+		if got := ids(results); got != fmt.Sprint(want) {
+			t.Errorf("%s: want: %v, got: %s", tc.index, want, got)
+		}
+	}
+
+	//sorted view is refreshed when the index changes
+	if err := testDb.Delete(gitdb.ID(payments[0])); err != nil {
+		t.Fatalf("testDb.Delete failed: %s", err)
+	}
+	results, err := testDb.SearchWhere("Payment", "Amount", gitdb.Gt(5000))
+	if err != nil {
+		t.Fatalf("testDb.SearchWhere failed: %s", err)
+	}
+	if len(results) != 1 || results[0].ID() != gitdb.ID(payments[3]) {
+		t.Errorf("want: [%s], got: %s", gitdb.ID(payments[3]), ids(results))
+	}
+}
+
+type Property struct {
+	testModel
+	PropertyId int
+	Lat        float64
+	Lng        float64
+}
+
+func (p *Property) GetSchema() *gitdb.Schema {
+	indexes := map[string]interface{}{"Lat": p.Lat, "Lng": p.Lng}
+	return gitdb.NewSchema("Property", "b0", fmt.Sprintf("%d", p.PropertyId), indexes).GeoIndex("Lat", "Lng")
+}
+
+func TestSearchNear(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	ikeja := &Property{PropertyId: 1, Lat: 6.6018, Lng: 3.3515}
+	abuja := &Property{PropertyId: 2, Lat: 9.0765, Lng: 7.3986}
+	lekki := &Property{PropertyId: 3, Lat: 6.4698, Lng: 3.5852}
+	for _, p := range []*Property{ikeja, abuja, lekki} {
+		if err := testDb.Insert(p); err != nil {
+			t.Fatalf("testDb.Insert failed: %s", err)
+		}
+	}
+
+	//from central Lagos ikeja is ~9km away and lekki ~24km
+	testCases := []struct {
+		radiusKm float64
+		want     []*Property
+	}{
+		{1, nil},
+		{15, []*Property{ikeja}},
+		{30, []*Property{ikeja, lekki}},
+		{1000, []*Property{ikeja, lekki, abuja}},
+	}
+
+	for _, tc := range testCases {
+		results, err := testDb.SearchNear("Property", 6.5244, 3.3792, tc.radiusKm)
+		if err != nil {
+			t.Fatalf("testDb.SearchNear failed: %s", err)
+		}
+
+		var got, want []string
+		for _, r := range results {
+			got = append(got, r.ID())
+		}
+		for _, p := range tc.want {
+			want = append(want, gitdb.ID(p))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%vkm: want: %v, got: %v", tc.radiusKm, want, got)
+		}
+	}
+}
+
+func TestGeoIndexFieldsMustBeNumbers(t *testing.T) {
+	schema := gitdb.NewSchema("Property", "b0", "1", map[string]interface{}{"Lat": "6.5", "Lng": 3.3}).GeoIndex("Lat", "Lng")
+	if err := schema.Validate(); err == nil {
+		t.Error("schema.Validate should fail for a geo index on a string field")
+	}
+}
+
+func TestSearchIDs(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	m1, m2, m3 := getTestMessageWithId(1), getTestMessageWithId(2), getTestMessageWithId(3)
+	m3.From = "carol@example.com"
+	for _, m := range []*Message{m1, m2, m3} {
+		if err := insert(m, false); err != nil {
+			t.Fatalf("insert failed: %s", err)
+		}
+	}
+
+	//blocks are never read so corrupting one must not matter
+	blockFile := filepath.Join(dbPath, "data", "Message", "b0.json")
+	if err := ioutil.WriteFile(blockFile, []byte("corrupt"), 0744); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := testDb.SearchIDs("Message", "From", "ALICE@example.com")
+	if err != nil {
+		t.Fatalf("testDb.SearchIDs failed: %s", err)
+	}
+	if want := []string{gitdb.ID(m1), gitdb.ID(m2)}; fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("want: %v, got: %v", want, ids)
+	}
+
+	values, err := testDb.IndexValues("Message", "From", gitdb.ID(m1), gitdb.ID(m3))
+	if err != nil {
+		t.Fatalf("testDb.IndexValues failed: %s", err)
+	}
+	if len(values) != 2 || values[gitdb.ID(m3)] != "carol@example.com" {
+		t.Errorf("want: From of %s and %s, got: %v", gitdb.ID(m1), gitdb.ID(m3), values)
+	}
+}
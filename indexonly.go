@@ -0,0 +1,52 @@
+package gitdb
+
+import (
+	"sort"
+	"strings"
+)
+
+//SearchIDs returns the ids of records in dataset whose index equals value, compared like
+//SearchEquals. Only the index is read so it is cheap enough for existence checks and
+//building sets of ids to intersect
+func (g *gitdb) SearchIDs(dataset string, index string, value string) ([]string, error) {
+	g.events <- newReadEvent("...", g.indexFile(dataset, index))
+
+	ids := []string{}
+	g.readCache(func() error {
+		queryValue := strings.ToLower(g.collateQuery(dataset, index, value))
+		for recordID, iv := range g.index(dataset, index) {
+			if searchMatch(iv.Value, queryValue, SearchEquals) {
+				ids = append(ids, recordID)
+			}
+		}
+		return nil
+	})
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+//IndexValues returns the values of an index for ids, or for every record in dataset
+//if no ids are given, without reading any blocks
+func (g *gitdb) IndexValues(dataset string, index string, ids ...string) (map[string]interface{}, error) {
+	g.events <- newReadEvent("...", g.indexFile(dataset, index))
+
+	values := map[string]interface{}{}
+	g.readCache(func() error {
+		idx := g.index(dataset, index)
+		if len(ids) == 0 {
+			for recordID, iv := range idx {
+				values[recordID] = iv.Value
+			}
+			return nil
+		}
+
+		for _, id := range ids {
+			if iv, ok := idx[id]; ok {
+				values[id] = iv.Value
+			}
+		}
+		return nil
+	})
+	return values, nil
+}
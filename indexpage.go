@@ -0,0 +1,205 @@
+package gitdb
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//indexNames returns the names of the indexes of dataset sorted
+func (g *gitdb) indexNames(dataset string) []string {
+	var names []string
+	for _, indexFile := range g.indexFiles(dataset) {
+		names = append(names, strings.TrimSuffix(filepath.Base(indexFile), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+//pageQuery asks for a page of the records of a dataset found, sorted and counted with its indexes
+type pageQuery struct {
+	dataset string
+	//where holds the values the indexes of matching records equal
+	where map[string]string
+	//filters are conditions on the indexes of matching records, all of which they meet
+	filters []indexFilter
+	//search is text the values of index in, or of any index when in is empty, contain
+	search string
+	in     string
+	sortBy string
+	desc   bool
+	offset int
+	//limit is how many records the page has at most. Pages with a limit of 0 only count the records
+	limit int
+	//hidden are indexes that can't be searched or sorted by e.g redacted fields
+	hidden func(index string) bool
+}
+
+//indexFilter matches records whose value of index matches value with mode or, when conds are set, satisfies conds
+type indexFilter struct {
+	index string
+	mode  SearchMode
+	value string
+	conds []Condition
+}
+
+//pageRecords returns the records of the page q asks for and how many records match q. Only the
+//records of the page are read from their blocks
+func (g *gitdb) pageRecords(q pageQuery) ([]*db.Record, int, error) {
+	var ids []string
+	var total int
+	searchBlocks := map[string][][]int{}
+	err := g.readCache(func() error {
+		var err error
+		ids, total, err = g.pageIDs(q, searchBlocks)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	records, err := g.hydrateSearchBlocks(q.dataset, searchBlocks)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	byID := map[string]*db.Record{}
+	for _, record := range records {
+		byID[record.ID()] = record
+	}
+	page := make([]*db.Record, 0, len(ids))
+	for _, id := range ids {
+		if record, ok := byID[id]; ok {
+			page = append(page, record)
+		}
+	}
+	return page, total, nil
+}
+
+//pageIDs returns the ids of the records of the page q asks for, adding their positions to searchBlocks,
+//and how many records match q
+func (g *gitdb) pageIDs(q pageQuery, searchBlocks map[string][][]int) ([]string, int, error) {
+	usable := map[string]bool{}
+	var indexes []string
+	for _, name := range g.indexNames(q.dataset) {
+		if q.hidden == nil || !q.hidden(name) {
+			usable[name] = true
+			indexes = append(indexes, name)
+		}
+	}
+
+	sortBy := q.sortBy
+	if !usable[sortBy] {
+		sortBy = "id"
+	}
+
+	//records without a value for the index sorted by follow the ones that have one
+	var ids []string
+	seen := map[string]bool{}
+	for _, entry := range g.orderedIndex(q.dataset, sortBy) {
+		ids = append(ids, entry.id)
+		seen[entry.id] = true
+	}
+	if sortBy != "id" {
+		for _, entry := range g.orderedIndex(q.dataset, "id") {
+			if !seen[entry.id] {
+				ids = append(ids, entry.id)
+			}
+		}
+	}
+	if q.desc {
+		for i, j := 0, len(ids)-1; i < j; i, j = i+1, j-1 {
+			ids[i], ids[j] = ids[j], ids[i]
+		}
+	}
+
+	for name, value := range q.where {
+		if !usable[name] {
+			return nil, 0, fmt.Errorf("%s is not an index of %s", name, q.dataset)
+		}
+		query := strings.ToLower(g.collateQuery(q.dataset, name, value))
+		index := g.index(q.dataset, name)
+		ids = filterIDs(ids, func(id string) bool {
+			iv, ok := index[id]
+			return ok && searchMatch(iv.Value, query, SearchEquals)
+		})
+	}
+
+	for _, filter := range q.filters {
+		if !usable[filter.index] {
+			return nil, 0, fmt.Errorf("%s is not an index of %s", filter.index, q.dataset)
+		}
+		ids = filterIDs(ids, g.filterMatch(q.dataset, filter))
+	}
+
+	if len(q.search) > 0 {
+		searched := indexes
+		if usable[q.in] {
+			searched = []string{q.in}
+		}
+
+		matched := map[string]bool{}
+		for _, name := range searched {
+			query := strings.ToLower(g.collateQuery(q.dataset, name, q.search))
+			for id, iv := range g.index(q.dataset, name) {
+				if !matched[id] && searchMatch(iv.Value, query, SearchContains) {
+					matched[id] = true
+				}
+			}
+		}
+		ids = filterIDs(ids, func(id string) bool { return matched[id] })
+	}
+
+	total := len(ids)
+	start, end := q.offset, q.offset+q.limit
+	if start > total {
+		start = total
+	}
+	if end > total {
+		end = total
+	}
+	ids = ids[start:end]
+
+	idIndex := g.index(q.dataset, "id")
+	for _, id := range ids {
+		_, block, _, err := ParseID(id)
+		if err != nil {
+			return nil, 0, err
+		}
+		iv := idIndex[id]
+		searchBlocks[block] = append(searchBlocks[block], []int{iv.Offset, iv.Len})
+	}
+	return ids, total, nil
+}
+
+//filterMatch returns a func reporting whether the record with an id matches filter
+func (g *gitdb) filterMatch(dataset string, filter indexFilter) func(id string) bool {
+	index := g.index(dataset, filter.index)
+	if len(filter.conds) > 0 {
+		conds := g.collateConds(dataset, filter.index, filter.conds)
+		return func(id string) bool {
+			iv, ok := index[id]
+			return ok && matches(toOrdered(iv.Value), conds)
+		}
+	}
+
+	query := strings.ToLower(g.collateQuery(dataset, filter.index, filter.value))
+	return func(id string) bool {
+		iv, ok := index[id]
+		return ok && searchMatch(iv.Value, query, filter.mode)
+	}
+}
+
+//filterIDs returns the ids keep reports true for, reusing ids
+func filterIDs(ids []string, keep func(id string) bool) []string {
+	kept := ids[:0]
+	for _, id := range ids {
+		if keep(id) {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,138 @@
+package gitdb
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+//isPath reports whether index is a dotted path into the model e.g Customer.Email
+func isPath(index string) bool {
+	return strings.Contains(index, ".")
+}
+
+//schemaOf returns the schema of m with the values of its path indexes read from m
+func schemaOf(m Model) *Schema {
+	schema := m.GetSchema()
+	schema.resolvePaths(m)
+	return schema
+}
+
+//resolvePaths sets the path indexes of the schema to their values in m, collated if they have a
+//collation, and rebuilds the composite indexes that include them
+func (a *Schema) resolvePaths(m Model) {
+	if len(a.paths) == 0 {
+		return
+	}
+
+	resolved := map[string]bool{}
+	for _, path := range a.paths {
+		v := valueAtPath(reflect.ValueOf(m), strings.Split(path, "."))
+		if c, ok := a.collations[path]; ok && v != nil {
+			v = collateValue(v, c)
+		}
+		a.indexes[path] = v
+		resolved[path] = true
+	}
+
+	for _, fields := range a.composites {
+		usesPath := false
+		values := make([]interface{}, len(fields))
+		for i, field := range fields {
+			values[i] = a.indexes[field]
+			usesPath = usesPath || resolved[field]
+		}
+		if usesPath {
+			a.indexes[strings.Join(fields, "+")] = Composite(values...)
+		}
+	}
+}
+
+//valueAtPath follows path through the structs and string keyed maps of v. Struct fields are
+//matched by name or JSON name. It returns nil if the path leads nowhere
+func valueAtPath(v reflect.Value, path []string) interface{} {
+	for _, name := range path {
+		v = indirect(v)
+		switch v.Kind() {
+		case reflect.Struct:
+			v = fieldByName(v, name)
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return nil
+			}
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		default:
+			return nil
+		}
+		if !v.IsValid() {
+			return nil
+		}
+	}
+
+	v = indirect(v)
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+//fieldByName returns the field of struct v named name, following embedded structs, or its invalid value
+func fieldByName(v reflect.Value, name string) reflect.Value {
+	index := fieldIndex(v.Type(), name)
+	if index == nil {
+		return reflect.Value{}
+	}
+
+	for i, x := range index {
+		//fields promoted from an embedded pointer are missing while it is nil
+		if i > 0 {
+			if v = indirect(v); !v.IsValid() {
+				return v
+			}
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+type fieldKey struct {
+	t    reflect.Type
+	name string
+}
+
+//fieldIndexCache holds the index sequence of each field looked up by fieldByName, so path indexes are
+//read with a walk over the fields of the model rather than a search of its type on every write
+var fieldIndexCache sync.Map
+
+//fieldIndex returns the index sequence of the field of struct type t with name or JSON name name, or nil
+func fieldIndex(t reflect.Type, name string) []int {
+	key := fieldKey{t: t, name: name}
+	if cached, ok := fieldIndexCache.Load(key); ok {
+		return cached.([]int)
+	}
+
+	var index []int
+	if sf, ok := t.FieldByName(name); ok {
+		index = sf.Index
+	} else {
+		for i := 0; i < t.NumField(); i++ {
+			if tag := strings.Split(t.Field(i).Tag.Get("json"), ","); tag[0] == name {
+				index = []int{i}
+				break
+			}
+		}
+	}
+
+	fieldIndexCache.Store(key, index)
+	return index
+}
@@ -0,0 +1,77 @@
+package gitdb_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+type Address struct {
+	City     string
+	Postcode string `json:"postcode"`
+}
+
+type Shipment struct {
+	testModel
+	ShipmentId int
+	Customer   struct {
+		Name    string
+		Email   string
+		Address *Address
+	}
+	Tags map[string]string
+}
+
+func (s *Shipment) GetSchema() *gitdb.Schema {
+	return gitdb.NewSchema("Shipment", "b0", fmt.Sprintf("%d", s.ShipmentId), map[string]interface{}{}).
+		Index("Customer.Email").
+		Index("Customer.Address.postcode").
+		Index("Tags.carrier").
+		Collate("Customer.Email", gitdb.CollateFoldCase)
+}
+
+func TestPathIndex(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	s1 := &Shipment{ShipmentId: 1, Tags: map[string]string{"carrier": "dhl"}}
+	s1.Customer.Email = "Alice@Example.com"
+	s1.Customer.Address = &Address{City: "London", Postcode: "N1"}
+	s2 := &Shipment{ShipmentId: 2}
+	s2.Customer.Email = "bob@example.com"
+	for _, s := range []*Shipment{s1, s2} {
+		if err := testDb.Insert(s); err != nil {
+			t.Fatalf("testDb.Insert failed: %s", err)
+		}
+	}
+
+	tests := []struct {
+		index string
+		value string
+		want  []string
+	}{
+		{"Customer.Email", "alice@example.com", []string{gitdb.ID(s1)}},
+		{"Customer.Address.postcode", "N1", []string{gitdb.ID(s1)}},
+		{"Tags.carrier", "dhl", []string{gitdb.ID(s1)}},
+	}
+	for _, tt := range tests {
+		results, err := testDb.Search("Shipment", []*gitdb.SearchParam{{Index: tt.index, Value: tt.value}}, gitdb.SearchEquals)
+		if err != nil {
+			t.Errorf("testDb.Search(%s) failed: %s", tt.index, err)
+			continue
+		}
+		var got []string
+		for _, r := range results {
+			got = append(got, r.ID())
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: want: %v, got: %v", tt.index, tt.want, got)
+		}
+	}
+
+	//paths that lead nowhere are indexed as nil
+	if v, ok := gitdb.Indexes(s2)["Customer.Address.postcode"]; !ok || v != nil {
+		t.Errorf("want: nil index value, got: %v", v)
+	}
+}
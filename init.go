@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"sync"
 
@@ -13,6 +14,23 @@ import (
 var mu sync.Mutex
 var conns map[string]GitDb
 
+//connsMu guards conns as connections can be opened and closed from any goroutine
+var connsMu sync.Mutex
+
+//setConn caches conn as the connection named name, or forgets it if conn is nil
+func setConn(name string, conn GitDb) {
+	connsMu.Lock()
+	defer connsMu.Unlock()
+	if conn == nil {
+		delete(conns, name)
+		return
+	}
+	if conns == nil {
+		conns = make(map[string]GitDb)
+	}
+	conns[name] = conn
+}
+
 //Open opens a connection to GitDB
 func Open(config *Config) (GitDb, error) {
 
@@ -22,20 +40,20 @@ func Open(config *Config) (GitDb, error) {
 		return nil, err
 	}
 
-	if conns == nil {
-		conns = make(map[string]GitDb)
-	}
-
 	if cfg.Mock {
 		conn := newMockConnection()
 		conn.configure(cfg)
-		conns[cfg.ConnectionName] = conn
+		setConn(cfg.ConnectionName, conn)
 		return conn, nil
 	}
 
 	conn := newConnection()
 	conn.configure(cfg)
 
+	if err := conn.acquireDbLock(); err != nil {
+		return nil, err
+	}
+
 	err := conn.boot()
 	logMsg := "Db booted fine"
 	if err != nil {
@@ -45,6 +63,7 @@ func Open(config *Config) (GitDb, error) {
 	log.Info(logMsg)
 
 	if err != nil {
+		conn.releaseDbLock()
 		return nil, err
 	}
 
@@ -52,19 +71,25 @@ func Open(config *Config) (GitDb, error) {
 	if !conn.loopStarted {
 		conn.startEventLoop()
 		conn.startSyncClock()
+		conn.startMaintenanceClock()
+		conn.startWebhooks()
 		if cfg.EnableUI {
 			conn.startUI()
 		}
 		conn.loopStarted = true
 	}
 
-	conns[cfg.ConnectionName] = conn
+	conn.backfillIndexes()
+
+	setConn(cfg.ConnectionName, conn)
 	return conn, nil
 }
 
 //Conn returns the last connection started by Open(*Config)
 // if you opened more than one connection use GetConn(name) instead
 func Conn() GitDb {
+	connsMu.Lock()
+	defer connsMu.Unlock()
 
 	if len(conns) > 1 {
 		panic("Multiple gitdb connections found. Use GetConn function instead")
@@ -89,6 +114,8 @@ func Conn() GitDb {
 
 //GetConn returns a specific gitdb connection by name
 func GetConn(name string) GitDb {
+	connsMu.Lock()
+	defer connsMu.Unlock()
 	if _, ok := conns[name]; !ok {
 		panic("No gitdb connection found")
 	}
@@ -151,10 +178,34 @@ func (g *gitdb) boot() error {
 		}
 	}
 
+	if err := g.setupMirrors(); err != nil {
+		return err
+	}
+
+	//resolve block conflicts at the record level if the gitdb binary is available
+	if bin, err := exec.LookPath("gitdb"); err == nil {
+		if err := g.gitDriver.registerMergeDriver(bin); err != nil {
+			log.Error("Failed to register merge driver: " + err.Error())
+		}
+	}
+
+	if len(g.config.SigningKey) > 0 {
+		if err := g.setupSigning(); err != nil {
+			return fmt.Errorf("failed to setup commit signing: %s", err)
+		}
+	}
+
 	//rebuild index if we have to
 	if _, err := os.Stat(g.indexDir()); err != nil {
 		//no index directory found so we need to re-index the whole db
-		go g.buildIndexFull()
+		g.indexing.Add(1)
+		go func() {
+			defer g.indexing.Done()
+			//hold off writes as they update the same indexes
+			g.cacheMu.Lock()
+			defer g.cacheMu.Unlock()
+			g.buildIndexFull()
+		}()
 	}
 
 	return nil
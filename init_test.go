@@ -1,7 +1,12 @@
 package gitdb_test
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gogitdb/gitdb/v2"
 )
@@ -23,3 +28,100 @@ func TestGetConn(t *testing.T) {
 		t.Errorf("connection don't match")
 	}
 }
+
+func TestOpenLocked(t *testing.T) {
+	cfg := getConfig()
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	other := *cfg
+	other.ConnectionName = "other"
+	if _, err := gitdb.Open(&other); err != gitdb.ErrDatabaseLocked {
+		t.Fatalf("want: %v, got: %v", gitdb.ErrDatabaseLocked, err)
+	}
+
+	//read-only connections open alongside the writer and see its writes
+	m := getTestMessage()
+	insert(m, true)
+	for _, name := range []string{"reader1", "reader2"} {
+		reader := *cfg
+		reader.ConnectionName = name
+		reader.SyncMode = gitdb.SyncManual
+		conn, err := gitdb.OpenReadOnly(&reader)
+		if err != nil {
+			t.Fatalf("gitdb.OpenReadOnly failed: %s", err)
+		}
+		defer conn.Close()
+		if err := conn.Get(gitdb.ID(m), &Message{}); err != nil {
+			t.Errorf("%s.Get failed: %s", name, err)
+		}
+	}
+
+	//wait for the database to be closed
+	other.LockTimeout = time.Second * 5
+	go func() {
+		time.Sleep(time.Millisecond * 200)
+		testDb.Close()
+	}()
+	conn, err := gitdb.Open(&other)
+	if err != nil {
+		t.Fatalf("gitdb.Open failed: %s", err)
+	}
+	conn.Close()
+}
+
+func TestSparseClone(t *testing.T) {
+	cfg := getConfig()
+	cfg.SyncMode = gitdb.SyncManual
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	if err := testDb.Insert(getTestMessageWithId(0)); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+	if err := testDb.Insert(&MessageV2{MessageId: 1, Body: "sparse"}); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+	if err := testDb.Sync(); err != nil {
+		t.Fatalf("testDb.Sync failed: %s", err)
+	}
+
+	cloneCfg := gitdb.NewConfig(testData + "/sparse")
+	cloneCfg.ConnectionName = "sparse"
+	cloneCfg.OnlineRemote = "file://" + fakeRemote
+	cloneCfg.SyncMode = gitdb.SyncManual
+	cloneCfg.CloneDepth = 1
+	cloneCfg.SparseDatasets = []string{"MessageV2"}
+	clone, err := gitdb.Open(cloneCfg)
+	if err != nil {
+		t.Fatalf("gitdb.Open failed: %s", err)
+	}
+	defer clone.Close()
+
+	dataDir := filepath.Join(cloneCfg.DbPath, "data")
+	if _, err := os.Stat(filepath.Join(dataDir, "MessageV2")); err != nil {
+		t.Errorf("MessageV2 dataset should be checked out: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "Message")); !os.IsNotExist(err) {
+		t.Errorf("Message dataset should not be checked out")
+	}
+
+	out, err := exec.Command("git", "-C", dataDir, "rev-parse", "--is-shallow-repository").CombinedOutput()
+	if err != nil || strings.TrimSpace(string(out)) != "true" {
+		t.Errorf("want shallow clone, got: %s", out)
+	}
+
+	//datasets that aren't checked out can't be written
+	if err := clone.Insert(&Order{OrderId: 1}); err == nil {
+		t.Error("want: error inserting into Order outside the sparse datasets")
+	}
+	if err := clone.Delete("Message/b0/0"); err == nil {
+		t.Error("want: error deleting from Message outside the sparse datasets")
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "Order")); !os.IsNotExist(err) {
+		t.Errorf("Order dataset should not be written")
+	}
+	if err := clone.Insert(&MessageV2{MessageId: 2, Body: "sparse"}); err != nil {
+		t.Errorf("clone.Insert failed: %s", err)
+	}
+}
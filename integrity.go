@@ -0,0 +1,253 @@
+package gitdb
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bouggo/log"
+)
+
+//Tampering is a block file whose content was changed without going through GitDB
+type Tampering struct {
+	File   string
+	Reason string
+}
+
+//Verify checks every block file against the manifest of HMACs GitDB keeps of the files it writes,
+//and against the last commit, and returns the files that were added, modified or deleted by other
+//means e.g editing the working tree or committing to the repository by hand. Without a manifest no
+//block file is trusted until TrustBlocks records them. Requires Config.IntegrityKey
+func (g *gitdb) Verify() ([]Tampering, error) {
+	if len(g.config.IntegrityKey) == 0 {
+		return nil, errors.New("Verify requires Config.IntegrityKey")
+	}
+
+	files, err := g.allBlockFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	g.manifestMu.Lock()
+	defer g.manifestMu.Unlock()
+	//read the manifest afresh so a manifest removed or edited on disk isn't trusted
+	g.manifest = nil
+	manifest := g.loadManifest()
+
+	var tampered []Tampering
+	seen := map[string]bool{}
+	for _, file := range files {
+		rel := g.relPath(file)
+		seen[rel] = true
+
+		data, err := g.readBlockFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		mac, ok := manifest[rel]
+		switch {
+		case !ok:
+			tampered = append(tampered, Tampering{File: rel, Reason: "not written by GitDB"})
+		case !hmac.Equal([]byte(mac), []byte(g.fileMAC(data))):
+			tampered = append(tampered, Tampering{File: rel, Reason: "modified outside GitDB"})
+		case !g.config.ObjectReads && g.gitDriver.isDirty(rel):
+			tampered = append(tampered, Tampering{File: rel, Reason: "not committed"})
+		}
+	}
+
+	for rel := range manifest {
+		if !seen[rel] {
+			tampered = append(tampered, Tampering{File: rel, Reason: "deleted outside GitDB"})
+		}
+	}
+
+	sort.Slice(tampered, func(i, j int) bool { return tampered[i].File < tampered[j].File })
+	return tampered, nil
+}
+
+//allBlockFiles returns the paths of the block files of every dataset
+func (g *gitdb) allBlockFiles() ([]string, error) {
+	datasets, err := g.datasetNames()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, dataset := range datasets {
+		blockFiles, err := g.blockFiles(dataset)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, blockFiles...)
+	}
+	return files, nil
+}
+
+//fileMAC returns the HMAC of data keyed with Config.IntegrityKey
+func (g *gitdb) fileMAC(data []byte) string {
+	mac := hmac.New(sha256.New, []byte(g.config.IntegrityKey))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+//TrustBlocks records the block files as they are now in the manifest checked by Verify, e.g after setting
+//Config.IntegrityKey on an existing database or checking the files Verify reports. Requires Config.IntegrityKey
+func (g *gitdb) TrustBlocks() error {
+	if len(g.config.IntegrityKey) == 0 {
+		return errors.New("TrustBlocks requires Config.IntegrityKey")
+	}
+
+	files, err := g.allBlockFiles()
+	if err != nil {
+		return err
+	}
+
+	manifest := map[string]string{}
+	for _, file := range files {
+		data, err := g.readBlockFile(file)
+		if err != nil {
+			return err
+		}
+		manifest[g.relPath(file)] = g.fileMAC(data)
+	}
+
+	g.manifestMu.Lock()
+	defer g.manifestMu.Unlock()
+	g.manifest = manifest
+	g.saveManifest()
+	log.Info(fmt.Sprintf("Trusting %d block files", len(manifest)))
+	return nil
+}
+
+//loadManifest returns the manifest of block files by path relative to the repository. A missing manifest
+//is empty so none of the block files are trusted. Callers must hold manifestMu
+func (g *gitdb) loadManifest() map[string]string {
+	if g.manifest != nil {
+		return g.manifest
+	}
+
+	g.manifest = map[string]string{}
+	data, err := ioutil.ReadFile(g.manifestFile())
+	if err == nil {
+		err = json.Unmarshal(data, &g.manifest)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		log.Error(err.Error())
+		g.manifest = map[string]string{}
+	}
+	return g.manifest
+}
+
+//saveManifest writes the manifest to disk. Callers must hold manifestMu
+func (g *gitdb) saveManifest() {
+	data, err := json.Marshal(g.manifest)
+	if err == nil {
+		err = ioutil.WriteFile(g.manifestFile(), data, 0744)
+	}
+	if err != nil {
+		log.Error("Failed to save manifest: " + err.Error())
+	}
+}
+
+func (g *gitdb) manifestFile() string {
+	return filepath.Join(g.absDbPath(), g.internalDirName(), "manifest.json")
+}
+
+//recordFile updates the manifest entry of blockFile after GitDB wrote data to it
+func (g *gitdb) recordFile(blockFile string, data []byte) {
+	if len(g.config.IntegrityKey) == 0 {
+		return
+	}
+
+	g.manifestMu.Lock()
+	defer g.manifestMu.Unlock()
+	g.loadManifest()[g.relPath(blockFile)] = g.fileMAC(data)
+	g.saveManifest()
+}
+
+//refreshFiles updates the manifest entries of files, relative to the repository, from their current content
+func (g *gitdb) refreshFiles(files []string) {
+	if len(g.config.IntegrityKey) == 0 || len(files) == 0 {
+		return
+	}
+
+	g.manifestMu.Lock()
+	defer g.manifestMu.Unlock()
+	manifest := g.loadManifest()
+	for _, rel := range files {
+		if !isBlockFile(rel) {
+			continue
+		}
+
+		data, err := g.readBlockFile(filepath.Join(g.dbDir(), filepath.FromSlash(rel)))
+		if os.IsNotExist(err) {
+			delete(manifest, rel)
+			continue
+		}
+		if err != nil {
+			log.Error(err.Error())
+			continue
+		}
+		manifest[rel] = g.fileMAC(data)
+	}
+	g.saveManifest()
+}
+
+//trustChanges updates the manifest with the files changed by git since commit before e.g by a pull or merge
+func (g *gitdb) trustChanges(before string) {
+	if len(g.config.IntegrityKey) == 0 {
+		return
+	}
+
+	after, err := g.gitDriver.head()
+	if err != nil || before == after {
+		return
+	}
+
+	changes, err := g.gitDriver.diffFiles(before, after)
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+
+	files := make([]string, 0, len(changes))
+	for _, c := range changes {
+		files = append(files, c.file)
+	}
+	g.refreshFiles(files)
+}
+
+//snapshotManifest returns a copy of the manifest so it can be restored when a transaction is reverted
+func (g *gitdb) snapshotManifest() map[string]string {
+	if len(g.config.IntegrityKey) == 0 {
+		return nil
+	}
+
+	g.manifestMu.Lock()
+	defer g.manifestMu.Unlock()
+	snapshot := map[string]string{}
+	for rel, mac := range g.loadManifest() {
+		snapshot[rel] = mac
+	}
+	return snapshot
+}
+
+//restoreManifest puts back a manifest returned by snapshotManifest
+func (g *gitdb) restoreManifest(snapshot map[string]string) {
+	if snapshot == nil {
+		return
+	}
+
+	g.manifestMu.Lock()
+	defer g.manifestMu.Unlock()
+	g.manifest = snapshot
+	g.saveManifest()
+}
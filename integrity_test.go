@@ -0,0 +1,76 @@
+package gitdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestVerify(t *testing.T) {
+	cfg := getConfig()
+	cfg.IntegrityKey = "manifest-key"
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	m := getTestMessage()
+	if err := insert(m, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+	if err := testDb.Insert(&Credential{Username: "ada", Password: "pass", Token: "tok"}); err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+
+	if tampered, err := testDb.Verify(); err != nil || len(tampered) != 0 {
+		t.Fatalf("want: no tampering, got: %v (%v)", tampered, err)
+	}
+
+	//without a manifest no block file is trusted until TrustBlocks
+	if err := os.Remove(filepath.Join(dbPath, ".gitdb", "manifest.json")); err != nil {
+		t.Fatal(err)
+	}
+	tampered, err := testDb.Verify()
+	if err != nil || len(tampered) != 2 || tampered[0].Reason != "not written by GitDB" {
+		t.Errorf("want: 2 block files not written by GitDB, got: %v (%v)", tampered, err)
+	}
+	if err := testDb.TrustBlocks(); err != nil {
+		t.Fatalf("TrustBlocks failed: %s", err)
+	}
+	if tampered, err := testDb.Verify(); err != nil || len(tampered) != 0 {
+		t.Fatalf("want: no tampering, got: %v (%v)", tampered, err)
+	}
+
+	//edit a block file and commit it by hand
+	blockFile := filepath.Join(dbPath, "data", "Credential", "b0.json")
+	if err := ioutil.WriteFile(blockFile, []byte(`{"Credential/b0/ada": "{}"}`), 0744); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "-C", filepath.Join(dbPath, "data"), "commit", "-qam", "edit").CombinedOutput(); err != nil {
+		t.Fatalf("git commit failed: %s", out)
+	}
+
+	//add a block file without committing it
+	if err := ioutil.WriteFile(filepath.Join(dbPath, "data", "Credential", "b1.json"), []byte(`{}`), 0744); err != nil {
+		t.Fatal(err)
+	}
+
+	tampered, err = testDb.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []gitdb.Tampering{
+		{File: "Credential/b0.json", Reason: "modified outside GitDB"},
+		{File: "Credential/b1.json", Reason: "not written by GitDB"},
+	}
+	if len(tampered) != len(want) {
+		t.Fatalf("want: %v, got: %v", want, tampered)
+	}
+	for i := range want {
+		if tampered[i] != want[i] {
+			t.Errorf("want: %v, got: %v", want[i], tampered[i])
+		}
+	}
+}
@@ -5,50 +5,97 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"io"
+	"strings"
 )
 
-//Encrypt message with key
-func Encrypt(key string, message string) string {
-	plainText := []byte(message)
+//header marks messages encrypted with AES-GCM. Messages without it were encrypted
+//with AES-CFB by earlier versions and are only readable through the legacy path
+const header = "v2:"
 
-	block, err := aes.NewCipher([]byte(key))
+var (
+	//ErrAuthFailed is returned when a message was not encrypted with the key or has been tampered with
+	ErrAuthFailed = errors.New("message authentication failed: wrong key or tampered data")
+	//ErrMalformed is returned when a message is not an encrypted message
+	ErrMalformed = errors.New("malformed encrypted message")
+)
+
+//Encrypt message with key using AES-GCM. The result is header followed by the nonce and sealed message in base64.
+//It fails if key is not 16, 24 or 32 bytes long
+func Encrypt(key string, message string) (string, error) {
+	gcm, err := newGCM(key)
 	if err != nil {
-		println(err.Error())
-		return ""
+		return "", err
 	}
 
-	//IV needs to be unique, but doesn't have to be secure.
-	//It's common to put it at the beginning of the ciphertext.
-	cipherText := make([]byte, aes.BlockSize+len(plainText))
-	iv := cipherText[:aes.BlockSize]
-	if _, err = io.ReadFull(rand.Reader, iv); err != nil {
-		println(err.Error())
-		return ""
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	cipherText := gcm.Seal(nonce, nonce, []byte(message), nil)
+	return header + base64.URLEncoding.EncodeToString(cipherText), nil
+}
+
+//Decrypt message with key. Messages without header are decrypted with AES-CFB which can't
+//detect a wrong key so callers must check the result themselves
+func Decrypt(key string, secureMessage string) (string, error) {
+	if !Versioned(secureMessage) {
+		return decryptCFB(key, secureMessage)
+	}
+
+	cipherText, err := base64.URLEncoding.DecodeString(strings.TrimPrefix(secureMessage, header))
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(cipherText) < gcm.NonceSize() {
+		return "", ErrMalformed
+	}
+
+	nonce, cipherText := cipherText[:gcm.NonceSize()], cipherText[gcm.NonceSize():]
+	plainText, err := gcm.Open(nil, nonce, cipherText, nil)
+	if err != nil {
+		return "", ErrAuthFailed
 	}
 
-	stream := cipher.NewCFBEncrypter(block, iv)
-	stream.XORKeyStream(cipherText[aes.BlockSize:], plainText)
+	return string(plainText), nil
+}
 
-	//returns to base64 encoded string
-	encmess := base64.URLEncoding.EncodeToString(cipherText)
-	return encmess
+//Versioned reports whether secureMessage was encrypted with AES-GCM
+func Versioned(secureMessage string) bool {
+	return strings.HasPrefix(secureMessage, header)
 }
 
-//Decrypt message with key
-func Decrypt(key string, secureMessage string) string {
+func newGCM(key string) (cipher.AEAD, error) {
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+//decryptCFB decrypts messages encrypted by earlier versions
+func decryptCFB(key string, secureMessage string) (string, error) {
 	cipherText, err := base64.URLEncoding.DecodeString(secureMessage)
 	if err != nil {
-		return ""
+		return "", ErrMalformed
 	}
 
 	block, err := aes.NewCipher([]byte(key))
 	if err != nil {
-		return ""
+		return "", err
 	}
 
 	if len(cipherText) < aes.BlockSize {
-		return ""
+		return "", ErrMalformed
 	}
 
 	iv := cipherText[:aes.BlockSize]
@@ -58,6 +105,46 @@ func Decrypt(key string, secureMessage string) string {
 	// XORKeyStream can work in-place if the two arguments are the same.
 	stream.XORKeyStream(cipherText, cipherText)
 
-	decodedmess := string(cipherText)
-	return decodedmess
+	return string(cipherText), nil
+}
+
+//keySep separates the keys of a keyring
+const keySep = "\n"
+
+//Keyring joins keys into a single key string for the Decrypt functions to try in turn
+func Keyring(keys ...string) string {
+	var ring []string
+	for _, key := range keys {
+		if len(key) > 0 {
+			ring = append(ring, key)
+		}
+	}
+	return strings.Join(ring, keySep)
+}
+
+//Keys returns the keys of keyring
+func Keys(keyring string) []string {
+	if len(keyring) == 0 {
+		return nil
+	}
+	return strings.Split(keyring, keySep)
 }
+
+//DecryptJSON decrypts secureMessage with the first key of keyring that authenticates it or,
+//for messages of earlier versions, that yields valid JSON
+func DecryptJSON(keyring string, secureMessage string) (string, error) {
+	for _, key := range Keys(keyring) {
+		dec, err := Decrypt(key, secureMessage)
+		if err == ErrMalformed {
+			return "", err
+		}
+		if err == nil && json.Valid([]byte(dec)) {
+			return dec, nil
+		}
+	}
+	return "", ErrAuthFailed
+}
+
+//FieldKey is the key of the object an encrypted field of a record is stored as
+//e.g {"SSN": {"$enc": "v2:..."}}
+const FieldKey = "$enc"
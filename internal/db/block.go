@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/bouggo/log"
 	"github.com/gogitdb/gitdb/v2/internal/digital"
@@ -18,7 +20,7 @@ import (
 type Block struct {
 	dataset    *Dataset
 	path       string
-	key        string
+	key        Decrypter
 	size       int64
 	badRecords []string
 	records    map[string]*Record
@@ -38,12 +40,16 @@ func (b *EmptyBlock) HydrateByPositions(blockFilePath string, positions ...[]int
 	}
 	defer fd.Close()
 
+	return b.HydrateByPositionsFrom(fd, positions...)
+}
+
+//HydrateByPositionsFrom is HydrateByPositions for block data read from r
+func (b *EmptyBlock) HydrateByPositionsFrom(r io.ReaderAt, positions ...[]int) error {
 	blockJSON := []byte("{")
 	for i, pos := range positions {
 
-		fd.Seek(int64(pos[0]), 0)
 		line := make([]byte, pos[1])
-		fd.Read(line)
+		r.ReadAt(line, int64(pos[0]))
 
 		line = bytes.TrimSpace(line)
 		ln := len(line) - 1
@@ -86,6 +92,11 @@ func (b *Block) Dataset() *Dataset {
 	return b.dataset
 }
 
+//Name returns the name of the block file without its extension
+func (b *Block) Name() string {
+	return strings.TrimSuffix(path.Base(b.path), ".json")
+}
+
 //HumanSize returns human readable size of a block
 func (b *Block) HumanSize() string {
 	return digital.FormatBytes(uint64(b.size))
@@ -102,7 +113,7 @@ func (b *Block) BadRecords() []string {
 }
 
 //NewEmptyBlock should be used to store records from multiple blocks
-func NewEmptyBlock(key string) *EmptyBlock {
+func NewEmptyBlock(key Decrypter) *EmptyBlock {
 	block := &EmptyBlock{}
 	block.key = key
 	block.records = map[string]*Record{}
@@ -111,18 +122,35 @@ func NewEmptyBlock(key string) *EmptyBlock {
 }
 
 //LoadBlock loads a block at a particular path
-func LoadBlock(blockFilePath, key string) *Block {
+func LoadBlock(blockFilePath string, key Decrypter) *Block {
+	block := newBlock(blockFilePath, key)
+	if err := block.loadBlock(); err != nil {
+		log.Error(err.Error())
+		block.dataset.badBlocks = append(block.dataset.badBlocks, blockFilePath)
+	}
+
+	return block
+}
+
+//ParseBlock loads a block from data read from blockFilePath by other means e.g git objects
+func ParseBlock(blockFilePath string, key Decrypter, data []byte) *Block {
+	block := newBlock(blockFilePath, key)
+	block.size = int64(len(data))
+	if err := json.Unmarshal(data, block); err != nil {
+		log.Error(err.Error())
+		block.dataset.badBlocks = append(block.dataset.badBlocks, blockFilePath)
+	}
+
+	return block
+}
+
+func newBlock(blockFilePath string, key Decrypter) *Block {
 	block := &Block{path: blockFilePath}
 	block.key = key
 	block.records = map[string]*Record{}
 	block.badRecords = []string{}
 	//TODO figure out a neat way to inject key
 	block.dataset = &Dataset{path: path.Dir(block.path), key: key}
-	if err := block.loadBlock(); err != nil {
-		log.Error(err.Error())
-		block.dataset.badBlocks = append(block.dataset.badBlocks, blockFilePath)
-	}
-
 	return block
 }
 
@@ -130,7 +158,7 @@ func LoadBlock(blockFilePath, key string) *Block {
 func (b *Block) MarshalJSON() ([]byte, error) {
 	raw := map[string]string{}
 	for k, v := range b.records {
-		raw[k] = v.data
+		raw[k] = v.raw
 	}
 
 	return json.Marshal(raw)
@@ -164,6 +192,16 @@ func (b *Block) loadBlock() error {
 	return json.Unmarshal(data, b)
 }
 
+//checkRecords adds the records of the block that can't be read to the bad records of the block and its dataset
+func (b *Block) checkRecords() {
+	for _, record := range b.Records() {
+		if record.Check() != nil {
+			b.badRecords = append(b.badRecords, record.id)
+			b.dataset.badRecords = append(b.dataset.badRecords, record.id)
+		}
+	}
+}
+
 //Record returns record in specifed index i
 func (b *Block) Record(i int) *Record {
 	records := b.Records()
@@ -206,6 +244,7 @@ func (b *Block) Len() int {
 func (b *Block) Records() []*Record {
 	var records []*Record
 	for _, v := range b.records {
+		v.key = b.key
 		v.decrypt(b.key)
 		records = append(records, v)
 	}
@@ -19,11 +19,11 @@ type Dataset struct {
 	badRecords   []string
 	lastModified time.Time
 
-	key string
+	key Decrypter
 }
 
 //LoadDataset loads the dataset at path
-func LoadDataset(datasetPath, key string) *Dataset {
+func LoadDataset(datasetPath string, key Decrypter) *Dataset {
 	ds := &Dataset{
 		path: datasetPath,
 		key:  key,
@@ -33,8 +33,8 @@ func LoadDataset(datasetPath, key string) *Dataset {
 	return ds
 }
 
-//LoadDatasets loads all datasets in given gitdb path
-func LoadDatasets(dbPath, key string) []*Dataset {
+//LoadDatasets loads all datasets in given gitdb path decrypting the records of each dataset with keyring(dataset)
+func LoadDatasets(dbPath string, keyring func(dataset string) Decrypter) []*Dataset {
 	var datasets []*Dataset
 
 	dirs, err := ioutil.ReadDir(dbPath)
@@ -48,7 +48,7 @@ func LoadDatasets(dbPath, key string) []*Dataset {
 			ds := &Dataset{
 				path:         filepath.Join(dbPath, dir.Name()),
 				lastModified: dir.ModTime(),
-				key:          key,
+				key:          keyring(dir.Name()),
 			}
 
 			datasets = append(datasets, ds)
@@ -141,11 +141,13 @@ func (d *Dataset) Blocks() []*Block {
 
 //BadBlocks returns all the bad blocks in a dataset
 func (d *Dataset) BadBlocks() []string {
+	d.BlockCount() //load blocks so errors are populated
 	return d.badBlocks
 }
 
 //BadRecords returns all the bad records in a dataset
 func (d *Dataset) BadRecords() []string {
+	d.BlockCount() //load blocks so errors are populated
 	return d.badRecords
 }
 
@@ -162,8 +164,16 @@ func (d *Dataset) loadBlocks() {
 	}
 
 	for _, blk := range blks {
-		if !blk.IsDir() && strings.HasSuffix(blk.Name(), ".json") {
-			b := LoadBlock(filepath.Join(d.path, blk.Name()), d.key)
+		//files starting with . such as .meta.json are not blocks
+		if !blk.IsDir() && strings.HasSuffix(blk.Name(), ".json") && !strings.HasPrefix(blk.Name(), ".") {
+			b := newBlock(filepath.Join(d.path, blk.Name()), d.key)
+			b.dataset = d
+			if err := b.loadBlock(); err != nil {
+				log.Error(err.Error())
+				d.badBlocks = append(d.badBlocks, b.path)
+			} else {
+				b.checkRecords()
+			}
 			d.blocks = append(d.blocks, b)
 		}
 	}
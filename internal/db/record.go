@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"sort"
 
 	"github.com/bouggo/log"
 	"github.com/gogitdb/gitdb/v2/internal/crypto"
@@ -13,17 +14,36 @@ import (
 //Record represents a model stored in gitdb
 type Record struct {
 	id    string
+	raw   string
 	data  string
 	index map[string]interface{}
-	key   string
+	key   Decrypter
 
-	p         fastjson.Parser
+	p fastjson.Parser
+	//version and indexed cache the layout and indexes of the record, which don't change once it is read
+	version   string
+	indexed   bool
 	decrypted bool
+	//decryptErr is why the record could not be decrypted
+	decryptErr error
+}
+
+//Decrypter decrypts records, and fields of records, stored encrypted
+type Decrypter interface {
+	Decrypt(secureMessage string) (string, error)
+}
+
+//Keyring is a Decrypter that decrypts with the first key of a crypto.Keyring that can
+type Keyring string
+
+//Decrypt implements Decrypter
+func (k Keyring) Decrypt(secureMessage string) (string, error) {
+	return crypto.DecryptJSON(string(k), secureMessage)
 }
 
 //newRecord constructs a Record
 func newRecord(id, data string) *Record {
-	return &Record{id: id, data: data, index: map[string]interface{}{}}
+	return &Record{id: id, raw: data, data: data, index: map[string]interface{}{}}
 }
 
 //ID returns record id
@@ -31,14 +51,34 @@ func (r *Record) ID() string {
 	return r.id
 }
 
-//Data returns record data unmodified
+//Data returns record data unmodified i.e as stored in the block file
 func (r *Record) Data() string {
-	return r.data
+	return r.raw
 }
 
-//Hydrate populates given interfacce with underlying record data
+//afterFinder is implemented by models that run code after they are read e.g gitdb.AfterFinder
+type afterFinder interface {
+	AfterFind() error
+}
+
+//Hydrate populates given interfacce with underlying record data then calls its AfterFind hook if it has one
 func (r *Record) Hydrate(model interface{}) error {
-	r.decrypt(r.key)
+	if err := r.hydrate(model); err != nil {
+		return err
+	}
+
+	if hook, ok := model.(afterFinder); ok {
+		if err := hook.AfterFind(); err != nil {
+			return fmt.Errorf("Model.AfterFind failed: %s", err)
+		}
+	}
+	return nil
+}
+
+func (r *Record) hydrate(model interface{}) error {
+	if err := r.decrypt(r.key); err != nil {
+		return err
+	}
 	version := r.Version()
 	switch version {
 	case "v1":
@@ -56,6 +96,10 @@ func (r *Record) Hydrate(model interface{}) error {
 		buf := make([]byte, obj.Len())
 		buf = obj.MarshalTo(buf)
 		buf = bytes.Trim(buf, "\x00")
+		buf, err = r.decryptFields(buf)
+		if err != nil {
+			return err
+		}
 
 		// fmt.Printf("%s\n", oh)
 		if err := json.Unmarshal(buf, model); err != nil {
@@ -72,28 +116,137 @@ func (r *Record) Hydrate(model interface{}) error {
 	}
 }
 
-func (r *Record) decrypt(key string) {
-	if len(key) > 0 && !r.decrypted {
-		log.Test("decrypting with: " + key)
-		dec := crypto.Decrypt(key, r.data)
-		if len(dec) > 0 {
-			r.data = dec
-		}
+//decrypt decrypts the record with keyring. Records that are already JSON were stored unencrypted
+func (r *Record) decrypt(keyring Decrypter) error {
+	if keyring != nil && !r.decrypted {
 		r.decrypted = true
+		if json.Valid([]byte(r.data)) {
+			return nil
+		}
+
+		log.Test("decrypting " + r.id)
+		dec, err := keyring.Decrypt(r.data)
+		if err != nil {
+			r.decryptErr = fmt.Errorf("Record %s could not be decrypted: %w", r.id, err)
+			return r.decryptErr
+		}
+		r.data = dec
+	}
+
+	return r.decryptErr
+}
+
+//Check returns why the record can't be read i.e it can't be decrypted or isn't JSON, nil if it can
+func (r *Record) Check() error {
+	if err := r.decrypt(r.key); err != nil {
+		return err
+	}
+	if !json.Valid([]byte(r.data)) {
+		return fmt.Errorf("Record %s is not valid JSON", r.id)
+	}
+	return nil
+}
+
+//decryptFields replaces the fields of data encrypted with Schema.EncryptFields with their values
+func (r *Record) decryptFields(data []byte) ([]byte, error) {
+	if !bytes.Contains(data, []byte(`"`+crypto.FieldKey+`"`)) {
+		return data, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	for name, value := range fields {
+		var enc map[string]string
+		if json.Unmarshal(value, &enc) != nil || len(enc) != 1 {
+			continue
+		}
+
+		secureValue, ok := enc[crypto.FieldKey]
+		if !ok {
+			continue
+		}
+
+		if r.key == nil {
+			return nil, fmt.Errorf("Field %s of record %s could not be decrypted: %w", name, r.id, crypto.ErrAuthFailed)
+		}
+		dec, err := r.key.Decrypt(secureValue)
+		if err != nil {
+			return nil, fmt.Errorf("Field %s of record %s could not be decrypted: %w", name, r.id, err)
+		}
+		fields[name] = json.RawMessage(dec)
 	}
+
+	return json.Marshal(fields)
+}
+
+//Migrate replaces the decrypted data of the record with what migrate returns for it. Data still
+//returns the record as stored
+func (r *Record) Migrate(migrate func(data string) (string, error)) error {
+	if err := r.decrypt(r.key); err != nil {
+		return err
+	}
+
+	data, err := migrate(r.data)
+	if err != nil {
+		return err
+	}
+	r.data = data
+	return nil
+}
+
+//Copy returns a decrypted copy of the record. Copies of a record can be read by different goroutines
+func (r *Record) Copy() *Record {
+	r.decrypt(r.key)
+	c := &Record{id: r.id, raw: r.raw, data: r.data, key: r.key, decrypted: r.decrypted, decryptErr: r.decryptErr}
+	c.index = make(map[string]interface{}, len(r.index))
+	for k, v := range r.index {
+		c.index[k] = v
+	}
+	return c
 }
 
 //Indexes returns v2 indexes for GitDB
 func (r *Record) Indexes() map[string]interface{} {
-	var m map[string]interface{}
-	r.Hydrate(&m)
+	if !r.indexed {
+		var m map[string]interface{}
+		r.indexed = r.Hydrate(&m) == nil
+	}
 	return r.index
 }
 
+//Plain returns data decrypted, or as stored if it can't be decrypted
+func (r *Record) Plain() string {
+	r.decrypt(r.key)
+	return r.data
+}
+
+//EncryptedFields returns the fields of the decrypted record stored encrypted with Schema.EncryptFields
+func (r *Record) EncryptedFields() []string {
+	var rec struct {
+		Data map[string]json.RawMessage
+	}
+	json.Unmarshal([]byte(r.Plain()), &rec)
+
+	var fields []string
+	for name, value := range rec.Data {
+		var enc map[string]json.RawMessage
+		if json.Unmarshal(value, &enc) == nil && len(enc) == 1 && enc[crypto.FieldKey] != nil {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
 //JSON returns data decrypted and indented
 func (r *Record) JSON() string {
 	var buf bytes.Buffer
-	r.decrypt(r.key)
+	if err := r.decrypt(r.key); err != nil {
+		log.Error(err.Error())
+	}
 	if err := json.Indent(&buf, []byte(r.data), "", "\t"); err != nil {
 		log.Error(err.Error())
 	}
@@ -101,22 +254,59 @@ func (r *Record) JSON() string {
 	return buf.String()
 }
 
-//Version returns the version of the record
+//Version returns the version of the record's layout. v1 records are the JSON of the model on its own,
+//later versions wrap it as Data alongside Version and Indexes. The layout is sniffed rather than
+//taken from Version alone so a v1 model with a Version field of its own is still read as v1
 func (r *Record) Version() string {
+	if len(r.version) > 0 {
+		return r.version
+	}
+	if err := r.decrypt(r.key); err != nil {
+		return "v1"
+	}
+
 	v, err := r.p.Parse(r.data)
 	if err != nil {
 		return "v1"
 	}
 
-	versionBytes := v.GetStringBytes("Version")
-	version := string(versionBytes)
-	if len(version) == 0 {
+	version := string(v.GetStringBytes("Version"))
+	if data := v.Get("Data"); len(version) == 0 || data == nil || data.Type() != fastjson.TypeObject {
 		version = "v1"
 	}
 
+	r.version = version
 	return version
 }
 
+//Revision returns how many times the record has been written. Records written before
+//revisions were kept are at revision 0
+func (r *Record) Revision() int {
+	if err := r.decrypt(r.key); err != nil {
+		return 0
+	}
+
+	v, err := r.p.Parse(r.data)
+	if err != nil {
+		return 0
+	}
+	return v.GetInt("Revision")
+}
+
+//Type returns the name of the concrete model the record was stored as in a polymorphic dataset.
+//Records of other datasets have no type
+func (r *Record) Type() string {
+	if err := r.decrypt(r.key); err != nil {
+		return ""
+	}
+
+	v, err := r.p.Parse(r.data)
+	if err != nil {
+		return ""
+	}
+	return string(v.GetStringBytes("Type"))
+}
+
 //ConvertModel converts a Model to a record
 func ConvertModel(id string, m interface{}) *Record {
 	b, _ := json.Marshal(m)
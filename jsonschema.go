@@ -0,0 +1,274 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//jsonSchemaDraft is the JSON Schema version ExportJSONSchema writes
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+//ExportJSONSchema returns a JSON Schema document describing the models of dataset as they are
+//stored, built from the model Config.Factory returns or RegisterModel registered for it. Models of
+//polymorphic datasets are described by one schema per type of Config.Types under oneOf. The rules of
+//gitdb tags are exported as the matching keywords e.g max=64 as maxLength, though JSON Schema applies
+//them to empty values of optional fields which validation lets through
+func (g *gitdb) ExportJSONSchema(dataset string) ([]byte, error) {
+	return g.config.jsonSchema(dataset)
+}
+
+func (c Config) jsonSchema(dataset string) ([]byte, error) {
+	doc := map[string]interface{}{}
+	if types, ok := c.Types[dataset]; ok {
+		names := make([]string, 0, len(types))
+		for name := range types {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var schemas []interface{}
+		for _, name := range names {
+			schema, err := modelJSONSchema(types[name]())
+			if err != nil {
+				return nil, err
+			}
+			schema["title"] = name
+			schemas = append(schemas, schema)
+		}
+		doc["oneOf"] = schemas
+	} else {
+		m := c.factory(dataset)
+		if m == nil {
+			return nil, fmt.Errorf("%s has no model in Config.Types, Config.Factory or RegisterModel", dataset)
+		}
+
+		schema, err := modelJSONSchema(m)
+		if err != nil {
+			return nil, err
+		}
+		doc = schema
+	}
+
+	doc["$schema"] = jsonSchemaDraft
+	doc["title"] = dataset
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func modelJSONSchema(m Model) (map[string]interface{}, error) {
+	t := reflect.TypeOf(m)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%T is not a struct", m)
+	}
+	return typeSchema(t, map[reflect.Type]bool{})
+}
+
+//typeSchema describes how values of t are marshalled to JSON. seen holds the structs being
+//described so recursive types end in a plain object instead of looping
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) (map[string]interface{}, error) {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}, nil
+	}
+	//types that marshal themselves can be anything
+	if t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) {
+		return map[string]interface{}{}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema, err := typeSchema(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return nullable(schema), nil
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, nil
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, nil
+	case reflect.Interface:
+		return map[string]interface{}{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}, nil
+		}
+		items, err := typeSchema(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		schema := map[string]interface{}{"type": "array", "items": items}
+		if t.Kind() == reflect.Slice {
+			return nullable(schema), nil
+		}
+		schema["minItems"], schema["maxItems"] = t.Len(), t.Len()
+		return schema, nil
+	case reflect.Map:
+		values, err := typeSchema(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return nullable(map[string]interface{}{"type": "object", "additionalProperties": values}), nil
+	case reflect.Struct:
+		if seen[t] {
+			return map[string]interface{}{"type": "object"}, nil
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		properties := map[string]interface{}{}
+		required := []string{}
+		if err := structProperties(t, seen, properties, &required); err != nil {
+			return nil, err
+		}
+		sort.Strings(required)
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema, nil
+	}
+	return nil, fmt.Errorf("%s can't be described by JSON Schema", t)
+}
+
+//structProperties adds the fields of struct t to properties. Fields without omitempty are always
+//marshalled so they are required. Fields of embedded structs are added as fields of t
+func structProperties(t reflect.Type, seen map[reflect.Type]bool, properties map[string]interface{}, required *[]string) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" || isTransient(sf) {
+			continue
+		}
+
+		name := sf.Name
+		opts := strings.Split(tag, ",")
+		if opts[0] != "" {
+			name = opts[0]
+		}
+
+		ft := sf.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && opts[0] == "" && ft.Kind() == reflect.Struct {
+			if err := structProperties(ft, seen, properties, required); err != nil {
+				return err
+			}
+			continue
+		}
+		if sf.PkgPath != "" || sf.Type.Kind() == reflect.Func || sf.Type.Kind() == reflect.Chan {
+			continue
+		}
+
+		schema, err := typeSchema(sf.Type, seen)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", t.Name(), sf.Name, err)
+		}
+		for _, opt := range opts[1:] {
+			if opt == "string" {
+				schema = map[string]interface{}{"type": "string"}
+			}
+		}
+
+		rules, err := parseRules(sf.Tag.Get("gitdb"))
+		if err != nil {
+			return fmt.Errorf("%s.%s has an invalid gitdb tag: %s", t.Name(), sf.Name, err)
+		}
+		applyRules(schema, ft, rules)
+
+		properties[name] = schema
+		omitempty := false
+		for _, opt := range opts[1:] {
+			omitempty = omitempty || opt == "omitempty"
+		}
+		if !omitempty {
+			*required = append(*required, name)
+		}
+	}
+	return nil
+}
+
+//applyRules adds the JSON Schema keywords of the rules of a gitdb tag to schema, which describes values of t
+func applyRules(schema map[string]interface{}, t reflect.Type, rules []fieldRule) {
+	for _, rule := range rules {
+		switch rule.name {
+		case "required":
+			switch t.Kind() {
+			case reflect.String:
+				schema["minLength"] = 1
+			case reflect.Slice, reflect.Map:
+				schema[sizeKeyword(t.Kind(), "min")] = 1
+			}
+		case "min", "max":
+			switch t.Kind() {
+			case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+				schema[sizeKeyword(t.Kind(), rule.name)] = int(rule.n)
+			default:
+				if rule.name == "min" {
+					schema["minimum"] = rule.n
+				} else {
+					schema["maximum"] = rule.n
+				}
+			}
+		case "email":
+			schema["format"] = "email"
+		case "oneof":
+			var values []interface{}
+			for _, v := range strings.Fields(rule.arg) {
+				values = append(values, enumValue(t, v))
+			}
+			schema["enum"] = values
+		}
+	}
+}
+
+//sizeKeyword returns the keyword that limits the size of values of kind k e.g maxLength for strings
+func sizeKeyword(k reflect.Kind, limit string) string {
+	switch k {
+	case reflect.String:
+		return limit + "Length"
+	case reflect.Map:
+		return limit + "Properties"
+	}
+	return limit + "Items"
+}
+
+//enumValue returns v as the JSON value of a field of type t
+func enumValue(t reflect.Type, v string) interface{} {
+	switch {
+	case t.Kind() == reflect.Bool:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	case isNumber(t.Kind()):
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	}
+	return v
+}
+
+//nullable lets schema also match null, which nil pointers, slices and maps are marshalled to
+func nullable(schema map[string]interface{}) map[string]interface{} {
+	//schemas without a type match null already
+	if typ, ok := schema["type"].(string); ok {
+		schema["type"] = []string{typ, "null"}
+	}
+	return schema
+}
@@ -0,0 +1,90 @@
+package gitdb_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func exportJSONSchema(t *testing.T, dataset string) map[string]interface{} {
+	t.Helper()
+	b, err := testDb.ExportJSONSchema(dataset)
+	if err != nil {
+		t.Fatalf("testDb.ExportJSONSchema(%s) failed: %s", dataset, err)
+	}
+
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("ExportJSONSchema returned invalid JSON: %s", err)
+	}
+	return doc
+}
+
+func TestExportJSONSchema(t *testing.T) {
+	cfg := getConfig()
+	cfg.Factory = func(dataset string) gitdb.Model {
+		if dataset == "Contact" {
+			return &Contact{}
+		}
+		return nil
+	}
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	doc := exportJSONSchema(t, "Contact")
+	if doc["title"] != "Contact" || doc["type"] != "object" {
+		t.Errorf("want: object titled Contact, got: %v", doc)
+	}
+
+	props := doc["properties"].(map[string]interface{})
+	want := map[string]map[string]interface{}{
+		"ContactId": {"type": "integer"},
+		"Name":      {"type": "string", "minLength": 1.0, "maxLength": 16.0},
+		"Email":     {"type": "string", "minLength": 1.0, "format": "email"},
+		"Age":       {"type": "integer", "minimum": 18.0},
+		"Status":    {"type": "string", "enum": []interface{}{"active", "inactive"}},
+		"CreatedAt": {"type": "string", "format": "date-time"},
+	}
+	for field, schema := range want {
+		if !reflect.DeepEqual(props[field], map[string]interface{}(schema)) {
+			t.Errorf("%s want: %v, got: %v", field, schema, props[field])
+		}
+	}
+
+	required := doc["required"].([]interface{})
+	if len(required) != 7 {
+		t.Errorf("want: 7 required fields, got: %v", required)
+	}
+
+	if _, err := testDb.ExportJSONSchema("Unknown"); err == nil {
+		t.Error("ExportJSONSchema should fail for a dataset without a model")
+	}
+}
+
+func TestExportJSONSchemaTypes(t *testing.T) {
+	cfg := getConfig()
+	cfg.Types = map[string]map[string]func() gitdb.Model{
+		"Payments": {
+			"card":     func() gitdb.Model { return &CardPayment{} },
+			"transfer": func() gitdb.Model { return &TransferPayment{} },
+		},
+	}
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	doc := exportJSONSchema(t, "Payments")
+	schemas := doc["oneOf"].([]interface{})
+	if len(schemas) != 2 {
+		t.Fatalf("want: 2 schemas, got: %d", len(schemas))
+	}
+
+	card := schemas[0].(map[string]interface{})
+	if card["title"] != "card" {
+		t.Errorf("want: card, got: %v", card["title"])
+	}
+	if _, ok := card["properties"].(map[string]interface{})["Last4"]; !ok {
+		t.Errorf("want: Last4 in card schema, got: %v", card["properties"])
+	}
+}
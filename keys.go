@@ -0,0 +1,122 @@
+package gitdb
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bouggo/log"
+	"github.com/gogitdb/gitdb/v2/internal/crypto"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//KeyProvider supplies the encryption key of each dataset so keys can be kept in a secrets
+//manager or KMS instead of Config. Keys must be 16, 24 or 32 bytes long
+type KeyProvider interface {
+	GetKey(dataset string) ([]byte, error)
+}
+
+//KeyFunc adapts a function to a KeyProvider e.g to fetch keys from Vault
+type KeyFunc func(dataset string) ([]byte, error)
+
+//GetKey implements KeyProvider
+func (f KeyFunc) GetKey(dataset string) ([]byte, error) {
+	return f(dataset)
+}
+
+//EnvKeyProvider reads keys from environment variables. The key of a dataset is read
+//from Var_DATASET e.g GITDB_KEY_BOOKINGS falling back to Var
+type EnvKeyProvider struct {
+	Var string
+}
+
+//GetKey implements KeyProvider
+func (p EnvKeyProvider) GetKey(dataset string) ([]byte, error) {
+	for _, name := range []string{p.Var + "_" + strings.ToUpper(dataset), p.Var} {
+		if key := os.Getenv(name); len(key) > 0 {
+			return []byte(key), nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not set", p.Var)
+}
+
+//FileKeyProvider reads keys from files. When Path is a directory the key of a dataset is read from
+//the file named after the dataset falling back to a file named default, otherwise every dataset
+//uses the key in Path. Leading and trailing white space is ignored
+type FileKeyProvider struct {
+	Path string
+}
+
+//GetKey implements KeyProvider
+func (p FileKeyProvider) GetKey(dataset string) ([]byte, error) {
+	files := []string{p.Path}
+	if info, err := os.Stat(p.Path); err == nil && info.IsDir() {
+		files = []string{filepath.Join(p.Path, dataset), filepath.Join(p.Path, "default")}
+	}
+
+	var err error
+	for _, file := range files {
+		var key []byte
+		if key, err = ioutil.ReadFile(file); err == nil {
+			return []byte(strings.TrimSpace(string(key))), nil
+		}
+	}
+	return nil, err
+}
+
+//validKeyLength reports whether key is long enough for AES-128, AES-192 or AES-256
+func validKeyLength(key string) bool {
+	switch len(key) {
+	case 16, 24, 32:
+		return true
+	}
+	return false
+}
+
+//encryptionKey returns the key records of dataset are encrypted with
+func (g *gitdb) encryptionKey(dataset string) (string, error) {
+	if g.config.KeyProvider == nil {
+		return g.config.EncryptionKey, nil
+	}
+
+	g.keysMu.Lock()
+	defer g.keysMu.Unlock()
+	if key, ok := g.keys[dataset]; ok {
+		return key, nil
+	}
+
+	key, err := g.config.KeyProvider.GetKey(dataset)
+	if err != nil {
+		return "", fmt.Errorf("failed to get encryption key of %s: %w", dataset, err)
+	}
+
+	if !validKeyLength(string(key)) {
+		return "", errors.New("Invalid encryption key of " + dataset + ": must be 16, 24 or 32 bytes long")
+	}
+
+	if g.keys == nil {
+		g.keys = map[string]string{}
+	}
+	g.keys[dataset] = string(key)
+	return g.keys[dataset], nil
+}
+
+//forgetKey drops the cached key of dataset so it is asked from Config.KeyProvider again
+func (g *gitdb) forgetKey(dataset string) {
+	g.keysMu.Lock()
+	defer g.keysMu.Unlock()
+	delete(g.keys, dataset)
+}
+
+//keyring returns what records of dataset are read with: Config.Cipher or the key of dataset
+//followed by Config.PreviousKeys
+func (g *gitdb) keyring(dataset string) db.Decrypter {
+	key, err := g.encryptionKey(dataset)
+	if err != nil {
+		log.Error(err.Error())
+	}
+	return g.config.decrypter(dataset, crypto.Keyring(append([]string{key}, g.config.PreviousKeys...)...))
+}
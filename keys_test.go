@@ -0,0 +1,148 @@
+package gitdb_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestKeyProvider(t *testing.T) {
+	messageKey := "0123456789abcdef0123456789abcdef"
+	cfg := getConfig()
+	cfg.EncryptionKey = ""
+	cfg.KeyProvider = gitdb.KeyFunc(func(dataset string) ([]byte, error) {
+		if dataset == "Message" {
+			return []byte(messageKey), nil
+		}
+		return nil, errors.New("no key")
+	})
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	m := getTestMessage()
+	if err := insert(m, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	if err := testDb.Insert(&Patient{PatientId: 1, SSN: "078-05-1120"}); err == nil {
+		t.Error("insert should fail when the provider has no key for the dataset")
+	}
+
+	//the key from env vars and files decrypts the record
+	os.Setenv("GITDB_TEST_KEY_MESSAGE", messageKey)
+	defer os.Unsetenv("GITDB_TEST_KEY_MESSAGE")
+
+	keyDir := filepath.Join(testData, "keys")
+	if err := os.MkdirAll(keyDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(keyDir, "default"), []byte(messageKey+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, provider := range []gitdb.KeyProvider{gitdb.EnvKeyProvider{Var: "GITDB_TEST_KEY"}, gitdb.FileKeyProvider{Path: keyDir}} {
+		testDb.Close()
+		cfg.KeyProvider = provider
+		testDb = getDbConn(t, cfg)
+
+		result := &Message{}
+		if err := testDb.Get(gitdb.ID(m), result); err != nil || result.Body != m.Body {
+			t.Errorf("%T: want: %s, got: %s (%v)", provider, m.Body, result.Body, err)
+		}
+	}
+}
+
+func TestInsertEncryptedWithoutKey(t *testing.T) {
+	cfg := getConfig()
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	for _, key := range []string{"", "too-short"} {
+		testDb.Close()
+		cfg.EncryptionKey = key
+		testDb = getDbConn(t, cfg)
+
+		m := getTestMessageWithId(1)
+		if err := testDb.Insert(m); err == nil {
+			t.Errorf("want: error inserting a model that should be encrypted with key %q", key)
+		}
+		if err := testDb.Exists(gitdb.ID(m)); err == nil {
+			t.Errorf("want: nothing stored with key %q", key)
+		}
+	}
+}
+
+//reverseCipher is a toy Cipher that records the datasets it is used for
+type reverseCipher struct {
+	datasets map[string]bool
+}
+
+func (c *reverseCipher) reverse(dataset string, in []byte) []byte {
+	c.datasets[dataset] = true
+	out := make([]byte, len(in))
+	for i, b := range in {
+		out[len(in)-1-i] = b ^ 0x5a
+	}
+	return out
+}
+
+func (c *reverseCipher) Encrypt(dataset string, plaintext []byte) ([]byte, error) {
+	return c.reverse(dataset, plaintext), nil
+}
+
+func (c *reverseCipher) Decrypt(dataset string, ciphertext []byte) ([]byte, error) {
+	return c.reverse(dataset, ciphertext), nil
+}
+
+func TestCipher(t *testing.T) {
+	cfg := getConfig()
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	//records encrypted before the cipher is configured stay readable
+	before := getTestMessage()
+	if err := insert(before, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	testDb.Close()
+	cipher := &reverseCipher{datasets: map[string]bool{}}
+	cfg.Cipher = cipher
+	testDb = getDbConn(t, cfg)
+
+	m := getTestMessage()
+	if err := insert(m, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+	p := &Patient{PatientId: 1, Name: "Ada Obi", SSN: "078-05-1120"}
+	if err := testDb.Insert(p); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dbPath, "data", "Patient", "b0.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "cipher:") || strings.Contains(string(data), p.SSN) {
+		t.Errorf("want: SSN encrypted with the cipher, got: %s", data)
+	}
+	if !cipher.datasets["Message"] || !cipher.datasets["Patient"] {
+		t.Errorf("want: cipher used for Message and Patient, got: %v", cipher.datasets)
+	}
+
+	for _, want := range []*Message{before, m} {
+		result := &Message{}
+		if err := testDb.Get(gitdb.ID(want), result); err != nil || result.Body != want.Body {
+			t.Errorf("want: %s, got: %s (%v)", want.Body, result.Body, err)
+		}
+	}
+	result := &Patient{}
+	if err := testDb.Get(gitdb.ID(p), result); err != nil || result.SSN != p.SSN {
+		t.Errorf("want: %s, got: %s (%v)", p.SSN, result.SSN, err)
+	}
+}
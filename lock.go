@@ -2,93 +2,125 @@ package gitdb
 
 import (
 	"errors"
-	"io/ioutil"
 	"os"
-	"path/filepath"
 	"strings"
-
-	"github.com/bouggo/log"
+	"time"
 )
 
+const lockRetryInterval = time.Millisecond * 50
+const maxLockRetryInterval = time.Second
+
 func (g *gitdb) Lock(mo Model) error {
+	return g.lock(mo, nil)
+}
+
+func (g *gitdb) lock(mo Model, user *User) error {
+	if err := g.writable(); err != nil {
+		return err
+	}
 
 	m := wrap(mo)
 	if !m.IsLockable() {
 		return errors.New("Model is not lockable")
 	}
 
-	var lockFilesWritten []string
+	names := lockNames(m)
+	owner := g.lockOwner(user)
 
-	fullPath := g.lockDir(m)
-	if _, err := os.Stat(fullPath); err != nil {
-		err := os.MkdirAll(fullPath, 0755)
-		if err != nil {
-			return err
-		}
+	//checking and taking the locks is one step for the goroutines of this connection
+	g.lockMu.Lock()
+	err := g.lockBackend(user, ID(m)).Acquire(names, owner, g.config.LockTTL)
+	g.lockMu.Unlock()
+	g.watchLock(names, owner, ID(m), err)
+	if err != nil {
+		return err
 	}
 
-	lockFiles := m.GetLockFileNames()
-	for _, file := range lockFiles {
-		lockFile := filepath.Join(fullPath, file+".lock")
-		g.events <- newWriteBeforeEvent("...", lockFile)
+	if g.config.RenewLocks && g.config.LockTTL > 0 {
+		g.renewLocks(ID(m), names, user)
+	}
+	return nil
+}
 
-		//when locking a model, lockfile should not exist
-		if _, err := os.Stat(lockFile); err == nil {
-			if derr := g.deleteLockFiles(lockFilesWritten); derr != nil {
-				log.Error(derr.Error())
-			}
-			return errors.New("Lock file already exist: " + lockFile)
+//TryLock takes the locks of m and returns true, or returns false straight away if another owner holds any of them
+func (g *gitdb) TryLock(m Model) (bool, error) {
+	return g.tryLock(m, nil)
+}
+
+func (g *gitdb) tryLock(m Model, user *User) (bool, error) {
+	err := g.lock(m, user)
+	var locked *ErrLocked
+	if errors.As(err, &locked) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+//LockWait takes the locks of m, waiting up to timeout for other owners to release them. It returns
+//*ErrLocked if one is still held when timeout has passed. A negative timeout waits forever
+func (g *gitdb) LockWait(m Model, timeout time.Duration) error {
+	return g.lockWait(m, timeout, nil)
+}
+
+func (g *gitdb) lockWait(m Model, timeout time.Duration, user *User) error {
+	deadline := time.Now().Add(timeout)
+	wait := lockRetryInterval
+	for {
+		err := g.lock(m, user)
+		var locked *ErrLocked
+		if !errors.As(err, &locked) {
+			return err
 		}
 
-		err := ioutil.WriteFile(lockFile, []byte(""), 0644)
-		if err != nil {
-			if derr := g.deleteLockFiles(lockFilesWritten); derr != nil {
-				log.Error(derr.Error())
+		sleep := wait
+		if timeout >= 0 {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return err
+			}
+			if remaining < sleep {
+				sleep = remaining
 			}
-			return errors.New("Failed to write lock " + lockFile + ": " + err.Error())
 		}
 
-		lockFilesWritten = append(lockFilesWritten, lockFile)
-	}
-
-	g.commit.Add(1)
-	commitMsg := "Created Lock Files for: " + ID(m)
-	g.events <- newWriteEvent(commitMsg, fullPath, g.autoCommit)
+		select {
+		case <-g.shutdown:
+			return errConnectionClosed
+		case <-time.After(sleep):
+		}
 
-	//block here until write has been committed
-	g.waitForCommit()
-	return nil
+		//back off so waiting on SharedLocks doesn't sync with the remote too often
+		if wait *= 2; wait > maxLockRetryInterval {
+			wait = maxLockRetryInterval
+		}
+	}
 }
 
 func (g *gitdb) Unlock(mo Model) error {
+	return g.unlock(mo, nil)
+}
+
+func (g *gitdb) unlock(mo Model, user *User) error {
+	if err := g.writable(); err != nil {
+		return err
+	}
 
 	m := wrap(mo)
 	if !m.IsLockable() {
 		return errors.New("Model is not lockable")
 	}
 
-	fullPath := g.lockDir(m)
-
-	lockFiles := m.GetLockFileNames()
-	for _, file := range lockFiles {
-		lockFile := filepath.Join(fullPath, file+".lock")
+	g.stopRenewingLocks(ID(m), user)
 
-		if _, err := os.Stat(lockFile); err == nil {
-			//log.PutInfo("Removing " + lockFile)
-			err := os.Remove(lockFile)
-			if err != nil {
-				return errors.New("Could not delete lock file: " + lockFile)
-			}
-		}
+	names := lockNames(m)
+	owner := g.lockOwner(user)
+	g.lockMu.Lock()
+	err := g.lockBackend(user, ID(m)).Release(names, owner)
+	g.lockMu.Unlock()
+	if err == nil && g.config.LockStallTimeout > 0 {
+		g.lockWatch.released(names, owner)
 	}
-
-	g.commit.Add(1)
-	commitMsg := "Removing Lock Files for: " + ID(m)
-	g.events <- newWriteEvent(commitMsg, fullPath, g.autoCommit)
-
-	//block here until write has been committed
-	g.waitForCommit()
-	return nil
+	return err
 }
 
 func (g *gitdb) deleteLockFiles(files []string) error {
@@ -0,0 +1,500 @@
+package gitdb
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bouggo/log"
+)
+
+//LockBackend holds the locks taken with Lock and Unlock. Locks are named after the dataset of the
+//Model and each of its GetLockFileNames e.g "Booking/room-12". Set Config.LockBackend to coordinate
+//writers on several nodes through an external store such as etcd, Consul or Redis
+type LockBackend interface {
+	//Acquire takes every lock in names for owner until ttl has passed, or indefinitely if ttl is zero.
+	//It returns *ErrLocked, and takes none of them, if any is held, by owner included, so taking a lock
+	//twice fails like taking a lock held by someone else.
+	//names are sorted so backends that take locks one at a time take them in the same order
+	Acquire(names []string, owner string, ttl time.Duration) error
+	//Renew extends the locks in names held by owner until ttl has passed. It returns *ErrLocked if owner
+	//no longer holds one of them
+	Renew(names []string, owner string, ttl time.Duration) error
+	//Release frees the locks in names held by owner. Locks that never expire can also be freed by another
+	//connection of the same user on the same host, as the one that took them is gone after a restart
+	Release(names []string, owner string) error
+}
+
+//ErrLocked is returned by Lock and Unlock when a lock is held by another owner
+type ErrLocked struct {
+	Name  string
+	Owner string
+}
+
+func (e *ErrLocked) Error() string {
+	owner := e.Owner
+	if len(owner) == 0 {
+		owner = "another owner"
+	}
+	return fmt.Sprintf("%s is locked by %s", e.Name, owner)
+}
+
+//lockHolder is the owner of a lock and when it expires. A zero Expires never expires
+type lockHolder struct {
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires"`
+}
+
+func (h lockHolder) expired() bool {
+	return !h.Expires.IsZero() && h.Expires.Before(time.Now())
+}
+
+//heldByOther reports whether the lock is held by someone other than owner
+func (h lockHolder) heldByOther(owner string) bool {
+	return h.Owner != owner && !h.expired()
+}
+
+//releasableBy reports whether owner can release the lock. A lock that never expires can be released by
+//the same user on the same host through another connection, e.g after a crash or restart
+func (h lockHolder) releasableBy(owner string) bool {
+	if !h.heldByOther(owner) {
+		return true
+	}
+	return h.Expires.IsZero() && lockUserHost(h.Owner) == lockUserHost(owner)
+}
+
+//held reports whether the lock is held by anyone
+func (h lockHolder) held() bool {
+	return !h.expired()
+}
+
+func lockExpiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+//lockBackend returns the LockBackend locks taken by user on record id are held in
+func (g *gitdb) lockBackend(user *User, id string) LockBackend {
+	switch {
+	case g.config.LockBackend != nil:
+		return g.config.LockBackend
+	case g.config.SharedLocks:
+		return &datasetLocks{db: g, user: user}
+	}
+	return &fileLocks{db: g, user: user, id: id}
+}
+
+//renewLocks renews the locks in names taken by user on record id every half of Config.LockTTL
+//until stopRenewingLocks is called or the connection is closed
+func (g *gitdb) renewLocks(id string, names []string, user *User) {
+	owner := g.lockOwner(user)
+	key := owner + "\x00" + id
+
+	g.leaseMu.Lock()
+	defer g.leaseMu.Unlock()
+	if _, ok := g.leases[key]; ok {
+		return
+	}
+	if g.leases == nil {
+		g.leases = map[string]*lease{}
+	}
+	l := &lease{stop: make(chan bool), done: make(chan bool)}
+	g.leases[key] = l
+
+	go func() {
+		defer close(l.done)
+		ticker := time.NewTicker(g.config.LockTTL / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := g.lockBackend(user, id).Renew(names, owner, g.config.LockTTL); err != nil {
+					log.Error(fmt.Sprintf("Failed to renew locks of %s: %s", id, err))
+				}
+			case <-l.stop:
+				return
+			case <-g.shutdown:
+				return
+			}
+		}
+	}()
+}
+
+//stopRenewingLocks stops renewing the locks taken by user on record id
+func (g *gitdb) stopRenewingLocks(id string, user *User) {
+	key := g.lockOwner(user) + "\x00" + id
+
+	g.leaseMu.Lock()
+	defer g.leaseMu.Unlock()
+	if l, ok := g.leases[key]; ok {
+		close(l.stop)
+		<-l.done
+		delete(g.leases, key)
+	}
+}
+
+//lease is the goroutine renewing a set of locks
+type lease struct {
+	stop chan bool
+	//done is closed once the goroutine has stopped
+	done chan bool
+}
+
+//lockOwner identifies user of this connection as the owner of locks. The token of the connection tells
+//apart processes, and connections, of the same user on the same host
+func (g *gitdb) lockOwner(user *User) string {
+	if user == nil {
+		user = g.config.User
+	}
+	host, _ := os.Hostname()
+	return user.String() + " on " + host + " #" + g.lockToken
+}
+
+//lockUserHost returns the user and host of owner without the token of its connection
+func lockUserHost(owner string) string {
+	if i := strings.LastIndex(owner, " #"); i >= 0 {
+		return owner[:i]
+	}
+	return owner
+}
+
+//newLockToken returns a random token telling apart the locks of each connection
+func newLockToken() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+//lockNames returns the names of the locks of m in order. Locks are always taken in the same order
+//so two models sharing locks can't each hold one the other wants
+func lockNames(m Model) []string {
+	dataset := m.GetSchema().name()
+	seen := map[string]bool{}
+	var names []string
+	for _, file := range m.GetLockFileNames() {
+		name := dataset + "/" + file
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+//fileLocks holds locks in files committed to the Lock directory of each dataset. They only
+//coordinate writers sharing the clone of the database until the files are pushed
+type fileLocks struct {
+	db   *gitdb
+	user *User
+	//id is the record the locks are taken on
+	id string
+}
+
+func (f *fileLocks) path(name string) string {
+	parts := strings.SplitN(name, "/", 2)
+	return filepath.Join(f.db.dbDir(), parts[0], "Lock", parts[len(parts)-1]+".lock")
+}
+
+//holder returns the holder of the lock in lockFile. Files written before locks had owners have no
+//owner and, when Config.LockTTL is set, expire LockTTL after they were written
+func (f *fileLocks) holder(lockFile string) (lockHolder, bool) {
+	data, err := ioutil.ReadFile(lockFile)
+	if err != nil {
+		return lockHolder{}, false
+	}
+
+	var holder lockHolder
+	json.Unmarshal(data, &holder)
+	if len(holder.Owner) == 0 && f.db.config.LockTTL > 0 {
+		if info, err := os.Stat(lockFile); err == nil {
+			holder.Expires = info.ModTime().Add(f.db.config.LockTTL)
+		}
+	}
+	return holder, true
+}
+
+//Acquire implements LockBackend
+func (f *fileLocks) Acquire(names []string, owner string, ttl time.Duration) error {
+	var lockFilesWritten []string
+	dirs := map[string]bool{}
+	data, _ := json.Marshal(lockHolder{Owner: owner, Expires: lockExpiry(ttl)})
+	for _, name := range names {
+		lockFile := f.path(name)
+		if err := os.MkdirAll(filepath.Dir(lockFile), 0755); err != nil {
+			return err
+		}
+		f.db.events <- newWriteBeforeEvent("...", lockFile)
+
+		holder, err := f.claim(lockFile, data)
+		if err != nil {
+			if derr := f.db.deleteLockFiles(lockFilesWritten); derr != nil {
+				log.Error(derr.Error())
+			}
+			if holder != nil {
+				return &ErrLocked{Name: name, Owner: holder.Owner}
+			}
+			return fmt.Errorf("Failed to write lock %s: %s", lockFile, err)
+		}
+
+		lockFilesWritten = append(lockFilesWritten, lockFile)
+		dirs[filepath.Dir(lockFile)] = true
+	}
+
+	f.commit(dirs, "Created Lock Files for: "+f.id, "lock")
+	return nil
+}
+
+//claim writes data to lockFile if the lock is free, creating it with O_EXCL so two writers can't both
+//find it free. An expired lock is moved aside first so only one of the writers that found it expired
+//replaces it. It returns the holder of the lock, with an error, if the lock is held
+func (f *fileLocks) claim(lockFile string, data []byte) (*lockHolder, error) {
+	for {
+		file, err := os.OpenFile(lockFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = file.Write(data)
+			if cerr := file.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(lockFile)
+			}
+			return nil, err
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		holder, ok := f.holder(lockFile)
+		if !ok {
+			//released in the meantime
+			continue
+		}
+		if holder.held() {
+			return &holder, os.ErrExist
+		}
+
+		aside := lockFile + "." + f.db.lockToken + ".expired"
+		if err := os.Rename(lockFile, aside); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		//another writer may have replaced the expired lock before it was moved aside
+		if moved, ok := f.holder(aside); ok && moved.held() {
+			os.Link(aside, lockFile)
+			os.Remove(aside)
+			return &moved, os.ErrExist
+		}
+		if err := os.Remove(aside); err != nil {
+			return nil, err
+		}
+	}
+}
+
+//Release implements LockBackend
+func (f *fileLocks) Release(names []string, owner string) error {
+	dirs := map[string]bool{}
+	for _, name := range names {
+		lockFile := f.path(name)
+		holder, ok := f.holder(lockFile)
+		if !ok {
+			continue
+		}
+		if len(holder.Owner) > 0 && !holder.releasableBy(owner) {
+			return &ErrLocked{Name: name, Owner: holder.Owner}
+		}
+
+		if err := os.Remove(lockFile); err != nil {
+			return fmt.Errorf("Could not delete lock file: %s", lockFile)
+		}
+		dirs[filepath.Dir(lockFile)] = true
+	}
+
+	f.commit(dirs, "Removing Lock Files for: "+f.id, "unlock")
+	return nil
+}
+
+//Renew implements LockBackend. The lock files are committed with the next write
+func (f *fileLocks) Renew(names []string, owner string, ttl time.Duration) error {
+	for _, name := range names {
+		lockFile := f.path(name)
+		holder, ok := f.holder(lockFile)
+		if !ok || holder.Owner != owner {
+			return &ErrLocked{Name: name, Owner: holder.Owner}
+		}
+
+		data, _ := json.Marshal(lockHolder{Owner: owner, Expires: lockExpiry(ttl)})
+		if err := ioutil.WriteFile(lockFile, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fileLocks) commit(dirs map[string]bool, commitMsg string, op string) {
+	f.db.commitMu.Lock()
+	defer f.db.commitMu.Unlock()
+	for dir := range dirs {
+		f.db.commit.Add(1)
+		f.db.events <- newWriteEvent(commitMsg, dir, f.db.autoCommit, f.user, op, f.id)
+
+		//block here until write has been committed
+		f.db.waitForCommit()
+	}
+}
+
+const locksDataset = "_locks"
+
+//lockRecord is a lock held in the _locks dataset
+type lockRecord struct {
+	Name string
+	lockHolder
+	TimeStampedModel
+}
+
+func (l *lockRecord) GetSchema() *Schema {
+	return newSchema(locksDataset, "b0", url.QueryEscape(l.Name), map[string]interface{}{"Owner": l.Owner})
+}
+
+func (l *lockRecord) Validate() error            { return nil }
+func (l *lockRecord) IsLockable() bool           { return false }
+func (l *lockRecord) GetLockFileNames() []string { return nil }
+func (l *lockRecord) ShouldEncrypt() bool        { return false }
+
+//datasetLocks holds locks in the _locks dataset and syncs with the online remote before and after
+//every change so writers on nodes sharing the remote see each other's locks
+type datasetLocks struct {
+	db   *gitdb
+	user *User
+}
+
+//holder returns the holder of lock name
+func (d *datasetLocks) holder(name string) (lockHolder, bool) {
+	l := &lockRecord{Name: name}
+	if err := d.db.Get(ID(l), l); err != nil {
+		return lockHolder{}, false
+	}
+	return l.lockHolder, true
+}
+
+func (d *datasetLocks) sync() error {
+	if len(d.db.config.OnlineRemote) == 0 {
+		return nil
+	}
+	head, err := d.db.gitDriver.head()
+	if err != nil {
+		return err
+	}
+
+	err = d.db.sync()
+	//a database without commits has nothing to push, which fails while the online remote is empty too
+	if len(head) == 0 {
+		return nil
+	}
+	return err
+}
+
+//Acquire implements LockBackend
+func (d *datasetLocks) Acquire(names []string, owner string, ttl time.Duration) error {
+	if err := d.sync(); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if holder, ok := d.holder(name); ok && holder.held() {
+			return &ErrLocked{Name: name, Owner: holder.Owner}
+		}
+	}
+
+	var models []Model
+	for _, name := range names {
+		models = append(models, &lockRecord{Name: name, lockHolder: lockHolder{Owner: owner, Expires: lockExpiry(ttl)}})
+	}
+	if err := d.db.insertMany(models, d.user); err != nil {
+		return err
+	}
+
+	if err := d.sync(); err != nil {
+		return err
+	}
+
+	//another node may have taken a lock at the same time and won the merge
+	for _, name := range names {
+		if holder, ok := d.holder(name); ok && holder.heldByOther(owner) {
+			d.releaseOwn(names, owner)
+			return &ErrLocked{Name: name, Owner: holder.Owner}
+		}
+	}
+	return nil
+}
+
+//Renew implements LockBackend
+func (d *datasetLocks) Renew(names []string, owner string, ttl time.Duration) error {
+	if err := d.sync(); err != nil {
+		return err
+	}
+
+	var models []Model
+	for _, name := range names {
+		holder, ok := d.holder(name)
+		if !ok || holder.Owner != owner {
+			return &ErrLocked{Name: name, Owner: holder.Owner}
+		}
+		models = append(models, &lockRecord{Name: name, lockHolder: lockHolder{Owner: owner, Expires: lockExpiry(ttl)}})
+	}
+	if err := d.db.insertMany(models, d.user); err != nil {
+		return err
+	}
+	return d.sync()
+}
+
+//releaseOwn frees the locks in names still held by owner after losing one of them to another node
+func (d *datasetLocks) releaseOwn(names []string, owner string) {
+	for _, name := range names {
+		if holder, ok := d.holder(name); ok && holder.Owner == owner {
+			if err := d.db.dodelete(ID(&lockRecord{Name: name}), false, d.user); err != nil {
+				log.Error(err.Error())
+			}
+		}
+	}
+	if err := d.sync(); err != nil {
+		log.Error(err.Error())
+	}
+}
+
+//Release implements LockBackend
+func (d *datasetLocks) Release(names []string, owner string) error {
+	if err := d.sync(); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		holder, ok := d.holder(name)
+		if !ok {
+			continue
+		}
+		if !holder.releasableBy(owner) {
+			return &ErrLocked{Name: name, Owner: holder.Owner}
+		}
+		if err := d.db.dodelete(ID(&lockRecord{Name: name}), false, d.user); err != nil {
+			return err
+		}
+	}
+
+	return d.sync()
+}
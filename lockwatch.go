@@ -0,0 +1,242 @@
+package gitdb
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bouggo/log"
+)
+
+//HeldLock is a lock taken with Lock through this connection
+type HeldLock struct {
+	Name  string
+	Owner string
+	//Record is the id of the model the lock was taken for
+	Record string
+	//Stack is the stack of the goroutine that took the lock
+	Stack string
+	Since time.Time
+}
+
+//LockWait is an owner whose last Lock failed because Name is held by another owner
+type LockWait struct {
+	//Waiter is the owner that wants Name and Owner the owner holding it
+	Waiter string
+	Name   string
+	Owner  string
+	Since  time.Time
+}
+
+//LockDiagnostics is a snapshot of the locks held and waited for through this connection
+type LockDiagnostics struct {
+	Held    []HeldLock
+	Waiting []LockWait
+	//Deadlocks are owners that each wait for a lock held by the next, the last waiting for the first.
+	//None of them can take the lock it wants until one of them gives up the locks it holds
+	Deadlocks [][]string
+}
+
+//lockWatch keeps track of the locks taken and waited for through a connection while Config.LockStallTimeout is set
+type lockWatch struct {
+	mu   sync.Mutex
+	held map[string]*HeldLock
+	//waiting holds the last failed Lock of each owner until it takes or releases a lock
+	waiting  map[string]*LockWait
+	reported map[string]bool
+	started  bool
+}
+
+//acquired records the locks in names taken by owner for record id from a goroutine at stack
+func (w *lockWatch) acquired(names []string, owner string, id string, stack string) {
+	now := time.Now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.held == nil {
+		w.held = map[string]*HeldLock{}
+	}
+	for _, name := range names {
+		if held, ok := w.held[name]; ok && held.Owner == owner {
+			continue
+		}
+		w.held[name] = &HeldLock{Name: name, Owner: owner, Record: id, Stack: stack, Since: now}
+	}
+	delete(w.waiting, owner)
+}
+
+//refused records that waiter could not take the lock name held by owner
+func (w *lockWatch) refused(waiter string, name string, owner string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.waiting == nil {
+		w.waiting = map[string]*LockWait{}
+	}
+	if wait, ok := w.waiting[waiter]; ok && wait.Name == name {
+		return
+	}
+	w.waiting[waiter] = &LockWait{Waiter: waiter, Name: name, Owner: owner, Since: time.Now()}
+}
+
+//released forgets the locks in names given up by owner and the Lock it was waiting on
+func (w *lockWatch) released(names []string, owner string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, name := range names {
+		if held, ok := w.held[name]; ok && held.Owner == owner {
+			delete(w.held, name)
+			delete(w.reported, name)
+		}
+	}
+	delete(w.waiting, owner)
+}
+
+func (w *lockWatch) diagnose() LockDiagnostics {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var d LockDiagnostics
+	for _, held := range w.held {
+		d.Held = append(d.Held, *held)
+	}
+	sort.Slice(d.Held, func(i, j int) bool { return d.Held[i].Name < d.Held[j].Name })
+
+	//each waiting owner waits for the one owner holding the lock it wants
+	waitsFor := map[string]string{}
+	for _, wait := range w.waiting {
+		d.Waiting = append(d.Waiting, *wait)
+		if held, ok := w.held[wait.Name]; ok && held.Owner != wait.Waiter {
+			waitsFor[wait.Waiter] = held.Owner
+		}
+	}
+	sort.Slice(d.Waiting, func(i, j int) bool { return d.Waiting[i].Waiter < d.Waiting[j].Waiter })
+
+	d.Deadlocks = waitCycles(waitsFor)
+	return d
+}
+
+//waitCycles returns the cycles in waitsFor, each starting with its lowest owner
+func waitCycles(waitsFor map[string]string) [][]string {
+	var cycles [][]string
+	seen := map[string]bool{}
+	for start := range waitsFor {
+		path := map[string]int{}
+		var order []string
+		owner := start
+		for {
+			if seen[owner] {
+				break
+			}
+			if i, ok := path[owner]; ok {
+				cycles = append(cycles, rotateLowest(order[i:]))
+				break
+			}
+			path[owner] = len(order)
+			order = append(order, owner)
+
+			next, ok := waitsFor[owner]
+			if !ok {
+				break
+			}
+			owner = next
+		}
+		for _, owner := range order {
+			seen[owner] = true
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
+
+func rotateLowest(cycle []string) []string {
+	lowest := 0
+	for i := range cycle {
+		if cycle[i] < cycle[lowest] {
+			lowest = i
+		}
+	}
+	return append(append([]string{}, cycle[lowest:]...), cycle[:lowest]...)
+}
+
+//DiagnoseLocks returns the locks held and waited for through this connection, and the owners deadlocked on
+//them. Locks are only kept track of while Config.LockStallTimeout is set
+func (g *gitdb) DiagnoseLocks() LockDiagnostics {
+	return g.lockWatch.diagnose()
+}
+
+//watchLock records the outcome of a call to Lock by owner and starts the watchdog of Config.LockStallTimeout
+//on first use. Nothing is recorded while the watchdog is off
+func (g *gitdb) watchLock(names []string, owner string, id string, err error) {
+	if g.config.LockStallTimeout <= 0 {
+		return
+	}
+
+	var locked *ErrLocked
+	switch {
+	case err == nil:
+		g.lockWatch.acquired(names, owner, id, string(debug.Stack()))
+	case errors.As(err, &locked):
+		g.lockWatch.refused(owner, locked.Name, locked.Owner)
+	default:
+		return
+	}
+
+	g.lockWatch.mu.Lock()
+	defer g.lockWatch.mu.Unlock()
+	if !g.lockWatch.started {
+		g.lockWatch.started = true
+		go g.lockWatchdog()
+	}
+}
+
+//lockWatchdog logs locks held for longer than Config.LockStallTimeout and deadlocked owners
+func (g *gitdb) lockWatchdog() {
+	ticker := time.NewTicker(g.config.LockStallTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.shutdown:
+			return
+		case <-ticker.C:
+			g.reportStalls()
+		}
+	}
+}
+
+func (g *gitdb) reportStalls() {
+	d := g.DiagnoseLocks()
+
+	g.lockWatch.mu.Lock()
+	defer g.lockWatch.mu.Unlock()
+	if g.lockWatch.reported == nil {
+		g.lockWatch.reported = map[string]bool{}
+	}
+
+	for _, held := range d.Held {
+		if time.Since(held.Since) < g.config.LockStallTimeout || g.lockWatch.reported[held.Name] {
+			continue
+		}
+		g.lockWatch.reported[held.Name] = true
+		log.Warn(fmt.Sprintf("Lock %s has been held by %s for %s:\n%s",
+			held.Name, held.Owner, time.Since(held.Since).Round(time.Second), held.Stack))
+	}
+
+	for _, cycle := range d.Deadlocks {
+		key := fmt.Sprint(cycle)
+		if g.lockWatch.reported[key] {
+			continue
+		}
+		g.lockWatch.reported[key] = true
+
+		var waits []string
+		for i, owner := range cycle {
+			waits = append(waits, fmt.Sprintf("%s waits for %s", owner, cycle[(i+1)%len(cycle)]))
+		}
+		log.Warn("Lock deadlock: " + strings.Join(waits, ", "))
+	}
+}
@@ -0,0 +1,122 @@
+package gitdb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/bouggo/log"
+)
+
+//maintenanceIdleTime is how long the database must go without commits before scheduled maintenance runs
+const maintenanceIdleTime = time.Minute
+
+//MaintenanceReport describes the effect of running repository maintenance
+type MaintenanceReport struct {
+	SizeBefore int64
+	SizeAfter  int64
+	Duration   time.Duration
+}
+
+//Reclaimed returns the number of bytes freed by maintenance
+func (r *MaintenanceReport) Reclaimed() int64 {
+	return r.SizeBefore - r.SizeAfter
+}
+
+//RepoObjects describes the objects of the database repository as git count-objects reports them
+type RepoObjects struct {
+	Loose     int
+	LooseSize int64
+	Packs     int
+	PackSize  int64
+	//Garbage is the number of files in the object directory that aren't objects or packs
+	Garbage     int
+	GarbageSize int64
+}
+
+//Reclaimable estimates the most bytes Maintain frees by packing the loose objects and removing garbage
+func (o *RepoObjects) Reclaimable() int64 {
+	return o.LooseSize + o.GarbageSize
+}
+
+//RepoObjects counts the objects of the database repository so the space Maintain would free can be
+//estimated before running it
+func (g *gitdb) RepoObjects() (*RepoObjects, error) {
+	return g.gitDriver.countObjects()
+}
+
+//Maintain garbage collects, repacks and prunes the database repository.
+//Writes are blocked while maintenance runs
+func (g *gitdb) Maintain() (*MaintenanceReport, error) {
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	gitDir := filepath.Join(g.dbDir(), ".git")
+	start := time.Now()
+	report := &MaintenanceReport{SizeBefore: dirSize(gitDir)}
+
+	if err := g.gitDriver.gc(); err != nil {
+		return nil, err
+	}
+
+	report.SizeAfter = dirSize(gitDir)
+	report.Duration = time.Since(start)
+
+	log.Info(fmt.Sprintf("Maintenance completed in %s: repository size %d -> %d bytes", report.Duration, report.SizeBefore, report.SizeAfter))
+	return report, nil
+}
+
+func (g *gitdb) markActive() {
+	atomic.StoreInt64(&g.lastActive, time.Now().UnixNano())
+}
+
+func (g *gitdb) idleFor() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&g.lastActive)))
+}
+
+func (g *gitdb) startMaintenanceClock() {
+	if g.config.MaintenanceEvery <= 0 {
+		return
+	}
+
+	go func(g *gitdb) {
+		log.Test(fmt.Sprintf("starting maintenance clock @ interval %s", g.config.MaintenanceEvery))
+		timer := time.NewTimer(g.config.MaintenanceEvery)
+		defer timer.Stop()
+		for {
+			select {
+			case <-g.shutdown:
+				log.Test("shutting down maintenance clock")
+				return
+			case <-timer.C:
+				//wait for the database to go quiet before running maintenance
+				if idle := g.idleFor(); idle < maintenanceIdleTime {
+					timer.Reset(maintenanceIdleTime - idle)
+					continue
+				}
+
+				if _, err := g.Maintain(); err != nil {
+					log.Error("Maintenance failed: " + err.Error())
+				}
+				timer.Reset(g.config.MaintenanceEvery)
+			}
+		}
+	}(g)
+}
+
+//dirSize returns the total size in bytes of files in dir
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,34 @@
+package gitdb_test
+
+import (
+	"testing"
+)
+
+func TestMaintain(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	for i := 0; i < 10; i++ {
+		if err := insert(getTestMessage(), false); err != nil {
+			t.Fatalf("insert failed: %s", err)
+		}
+	}
+
+	report, err := testDb.Maintain()
+	if err != nil {
+		t.Fatalf("testDb.Maintain failed: %s", err)
+	}
+
+	if report.SizeBefore <= 0 || report.SizeAfter <= 0 {
+		t.Errorf("want repository sizes, got: %d -> %d", report.SizeBefore, report.SizeAfter)
+	}
+
+	//loose objects should have been packed
+	if report.Reclaimed() <= 0 {
+		t.Errorf("want reclaimed space, got: %d", report.Reclaimed())
+	}
+
+	if err := insert(getTestMessage(), false); err != nil {
+		t.Errorf("insert after maintenance failed: %s", err)
+	}
+}
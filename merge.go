@@ -0,0 +1,186 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bouggo/log"
+	"github.com/gogitdb/gitdb/v2/internal/crypto"
+)
+
+//conflictsDataset holds records that lost a merge so they are never silently dropped
+const conflictsDataset = "_conflicts"
+
+//mergeKeyEnv is used to pass the encryption key to the merge driver
+//so it can read timestamps of encrypted records
+const mergeKeyEnv = "GITDB_ENCRYPTION_KEY"
+
+//MergeBlocks performs a three-way merge of a block file at the record level.
+//base, ours and theirs are the paths git passes to a merge driver (%O %A %B)
+//and blockPath is the path of the block in the repository (%P). The result is
+//written to ours. When both sides changed the same record, the record with the
+//most recent UpdatedAt wins and the loser is saved in the _conflicts dataset
+func MergeBlocks(blockPath, base, ours, theirs, key string) error {
+	baseRecords, err := readBlockFile(base)
+	if err != nil {
+		return err
+	}
+
+	ourRecords, err := readBlockFile(ours)
+	if err != nil {
+		return err
+	}
+
+	theirRecords, err := readBlockFile(theirs)
+	if err != nil {
+		return err
+	}
+
+	merged, losers := mergeRecords(baseRecords, ourRecords, theirRecords, key)
+	if err := writeBlockFile(ours, merged); err != nil {
+		return err
+	}
+
+	if len(losers) > 0 {
+		return recordConflicts(blockPath, losers)
+	}
+
+	return nil
+}
+
+func mergeRecords(base, ours, theirs map[string]string, key string) (map[string]string, map[string]string) {
+	merged := map[string]string{}
+	losers := map[string]string{}
+
+	ids := map[string]bool{}
+	for _, side := range []map[string]string{base, ours, theirs} {
+		for id := range side {
+			ids[id] = true
+		}
+	}
+
+	for id := range ids {
+		b, inBase := base[id]
+		o, inOurs := ours[id]
+		t, inTheirs := theirs[id]
+
+		switch {
+		case inOurs == inTheirs && o == t:
+			//both sides agree
+		case inOurs == inBase && o == b:
+			//only theirs changed
+			o, inOurs = t, inTheirs
+		case inTheirs == inBase && t == b:
+			//only ours changed
+		case !inOurs:
+			//deleted by us and modified by them; keep the modification
+			o, inOurs = t, true
+		case !inTheirs:
+			//deleted by them and modified by us; keep the modification
+		default:
+			winner, loser := o, t
+			if recordWins(t, o, key) {
+				winner, loser = t, o
+			}
+			o = winner
+			losers[id] = loser
+			log.Info("merge conflict on " + id + " resolved using UpdatedAt")
+		}
+
+		if inOurs {
+			merged[id] = o
+		}
+	}
+
+	return merged, losers
+}
+
+//recordWins reports whether record a should win a merge against record b
+func recordWins(a, b, key string) bool {
+	ta, tb := recordUpdatedAt(a, key), recordUpdatedAt(b, key)
+	if ta.Equal(tb) {
+		//fallback to a deterministic choice so every node resolves the same way
+		return a > b
+	}
+	return ta.After(tb)
+}
+
+func recordUpdatedAt(data, key string) time.Time {
+	if len(key) > 0 {
+		if dec, err := crypto.DecryptJSON(key, data); err == nil {
+			data = dec
+		}
+	}
+
+	var rec struct {
+		UpdatedAt time.Time
+		Data      struct {
+			UpdatedAt time.Time
+		}
+	}
+
+	json.Unmarshal([]byte(data), &rec)
+	if !rec.Data.UpdatedAt.IsZero() {
+		return rec.Data.UpdatedAt
+	}
+	return rec.UpdatedAt
+}
+
+func recordConflicts(blockPath string, losers map[string]string) error {
+	dataset := filepath.Base(filepath.Dir(blockPath))
+	block := strings.TrimSuffix(filepath.Base(blockPath), ".json")
+
+	conflictsFile := filepath.Join(filepath.Dir(filepath.Dir(blockPath)), conflictsDataset, dataset+".json")
+	if err := os.MkdirAll(filepath.Dir(conflictsFile), 0755); err != nil {
+		return err
+	}
+
+	conflicts := map[string]string{}
+	if _, err := os.Stat(conflictsFile); err == nil {
+		if conflicts, err = readBlockFile(conflictsFile); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now().Unix()
+	for id, data := range losers {
+		_, _, record, _ := ParseID(id)
+		conflictID := fmt.Sprintf("%s/%s/%s.%s.%d", conflictsDataset, dataset, block, record, now)
+		conflicts[conflictID] = data
+	}
+
+	return writeBlockFile(conflictsFile, conflicts)
+}
+
+func readBlockFile(path string) (map[string]string, error) {
+	records := map[string]string{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return records, err
+	}
+
+	//an empty file is valid i.e the block did not exist on one side
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return records, nil
+	}
+
+	if err := json.Unmarshal(data, &records); err != nil {
+		return records, errBadBlock
+	}
+
+	return records, nil
+}
+
+func writeBlockFile(path string, records map[string]string) error {
+	b, err := json.MarshalIndent(records, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0744)
+}
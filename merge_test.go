@@ -0,0 +1,117 @@
+package gitdb_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func writeTestBlock(t *testing.T, path string, records map[string]string) {
+	b, _ := json.Marshal(records)
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatalf("write block failed: %s", err)
+	}
+}
+
+func readTestBlock(t *testing.T, path string) map[string]string {
+	records := map[string]string{}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read block failed: %s", err)
+	}
+	if err := json.Unmarshal(b, &records); err != nil {
+		t.Fatalf("read block failed: %s", err)
+	}
+	return records
+}
+
+func TestMergeBlocks(t *testing.T) {
+	dir := filepath.Join(testData, "merge")
+	if err := os.MkdirAll(filepath.Join(dir, "Message"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testData)
+
+	older := `{"Version":"v2","Data":{"UpdatedAt":"2020-01-01T00:00:00Z","Body":"theirs"}}`
+	newer := `{"Version":"v2","Data":{"UpdatedAt":"2020-02-01T00:00:00Z","Body":"ours"}}`
+	orig := `{"Version":"v2","Data":{"UpdatedAt":"2019-01-01T00:00:00Z","Body":"base"}}`
+
+	base, ours, theirs := filepath.Join(dir, "base"), filepath.Join(dir, "ours"), filepath.Join(dir, "theirs")
+	writeTestBlock(t, base, map[string]string{"Message/b0/1": orig, "Message/b0/2": orig})
+	writeTestBlock(t, ours, map[string]string{"Message/b0/1": newer, "Message/b0/2": orig})
+	writeTestBlock(t, theirs, map[string]string{"Message/b0/1": older, "Message/b0/3": orig})
+
+	blockPath := filepath.Join(dir, "Message", "b0.json")
+	if err := gitdb.MergeBlocks(blockPath, base, ours, theirs, ""); err != nil {
+		t.Fatalf("gitdb.MergeBlocks failed: %s", err)
+	}
+
+	merged := readTestBlock(t, ours)
+	if merged["Message/b0/1"] != newer {
+		t.Errorf("Message/b0/1 should resolve to the most recently updated record")
+	}
+
+	if _, ok := merged["Message/b0/2"]; ok {
+		t.Errorf("Message/b0/2 was deleted by theirs and should not be in merged block")
+	}
+
+	if _, ok := merged["Message/b0/3"]; !ok {
+		t.Errorf("Message/b0/3 was added by theirs and should be in merged block")
+	}
+
+	conflicts := readTestBlock(t, filepath.Join(dir, "_conflicts", "Message.json"))
+	if len(conflicts) != 1 {
+		t.Errorf("want 1 conflict, got %d", len(conflicts))
+	}
+
+	for _, data := range conflicts {
+		if data != older {
+			t.Errorf("losing record should be saved in conflicts dataset")
+		}
+	}
+}
+
+func TestMergeDriverAttributes(t *testing.T) {
+	//the merge driver is registered when the gitdb binary is on the path
+	bin, err := ioutil.TempDir("", "bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(bin)
+	ioutil.WriteFile(filepath.Join(bin, "gitdb"), []byte("#!/bin/sh\n"), 0755)
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", bin+string(os.PathListSeparator)+path)
+	defer os.Setenv("PATH", path)
+
+	cfg := getConfig()
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	//entries of the user survive reopening the database, which registers the driver again
+	attributesFile := filepath.Join(dbPath, "data", ".git", "info", "attributes")
+	data, _ := ioutil.ReadFile(attributesFile)
+	ioutil.WriteFile(attributesFile, append([]byte("*.csv binary\n"), data...), 0644)
+	testDb.Close()
+	testDb = getDbConn(t, cfg)
+	if data, _ = ioutil.ReadFile(attributesFile); !strings.HasPrefix(string(data), "*.csv binary\n") || strings.Count(string(data), "merge=gitdb") != 1 {
+		t.Errorf("want: entries of the user kept and the driver registered once, got: %s", data)
+	}
+
+	want := map[string]string{
+		"data.csv":            "unset",
+		"Message/b0.json":     "gitdb",
+		"Message/.meta.json":  "text",
+		"schemas.json":        "unspecified",
+		"Message/old/b0.json": "unspecified",
+	}
+	for file, driver := range want {
+		if got := gitOutput(t, "check-attr", "merge", "--", file); !strings.HasSuffix(got, ": merge: "+driver) {
+			t.Errorf("want: %s merged with %s, got: %s", file, driver, got)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/bouggo/log"
+)
+
+//schemaMeta holds the parts of schemas GitDB needs when it only has a dataset name
+//e.g when deleting by id. It is recorded by inserts and from Config.Factory and registered models
+type schemaMeta struct {
+	Refs       map[string]ref       `json:"refs"`
+	Collations map[string]Collation `json:"collations"`
+	//Views holds the Query each view materializes
+	Views map[string]*Query `json:"views"`
+	//Blind holds the blind indexes of each dataset
+	Blind map[string]bool `json:"blind"`
+	//Redacted holds the redacted fields of each dataset
+	Redacted map[string]bool `json:"redacted"`
+	//Migrated holds the version of Config.Migrations every record of each dataset has been migrated to
+	Migrated map[string]int `json:"migrated"`
+}
+
+//add merges the declarations of schema and reports whether anything changed
+func (s *schemaMeta) add(schema *Schema) bool {
+	changed := false
+	for _, r := range schema.refs {
+		if existing, ok := s.Refs[r.key()]; !ok || existing != r {
+			s.Refs[r.key()] = r
+			changed = true
+		}
+	}
+
+	for index, c := range schema.collations {
+		key := schema.name() + "." + index
+		if existing, ok := s.Collations[key]; !ok || existing != c {
+			s.Collations[key] = c
+			changed = true
+		}
+	}
+
+	for index := range schema.blind {
+		key := schema.name() + "." + index
+		if !s.Blind[key] {
+			s.Blind[key] = true
+			changed = true
+		}
+	}
+
+	for _, field := range schema.redacted {
+		key := schema.name() + "." + field
+		if !s.Redacted[key] {
+			s.Redacted[key] = true
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+//meta returns the schema declarations persisted by inserts and declared by Config.Factory and registered models
+func (g *gitdb) meta() *schemaMeta {
+	if g.schemaMeta != nil {
+		return g.schemaMeta
+	}
+
+	g.schemaMeta = &schemaMeta{Refs: map[string]ref{}, Collations: map[string]Collation{}, Views: map[string]*Query{}, Blind: map[string]bool{}, Redacted: map[string]bool{}, Migrated: map[string]int{}}
+	if data, err := ioutil.ReadFile(g.metaFile()); err == nil {
+		if err := json.Unmarshal(data, g.schemaMeta); err != nil {
+			log.Error(err.Error())
+		}
+	}
+
+	if g.config.hasFactory() {
+		datasets, _ := g.datasetNames()
+		for _, dataset := range datasets {
+			if m := g.config.factory(dataset); m != nil {
+				g.schemaMeta.add(m.GetSchema())
+			}
+		}
+	}
+
+	return g.schemaMeta
+}
+
+//registerSchema persists the declarations of schema that are needed without a model
+func (g *gitdb) registerSchema(schema *Schema) {
+	if g.meta().add(schema) {
+		g.saveMeta()
+	}
+}
+
+//saveMeta writes the schema declarations to disk
+func (g *gitdb) saveMeta() {
+	data, err := json.Marshal(g.schemaMeta)
+	if err == nil {
+		err = ioutil.WriteFile(g.metaFile(), data, 0744)
+	}
+	if err != nil {
+		log.Error("Failed to save schemas: " + err.Error())
+	}
+}
+
+func (g *gitdb) metaFile() string {
+	return filepath.Join(g.absDbPath(), g.internalDirName(), "schemas.json")
+}
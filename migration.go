@@ -0,0 +1,286 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bouggo/log"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//Migration changes the shape of the records of a dataset to Version. Up is given the Data of a record
+//as it was at the previous version and returns it as it is at Version. Fields encrypted with
+//Schema.EncryptFields are passed as they are stored
+type Migration struct {
+	Version int
+	Up      func(old map[string]interface{}) (map[string]interface{}, error)
+}
+
+//validateMigrations checks the migrations of each dataset are in order of Version
+func validateMigrations(migrations map[string][]Migration) error {
+	for dataset, list := range migrations {
+		last := 0
+		for _, m := range list {
+			if m.Up == nil {
+				return fmt.Errorf("Migration %d of %s has no Up", m.Version, dataset)
+			}
+			if m.Version <= last {
+				return fmt.Errorf("Migrations of %s must have versions above 0 in ascending order", dataset)
+			}
+			last = m.Version
+		}
+	}
+	return nil
+}
+
+//schemaVersion returns the version records of dataset are written at i.e the version of its last migration
+func (g *gitdb) schemaVersion(dataset string) int {
+	migrations := g.config.Migrations[dataset]
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+//migrateData applies the migrations of dataset that data, the JSON of a record, is behind on.
+//It reports whether data was changed
+func (g *gitdb) migrateData(dataset string, data string) (string, bool, error) {
+	latest := g.schemaVersion(dataset)
+	if latest == 0 {
+		return data, false, nil
+	}
+
+	var rec map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &rec); err != nil || string(rec["Version"]) != `"`+RecVersion+`"` {
+		//records that can't be decrypted or predate the record wrapper are left as they are
+		return data, false, nil
+	}
+
+	version := 0
+	if v, ok := rec["SchemaVersion"]; ok {
+		if err := json.Unmarshal(v, &version); err != nil {
+			return "", false, err
+		}
+	}
+	if version >= latest {
+		return data, false, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(rec["Data"], &fields); err != nil {
+		return "", false, err
+	}
+
+	for _, m := range g.config.Migrations[dataset] {
+		if m.Version <= version {
+			continue
+		}
+
+		var err error
+		if fields, err = m.Up(fields); err != nil {
+			return "", false, fmt.Errorf("Migration %d of %s failed: %w", m.Version, dataset, err)
+		}
+	}
+
+	var err error
+	if rec["Data"], err = json.Marshal(fields); err != nil {
+		return "", false, err
+	}
+	rec["SchemaVersion"], _ = json.Marshal(latest)
+
+	migrated, err := json.Marshal(rec)
+	if err != nil {
+		return "", false, err
+	}
+	return string(migrated), true, nil
+}
+
+//migrateRecords brings records read from the database up to the version of their dataset
+func (g *gitdb) migrateRecords(records ...*db.Record) error {
+	for _, record := range records {
+		dataset, _, _, err := ParseID(record.ID())
+		if err != nil || g.schemaVersion(dataset) == 0 {
+			continue
+		}
+
+		err = record.Migrate(func(data string) (string, error) {
+			migrated, _, err := g.migrateData(dataset, data)
+			return migrated, err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//migrateBlock rewrites the records of dataBlock that are behind the version of dataset
+//and returns their ids
+func (g *gitdb) migrateBlock(dataset string, dataBlock *db.Block) ([]string, error) {
+	if g.schemaVersion(dataset) == 0 || g.meta().Migrated[dataset] >= g.schemaVersion(dataset) {
+		return nil, nil
+	}
+
+	var key string
+	if g.config.Cipher == nil {
+		key, _ = g.encryptionKey(dataset)
+	}
+
+	var ids []string
+	for _, record := range dataBlock.Records() {
+		var migrated string
+		changed := false
+		err := record.Migrate(func(data string) (string, error) {
+			var err error
+			migrated, changed, err = g.migrateData(dataset, data)
+			return migrated, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			continue
+		}
+
+		//records stay encrypted if they were
+		stored := migrated
+		if !json.Valid([]byte(record.Data())) {
+			if stored, err = g.encrypt(dataset, key, migrated); err != nil {
+				return nil, err
+			}
+		}
+		dataBlock.Add(record.ID(), stored)
+		ids = append(ids, record.ID())
+	}
+	return ids, nil
+}
+
+//MigrationProgress is how far RunMigrations has got with a dataset
+type MigrationProgress struct {
+	Dataset string
+	//Version is the version the records of the dataset are migrated to
+	Version int
+	Blocks  int
+	//Done is the number of blocks of the dataset migrated so far
+	Done int
+	//Records is the number of records of the dataset migrated so far
+	Records int
+}
+
+type migrateOptions struct {
+	dryRun   bool
+	progress func(*MigrationProgress)
+}
+
+//MigrationOption customises a call to GitDb.RunMigrations
+type MigrationOption func(*migrateOptions)
+
+//MigrateDryRun runs the migrations on every record that is behind without writing or committing
+//them, e.g to check they succeed and count the records they change before running them
+func MigrateDryRun() MigrationOption {
+	return func(o *migrateOptions) {
+		o.dryRun = true
+	}
+}
+
+//MigrateProgress calls progress after each block of a dataset is migrated
+func MigrateProgress(progress func(*MigrationProgress)) MigrationOption {
+	return func(o *migrateOptions) {
+		o.progress = progress
+	}
+}
+
+//RunMigrations rewrites every record of the datasets in Config.Migrations that is behind the version of
+//its last migration and commits them. Without it records are migrated as they are read and written to
+//disk when their block is next written
+func (g *gitdb) RunMigrations(opts ...MigrationOption) error {
+	o := &migrateOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if !o.dryRun {
+		if err := g.writable(); err != nil {
+			return err
+		}
+	}
+
+	g.commitMu.Lock()
+	defer g.commitMu.Unlock()
+
+	datasets := make([]string, 0, len(g.config.Migrations))
+	for dataset := range g.config.Migrations {
+		datasets = append(datasets, dataset)
+	}
+	sort.Strings(datasets)
+
+	var ids []string
+	var migrated []string
+	for _, dataset := range datasets {
+		version := g.schemaVersion(dataset)
+		if version == 0 || g.meta().Migrated[dataset] >= version {
+			continue
+		}
+
+		blockFiles, err := g.blockFiles(dataset)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		progress := &MigrationProgress{Dataset: dataset, Version: version, Blocks: len(blockFiles)}
+		for _, blockFile := range blockFiles {
+			var dataBlock *db.Block
+			if o.dryRun {
+				//a copy of the block so the block cached for reads isn't changed
+				dataBlock = g.readBlock(blockFile)
+			} else if dataBlock, err = g.loadBlock(blockFile); err != nil {
+				return err
+			}
+
+			changed, err := g.migrateBlock(dataset, dataBlock)
+			if err != nil {
+				return err
+			}
+			progress.Done++
+			progress.Records += len(changed)
+			if len(changed) > 0 && !o.dryRun {
+				if err := g.writeBlock(blockFile, dataBlock); err != nil {
+					return err
+				}
+				g.updateIndexes(dataBlock)
+				for _, id := range changed {
+					g.reads.forget(id)
+				}
+				ids = append(ids, changed...)
+			}
+			if o.progress != nil {
+				o.progress(progress)
+			}
+		}
+		migrated = append(migrated, fmt.Sprintf("%s to version %d", dataset, version))
+		if !o.dryRun {
+			g.meta().Migrated[dataset] = version
+		}
+	}
+
+	if o.dryRun {
+		return nil
+	}
+	if err := g.flushIndex(); err != nil {
+		return err
+	}
+
+	if len(ids) > 0 {
+		g.commit.Add(1)
+		g.events <- newWriteEvent("Migrating "+strings.Join(migrated, ", "), ".", true, nil, "migrate", ids...)
+		g.waitForCommit()
+	}
+
+	if len(migrated) > 0 {
+		g.saveMeta()
+		log.Info(fmt.Sprintf("Migrated %d records of %s", len(ids), strings.Join(migrated, ", ")))
+	}
+	return nil
+}
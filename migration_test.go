@@ -0,0 +1,111 @@
+package gitdb_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestMigrations(t *testing.T) {
+	cfg := getConfig()
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	m := getTestMessage()
+	if err := insert(m, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	testDb.Close()
+	cfg.Migrations = map[string][]gitdb.Migration{
+		"Message": {
+			{Version: 1, Up: func(old map[string]interface{}) (map[string]interface{}, error) {
+				old["Body"] = strings.ToUpper(old["Body"].(string))
+				return old, nil
+			}},
+		},
+	}
+	testDb = getDbConn(t, cfg)
+
+	//records are migrated as they are read
+	result := &Message{}
+	if err := testDb.Get(gitdb.ID(m), result); err != nil || result.Body != "HELLO" {
+		t.Errorf("want: HELLO, got: %s (%v)", result.Body, err)
+	}
+
+	if err := testDb.RunMigrations(); err != nil {
+		t.Fatalf("testDb.RunMigrations failed: %s", err)
+	}
+	if got := gitOutput(t, "log", "-1", "--format=%s"); !strings.Contains(got, "Migrating Message to version 1") {
+		t.Errorf("want: migration commit, got: %s", got)
+	}
+
+	//records already migrated are not migrated again
+	commits := gitOutput(t, "rev-list", "--count", "HEAD")
+	if err := testDb.RunMigrations(); err != nil {
+		t.Fatalf("testDb.RunMigrations failed: %s", err)
+	}
+	if got := gitOutput(t, "rev-list", "--count", "HEAD"); got != commits {
+		t.Errorf("want: %s commits, got: %s", commits, got)
+	}
+
+	result = &Message{}
+	if err := testDb.Get(gitdb.ID(m), result); err != nil || result.Body != "HELLO" {
+		t.Errorf("want: HELLO, got: %s (%v)", result.Body, err)
+	}
+}
+
+func TestMigrationsValidate(t *testing.T) {
+	cfg := getConfig()
+	up := func(old map[string]interface{}) (map[string]interface{}, error) { return old, nil }
+	cfg.Migrations = map[string][]gitdb.Migration{"Message": {{Version: 2, Up: up}, {Version: 1, Up: up}}}
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("cfg.Validate should fail if migrations are out of order")
+	}
+}
+
+func TestMigrationsDryRun(t *testing.T) {
+	cfg := getConfig()
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	insert(getTestMessageWithId(1), false)
+	insert(getTestMessageWithId(2), true)
+
+	testDb.Close()
+	cfg.Migrations = map[string][]gitdb.Migration{
+		"Message": {
+			{Version: 1, Up: func(old map[string]interface{}) (map[string]interface{}, error) {
+				old["Body"] = strings.ToUpper(old["Body"].(string))
+				return old, nil
+			}},
+		},
+	}
+	testDb = getDbConn(t, cfg)
+
+	var progress []gitdb.MigrationProgress
+	track := gitdb.MigrateProgress(func(p *gitdb.MigrationProgress) { progress = append(progress, *p) })
+	commits := gitOutput(t, "rev-list", "--count", "HEAD")
+	if err := testDb.RunMigrations(gitdb.MigrateDryRun(), track); err != nil {
+		t.Fatalf("testDb.RunMigrations failed: %s", err)
+	}
+	if len(progress) != 1 || progress[0].Dataset != "Message" || progress[0].Done != 1 || progress[0].Records != 2 {
+		t.Errorf("want: 2 records of 1 block of Message, got: %+v", progress)
+	}
+	if got := gitOutput(t, "rev-list", "--count", "HEAD"); got != commits {
+		t.Errorf("want: nothing committed by a dry run, got: %s commits", got)
+	}
+
+	//records are still migrated by a run after a dry run
+	progress = nil
+	if err := testDb.RunMigrations(track); err != nil {
+		t.Fatalf("testDb.RunMigrations failed: %s", err)
+	}
+	if len(progress) != 1 || progress[0].Records != 2 {
+		t.Errorf("want: 2 records migrated, got: %+v", progress)
+	}
+	if got := gitOutput(t, "log", "-1", "--format=%s"); !strings.Contains(got, "Migrating Message to version 1") {
+		t.Errorf("want: migration commit, got: %s", got)
+	}
+}
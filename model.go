@@ -38,19 +38,45 @@ func (m *TimeStampedModel) BeforeInsert() error {
 
 type model struct {
 	Version string
+	//Revision is incremented on every write of the record
+	Revision int
+	//SchemaVersion is the version of the last of Config.Migrations of the dataset when the record was written
+	SchemaVersion int `json:",omitempty"`
+	//Type is the name of the concrete model in Config.Types for records of polymorphic datasets
+	Type    string `json:",omitempty"`
 	Indexes map[string]interface{}
 	Data    Model
+
+	//expected is the revision the record must be at for it to be written
+	expected int
+	//dirty writes the record only if its fields differ from the stored record, see Update
+	dirty bool
+	//revert is the commit the record is restored to, see RevertRecord
+	revert string
+	//audit is set on the entries of the audit log, the only records written to the _audit dataset
+	audit bool
+	//batched records are written without being committed, writeBatch commits them together, see InsertAsync
+	batched bool
+	//schema is the schema of Data, kept once the model is prepared for writing so it isn't built on every use
+	schema *Schema
 }
 
+//anyRevision writes a record whatever its revision
+const anyRevision = -1
+
 func wrap(m Model) *model {
 	return &model{
-		Version: RecVersion,
-		Data:    m,
+		Version:  RecVersion,
+		Data:     m,
+		expected: anyRevision,
 	}
 }
 
 func (m *model) GetSchema() *Schema {
-	return m.Data.GetSchema()
+	if m.schema != nil {
+		return m.schema
+	}
+	return schemaOf(m.Data)
 }
 
 func (m *model) Validate() error {
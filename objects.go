@@ -0,0 +1,152 @@
+package gitdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bouggo/log"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//relPath returns the path of file relative to the database repository
+func (g *gitdb) relPath(file string) string {
+	rel, err := filepath.Rel(g.dbDir(), file)
+	if err != nil {
+		return file
+	}
+	return filepath.ToSlash(rel)
+}
+
+//readBlockFile returns the content of a block file from the working tree or,
+//with Config.ObjectReads, straight from git objects at HEAD
+func (g *gitdb) readBlockFile(blockFile string) ([]byte, error) {
+	if g.config.ObjectReads {
+		return g.gitDriver.show("HEAD", g.relPath(blockFile))
+	}
+
+	if g.config.readOnly {
+		defer g.lockWorktree(true)()
+	}
+	defer g.blockLocks.rlock(blockFile)()
+	return ioutil.ReadFile(blockFile)
+}
+
+func (g *gitdb) blockFileExists(blockFile string) bool {
+	if g.config.ObjectReads {
+		files, err := g.gitDriver.lsTree("HEAD", g.relPath(blockFile), false)
+		return err == nil && len(files) > 0
+	}
+
+	_, err := os.Stat(blockFile)
+	return err == nil
+}
+
+//readBlock loads a block file for reading
+func (g *gitdb) readBlock(blockFile string) *db.Block {
+	keyring := g.keyring(filepath.Base(filepath.Dir(blockFile)))
+	if !g.config.ObjectReads {
+		if g.config.readOnly {
+			defer g.lockWorktree(true)()
+		}
+		defer g.blockLocks.rlock(blockFile)()
+		return db.LoadBlock(blockFile, keyring)
+	}
+
+	data, err := g.readBlockFile(blockFile)
+	if err != nil {
+		log.Error(err.Error())
+	}
+	return db.ParseBlock(blockFile, keyring, data)
+}
+
+//hydrate reads all records in blockFile into block
+func (g *gitdb) hydrate(block *db.EmptyBlock, blockFile string) error {
+	data, err := g.readBlockFile(blockFile)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, block)
+}
+
+//hydrateByPositions reads the records at positions in blockFile into block
+func (g *gitdb) hydrateByPositions(block *db.EmptyBlock, blockFile string, positions ...[]int) error {
+	if !g.config.ObjectReads {
+		if g.config.readOnly {
+			defer g.lockWorktree(true)()
+		}
+		defer g.blockLocks.rlock(blockFile)()
+		return block.HydrateByPositions(blockFile, positions...)
+	}
+
+	data, err := g.readBlockFile(blockFile)
+	if err != nil {
+		return err
+	}
+	return block.HydrateByPositionsFrom(bytes.NewReader(data), positions...)
+}
+
+//blockFiles returns the paths of all block files in dataset
+func (g *gitdb) blockFiles(dataset string) ([]string, error) {
+	var files []string
+	if g.config.ObjectReads {
+		names, err := g.gitDriver.lsTree("HEAD", dataset+"/", false)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range names {
+			if isBlockFile(name) {
+				files = append(files, filepath.Join(g.dbDir(), filepath.FromSlash(name)))
+			}
+		}
+		return files, nil
+	}
+
+	datasetPath := g.datasetPath(dataset)
+	infos, err := ioutil.ReadDir(datasetPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, info := range infos {
+		if !info.IsDir() && isBlockFile(info.Name()) {
+			files = append(files, filepath.Join(datasetPath, info.Name()))
+		}
+	}
+	return files, nil
+}
+
+//datasetNames returns the names of all datasets in the database
+func (g *gitdb) datasetNames() ([]string, error) {
+	var names []string
+	if g.config.ObjectReads {
+		dirs, err := g.gitDriver.lsTree("HEAD", "", true)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dir := range dirs {
+			if !strings.HasPrefix(dir, ".") {
+				names = append(names, dir)
+			}
+		}
+		return names, nil
+	}
+
+	infos, err := ioutil.ReadDir(g.dbDir())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, info := range infos {
+		if info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
+			names = append(names, info.Name())
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,247 @@
+package gitdb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+const (
+	orderNumber = iota
+	orderTime
+	orderString
+)
+
+//orderedValue is an index value in a form that can be ordered. Numbers sort before
+//times which sort before strings. Strings in RFC3339 format are treated as times
+type orderedValue struct {
+	kind int
+	num  float64
+	t    time.Time
+	str  string
+}
+
+func toOrdered(v interface{}) orderedValue {
+	switch n := v.(type) {
+	case float64:
+		return orderedValue{kind: orderNumber, num: n}
+	case float32:
+		return orderedValue{kind: orderNumber, num: float64(n)}
+	case int:
+		return orderedValue{kind: orderNumber, num: float64(n)}
+	case int32:
+		return orderedValue{kind: orderNumber, num: float64(n)}
+	case int64:
+		return orderedValue{kind: orderNumber, num: float64(n)}
+	case uint:
+		return orderedValue{kind: orderNumber, num: float64(n)}
+	case uint32:
+		return orderedValue{kind: orderNumber, num: float64(n)}
+	case uint64:
+		return orderedValue{kind: orderNumber, num: float64(n)}
+	case time.Time:
+		return orderedValue{kind: orderTime, t: n}
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, n); err == nil {
+			return orderedValue{kind: orderTime, t: t}
+		}
+		return orderedValue{kind: orderString, str: n}
+	}
+	return orderedValue{kind: orderString, str: fmt.Sprint(v)}
+}
+
+func (a orderedValue) compare(b orderedValue) int {
+	if a.kind != b.kind {
+		if a.kind < b.kind {
+			return -1
+		}
+		return 1
+	}
+
+	switch a.kind {
+	case orderNumber:
+		if a.num < b.num {
+			return -1
+		} else if a.num > b.num {
+			return 1
+		}
+	case orderTime:
+		if a.t.Before(b.t) {
+			return -1
+		} else if a.t.After(b.t) {
+			return 1
+		}
+	default:
+		return strings.Compare(a.str, b.str)
+	}
+	return 0
+}
+
+//Condition filters the values of an index in SearchWhere
+type Condition struct {
+	lower, upper         *orderedValue
+	lowerOpen, upperOpen bool
+}
+
+//Eq matches index values equal to v
+func Eq(v interface{}) Condition {
+	o := toOrdered(v)
+	return Condition{lower: &o, upper: &o}
+}
+
+//Gt matches index values greater than v
+func Gt(v interface{}) Condition {
+	o := toOrdered(v)
+	return Condition{lower: &o, lowerOpen: true}
+}
+
+//Gte matches index values greater than or equal to v
+func Gte(v interface{}) Condition {
+	o := toOrdered(v)
+	return Condition{lower: &o}
+}
+
+//Lt matches index values less than v
+func Lt(v interface{}) Condition {
+	o := toOrdered(v)
+	return Condition{upper: &o, upperOpen: true}
+}
+
+//Lte matches index values less than or equal to v
+func Lte(v interface{}) Condition {
+	o := toOrdered(v)
+	return Condition{upper: &o}
+}
+
+//Between matches index values from from to to inclusive
+func Between(from interface{}, to interface{}) Condition {
+	f, t := toOrdered(from), toOrdered(to)
+	return Condition{lower: &f, upper: &t}
+}
+
+//matches reports whether v satisfies every condition
+func matches(v orderedValue, conds []Condition) bool {
+	for _, c := range conds {
+		if c.lower != nil {
+			if cmp := v.compare(*c.lower); cmp < 0 || (cmp == 0 && c.lowerOpen) {
+				return false
+			}
+		}
+		if c.upper != nil {
+			if cmp := v.compare(*c.upper); cmp > 0 || (cmp == 0 && c.upperOpen) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+type orderedEntry struct {
+	id    string
+	value orderedValue
+	iv    gdbIndexValue
+}
+
+//orderedIndex returns the entries of an index sorted by value. The sorted view is
+//cached until the index changes so range searches don't scan the whole index
+func (g *gitdb) orderedIndex(dataset string, name string) []orderedEntry {
+	indexFile := g.indexFile(dataset, name)
+	if entries, ok := g.ordered[indexFile]; ok {
+		return entries
+	}
+
+	index := g.index(dataset, name)
+	entries := make([]orderedEntry, 0, len(index))
+	for id, iv := range index {
+		entries = append(entries, orderedEntry{id: id, value: toOrdered(iv.Value), iv: iv})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if cmp := entries[i].value.compare(entries[j].value); cmp != 0 {
+			return cmp < 0
+		}
+		return entries[i].id < entries[j].id
+	})
+
+	if g.ordered == nil {
+		g.ordered = map[string][]orderedEntry{}
+	}
+	g.ordered[indexFile] = entries
+	return entries
+}
+
+//SearchWhere returns records in dataset whose index value satisfies all conds, ordered by
+//index value e.g db.SearchWhere("Bookings", "Amount", gitdb.Gte(5000)). Numbers, times and
+//strings in RFC3339 format are compared by value, other strings lexically
+func (g *gitdb) SearchWhere(dataset string, index string, conds ...Condition) ([]*db.Record, error) {
+	g.events <- newReadEvent("...", g.indexFile(dataset, index))
+
+	//the sorted entries are replaced, not changed, by writes so they are read outside the cache
+	var entries []orderedEntry
+	g.readCache(func() error {
+		conds = g.collateConds(dataset, index, conds)
+		entries = g.orderedIndex(dataset, index)
+		return nil
+	})
+
+	//binary search for the first entry above every lower bound
+	start := sort.Search(len(entries), func(i int) bool {
+		for _, c := range conds {
+			if c.lower != nil {
+				if cmp := entries[i].value.compare(*c.lower); cmp < 0 || (cmp == 0 && c.lowerOpen) {
+					return false
+				}
+			}
+		}
+		return true
+	})
+
+	searchBlocks := map[string][][]int{}
+	var ids []string
+	for _, entry := range entries[start:] {
+		if !matches(entry.value, conds) {
+			//entries are sorted so once past an upper bound nothing else matches
+			if upperBound(entry.value, conds) {
+				break
+			}
+			continue
+		}
+
+		_, block, _, err := ParseID(entry.id)
+		if err != nil {
+			return nil, err
+		}
+		searchBlocks[block] = append(searchBlocks[block], []int{entry.iv.Offset, entry.iv.Len})
+		ids = append(ids, entry.id)
+	}
+
+	records, err := g.hydrateSearchBlocks(dataset, searchBlocks)
+	if err != nil {
+		return nil, err
+	}
+
+	order := map[string]int{}
+	for i, id := range ids {
+		order[id] = i
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return order[records[i].ID()] < order[records[j].ID()]
+	})
+
+	return records, nil
+}
+
+//upperBound reports whether v is past the upper bound of any condition
+func upperBound(v orderedValue, conds []Condition) bool {
+	for _, c := range conds {
+		if c.upper != nil {
+			if cmp := v.compare(*c.upper); cmp > 0 || (cmp == 0 && c.upperOpen) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,7 +1,9 @@
 package gitdb
 
 import (
+	"path"
 	"path/filepath"
+	"strings"
 )
 
 func (g *gitdb) absDbPath() string {
@@ -18,15 +20,22 @@ func (g *gitdb) dbDir() string {
 }
 
 func (g *gitdb) fullPath(m Model) string {
-	return filepath.Join(g.dbDir(), m.GetSchema().name())
+	return g.datasetPath(m.GetSchema().name())
+}
+
+func (g *gitdb) datasetPath(dataset string) string {
+	return filepath.Join(g.dbDir(), dataset)
 }
 
 func (g *gitdb) blockFilePath(dataset, block string) string {
 	return filepath.Join(g.dbDir(), dataset, block+".json")
 }
 
-func (g *gitdb) lockDir(m Model) string {
-	return filepath.Join(g.fullPath(m), "Lock")
+//isBlockFile reports whether file, a path with / or OS separators, is a block of records. Files of
+//a dataset starting with . e.g .meta.json are not
+func isBlockFile(file string) bool {
+	name := path.Base(filepath.ToSlash(file))
+	return path.Ext(name) == ".json" && !strings.HasPrefix(name, ".")
 }
 
 //index path
@@ -38,6 +47,22 @@ func (g *gitdb) indexPath(dataset string) string {
 	return filepath.Join(g.indexDir(), dataset)
 }
 
+//full-text index path
+func (g *gitdb) ftsDir() string {
+	return filepath.Join(g.absDbPath(), g.internalDirName(), "fts")
+}
+
+//bloom filter path
+func (g *gitdb) bloomDir() string {
+	return filepath.Join(g.absDbPath(), g.internalDirName(), "bloom")
+}
+
+//temporary files are written here before being renamed into place, see writeFileAtomic. It is
+//outside the repository so git never sees them
+func (g *gitdb) tmpDir() string {
+	return filepath.Join(g.absDbPath(), g.internalDirName(), "tmp")
+}
+
 //ssh paths
 func (g *gitdb) sshDir() string {
 	return filepath.Join(g.absDbPath(), g.internalDirName(), "ssh")
@@ -0,0 +1,33 @@
+package gitdb_test
+
+import (
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestGetRecordsNeedingEscapes(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	//json.MarshalIndent escapes these when writing the block so the positions of later records move
+	bodies := []string{`<b>"quoted" & 'single'</b>`, "line\nbreak\ttab \\ backslash", "unicode é ✓"}
+	var ids []string
+	for i, body := range bodies {
+		m := &MessageV2{MessageId: i + 1, From: "alice@example.com", Body: body}
+		if err := testDb.Insert(m); err != nil {
+			t.Fatalf("testDb.Insert failed: %s", err)
+		}
+		ids = append(ids, gitdb.ID(m))
+	}
+
+	for i, id := range ids {
+		m := &MessageV2{}
+		if err := testDb.Get(id, m); err != nil {
+			t.Fatalf("testDb.Get(%s) failed: %s", id, err)
+		}
+		if m.Body != bodies[i] {
+			t.Errorf("want: %q, got: %q", bodies[i], m.Body)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package gitdb_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+//Tests in this file read and write from many goroutines at once.
+//Run them with the race detector: make race
+
+func TestConcurrentFetch(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	n := 20
+	body := strings.Repeat("Hello ", 2000)
+	for i := 0; i < n/2; i++ {
+		m := getTestMessageWithId(i)
+		m.Body = body
+		if err := insert(m, false); err != nil {
+			t.Fatalf("insert failed: %s", err)
+		}
+	}
+
+	done := make(chan bool)
+	var readers sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				//every fetch sees the block before or after a write, never part way through one
+				records, err := testDb.Fetch("Message")
+				if err != nil {
+					t.Errorf("testDb.Fetch failed: %s", err)
+					return
+				}
+				if len(records) < n/2 || len(records) > n {
+					t.Errorf("want: %d to %d records, got: %d", n/2, n, len(records))
+					return
+				}
+			}
+		}()
+	}
+
+	for i := n / 2; i < n; i++ {
+		m := getTestMessageWithId(i)
+		m.Body = body
+		if err := testDb.Insert(m); err != nil {
+			t.Errorf("testDb.Insert failed: %s", err)
+		}
+	}
+	close(done)
+	readers.Wait()
+
+	if records, err := testDb.Fetch("Message"); err != nil || len(records) != n {
+		t.Errorf("want: %d records, got: %d (%v)", n, len(records), err)
+	}
+}
+
+func TestConcurrentFetchDuringDelete(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	n := 10
+	body := strings.Repeat("Hello ", 2000)
+	var ids []string
+	for i := 0; i < n; i++ {
+		m := getTestMessageWithId(i)
+		m.Body = body
+		if err := insert(m, false); err != nil {
+			t.Fatalf("insert failed: %s", err)
+		}
+		ids = append(ids, gitdb.ID(m))
+	}
+
+	done := make(chan bool)
+	var readers sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				if _, err := testDb.Fetch("Message"); err != nil {
+					t.Errorf("testDb.Fetch failed: %s", err)
+					return
+				}
+			}
+		}()
+	}
+
+	for _, id := range ids {
+		if err := testDb.Delete(id); err != nil {
+			t.Errorf("testDb.Delete failed: %s", err)
+		}
+	}
+	close(done)
+	readers.Wait()
+}
+
+func TestConcurrentGet(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	m := getTestMessage()
+	if err := insert(m, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	//goroutines getting the same record at once share a read of its block
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := &Message{}
+			if err := testDb.Get(gitdb.ID(m), result); err != nil || result.Body != m.Body {
+				t.Errorf("want: %s, got: %s (%v)", m.Body, result.Body, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	//a record is read again once it has been written
+	update := getTestMessageWithId(m.MessageId)
+	update.Body = "updated"
+	if err := testDb.Insert(update); err != nil {
+		t.Errorf("testDb.Insert failed: %s", err)
+	}
+	result := &Message{}
+	if err := testDb.Get(gitdb.ID(m), result); err != nil || result.Body != update.Body {
+		t.Errorf("want: %s, got: %s (%v)", update.Body, result.Body, err)
+	}
+}
+
+func TestConcurrentSearch(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	n := 20
+	if err := insert(getTestMessageWithId(0), false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	done := make(chan bool)
+	var readers sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				//searches read the indexes the writes change
+				records, err := testDb.Search("Message", []*gitdb.SearchParam{{Index: "From", Value: "alice@example.com"}}, gitdb.SearchEquals)
+				if err != nil || len(records) == 0 || len(records) > n {
+					t.Errorf("want: 1 to %d records, got: %d (%v)", n, len(records), err)
+					return
+				}
+				if err := testDb.Get("Message/b0/0", &Message{}); err != nil {
+					t.Errorf("testDb.Get failed: %s", err)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 1; i < n; i++ {
+		if err := testDb.Insert(getTestMessageWithId(i)); err != nil {
+			t.Errorf("testDb.Insert failed: %s", err)
+		}
+	}
+	close(done)
+	readers.Wait()
+}
@@ -2,15 +2,22 @@ package gitdb
 
 import (
 	"fmt"
-	"io/ioutil"
-	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/bouggo/log"
 	"github.com/gogitdb/gitdb/v2/internal/db"
 )
 
+//readCache runs fn, which reads the cached blocks, indexes or schemas, with writers kept out of them. Writers
+//hold cacheMu as they change the cache so only readers call it, and fn must not wait on a writer
+func (g *gitdb) readCache(fn func() error) error {
+	g.cacheMu.Lock()
+	defer g.cacheMu.Unlock()
+	return fn()
+}
+
 func (g *gitdb) loadBlock(blockFile string) (*db.Block, error) {
 
 	if g.loadedBlocks == nil {
@@ -19,49 +26,73 @@ func (g *gitdb) loadBlock(blockFile string) (*db.Block, error) {
 
 	//if block file is not cached, load into cache
 	if _, ok := g.loadedBlocks[blockFile]; !ok {
-		g.loadedBlocks[blockFile] = db.LoadBlock(blockFile, g.config.EncryptionKey)
+		g.loadedBlocks[blockFile] = g.readBlock(blockFile)
 	}
 
 	return g.loadedBlocks[blockFile], nil
 }
 
+//errNotFound is returned by reads of records that don't exist
+type errNotFound struct {
+	id      string
+	dataset string
+}
+
+func (e *errNotFound) Error() string {
+	return fmt.Sprintf("Record %s not found in %s", e.id, e.dataset)
+}
+
+//doget reads record id, sharing the read with goroutines reading id at the same time
 func (g *gitdb) doget(id string) (*db.Record, error) {
+	return g.reads.do(id, func() (*db.Record, error) {
+		record, err := g.readRecord(id)
+		if err == nil {
+			err = g.migrateRecords(record)
+		}
+		return record, err
+	})
+}
 
+func (g *gitdb) readRecord(id string) (*db.Record, error) {
 	dataset, block, _, err := ParseID(id)
 	if err != nil {
 		return nil, err
 	}
 
 	blockFilePath := filepath.Join(g.dbDir(), dataset, block+".json")
-	if _, err := os.Stat(blockFilePath); err != nil {
-		return nil, fmt.Errorf("Record %s not found in %s", id, dataset)
+	var found bool
+	g.readCache(func() error {
+		found = g.mightContain(id)
+		return nil
+	})
+	if !found || !g.blockFileExists(blockFilePath) {
+		return nil, &errNotFound{id: id, dataset: dataset}
 	}
 
 	//read id index
-	indexFile := filepath.Join(g.indexDir(), dataset, "id.json")
-	if _, ok := g.indexCache[indexFile]; !ok {
-		g.buildIndexTargeted(dataset)
+	var iv gdbIndexValue
+	g.readCache(func() error {
+		iv, found = g.index(dataset, "id")[id]
+		return nil
+	})
+	if !found {
+		return nil, &errNotFound{id: id, dataset: dataset}
 	}
 
-	iv, ok := g.indexCache[indexFile][id]
-	if ok {
-		dataBlock := db.NewEmptyBlock(g.config.EncryptionKey)
-		err = dataBlock.HydrateByPositions(blockFilePath, []int{iv.Offset, iv.Len})
-		if err != nil {
-			log.Error(err.Error())
-			return nil, fmt.Errorf("Record %s not found in %s", id, dataset)
-		}
-
-		record, err := dataBlock.Get(id)
-		if err != nil {
-			log.Error(err.Error())
-			return nil, fmt.Errorf("Record %s not found in %s", id, dataset)
-		}
+	dataBlock := db.NewEmptyBlock(g.keyring(dataset))
+	err = g.hydrateByPositions(dataBlock, blockFilePath, []int{iv.Offset, iv.Len})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", id, err)
+	}
 
-		return record, nil
+	//the id index is behind the block
+	record, err := dataBlock.Get(id)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, &errNotFound{id: id, dataset: dataset}
 	}
 
-	return nil, fmt.Errorf("Record %s not found in %s", id, dataset)
+	return record, nil
 }
 
 //Get hydrates a model with specified id into result Model
@@ -87,34 +118,32 @@ func (g *gitdb) Exists(id string) error {
 
 func (g *gitdb) Fetch(dataset string) ([]*db.Record, error) {
 
-	dataBlock := db.NewEmptyBlock(g.config.EncryptionKey)
+	dataBlock := db.NewEmptyBlock(g.keyring(dataset))
 	err := g.dofetch(dataset, dataBlock)
 	if err != nil {
 		return nil, err
 	}
 
 	log.Info(fmt.Sprintf("%d records found in %s", dataBlock.Len(), dataset))
-	return dataBlock.Records(), nil
+	records := dataBlock.Records()
+	if err := g.migrateRecords(records...); err != nil {
+		return nil, err
+	}
+	return records, nil
 }
 
 func (g *gitdb) dofetch(dataset string, dataBlock *db.EmptyBlock) error {
 
-	fullPath := filepath.Join(g.dbDir(), dataset)
 	//events <- newReadEvent("...", fullPath)
-	log.Info("Fetching records from - " + fullPath)
-	files, err := ioutil.ReadDir(fullPath)
+	log.Info("Fetching records from - " + g.datasetPath(dataset))
+	files, err := g.blockFiles(dataset)
 	if err != nil {
 		return err
 	}
 
-	var fileName string
-	for _, file := range files {
-		fileName = filepath.Join(fullPath, file.Name())
-		if filepath.Ext(fileName) == ".json" {
-			err := dataBlock.Hydrate(fileName)
-			if err != nil {
-				return err
-			}
+	for _, fileName := range files {
+		if err := g.hydrate(dataBlock, fileName); err != nil {
+			return err
 		}
 	}
 
@@ -127,47 +156,103 @@ func (g *gitdb) Search(dataset string, searchParams []*SearchParam, searchMode S
 	searchBlocks := map[string][][]int{}
 	for _, searchParam := range searchParams {
 		indexFile := filepath.Join(g.indexDir(), dataset, searchParam.Index+".json")
-		if _, ok := g.indexCache[indexFile]; !ok {
-			g.buildIndexTargeted(dataset)
-		}
-
 		g.events <- newReadEvent("...", indexFile)
 
-		queryValue := strings.ToLower(searchParam.Value)
-		for recordID, iv := range g.indexCache[indexFile] {
-			addResult := false
-			dbValue := strings.ToLower(iv.Value.(string))
-			switch searchMode {
-			case SearchEquals:
-				addResult = dbValue == queryValue
-			case SearchContains:
-				addResult = strings.Contains(dbValue, queryValue)
-			case SearchStartsWith:
-				addResult = strings.HasPrefix(dbValue, queryValue)
-			case SearchEndsWith:
-				addResult = strings.HasSuffix(dbValue, queryValue)
-			}
+		err := g.readCache(func() error {
+			queryValue := strings.ToLower(g.collateQuery(dataset, searchParam.Index, searchParam.Value))
+			for recordID, iv := range g.index(dataset, searchParam.Index) {
+				if searchMatch(iv.Value, queryValue, searchMode) {
+					_, block, _, err := ParseID(recordID)
+					if err != nil {
+						return err
+					}
 
-			if addResult {
-				_, block, _, err := ParseID(recordID)
-				if err != nil {
-					return nil, err
+					searchBlocks[block] = append(searchBlocks[block], []int{iv.Offset, iv.Len})
 				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 
-				searchBlocks[block] = append(searchBlocks[block], []int{iv.Offset, iv.Len})
+	return g.hydrateSearchBlocks(dataset, searchBlocks)
+}
+
+//searchMatch reports whether an index value matches the lowercased queryValue
+func searchMatch(value interface{}, queryValue string, searchMode SearchMode) bool {
+	dbValue := strings.ToLower(fmt.Sprint(value))
+	switch searchMode {
+	case SearchEquals:
+		return dbValue == queryValue
+	case SearchContains:
+		return strings.Contains(dbValue, queryValue)
+	case SearchStartsWith:
+		return strings.HasPrefix(dbValue, queryValue)
+	case SearchEndsWith:
+		return strings.HasSuffix(dbValue, queryValue)
+	}
+	return false
+}
+
+//SearchRange returns records whose index value is between from and to inclusive, ordered by
+//index value. Values are compared as strings so use it on composite indexes built with
+//Composite or on indexes whose values sort lexically e.g dates formatted as 2006-01-02
+func (g *gitdb) SearchRange(dataset string, index string, from string, to string) ([]*db.Record, error) {
+	indexFile := filepath.Join(g.indexDir(), dataset, index+".json")
+	g.events <- newReadEvent("...", indexFile)
+
+	searchBlocks := map[string][][]int{}
+	values := map[string]string{}
+	err := g.readCache(func() error {
+		from, to = g.collateQuery(dataset, index, from), g.collateQuery(dataset, index, to)
+		for recordID, iv := range g.index(dataset, index) {
+			value := fmt.Sprint(iv.Value)
+			if value < from || value > to {
+				continue
 			}
+
+			_, block, _, err := ParseID(recordID)
+			if err != nil {
+				return err
+			}
+
+			searchBlocks[block] = append(searchBlocks[block], []int{iv.Offset, iv.Len})
+			values[recordID] = value
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
+	records, err := g.hydrateSearchBlocks(dataset, searchBlocks)
+	if err != nil {
+		return nil, err
 	}
 
-	resultBlock := db.NewEmptyBlock(g.config.EncryptionKey)
+	sort.SliceStable(records, func(i, j int) bool {
+		return values[records[i].ID()] < values[records[j].ID()]
+	})
+
+	return records, nil
+}
+
+//hydrateSearchBlocks reads the records at the given positions of each block of dataset
+func (g *gitdb) hydrateSearchBlocks(dataset string, searchBlocks map[string][][]int) ([]*db.Record, error) {
+	resultBlock := db.NewEmptyBlock(g.keyring(dataset))
 	for block, pos := range searchBlocks {
 		blockFile := filepath.Join(g.dbDir(), dataset, block+".json")
-		err := resultBlock.HydrateByPositions(blockFile, pos...)
+		err := g.hydrateByPositions(resultBlock, blockFile, pos...)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	return resultBlock.Records(), nil
+	records := resultBlock.Records()
+	if err := g.migrateRecords(records...); err != nil {
+		return nil, err
+	}
+	return records, nil
 }
@@ -1,6 +1,9 @@
 package gitdb_test
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/bouggo/log"
@@ -116,3 +119,44 @@ func BenchmarkGet(b *testing.B) {
 		log.Test(m.Body)
 	}
 }
+
+func TestBloomFilterSkipsBlocks(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	for i := 0; i < 3; i++ {
+		m := getTestMessageWithId(i)
+		if err := insert(m, false); err != nil {
+			t.Fatalf("insert failed: %s", err)
+		}
+	}
+
+	bloomFile := filepath.Join(dbPath, ".gitdb", "bloom", "Message", "b0.json")
+	if _, err := os.Stat(bloomFile); err != nil {
+		t.Fatalf("want: bloom filter for Message/b0, got: %s", err)
+	}
+
+	if err := testDb.Exists("Message/b0/1"); err != nil {
+		t.Errorf("Message/b0/1 should exist: %s", err)
+	}
+	if err := testDb.Exists("Message/b0/99"); err == nil {
+		t.Error("Message/b0/99 should not exist")
+	}
+
+	//AutoBlock still finds records in blocks before the last one
+	m := getTestMessageWithId(1)
+	if got := gitdb.AutoBlock(dbPath, m, gitdb.BlockByCount, 2); got != "b0" {
+		t.Errorf("want: b0, got: %s", got)
+	}
+
+	//a delete reverted with its transaction must not leave the record ruled out
+	tx := testDb.StartTransaction("delete and fail")
+	tx.AddOperation(func() error { return testDb.Delete("Message/b0/1") })
+	tx.AddOperation(func() error { return errors.New("fail") })
+	if err := tx.Commit(); err == nil {
+		t.Fatal("transaction should fail")
+	}
+	if err := testDb.Exists("Message/b0/1"); err != nil {
+		t.Errorf("Message/b0/1 should exist after the transaction is reverted: %s", err)
+	}
+}
@@ -0,0 +1,65 @@
+package gitdb
+
+import (
+	"sync"
+
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//readFlight lets goroutines reading the same record at the same time share one read of its block
+type readFlight struct {
+	mu    sync.Mutex
+	calls map[string]*readCall
+}
+
+//readCall is a read in progress. record is only read once done is closed
+type readCall struct {
+	done   chan bool
+	record *db.Record
+	err    error
+}
+
+//do returns the result of read for id, or of the read of id already in progress. Every caller
+//gets its own copy of the record
+func (f *readFlight) do(id string, read func() (*db.Record, error)) (*db.Record, error) {
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = map[string]*readCall{}
+	}
+	if c, ok := f.calls[id]; ok {
+		f.mu.Unlock()
+		<-c.done
+		if c.err != nil {
+			return nil, c.err
+		}
+		return c.record.Copy(), nil
+	}
+
+	c := &readCall{done: make(chan bool)}
+	f.calls[id] = c
+	f.mu.Unlock()
+
+	record, err := read()
+	c.err = err
+	if record != nil {
+		//decrypted once for everyone waiting
+		c.record = record.Copy()
+	}
+
+	f.mu.Lock()
+	if f.calls[id] == c {
+		delete(f.calls, id)
+	}
+	f.mu.Unlock()
+	close(c.done)
+
+	return record, err
+}
+
+//forget makes reads of id that start after a write to it read the block again instead of sharing a
+//read that started before the write
+func (f *readFlight) forget(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.calls, id)
+}
@@ -0,0 +1,46 @@
+package gitdb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//ErrReadOnly is returned when writing to a connection opened with OpenReadOnly
+var ErrReadOnly = errors.New("Connection is read-only")
+
+//OpenReadOnly opens a connection to GitDB that can only be read from. Writes fail with
+//ErrReadOnly and the database is kept up to date by fetching from Config.OnlineRemote
+//every Config.SyncInterval, which makes it suitable for reporting replicas
+func OpenReadOnly(config *Config) (GitDb, error) {
+	cfg := *config
+	cfg.readOnly = true
+	//replicas never push so only interval syncing makes sense
+	if cfg.SyncMode.kind == syncImmediate {
+		cfg.SyncMode.kind = syncOnInterval
+	}
+
+	return Open(&cfg)
+}
+
+//writable returns ErrReadOnly if the connection was opened with OpenReadOnly
+func (g *gitdb) writable() error {
+	if g.config.readOnly {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+//sparseWritable returns an error if dataset is outside Config.SparseDatasets, as its files are not checked out
+//and git would leave them out of commits. The datasets GitDB keeps itself e.g _audit are always checked out
+func (g *gitdb) sparseWritable(dataset string) error {
+	if len(g.config.SparseDatasets) == 0 || strings.HasPrefix(dataset, "_") {
+		return nil
+	}
+	for _, sparse := range g.config.SparseDatasets {
+		if sparse == dataset {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not one of the Config.SparseDatasets of this clone and can't be written", dataset)
+}
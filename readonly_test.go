@@ -0,0 +1,132 @@
+package gitdb_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestOpenReadOnly(t *testing.T) {
+	cfg := getConfig()
+	cfg.SyncMode = gitdb.SyncManual
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	m1 := getTestMessage()
+	if err := insert(m1, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+	if err := testDb.Sync(); err != nil {
+		t.Fatalf("testDb.Sync failed: %s", err)
+	}
+
+	replicaCfg := gitdb.NewConfig(testData + "/replica")
+	replicaCfg.ConnectionName = "replica"
+	replicaCfg.OnlineRemote = fakeRemote
+	replicaCfg.EncryptionKey = cfg.EncryptionKey
+	replicaCfg.SyncMode = gitdb.SyncManual
+	replica, err := gitdb.OpenReadOnly(replicaCfg)
+	if err != nil {
+		t.Fatalf("gitdb.OpenReadOnly failed: %s", err)
+	}
+	defer replica.Close()
+
+	if err := replica.Get(gitdb.ID(m1), &Message{}); err != nil {
+		t.Errorf("replica.Get failed: %s", err)
+	}
+
+	if err := replica.Insert(getTestMessage()); err != gitdb.ErrReadOnly {
+		t.Errorf("want: %s, got: %v", gitdb.ErrReadOnly, err)
+	}
+
+	if err := replica.Delete(gitdb.ID(m1)); err != gitdb.ErrReadOnly {
+		t.Errorf("want: %s, got: %v", gitdb.ErrReadOnly, err)
+	}
+
+	if err := replica.WithUser("Jane", "jane@gitdb.io").Insert(getTestMessage()); err != gitdb.ErrReadOnly {
+		t.Errorf("want: %s, got: %v", gitdb.ErrReadOnly, err)
+	}
+
+	m2 := getTestMessage()
+	if err := insert(m2, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+	if err := testDb.Sync(); err != nil {
+		t.Fatalf("testDb.Sync failed: %s", err)
+	}
+
+	if err := replica.Sync(); err != nil {
+		t.Fatalf("replica.Sync failed: %s", err)
+	}
+
+	if err := replica.Get(gitdb.ID(m2), &Message{}); err != nil {
+		t.Errorf("replica.Get should find record fetched from remote: %s", err)
+	}
+}
+
+func TestObjectReads(t *testing.T) {
+	cfg := getConfig()
+	cfg.SyncMode = gitdb.SyncManual
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	m1 := getTestMessage()
+	if err := insert(m1, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+	if err := testDb.Sync(); err != nil {
+		t.Fatalf("testDb.Sync failed: %s", err)
+	}
+
+	replicaCfg := gitdb.NewConfig(testData + "/replica")
+	replicaCfg.ConnectionName = "replica"
+	replicaCfg.OnlineRemote = fakeRemote
+	replicaCfg.EncryptionKey = cfg.EncryptionKey
+	replicaCfg.SyncMode = gitdb.SyncManual
+	replicaCfg.ObjectReads = true
+	replica, err := gitdb.OpenReadOnly(replicaCfg)
+	if err != nil {
+		t.Fatalf("gitdb.OpenReadOnly failed: %s", err)
+	}
+	defer replica.Close()
+
+	//nothing should be checked out
+	if _, err := os.Stat(filepath.Join(replicaCfg.DbPath, "data", "Message")); !os.IsNotExist(err) {
+		t.Errorf("Message dataset should not be checked out")
+	}
+
+	m2 := getTestMessage()
+	if err := insert(m2, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+	if err := testDb.Sync(); err != nil {
+		t.Fatalf("testDb.Sync failed: %s", err)
+	}
+	if err := replica.Sync(); err != nil {
+		t.Fatalf("replica.Sync failed: %s", err)
+	}
+
+	for _, m := range []*Message{m1, m2} {
+		result := &Message{}
+		if err := replica.Get(gitdb.ID(m), result); err != nil || result.Body != m.Body {
+			t.Errorf("replica.Get(%s) failed: %v", gitdb.ID(m), err)
+		}
+	}
+
+	records, err := replica.Fetch("Message")
+	if err != nil || len(records) != 2 {
+		t.Errorf("want: 2 records, got: %d %v", len(records), err)
+	}
+
+	results, err := replica.Search("Message", []*gitdb.SearchParam{{Index: "From", Value: m1.From}}, gitdb.SearchEquals)
+	if err != nil || len(results) == 0 {
+		t.Errorf("replica.Search failed: %d %v", len(results), err)
+	}
+
+	cfg.ObjectReads = true
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Config.ObjectReads should only be valid for read-only connections")
+	}
+}
@@ -0,0 +1,74 @@
+package gitdb
+
+import (
+	"encoding/json"
+
+	"github.com/bouggo/log"
+)
+
+//redactedValue replaces the values of redacted fields
+const redactedValue = "[REDACTED]"
+
+//Redact masks fields of the dataset, e.g passwords and tokens, wherever GitDB shows record
+//values: the web UI, AuditEntry data and errors such as ErrUniqueViolation. Records are
+//stored and read unchanged so use EncryptFields to also protect the values at rest
+func (a *Schema) Redact(fields ...string) *Schema {
+	a.redacted = append(a.redacted, fields...)
+	return a
+}
+
+func (a *Schema) isRedacted(field string) bool {
+	for _, f := range a.redacted {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+//isRedacted reports whether field of dataset is redacted
+func (s *schemaMeta) isRedacted(dataset string, field string) bool {
+	return s.Redacted[dataset+"."+field]
+}
+
+//redact masks the redacted fields of dataset in data and reports whether any were found
+func (s *schemaMeta) redact(dataset string, data map[string]interface{}) bool {
+	masked := false
+	for field := range data {
+		if s.isRedacted(dataset, field) {
+			data[field] = redactedValue
+			masked = true
+		}
+	}
+	return masked
+}
+
+//redactJSON masks the redacted fields of dataset in the JSON object record, which is
+//either the data of a record or a record as stored with its data under "Data". record is
+//returned as is when it has no redacted fields
+func (s *schemaMeta) redactJSON(dataset string, record string) string {
+	if len(record) == 0 {
+		return record
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(record), &obj); err != nil {
+		log.Error(err.Error())
+		return record
+	}
+
+	data, ok := obj["Data"].(map[string]interface{})
+	if !ok {
+		data = obj
+	}
+	if !s.redact(dataset, data) {
+		return record
+	}
+
+	b, err := json.Marshal(obj)
+	if err != nil {
+		log.Error(err.Error())
+		return record
+	}
+	return string(b)
+}
@@ -0,0 +1,69 @@
+package gitdb_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+type Credential struct {
+	testModel
+	Username string
+	Password string
+	Token    string
+}
+
+func (a *Credential) GetSchema() *gitdb.Schema {
+	indexes := map[string]interface{}{"Token": a.Token}
+	return gitdb.NewSchema("Credential", "b0", a.Username, indexes).Unique("Token").Redact("Password", "Token")
+}
+
+func TestRedact(t *testing.T) {
+	cfg := getConfig()
+	cfg.Audit = true
+	cfg.EnableUI = true
+	cfg.UIPort = 4121
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	a := &Credential{Username: "ada", Password: "s3cret-pass", Token: "tok-1234"}
+	if err := testDb.Insert(a); err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+
+	//the record itself is not redacted
+	result := &Credential{}
+	if err := testDb.Get(gitdb.ID(a), result); err != nil || result.Password != a.Password {
+		t.Errorf("want: %s, got: %s (%v)", a.Password, result.Password, err)
+	}
+
+	var unique *gitdb.ErrUniqueViolation
+	err := testDb.Insert(&Credential{Username: "bob", Token: a.Token})
+	if !errors.As(err, &unique) || strings.Contains(err.Error(), a.Token) {
+		t.Errorf("want: %T without the token, got: %v", unique, err)
+	}
+
+	records, err := testDb.Search("_audit", []*gitdb.SearchParam{{Index: "Record", Value: gitdb.ID(a)}}, gitdb.SearchEquals)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("want: 1 audit entry, got: %d (%v)", len(records), err)
+	}
+	if strings.Contains(records[0].JSON(), a.Password) {
+		t.Errorf("audit entry should not contain the password: %s", records[0].JSON())
+	}
+
+	for _, url := range []string{"http://localhost:4121/list/Credential", "http://localhost:4121/view/Credential/b0/r0"} {
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatalf("GitDB UI Server request failed: %s", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if strings.Contains(string(body), a.Password) || !strings.Contains(string(body), "[REDACTED]") {
+			t.Errorf("%s: want: password redacted, got: %s", url, body)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package gitdb
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+//ref is a reference from an index of one dataset to the records of another
+type ref struct {
+	Dataset string `json:"dataset"`
+	Field   string `json:"field"`
+	Target  string `json:"target"`
+	Cascade bool   `json:"cascade"`
+}
+
+func (r ref) key() string {
+	return r.Dataset + "." + r.Field
+}
+
+//ErrRefViolation is returned when a write would leave a reference between datasets dangling
+type ErrRefViolation struct {
+	//ID is the record holding the reference
+	ID     string
+	Field  string
+	Value  interface{}
+	Target string
+}
+
+func (e *ErrRefViolation) Error() string {
+	return fmt.Sprintf("%s.%s references %s record %v", e.ID, e.Field, e.Target, e.Value)
+}
+
+//DanglingRef is a reference to a record that does not exist found by CheckIntegrity
+type DanglingRef struct {
+	//ID is the record holding the reference
+	ID     string
+	Field  string
+	Value  interface{}
+	Target string
+}
+
+//Ref declares that the field index holds the id, or record id, of a record in dataset.
+//Inserts fail if the record does not exist and deleting the record fails while it is referenced
+func (a *Schema) Ref(field string, dataset string) *Schema {
+	a.refs = append(a.refs, ref{Dataset: a.dataset, Field: field, Target: dataset})
+	return a
+}
+
+//CascadeRef is like Ref but deleting the record in dataset also deletes the records referencing it
+func (a *Schema) CascadeRef(field string, dataset string) *Schema {
+	a.refs = append(a.refs, ref{Dataset: a.dataset, Field: field, Target: dataset, Cascade: true})
+	return a
+}
+
+func (a *Schema) validateRefs() error {
+	for _, r := range a.refs {
+		if _, ok := a.indexes[r.Field]; !ok {
+			return fmt.Errorf("ref %s is not an index of %s", r.Field, a.dataset)
+		}
+		if len(r.Target) == 0 {
+			return fmt.Errorf("ref %s has no target dataset", r.Field)
+		}
+	}
+	return nil
+}
+
+//refTarget returns the id of the record in target value refers to. value is either
+//a full record id or the record part of one
+func (g *gitdb) refTarget(target string, value interface{}) (string, bool) {
+	v := fmt.Sprint(value)
+	ids := g.index(target, "id")
+	if strings.Contains(v, "/") {
+		_, ok := ids[v]
+		return v, ok
+	}
+
+	for id := range ids {
+		if strings.HasSuffix(id, "/"+v) && strings.Count(id, "/") == 2 {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+//isRef reports whether value is a reference to the record id
+func isRef(value interface{}, id string) bool {
+	v := fmt.Sprint(value)
+	_, _, record, _ := ParseID(id)
+	return v == id || v == record
+}
+
+func emptyRef(value interface{}) bool {
+	return value == nil || fmt.Sprint(value) == ""
+}
+
+//checkRefs returns *ErrRefViolation if a reference of the record id does not exist
+func (g *gitdb) checkRefs(schema *Schema, id string) error {
+	for _, r := range schema.refs {
+		value := schema.indexes[r.Field]
+		if emptyRef(value) {
+			continue
+		}
+
+		if _, ok := g.refTarget(r.Target, value); !ok {
+			return &ErrRefViolation{ID: id, Field: r.Field, Value: value, Target: r.Target}
+		}
+	}
+	return nil
+}
+
+//referencing returns the records referring to id through r
+func (g *gitdb) referencing(r ref, id string) []string {
+	var ids []string
+	for recordID, iv := range g.index(r.Dataset, r.Field) {
+		if isRef(iv.Value, id) {
+			ids = append(ids, recordID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+//enforceRefs blocks the delete of id if it is referenced or deletes the records
+//referencing it through a CascadeRef
+func (g *gitdb) enforceRefs(id string, user *User, deleting map[string]bool) error {
+	dataset, _, _, err := ParseID(id)
+	if err != nil {
+		return err
+	}
+
+	var cascade []string
+	err = g.readCache(func() error {
+		for _, r := range g.meta().Refs {
+			if r.Target != dataset {
+				continue
+			}
+
+			for _, recordID := range g.referencing(r, id) {
+				if deleting[recordID] {
+					continue
+				}
+				if !r.Cascade {
+					return &ErrRefViolation{ID: recordID, Field: r.Field, Value: id, Target: r.Target}
+				}
+				cascade = append(cascade, recordID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, recordID := range cascade {
+		if err := g.deleteCascade(recordID, false, user, deleting); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//cascadesTo returns dataset and the datasets a delete of one of its records can cascade to through CascadeRef
+func (g *gitdb) cascadesTo(dataset string) []string {
+	g.cacheMu.Lock()
+	defer g.cacheMu.Unlock()
+
+	datasets := []string{dataset}
+	seen := map[string]bool{dataset: true}
+	for i := 0; i < len(datasets); i++ {
+		for _, r := range g.meta().Refs {
+			if r.Cascade && r.Target == datasets[i] && !seen[r.Dataset] {
+				seen[r.Dataset] = true
+				datasets = append(datasets, r.Dataset)
+			}
+		}
+	}
+	return datasets
+}
+
+//CheckIntegrity returns every reference in the database to a record that does not exist
+func (g *gitdb) CheckIntegrity() ([]*DanglingRef, error) {
+	g.cacheMu.Lock()
+	defer g.cacheMu.Unlock()
+
+	refs := g.meta().Refs
+	keys := make([]string, 0, len(refs))
+	for key := range refs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var dangling []*DanglingRef
+	for _, key := range keys {
+		r := refs[key]
+		if _, err := os.Stat(g.datasetPath(r.Dataset)); err != nil && !g.config.ObjectReads {
+			continue
+		}
+
+		index := g.index(r.Dataset, r.Field)
+		ids := make([]string, 0, len(index))
+		for id := range index {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			value := index[id].Value
+			if emptyRef(value) {
+				continue
+			}
+			if _, ok := g.refTarget(r.Target, value); !ok {
+				dangling = append(dangling, &DanglingRef{ID: id, Field: r.Field, Value: value, Target: r.Target})
+			}
+		}
+	}
+
+	return dangling, nil
+}
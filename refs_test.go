@@ -0,0 +1,184 @@
+package gitdb_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+type Room struct {
+	testModel
+	RoomId string
+}
+
+func (r *Room) GetSchema() *gitdb.Schema {
+	return gitdb.NewSchema("Room", "b0", r.RoomId, map[string]interface{}{})
+}
+
+type Stay struct {
+	testModel
+	StayId  int
+	RoomId  string
+	cascade bool
+}
+
+func (s *Stay) GetSchema() *gitdb.Schema {
+	schema := gitdb.NewSchema("Stay", "b0", fmt.Sprintf("%d", s.StayId), map[string]interface{}{"RoomId": s.RoomId})
+	if s.cascade {
+		return schema.CascadeRef("RoomId", "Room")
+	}
+	return schema.Ref("RoomId", "Room")
+}
+
+type Review struct {
+	testModel
+	ReviewId int
+	StayId   string
+}
+
+func (r *Review) GetSchema() *gitdb.Schema {
+	return gitdb.NewSchema("Review", "b0", fmt.Sprintf("%d", r.ReviewId), map[string]interface{}{"StayId": r.StayId}).Ref("StayId", "Stay")
+}
+
+func TestRefs(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	room := &Room{RoomId: "101"}
+	if err := testDb.Insert(room); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+
+	var violation *gitdb.ErrRefViolation
+	if err := testDb.Insert(&Stay{StayId: 1, RoomId: "999"}); !errors.As(err, &violation) {
+		t.Errorf("want: *gitdb.ErrRefViolation for missing room, got: %v", err)
+	}
+
+	stay := &Stay{StayId: 1, RoomId: "101"}
+	if err := testDb.Insert(stay); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+
+	err := testDb.Delete(gitdb.ID(room))
+	if !errors.As(err, &violation) || violation.ID != gitdb.ID(stay) {
+		t.Errorf("want: delete blocked by %s, got: %v", gitdb.ID(stay), err)
+	}
+	if err := testDb.Exists(gitdb.ID(room)); err != nil {
+		t.Errorf("%s should still exist: %s", gitdb.ID(room), err)
+	}
+
+	//switch the reference to cascade
+	stay.cascade = true
+	if err := testDb.Insert(stay); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+	if err := testDb.Delete(gitdb.ID(room)); err != nil {
+		t.Fatalf("testDb.Delete failed: %s", err)
+	}
+	if err := testDb.Exists(gitdb.ID(stay)); err == nil {
+		t.Errorf("%s should be deleted with %s", gitdb.ID(stay), gitdb.ID(room))
+	}
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	rooms := []*Room{{RoomId: "101"}, {RoomId: "102"}}
+	for _, r := range rooms {
+		if err := testDb.Insert(r); err != nil {
+			t.Fatalf("testDb.Insert failed: %s", err)
+		}
+	}
+
+	for i, r := range rooms {
+		if err := testDb.Insert(&Stay{StayId: i, RoomId: gitdb.ID(r)}); err != nil {
+			t.Fatalf("testDb.Insert failed: %s", err)
+		}
+	}
+
+	dangling, err := testDb.CheckIntegrity()
+	if err != nil {
+		t.Fatalf("testDb.CheckIntegrity failed: %s", err)
+	}
+	if len(dangling) != 0 {
+		t.Errorf("want: no dangling refs, got: %d", len(dangling))
+	}
+
+	//remove a room behind gitdb's back
+	blockFile := filepath.Join(dbPath, "data", "Room", "b0.json")
+	data, err := ioutil.ReadFile(blockFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := map[string]interface{}{}
+	if err := json.Unmarshal(data, &block); err != nil {
+		t.Fatal(err)
+	}
+	delete(block, gitdb.ID(rooms[1]))
+	if data, err = json.Marshal(block); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(blockFile, data, 0744); err != nil {
+		t.Fatal(err)
+	}
+	if err := testDb.RebuildIndex("Room"); err != nil {
+		t.Fatalf("testDb.RebuildIndex failed: %s", err)
+	}
+
+	dangling, err = testDb.CheckIntegrity()
+	if err != nil {
+		t.Fatalf("testDb.CheckIntegrity failed: %s", err)
+	}
+	if len(dangling) != 1 || dangling[0].ID != "Stay/b0/1" || dangling[0].Target != "Room" {
+		t.Errorf("want: Stay/b0/1 dangling, got: %d dangling ref(s)", len(dangling))
+	}
+}
+
+func TestCascadeDelete(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	room := &Room{RoomId: "101"}
+	stays := []*Stay{{StayId: 1, RoomId: "101", cascade: true}, {StayId: 2, RoomId: "101", cascade: true}}
+	for _, m := range []gitdb.Model{room, stays[0], stays[1], &Review{ReviewId: 1, StayId: "2"}} {
+		if err := testDb.Insert(m); err != nil {
+			t.Fatalf("testDb.Insert failed: %s", err)
+		}
+	}
+	commits := gitOutput(t, "rev-list", "--count", "HEAD")
+
+	//the review of stay 2 blocks the cascade after stay 1 is deleted
+	var violation *gitdb.ErrRefViolation
+	if err := testDb.Delete(gitdb.ID(room)); !errors.As(err, &violation) || violation.ID != "Review/b0/1" {
+		t.Fatalf("want: delete blocked by Review/b0/1, got: %v", err)
+	}
+	for _, id := range []string{gitdb.ID(room), gitdb.ID(stays[0]), gitdb.ID(stays[1])} {
+		if err := testDb.Exists(id); err != nil {
+			t.Errorf("%s should still exist: %s", id, err)
+		}
+	}
+	if got := gitOutput(t, "status", "--porcelain"); len(got) > 0 || gitOutput(t, "rev-list", "--count", "HEAD") != commits {
+		t.Errorf("want: nothing deleted or committed, got: %s", got)
+	}
+	dangling, err := testDb.CheckIntegrity()
+	if err != nil || len(dangling) != 0 {
+		t.Errorf("want: no dangling refs, got: %d %v", len(dangling), err)
+	}
+
+	if err := testDb.Delete("Review/b0/1"); err != nil {
+		t.Fatalf("testDb.Delete failed: %s", err)
+	}
+	if err := testDb.Delete(gitdb.ID(room)); err != nil {
+		t.Fatalf("testDb.Delete failed: %s", err)
+	}
+	if got := gitOutput(t, "log", "-1", "--format=%B"); !strings.Contains(got, "Stay/b0/1") || !strings.Contains(got, "Stay/b0/2") || !strings.Contains(got, gitdb.ID(room)) {
+		t.Errorf("want: room and stays deleted in one commit, got: %s", got)
+	}
+}
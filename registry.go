@@ -0,0 +1,76 @@
+package gitdb
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var registryMu sync.RWMutex
+
+//registry holds the concrete type of the model of each dataset registered with RegisterModel
+var registry = map[string]reflect.Type{}
+
+//RegisterModel registers the concrete type of m as the model of its dataset, usually from an init func
+//e.g gitdb.RegisterModel(&Booking{}). Records of the dataset are then decoded into a new instance of it by
+//GetModel, FetchModels and Decode, and used by the UI and other tools that only know the dataset name.
+//Config.Factory, when it returns a model, takes precedence. RegisterModel panics if m is not a pointer to a
+//struct or another type is already registered for the dataset
+func RegisterModel(m Model) {
+	t := reflect.TypeOf(m)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("gitdb: RegisterModel needs a pointer to a struct, got %T", m))
+	}
+
+	dataset := m.GetSchema().name()
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if registered, ok := registry[dataset]; ok && registered != t {
+		panic(fmt.Sprintf("gitdb: RegisterModel called twice for %s with %s and %s", dataset, registered, t))
+	}
+	registry[dataset] = t
+}
+
+//registeredModel returns a new instance of the model registered for dataset or nil if there is none
+func registeredModel(dataset string) Model {
+	registryMu.RLock()
+	t, ok := registry[dataset]
+	registryMu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return reflect.New(t.Elem()).Interface().(Model)
+}
+
+func hasRegisteredModels() bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return len(registry) > 0
+}
+
+//hasFactory reports whether models can be made from the dataset name alone
+func (c Config) hasFactory() bool {
+	return c.Factory != nil || hasRegisteredModels()
+}
+
+//factory returns an empty model of dataset from Config.Factory or, failing that, from RegisterModel
+func (c Config) factory(dataset string) Model {
+	if c.Factory != nil {
+		if m := c.Factory(dataset); m != nil {
+			return m
+		}
+	}
+	return registeredModel(dataset)
+}
+
+//GetModel returns record id decoded into a model of its concrete type, found like Decode does
+func (g *gitdb) GetModel(id string) (Model, error) {
+	record, err := g.doget(id)
+	if err != nil {
+		return nil, err
+	}
+
+	g.events <- newReadEvent("...", id)
+
+	return g.Decode(record)
+}
@@ -0,0 +1,75 @@
+package gitdb_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+type Registered struct {
+	testModel
+	RegisteredId int
+	Name         string
+}
+
+func (r *Registered) GetSchema() *gitdb.Schema {
+	return gitdb.NewSchema("Registered", "b0", fmt.Sprintf("%d", r.RegisteredId), map[string]interface{}{"Name": r.Name})
+}
+
+//Impostor claims the dataset of Registered
+type Impostor struct {
+	Registered
+}
+
+func TestRegisterModel(t *testing.T) {
+	gitdb.RegisterModel(&Registered{})
+	//registering the same type again is allowed
+	gitdb.RegisterModel(&Registered{})
+
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	r := &Registered{RegisteredId: 1, Name: "registered"}
+	if err := testDb.Insert(r); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+
+	m, err := testDb.GetModel(gitdb.ID(r))
+	if err != nil {
+		t.Fatalf("testDb.GetModel failed: %s", err)
+	}
+	if got, ok := m.(*Registered); !ok || got.Name != "registered" {
+		t.Errorf("want: *Registered named registered, got: %#v", m)
+	}
+
+	models, err := testDb.FetchModels("Registered")
+	if err != nil {
+		t.Fatalf("testDb.FetchModels failed: %s", err)
+	}
+	if len(models) != 1 {
+		t.Fatalf("want: 1 model, got: %d", len(models))
+	}
+	if _, ok := models[0].(*Registered); !ok {
+		t.Errorf("want: *Registered, got: %T", models[0])
+	}
+
+	//datasets without a registered model still need Config.Factory
+	if err := testDb.Insert(getTestMessage()); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+	if _, err := testDb.FetchModels("Message"); err == nil {
+		t.Error("testDb.FetchModels should fail for a dataset without a model")
+	}
+}
+
+func TestRegisterModelConflict(t *testing.T) {
+	gitdb.RegisterModel(&Registered{})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("gitdb.RegisterModel should panic for a second type of the same dataset")
+		}
+	}()
+	gitdb.RegisterModel(&Impostor{})
+}
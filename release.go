@@ -0,0 +1,97 @@
+package gitdb
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//Release is a tagged snapshot of the database
+type Release struct {
+	Name    string
+	Commit  string
+	Time    time.Time
+	Message string
+}
+
+//TagRelease tags the current state of the database as a release e.g. an end-of-month close.
+//Releases are pushed to the online remote on the next sync
+func (g *gitdb) TagRelease(name string) error {
+	if err := g.writable(); err != nil {
+		return err
+	}
+
+	if err := g.checkTagName(name); err != nil {
+		return err
+	}
+
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	return g.gitDriver.tag(name, "Release "+name, g.config.User)
+}
+
+//checkTagName returns an error unless name can be the name of a release tag, so it can't be taken
+//for an option of git tag
+func (g *gitdb) checkTagName(name string) error {
+	if len(name) == 0 || strings.HasPrefix(name, "-") {
+		return errors.New("Invalid release name: " + name)
+	}
+	return g.gitDriver.checkTagName(name)
+}
+
+//ListReleases returns all releases, oldest first
+func (g *gitdb) ListReleases() ([]*Release, error) {
+	return g.gitDriver.tags()
+}
+
+//FetchAtTag returns all records in dataset as they were at release tag
+func (g *gitdb) FetchAtTag(dataset string, tag string) ([]*db.Record, error) {
+	release, err := g.release(tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.recordsAt(dataset, release.Commit)
+}
+
+//recordsAt returns all records in dataset as they were at commit
+func (g *gitdb) recordsAt(dataset string, commit string) ([]*db.Record, error) {
+	files, err := g.gitDriver.lsTree(commit, dataset+"/", false)
+	if err != nil {
+		return nil, err
+	}
+
+	dataBlock := db.NewEmptyBlock(g.keyring(dataset))
+	for _, file := range files {
+		if !isBlockFile(file) {
+			continue
+		}
+
+		if err := g.blockAt(commit, file, dataBlock); err != nil {
+			return nil, err
+		}
+	}
+
+	return dataBlock.Records(), nil
+}
+
+func (g *gitdb) release(name string) (*Release, error) {
+	releases, err := g.gitDriver.tags()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, release := range releases {
+		if release.Name == name {
+			return release, nil
+		}
+	}
+
+	return nil, errors.New("Unknown release: " + name)
+}
@@ -0,0 +1,64 @@
+package gitdb_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestTagRelease(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	m1, m2 := getTestMessageWithId(1), getTestMessageWithId(2)
+	if err := insert(m1, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	if err := testDb.TagRelease("v2024-06-30"); err != nil {
+		t.Fatalf("testDb.TagRelease failed: %s", err)
+	}
+	tagged := headCommit(t)
+
+	if err := insert(m2, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	records, err := testDb.FetchAtTag("Message", "v2024-06-30")
+	if err != nil {
+		t.Fatalf("testDb.FetchAtTag failed: %s", err)
+	}
+	if len(records) != 1 || records[0].ID() != gitdb.ID(m1) {
+		t.Errorf("want: [%s] at release, got: %d record(s)", gitdb.ID(m1), len(records))
+	}
+
+	releases, err := testDb.ListReleases()
+	if err != nil {
+		t.Fatalf("testDb.ListReleases failed: %s", err)
+	}
+	if len(releases) != 1 {
+		t.Fatalf("want: 1 release, got: %d", len(releases))
+	}
+	if releases[0].Name != "v2024-06-30" || releases[0].Commit != tagged {
+		t.Errorf("want: v2024-06-30 @ %s, got: %s @ %s", tagged, releases[0].Name, releases[0].Commit)
+	}
+
+	if err := testDb.TagRelease("v2024-06-30"); err == nil {
+		t.Error("testDb.TagRelease should fail for an existing release")
+	}
+
+	//names git tag would take for an option or that aren't valid tag names
+	for _, name := range []string{"", "-f", "--delete", "v1..2", "bad name", "v1~"} {
+		if err := testDb.TagRelease(name); err == nil || !strings.Contains(err.Error(), "Invalid release name") {
+			t.Errorf("want: invalid release name %q rejected, got: %v", name, err)
+		}
+	}
+	if releases, _ := testDb.ListReleases(); len(releases) != 1 {
+		t.Errorf("want: 1 release, got: %d", len(releases))
+	}
+
+	if _, err := testDb.FetchAtTag("Message", "v1999-01-01"); err == nil {
+		t.Error("testDb.FetchAtTag should fail for an unknown release")
+	}
+}
@@ -0,0 +1,156 @@
+package gitdb
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bouggo/log"
+)
+
+//onlineRemote is the git remote name of Config.OnlineRemote
+const onlineRemote = "online"
+
+//Remote is a mirror GitDB pushes to in addition to Config.OnlineRemote
+type Remote struct {
+	Name string
+	URL  string
+}
+
+//RemoteStatus reports the outcome of the last push to a remote
+type RemoteStatus struct {
+	Name string
+	URL  string
+	//LastPull is when changes were last pulled from the remote successfully. Only the online remote is pulled from
+	LastPull time.Time
+	//LastPush is when the remote was last pushed to successfully
+	LastPush time.Time
+	//LastError is the error of the last push, nil if it succeeded
+	LastError error
+}
+
+type remoteStatuses struct {
+	mu       sync.Mutex
+	statuses map[string]*RemoteStatus
+}
+
+func (r *remoteStatuses) record(name string, url string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := r.entry(name, url)
+	status.LastError = err
+	if err == nil {
+		status.LastPush = time.Now()
+	}
+}
+
+func (r *remoteStatuses) pulled(name string, url string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entry(name, url).LastPull = time.Now()
+}
+
+//entry returns the status of the remote name, adding it if it has none. r.mu must be held
+func (r *remoteStatuses) entry(name string, url string) *RemoteStatus {
+	if r.statuses == nil {
+		r.statuses = map[string]*RemoteStatus{}
+	}
+
+	status, ok := r.statuses[name]
+	if !ok {
+		status = &RemoteStatus{Name: name, URL: url}
+		r.statuses[name] = status
+	}
+	return status
+}
+
+func (r *remoteStatuses) get(name string, url string) RemoteStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if status, ok := r.statuses[name]; ok {
+		return *status
+	}
+	return RemoteStatus{Name: name, URL: url}
+}
+
+//Remotes returns the pull and push status of the online remote followed by its mirrors
+func (g *gitdb) Remotes() []RemoteStatus {
+	var statuses []RemoteStatus
+	if len(g.config.OnlineRemote) > 0 {
+		statuses = append(statuses, g.remoteStatus.get(onlineRemote, g.config.OnlineRemote))
+	}
+
+	for _, mirror := range g.config.Mirrors {
+		statuses = append(statuses, g.remoteStatus.get(mirror.Name, mirror.URL))
+	}
+
+	return statuses
+}
+
+func (g *gitdb) mirror(name string) (Remote, bool) {
+	for _, mirror := range g.config.Mirrors {
+		if mirror.Name == name {
+			return mirror, true
+		}
+	}
+	return Remote{}, false
+}
+
+//setupMirrors adds Config.Mirrors as git remotes
+func (g *gitdb) setupMirrors() error {
+	for _, mirror := range g.config.Mirrors {
+		if err := g.gitDriver.setRemote(mirror.Name, mirror.URL); err != nil {
+			return fmt.Errorf("failed to add mirror %s: %s", mirror.Name, err)
+		}
+	}
+	return nil
+}
+
+//pushMirror pushes to a single mirror. Failed pushes are retried on the next sync
+func (g *gitdb) pushMirror(mirror Remote) error {
+	err := g.gitDriver.push(mirror.Name)
+	g.remoteStatus.record(mirror.Name, mirror.URL, err)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to push to mirror %s: %s", mirror.Name, err))
+	}
+	return err
+}
+
+//pushMirrors pushes to every mirror and returns the first error
+func (g *gitdb) pushMirrors() error {
+	var firstErr error
+	for _, mirror := range g.config.Mirrors {
+		if err := g.pushMirror(mirror); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+//forcePushAll replaces the branches and tags of the online remote and every mirror with the local ones
+//once their history is rewritten, so no remote keeps the old history or fails to take later pushes.
+//Every mirror is pushed to and the first error returned
+func (g *gitdb) forcePushAll() error {
+	if err := g.gitDriver.forcePush(onlineRemote); err != nil {
+		return err
+	}
+	g.pushQueue.mu.Lock()
+	g.pushQueue.pending = 0
+	g.pushQueue.mu.Unlock()
+
+	var firstErr error
+	for _, mirror := range g.config.Mirrors {
+		err := g.gitDriver.forcePush(mirror.Name)
+		g.remoteStatus.record(mirror.Name, mirror.URL, err)
+		if err != nil {
+			log.Error(fmt.Sprintf("Failed to force push to mirror %s: %s", mirror.Name, err))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
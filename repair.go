@@ -0,0 +1,137 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//repairBlock replaces block of dataset, which can't be read, with data and commits it as user.
+//data must be a JSON object of the records of the block
+func (g *gitdb) repairBlock(dataset string, block string, data []byte, user *User, commitMsg string) error {
+	if err := g.writable(); err != nil {
+		return err
+	}
+
+	var records map[string]string
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("Block %s/%s must be a JSON object of records: %w", dataset, block, err)
+	}
+	for id := range records {
+		ds, blk, _, err := ParseID(id)
+		if err != nil {
+			return err
+		}
+		if ds != dataset || blk != block {
+			return fmt.Errorf("Record %s does not belong in block %s/%s", id, dataset, block)
+		}
+	}
+
+	g.commitMu.Lock()
+	defer g.commitMu.Unlock()
+
+	blockFilePath := g.blockFilePath(dataset, block)
+	dataBlock := db.ParseBlock(blockFilePath, g.keyring(dataset), data)
+	for id := range records {
+		g.reads.forget(id)
+	}
+
+	g.events <- newWriteBeforeEvent("...", dataset+"/"+block)
+	if err := g.commitBlock(blockFilePath, dataBlock, user, "repair", dataset+"/"+block, commitMsg); err != nil {
+		return err
+	}
+	if g.loadedBlocks != nil {
+		g.loadedBlocks[blockFilePath] = dataBlock
+	}
+	return nil
+}
+
+//restoreBlock replaces block of dataset with its content at commit and commits it as user
+func (g *gitdb) restoreBlock(dataset string, block string, commit string, user *User) error {
+	data, err := g.gitDriver.show(commit, dataset+"/"+block+".json")
+	if err != nil {
+		return fmt.Errorf("Block %s/%s not found at %s", dataset, block, commit)
+	}
+	return g.repairBlock(dataset, block, data, user, "Restoring "+dataset+"/"+block+" to "+commit)
+}
+
+//repairRecord replaces the data of record id, which can't be read, with data and commits it as user.
+//data is encrypted if the dataset is encrypted
+func (g *gitdb) repairRecord(id string, data string, user *User) error {
+	if err := g.writable(); err != nil {
+		return err
+	}
+	if !json.Valid([]byte(data)) {
+		return fmt.Errorf("Record %s must be valid JSON", id)
+	}
+
+	dataset, block, _, err := ParseID(id)
+	if err != nil {
+		return err
+	}
+
+	g.commitMu.Lock()
+	defer g.commitMu.Unlock()
+
+	blockFilePath := g.blockFilePath(dataset, block)
+	if !g.blockFileExists(blockFilePath) {
+		return fmt.Errorf("Record %s not found in %s", id, dataset)
+	}
+	dataBlock, err := g.loadBlock(blockFilePath)
+	if err != nil {
+		return err
+	}
+	old, err := dataBlock.Get(id)
+	if err != nil {
+		return fmt.Errorf("Record %s not found in %s", id, dataset)
+	}
+
+	stored, err := g.encodeRepair(dataset, old.Data(), data)
+	if err != nil {
+		return err
+	}
+	dataBlock.Add(id, stored)
+
+	g.events <- newWriteBeforeEvent("...", id)
+	return g.commitBlock(blockFilePath, dataBlock, user, "repair", id, "Repairing "+id)
+}
+
+//encodeRepair returns data as stored in place of the record stored as old: encrypted if the dataset
+//is encrypted at rest or old was encrypted and the dataset has a key
+func (g *gitdb) encodeRepair(dataset string, old string, data string) (string, error) {
+	if !g.config.EncryptAtRest && json.Valid([]byte(old)) {
+		return data, nil
+	}
+
+	var key string
+	if g.config.Cipher == nil {
+		var err error
+		if key, err = g.encryptionKey(dataset); err != nil {
+			return "", err
+		}
+		if len(key) == 0 {
+			return data, nil
+		}
+	}
+	return g.encrypt(dataset, key, data)
+}
+
+//blockHistory returns the commits that changed block of dataset, most recent first
+func (g *gitdb) blockHistory(dataset string, block string) ([]*Change, error) {
+	entries, err := g.gitDriver.log(dataset + "/" + block + ".json")
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]*Change, 0, len(entries))
+	for _, entry := range entries {
+		change := &Change{Commit: entry.hash, Author: entry.author, Time: entry.time, Message: strings.TrimSpace(entry.message)}
+		if info, err := ParseCommitInfo(entry.message); err == nil {
+			change.Operation, change.Message = info.Operation, info.Message
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
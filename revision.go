@@ -0,0 +1,39 @@
+package gitdb
+
+import (
+	"errors"
+	"fmt"
+)
+
+//ErrStaleRecord is returned by InsertRevision when the record was written by someone else since it was read
+type ErrStaleRecord struct {
+	ID string
+	//Expected is the revision passed to InsertRevision
+	Expected int
+	//Revision is the revision the record is at
+	Revision int
+}
+
+func (e *ErrStaleRecord) Error() string {
+	return fmt.Sprintf("%s is at revision %d, not %d", e.ID, e.Revision, e.Expected)
+}
+
+//Revision returns how many times record id has been written, or 0 if it does not exist. Errors reading a record
+//that exists are returned
+func (g *gitdb) Revision(id string) (int, error) {
+	if _, _, _, err := ParseID(id); err != nil {
+		return 0, err
+	}
+
+	record, err := g.doget(id)
+	var notFound *errNotFound
+	if errors.As(err, &notFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	g.events <- newReadEvent("...", id)
+	return record.Revision(), nil
+}
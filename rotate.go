@@ -0,0 +1,195 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bouggo/log"
+	"github.com/gogitdb/gitdb/v2/internal/crypto"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//RotateKey re-encrypts the encrypted records of dataset with newKey and commits them. Records already
+//encrypted with newKey are left as they are so an interrupted rotation can be run again, except records
+//of earlier versions which are upgraded to AES-GCM e.g by passing the same oldKey and newKey. With
+//Config.EncryptAtRest records stored in plaintext are encrypted with newKey as well. When oldKey is
+//Config.EncryptionKey the connection switches to newKey. With Config.KeyProvider the key of dataset is asked
+//for again so the provider should return newKey from then on. oldKey and newKey are added to
+//Config.PreviousKeys so datasets that have not been rotated yet stay readable
+func (g *gitdb) RotateKey(dataset string, oldKey string, newKey string) error {
+	if err := g.writable(); err != nil {
+		return err
+	}
+
+	if g.config.Cipher != nil {
+		return errors.New("RotateKey can not rotate the keys of Config.Cipher")
+	}
+
+	switch len(newKey) {
+	case 16, 24, 32:
+	default:
+		return errors.New("Invalid encryption key: must be 16, 24 or 32 bytes long")
+	}
+
+	g.commitMu.Lock()
+	defer g.commitMu.Unlock()
+
+	blockFiles, err := g.blockFiles(dataset)
+	if err != nil {
+		return err
+	}
+
+	//re-encrypt every block before writing any so a record that can't be decrypted leaves the dataset untouched
+	blocks := map[string]*db.Block{}
+	var ids []string
+	for _, blockFile := range blockFiles {
+		dataBlock := db.LoadBlock(blockFile, nil)
+		changed := false
+		for _, record := range dataBlock.Records() {
+			data := record.Data()
+			encrypted := !json.Valid([]byte(data))
+			dec := data
+			current := false
+			if encrypted {
+				var err error
+				//records of earlier versions are re-encrypted even when they already use newKey
+				if dec, err = crypto.DecryptJSON(newKey, data); err == nil && crypto.Versioned(data) {
+					current = true
+				} else if dec, err = crypto.DecryptJSON(oldKey, data); err != nil {
+					return fmt.Errorf("Record %s can not be decrypted with the old key: %w", record.ID(), err)
+				}
+			}
+
+			//fields of Schema.EncryptFields are re-encrypted inside the record
+			rotated, fieldsChanged, err := rotateFields(oldKey, newKey, dec)
+			if err != nil {
+				return fmt.Errorf("Record %s can not be decrypted with the old key: %w", record.ID(), err)
+			}
+
+			switch {
+			case encrypted && (!current || fieldsChanged), !encrypted && g.config.EncryptAtRest:
+				if rotated, err = crypto.Encrypt(newKey, rotated); err != nil {
+					return err
+				}
+			case encrypted || !fieldsChanged:
+				continue
+			}
+
+			dataBlock.Add(record.ID(), rotated)
+			ids = append(ids, record.ID())
+			changed = true
+		}
+
+		if changed {
+			blocks[blockFile] = dataBlock
+		}
+	}
+
+	if g.config.KeyProvider == nil && oldKey == g.config.EncryptionKey {
+		g.config.EncryptionKey = newKey
+	}
+	g.forgetKey(dataset)
+
+	//keep reading records of either key until every dataset and node has moved to newKey
+	for _, key := range []string{oldKey, newKey} {
+		if !containsKey(g.config.keyring(), key) {
+			g.config.PreviousKeys = append(g.config.PreviousKeys, key)
+		}
+	}
+	g.gitDriver.configure(g)
+
+	for blockFile, dataBlock := range blocks {
+		if err := g.writeBlock(blockFile, dataBlock); err != nil {
+			return err
+		}
+
+		//cached blocks hold records decrypted with the old keyring
+		delete(g.loadedBlocks, blockFile)
+		g.updateIndexes(g.readBlock(blockFile))
+	}
+
+	//persisted indexes are encrypted with the key of the dataset too
+	if g.config.EncryptAtRest {
+		for _, indexFile := range g.indexFiles(dataset) {
+			g.cachedIndex(indexFile)
+			g.markIndexDirty(indexFile)
+		}
+		if _, ok := g.config.FullText[dataset]; ok {
+			g.fullText(dataset)
+			g.markIndexDirty(g.ftsFile(dataset))
+		}
+	}
+
+	if err := g.flushIndex(); err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	g.commit.Add(1)
+	g.events <- newWriteEvent("Rotating encryption key of "+dataset, g.datasetPath(dataset), true, nil, "rotate", ids...)
+	g.waitForCommit()
+	log.Info(fmt.Sprintf("Rotated encryption key of %d records in %s", len(ids), dataset))
+
+	return nil
+}
+
+//rotateFields re-encrypts the fields of Schema.EncryptFields in data, a record as stored, from oldKey to newKey
+//and reports whether any changed. Fields already encrypted with newKey are left as they are
+func rotateFields(oldKey string, newKey string, data string) (string, bool, error) {
+	if !strings.Contains(data, `"`+crypto.FieldKey+`"`) {
+		return data, false, nil
+	}
+
+	var rec storedRecord
+	if err := json.Unmarshal([]byte(data), &rec); err != nil {
+		return "", false, err
+	}
+
+	changed := false
+	for field, value := range rec.Data {
+		var enc map[string]string
+		if json.Unmarshal(value, &enc) != nil || len(enc) != 1 {
+			continue
+		}
+		secureValue, ok := enc[crypto.FieldKey]
+		if !ok {
+			continue
+		}
+		if _, err := crypto.DecryptJSON(newKey, secureValue); err == nil && crypto.Versioned(secureValue) {
+			continue
+		}
+
+		dec, err := crypto.DecryptJSON(oldKey, secureValue)
+		if err != nil {
+			return "", false, fmt.Errorf("field %s: %w", field, err)
+		}
+		secureValue, err = crypto.Encrypt(newKey, dec)
+		if err != nil {
+			return "", false, err
+		}
+		if rec.Data[field], err = json.Marshal(map[string]string{crypto.FieldKey: secureValue}); err != nil {
+			return "", false, err
+		}
+		changed = true
+	}
+
+	if !changed {
+		return data, false, nil
+	}
+	rotated, err := json.Marshal(rec)
+	return string(rotated), true, err
+}
+
+func containsKey(keyring string, key string) bool {
+	for _, k := range crypto.Keys(keyring) {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package gitdb_test
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+	"github.com/gogitdb/gitdb/v2/internal/crypto"
+)
+
+func TestRotateKey(t *testing.T) {
+	cfg := getConfig()
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	m := getTestMessage()
+	if err := insert(m, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	newKey := "0123456789abcdef0123456789abcdef"
+	if err := testDb.RotateKey("Message", "wrongwrongwrongwrongwrongwrongwr", newKey); err == nil {
+		t.Error("testDb.RotateKey should fail with the wrong old key")
+	}
+
+	if err := testDb.RotateKey("Message", cfg.EncryptionKey, newKey); err != nil {
+		t.Fatalf("testDb.RotateKey failed: %s", err)
+	}
+
+	result := &Message{}
+	if err := testDb.Get(gitdb.ID(m), result); err != nil || result.Body != m.Body {
+		t.Errorf("want: %s, got: %s (%v)", m.Body, result.Body, err)
+	}
+
+	//records are only readable with the new key
+	testDb.Close()
+	cfg.EncryptionKey = newKey
+	testDb = getDbConn(t, cfg)
+
+	result = &Message{}
+	if err := testDb.Get(gitdb.ID(m), result); err != nil || result.Body != m.Body {
+		t.Errorf("want: %s, got: %s (%v)", m.Body, result.Body, err)
+	}
+
+	testDb.Close()
+	cfg.EncryptionKey = "b61ba8270ccc3c1d42b4417e7bd60b71"
+	testDb = getDbConn(t, cfg)
+
+	result = &Message{}
+	if err := testDb.Get(gitdb.ID(m), result); err == nil && result.Body == m.Body {
+		t.Error("record should not be readable with the old key")
+	}
+
+	//a keyring of historical keys reads records of either key
+	testDb.Close()
+	cfg.PreviousKeys = []string{newKey}
+	testDb = getDbConn(t, cfg)
+
+	result = &Message{}
+	if err := testDb.Get(gitdb.ID(m), result); err != nil || result.Body != m.Body {
+		t.Errorf("want: %s, got: %s (%v)", m.Body, result.Body, err)
+	}
+}
+
+//editMessageBlock rewrites the stored data of record id behind gitdb's back
+func editMessageBlock(t *testing.T, id string, edit func(data string) string) {
+	t.Helper()
+	blockFile := filepath.Join(dbPath, "data", "Message", "b0.json")
+	data, err := ioutil.ReadFile(blockFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := map[string]string{}
+	if err := json.Unmarshal(data, &block); err != nil {
+		t.Fatal(err)
+	}
+	block[id] = edit(block[id])
+	if data, err = json.MarshalIndent(block, "", "\t"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(blockFile, data, 0744); err != nil {
+		t.Fatal(err)
+	}
+	if err := testDb.RebuildIndex("Message"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuthenticatedEncryption(t *testing.T) {
+	cfg := getConfig()
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	m := getTestMessage()
+	if err := insert(m, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	var plainText string
+	editMessageBlock(t, gitdb.ID(m), func(data string) string {
+		if !strings.HasPrefix(data, "v2:") {
+			t.Errorf("want: versioned ciphertext, got: %.10s...", data)
+		}
+		plainText, _ = crypto.Decrypt(cfg.EncryptionKey, data)
+		//flip a bit of the sealed message
+		sealed, _ := base64.URLEncoding.DecodeString(strings.TrimPrefix(data, "v2:"))
+		sealed[len(sealed)/2] ^= 1
+		return "v2:" + base64.URLEncoding.EncodeToString(sealed)
+	})
+
+	if err := testDb.Get(gitdb.ID(m), &Message{}); !errors.Is(err, crypto.ErrAuthFailed) {
+		t.Errorf("want: %s, got: %v", crypto.ErrAuthFailed, err)
+	}
+
+	//records encrypted with AES-CFB by earlier versions are still readable
+	editMessageBlock(t, gitdb.ID(m), func(string) string {
+		block, _ := aes.NewCipher([]byte(cfg.EncryptionKey))
+		cipherText := make([]byte, aes.BlockSize+len(plainText))
+		cipher.NewCFBEncrypter(block, cipherText[:aes.BlockSize]).XORKeyStream(cipherText[aes.BlockSize:], []byte(plainText))
+		return base64.URLEncoding.EncodeToString(cipherText)
+	})
+
+	result := &Message{}
+	if err := testDb.Get(gitdb.ID(m), result); err != nil || result.Body != m.Body {
+		t.Errorf("want: %s, got: %s (%v)", m.Body, result.Body, err)
+	}
+
+	//and are upgraded by rotating to the same key
+	if err := testDb.RotateKey("Message", cfg.EncryptionKey, cfg.EncryptionKey); err != nil {
+		t.Fatalf("testDb.RotateKey failed: %s", err)
+	}
+	editMessageBlock(t, gitdb.ID(m), func(data string) string {
+		if !strings.HasPrefix(data, "v2:") {
+			t.Errorf("want: versioned ciphertext, got: %.10s...", data)
+		}
+		return data
+	})
+}
+
+func TestEncryptAtRest(t *testing.T) {
+	cfg := getConfig()
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	blockFile := filepath.Join(dbPath, "data", "Credential", "b0.json")
+	plain := &Credential{Username: "ada", Password: "s3cret-pass", Token: "tok-1"}
+	if err := testDb.Insert(plain); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+
+	testDb.Close()
+	cfg.EncryptAtRest = true
+	testDb = getDbConn(t, cfg)
+
+	c := &Credential{Username: "bob", Password: "hunter2-pass", Token: "tok-2"}
+	if err := testDb.Insert(c); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+
+	data, err := ioutil.ReadFile(blockFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), c.Password) || !strings.Contains(string(data), plain.Password) {
+		t.Errorf("want: only the new record encrypted, got: %s", data)
+	}
+
+	//rotating to the same key encrypts the records written before
+	if err := testDb.RotateKey("Credential", cfg.EncryptionKey, cfg.EncryptionKey); err != nil {
+		t.Fatalf("testDb.RotateKey failed: %s", err)
+	}
+	if data, _ = ioutil.ReadFile(blockFile); strings.Contains(string(data), plain.Password) {
+		t.Errorf("want: every record encrypted, got: %s", data)
+	}
+
+	for _, want := range []*Credential{plain, c} {
+		result := &Credential{}
+		if err := testDb.Get(gitdb.ID(want), result); err != nil || result.Password != want.Password {
+			t.Errorf("want: %s, got: %s (%v)", want.Password, result.Password, err)
+		}
+	}
+
+	//the persisted indexes are encrypted too
+	data, err = ioutil.ReadFile(filepath.Join(dbPath, ".gitdb", "index", "Credential", "Token.json"))
+	if err != nil || strings.Contains(string(data), plain.Token) || strings.Contains(string(data), c.Token) {
+		t.Errorf("want: Token index encrypted, got: %s (%v)", data, err)
+	}
+	records, err := testDb.Search("Credential", []*gitdb.SearchParam{{Index: "Token", Value: c.Token}}, gitdb.SearchEquals)
+	if err != nil || len(records) != 1 {
+		t.Errorf("want: 1 result, got: %d (%v)", len(records), err)
+	}
+}
+
+func TestRotateKeyEncryptedFields(t *testing.T) {
+	cfg := getConfig()
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	p := &Patient{PatientId: 1, Name: "Ada Obi", SSN: "078-05-1120"}
+	if err := testDb.Insert(p); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+
+	testDb.Close()
+	cfg.EncryptAtRest = true
+	testDb = getDbConn(t, cfg)
+
+	newKey := "0123456789abcdef0123456789abcdef"
+	if err := testDb.RotateKey("Patient", cfg.EncryptionKey, newKey); err != nil {
+		t.Fatalf("testDb.RotateKey failed: %s", err)
+	}
+
+	//the encrypted fields inside the record are rotated along with it
+	testDb.Close()
+	cfg.EncryptionKey = newKey
+	testDb = getDbConn(t, cfg)
+
+	result := &Patient{}
+	if err := testDb.Get(gitdb.ID(p), result); err != nil || result.SSN != p.SSN {
+		t.Errorf("want: %s, got: %s (%v)", p.SSN, result.SSN, err)
+	}
+}
@@ -18,6 +18,30 @@ type Schema struct {
 	block   string
 	record  string
 	indexes map[string]interface{}
+	unique  []string
+	//required holds the indexes that can't be empty
+	required []string
+	//composites holds the fields of each composite index
+	composites [][]string
+	//paths holds the indexes that are dotted paths into the model
+	paths []string
+	//geo holds the lat and lng fields of the geo index
+	geo []string
+	//refs holds references to other datasets
+	refs []ref
+	//collations holds the collation of indexes
+	collations map[string]Collation
+	//encrypted holds the fields encrypted inside the record
+	encrypted []string
+	//blind holds the indexes stored as HMACs
+	blind map[string]bool
+	//redacted holds the fields masked in the UI, audit log and errors
+	redacted []string
+	//defaults and computed hold the fields set on insert
+	defaults []fieldValue
+	computed []fieldValue
+	//autoID is the field filled in with a generated id
+	autoID *autoID
 
 	internal bool
 }
@@ -31,6 +55,50 @@ func newSchema(name, block, record string, indexes map[string]interface{}) *Sche
 	return &Schema{dataset: name, block: block, record: record, indexes: indexes, internal: true}
 }
 
+//compositeSep separates the field values of a composite index
+const compositeSep = "\x1f"
+
+//Index adds a composite index over fields, which must already be indexes of the schema.
+//The index is named after the fields joined by "+" and its values are built with Composite
+//so records sort by the first field, then the second and so on.
+//Fields can also be dotted paths into nested structs and maps of the model e.g Customer.Email,
+//which are indexed under the path. Index("Customer.Email") adds just the path index
+func (a *Schema) Index(fields ...string) *Schema {
+	if a.indexes == nil {
+		a.indexes = map[string]interface{}{}
+	}
+
+	for _, field := range fields {
+		if _, ok := a.indexes[field]; !ok && isPath(field) {
+			//the value is read from the model when gitdb reads the schema
+			a.indexes[field] = nil
+			a.paths = append(a.paths, field)
+		}
+	}
+	if len(fields) == 1 && isPath(fields[0]) {
+		return a
+	}
+
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		values[i] = a.indexes[field]
+	}
+
+	a.indexes[strings.Join(fields, "+")] = Composite(values...)
+	a.composites = append(a.composites, fields)
+	return a
+}
+
+//Composite returns the value of a composite index for values in field order.
+//Pass fewer values than fields to SearchStartsWith on the leading fields
+func Composite(values ...interface{}) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = fmt.Sprint(value)
+	}
+	return strings.Join(parts, compositeSep)
+}
+
 //name returns name of schema
 func (a *Schema) name() string {
 	return a.dataset
@@ -52,7 +120,7 @@ func (a *Schema) Validate() error {
 		return errors.New("Invalid Schema Name")
 	}
 
-	if !a.internal && strings.Contains("gitdb,bucket,upload", strings.ToLower(a.dataset)) {
+	if !a.internal && (strings.Contains("gitdb,bucket,upload", strings.ToLower(a.dataset)) || a.dataset == auditDataset || a.dataset == locksDataset) {
 		return fmt.Errorf("%s is a reserved Schema Name", a.dataset)
 	}
 
@@ -68,12 +136,49 @@ func (a *Schema) Validate() error {
 		return fmt.Errorf("%s is a reserved index name", "id")
 	}
 
+	if err := a.validateGeo(); err != nil {
+		return err
+	}
+
+	if err := a.validateRefs(); err != nil {
+		return err
+	}
+
+	if err := a.validateCollations(); err != nil {
+		return err
+	}
+
+	if err := a.validateEncryptedFields(); err != nil {
+		return err
+	}
+
+	if err := a.validateBlindIndexes(); err != nil {
+		return err
+	}
+
+	for _, fields := range a.composites {
+		if len(fields) < 2 {
+			return errors.New("composite index needs at least 2 fields")
+		}
+		for _, field := range fields {
+			if _, ok := a.indexes[field]; !ok {
+				return fmt.Errorf("composite index field %s is not an index of %s", field, a.dataset)
+			}
+		}
+	}
+
+	for _, name := range a.unique {
+		if _, ok := a.indexes[name]; !ok {
+			return fmt.Errorf("unique index %s is not an index of %s", name, a.dataset)
+		}
+	}
+
 	return nil
 }
 
 //Indexes returns the index map of a given Model
 func Indexes(m Model) map[string]interface{} {
-	return m.GetSchema().indexes
+	return schemaOf(m).indexes
 }
 
 //ID returns the id of a given Model
@@ -117,8 +222,10 @@ func AutoBlock(dbPath string, m Model, method BlockMethod, n int64) string {
 		n = 1000
 	}
 
+	//resolves the paths of the database at dbPath
+	db := &gitdb{config: Config{DbPath: dbPath}}
 	dataset := m.GetSchema().name()
-	fullPath := filepath.Join(dbPath, "data", dataset)
+	fullPath := db.datasetPath(dataset)
 
 	if _, err := os.Stat(fullPath); err != nil {
 		return fmt.Sprintf("b%d", currentBlock)
@@ -131,7 +238,15 @@ func AutoBlock(dbPath string, m Model, method BlockMethod, n int64) string {
 		return ""
 	}
 
-	if len(files) == 0 {
+	//the last block is always read as its record count decides whether a new block is needed
+	var lastBlockFile string
+	for _, file := range files {
+		if isBlockFile(file.Name()) {
+			lastBlockFile = file.Name()
+		}
+	}
+
+	if len(lastBlockFile) == 0 {
 		log.Test("AutoBlock: no blocks found at " + fullPath)
 		return fmt.Sprintf("b%d", currentBlock)
 	}
@@ -139,11 +254,21 @@ func AutoBlock(dbPath string, m Model, method BlockMethod, n int64) string {
 	currentBlock = -1
 	for _, currentBlockFile = range files {
 		currentBlockFileName := filepath.Join(fullPath, currentBlockFile.Name())
-		if filepath.Ext(currentBlockFileName) != ".json" {
+		if !isBlockFile(currentBlockFileName) {
 			continue
 		}
 
 		currentBlock++
+		block := strings.Replace(filepath.Base(currentBlockFileName), filepath.Ext(currentBlockFileName), "", 1)
+		id := fmt.Sprintf("%s/%s/%s", dataset, block, m.GetSchema().record)
+
+		//skip reading blocks whose bloom filter rules the model out
+		if currentBlockFile.Name() != lastBlockFile {
+			if bloom := readBloom(db.bloomFile(dataset, block)); bloom != nil && !bloom.mightContain(id) {
+				continue
+			}
+		}
+
 		//TODO OPTIMIZE read file
 		b, err := ioutil.ReadFile(currentBlockFileName)
 		if err != nil {
@@ -158,9 +283,6 @@ func AutoBlock(dbPath string, m Model, method BlockMethod, n int64) string {
 			continue
 		}
 
-		block := strings.Replace(filepath.Base(currentBlockFileName), filepath.Ext(currentBlockFileName), "", 1)
-		id := fmt.Sprintf("%s/%s/%s", dataset, block, m.GetSchema().record)
-
 		log.Test("AutoBlock: searching for  - " + id)
 		//model already exists return its block
 		if _, ok := currentBlockrecords[id]; ok {
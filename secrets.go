@@ -0,0 +1,121 @@
+package gitdb
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/bouggo/log"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//DefaultSecretPatterns matches common API keys, private keys and card numbers that pass the Luhn check. Use it, or a copy
+//with patterns of your own added, as Config.SecretPatterns
+var DefaultSecretPatterns = map[string]*regexp.Regexp{
+	"AWS access key": regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`),
+	"GitHub token":   regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36}\b`),
+	"Slack token":    regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`),
+	"Stripe key":     regexp.MustCompile(`\b[rs]k_live_[A-Za-z0-9]{16,}\b`),
+	"private key":    regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`),
+	"card number":    cardNumber,
+}
+
+var cardNumber = regexp.MustCompile(`\b(4\d{3}|5[1-5]\d{2}|3[47]\d{2}|6011)[ -]?\d{4}[ -]?\d{4}[ -]?\d{1,4}\b`)
+
+//secretChecks confirm the matches of patterns that also match harmless values e.g order numbers shaped like card numbers
+var secretChecks = map[*regexp.Regexp]func(match string) bool{
+	cardNumber: luhnValid,
+}
+
+//luhnValid reports whether the digits of number pass the Luhn check every card number passes
+func luhnValid(number string) bool {
+	sum, digits := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		d := int(c - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits > 0 && sum%10 == 0
+}
+
+//ErrSecretDetected is returned when a record matches one of Config.SecretPatterns
+type ErrSecretDetected struct {
+	ID string
+	//Pattern is the name of the pattern the record matched
+	Pattern string
+}
+
+func (e *ErrSecretDetected) Error() string {
+	return fmt.Sprintf("%s looks like it contains a %s", e.ID, e.Pattern)
+}
+
+//scanBlock checks the records of block, as they will be committed in data, against Config.SecretPatterns. Every
+//write of a block goes through writeBlock so records are caught however they are written e.g by RotateKey or Migrate
+func (g *gitdb) scanBlock(block *db.Block, data []byte) error {
+	//most blocks match nothing so the records are only scanned one by one to find which one matched
+	if len(g.config.SecretPatterns) == 0 || len(g.secretIn(string(data))) == 0 {
+		return nil
+	}
+
+	for _, record := range block.Records() {
+		if err := g.scanSecrets(record.ID(), record.Data()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//scanSecrets checks record id, as it will be committed, against Config.SecretPatterns. Records and
+//fields that are encrypted can't match so only data that would reach the repository in the clear is caught
+func (g *gitdb) scanSecrets(id string, record string) error {
+	name := g.secretIn(record)
+	if len(name) == 0 {
+		return nil
+	}
+
+	err := &ErrSecretDetected{ID: id, Pattern: name}
+	if !g.config.WarnOnSecrets {
+		return err
+	}
+	log.Error("Warning: " + err.Error())
+	return nil
+}
+
+//secretIn returns the name of the first of Config.SecretPatterns s matches, or "" if it matches none
+func (g *gitdb) secretIn(s string) string {
+	//scan in a fixed order so the same record always reports the same pattern
+	names := make([]string, 0, len(g.config.SecretPatterns))
+	for name := range g.config.SecretPatterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		pattern := g.config.SecretPatterns[name]
+		check, ok := secretChecks[pattern]
+		if !ok {
+			if pattern.MatchString(s) {
+				return name
+			}
+			continue
+		}
+
+		for _, match := range pattern.FindAllString(s, -1) {
+			if check(match) {
+				return name
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,71 @@
+package gitdb
+
+import "time"
+
+//session is a view of a gitdb connection that attributes every change it makes to user
+type session struct {
+	*gitdb
+	user *User
+}
+
+//WithUser returns a view of the connection which commits changes as name <email>
+//instead of Config.User e.g db.WithUser("Jane", "jane@example.com").Insert(m)
+func (g *gitdb) WithUser(name string, email string) GitDb {
+	return &session{gitdb: g, user: NewUser(name, email)}
+}
+
+func (s *session) Insert(m Model) error {
+	return s.gitdb.insert(m, s.user)
+}
+
+func (s *session) InsertRevision(m Model, revision int) error {
+	return s.gitdb.insertRevision(m, revision, s.user)
+}
+
+func (s *session) Update(m Model) error {
+	return s.gitdb.update(m, s.user)
+}
+
+func (s *session) InsertMany(models []Model) error {
+	return s.gitdb.insertMany(models, s.user)
+}
+
+func (s *session) InsertAsync(m Model, done func(err error)) {
+	s.gitdb.insertAsync(m, done, s.user)
+}
+
+func (s *session) Delete(id string) error {
+	return s.gitdb.dodelete(id, false, s.user)
+}
+
+func (s *session) DeleteOrFail(id string) error {
+	return s.gitdb.dodelete(id, true, s.user)
+}
+
+func (s *session) Lock(m Model) error {
+	return s.gitdb.lock(m, s.user)
+}
+
+func (s *session) TryLock(m Model) (bool, error) {
+	return s.gitdb.tryLock(m, s.user)
+}
+
+func (s *session) LockWait(m Model, timeout time.Duration) error {
+	return s.gitdb.lockWait(m, timeout, s.user)
+}
+
+func (s *session) Unlock(m Model) error {
+	return s.gitdb.unlock(m, s.user)
+}
+
+func (s *session) RevertRecord(id string, commit string) error {
+	return s.gitdb.revertRecord(id, commit, s.user)
+}
+
+func (s *session) StartTransaction(name string) Transaction {
+	return s.gitdb.startTransaction(name, s.user)
+}
+
+func (s *session) SetDatasetMeta(dataset string, meta *DatasetMeta) error {
+	return s.gitdb.setDatasetMeta(dataset, meta, s.user)
+}
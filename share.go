@@ -0,0 +1,84 @@
+package gitdb
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var errBadShareLink = errors.New("Invalid share link")
+
+//shareToken is what a share link gives access to, signed with Config.ShareKey
+type shareToken struct {
+	Dataset string `json:"d"`
+	Commit  string `json:"c"`
+	//Expires is when the link stops working in seconds since the epoch
+	Expires int64 `json:"e"`
+}
+
+//ShareLink returns the path under Config.UIBasePath of a link to a read-only view of dataset as it was at commit,
+//which can also be exported as CSV or JSON, e.g to share a snapshot with an auditor who has no access to the
+//repository. commit is a hash, tag or branch and defaults to the current commit. The link stops working after ttl
+//and is signed with Config.ShareKey, so changing the key revokes every link given out
+func (g *gitdb) ShareLink(dataset string, commit string, ttl time.Duration) (string, error) {
+	if len(g.config.ShareKey) == 0 {
+		return "", errors.New("ShareLink requires Config.ShareKey")
+	}
+	if ttl <= 0 {
+		return "", errors.New("Share links must expire")
+	}
+	if len(commit) == 0 {
+		commit = "HEAD"
+	}
+
+	hash, err := g.gitDriver.revParse(commit + "^{commit}")
+	if err != nil {
+		return "", errors.New("Unknown commit: " + commit)
+	}
+	files, err := g.gitDriver.lsTree(hash, dataset+"/", false)
+	if err != nil || len(files) == 0 {
+		return "", errors.New("Dataset " + dataset + " does not exist at " + commit)
+	}
+
+	payload, err := json.Marshal(&shareToken{Dataset: dataset, Commit: hash, Expires: time.Now().Add(ttl).Unix()})
+	if err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(payload)
+	return strings.TrimSuffix(g.config.UIBasePath, "/") + "/snapshot/" + token + "." + g.shareMAC(token), nil
+}
+
+//openShare returns what the token of a share link gives access to if it was signed with Config.ShareKey and hasn't expired
+func (g *gitdb) openShare(token string) (*shareToken, error) {
+	if len(g.config.ShareKey) == 0 {
+		return nil, errBadShareLink
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(g.shareMAC(parts[0]))) {
+		return nil, errBadShareLink
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errBadShareLink
+	}
+	share := &shareToken{}
+	if err := json.Unmarshal(payload, share); err != nil {
+		return nil, errBadShareLink
+	}
+	if time.Now().Unix() >= share.Expires {
+		return nil, errors.New("Share link has expired")
+	}
+	return share, nil
+}
+
+//shareMAC returns the signature of the payload of a share link
+func (g *gitdb) shareMAC(payload string) string {
+	mac := hmac.New(sha256.New, []byte(g.config.ShareKey))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
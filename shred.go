@@ -0,0 +1,132 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/bouggo/log"
+)
+
+//Shred deletes record id and erases it, its copies in views and its audit entries from every commit
+//of every branch and tag so it can not be recovered from the history of the database e.g to honour a
+//request to erase personal data. Commits are rewritten so, like SquashHistory, Shred force pushes to
+//the online remote and requires Config.AllowForcePush when one is set
+func (g *gitdb) Shred(id string) error {
+	return g.shred(id, nil)
+}
+
+func (g *gitdb) shred(id string, user *User) error {
+	if err := g.writable(); err != nil {
+		return err
+	}
+
+	if len(g.config.OnlineRemote) > 0 && !g.config.AllowForcePush {
+		return errors.New("Shred requires Config.AllowForcePush when an online remote is set")
+	}
+
+	dataset, block, recordID, err := ParseID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := g.dodelete(id, false, user); err != nil {
+		return err
+	}
+
+	//ids to erase by block file relative to the repository
+	files := map[string][]string{}
+	files[dataset+"/"+block+".json"] = []string{id}
+	for _, view := range g.viewsOf(dataset) {
+		files[view+"/"+block+".json"] = []string{view + "/" + block + "/" + recordID}
+	}
+
+	if g.config.Audit {
+		entries, err := g.SearchIDs(auditDataset, "Record", id)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := g.deleteQueued(auditDataset, entry, false, user); err != nil {
+				return err
+			}
+			_, entryBlock, _, _ := ParseID(entry)
+			file := auditDataset + "/" + entryBlock + ".json"
+			files[file] = append(files[file], entry)
+		}
+	}
+
+	if err := g.eraseHistory(files); err != nil {
+		return err
+	}
+
+	g.audit(OpShred, id, "", "", false, user)
+	g.publishWrite(OpShred, id)
+	return nil
+}
+
+//eraseHistory removes the records of files, ids by block file relative to the repository,
+//from every version of the files and drops the commits they were in
+func (g *gitdb) eraseHistory(files map[string][]string) error {
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	paths := make([]string, 0, len(files))
+	for file := range files {
+		paths = append(paths, file)
+	}
+
+	erased, err := g.gitDriver.rewriteHistory(paths, func(file string, data []byte) ([]byte, error) {
+		return scrubBlock(data, files[file])
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info(fmt.Sprintf("Erased records from %d versions of %d block files", erased, len(files)))
+
+	if len(g.config.OnlineRemote) > 0 && erased > 0 {
+		if err := g.forcePushAll(); err != nil {
+			return err
+		}
+	}
+
+	return g.gitDriver.prune()
+}
+
+//viewsOf returns the views materialized from dataset, including views of those views
+func (g *gitdb) viewsOf(dataset string) []string {
+	var views []string
+	for name, q := range g.meta().Views {
+		if q.Dataset == dataset {
+			views = append(views, name)
+			views = append(views, g.viewsOf(name)...)
+		}
+	}
+	return views
+}
+
+//scrubBlock returns the content of a block file without the records ids
+func scrubBlock(data []byte, ids []string) ([]byte, error) {
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		//not a block GitDB wrote so there is nothing to scrub
+		return data, nil
+	}
+
+	scrubbed := false
+	for _, id := range ids {
+		if _, ok := raw[id]; ok {
+			delete(raw, id)
+			scrubbed = true
+		}
+	}
+
+	if !scrubbed {
+		return data, nil
+	}
+	return json.MarshalIndent(raw, "", "\t")
+}
@@ -0,0 +1,103 @@
+package gitdb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+)
+
+//commitSignature is the signature status of a commit as reported by git
+type commitSignature struct {
+	hash    string
+	status  string
+	subject string
+}
+
+//UnverifiedCommit is a commit whose signature could not be verified
+type UnverifiedCommit struct {
+	Hash    string
+	Subject string
+	Reason  string
+}
+
+//signatureProblems maps git's %G? signature codes to the reason a commit is not verified
+var signatureProblems = map[string]string{
+	"N": "unsigned",
+	"B": "bad signature",
+	"U": "good signature made by an untrusted key",
+	"X": "good signature that has expired",
+	"Y": "good signature made by an expired key",
+	"R": "good signature made by a revoked key",
+	"E": "signature cannot be checked",
+}
+
+//setupSigning configures the repository to sign every commit with Config.SigningKey.
+//A SigningKey that points to a file is used as an SSH key, otherwise it is treated as a GPG key ID
+func (g *gitdb) setupSigning() error {
+	key := g.config.SigningKey
+	if _, err := os.Stat(key); err != nil {
+		return g.gitDriver.configureSigning("openpgp", key, "")
+	}
+
+	key, err := filepath.Abs(key)
+	if err != nil {
+		return err
+	}
+
+	pubKey, err := sshPublicKey(key)
+	if err != nil {
+		return err
+	}
+
+	//trust the signing key for any committer so VerifyHistory can check ssh signatures
+	allowedSigners := filepath.Join(g.sshDir(), "allowed_signers")
+	if err := ioutil.WriteFile(allowedSigners, []byte(`* namespaces="git" `+string(pubKey)), 0644); err != nil {
+		return err
+	}
+
+	return g.gitDriver.configureSigning("ssh", key, allowedSigners)
+}
+
+//sshPublicKey returns the authorized_keys form of the public key of privateKeyFile
+func sshPublicKey(privateKeyFile string) ([]byte, error) {
+	if pubKey, err := ioutil.ReadFile(privateKeyFile + ".pub"); err == nil {
+		return pubKey, nil
+	}
+
+	pk, err := ioutil.ReadFile(privateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(pk)
+	if err != nil {
+		return nil, err
+	}
+
+	return ssh.MarshalAuthorizedKey(signer.PublicKey()), nil
+}
+
+//VerifyHistory returns every commit that touched dataset and is unsigned or badly signed.
+//Pass an empty dataset to verify the history of the whole database
+func (g *gitdb) VerifyHistory(dataset string) ([]UnverifiedCommit, error) {
+	path := "."
+	if len(dataset) > 0 {
+		path = dataset
+	}
+
+	sigs, err := g.gitDriver.signatures(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var unverified []UnverifiedCommit
+	for _, sig := range sigs {
+		if reason, ok := signatureProblems[sig.status]; ok {
+			unverified = append(unverified, UnverifiedCommit{Hash: sig.hash, Subject: sig.subject, Reason: reason})
+		}
+	}
+
+	return unverified, nil
+}
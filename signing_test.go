@@ -0,0 +1,127 @@
+package gitdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestVerifyHistory(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen is required to sign commits")
+	}
+
+	//make an unsigned commit before signing is configured
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	if err := insert(getTestMessage(), false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+	testDb.Close()
+
+	key := filepath.Join(testData, "signing_key")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen failed: %s", out)
+	}
+
+	cfg := getConfig()
+	cfg.SigningKey = key
+	testDb = getDbConn(t, cfg)
+
+	if err := insert(getTestMessage(), false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	unverified, err := testDb.VerifyHistory("Message")
+	if err != nil {
+		t.Fatalf("testDb.VerifyHistory failed: %s", err)
+	}
+
+	if len(unverified) != 1 || unverified[0].Reason != "unsigned" {
+		t.Errorf("want: 1 unsigned commit, got: %v", unverified)
+	}
+
+	//tamper with the signing setup so good signatures can no longer be checked
+	if err := os.Remove(filepath.Join(dbPath, ".gitdb", "ssh", "allowed_signers")); err != nil {
+		t.Fatal(err)
+	}
+
+	unverified, err = testDb.VerifyHistory("")
+	if err != nil {
+		t.Fatalf("testDb.VerifyHistory failed: %s", err)
+	}
+
+	if len(unverified) != 2 {
+		t.Errorf("want: 2 unverified commits, got: %v", unverified)
+	}
+}
+
+func TestSquashHistorySigned(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen is required to sign commits")
+	}
+
+	key := filepath.Join(os.TempDir(), "gitdb_squash_signing_key")
+	os.Remove(key)
+	os.Remove(key + ".pub")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen failed: %s", out)
+	}
+	defer os.Remove(key)
+	defer os.Remove(key + ".pub")
+
+	cfg := getConfig()
+	cfg.SigningKey = key
+	cfg.SyncMode = gitdb.SyncManual
+	cfg.AllowForcePush = true
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	for i := 1; i <= 3; i++ {
+		if err := insert(getTestMessageWithId(i), false); err != nil {
+			t.Fatalf("insert failed: %s", err)
+		}
+	}
+
+	//commit times have a resolution of a second
+	time.Sleep(time.Second)
+	before := time.Now()
+	time.Sleep(time.Second)
+	if err := insert(getTestMessageWithId(4), false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	if err := testDb.SquashHistory(before); err != nil {
+		t.Fatalf("testDb.SquashHistory failed: %s", err)
+	}
+
+	unverified, err := testDb.VerifyHistory("")
+	if err != nil {
+		t.Fatalf("testDb.VerifyHistory failed: %s", err)
+	}
+	if len(unverified) != 0 {
+		t.Errorf("want: squashed history signed, got: %v", unverified)
+	}
+}
+
+func TestInvalidSigningKey(t *testing.T) {
+	cfg := getConfig()
+	cfg.SigningKey = filepath.Join(testData, "bad_key")
+	if err := os.MkdirAll(testData, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(cfg.SigningKey, []byte("not a key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testData)
+
+	if _, err := gitdb.Open(cfg); err == nil {
+		t.Errorf("gitdb.Open should fail with an invalid SigningKey")
+	}
+}
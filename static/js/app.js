@@ -1,6 +1,8 @@
 window.addEventListener('load', (event) => {
     makeDatasetRowsClickable();
     makeRecordRowsClickable();
+    checkJSONEditors();
+    followChanges();
 });
 
 function makeDatasetRowsClickable() {
@@ -17,4 +19,41 @@ function makeRecordRowsClickable() {
             window.location = row.dataset.view
         });
     })
-}
\ No newline at end of file
+}
+
+function checkJSONEditors() {
+    document.querySelectorAll('textarea.json').forEach(editor => {
+        const status = document.getElementById(editor.dataset.status);
+        const check = () => {
+            try {
+                JSON.parse(editor.value);
+                status.textContent = 'Valid JSON';
+                status.className = 'help';
+            } catch (e) {
+                status.textContent = e.message;
+                status.className = 'error';
+            }
+        };
+        editor.addEventListener('input', check);
+        check();
+    })
+}
+
+function followChanges() {
+    const url = document.body.dataset.events;
+    if (!url || !window.EventSource) {
+        return;
+    }
+
+    let reload;
+    new EventSource(url).addEventListener('change', event => {
+        //don't reload under someone typing a search
+        const active = document.activeElement;
+        if (active && ['INPUT', 'SELECT', 'TEXTAREA'].includes(active.tagName)) {
+            document.getElementById('liveChanges').hidden = false;
+            return;
+        }
+        clearTimeout(reload);
+        reload = setTimeout(() => window.location.reload(), 500);
+    });
+}
@@ -0,0 +1,100 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//largestRecords is how many of the largest records of each dataset Stats returns
+const largestRecords = 5
+
+//Stats describes the datasets of a database and the size of its repository
+type Stats struct {
+	Datasets []*DatasetStats
+	//Size is the size of the block files of every dataset in bytes
+	Size int64
+	//RepoSize is the size of the objects of the git repository in bytes
+	RepoSize int64
+}
+
+//DatasetStats describes the records and blocks of a dataset
+type DatasetStats struct {
+	Name    string
+	Records int
+	Blocks  int
+	//Size is the size of the block files of the dataset in bytes
+	Size       int64
+	BadBlocks  int
+	BadRecords int
+	//Largest are the largest records of the dataset as stored, largest first
+	Largest []RecordSize
+}
+
+//RecordSize is the size of a record as stored in bytes
+type RecordSize struct {
+	ID   string
+	Size int
+}
+
+//Stats counts the records, blocks and bytes of every dataset, the blocks and records that can't be read,
+//and finds the largest records of each dataset
+func (g *gitdb) Stats() (*Stats, error) {
+	datasets, err := g.datasetNames()
+	if err != nil {
+		return nil, err
+	}
+	return g.stats(datasets)
+}
+
+//stats returns the Stats of datasets
+func (g *gitdb) stats(datasets []string) (*Stats, error) {
+	stats := &Stats{RepoSize: dirSize(filepath.Join(g.dbDir(), ".git", "objects"))}
+	for _, dataset := range datasets {
+		ds, err := g.datasetStats(dataset)
+		if err != nil {
+			return nil, err
+		}
+		stats.Datasets = append(stats.Datasets, ds)
+		stats.Size += ds.Size
+	}
+	return stats, nil
+}
+
+//datasetStats returns the DatasetStats of dataset
+func (g *gitdb) datasetStats(dataset string) (*DatasetStats, error) {
+	blockFiles, err := g.blockFiles(dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &DatasetStats{Name: dataset}
+	for _, blockFile := range blockFiles {
+		stats.Blocks++
+		data, err := g.readBlockFile(blockFile)
+		if err != nil {
+			return nil, err
+		}
+		stats.Size += int64(len(data))
+		if json.Unmarshal(data, &map[string]string{}) != nil {
+			stats.BadBlocks++
+			continue
+		}
+
+		for _, record := range db.ParseBlock(blockFile, g.keyring(dataset), data).Records() {
+			stats.Records++
+			stats.Largest = append(stats.Largest, RecordSize{ID: record.ID(), Size: len(record.Data())})
+			if record.Check() != nil {
+				stats.BadRecords++
+			}
+		}
+	}
+
+	sort.SliceStable(stats.Largest, func(i, j int) bool { return stats.Largest[i].Size > stats.Largest[j].Size })
+	if len(stats.Largest) > largestRecords {
+		stats.Largest = stats.Largest[:largestRecords]
+	}
+	return stats, nil
+}
@@ -0,0 +1,44 @@
+package gitdb_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	cfg := getConfig()
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	insert(getTestMessageWithId(1), true)
+	m := getTestMessageWithId(2)
+	m.Body = "a longer body than the other message has"
+	insert(m, true)
+
+	stats, err := testDb.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %s", err)
+	}
+	if len(stats.Datasets) != 1 || stats.RepoSize == 0 || stats.Size != stats.Datasets[0].Size {
+		t.Fatalf("want: stats of Message and the repository, got: %+v", stats)
+	}
+	ds := stats.Datasets[0]
+	if ds.Name != "Message" || ds.Records != 2 || ds.Blocks != 1 || ds.BadRecords != 0 || len(ds.Largest) != 2 || ds.Largest[0].ID != "Message/b0/2" {
+		t.Errorf("want: 2 records of Message in 1 block, Message/b0/2 the largest, got: %+v", ds)
+	}
+
+	blockFile := filepath.Join(cfg.DbPath, "data", "Message", "b0.json")
+	data, _ := ioutil.ReadFile(blockFile)
+	var records map[string]string
+	json.Unmarshal(data, &records)
+	records["Message/b0/1"] = `{"MessageId": 1,`
+	data, _ = json.Marshal(records)
+	ioutil.WriteFile(blockFile, data, 0744)
+
+	stats, err = testDb.Stats()
+	if err != nil || stats.Datasets[0].BadRecords != 1 {
+		t.Errorf("want: 1 bad record, got: %+v %v", stats.Datasets[0], err)
+	}
+}
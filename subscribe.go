@@ -0,0 +1,120 @@
+package gitdb
+
+//EventKind is a kind of change Subscribe handlers are called for. Kinds are combined with | e.g
+//EventInsert|EventDelete
+type EventKind int
+
+const (
+	//EventInsert is a record inserted through this connection
+	EventInsert EventKind = 1 << iota
+	//EventUpdate is a record updated through this connection
+	EventUpdate
+	//EventDelete is a record deleted or shredded through this connection
+	EventDelete
+	//EventSync is a record changed by another node and pulled in by a sync
+	EventSync
+
+	//EventAll is every kind of change
+	EventAll = EventInsert | EventUpdate | EventDelete | EventSync
+)
+
+//Event is a change to a record passed to Subscribe handlers
+type Event struct {
+	Kind    EventKind
+	Dataset string
+	ID      string
+	//Op is the change made to the record. For EventSync it is the change the other node made
+	Op Op
+	//SHA is the commit the change is in, or the commit a sync brought the database to for EventSync.
+	//It is empty for changes made in a transaction as they are committed later
+	SHA string
+}
+
+//EventHandler is called with every change a subscription is for
+type EventHandler func(e Event)
+
+type subscription struct {
+	kinds   EventKind
+	handler EventHandler
+}
+
+//Subscribe registers handler to be called for every change of kinds to any dataset e.g
+//
+//	db.Subscribe(gitdb.EventInsert|gitdb.EventDelete, func(e gitdb.Event) { cache.Forget(e.ID) })
+//
+//Handlers are called one at a time on the goroutine that made the change once it is committed,
+//so they can write to the database but should hand slow work off
+func (g *gitdb) Subscribe(kinds EventKind, handler EventHandler) {
+	g.watchMu.Lock()
+	defer g.watchMu.Unlock()
+
+	g.subscriptions = append(g.subscriptions, subscription{kinds: kinds, handler: handler})
+}
+
+//publish adds op made to record id in commit sha to the change feed and calls the handlers subscribed to changes of kind
+func (g *gitdb) publish(kind EventKind, op Op, id string, sha string) {
+	dataset, _, _, err := ParseID(id)
+	if err != nil || dataset == auditDataset || dataset == locksDataset {
+		return
+	}
+	g.appendChange(dataset, op, id, sha)
+
+	g.watchMu.RLock()
+	var handlers []EventHandler
+	for _, s := range g.subscriptions {
+		if s.kinds&kind != 0 {
+			handlers = append(handlers, s.handler)
+		}
+	}
+	g.watchMu.RUnlock()
+
+	e := Event{Kind: kind, Dataset: dataset, ID: id, Op: op, SHA: sha}
+	for _, handler := range handlers {
+		handler(e)
+	}
+}
+
+//publishing reports whether changes have anywhere to go
+func (g *gitdb) publishing() bool {
+	g.watchMu.RLock()
+	defer g.watchMu.RUnlock()
+	return g.config.ChangeFeed || len(g.subscriptions) > 0
+}
+
+//headSHA returns the commit changes are in once committed. It is empty while writes are not
+//committed as they are made e.g in a transaction
+func (g *gitdb) headSHA() string {
+	if !g.autoCommit {
+		return ""
+	}
+	sha, _ := g.gitDriver.head()
+	return sha
+}
+
+//publishWrite publishes a change made through this connection
+func (g *gitdb) publishWrite(op Op, id string) {
+	if !g.publishing() {
+		return
+	}
+
+	switch op {
+	case OpInsert:
+		g.publish(EventInsert, op, id, g.headSHA())
+	case OpUpdate:
+		g.publish(EventUpdate, op, id, g.headSHA())
+	case OpDelete, OpShred:
+		g.publish(EventDelete, op, id, g.headSHA())
+	}
+}
+
+//publishSync publishes the changes pulled in by a sync
+func (g *gitdb) publishSync(changes []recordChange) {
+	if len(changes) == 0 || !g.publishing() {
+		return
+	}
+
+	sha := g.headSHA()
+	for _, c := range changes {
+		g.publish(EventSync, c.op, c.id, sha)
+	}
+}
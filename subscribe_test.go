@@ -0,0 +1,95 @@
+package gitdb_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func eventString(e gitdb.Event) string {
+	return fmt.Sprintf("%d %s %s", e.Kind, e.Op, e.ID)
+}
+
+func TestSubscribe(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	var got, deletes []string
+	testDb.Subscribe(gitdb.EventAll, func(e gitdb.Event) {
+		if e.Dataset != "Message" {
+			t.Errorf("want: Message, got: %s", e.Dataset)
+		}
+		got = append(got, eventString(e))
+	})
+	testDb.Subscribe(gitdb.EventDelete, func(e gitdb.Event) {
+		deletes = append(deletes, e.ID)
+	})
+
+	m := getTestMessageWithId(1)
+	id := gitdb.ID(m)
+	if err := testDb.Insert(m); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+	if err := testDb.Insert(m); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+	if err := testDb.Delete(id); err != nil {
+		t.Fatalf("testDb.Delete failed: %s", err)
+	}
+	//deleting a record that doesn't exist changes nothing
+	if err := testDb.Delete(id); err != nil {
+		t.Fatalf("testDb.Delete failed: %s", err)
+	}
+
+	want := []string{
+		eventString(gitdb.Event{Kind: gitdb.EventInsert, Op: gitdb.OpInsert, ID: id}),
+		eventString(gitdb.Event{Kind: gitdb.EventUpdate, Op: gitdb.OpUpdate, ID: id}),
+		eventString(gitdb.Event{Kind: gitdb.EventDelete, Op: gitdb.OpDelete, ID: id}),
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("want: %v, got: %v", want, got)
+	}
+	if len(deletes) != 1 || deletes[0] != id {
+		t.Errorf("want: [%s], got: %v", id, deletes)
+	}
+}
+
+func TestSubscribeSync(t *testing.T) {
+	cfg := getConfig()
+	cfg.SyncMode = gitdb.SyncManual
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	//a second node syncing with the same online remote
+	nodeCfg := gitdb.NewConfig(testData + "/node")
+	nodeCfg.ConnectionName = "node"
+	nodeCfg.OnlineRemote = fakeRemote
+	nodeCfg.EncryptionKey = cfg.EncryptionKey
+	nodeCfg.SyncMode = gitdb.SyncManual
+	node, err := gitdb.Open(nodeCfg)
+	if err != nil {
+		t.Fatalf("gitdb.Open failed: %s", err)
+	}
+	defer node.Close()
+
+	var got []gitdb.Event
+	node.Subscribe(gitdb.EventSync, func(e gitdb.Event) {
+		got = append(got, e)
+	})
+
+	m := getTestMessageWithId(1)
+	if err := testDb.Insert(m); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+	if err := testDb.Sync(); err != nil {
+		t.Fatalf("testDb.Sync failed: %s", err)
+	}
+	if err := node.Sync(); err != nil {
+		t.Fatalf("node.Sync failed: %s", err)
+	}
+
+	if len(got) != 1 || got[0].ID != gitdb.ID(m) || got[0].Op != gitdb.OpInsert || got[0].Dataset != "Message" {
+		t.Errorf("want: a synced insert of %s, got: %v", gitdb.ID(m), got)
+	}
+}
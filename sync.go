@@ -0,0 +1,308 @@
+package gitdb
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bouggo/log"
+	"github.com/distatus/battery"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+type syncKind int
+
+const (
+	syncOnInterval syncKind = iota
+	syncImmediate
+	syncManual
+)
+
+//SyncMode controls when gitdb syncs with the online remote
+type SyncMode struct {
+	kind     syncKind
+	interval time.Duration
+}
+
+var (
+	//SyncImmediate pushes to the online remote after every commit
+	SyncImmediate = SyncMode{kind: syncImmediate}
+	//SyncManual never syncs automatically. Use GitDb.Sync to sync
+	SyncManual = SyncMode{kind: syncManual}
+)
+
+//SyncInterval syncs with the online remote every d
+func SyncInterval(d time.Duration) SyncMode {
+	return SyncMode{kind: syncOnInterval, interval: d}
+}
+
+const minPushBackoff = time.Second
+const maxPushBackoff = time.Minute * 5
+
+//pushQueue keeps track of commits that are yet to reach the online remote
+//so they can be retried with exponential backoff when the remote is unreachable
+type pushQueue struct {
+	mu       sync.Mutex
+	pending  int
+	failures int
+	retry    *time.Timer
+}
+
+func (q *pushQueue) add() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending++
+}
+
+func (q *pushQueue) backingOff() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.failures > 0
+}
+
+func (q *pushQueue) backoff() time.Duration {
+	if q.failures > 10 {
+		return maxPushBackoff
+	}
+
+	backoff := minPushBackoff << uint(q.failures-1)
+	if backoff > maxPushBackoff {
+		backoff = maxPushBackoff
+	}
+	return backoff
+}
+
+//PendingPushes returns the number of commits waiting to be pushed to the online remote
+func (g *gitdb) PendingPushes() int {
+	g.pushQueue.mu.Lock()
+	defer g.pushQueue.mu.Unlock()
+	return g.pushQueue.pending
+}
+
+type syncOptions struct {
+	remote string
+}
+
+//SyncOption customises a call to GitDb.Sync
+type SyncOption func(*syncOptions)
+
+//RemoteOnly limits a sync to the named remote. Syncing a mirror only pushes to it,
+//use "online" to pull from and push to Config.OnlineRemote without pushing to mirrors
+func RemoteOnly(name string) SyncOption {
+	return func(o *syncOptions) {
+		o.remote = name
+	}
+}
+
+//Sync pulls changes from the online remote and pushes changes to it and all mirrors
+func (g *gitdb) Sync(opts ...SyncOption) error {
+	if len(g.config.OnlineRemote) <= 0 {
+		return errors.New("Syncing disabled: online remote is not set")
+	}
+
+	o := &syncOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	switch o.remote {
+	case "":
+		return g.sync()
+	case onlineRemote:
+		changes, err := g.pullAndPush(false)
+		g.notifyWatchers(changes)
+		g.publishSync(changes)
+		return err
+	}
+
+	mirror, ok := g.mirror(o.remote)
+	if !ok {
+		return errors.New("Unknown remote: " + o.remote)
+	}
+
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
+	return g.pushMirror(mirror)
+}
+
+func (g *gitdb) sync() error {
+	changes, err := g.pullAndPush(true)
+	//handlers run after writeMu is released so they can write to the database
+	g.notifyWatchers(changes)
+	g.publishSync(changes)
+	return err
+}
+
+//pullAndPush syncs with the online remote, and mirrors if asked to,
+//and returns the records changed by the pull
+func (g *gitdb) pullAndPush(mirrors bool) ([]recordChange, error) {
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
+
+	log.Info("Syncing database...")
+	changedFiles := g.gitChangedFiles()
+	before, _ := g.gitDriver.head()
+	var err1 error
+	if g.config.ObjectReads {
+		err1 = g.gitDriver.fastForward()
+	} else {
+		release := g.lockWorktree(false)
+		err1 = g.gitPull()
+		release()
+	}
+	if err1 == nil {
+		g.remoteStatus.pulled(onlineRemote, g.config.OnlineRemote)
+	}
+	after, _ := g.gitDriver.head()
+
+	var err2 error
+	if !g.config.readOnly {
+		err2 = g.push()
+	}
+	if mirrors && !g.config.readOnly {
+		if err := g.pushMirrors(); err != nil && err2 == nil {
+			err2 = err
+		}
+	}
+	if err1 != nil || err2 != nil {
+		log.Info("Database sync failed")
+	}
+
+	//commit losing records saved by the merge driver
+	if g.gitDriver.isDirty(conflictsDataset) {
+		g.gitCommit([]string{conflictsDataset}, g.commitMessage(newCommitInfo("conflict", "Recording merge conflicts", nil)), g.config.User)
+	}
+	g.trustChanges(before)
+
+	//reset loaded blocks and the bloom filters of blocks the pull may have changed
+	g.cacheMu.Lock()
+	g.loadedBlocks = map[string]*db.Block{}
+	if before != after {
+		if err := g.resetBlooms(); err != nil {
+			log.Error(err.Error())
+		}
+	}
+
+	g.buildIndexSmart(changedFiles)
+	g.cacheMu.Unlock()
+
+	var changes []recordChange
+	if before != after {
+		var err error
+		if changes, err = g.diff(before, after); err != nil {
+			log.Error("Failed to diff pulled changes: " + err.Error())
+		}
+	}
+
+	if err1 != nil {
+		return changes, err1
+	}
+	return changes, err2
+}
+
+//push pushes all pending commits and schedules a retry if the push fails
+func (g *gitdb) push() error {
+	err := g.gitPush()
+	g.remoteStatus.record(onlineRemote, g.config.OnlineRemote, err)
+
+	q := &g.pushQueue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err != nil {
+		q.failures++
+		//in manual sync mode the next call to Sync pushes the pending commits
+		if g.config.SyncMode.kind == syncManual {
+			log.Info(fmt.Sprintf("Push failed: %d commit(s) pending", q.pending))
+			return err
+		}
+
+		backoff := q.backoff()
+		if q.retry == nil {
+			q.retry = time.AfterFunc(backoff, g.retryPush)
+		} else {
+			q.retry.Reset(backoff)
+		}
+		log.Info(fmt.Sprintf("Push failed: %d commit(s) pending, retrying in %s", q.pending, backoff))
+		return err
+	}
+
+	if q.failures > 0 {
+		msg := fmt.Sprintf("%d pending commit(s) pushed to online remote after %d failed attempt(s)", q.pending, q.failures)
+		log.Info("Database sync resumed: " + msg)
+		g.sendMail(newMail("Database Sync Resumed", msg))
+	}
+
+	q.pending = 0
+	q.failures = 0
+	if q.retry != nil {
+		q.retry.Stop()
+	}
+
+	return nil
+}
+
+func (g *gitdb) retryPush() {
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
+
+	//wait for a commit in progress to finish
+	mu.Lock()
+	defer mu.Unlock()
+
+	if g.closed || g.config.SyncMode.kind == syncManual {
+		return
+	}
+
+	g.push()
+}
+
+func (g *gitdb) startSyncClock() {
+
+	go func(g *gitdb) {
+		if len(g.config.OnlineRemote) <= 0 {
+			log.Info("Syncing disabled: online remote is not set")
+			return
+		}
+
+		if g.config.SyncMode.kind == syncManual {
+			log.Info("Syncing disabled: manual sync mode")
+			return
+		}
+
+		interval := g.config.SyncMode.interval
+		log.Test(fmt.Sprintf("starting sync clock @ interval %s", interval))
+		ticker := time.NewTicker(interval)
+		for {
+			select {
+			case <-g.shutdown:
+				log.Test("shutting down sync clock")
+				return
+			case <-ticker.C:
+				//if client PC has at least 20% battery life
+				if !hasSufficientBatteryPower(20) {
+					log.Info("Syncing disabled: insufficient battery power")
+					continue
+				}
+
+				g.sync()
+			}
+		}
+	}(g)
+}
+
+func hasSufficientBatteryPower(threshold float64) bool {
+	batt, err := battery.Get(0)
+	if err != nil {
+		//device is probably running on direct power
+		return true
+	}
+
+	percentageCharge := batt.Current / batt.Full * 100
+
+	log.Info(fmt.Sprintf("Battery Level: %6.2f%%", percentageCharge))
+
+	//return true if battery life is above threshold
+	return percentageCharge >= threshold
+}
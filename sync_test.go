@@ -0,0 +1,173 @@
+package gitdb_test
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func remoteCommitCount(t *testing.T) int {
+	out, err := exec.Command("git", "-C", fakeRemote, "log", "--all", "--oneline").CombinedOutput()
+	log := strings.TrimSpace(string(out))
+	if err != nil || len(log) == 0 {
+		//bare repo without commits
+		return 0
+	}
+	return len(strings.Split(log, "\n"))
+}
+
+func TestSyncManual(t *testing.T) {
+	cfg := getConfig()
+	cfg.SyncMode = gitdb.SyncManual
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	if err := insert(getTestMessageWithId(0), false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	if got := remoteCommitCount(t); got != 0 {
+		t.Errorf("manual sync mode should not push, remote has %d commits", got)
+	}
+
+	if err := testDb.Sync(); err != nil {
+		t.Fatalf("testDb.Sync failed: %s", err)
+	}
+
+	if got := remoteCommitCount(t); got != 1 {
+		t.Errorf("want: 1 commit on remote, got: %d", got)
+	}
+}
+
+func TestSyncImmediate(t *testing.T) {
+	cfg := getConfig()
+	cfg.SyncMode = gitdb.SyncImmediate
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	if err := insert(getTestMessageWithId(0), false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	if got := remoteCommitCount(t); got != 1 {
+		t.Errorf("want: 1 commit on remote, got: %d", got)
+	}
+}
+
+func TestSyncWithoutRemote(t *testing.T) {
+	cfg := getConfig()
+	cfg.OnlineRemote = ""
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	if err := testDb.Sync(); err == nil {
+		t.Errorf("testDb.Sync should fail when online remote is not set")
+	}
+}
+
+func TestPendingPushes(t *testing.T) {
+	cfg := getConfig()
+	cfg.SyncMode = gitdb.SyncManual
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	//take the online remote offline
+	offline := fakeRemote + "-offline"
+	if err := os.Rename(fakeRemote, offline); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := insert(getTestMessageWithId(0), false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	if err := testDb.Sync(); err == nil {
+		t.Errorf("testDb.Sync should fail when online remote is unreachable")
+	}
+
+	if got := testDb.PendingPushes(); got != 1 {
+		t.Errorf("want: 1 pending push, got: %d", got)
+	}
+
+	if err := os.Rename(offline, fakeRemote); err != nil {
+		t.Fatal(err)
+	}
+
+	//failed pushes aren't retried in manual sync mode
+	time.Sleep(time.Second + time.Second/2)
+	if got := remoteCommitCount(t); got != 0 {
+		t.Errorf("manual sync mode should not retry pushes, remote has %d commits", got)
+	}
+
+	if err := testDb.Sync(); err != nil {
+		t.Fatalf("testDb.Sync failed: %s", err)
+	}
+
+	if got := testDb.PendingPushes(); got != 0 {
+		t.Errorf("want: 0 pending pushes, got: %d", got)
+	}
+
+	if mails := testDb.GetMails(); len(mails) != 1 {
+		t.Errorf("want: 1 sync resumed mail, got: %d", len(mails))
+	}
+}
+
+func TestSyncMirrors(t *testing.T) {
+	backup := testData + "/backup"
+	cfg := getConfig()
+	cfg.SyncMode = gitdb.SyncManual
+	cfg.Mirrors = []gitdb.Remote{{Name: "backup", URL: backup}}
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	if out, err := exec.Command("git", "init", "--bare", backup).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %s", out)
+	}
+
+	if err := insert(getTestMessageWithId(0), false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	if err := testDb.Sync(gitdb.RemoteOnly("backup")); err != nil {
+		t.Fatalf("testDb.Sync(backup) failed: %s", err)
+	}
+
+	out, _ := exec.Command("git", "-C", backup, "rev-list", "--all", "--count").CombinedOutput()
+	if got := strings.TrimSpace(string(out)); got != "1" {
+		t.Errorf("want: 1 commit on backup, got: %s", got)
+	}
+	if got := remoteCommitCount(t); got != 0 {
+		t.Errorf("syncing backup only should not push to online remote, remote has %d commits", got)
+	}
+
+	if err := testDb.Sync(gitdb.RemoteOnly("nowhere")); err == nil {
+		t.Errorf("testDb.Sync should fail for unknown remote")
+	}
+
+	//take the mirror offline
+	if err := os.RemoveAll(backup); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := testDb.Sync(); err == nil {
+		t.Errorf("testDb.Sync should report failed mirror push")
+	}
+	if got := remoteCommitCount(t); got != 1 {
+		t.Errorf("want: 1 commit on online remote, got: %d", got)
+	}
+
+	remotes := testDb.Remotes()
+	if len(remotes) != 2 {
+		t.Fatalf("want: 2 remotes, got: %d", len(remotes))
+	}
+	if remotes[0].Name != "online" || remotes[0].LastError != nil || remotes[0].LastPush.IsZero() {
+		t.Errorf("want: successful push to online, got: %+v", remotes[0])
+	}
+	if remotes[1].Name != "backup" || remotes[1].LastError == nil {
+		t.Errorf("want: failed push to backup, got: %+v", remotes[1])
+	}
+}
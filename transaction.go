@@ -18,15 +18,21 @@ type transaction struct {
 	name       string
 	operations []operation
 	db         *gitdb
+	user       *User
 }
 
 func (t *transaction) Commit() error {
+	if err := t.db.writable(); err != nil {
+		return err
+	}
+
 	t.db.autoCommit = false
+	manifest := t.db.snapshotManifest()
 	for _, o := range t.operations {
 		if err := o(); err != nil {
 			log.Info("Reverting transaction: " + err.Error())
-			err2 := t.db.gitUndo()
 			t.db.autoCommit = true
+			err2 := t.db.revertUncommitted(manifest)
 			if err2 != nil {
 				err = fmt.Errorf("%s - %s", err.Error(), err2.Error())
 			}
@@ -38,7 +44,7 @@ func (t *transaction) Commit() error {
 	t.db.autoCommit = true
 	commitMsg := "Committing transaction: " + t.name
 	t.db.commit.Add(1)
-	t.db.events <- newWriteEvent(commitMsg, ".", t.db.autoCommit)
+	t.db.events <- newWriteEvent(commitMsg, ".", t.db.autoCommit, t.user, "transaction")
 	t.db.waitForCommit()
 	return nil
 }
@@ -48,5 +54,9 @@ func (t *transaction) AddOperation(o operation) {
 }
 
 func (g *gitdb) StartTransaction(name string) Transaction {
-	return &transaction{name: name, db: g}
+	return g.startTransaction(name, nil)
+}
+
+func (g *gitdb) startTransaction(name string, user *User) Transaction {
+	return &transaction{name: name, db: g, user: user}
 }
@@ -0,0 +1,190 @@
+package gitdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+//transientCache holds the paths of the transient fields of each struct type
+var transientCache sync.Map
+
+//isTransient reports whether sf is tagged gitdb:"-" so it is kept in memory but never written to block files
+func isTransient(sf reflect.StructField) bool {
+	return sf.Tag.Get("gitdb") == "-"
+}
+
+//transientPaths returns the paths of JSON names to the transient fields of struct type t,
+//following the fields of nested structs and flattening embedded structs the way encoding/json does
+func transientPaths(t reflect.Type) [][]string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	if cached, ok := transientCache.Load(t); ok {
+		return cached.([][]string)
+	}
+
+	var paths [][]string
+	addTransientPaths(t, nil, map[reflect.Type]bool{}, &paths)
+	transientCache.Store(t, paths)
+	return paths
+}
+
+func addTransientPaths(t reflect.Type, prefix []string, seen map[reflect.Type]bool, paths *[][]string) {
+	if seen[t] {
+		return
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := sf.Name
+		opts := strings.Split(tag, ",")
+		if opts[0] != "" {
+			name = opts[0]
+		}
+
+		ft := sf.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && opts[0] == "" && ft.Kind() == reflect.Struct {
+			addTransientPaths(ft, prefix, seen, paths)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		path := append(append([]string{}, prefix...), name)
+		if isTransient(sf) {
+			*paths = append(*paths, path)
+			continue
+		}
+		if ft.Kind() == reflect.Struct && ft != timeType {
+			addTransientPaths(ft, path, seen, paths)
+		}
+	}
+}
+
+//validateTransientIndexes returns an error if an index of schema is a transient field of m or a path through one,
+//as its value would be stored in the index files while the field is never written to block files
+func validateTransientIndexes(schema *Schema, m Model) error {
+	t := reflect.TypeOf(m)
+	paths := transientPaths(t)
+	if len(paths) == 0 {
+		return nil
+	}
+	//the JSON names of fields promoted from embedded structs aren't found by name
+	transient := map[string]bool{}
+	for _, path := range paths {
+		transient[strings.Join(path, ".")] = true
+	}
+	for name := range schema.indexes {
+		if transient[name] || isTransientPath(t, strings.Split(name, ".")) {
+			return fmt.Errorf("transient field %s can't be an index of %s", name, schema.name())
+		}
+	}
+	return nil
+}
+
+//isTransientPath reports whether path, matched by name or JSON name as path indexes are, goes through a
+//transient field of struct type t
+func isTransientPath(t reflect.Type, path []string) bool {
+	for _, name := range path {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return false
+		}
+		index := fieldIndex(t, name)
+		if index == nil {
+			return false
+		}
+		sf := t.FieldByIndex(index)
+		if isTransient(sf) {
+			return true
+		}
+		t = sf.Type
+	}
+	return false
+}
+
+//stripTransient removes the fields at paths from the JSON object data
+func stripTransient(data []byte, paths [][]string) ([]byte, error) {
+	if len(paths) == 0 {
+		return data, nil
+	}
+
+	//null, e.g a nil pointer to a nested struct, or a struct with a MarshalJSON of its own has nothing to strip
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] != '{' {
+		return data, nil
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+
+	nested := map[string][][]string{}
+	for _, path := range paths {
+		if len(path) == 1 {
+			delete(obj, path[0])
+			continue
+		}
+		nested[path[0]] = append(nested[path[0]], path[1:])
+	}
+
+	for name, nestedPaths := range nested {
+		value, ok := obj[name]
+		if !ok {
+			continue
+		}
+		stripped, err := stripTransient(value, nestedPaths)
+		if err != nil {
+			return nil, err
+		}
+		obj[name] = stripped
+	}
+
+	return json.Marshal(obj)
+}
+
+//marshalRecord marshals the wrapped model m leaving out the transient fields of its data
+func marshalRecord(m Model) ([]byte, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, ok := m.(*model)
+	if !ok {
+		return data, nil
+	}
+	paths := transientPaths(reflect.TypeOf(wrapped.Data))
+	if len(paths) == 0 {
+		return data, nil
+	}
+
+	var rec map[string]json.RawMessage
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	if rec["Data"], err = stripTransient(rec["Data"], paths); err != nil {
+		return nil, err
+	}
+	return json.Marshal(rec)
+}
@@ -0,0 +1,104 @@
+package gitdb_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+type Card struct {
+	Last4 string
+	PAN   string `gitdb:"-"`
+}
+
+type Order struct {
+	testModel
+	OrderId  int
+	Quantity int
+	Price    float64
+	Total    float64 `json:"total" gitdb:"-"`
+	Card     Card
+}
+
+func (o *Order) GetSchema() *gitdb.Schema {
+	return gitdb.NewSchema("Order", "b0", fmt.Sprintf("%d", o.OrderId), map[string]interface{}{})
+}
+
+func TestTransientFields(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	o := &Order{OrderId: 1, Quantity: 3, Price: 2.5, Total: 7.5, Card: Card{Last4: "4242", PAN: "4242424242424242"}}
+	if err := testDb.Insert(o); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dbPath, "data", "Order", "b0.json"))
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile failed: %s", err)
+	}
+	block := string(data)
+	if strings.Contains(block, "total") || strings.Contains(block, "4242424242424242") {
+		t.Errorf("transient fields should not be stored: %s", block)
+	}
+	if !strings.Contains(block, "4242") {
+		t.Errorf("Card.Last4 should be stored: %s", block)
+	}
+
+	got := &Order{}
+	if err := testDb.Get(gitdb.ID(o), got); err != nil {
+		t.Fatalf("testDb.Get failed: %s", err)
+	}
+	if got.Quantity != 3 || got.Total != 0 || got.Card.Last4 != "4242" || got.Card.PAN != "" {
+		t.Errorf("want: stored fields only, got: %+v", got)
+	}
+
+	//changing a transient field changes nothing stored
+	inserted := headCommitMessage(t)
+	o.Total = 10
+	if err := testDb.Update(o); err != nil {
+		t.Fatalf("testDb.Update failed: %s", err)
+	}
+	if got := headCommitMessage(t); got != inserted {
+		t.Errorf("want no commit, got: %s", got)
+	}
+}
+
+type IndexedOrder struct {
+	Order
+	indexes map[string]interface{}
+	path    string
+}
+
+func (o *IndexedOrder) GetSchema() *gitdb.Schema {
+	schema := gitdb.NewSchema("Order", "b0", fmt.Sprintf("%d", o.OrderId), o.indexes)
+	if o.path != "" {
+		schema.Index(o.path)
+	}
+	return schema
+}
+
+func TestTransientIndexes(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	tests := []*IndexedOrder{
+		{Order: Order{OrderId: 1, Total: 7.5}, indexes: map[string]interface{}{"total": 7.5}},
+		{Order: Order{OrderId: 2, Total: 7.5}, indexes: map[string]interface{}{"Total": 7.5}},
+		{Order: Order{OrderId: 3, Card: Card{PAN: "4242424242424242"}}, indexes: map[string]interface{}{}, path: "Card.PAN"},
+	}
+	for _, o := range tests {
+		if err := testDb.Insert(o); err == nil || !strings.Contains(err.Error(), "transient") {
+			t.Errorf("want: transient field %v%s rejected as an index, got: %v", o.indexes, o.path, err)
+		}
+	}
+
+	o := &IndexedOrder{Order: Order{OrderId: 4, Card: Card{Last4: "4242"}}, indexes: map[string]interface{}{"Quantity": 0}, path: "Card.Last4"}
+	if err := testDb.Insert(o); err != nil {
+		t.Errorf("testDb.Insert failed: %s", err)
+	}
+}
@@ -0,0 +1,88 @@
+package gitdb
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//typeName returns the name m is registered under in Config.Types for dataset, or an empty string
+//if dataset is not polymorphic. Models of polymorphic datasets must be registered
+func (g *gitdb) typeName(dataset string, m Model) (string, error) {
+	types, ok := g.config.Types[dataset]
+	if !ok {
+		return "", nil
+	}
+
+	t := reflect.TypeOf(m)
+	key := typeKey{dataset: dataset, t: t}
+	if name, ok := g.typeNames.Load(key); ok {
+		return name.(string), nil
+	}
+
+	for name, factory := range types {
+		if reflect.TypeOf(factory()) == t {
+			g.typeNames.Store(key, name)
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%T is not one of the Config.Types of %s", m, dataset)
+}
+
+type typeKey struct {
+	dataset string
+	t       reflect.Type
+}
+
+//newModel returns an empty model of the concrete type of record, from Config.Types when the record
+//has a type and Config.Factory or RegisterModel otherwise. It returns nil if none of them knows the record
+func (g *gitdb) newModel(dataset string, record *db.Record) Model {
+	if name := record.Type(); len(name) > 0 {
+		if factory, ok := g.config.Types[dataset][name]; ok {
+			return factory()
+		}
+		return nil
+	}
+
+	return g.config.factory(dataset)
+}
+
+//Decode returns record hydrated into a model of its concrete type, found by the type it was stored
+//with in Config.Types or, for records of other datasets, with Config.Factory or RegisterModel
+func (g *gitdb) Decode(record *db.Record) (Model, error) {
+	dataset, _, _, err := ParseID(record.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	m := g.newModel(dataset, record)
+	if m == nil {
+		if name := record.Type(); len(name) > 0 {
+			return nil, fmt.Errorf("%s is of type %s which is not one of the Config.Types of %s", record.ID(), name, dataset)
+		}
+		return nil, fmt.Errorf("%s can't be decoded without Config.Types, Config.Factory or RegisterModel", record.ID())
+	}
+
+	if err := record.Hydrate(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+//FetchModels fetches the records of dataset like Fetch and decodes each one into a model of its
+//concrete type. Use it to read polymorphic datasets e.g Payments holding CardPayment and TransferPayment
+func (g *gitdb) FetchModels(dataset string) ([]Model, error) {
+	records, err := g.Fetch(dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	models := make([]Model, len(records))
+	for i, record := range records {
+		if models[i], err = g.Decode(record); err != nil {
+			return nil, err
+		}
+	}
+	return models, nil
+}
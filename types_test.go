@@ -0,0 +1,86 @@
+package gitdb_test
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+type CardPayment struct {
+	testModel
+	PaymentId int
+	Amount    int
+	Last4     string
+}
+
+func (p *CardPayment) GetSchema() *gitdb.Schema {
+	return gitdb.NewSchema("Payments", "b0", fmt.Sprintf("%d", p.PaymentId), map[string]interface{}{"Amount": p.Amount})
+}
+
+func (p *CardPayment) ShouldEncrypt() bool { return true }
+
+type TransferPayment struct {
+	testModel
+	PaymentId int
+	Amount    int
+	IBAN      string
+}
+
+func (p *TransferPayment) GetSchema() *gitdb.Schema {
+	return gitdb.NewSchema("Payments", "b0", fmt.Sprintf("%d", p.PaymentId), map[string]interface{}{"Amount": p.Amount})
+}
+
+func TestPolymorphicDataset(t *testing.T) {
+	cfg := getConfig()
+	cfg.Types = map[string]map[string]func() gitdb.Model{
+		"Payments": {
+			"card":     func() gitdb.Model { return &CardPayment{} },
+			"transfer": func() gitdb.Model { return &TransferPayment{} },
+		},
+	}
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	payments := []gitdb.Model{
+		&CardPayment{PaymentId: 1, Amount: 10, Last4: "4242"},
+		&TransferPayment{PaymentId: 2, Amount: 20, IBAN: "GB33BUKB20201555555555"},
+	}
+	for _, p := range payments {
+		if err := testDb.Insert(p); err != nil {
+			t.Fatalf("testDb.Insert failed: %s", err)
+		}
+	}
+
+	models, err := testDb.FetchModels("Payments")
+	if err != nil {
+		t.Fatalf("testDb.FetchModels failed: %s", err)
+	}
+
+	var got []string
+	for _, m := range models {
+		switch p := m.(type) {
+		case *CardPayment:
+			got = append(got, "card "+p.Last4)
+		case *TransferPayment:
+			got = append(got, "transfer "+p.IBAN)
+		default:
+			t.Errorf("unexpected model %T", m)
+		}
+	}
+	sort.Strings(got)
+	if want := "card 4242, transfer GB33BUKB20201555555555"; strings.Join(got, ", ") != want {
+		t.Errorf("want: %s, got: %s", want, strings.Join(got, ", "))
+	}
+
+	//models of a polymorphic dataset must be registered
+	unregistered := &CardPayment{PaymentId: 3}
+	cfg.Types["Payments"] = map[string]func() gitdb.Model{"transfer": func() gitdb.Model { return &TransferPayment{} }}
+	testDb.Close()
+	testDb = getDbConn(t, cfg)
+	if err := testDb.Insert(unregistered); err == nil {
+		t.Errorf("testDb.Insert should fail for a model that is not one of Config.Types")
+	}
+}
@@ -1,14 +1,13 @@
-//go:generate gitdb embed-ui -o ./ui_static.go
 package gitdb
 
 import (
+	"bytes"
 	"context"
-	"encoding/base64"
+	"encoding/json"
 	"fmt"
-	"html/template"
-	"io/ioutil"
 	"net/http"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/bouggo/log"
@@ -16,20 +15,11 @@ import (
 	"github.com/gorilla/mux"
 )
 
-var fs *fileSystem
-
-func getFs() *fileSystem {
-	if fs == nil {
-		fs = &fileSystem{}
-	}
-	return fs
-}
-
 func (g *gitdb) startUI() {
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf("localhost:%d", g.config.UIPort),
-		Handler: new(router).configure(g.config),
+		Handler: (&router{meta: g.meta(), db: g}).handler(g.config),
 	}
 
 	log.Info("GitDB GUI will run at http://" + server.Addr)
@@ -50,54 +40,101 @@ func (g *gitdb) startUI() {
 	}()
 }
 
-type fileSystem struct {
-	files map[string]string
-}
-
-func (e *fileSystem) embed(name, src string) {
-	if e.files == nil {
-		e.files = make(map[string]string)
-	}
-	e.files[name] = src
-}
-
-func (e *fileSystem) has(name string) bool {
-	_, ok := e.files[name]
-	return ok
+//router provides all the http handlers for the UI
+type router struct {
+	datasets  []*db.Dataset
+	refreshAt time.Time
+	//meta holds the redacted fields of datasets
+	meta     *schemaMeta
+	cfg      Config
+	sessions uiSessions
+	//logins throttles failed sign ins by address
+	logins loginThrottle
+	//db serves the REST API
+	db *gitdb
+	//assets are the pages, stylesheets and scripts of the UI
+	assets uiAssets
+	//databases are the connections of the UIHandler the router is one of and database is its own
+	databases []string
+	database  string
+	//stream sends the changes to the database to the clients of /events
+	stream changeStream
 }
 
-func (e *fileSystem) get(name string) []byte {
-	content := e.files[name]
-	decoded, err := base64.StdEncoding.DecodeString(content)
-	if err != nil {
-		log.Error(err.Error())
-		return []byte("")
+//handler returns the router configured with cfg serving requests under Config.UIBasePath
+func (u *router) handler(cfg Config) http.Handler {
+	router := u.configure(cfg)
+	base := u.path("")
+	if len(base) == 0 {
+		return router
 	}
 
-	return decoded
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//requests mounted with http.StripPrefix have had the base path taken off already
+		if r.URL.Path == base || strings.HasPrefix(r.URL.Path, base+"/") {
+			r2 := r.Clone(r.Context())
+			r2.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, base), "/")
+			r2.URL.RawPath = ""
+			r = r2
+		}
+		router.ServeHTTP(w, r)
+	})
 }
 
-//router provides all the http handlers for the UI
-type router struct {
-	datasets  []*db.Dataset
-	refreshAt time.Time
+//path returns the path p of the UI under Config.UIBasePath
+func (u *router) path(p string) string {
+	return strings.TrimSuffix(u.cfg.UIBasePath, "/") + p
 }
 
 func (u *router) configure(cfg Config) *mux.Router {
+	u.cfg = cfg
+	u.assets = uiAssets{dir: cfg.UIDir}
 	router := mux.NewRouter()
 	for path, handler := range u.getEndpoints() {
 		router.HandleFunc(path, handler)
 	}
+	for path, handler := range u.apiEndpoints() {
+		router.HandleFunc(path, handler)
+	}
+	for path, handler := range u.editEndpoints() {
+		router.HandleFunc(path, handler)
+	}
+	for path, handler := range u.historyEndpoints() {
+		router.HandleFunc(path, handler)
+	}
+	for path, handler := range u.consoleEndpoints() {
+		router.HandleFunc(path, handler)
+	}
+	for path, handler := range u.repairEndpoints() {
+		router.HandleFunc(path, handler)
+	}
+	for path, handler := range u.shareEndpoints() {
+		router.HandleFunc(path, handler)
+	}
+	for path, handler := range u.streamEndpoints() {
+		router.HandleFunc(path, handler)
+	}
+	for path, handler := range u.statusEndpoints() {
+		router.HandleFunc(path, handler)
+	}
+	if cfg.GraphQL {
+		for path, handler := range u.graphQLEndpoints() {
+			router.HandleFunc(path, handler)
+		}
+	}
 
 	//refresh dataset after 1 minute
 	router.Use(func(h http.Handler) http.Handler {
 		if u.refreshAt.IsZero() || u.refreshAt.Before(time.Now()) {
-			u.datasets = db.LoadDatasets(filepath.Join(cfg.DbPath, "data"), cfg.EncryptionKey)
+			u.datasets = db.LoadDatasets(filepath.Join(cfg.DbPath, "data"), func(dataset string) db.Decrypter {
+				return cfg.decrypter(dataset, cfg.keyring())
+			})
 			u.refreshAt = time.Now().Add(time.Second * 10)
 		}
 
 		return h
 	})
+	router.Use(u.requireLogin)
 
 	return router
 }
@@ -105,59 +142,77 @@ func (u *router) configure(cfg Config) *mux.Router {
 //getEndpoints maps a path to a http handler
 func (u *router) getEndpoints() map[string]http.HandlerFunc {
 	return map[string]http.HandlerFunc{
-		"/css/app.css":              u.appCSS,
-		"/js/app.js":                u.appJS,
-		"/":                         u.overview,
-		"/errors/{dataset}":         u.viewErrors,
-		"/list/{dataset}":           u.list,
-		"/view/{dataset}":           u.view,
-		"/view/{dataset}/b{b}/r{r}": u.view,
+		"/css/{file}":                        u.asset,
+		"/js/{file}":                         u.asset,
+		"/":                                  u.overview,
+		"/dashboard":                         u.dashboard,
+		"/login":                             u.login,
+		"/logout":                            u.logout,
+		"/errors/{dataset}":                  u.viewErrors,
+		"/list/{dataset}":                    u.list,
+		"/view/{dataset}":                    u.view,
+		"/view/{dataset}/b{b}/r{r}":          u.view,
+		"/record/{dataset}/{block}/{record}": u.record,
 	}
 }
 
-func (u *router) appCSS(w http.ResponseWriter, r *http.Request) {
-	src := readView("static/css/app.css")
-	w.Header().Set("Content-Type", "text/css")
-	w.Write(src)
-}
-
-func (u *router) appJS(w http.ResponseWriter, r *http.Request) {
-	src := readView("static/js/app.js")
-	w.Header().Set("Content-Type", "text/javascript")
-	w.Write(src)
-}
-
 func (u *router) overview(w http.ResponseWriter, r *http.Request) {
 	viewModel := &overviewViewModel{}
 	viewModel.Title = "Overview"
-	viewModel.DataSets = u.datasets
+	viewModel.DataSets = u.readable(u.role(r))
 
-	render(w, viewModel, "static/index.html", "static/sidebar.html")
+	u.render(w, viewModel, "static/index.html", "static/sidebar.html")
 }
 
 func (u *router) list(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	viewDs := vars["dataset"]
 
-	dataset := u.findDataset(viewDs)
+	dataset := u.findDataset(r, viewDs)
 	if dataset == nil {
 		w.Write([]byte("Dataset (" + viewDs + ") does not exist"))
 		return
 	}
 
-	block := dataset.Block(0)
-	table := tablulate(block)
-	viewModel := &listDataSetViewModel{DataSet: dataset, Table: table}
-	viewModel.DataSets = u.datasets
+	q := parseListQuery(r)
+	records, total, err := u.listRecords(viewDs, q)
+	if err != nil {
+		u.editFail(w, err)
+		return
+	}
+
+	table := tablulate(records, func(data map[string]interface{}) { u.redact(viewDs, data) })
+	viewModel := &listDataSetViewModel{DataSet: dataset, Table: table, Query: q, Total: total}
+	viewModel.Columns = u.listColumns(viewDs, table.Headers, q)
+	viewModel.Indexes = u.searchable(viewDs)
+	viewModel.Pages = (total + listPageSize - 1) / listPageSize
+	if q.Page > 1 {
+		prev := q
+		prev.Page--
+		viewModel.PrevURL = prev.url(viewDs)
+	}
+	if q.Page < viewModel.Pages {
+		next := q
+		next.Page++
+		viewModel.NextURL = next.url(viewDs)
+	}
+	if meta, err := readDatasetMeta(filepath.Join(u.cfg.DbPath, "data", viewDs)); err == nil {
+		viewModel.Meta = meta
+	}
+	viewModel.DataSets = u.readable(u.role(r))
+	if u.editable(r, viewDs) {
+		viewModel.New = u.newLinks(viewDs)
+	}
+	viewModel.Shareable = len(u.cfg.ShareKey) > 0
 
-	render(w, viewModel, "static/list.html", "static/sidebar.html")
+	u.render(w, viewModel, "static/list.html", "static/sidebar.html")
 }
 
 func (u *router) view(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	viewDs := vars["dataset"]
 
-	dataset := u.findDataset(viewDs)
+	dataset := u.findDataset(r, viewDs)
 	if dataset == nil {
 		w.Write([]byte("Dataset (" + viewDs + ") does not exist"))
 		return
@@ -168,7 +223,7 @@ func (u *router) view(w http.ResponseWriter, r *http.Request) {
 		Content: "No record found",
 		Pager:   &pager{totalBlocks: dataset.BlockCount()},
 	}
-	viewModel.DataSets = u.datasets
+	viewModel.DataSets = u.readable(u.role(r))
 	if vars["b"] != "" && vars["r"] != "" {
 		viewModel.Pager.set(vars["b"], vars["r"])
 	}
@@ -176,77 +231,103 @@ func (u *router) view(w http.ResponseWriter, r *http.Request) {
 	viewModel.Block = block
 	viewModel.Pager.totalRecords = block.RecordCount()
 	if viewModel.Pager.totalRecords > viewModel.Pager.recordPage {
-		viewModel.Content = block.Record(viewModel.Pager.recordPage).JSON()
+		record := block.Record(viewModel.Pager.recordPage)
+		viewModel.Content = u.redactJSON(viewDs, record.JSON())
+		viewModel.RecordID = record.ID()
+		viewModel.Editable = u.editable(r, viewDs)
 	}
 
-	render(w, viewModel, "static/view.html", "static/sidebar.html")
+	u.render(w, viewModel, "static/view.html", "static/sidebar.html")
 }
 
-func (u *router) viewErrors(w http.ResponseWriter, r *http.Request) {
+//record shows the record with the id in the path
+func (u *router) record(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	viewDs := vars["dataset"]
+	id := viewDs + "/" + vars["block"] + "/" + vars["record"]
 
-	dataset := u.findDataset(viewDs)
+	dataset := u.findDataset(r, viewDs)
 	if dataset == nil {
 		w.Write([]byte("Dataset (" + viewDs + ") does not exist"))
 		return
 	}
-	viewModel := &errorsViewModel{DataSet: dataset}
-	viewModel.Title = "Errors"
-	viewModel.DataSets = u.datasets
 
-	render(w, viewModel, "static/errors.html", "static/sidebar.html")
+	record, err := u.db.doget(id)
+	if err != nil {
+		u.editFail(w, &apiErr{http.StatusNotFound, err.Error()})
+		return
+	}
+
+	viewModel := &viewDataSetViewModel{DataSet: dataset, Content: u.redactJSON(viewDs, record.JSON()), RecordID: id}
+	viewModel.Editable = u.editable(r, viewDs)
+	viewModel.DataSets = u.readable(u.role(r))
+
+	u.render(w, viewModel, "static/view.html", "static/sidebar.html")
 }
 
-func (u *router) findDataset(name string) *db.Dataset {
-	for _, ds := range u.datasets {
-		if ds.Name() == name {
-			return ds
-		}
+func (u *router) viewErrors(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	viewDs := vars["dataset"]
+
+	dataset := u.findDataset(r, viewDs)
+	if dataset == nil {
+		w.Write([]byte("Dataset (" + viewDs + ") does not exist"))
+		return
 	}
-	return nil
-}
+	//blocks are read afresh so repairs, and blocks broken since the datasets were loaded, show at once
+	dataset = db.LoadDataset(dataset.Path(), u.cfg.decrypter(viewDs, u.cfg.keyring()))
+	viewModel := &errorsViewModel{DataSet: dataset, BadRecords: dataset.BadRecords(), Repairable: u.canEdit(r, viewDs) == nil}
+	for _, blockFile := range dataset.BadBlocks() {
+		viewModel.BadBlocks = append(viewModel.BadBlocks, strings.TrimSuffix(filepath.Base(blockFile), ".json"))
+	}
+	viewModel.Title = "Errors"
+	viewModel.DataSets = u.readable(u.role(r))
 
-func render(w http.ResponseWriter, data interface{}, templates ...string) {
+	u.render(w, viewModel, "static/errors.html", "static/sidebar.html")
+}
 
-	parseFiles := false
-	for _, template := range templates {
-		if !getFs().has(template) {
-			parseFiles = true
-		}
+//readable returns the datasets role can read. Every dataset is readable without a role
+func (u *router) readable(role string) []*db.Dataset {
+	if len(role) == 0 {
+		return u.datasets
 	}
 
-	var t *template.Template
-	var err error
-	if parseFiles {
-		t, err = template.ParseFiles(templates...)
-		if err != nil {
-			log.Error(err.Error())
-		}
-	} else {
-		t = template.New("overview")
-		for _, template := range templates {
-			log.Test("Reading EMBEDDED file - " + template)
-			t, err = t.Parse(string(getFs().get(template)))
-			if err != nil {
-				log.Error(err.Error())
-			}
+	var readable []*db.Dataset
+	for _, ds := range u.datasets {
+		if u.cfg.access(role, ds.Name(), PermRead) == nil {
+			readable = append(readable, ds)
 		}
 	}
+	return readable
+}
 
-	t.Execute(w, data)
+//redact masks the redacted fields of dataset in data
+func (u *router) redact(dataset string, data map[string]interface{}) {
+	if u.meta != nil {
+		u.meta.redact(dataset, data)
+	}
 }
 
-func readView(fileName string) []byte {
-	if getFs().has(fileName) {
-		return getFs().get(fileName)
+//redactJSON masks the redacted fields of dataset in record keeping it indented
+func (u *router) redactJSON(dataset string, record string) string {
+	if u.meta == nil {
+		return record
 	}
 
-	data, err := ioutil.ReadFile(fileName)
-	if err != nil {
-		log.Error(err.Error())
-		return []byte("")
+	redacted := u.meta.redactJSON(dataset, record)
+	var buf bytes.Buffer
+	if redacted == record || json.Indent(&buf, []byte(redacted), "", "\t") != nil {
+		return redacted
 	}
+	return buf.String()
+}
 
-	return data
+//findDataset returns the dataset name if the role of the request can read it
+func (u *router) findDataset(r *http.Request, name string) *db.Dataset {
+	for _, ds := range u.readable(u.role(r)) {
+		if ds.Name() == name {
+			return ds
+		}
+	}
+	return nil
 }
@@ -0,0 +1,371 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bouggo/log"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+	"github.com/gorilla/mux"
+)
+
+const defaultAPILimit = 50
+const maxAPILimit = 1000
+
+//maxInt is the largest int, which the offset of a page must not pass
+const maxInt = int(^uint(0) >> 1)
+
+//apiRecord is a record as the REST API serves it
+type apiRecord struct {
+	ID       string          `json:"id"`
+	Revision int             `json:"revision"`
+	Data     json.RawMessage `json:"data"`
+}
+
+//apiPage is a page of the records of a dataset
+type apiPage struct {
+	Dataset string       `json:"dataset"`
+	Page    int          `json:"page"`
+	Limit   int          `json:"limit"`
+	Total   int          `json:"total"`
+	Records []*apiRecord `json:"records"`
+}
+
+//apiErr is an error the API responds to with status
+type apiErr struct {
+	status int
+	msg    string
+}
+
+func (e *apiErr) Error() string {
+	return e.msg
+}
+
+//apiEndpoints maps the paths of the REST API to their handlers
+func (u *router) apiEndpoints() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/api/datasets":                           u.apiDatasets,
+		"/api/datasets/{dataset}":                 u.apiDataset,
+		"/api/records/{dataset}/{block}/{record}": u.apiRecord,
+	}
+}
+
+//apiDatasets lists the datasets the request can read
+func (u *router) apiDatasets(w http.ResponseWriter, r *http.Request) {
+	if !u.apiAccepts(w, r, http.MethodGet) {
+		return
+	}
+
+	names, err := u.db.datasetNames()
+	if err != nil {
+		apiFail(w, err)
+		return
+	}
+
+	readable := []string{}
+	for _, name := range names {
+		if u.can(r, name, PermRead) == nil {
+			readable = append(readable, name)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{"datasets": readable})
+}
+
+//apiDataset serves a page of the records of a dataset on GET and inserts the record posted on POST
+func (u *router) apiDataset(w http.ResponseWriter, r *http.Request) {
+	if !u.apiAccepts(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	dataset := mux.Vars(r)["dataset"]
+	if r.Method == http.MethodPost {
+		u.apiWrite(w, r, dataset, "")
+		return
+	}
+
+	if err := u.can(r, dataset, PermRead); err != nil {
+		apiFail(w, err)
+		return
+	}
+	if !u.apiHasDataset(dataset) {
+		apiFail(w, &apiErr{http.StatusNotFound, "Dataset " + dataset + " does not exist"})
+		return
+	}
+
+	page, limit, err := apiPaging(r)
+	if err != nil {
+		apiFail(w, err)
+		return
+	}
+
+	records, err := u.db.Fetch(dataset)
+	if err != nil {
+		apiFail(w, err)
+		return
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID() < records[j].ID() })
+
+	result := &apiPage{Dataset: dataset, Page: page, Limit: limit, Total: len(records), Records: []*apiRecord{}}
+	for i := (page - 1) * limit; i < len(records) && i < page*limit; i++ {
+		result.Records = append(result.Records, u.apiRecordOf(dataset, records[i]))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+//apiRecord serves a record on GET, inserts or updates it on POST and deletes it on DELETE
+func (u *router) apiRecord(w http.ResponseWriter, r *http.Request) {
+	if !u.apiAccepts(w, r, http.MethodGet, http.MethodPost, http.MethodDelete) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	dataset := vars["dataset"]
+	id := dataset + "/" + vars["block"] + "/" + vars["record"]
+
+	switch r.Method {
+	case http.MethodPost:
+		u.apiWrite(w, r, dataset, id)
+	case http.MethodDelete:
+		if err := u.canWrite(r, dataset, PermDelete); err != nil {
+			apiFail(w, err)
+			return
+		}
+		if err := u.db.Exists(id); err != nil {
+			apiFail(w, &apiErr{http.StatusNotFound, err.Error()})
+			return
+		}
+		if err := u.apiConn(r).Delete(id); err != nil {
+			apiFail(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		if err := u.can(r, dataset, PermRead); err != nil {
+			apiFail(w, err)
+			return
+		}
+		record, err := u.db.doget(id)
+		if err != nil {
+			apiFail(w, &apiErr{http.StatusNotFound, err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, u.apiRecordOf(dataset, record))
+	}
+}
+
+//apiWrite decodes the body of r into the model of dataset and inserts it. id is the id the record
+//must have, or empty when it is created with the id the model gives it
+func (u *router) apiWrite(w http.ResponseWriter, r *http.Request, dataset string, id string) {
+	if err := u.canWrite(r, dataset, PermWrite); err != nil {
+		apiFail(w, err)
+		return
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		apiFail(w, &apiErr{http.StatusUnsupportedMediaType, "records must be posted as application/json"})
+		return
+	}
+
+	m, err := u.apiModel(r, dataset)
+	if err != nil {
+		apiFail(w, err)
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(m); err != nil {
+		apiFail(w, &apiErr{http.StatusBadRequest, "invalid JSON: " + err.Error()})
+		return
+	}
+	if m.GetSchema().name() != dataset {
+		apiFail(w, &apiErr{http.StatusBadRequest, fmt.Sprintf("the model posted is a record of %s, not %s", m.GetSchema().name(), dataset)})
+		return
+	}
+	if len(id) > 0 && ID(m) != id {
+		apiFail(w, &apiErr{http.StatusBadRequest, fmt.Sprintf("the record posted has id %s, not %s", ID(m), id)})
+		return
+	}
+
+	status := http.StatusCreated
+	if u.db.Exists(ID(m)) == nil {
+		status = http.StatusOK
+	}
+	if err := u.apiConn(r).Insert(m); err != nil {
+		apiFail(w, err)
+		return
+	}
+
+	record, err := u.db.doget(ID(m))
+	if err != nil {
+		apiFail(w, err)
+		return
+	}
+	w.Header().Set("Location", "/api/records/"+ID(m))
+	writeJSON(w, status, u.apiRecordOf(dataset, record))
+}
+
+//apiModel returns an empty model of dataset to decode a posted record into. Records of polymorphic
+//datasets name their type of Config.Types in the type query parameter
+func (u *router) apiModel(r *http.Request, dataset string) (Model, error) {
+	if types, ok := u.db.config.Types[dataset]; ok {
+		name := r.URL.Query().Get("type")
+		factory, ok := types[name]
+		if !ok {
+			return nil, &apiErr{http.StatusBadRequest, "records of " + dataset + " need a type query parameter naming one of its Config.Types"}
+		}
+		return factory(), nil
+	}
+
+	if m := u.db.config.factory(dataset); m != nil {
+		return m, nil
+	}
+	return nil, &apiErr{http.StatusUnprocessableEntity, dataset + " has no model to decode records into. Register one with RegisterModel or Config.Factory"}
+}
+
+//apiRecordOf returns record as the API serves it, with the redacted fields of dataset masked
+func (u *router) apiRecordOf(dataset string, record *db.Record) *apiRecord {
+	data := recordData(record)
+	if u.meta != nil {
+		data = u.meta.redactJSON(dataset, data)
+	}
+	return &apiRecord{ID: record.ID(), Revision: record.Revision(), Data: json.RawMessage(data)}
+}
+
+//apiConn returns the connection the writes of the request are made with, attributed to the signed in user if they have an email
+func (u *router) apiConn(r *http.Request) GitDb {
+	if user, ok := r.Context().Value(uiUserKey{}).(UIUser); ok && len(user.Email) > 0 {
+		return u.db.WithUser(user.Name, user.Email)
+	}
+	return u.db
+}
+
+//can returns *ErrAccessDenied unless the role of the request has permission p on dataset
+func (u *router) can(r *http.Request, dataset string, p Permission) error {
+	role := u.role(r)
+	if len(role) == 0 {
+		return nil
+	}
+	return u.cfg.access(role, dataset, p)
+}
+
+//canWrite is like can for changes, which are only made through the API with Config.APIWrites set
+func (u *router) canWrite(r *http.Request, dataset string, p Permission) error {
+	if !u.cfg.APIWrites {
+		return &apiErr{http.StatusForbidden, "changes can't be made through the API without Config.APIWrites"}
+	}
+	return u.can(r, dataset, p)
+}
+
+func (u *router) apiHasDataset(dataset string) bool {
+	names, err := u.db.datasetNames()
+	if err != nil {
+		return false
+	}
+	for _, name := range names {
+		if name == dataset {
+			return true
+		}
+	}
+	return false
+}
+
+//apiAccepts checks the method of r is one of methods and that the client takes JSON, responding with an error if not
+func (u *router) apiAccepts(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	allowed := false
+	for _, method := range methods {
+		allowed = allowed || r.Method == method
+	}
+	if !allowed {
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		apiFail(w, &apiErr{http.StatusMethodNotAllowed, r.Method + " is not allowed"})
+		return false
+	}
+
+	if !acceptsJSON(r.Header.Get("Accept")) {
+		apiFail(w, &apiErr{http.StatusNotAcceptable, "the API only responds with application/json"})
+		return false
+	}
+	return true
+}
+
+//acceptsJSON reports whether the Accept header accept allows a JSON response
+func acceptsJSON(accept string) bool {
+	if len(strings.TrimSpace(accept)) == 0 {
+		return true
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		switch {
+		case mediaType == "application/json", mediaType == "application/*", mediaType == "*/*", strings.HasSuffix(mediaType, "+json"):
+			return true
+		}
+	}
+	return false
+}
+
+//apiPaging reads the page and limit query parameters of r
+func apiPaging(r *http.Request) (page int, limit int, err error) {
+	page, limit = 1, defaultAPILimit
+	query := r.URL.Query()
+	if p := query.Get("page"); len(p) > 0 {
+		if page, err = strconv.Atoi(p); err != nil || page < 1 {
+			return 0, 0, &apiErr{http.StatusBadRequest, "page must be a number from 1"}
+		}
+	}
+	if l := query.Get("limit"); len(l) > 0 {
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 || limit > maxAPILimit {
+			return 0, 0, &apiErr{http.StatusBadRequest, fmt.Sprintf("limit must be a number from 1 to %d", maxAPILimit)}
+		}
+	}
+	if page > maxInt/limit {
+		return 0, 0, &apiErr{http.StatusBadRequest, fmt.Sprintf("page must be a number from 1 to %d", maxInt/limit)}
+	}
+	return page, limit, nil
+}
+
+//apiFail responds with err and the status matching it
+func apiFail(w http.ResponseWriter, err error) {
+	var invalid ValidationErrors
+	if errors.As(err, &invalid) {
+		if err := invalid.WriteJSON(w); err != nil {
+			log.Error(err.Error())
+		}
+		return
+	}
+
+	writeJSON(w, errStatus(err), map[string]string{"error": err.Error()})
+}
+
+//errStatus returns the HTTP status matching err
+func errStatus(err error) int {
+	var e *apiErr
+	var invalid ValidationErrors
+	var denied *ErrAccessDenied
+	var unique *ErrUniqueViolation
+	var stale *ErrStaleRecord
+	switch {
+	case errors.As(err, &e):
+		return e.status
+	case errors.As(err, &invalid):
+		return http.StatusUnprocessableEntity
+	case errors.As(err, &denied), errors.Is(err, ErrReadOnly):
+		return http.StatusForbidden
+	case errors.As(err, &unique), errors.As(err, &stale):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error(err.Error())
+	}
+}
@@ -0,0 +1,118 @@
+package gitdb_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestServerAPI(t *testing.T) {
+	cfg := getConfig()
+	cfg.EnableUI = true
+	cfg.UIPort = 4124
+	cfg.APIWrites = true
+	cfg.Factory = func(dataset string) gitdb.Model {
+		if dataset == "Message" {
+			return &Message{}
+		}
+		return nil
+	}
+	cfg.Roles = map[string]gitdb.Role{"reporting": {"Message": gitdb.PermRead}}
+	cfg.UIUsers = []gitdb.UIUser{{Name: "ada", Password: "s3cret"}, {Name: "bob", Password: "hunter2", Role: "reporting"}}
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	for i := 1; i <= 3; i++ {
+		insert(getTestMessageWithId(i), false)
+	}
+
+	do := func(user, method, path, contentType, body string) (*http.Response, string) {
+		req, _ := http.NewRequest(method, "http://localhost:4124"+path, strings.NewReader(body))
+		if len(user) > 0 {
+			req.SetBasicAuth(user, map[string]string{"ada": "s3cret", "bob": "hunter2"}[user])
+		}
+		if len(contentType) > 0 {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GitDB UI Server request failed: %s", err)
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return resp, string(b)
+	}
+
+	if resp, _ := do("", http.MethodGet, "/api/datasets", "", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("want: %d, got: %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	resp, body := do("ada", http.MethodGet, "/api/datasets/Message?limit=2&page=2", "", "")
+	page := struct {
+		Total   int
+		Records []struct{ ID string }
+	}{}
+	if err := json.Unmarshal([]byte(body), &page); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("want: page of Message, got: %d %s", resp.StatusCode, body)
+	}
+	if page.Total != 3 || len(page.Records) != 1 || page.Records[0].ID != "Message/b0/3" {
+		t.Errorf("want: Message/b0/3 of 3 records, got: %s", body)
+	}
+	if resp, body := do("ada", http.MethodGet, "/api/datasets/Message?limit=2&page=9223372036854775807", "", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("want: %d for a page past the largest offset, got: %d %s", http.StatusBadRequest, resp.StatusCode, body)
+	}
+
+	if resp, body := do("ada", http.MethodGet, "/api/records/Message/b0/1", "", ""); resp.StatusCode != http.StatusOK || !strings.Contains(body, `"id":"Message/b0/1"`) {
+		t.Errorf("want: Message/b0/1, got: %d %s", resp.StatusCode, body)
+	}
+	if resp, _ := do("ada", http.MethodGet, "/api/records/Message/b0/404", "", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("want: %d, got: %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost:4124/api/datasets", nil)
+	req.SetBasicAuth("ada", "s3cret")
+	req.Header.Set("Accept", "text/html")
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNotAcceptable {
+		t.Errorf("want: %d, got: %v (%v)", http.StatusNotAcceptable, resp, err)
+	} else {
+		resp.Body.Close()
+	}
+
+	//insert then update
+	message := `{"MessageId": 4, "From": "ada", "To": "bob", "Body": "hello"}`
+	if resp, _ := do("ada", http.MethodPost, "/api/datasets/Message", "text/plain", message); resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("want: %d, got: %d", http.StatusUnsupportedMediaType, resp.StatusCode)
+	}
+	resp, body = do("ada", http.MethodPost, "/api/datasets/Message", "application/json", message)
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("Location") != "/api/records/Message/b0/4" {
+		t.Errorf("want: %d, got: %d %s", http.StatusCreated, resp.StatusCode, body)
+	}
+	if resp, body := do("ada", http.MethodPost, "/api/records/Message/b0/4", "application/json", message); resp.StatusCode != http.StatusOK {
+		t.Errorf("want: %d, got: %d %s", http.StatusOK, resp.StatusCode, body)
+	}
+	if resp, _ := do("ada", http.MethodPost, "/api/records/Message/b0/5", "application/json", message); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("want: %d, got: %d", http.StatusBadRequest, resp.StatusCode)
+	}
+	if err := testDb.Exists("Message/b0/4"); err != nil {
+		t.Errorf("want: Message/b0/4 inserted, got: %s", err)
+	}
+
+	//bob can only read
+	if resp, _ := do("bob", http.MethodGet, "/api/records/Message/b0/4", "", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("want: %d, got: %d", http.StatusOK, resp.StatusCode)
+	}
+	if resp, _ := do("bob", http.MethodDelete, "/api/records/Message/b0/4", "", ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("want: %d, got: %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	if resp, _ := do("ada", http.MethodDelete, "/api/records/Message/b0/4", "", ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("want: %d, got: %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if resp, _ := do("ada", http.MethodDelete, "/api/records/Message/b0/4", "", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("want: %d, got: %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
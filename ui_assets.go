@@ -0,0 +1,80 @@
+package gitdb
+
+import (
+	"embed"
+	"html/template"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bouggo/log"
+)
+
+//embedded holds the pages, stylesheets and scripts of the web UI
+//
+//go:embed static
+var embedded embed.FS
+
+//uiAssets reads the files of the web UI from dir, when set, falling back to the embedded ones
+type uiAssets struct {
+	dir string
+}
+
+//open returns the content of name, a path under static/ e.g static/list.html
+func (a uiAssets) open(name string) ([]byte, error) {
+	if len(a.dir) > 0 {
+		data, err := ioutil.ReadFile(filepath.Join(a.dir, filepath.FromSlash(strings.TrimPrefix(name, "static/"))))
+		if err == nil || !os.IsNotExist(err) {
+			return data, err
+		}
+	}
+	return embedded.ReadFile(name)
+}
+
+//read returns the content of name or nothing if it can't be read
+func (a uiAssets) read(name string) []byte {
+	data, err := a.open(name)
+	if err != nil {
+		log.Error(err.Error())
+		return []byte("")
+	}
+	return data
+}
+
+//asset serves the stylesheets and scripts of the web UI
+func (u *router) asset(w http.ResponseWriter, r *http.Request) {
+	name := path.Join("static", path.Clean(r.URL.Path))
+	data, err := u.assets.open(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(name)))
+	w.Write(data)
+}
+
+//render executes templates, the page first followed by the templates it uses, with data
+func (u *router) render(w http.ResponseWriter, data interface{}, templates ...string) {
+	if viewModel, ok := data.(interface{ base() *baseViewModel }); ok {
+		viewModel.base().Databases = u.switcher()
+		viewModel.base().Base = u.path("")
+	}
+
+	t := template.New("overview")
+	for _, name := range templates {
+		var err error
+		if t, err = t.Parse(string(u.assets.read(name))); err != nil {
+			log.Error(err.Error())
+			return
+		}
+	}
+
+	if err := t.Execute(w, data); err != nil {
+		log.Error(err.Error())
+	}
+}
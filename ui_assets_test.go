@@ -0,0 +1,58 @@
+package gitdb_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUIDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitdb-ui")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(filepath.Join(dir, "css"), 0755)
+	if err := ioutil.WriteFile(filepath.Join(dir, "css", "app.css"), []byte("body {color: red;}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := getConfig()
+	cfg.EnableUI = true
+	cfg.UIPort = 4140
+	cfg.UIDir = dir
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	insert(getTestMessageWithId(1), false)
+
+	get := func(path string) (*http.Response, string) {
+		resp, err := http.Get("http://localhost:4140" + path)
+		if err != nil {
+			t.Fatalf("request for %s failed: %s", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	resp, body := get("/css/app.css")
+	if body != "body {color: red;}" || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/css") {
+		t.Errorf("want: app.css of Config.UIDir, got: %s %s", resp.Header.Get("Content-Type"), body)
+	}
+
+	//pages missing from Config.UIDir are embedded
+	if _, body := get("/"); !strings.Contains(body, "Overview") {
+		t.Errorf("want: embedded overview page, got: %s", body)
+	}
+	if _, body := get("/js/app.js"); len(body) == 0 {
+		t.Error("want: embedded app.js")
+	}
+	if resp, _ := get("/css/missing.css"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("want: 404 for a missing asset, got: %d", resp.StatusCode)
+	}
+}
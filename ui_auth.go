@@ -0,0 +1,304 @@
+package gitdb
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+//UIUser is a user who can sign in to the web UI
+type UIUser struct {
+	Name string
+	//Password is either the password or its bcrypt hash
+	Password string
+	//Role limits the user to the datasets the role of Config.Roles can read, and write through the
+	//REST API or the forms of the UI. Defaults to Config.UIRole
+	Role string
+	//Email attributes the changes the user makes through the REST API or the UI to Name <Email> instead of Config.User
+	Email string
+}
+
+//checkPassword reports whether password is the password of the user
+func (u UIUser) checkPassword(password string) bool {
+	if strings.HasPrefix(u.Password, "$2") {
+		return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) == nil
+	}
+	return subtle.ConstantTimeCompare([]byte(u.Password), []byte(password)) == 1
+}
+
+const uiSessionCookie = "gitdb_session"
+const uiSessionTTL = time.Hour * 12
+
+//uiSession is a signed in user of the web UI
+type uiSession struct {
+	user    UIUser
+	expires time.Time
+}
+
+//uiSessions holds the sessions of signed in users
+type uiSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*uiSession
+}
+
+//start creates a session for user and returns its id
+func (s *uiSessions) start(user UIUser) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions == nil {
+		s.sessions = map[string]*uiSession{}
+	}
+	//sweep the sessions that expired without signing out
+	now := time.Now()
+	for sid, session := range s.sessions {
+		if session.expires.Before(now) {
+			delete(s.sessions, sid)
+		}
+	}
+	s.sessions[id] = &uiSession{user: user, expires: time.Now().Add(uiSessionTTL)}
+	return id, nil
+}
+
+//get returns the user of session id if it has not expired
+func (s *uiSessions) get(id string) (UIUser, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return UIUser{}, false
+	}
+	if session.expires.Before(time.Now()) {
+		delete(s.sessions, id)
+		return UIUser{}, false
+	}
+	return session.user, true
+}
+
+func (s *uiSessions) end(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+//uiLoginAttempts is the number of failed sign ins from an address before it has to wait uiLoginBackoff, which
+//doubles with every further failure up to uiLoginMaxBackoff
+const uiLoginAttempts = 5
+const uiLoginBackoff = time.Second
+const uiLoginMaxBackoff = time.Minute * 5
+
+//loginThrottle holds the failed sign ins of each address
+type loginThrottle struct {
+	mu       sync.Mutex
+	failures map[string]*loginFailures
+}
+
+type loginFailures struct {
+	count int
+	until time.Time
+}
+
+//wait returns how long addr has to wait before it can try to sign in again
+func (t *loginThrottle) wait(addr string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if f, ok := t.failures[addr]; ok {
+		if wait := time.Until(f.until); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+//fail records a failed sign in from addr
+func (t *loginThrottle) fail(addr string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.failures == nil {
+		t.failures = map[string]*loginFailures{}
+	}
+
+	//sweep the addresses that have not failed since their backoff ran out
+	now := time.Now()
+	for a, f := range t.failures {
+		if now.Sub(f.until) > uiLoginMaxBackoff {
+			delete(t.failures, a)
+		}
+	}
+
+	f, ok := t.failures[addr]
+	if !ok {
+		f = &loginFailures{}
+		t.failures[addr] = f
+	}
+	f.count++
+	if f.count < uiLoginAttempts {
+		f.until = now
+		return
+	}
+	backoff := uiLoginMaxBackoff
+	if n := f.count - uiLoginAttempts; n < 9 {
+		if d := uiLoginBackoff << uint(n); d < backoff {
+			backoff = d
+		}
+	}
+	f.until = now.Add(backoff)
+}
+
+func (t *loginThrottle) reset(addr string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failures, addr)
+}
+
+//errTooManyLogins is returned in place of signing in an address that has to wait after failing to sign in
+func errTooManyLogins(wait time.Duration) error {
+	return &apiErr{http.StatusTooManyRequests, fmt.Sprintf("too many failed sign ins, try again in %s", wait.Round(time.Second))}
+}
+
+type uiUserKey struct{}
+
+//authenticate returns the UIUser with name and password
+func (u *router) authenticate(name string, password string) (UIUser, bool) {
+	for _, user := range u.cfg.UIUsers {
+		if user.Name == name && user.checkPassword(password) {
+			return user, true
+		}
+	}
+	return UIUser{}, false
+}
+
+//signIn is authenticate throttled by the address of r. It returns errTooManyLogins without checking the
+//password when the address has to wait after failing to sign in
+func (u *router) signIn(r *http.Request, name string, password string) (UIUser, bool, error) {
+	addr := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	if wait := u.logins.wait(addr); wait > 0 {
+		return UIUser{}, false, errTooManyLogins(wait)
+	}
+
+	user, ok := u.authenticate(name, password)
+	if ok {
+		u.logins.reset(addr)
+	} else {
+		u.logins.fail(addr)
+	}
+	return user, ok, nil
+}
+
+//WithUIUser returns a copy of ctx signed in to the web UI as user, for UIHandler mounted behind sign in of your own.
+//Requests with it skip the sign in of Config.UIUsers and are limited to the datasets of user.Role
+func WithUIUser(ctx context.Context, user UIUser) context.Context {
+	return context.WithValue(ctx, uiUserKey{}, user)
+}
+
+//requireLogin sends requests without a session or basic auth credentials of a UIUser to the login page,
+//or turns them away with 401 Unauthorized if they are for the REST API, GraphQL, /status or /events. /healthz and share links are open to all
+func (u *router) requireLogin(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(uiUserKey{}).(UIUser); ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if len(u.cfg.UIUsers) == 0 || r.URL.Path == "/login" || strings.HasPrefix(r.URL.Path, "/css/") || r.URL.Path == "/healthz" || strings.HasPrefix(r.URL.Path, "/snapshot/") {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		var user UIUser
+		ok := false
+		if cookie, err := r.Cookie(u.sessionCookie()); err == nil {
+			user, ok = u.sessions.get(cookie.Value)
+		}
+		if name, password, basic := r.BasicAuth(); !ok && basic {
+			var err error
+			if user, ok, err = u.signIn(r, name, password); err != nil {
+				apiFail(w, err)
+				return
+			}
+		}
+		if !ok && (strings.HasPrefix(r.URL.Path, "/api/") || strings.HasPrefix(r.URL.Path, "/graphql") || r.URL.Path == "/status" || r.URL.Path == "/events") {
+			w.Header().Set("WWW-Authenticate", `Basic realm="gitdb"`)
+			apiFail(w, &apiErr{http.StatusUnauthorized, "sign in with basic auth or a session cookie"})
+			return
+		}
+		if !ok {
+			http.Redirect(w, r, u.path("/login"), http.StatusSeeOther)
+			return
+		}
+
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), uiUserKey{}, user)))
+	})
+}
+
+//role returns the role the request is limited to
+func (u *router) role(r *http.Request) string {
+	if user, ok := r.Context().Value(uiUserKey{}).(UIUser); ok && len(user.Role) > 0 {
+		return user.Role
+	}
+	return u.cfg.UIRole
+}
+
+func (u *router) login(w http.ResponseWriter, r *http.Request) {
+	viewModel := &loginViewModel{}
+	viewModel.Title = "Sign in"
+
+	if r.Method == http.MethodPost {
+		user, ok, err := u.signIn(r, r.PostFormValue("name"), r.PostFormValue("password"))
+		if ok {
+			id, err := u.sessions.start(user)
+			if err == nil {
+				http.SetCookie(w, &http.Cookie{
+					Name:     u.sessionCookie(),
+					Value:    id,
+					Path:     u.path("/"),
+					Expires:  time.Now().Add(uiSessionTTL),
+					HttpOnly: true,
+					Secure:   r.TLS != nil,
+					SameSite: http.SameSiteStrictMode,
+				})
+				http.Redirect(w, r, u.path("/"), http.StatusSeeOther)
+				return
+			}
+		}
+		if err != nil {
+			viewModel.Error = err.Error()
+			w.WriteHeader(http.StatusTooManyRequests)
+		} else {
+			viewModel.Error = "Invalid name or password"
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}
+
+	u.render(w, viewModel, "static/login.html")
+}
+
+//logout ends the session when the logout form is posted so other sites can't sign users out with a link
+func (u *router) logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		u.editFail(w, &apiErr{http.StatusMethodNotAllowed, "sign out by posting the logout form"})
+		return
+	}
+	if cookie, err := r.Cookie(u.sessionCookie()); err == nil {
+		u.sessions.end(cookie.Value)
+	}
+	http.SetCookie(w, &http.Cookie{Name: u.sessionCookie(), Path: u.path("/"), MaxAge: -1, HttpOnly: true, Secure: r.TLS != nil})
+	http.Redirect(w, r, u.path("/login"), http.StatusSeeOther)
+}
@@ -0,0 +1,325 @@
+package gitdb
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/bouggo/log"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//consoleQuery is a query of the query console e.g
+//Bookings where Status = confirmed and Amount >= 5000 order by CheckIn desc limit 20
+type consoleQuery struct {
+	dataset string
+	filters []indexFilter
+	sortBy  string
+	desc    bool
+	//limit is how many records the query returns at most, 0 when it doesn't say
+	limit  int
+	offset int
+}
+
+//consoleToken is a word, operator or quoted string of a console query
+type consoleToken struct {
+	text   string
+	quoted bool
+}
+
+//keyword reports whether t is the unquoted keyword word
+func (t consoleToken) keyword(word string) bool {
+	return !t.quoted && strings.EqualFold(t.text, word)
+}
+
+//parseConsoleQuery parses a query of the query console:
+//<dataset> [where <index> <op> <value> [and ...]] [order by <index> [asc|desc]] [limit <n>] [offset <n>]
+//where op is one of = contains startswith endswith > >= < <=
+func parseConsoleQuery(s string) (*consoleQuery, error) {
+	tokens, err := lexConsoleQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 || tokens[0].quoted {
+		return nil, errors.New("query must start with the name of a dataset")
+	}
+
+	q := &consoleQuery{dataset: tokens[0].text}
+	pos := 1
+	next := func(what string) (consoleToken, error) {
+		if pos >= len(tokens) {
+			return consoleToken{}, fmt.Errorf("expected %s at the end of the query", what)
+		}
+		pos++
+		return tokens[pos-1], nil
+	}
+	number := func(what string) (int, error) {
+		t, err := next(what)
+		if err != nil {
+			return 0, err
+		}
+		n, err := strconv.Atoi(t.text)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%s must be a number, got %s", what, t.text)
+		}
+		return n, nil
+	}
+
+	for pos < len(tokens) {
+		t, _ := next("")
+		switch {
+		case t.keyword("where"):
+			for {
+				filter, err := parseConsoleFilter(next)
+				if err != nil {
+					return nil, err
+				}
+				q.filters = append(q.filters, filter)
+				if pos >= len(tokens) || !tokens[pos].keyword("and") {
+					break
+				}
+				pos++
+			}
+		case t.keyword("order"):
+			if by, err := next("by"); err != nil || !by.keyword("by") {
+				return nil, errors.New("expected by after order")
+			}
+			index, err := next("an index to order by")
+			if err != nil {
+				return nil, err
+			}
+			q.sortBy = index.text
+			if pos < len(tokens) && (tokens[pos].keyword("asc") || tokens[pos].keyword("desc")) {
+				q.desc = tokens[pos].keyword("desc")
+				pos++
+			}
+		case t.keyword("limit"):
+			if q.limit, err = number("limit"); err != nil {
+				return nil, err
+			}
+		case t.keyword("offset"):
+			if q.offset, err = number("offset"); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %s, expected where, order by, limit or offset", t.text)
+		}
+	}
+	return q, nil
+}
+
+//parseConsoleFilter parses <index> <op> <value> from the tokens next returns
+func parseConsoleFilter(next func(what string) (consoleToken, error)) (indexFilter, error) {
+	index, err := next("an index")
+	if err != nil {
+		return indexFilter{}, err
+	}
+	op, err := next("an operator after " + index.text)
+	if err != nil {
+		return indexFilter{}, err
+	}
+	value, err := next("a value after " + op.text)
+	if err != nil {
+		return indexFilter{}, err
+	}
+
+	filter := indexFilter{index: index.text, value: value.text}
+	var literal interface{} = value.text
+	if n, err := strconv.ParseFloat(value.text, 64); err == nil && !value.quoted {
+		literal = n
+	}
+
+	switch strings.ToLower(op.text) {
+	case "=":
+		filter.mode = SearchEquals
+	case "contains":
+		filter.mode = SearchContains
+	case "startswith":
+		filter.mode = SearchStartsWith
+	case "endswith":
+		filter.mode = SearchEndsWith
+	case ">":
+		filter.conds = []Condition{Gt(literal)}
+	case ">=":
+		filter.conds = []Condition{Gte(literal)}
+	case "<":
+		filter.conds = []Condition{Lt(literal)}
+	case "<=":
+		filter.conds = []Condition{Lte(literal)}
+	default:
+		return indexFilter{}, fmt.Errorf("unknown operator %s, use one of = contains startswith endswith > >= < <=", op.text)
+	}
+	return filter, nil
+}
+
+//lexConsoleQuery splits s into words, the operators = > >= < <= and quoted strings
+func lexConsoleQuery(s string) ([]consoleToken, error) {
+	var tokens []consoleToken
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != c; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				b.WriteRune(runes[j])
+			}
+			if j == len(runes) {
+				return nil, errors.New("unterminated string " + string(runes[i:]))
+			}
+			tokens = append(tokens, consoleToken{text: b.String(), quoted: true})
+			i = j + 1
+		case strings.ContainsRune("=<>", c):
+			j := i + 1
+			if c != '=' && j < len(runes) && runes[j] == '=' {
+				j++
+			}
+			tokens = append(tokens, consoleToken{text: string(runes[i:j])})
+			i = j
+		default:
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && !strings.ContainsRune("=<>\"'", runes[j]) {
+				j++
+			}
+			tokens = append(tokens, consoleToken{text: string(runes[i:j])})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+//consoleEndpoints maps the path of the query console to its handler
+func (u *router) consoleEndpoints() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/console": u.console,
+	}
+}
+
+//console runs the query typed in against the dataset it names and shows the records it returns,
+//or exports them as CSV or JSON when format is csv or json
+func (u *router) console(w http.ResponseWriter, r *http.Request) {
+	viewModel := &consoleViewModel{Query: strings.TrimSpace(r.URL.Query().Get("q"))}
+	viewModel.Title = "Query console"
+	viewModel.DataSets = u.readable(u.role(r))
+	if len(viewModel.Query) == 0 {
+		u.render(w, viewModel, "static/console.html", "static/sidebar.html")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	q, records, total, err := u.runConsoleQuery(r, viewModel.Query, len(format) > 0)
+	if err != nil && len(format) > 0 {
+		u.editFail(w, err)
+		return
+	}
+	if err != nil {
+		viewModel.Error = err.Error()
+		u.render(w, viewModel, "static/console.html", "static/sidebar.html")
+		return
+	}
+
+	if len(format) > 0 {
+		u.export(w, q.dataset, q.dataset, records, format)
+		return
+	}
+
+	viewModel.DataSet = q.dataset
+	viewModel.Table = tablulate(records, func(data map[string]interface{}) { u.redact(q.dataset, data) })
+	viewModel.Total = total
+	viewModel.Indexes = u.searchable(q.dataset)
+	u.render(w, viewModel, "static/console.html", "static/sidebar.html")
+}
+
+//export sends records of dataset, with redacted fields masked, as a file called name in format, csv or json
+func (u *router) export(w http.ResponseWriter, dataset string, name string, records []*db.Record, format string) {
+	switch format {
+	case "json":
+		exported := make([]*apiRecord, len(records))
+		for i, record := range records {
+			exported[i] = u.apiRecordOf(dataset, record)
+		}
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.json"`)
+		writeJSON(w, http.StatusOK, exported)
+	case "csv":
+		table := tablulate(records, func(data map[string]interface{}) { u.redact(dataset, data) })
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.csv"`)
+		cw := csv.NewWriter(w)
+		cw.Write(append([]string{"id"}, table.Headers...))
+		for _, row := range table.Rows {
+			line := []string{row.ID}
+			for _, cell := range row.Cells {
+				line = append(line, cell.Full)
+			}
+			cw.Write(line)
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			log.Error(err.Error())
+		}
+	default:
+		u.editFail(w, &apiErr{http.StatusBadRequest, "unknown format " + format + ", use csv or json"})
+	}
+}
+
+//runConsoleQuery runs text against the dataset it names if the request can read it and returns the
+//records it returns and how many records match it. Exports have every record, the console a page of them
+func (u *router) runConsoleQuery(r *http.Request, text string, export bool) (*consoleQuery, []*db.Record, int, error) {
+	q, err := parseConsoleQuery(text)
+	if err != nil {
+		return nil, nil, 0, &apiErr{http.StatusBadRequest, err.Error()}
+	}
+	if err := u.can(r, q.dataset, PermRead); err != nil {
+		return nil, nil, 0, err
+	}
+	if u.findDataset(r, q.dataset) == nil {
+		return nil, nil, 0, &apiErr{http.StatusNotFound, "Dataset (" + q.dataset + ") does not exist"}
+	}
+	indexes := u.searchable(q.dataset)
+	for _, filter := range q.filters {
+		if !containsString(indexes, filter.index) {
+			return nil, nil, 0, &apiErr{http.StatusBadRequest, filter.index + " is not an index of " + q.dataset}
+		}
+	}
+	//pageRecords sorts by id when it can't sort by the index asked for
+	if len(q.sortBy) > 0 && !containsString(indexes, q.sortBy) {
+		return nil, nil, 0, &apiErr{http.StatusBadRequest, q.sortBy + " is not an index of " + q.dataset}
+	}
+
+	limit := q.limit
+	if !export && (limit == 0 || limit > listPageSize) {
+		limit = listPageSize
+	} else if limit == 0 {
+		limit = math.MaxInt32
+	}
+	records, total, err := u.db.pageRecords(pageQuery{
+		dataset: q.dataset,
+		filters: q.filters,
+		sortBy:  q.sortBy,
+		desc:    q.desc,
+		offset:  q.offset,
+		limit:   limit,
+		hidden:  func(index string) bool { return u.isRedacted(q.dataset, index) },
+	})
+	return q, records, total, err
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package gitdb_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestConsole(t *testing.T) {
+	cfg := getConfig()
+	cfg.EnableUI = true
+	cfg.UIPort = 4144
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	insert(getTestMessageWithId(1), false)
+	insert(getTestMessageWithId(2), false)
+	m := getTestMessageWithId(3)
+	m.From = "carol@example.com"
+	insert(m, true)
+
+	get := func(query string, format string) (int, string) {
+		u := "http://localhost:4144/console?" + url.Values{"q": {query}, "format": {format}}.Encode()
+		resp, err := http.Get(u)
+		if err != nil {
+			t.Fatalf("console request failed: %s", err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if _, page := get("Message where From = carol@example.com", ""); !strings.Contains(page, "Message/b0/3") || !strings.Contains(page, "1 of 1 matching records") {
+		t.Errorf("want: Message/b0/3 found, got: %s", page)
+	}
+	if _, page := get(`Message where From startswith "ALICE" order by From desc limit 1`, ""); !strings.Contains(page, "1 of 2 matching records") {
+		t.Errorf("want: 1 of the 2 messages from alice, got: %s", page)
+	}
+	if _, page := get("Message where To = bob@example.com", ""); !strings.Contains(page, "To is not an index of Message") {
+		t.Errorf("want: To refused as it isn't indexed, got: %s", page)
+	}
+	if _, page := get("Message where From", ""); !strings.Contains(page, `class="error"`) {
+		t.Errorf("want: incomplete query refused, got: %s", page)
+	}
+
+	status, body := get("Message where From contains example order by From", "csv")
+	lines, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	if status != http.StatusOK || err != nil || len(lines) != 4 || lines[0][0] != "id" || lines[3][0] != "Message/b0/3" {
+		t.Errorf("want: header and 3 messages exported as CSV, got: %d %s", status, body)
+	}
+
+	status, body = get("Message where From contains alice", "json")
+	var records []struct {
+		ID   string
+		Data map[string]interface{}
+	}
+	if err := json.Unmarshal([]byte(body), &records); status != http.StatusOK || err != nil || len(records) != 2 {
+		t.Errorf("want: 2 messages exported as JSON, got: %d %s", status, body)
+	}
+
+	if status, _ := get("Message order by To", "csv"); status != http.StatusBadRequest {
+		t.Errorf("want: 400 ordering by a field that isn't indexed, got: %d", status)
+	}
+}
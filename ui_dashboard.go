@@ -0,0 +1,211 @@
+package gitdb
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bouggo/log"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//dashboardDays is how many days back the dashboard charts go
+const dashboardDays = 30
+
+const (
+	barWidth            = 200
+	sparkWidth          = 120
+	sparkHeight         = 24
+	activityHeight      = 100
+	activityColumnWidth = 16
+)
+
+//dashboardRow is a dataset on the dashboard with its bars and the sparkline of its growth
+type dashboardRow struct {
+	*db.Dataset
+	//RecordsBar and SizeBar are the widths of the bars of the record count and size of the dataset
+	RecordsBar int
+	SizeBar    int
+	//Commits is how many commits changed the dataset in the days of the dashboard
+	Commits int
+	//Trend are the points of the sparkline of the number of blocks at the end of each day
+	Trend       string
+	BlocksFrom  int
+	BlocksTo    int
+	trendBlocks []int
+}
+
+//activityColumn is a day of the commit activity chart
+type activityColumn struct {
+	Day     string
+	Commits int
+	X       int
+	Y       int
+	Height  int
+}
+
+//datasetActivity is the commits that changed a dataset and the blocks it had at the end of each day
+type datasetActivity struct {
+	commits []commitEntry
+	blocks  []int
+}
+
+//dashboard shows the size of the datasets the request can read, how they grew and how often they were committed to
+func (u *router) dashboard(w http.ResponseWriter, r *http.Request) {
+	viewModel := &dashboardViewModel{Days: dashboardDays}
+	viewModel.Title = "Dashboard"
+	viewModel.DataSets = u.readable(u.role(r))
+
+	start := dashboardStart(time.Now())
+	perDay := make([]int, dashboardDays)
+	seen := map[string]bool{}
+
+	var maxRecords int
+	var maxSize int64
+	for _, ds := range viewModel.DataSets {
+		row := &dashboardRow{Dataset: ds}
+		if count := ds.RecordCount(); count > maxRecords {
+			maxRecords = count
+		}
+		if size := ds.Size(); size > maxSize {
+			maxSize = size
+		}
+
+		activity, err := u.db.datasetActivity(ds.Name(), start)
+		if err != nil {
+			log.Error(fmt.Sprintf("Failed to read the activity of %s: %s", ds.Name(), err))
+			activity = &datasetActivity{}
+		}
+		for _, commit := range activity.commits {
+			if day := int(commit.time.Sub(start).Hours() / 24); day >= 0 && day < dashboardDays {
+				row.Commits++
+				if !seen[commit.hash] {
+					seen[commit.hash] = true
+					perDay[day]++
+				}
+			}
+		}
+		row.trendBlocks = activity.blocks
+		viewModel.Rows = append(viewModel.Rows, row)
+	}
+
+	for _, row := range viewModel.Rows {
+		row.RecordsBar = scale(int64(row.RecordCount()), int64(maxRecords), barWidth)
+		row.SizeBar = scale(row.Size(), maxSize, barWidth)
+		row.Trend = sparkline(row.trendBlocks)
+		if len(row.trendBlocks) > 0 {
+			row.BlocksFrom, row.BlocksTo = row.trendBlocks[0], row.trendBlocks[len(row.trendBlocks)-1]
+		}
+	}
+
+	var maxCommits int
+	for _, commits := range perDay {
+		if commits > maxCommits {
+			maxCommits = commits
+		}
+	}
+	for i, commits := range perDay {
+		height := scale(int64(commits), int64(maxCommits), activityHeight)
+		viewModel.Activity = append(viewModel.Activity, &activityColumn{
+			Day:     start.AddDate(0, 0, i).Format("2 Jan"),
+			Commits: commits,
+			X:       i * activityColumnWidth,
+			Y:       activityHeight - height,
+			Height:  height,
+		})
+		viewModel.Commits += commits
+	}
+	viewModel.ActivityWidth = dashboardDays * activityColumnWidth
+	viewModel.ActivityHeight = activityHeight
+
+	u.render(w, viewModel, "static/dashboard.html", "static/sidebar.html")
+}
+
+//dashboardStart returns the start of the first day of the dashboard ending today
+func dashboardStart(now time.Time) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return today.AddDate(0, 0, 1-dashboardDays)
+}
+
+//datasetActivity returns the commits that changed dataset and how many blocks it had
+//at the end of each of the days of the dashboard starting from start
+func (g *gitdb) datasetActivity(dataset string, start time.Time) (*datasetActivity, error) {
+	entries, err := g.gitDriver.log(dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	activity := &datasetActivity{commits: entries, blocks: make([]int, dashboardDays)}
+	counted := map[string]int{}
+	for day := range activity.blocks {
+		end := start.AddDate(0, 0, day+1)
+		//entries are most recent first
+		for _, entry := range entries {
+			if !entry.time.Before(end) {
+				continue
+			}
+			blocks, ok := counted[entry.hash]
+			if !ok {
+				if blocks, err = g.blocksAt(entry.hash, dataset); err != nil {
+					return nil, err
+				}
+				counted[entry.hash] = blocks
+			}
+			activity.blocks[day] = blocks
+			break
+		}
+	}
+	return activity, nil
+}
+
+//blocksAt returns how many blocks dataset had at commit
+func (g *gitdb) blocksAt(commit string, dataset string) (int, error) {
+	files, err := g.gitDriver.lsTree(commit, dataset+"/", false)
+	if err != nil {
+		return 0, err
+	}
+
+	blocks := 0
+	for _, file := range files {
+		name := path.Base(file)
+		if strings.HasSuffix(name, ".json") && !strings.HasPrefix(name, ".") {
+			blocks++
+		}
+	}
+	return blocks, nil
+}
+
+//scale returns value as a share of length where max is the whole length
+func scale(value int64, max int64, length int) int {
+	if max <= 0 {
+		return 0
+	}
+	return int(value * int64(length) / max)
+}
+
+//sparkline returns the points of an svg polyline of values
+func sparkline(values []int) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	max := 0
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+
+	points := make([]string, len(values))
+	for i, v := range values {
+		x := 0
+		if len(values) > 1 {
+			x = i * sparkWidth / (len(values) - 1)
+		}
+		y := sparkHeight - 1 - scale(int64(v), int64(max), sparkHeight-2)
+		points[i] = fmt.Sprintf("%d,%d", x, y)
+	}
+	return strings.Join(points, " ")
+}
@@ -0,0 +1,37 @@
+package gitdb_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDashboard(t *testing.T) {
+	cfg := getConfig()
+	cfg.EnableUI = true
+	cfg.UIPort = 4142
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	insert(getTestMessageWithId(1), true)
+	insert(getTestMessageWithId(2), true)
+
+	resp, err := http.Get("http://localhost:4142/dashboard")
+	if err != nil {
+		t.Fatalf("dashboard request failed: %s", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	page := string(body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(page, `data-view="/list/Message"`) {
+		t.Fatalf("want: Message on the dashboard, got: %d %s", resp.StatusCode, page)
+	}
+	if strings.Contains(page, "Commits in the last 30 days: 0") {
+		t.Errorf("want: commits of today in the activity chart, got: %s", page)
+	}
+	if !strings.Contains(page, "&rarr; 1</td>") {
+		t.Errorf("want: Message grown to 1 block, got: %s", page)
+	}
+}
@@ -0,0 +1,430 @@
+package gitdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+//formField is an input of the form editing a record, generated from the JSON Schema of its model
+type formField struct {
+	Name string
+	//Input is the kind of input the field is edited with: text, number, checkbox, select, password or json
+	Input    string
+	Value    string
+	Checked  bool
+	Options  []string
+	Required bool
+	Errors   []string
+	//Step is the step of number inputs, 1 for integers
+	Step string
+
+	kind     string
+	format   string
+	nullable bool
+}
+
+//editEndpoints maps the paths of the forms editing records to their handlers
+func (u *router) editEndpoints() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/new/{dataset}":                     u.newRecord,
+		"/edit/{dataset}/{block}/{record}":   u.editRecord,
+		"/delete/{dataset}/{block}/{record}": u.deleteRecord,
+	}
+}
+
+//newRecord shows the form creating a record of a dataset and inserts it when the form is posted
+func (u *router) newRecord(w http.ResponseWriter, r *http.Request) {
+	dataset := mux.Vars(r)["dataset"]
+	if err := u.canEdit(r, dataset); err != nil {
+		u.editFail(w, err)
+		return
+	}
+
+	m, err := u.apiModel(r, dataset)
+	if err != nil {
+		u.editFail(w, err)
+		return
+	}
+
+	action := "/new/" + dataset
+	if t := r.URL.Query().Get("type"); len(t) > 0 {
+		action += "?type=" + url.QueryEscape(t)
+	}
+	u.recordForm(w, r, dataset, "", action, m)
+}
+
+//editRecord shows the form editing a record and updates it when the form is posted
+func (u *router) editRecord(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	dataset := vars["dataset"]
+	id := dataset + "/" + vars["block"] + "/" + vars["record"]
+	if err := u.canEdit(r, dataset); err != nil {
+		u.editFail(w, err)
+		return
+	}
+
+	record, err := u.db.doget(id)
+	if err != nil {
+		u.editFail(w, &apiErr{http.StatusNotFound, err.Error()})
+		return
+	}
+	m, err := u.db.Decode(record)
+	if err != nil {
+		u.editFail(w, &apiErr{http.StatusUnprocessableEntity, err.Error()})
+		return
+	}
+
+	u.recordForm(w, r, dataset, id, "/edit/"+id, m)
+}
+
+//deleteRecord deletes a record when its delete form is posted
+func (u *router) deleteRecord(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	dataset := vars["dataset"]
+	id := dataset + "/" + vars["block"] + "/" + vars["record"]
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		u.editFail(w, &apiErr{http.StatusMethodNotAllowed, "records are deleted by posting their delete form"})
+		return
+	}
+	if err := u.canEdit(r, dataset); err != nil {
+		u.editFail(w, err)
+		return
+	}
+	if err := u.can(r, dataset, PermDelete); err != nil {
+		u.editFail(w, err)
+		return
+	}
+	if err := u.db.Exists(id); err != nil {
+		u.editFail(w, &apiErr{http.StatusNotFound, err.Error()})
+		return
+	}
+
+	if err := u.apiConn(r).Delete(id); err != nil {
+		u.editFail(w, err)
+		return
+	}
+	u.refreshAt = time.Time{}
+	http.Redirect(w, r, u.path("/list/"+dataset), http.StatusSeeOther)
+}
+
+//recordForm renders the form editing m, the record id of dataset or a new one when id is empty. When the form
+//is posted its values are decoded into m and saved, showing the errors of each field next to it if it isn't valid
+func (u *router) recordForm(w http.ResponseWriter, r *http.Request, dataset string, id string, action string, m Model) {
+	fields, err := u.formFields(dataset, m, len(id) == 0)
+	if err != nil {
+		u.editFail(w, &apiErr{http.StatusUnprocessableEntity, err.Error()})
+		return
+	}
+
+	viewModel := &editViewModel{DataSet: dataset, ID: id, Action: action, Fields: fields}
+	viewModel.Title = "New " + dataset
+	if len(id) > 0 {
+		viewModel.Title = "Edit " + id
+	}
+	viewModel.DataSets = u.readable(u.role(r))
+
+	if r.Method == http.MethodPost {
+		err := u.saveForm(r, id, m, fields)
+		if err == nil {
+			u.refreshAt = time.Time{}
+			http.Redirect(w, r, u.path("/list/"+dataset), http.StatusSeeOther)
+			return
+		}
+
+		viewModel.Errors = fieldErrors(fields, err)
+		w.WriteHeader(errStatus(err))
+	}
+
+	u.render(w, viewModel, "static/edit.html", "static/sidebar.html")
+}
+
+//saveForm decodes the values posted for fields into m and inserts it. id is the id m must keep
+func (u *router) saveForm(r *http.Request, id string, m Model, fields []*formField) error {
+	if !sameOrigin(r) {
+		return &apiErr{http.StatusForbidden, "forms can only be posted from the web UI"}
+	}
+	if err := r.ParseForm(); err != nil {
+		return &apiErr{http.StatusBadRequest, err.Error()}
+	}
+
+	values := map[string]json.RawMessage{}
+	invalid := ValidationErrors{}
+	for _, f := range fields {
+		value := r.PostForm.Get(f.Name)
+		f.Value, f.Checked = value, len(value) > 0
+		//redacted fields are never shown so they are only changed when a new value is given
+		if f.Input == "password" && len(value) == 0 {
+			continue
+		}
+
+		raw, err := f.parse(value)
+		if err != nil {
+			invalid.Add(f.Name, f.Name+" "+err.Error())
+			continue
+		}
+		values[f.Name] = raw
+	}
+	for _, f := range fields {
+		if f.Input == "password" {
+			f.Value = ""
+		}
+	}
+	if err := invalid.Err(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return &apiErr{http.StatusBadRequest, err.Error()}
+	}
+	if len(id) > 0 && ID(m) != id {
+		return &apiErr{http.StatusBadRequest, fmt.Sprintf("the record would move to %s. The fields its id is made of can't be changed", ID(m))}
+	}
+
+	return u.apiConn(r).Insert(m)
+}
+
+//formFields returns the inputs of the fields of m, as described by its JSON Schema, filled with its values.
+//Redacted fields are edited with password inputs which are left empty
+func (u *router) formFields(dataset string, m Model, isNew bool) ([]*formField, error) {
+	schema, err := modelJSONSchema(m)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := make([]*formField, 0, len(names))
+	for _, name := range names {
+		property, _ := properties[name].(map[string]interface{})
+		f := newFormField(name, property)
+		if u.meta != nil && u.meta.isRedacted(dataset, name) {
+			f.Input = "password"
+			f.Required = isNew && f.Required
+			fields = append(fields, f)
+			continue
+		}
+		f.fill(values[name])
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+//newFormField returns the input of the field name described by the JSON Schema property
+func newFormField(name string, property map[string]interface{}) *formField {
+	f := &formField{Name: name, Input: "json"}
+	switch typ := property["type"].(type) {
+	case string:
+		f.kind = typ
+	case []string:
+		f.kind, f.nullable = typ[0], true
+	}
+	f.format, _ = property["format"].(string)
+	//fields tagged gitdb:"required" have a minLength of 1
+	if n, ok := property["minLength"].(int); ok && n > 0 {
+		f.Required = true
+	}
+
+	switch f.kind {
+	case "string":
+		f.Input = "text"
+	case "integer":
+		f.Input, f.Step = "number", "1"
+	case "number":
+		f.Input, f.Step = "number", "any"
+	case "boolean":
+		f.Input = "checkbox"
+	}
+	if enum, ok := property["enum"].([]interface{}); ok {
+		f.Input = "select"
+		for _, v := range enum {
+			f.Options = append(f.Options, fmt.Sprint(v))
+		}
+	}
+	return f
+}
+
+//fill sets the value of f to the JSON value raw
+func (f *formField) fill(raw json.RawMessage) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return
+	}
+
+	switch f.Input {
+	case "checkbox":
+		f.Checked = string(raw) == "true"
+	case "json":
+		var buf bytes.Buffer
+		if json.Indent(&buf, raw, "", "  ") == nil {
+			f.Value = buf.String()
+		}
+	default:
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			f.Value = s
+			return
+		}
+		f.Value = string(raw)
+	}
+}
+
+//parse returns the JSON value of the field posted as value
+func (f *formField) parse(value string) (json.RawMessage, error) {
+	if f.kind != "string" {
+		value = strings.TrimSpace(value)
+	}
+	if len(value) == 0 && (f.nullable || f.Input == "json") {
+		return json.RawMessage("null"), nil
+	}
+
+	switch f.kind {
+	case "boolean":
+		return json.Marshal(len(value) > 0)
+	case "integer":
+		if len(value) == 0 {
+			value = "0"
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errors.New("must be a whole number")
+		}
+		return json.Marshal(n)
+	case "number":
+		if len(value) == 0 {
+			value = "0"
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, errors.New("must be a number")
+		}
+		return json.Marshal(n)
+	case "string":
+		if f.format == "date-time" {
+			if len(value) == 0 {
+				value = time.Time{}.Format(time.RFC3339)
+			}
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				return nil, errors.New("must be a time like " + time.RFC3339)
+			}
+		}
+		return json.Marshal(value)
+	}
+
+	if !json.Valid([]byte(value)) {
+		return nil, errors.New("must be valid JSON")
+	}
+	return json.RawMessage(value), nil
+}
+
+//fieldErrors shows the errors of each field of err next to it and returns the rest
+func fieldErrors(fields []*formField, err error) []string {
+	var invalid ValidationErrors
+	if !errors.As(err, &invalid) {
+		return []string{err.Error()}
+	}
+
+	byName := map[string]*formField{}
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+
+	var rest []string
+	for _, field := range sortedKeys(invalid) {
+		//errors of nested fields e.g Address.City are shown next to Address
+		if f, ok := byName[strings.SplitN(field, ".", 2)[0]]; ok {
+			f.Errors = append(f.Errors, invalid[field]...)
+			continue
+		}
+		rest = append(rest, invalid[field]...)
+	}
+	return rest
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//canEdit returns an error unless records of dataset can be edited with the forms of the UI by the request
+func (u *router) canEdit(r *http.Request, dataset string) error {
+	if !u.cfg.UIWrites {
+		return &apiErr{http.StatusForbidden, "records can't be edited in the web UI without Config.UIWrites"}
+	}
+	return u.can(r, dataset, PermWrite)
+}
+
+//editable reports whether the request can create and edit records of dataset with the forms of the UI
+func (u *router) editable(r *http.Request, dataset string) bool {
+	if u.canEdit(r, dataset) != nil {
+		return false
+	}
+	_, polymorphic := u.cfg.Types[dataset]
+	return polymorphic || u.cfg.factory(dataset) != nil
+}
+
+//newLinks returns the links to the forms creating records of dataset, one for each of its Config.Types
+func (u *router) newLinks(dataset string) []*newLink {
+	types, ok := u.cfg.Types[dataset]
+	if !ok {
+		return []*newLink{{Label: "New record", URL: "/new/" + dataset}}
+	}
+
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	links := make([]*newLink, len(names))
+	for i, name := range names {
+		links[i] = &newLink{Label: "New " + name, URL: "/new/" + dataset + "?type=" + url.QueryEscape(name)}
+	}
+	return links
+}
+
+//editFail responds with err and the status matching it
+func (u *router) editFail(w http.ResponseWriter, err error) {
+	w.WriteHeader(errStatus(err))
+	w.Write([]byte(err.Error()))
+}
+
+//sameOrigin reports whether r, if sent by a browser that names its origin, comes from a page of the UI
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if len(origin) == 0 {
+		return true
+	}
+	o, err := url.Parse(origin)
+	return err == nil && o.Host == r.Host
+}
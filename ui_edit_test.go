@@ -0,0 +1,116 @@
+package gitdb_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestServerEdit(t *testing.T) {
+	cfg := getConfig()
+	cfg.EnableUI = true
+	cfg.UIPort = 4126
+	cfg.UIWrites = true
+	cfg.Factory = func(dataset string) gitdb.Model {
+		if dataset == "Message" {
+			return &Message{}
+		}
+		return nil
+	}
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	insert(getTestMessageWithId(1), false)
+
+	//redirects are not followed to check where saves lead
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	do := func(method, path string, form url.Values) (int, string) {
+		req, _ := http.NewRequest(method, "http://localhost:4126"+path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("GitDB UI Server request failed: %s", err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if _, body := do(http.MethodGet, "/list/Message", nil); !strings.Contains(body, `href="/new/Message"`) {
+		t.Errorf("want: link to new record form, got: %s", body)
+	}
+	if _, body := do(http.MethodGet, "/view/Message/b0/r0", nil); !strings.Contains(body, `href="/edit/Message/b0/1"`) {
+		t.Errorf("want: link to edit form, got: %s", body)
+	}
+
+	_, body := do(http.MethodGet, "/edit/Message/b0/1", nil)
+	if !strings.Contains(body, `name="MessageId" value="1"`) || !strings.Contains(body, `name="Body" value="Hello"`) {
+		t.Errorf("want: form filled with Message/b0/1, got: %s", body)
+	}
+
+	form := url.Values{"MessageId": {"2"}, "From": {"ada"}, "To": {"bob"}, "Body": {"hello"}}
+	if status, body := do(http.MethodPost, "/new/Message", form); status != http.StatusSeeOther {
+		t.Errorf("want: %d, got: %d %s", http.StatusSeeOther, status, body)
+	}
+	if err := testDb.Exists("Message/b0/2"); err != nil {
+		t.Errorf("want: Message/b0/2 created, got: %s", err)
+	}
+	if got := headCommitMessage(t); !strings.Contains(got, "Message/b0/2") {
+		t.Errorf("want: commit of Message/b0/2, got: %s", got)
+	}
+
+	form.Set("MessageId", "two")
+	status, body := do(http.MethodPost, "/new/Message", form)
+	if status != http.StatusUnprocessableEntity || !strings.Contains(body, "MessageId must be a whole number") {
+		t.Errorf("want: %d with inline error, got: %d %s", http.StatusUnprocessableEntity, status, body)
+	}
+
+	form.Set("MessageId", "1")
+	form.Set("Body", "edited")
+	if status, body := do(http.MethodPost, "/edit/Message/b0/1", form); status != http.StatusSeeOther {
+		t.Errorf("want: %d, got: %d %s", http.StatusSeeOther, status, body)
+	}
+	m := &Message{}
+	if err := testDb.Get("Message/b0/1", m); err != nil || m.Body != "edited" {
+		t.Errorf("want: Message/b0/1 edited, got: %+v (%v)", m, err)
+	}
+
+	//the fields the id is made of can't be changed
+	form.Set("MessageId", "3")
+	if status, _ := do(http.MethodPost, "/edit/Message/b0/1", form); status != http.StatusBadRequest {
+		t.Errorf("want: %d, got: %d", http.StatusBadRequest, status)
+	}
+
+	if status, _ := do(http.MethodGet, "/delete/Message/b0/2", nil); status != http.StatusMethodNotAllowed {
+		t.Errorf("want: %d, got: %d", http.StatusMethodNotAllowed, status)
+	}
+	if status, _ := do(http.MethodPost, "/delete/Message/b0/2", nil); status != http.StatusSeeOther {
+		t.Errorf("want: %d, got: %d", http.StatusSeeOther, status)
+	}
+	if err := testDb.Exists("Message/b0/2"); err == nil {
+		t.Error("want: Message/b0/2 deleted")
+	}
+}
+
+func TestServerEditDisabled(t *testing.T) {
+	cfg := getConfig()
+	cfg.EnableUI = true
+	cfg.UIPort = 4128
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	insert(getTestMessageWithId(1), false)
+
+	resp, err := http.Get("http://localhost:4128/edit/Message/b0/1")
+	if err != nil {
+		t.Fatalf("GitDB UI Server request failed: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("want: %d, got: %d", http.StatusForbidden, resp.StatusCode)
+	}
+}
@@ -0,0 +1,164 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bouggo/log"
+)
+
+//streamKeepAlive is how often the events stream sends a comment so proxies don't close it while nothing changes
+const streamKeepAlive = 30 * time.Second
+
+//streamBuffer is how many changes a client of the events stream can fall behind by before it misses some
+const streamBuffer = 64
+
+//changeStream fans the changes of a database out to the clients of the events stream of its UI
+type changeStream struct {
+	//subscribe subscribes to the changes of the database when the first client joins, so writes
+	//don't pay for publishing them until the stream is used
+	subscribe sync.Once
+	mu        sync.Mutex
+	clients   map[chan Event]bool
+}
+
+//streamEvent is a change as sent to the clients of the events stream
+type streamEvent struct {
+	Kind    string `json:"kind"`
+	Dataset string `json:"dataset"`
+	ID      string `json:"id"`
+	Op      Op     `json:"op"`
+	SHA     string `json:"sha,omitempty"`
+}
+
+//eventKinds are the names of the kinds of change of the events stream
+var eventKinds = map[string]EventKind{
+	"insert": EventInsert,
+	"update": EventUpdate,
+	"delete": EventDelete,
+	"sync":   EventSync,
+}
+
+//streamEndpoints maps the path of the events stream to its handler
+func (u *router) streamEndpoints() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/events": u.events,
+	}
+}
+
+//events streams the changes to the datasets the request can read as server-sent events until the client
+//goes away. The dataset query parameter, which can be given more than once, limits the stream to those
+//datasets and kind, a comma separated list of insert, update, delete and sync, to those kinds of change
+func (u *router) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		apiFail(w, &apiErr{http.StatusInternalServerError, "streaming is not supported"})
+		return
+	}
+
+	datasets := r.URL.Query()["dataset"]
+	for _, dataset := range datasets {
+		if err := u.can(r, dataset, PermRead); err != nil {
+			apiFail(w, err)
+			return
+		}
+	}
+	kinds := EventAll
+	if names := r.URL.Query().Get("kind"); len(names) > 0 {
+		kinds = 0
+		for _, name := range strings.Split(names, ",") {
+			kind, ok := eventKinds[strings.TrimSpace(name)]
+			if !ok {
+				apiFail(w, &apiErr{http.StatusBadRequest, "unknown kind " + name + ", use insert, update, delete or sync"})
+				return
+			}
+			kinds |= kind
+		}
+	}
+
+	changes := u.joinStream()
+	defer u.stream.leave(changes)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-u.db.shutdown:
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case e := <-changes:
+			if e.Kind&kinds == 0 || (len(datasets) > 0 && !containsString(datasets, e.Dataset)) || u.can(r, e.Dataset, PermRead) != nil {
+				continue
+			}
+			data, err := json.Marshal(&streamEvent{Kind: kindName(e.Kind), Dataset: e.Dataset, ID: e.ID, Op: e.Op, SHA: e.SHA})
+			if err != nil {
+				log.Error(err.Error())
+				continue
+			}
+			fmt.Fprintf(w, "event: change\ndata: %s\n\n", data)
+		}
+		flusher.Flush()
+	}
+}
+
+//joinStream returns a channel of the changes to the database of the UI, subscribing to them if no client has yet
+func (u *router) joinStream() chan Event {
+	u.stream.subscribe.Do(func() {
+		u.db.Subscribe(EventAll, func(e Event) {
+			//datasets are loaded again so pages show the change
+			u.refreshAt = time.Time{}
+			u.stream.broadcast(e)
+		})
+	})
+
+	changes := make(chan Event, streamBuffer)
+	u.stream.mu.Lock()
+	defer u.stream.mu.Unlock()
+	if u.stream.clients == nil {
+		u.stream.clients = map[chan Event]bool{}
+	}
+	u.stream.clients[changes] = true
+	return changes
+}
+
+//leave stops sending changes to a client
+func (s *changeStream) leave(changes chan Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clients, changes)
+}
+
+//broadcast sends e to every client. Clients that have fallen behind miss it rather than hold up the write
+func (s *changeStream) broadcast(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for changes := range s.clients {
+		select {
+		case changes <- e:
+		default:
+		}
+	}
+}
+
+//kindName returns the name of kind in the events stream
+func kindName(kind EventKind) string {
+	for name, k := range eventKinds {
+		if k == kind {
+			return name
+		}
+	}
+	return ""
+}
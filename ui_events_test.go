@@ -0,0 +1,86 @@
+package gitdb_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestUIEvents(t *testing.T) {
+	teardown := setup(t, getConfig())
+	defer teardown(t)
+
+	handler, err := gitdb.UIHandler(testDb)
+	if err != nil {
+		t.Fatalf("gitdb.UIHandler failed: %s", err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events?kind=rename")
+	if err != nil {
+		t.Fatalf("events request failed: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("want: 400 for an unknown kind, got: %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "/events?dataset=Message&kind=insert,delete")
+	if err != nil {
+		t.Fatalf("events request failed: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("want: text/event-stream, got: %s", resp.Header.Get("Content-Type"))
+	}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	next := func(prefix string) string {
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatalf("stream ended waiting for %s", prefix)
+				}
+				if strings.HasPrefix(line, prefix) {
+					return line
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for %s", prefix)
+			}
+		}
+	}
+	next(": connected")
+
+	//changes to other datasets and of other kinds aren't streamed
+	if err := testDb.Insert(&MessageV2{MessageId: 1, Body: "other"}); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+	insert(getTestMessageWithId(1), true)
+	m := getTestMessageWithId(1)
+	m.Body = "edited"
+	insert(m, true)
+	if err := testDb.Delete("Message/b0/1"); err != nil {
+		t.Fatalf("delete failed: %s", err)
+	}
+
+	if data := next("data: "); !strings.Contains(data, `"kind":"insert"`) || !strings.Contains(data, `"id":"Message/b0/1"`) {
+		t.Errorf("want: insert of Message/b0/1, got: %s", data)
+	}
+	if data := next("data: "); !strings.Contains(data, `"kind":"delete"`) {
+		t.Errorf("want: delete of Message/b0/1, got: %s", data)
+	}
+}
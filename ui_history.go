@@ -0,0 +1,225 @@
+package gitdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gogitdb/gitdb/v2/internal/db"
+	"github.com/gorilla/mux"
+)
+
+//historyVersion is a version of a record in its history
+type historyVersion struct {
+	*Change
+	//Short is the abbreviated hash of the commit of the version
+	Short    string
+	Selected bool
+}
+
+//diffLine is a row of a side by side diff. Op is empty for lines that didn't change, removed, added or changed
+type diffLine struct {
+	Before string
+	After  string
+	Op     string
+}
+
+//historyEndpoints maps the paths of the history browser to their handlers
+func (u *router) historyEndpoints() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/history/{dataset}/{block}/{record}": u.history,
+		"/revert/{dataset}/{block}/{record}":  u.revert,
+	}
+}
+
+//history shows the commits that changed a record and the diff of the version of the one selected
+//with the version before it
+func (u *router) history(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	dataset := vars["dataset"]
+	id := dataset + "/" + vars["block"] + "/" + vars["record"]
+	if err := u.can(r, dataset, PermRead); err != nil {
+		u.editFail(w, err)
+		return
+	}
+
+	changes, err := u.db.History(id)
+	if err != nil {
+		u.editFail(w, err)
+		return
+	}
+	if len(changes) == 0 {
+		u.editFail(w, &apiErr{http.StatusNotFound, "Record " + id + " has no history"})
+		return
+	}
+
+	viewModel := &historyViewModel{DataSet: dataset, ID: id}
+	viewModel.Title = "History of " + id
+	viewModel.DataSets = u.readable(u.role(r))
+
+	selected := 0
+	commit := r.URL.Query().Get("commit")
+	for i, change := range changes {
+		version := &historyVersion{Change: change, Short: change.Commit}
+		if len(version.Short) > 7 {
+			version.Short = version.Short[:7]
+		}
+		if len(commit) > 0 && strings.HasPrefix(change.Commit, commit) {
+			selected = i
+		}
+		viewModel.Versions = append(viewModel.Versions, version)
+	}
+	viewModel.Selected = viewModel.Versions[selected]
+	viewModel.Selected.Selected = true
+
+	after := u.versionJSON(id, changes[selected].Commit)
+	before := ""
+	if selected+1 < len(changes) {
+		before = u.versionJSON(id, changes[selected+1].Commit)
+	}
+	viewModel.Diff = sideBySide(before, after)
+	//the latest version is the record as it is and deletes have nothing to revert to
+	viewModel.Revertible = selected > 0 && len(after) > 0 && u.canEdit(r, dataset) == nil
+
+	u.render(w, viewModel, "static/history.html", "static/sidebar.html")
+}
+
+//commitHash matches the commit hashes the history and repair forms post
+var commitHash = regexp.MustCompile("^[0-9a-f]{4,40}$")
+
+//revert restores a record to the version of the commit posted
+func (u *router) revert(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	dataset := vars["dataset"]
+	id := dataset + "/" + vars["block"] + "/" + vars["record"]
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		u.editFail(w, &apiErr{http.StatusMethodNotAllowed, "records are reverted by posting their revert form"})
+		return
+	}
+	if err := u.canEdit(r, dataset); err != nil {
+		u.editFail(w, err)
+		return
+	}
+	if !sameOrigin(r) {
+		u.editFail(w, &apiErr{http.StatusForbidden, "forms can only be posted from the web UI"})
+		return
+	}
+
+	commit := r.PostFormValue("commit")
+	if len(commit) == 0 {
+		u.editFail(w, &apiErr{http.StatusBadRequest, "commit to revert to is missing"})
+		return
+	}
+	if !commitHash.MatchString(commit) {
+		u.editFail(w, &apiErr{http.StatusBadRequest, "commit to revert to must be a commit hash"})
+		return
+	}
+	if err := u.apiConn(r).RevertRecord(id, commit); err != nil {
+		u.editFail(w, err)
+		return
+	}
+
+	u.refreshAt = time.Time{}
+	http.Redirect(w, r, u.path("/history/"+id), http.StatusSeeOther)
+}
+
+//versionJSON returns the data of record id at commit indented, with redacted fields masked, or an empty
+//string if the record didn't exist at commit
+func (u *router) versionJSON(id string, commit string) string {
+	dataset, block, _, err := ParseID(id)
+	if err != nil {
+		return ""
+	}
+
+	dataBlock := db.NewEmptyBlock(u.db.keyring(dataset))
+	if err := u.db.blockAt(commit, dataset+"/"+block+".json", dataBlock); err != nil {
+		return ""
+	}
+	record, err := dataBlock.Get(id)
+	if err != nil {
+		return ""
+	}
+
+	data := recordData(record)
+	if u.meta != nil {
+		data = u.meta.redactJSON(dataset, data)
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(data), "", "  "); err != nil {
+		return data
+	}
+	return buf.String()
+}
+
+//sideBySide returns the lines of before and after lined up, with lines that were removed and added
+//between lines they have in common paired up as changed
+func sideBySide(before string, after string) []*diffLine {
+	a, b := splitLines(before), splitLines(after)
+
+	//lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []*diffLine
+	var removed, added []string
+	flush := func() {
+		for k := 0; k < len(removed) || k < len(added); k++ {
+			line := &diffLine{Op: "changed"}
+			if k < len(removed) {
+				line.Before = removed[k]
+			} else {
+				line.Op = "added"
+			}
+			if k < len(added) {
+				line.After = added[k]
+			} else {
+				line.Op = "removed"
+			}
+			lines = append(lines, line)
+		}
+		removed, added = nil, nil
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			flush()
+			lines = append(lines, &diffLine{Before: a[i], After: b[j]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			removed = append(removed, a[i])
+			i++
+		default:
+			added = append(added, b[j])
+			j++
+		}
+	}
+	flush()
+	return lines
+}
+
+func splitLines(s string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,73 @@
+package gitdb_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestServerHistory(t *testing.T) {
+	cfg := getConfig()
+	cfg.EnableUI = true
+	cfg.UIPort = 4130
+	cfg.UIWrites = true
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	m := getTestMessageWithId(1)
+	insert(m, false)
+	m.Body = "edited"
+	insert(m, false)
+
+	changes, err := testDb.History("Message/b0/1")
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("want: 2 changes, got: %d (%v)", len(changes), err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	get := func(path string) (int, string) {
+		resp, err := client.Get("http://localhost:4130" + path)
+		if err != nil {
+			t.Fatalf("GitDB UI Server request failed: %s", err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get("/history/Message/b0/1")
+	if status != http.StatusOK || !strings.Contains(body, changes[0].Commit[:7]) || !strings.Contains(body, changes[1].Commit[:7]) {
+		t.Fatalf("want: both commits of Message/b0/1, got: %d %s", status, body)
+	}
+	if !strings.Contains(body, `<tr class="changed">`) || !strings.Contains(body, "edited") {
+		t.Errorf("want: diff of the edit, got: %s", body)
+	}
+	//the latest version can't be reverted to
+	if strings.Contains(body, "Revert to this version") {
+		t.Errorf("want: no revert of the latest version, got: %s", body)
+	}
+
+	if _, body := get("/history/Message/b0/1?commit=" + changes[1].Commit); !strings.Contains(body, "Revert to this version") {
+		t.Errorf("want: revert of the first version, got: %s", body)
+	}
+
+	resp, err := client.PostForm("http://localhost:4130/revert/Message/b0/1", url.Values{"commit": {changes[1].Commit}})
+	if err != nil {
+		t.Fatalf("GitDB UI Server request failed: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Errorf("want: %d, got: %d", http.StatusSeeOther, resp.StatusCode)
+	}
+
+	reverted := &Message{}
+	if err := testDb.Get("Message/b0/1", reverted); err != nil || reverted.Body != "Hello" {
+		t.Errorf("want: Message/b0/1 reverted, got: %+v (%v)", reverted, err)
+	}
+
+	if status, _ := get("/history/Message/b0/404"); status != http.StatusNotFound {
+		t.Errorf("want: %d, got: %d", http.StatusNotFound, status)
+	}
+}
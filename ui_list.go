@@ -0,0 +1,124 @@
+package gitdb
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//listPageSize is how many records a page of the list of a dataset shows
+const listPageSize = 50
+
+//listQuery is how the list of a dataset is searched, sorted and paged
+type listQuery struct {
+	//Search is matched against the values of indexed fields
+	Search string
+	//In is the index Search is matched against, every index of the dataset when empty
+	In   string
+	Sort string
+	Desc bool
+	Page int
+}
+
+//listColumn is a column of the list of a dataset. Columns of indexed fields can be sorted by
+type listColumn struct {
+	Name    string
+	SortURL string
+	Sorted  bool
+	Desc    bool
+}
+
+//parseListQuery reads the list query of r
+func parseListQuery(r *http.Request) listQuery {
+	query := r.URL.Query()
+	q := listQuery{
+		Search: strings.TrimSpace(query.Get("q")),
+		In:     query.Get("in"),
+		Sort:   query.Get("sort"),
+		Desc:   query.Get("desc") == "1",
+	}
+	q.Page, _ = strconv.Atoi(query.Get("page"))
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	return q
+}
+
+//url returns the url of the list of dataset with q
+func (q listQuery) url(dataset string) string {
+	values := url.Values{}
+	if len(q.Search) > 0 {
+		values.Set("q", q.Search)
+	}
+	if len(q.In) > 0 {
+		values.Set("in", q.In)
+	}
+	if len(q.Sort) > 0 {
+		values.Set("sort", q.Sort)
+	}
+	if q.Desc {
+		values.Set("desc", "1")
+	}
+	if q.Page > 1 {
+		values.Set("page", strconv.Itoa(q.Page))
+	}
+
+	u := "/list/" + dataset
+	if len(values) > 0 {
+		u += "?" + values.Encode()
+	}
+	return u
+}
+
+//listRecords returns the page of the records of dataset q asks for and how many records match q
+func (u *router) listRecords(dataset string, q listQuery) ([]*db.Record, int, error) {
+	return u.db.pageRecords(pageQuery{
+		dataset: dataset,
+		search:  q.Search,
+		in:      q.In,
+		sortBy:  q.Sort,
+		desc:    q.Desc,
+		offset:  (q.Page - 1) * listPageSize,
+		limit:   listPageSize,
+		hidden:  func(index string) bool { return u.isRedacted(dataset, index) },
+	})
+}
+
+//searchable returns the indexes of dataset the list can be searched by
+func (u *router) searchable(dataset string) []string {
+	var names []string
+	for _, name := range u.db.indexNames(dataset) {
+		if !u.isRedacted(dataset, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+//listColumns returns the columns of headers, with links sorting the list of dataset by the indexed ones
+func (u *router) listColumns(dataset string, headers []string, q listQuery) []*listColumn {
+	indexed := map[string]bool{}
+	for _, name := range u.db.indexNames(dataset) {
+		indexed[name] = true
+	}
+
+	columns := make([]*listColumn, len(headers))
+	for i, name := range headers {
+		column := &listColumn{Name: name}
+		if indexed[name] && !u.isRedacted(dataset, name) {
+			sorted := q
+			sorted.Sort, sorted.Desc, sorted.Page = name, q.Sort == name && !q.Desc, 1
+			column.SortURL = sorted.url(dataset)
+			column.Sorted, column.Desc = q.Sort == name, q.Sort == name && q.Desc
+		}
+		columns[i] = column
+	}
+	return columns
+}
+
+func (u *router) isRedacted(dataset string, field string) bool {
+	return u.meta != nil && u.meta.isRedacted(dataset, field)
+}
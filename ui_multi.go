@@ -0,0 +1,102 @@
+package gitdb
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+//uiDatabaseCookie remembers the database picked with the switcher of a UIHandler
+const uiDatabaseCookie = "gitdb_database"
+
+//uiDatabase is a database of the switcher of the web UI
+type uiDatabase struct {
+	Name     string
+	Selected bool
+}
+
+//databases serves the web UI of several connections, each with a router of its own
+type databases struct {
+	names   []string
+	routers map[string]http.Handler
+}
+
+//UIHandler returns a handler serving the web UI and REST API of conns, so they can be mounted on a server
+//or router of your own, with its own middleware and TLS, instead of the one Config.EnableUI starts e.g
+//http.Handle("/gitdb/", gitdb.UIHandler(conn)) with Config.UIBasePath set to /gitdb.
+//With more than one connection the sidebar gets a switcher between their databases. Requests pick a database
+//with the db query parameter, which is remembered in a cookie, and go to the first one otherwise.
+//Each database keeps the users and roles of its Config
+func UIHandler(conns ...GitDb) (http.Handler, error) {
+	if len(conns) == 0 {
+		return nil, errors.New("UIHandler needs at least one connection")
+	}
+
+	var names []string
+	for _, conn := range conns {
+		names = append(names, conn.Config().ConnectionName)
+	}
+
+	d := &databases{names: names, routers: map[string]http.Handler{}}
+	for _, conn := range conns {
+		g, ok := conn.(*gitdb)
+		if !ok {
+			return nil, errors.New("UIHandler needs connections returned by Open")
+		}
+		name := g.config.ConnectionName
+		if _, ok := d.routers[name]; ok {
+			return nil, errors.New("Connection " + name + " is given more than once")
+		}
+		u := &router{meta: g.meta(), db: g}
+		if len(conns) > 1 {
+			u.databases, u.database = names, name
+		}
+		d.routers[name] = u.handler(g.config)
+	}
+	return d, nil
+}
+
+func (d *databases) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := d.names[0]
+	if len(d.names) == 1 {
+		d.routers[name].ServeHTTP(w, r)
+		return
+	}
+	if cookie, err := r.Cookie(uiDatabaseCookie); err == nil {
+		if picked, err := url.QueryUnescape(cookie.Value); err == nil && d.routers[picked] != nil {
+			name = picked
+		}
+	}
+	if picked := r.URL.Query().Get("db"); d.routers[picked] != nil {
+		name = picked
+		http.SetCookie(w, &http.Cookie{
+			Name:     uiDatabaseCookie,
+			Value:    url.QueryEscape(name),
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	d.routers[name].ServeHTTP(w, r)
+}
+
+//switcher returns the databases the UI switches between, none when it serves one database
+func (u *router) switcher() []*uiDatabase {
+	var switcher []*uiDatabase
+	if len(u.databases) > 1 {
+		for _, name := range u.databases {
+			switcher = append(switcher, &uiDatabase{Name: name, Selected: name == u.database})
+		}
+	}
+	return switcher
+}
+
+//sessionCookie returns the name of the session cookie of the UI. Each database of a UIHandler
+//has its own users so it has a cookie of its own
+func (u *router) sessionCookie() string {
+	if len(u.database) == 0 {
+		return uiSessionCookie
+	}
+	return uiSessionCookie + "_" + url.QueryEscape(u.database)
+}
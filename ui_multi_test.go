@@ -0,0 +1,113 @@
+package gitdb_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestUIHandlerDatabases(t *testing.T) {
+	teardown := setup(t, getConfig())
+	defer teardown(t)
+	insert(getTestMessageWithId(1), false)
+
+	reportsCfg := gitdb.NewConfig(testData + "/reports")
+	reportsCfg.ConnectionName = "reports"
+	reports, err := gitdb.Open(reportsCfg)
+	if err != nil {
+		t.Fatalf("gitdb.Open failed: %s", err)
+	}
+	defer os.RemoveAll(reportsCfg.DbPath)
+	defer reports.Close()
+	if err := reports.Insert(&MessageV2{MessageId: 1, Body: "report"}); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+
+	if _, err := gitdb.UIHandler(testDb, testDb); err == nil {
+		t.Error("want: error for a connection given twice")
+	}
+
+	handler, err := gitdb.UIHandler(testDb, reports)
+	if err != nil {
+		t.Fatalf("gitdb.UIHandler failed: %s", err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	get := func(path string) string {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("request for %s failed: %s", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	if page := get("/"); !strings.Contains(page, `href="/list/Message"`) || !strings.Contains(page, `href="/?db=reports"`) {
+		t.Errorf("want: first database with a switcher to reports, got: %s", page)
+	}
+	if page := get("/?db=reports"); !strings.Contains(page, `href="/list/MessageV2"`) || strings.Contains(page, `href="/list/Message"`) {
+		t.Errorf("want: datasets of reports, got: %s", page)
+	}
+	//the database picked is remembered
+	if page := get("/list/MessageV2"); !strings.Contains(page, "report") {
+		t.Errorf("want: records of reports, got: %s", page)
+	}
+}
+
+func TestUIHandler(t *testing.T) {
+	cfg := getConfig()
+	cfg.UIBasePath = "/gitdb"
+	cfg.UIUsers = []gitdb.UIUser{{Name: "ada", Password: "s3cret"}}
+	teardown := setup(t, cfg)
+	defer teardown(t)
+	insert(getTestMessageWithId(1), false)
+
+	handler, err := gitdb.UIHandler(testDb)
+	if err != nil {
+		t.Fatalf("gitdb.UIHandler failed: %s", err)
+	}
+
+	//sign in of the app the UI is mounted on
+	mux := http.NewServeMux()
+	mux.Handle("/gitdb/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-App-User") == "ada" {
+			r = r.WithContext(gitdb.WithUIUser(r.Context(), gitdb.UIUser{Name: "ada"}))
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	get := func(path string, user string) (*http.Response, string) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header.Set("X-App-User", user)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request for %s failed: %s", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	if resp, _ := get("/gitdb/list/Message", ""); resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "/gitdb/login" {
+		t.Errorf("want: redirect to /gitdb/login, got: %d %s", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if resp, page := get("/gitdb/", "ada"); resp.StatusCode != http.StatusOK || !strings.Contains(page, `href="/gitdb/list/Message"`) || !strings.Contains(page, `href="/gitdb/css/app.css"`) {
+		t.Errorf("want: overview with links under /gitdb, got: %d %s", resp.StatusCode, page)
+	}
+	if resp, body := get("/gitdb/api/datasets", "ada"); resp.StatusCode != http.StatusOK || !strings.Contains(body, "Message") {
+		t.Errorf("want: REST API under /gitdb, got: %d %s", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,152 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gogitdb/gitdb/v2/internal/db"
+	"github.com/gorilla/mux"
+)
+
+//repairEndpoints maps the paths of the repair pages of bad blocks and records to their handlers
+func (u *router) repairEndpoints() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/repair/{dataset}/{block}":          u.repair,
+		"/repair/{dataset}/{block}/{record}": u.repair,
+	}
+}
+
+//repair shows a block, or a record of it, that can't be read as it is stored so it can be fixed and
+//committed or restored to a version of its history. Repairs are changes made as stored, bypassing the
+//models and redaction of the dataset, so they need Config.UIWrites and write permission to view
+func (u *router) repair(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	dataset := vars["dataset"]
+	if err := u.canEdit(r, dataset); err != nil {
+		u.editFail(w, err)
+		return
+	}
+
+	viewModel := &repairViewModel{DataSet: dataset, Block: dataset + "/" + vars["block"]}
+	if record, ok := vars["record"]; ok {
+		viewModel.ID = viewModel.Block + "/" + record
+		viewModel.Title = "Repair " + viewModel.ID
+	} else {
+		viewModel.Title = "Repair " + viewModel.Block
+	}
+	viewModel.DataSets = u.readable(u.role(r))
+
+	if r.Method == http.MethodPost {
+		if !sameOrigin(r) {
+			u.editFail(w, &apiErr{http.StatusForbidden, "forms can only be posted from the web UI"})
+			return
+		}
+		err := u.applyRepair(r, viewModel)
+		if err == nil {
+			u.refreshAt = time.Time{}
+			http.Redirect(w, r, u.path("/errors/"+dataset), http.StatusSeeOther)
+			return
+		}
+		viewModel.Error = err.Error()
+	}
+
+	if err := u.loadRepair(viewModel); err != nil {
+		u.editFail(w, err)
+		return
+	}
+	if r.Method == http.MethodPost && r.PostFormValue("action") == "repair" {
+		//keep the fix that failed so it can be corrected
+		viewModel.Content = r.PostFormValue("data")
+	}
+
+	u.render(w, viewModel, "static/repair.html", "static/sidebar.html")
+}
+
+//applyRepair commits the fix or restores the version posted for the block or record of viewModel
+func (u *router) applyRepair(r *http.Request, viewModel *repairViewModel) error {
+	dataset, block := viewModel.DataSet, path.Base(viewModel.Block)
+	user := u.commitUser(r)
+	switch r.PostFormValue("action") {
+	case "repair":
+		data := strings.TrimSpace(r.PostFormValue("data"))
+		if len(viewModel.ID) > 0 {
+			return u.db.repairRecord(viewModel.ID, data, user)
+		}
+		return u.db.repairBlock(dataset, block, []byte(data), user, "Repairing "+viewModel.Block)
+	case "restore":
+		commit := r.PostFormValue("commit")
+		if len(commit) == 0 {
+			return errors.New("commit to restore is missing")
+		}
+		if !commitHash.MatchString(commit) {
+			return errors.New("commit to restore must be a commit hash")
+		}
+		if len(viewModel.ID) > 0 {
+			return u.apiConn(r).RevertRecord(viewModel.ID, commit)
+		}
+		return u.db.restoreBlock(dataset, block, commit, user)
+	default:
+		return errors.New("unknown action " + r.PostFormValue("action"))
+	}
+}
+
+//loadRepair reads the block or record of viewModel as stored, why it can't be read and its history
+func (u *router) loadRepair(viewModel *repairViewModel) error {
+	dataset, block := viewModel.DataSet, path.Base(viewModel.Block)
+	blockFile := u.db.blockFilePath(dataset, block)
+	if !u.db.blockFileExists(blockFile) {
+		return &apiErr{http.StatusNotFound, "Block " + viewModel.Block + " does not exist"}
+	}
+	data, err := u.db.readBlockFile(blockFile)
+	if err != nil {
+		return err
+	}
+
+	var changes []*Change
+	if len(viewModel.ID) == 0 {
+		viewModel.Content = string(data)
+		var records map[string]string
+		if err := json.Unmarshal(data, &records); err != nil {
+			viewModel.Problem = err.Error()
+		}
+		changes, err = u.db.blockHistory(dataset, block)
+	} else {
+		dataBlock := db.NewEmptyBlock(u.db.keyring(dataset))
+		if err := json.Unmarshal(data, dataBlock); err != nil {
+			return &apiErr{http.StatusConflict, "Block " + viewModel.Block + " can't be read, repair it first"}
+		}
+		record, getErr := dataBlock.Get(viewModel.ID)
+		if getErr != nil {
+			return &apiErr{http.StatusNotFound, "Record " + viewModel.ID + " does not exist"}
+		}
+		viewModel.Content = record.Plain()
+		if err := record.Check(); err != nil {
+			viewModel.Problem = err.Error()
+		}
+		changes, err = u.db.History(viewModel.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		version := &historyVersion{Change: change, Short: change.Commit}
+		if len(version.Short) > 7 {
+			version.Short = version.Short[:7]
+		}
+		viewModel.Versions = append(viewModel.Versions, version)
+	}
+	return nil
+}
+
+//commitUser returns the user changes made by the request are committed as, nil for Config.User
+func (u *router) commitUser(r *http.Request) *User {
+	if user, ok := r.Context().Value(uiUserKey{}).(UIUser); ok && len(user.Email) > 0 {
+		return NewUser(user.Name, user.Email)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package gitdb_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestServerRepair(t *testing.T) {
+	cfg := getConfig()
+	cfg.EnableUI = true
+	cfg.UIPort = 4146
+	cfg.UIWrites = true
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	insert(getTestMessageWithId(1), true)
+	insert(getTestMessageWithId(2), true)
+
+	blockFile := filepath.Join(cfg.DbPath, "data", "Message", "b0.json")
+	good, err := ioutil.ReadFile(blockFile)
+	if err != nil {
+		t.Fatalf("failed to read block: %s", err)
+	}
+	var records map[string]string
+	json.Unmarshal(good, &records)
+	records["Message/b0/2"] = `{"MessageId": 2,`
+	corrupt, _ := json.Marshal(records)
+	ioutil.WriteFile(blockFile, corrupt, 0744)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	get := func(path string) string {
+		resp, err := client.Get("http://localhost:4146" + path)
+		if err != nil {
+			t.Fatalf("GitDB UI Server request failed: %s", err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return string(body)
+	}
+	post := func(path string, form url.Values) (int, string) {
+		resp, err := client.PostForm("http://localhost:4146"+path, form)
+		if err != nil {
+			t.Fatalf("GitDB UI Server request failed: %s", err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if page := get("/errors/Message"); !strings.Contains(page, `href="/repair/Message/b0/2"`) {
+		t.Fatalf("want: Message/b0/2 reported bad, got: %s", page)
+	}
+	if page := get("/repair/Message/b0/2"); !strings.Contains(page, `<p class="error">Record Message/b0/2`) || !strings.Contains(page, `{&#34;MessageId&#34;: 2,`) {
+		t.Errorf("want: the failing content of Message/b0/2, got: %s", page)
+	}
+	if status, page := post("/repair/Message/b0/2", url.Values{"action": {"repair"}, "data": {`{"MessageId": 2`}}); status != http.StatusOK || !strings.Contains(page, `class="error"`) {
+		t.Errorf("want: invalid fix refused, got: %d %s", status, page)
+	}
+	if status, page := post("/repair/Message/b0/2", url.Values{"action": {"repair"}, "data": {`{"MessageId": 2, "From": "alice@example.com", "To": "bob@example.com", "Body": "Hello"}`}}); status != http.StatusSeeOther {
+		t.Fatalf("want: 303 after the repair, got: %d %s", status, page)
+	}
+
+	m := &Message{}
+	if err := testDb.Get("Message/b0/2", m); err != nil || m.MessageId != 2 {
+		t.Errorf("want: Message/b0/2 repaired, got: %v", err)
+	}
+	if page := get("/errors/Message"); strings.Contains(page, "/repair/") {
+		t.Errorf("want: no more errors, got: %s", page)
+	}
+
+	//a block that isn't JSON is restored to the last commit
+	ioutil.WriteFile(blockFile, []byte("{garbage"), 0744)
+	if page := get("/errors/Message"); !strings.Contains(page, `href="/repair/Message/b0"`) {
+		t.Fatalf("want: Message/b0 reported bad, got: %s", page)
+	}
+	page := get("/repair/Message/b0")
+	commits := regexp.MustCompile(`name="commit" value="(\w+)"`).FindStringSubmatch(page)
+	if !strings.Contains(page, "{garbage") || len(commits) < 2 {
+		t.Fatalf("want: the failing block and its history, got: %s", page)
+	}
+	if status, _ := post("/repair/Message/b0", url.Values{"action": {"restore"}, "commit": {commits[1]}}); status != http.StatusSeeOther {
+		t.Fatalf("want: 303 after the restore, got: %d", status)
+	}
+	if err := testDb.Get("Message/b0/1", m); err != nil || m.MessageId != 1 {
+		t.Errorf("want: Message/b0/1 restored, got: %v", err)
+	}
+}
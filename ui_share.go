@@ -0,0 +1,142 @@
+package gitdb
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+//shareExpiries are how long the share links made in the web UI can last
+var shareExpiries = []*shareExpiry{
+	{Label: "1 hour", TTL: time.Hour},
+	{Label: "1 day", TTL: 24 * time.Hour},
+	{Label: "7 days", TTL: 7 * 24 * time.Hour},
+	{Label: "30 days", TTL: 30 * 24 * time.Hour},
+}
+
+//shareExpiry is an option of how long a share link lasts
+type shareExpiry struct {
+	Label string
+	TTL   time.Duration
+}
+
+//shareEndpoints maps the paths of share links to their handlers
+func (u *router) shareEndpoints() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/share/{dataset}":  u.share,
+		"/snapshot/{token}": u.snapshot,
+	}
+}
+
+//share makes a link to a read-only view of a dataset at a commit for someone without access to the UI.
+//Users can share the datasets they can read when Config.ShareKey is set
+func (u *router) share(w http.ResponseWriter, r *http.Request) {
+	dataset := mux.Vars(r)["dataset"]
+	if len(u.cfg.ShareKey) == 0 {
+		u.editFail(w, &apiErr{http.StatusForbidden, "datasets can't be shared without Config.ShareKey"})
+		return
+	}
+	if err := u.can(r, dataset, PermRead); err != nil {
+		u.editFail(w, err)
+		return
+	}
+
+	viewModel := &shareViewModel{DataSet: dataset, Expiries: shareExpiries}
+	viewModel.Title = "Share " + dataset
+	viewModel.DataSets = u.readable(u.role(r))
+	if r.Method == http.MethodPost {
+		if !sameOrigin(r) {
+			u.editFail(w, &apiErr{http.StatusForbidden, "forms can only be posted from the web UI"})
+			return
+		}
+		viewModel.Commit = r.PostFormValue("commit")
+		ttl, err := time.ParseDuration(r.PostFormValue("ttl"))
+		if err == nil {
+			viewModel.Link, err = u.db.ShareLink(dataset, viewModel.Commit, ttl)
+		}
+		if err != nil {
+			viewModel.Error = err.Error()
+		} else {
+			viewModel.Link = u.shareURL(r, viewModel.Link, nil)
+			viewModel.Expires = time.Now().Add(ttl)
+		}
+	}
+
+	u.render(w, viewModel, "static/share.html", "static/sidebar.html")
+}
+
+//snapshot shows a page of the records of the dataset a share link is for as they were at its commit,
+//or exports all of them as CSV or JSON when format is csv or json. It needs no sign in
+func (u *router) snapshot(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	share, err := u.db.openShare(token)
+	if err != nil {
+		u.editFail(w, &apiErr{http.StatusForbidden, err.Error()})
+		return
+	}
+	records, err := u.db.recordsAt(share.Dataset, share.Commit)
+	if err != nil {
+		u.editFail(w, err)
+		return
+	}
+
+	short := share.Commit
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	if format := r.URL.Query().Get("format"); len(format) > 0 {
+		u.export(w, share.Dataset, share.Dataset+"-"+short, records, format)
+		return
+	}
+
+	link := u.path("/snapshot/" + token)
+	viewModel := &snapshotViewModel{DataSet: share.Dataset, Commit: short, Expires: time.Unix(share.Expires, 0), Total: len(records)}
+	viewModel.Title = share.Dataset + " at " + short
+	viewModel.CSVURL = u.shareURL(r, link, url.Values{"format": {"csv"}})
+	viewModel.JSONURL = u.shareURL(r, link, url.Values{"format": {"json"}})
+
+	viewModel.Page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+	viewModel.Pages = (len(records) + listPageSize - 1) / listPageSize
+	if viewModel.Page < 1 || viewModel.Page > viewModel.Pages {
+		viewModel.Page = 1
+	}
+	if viewModel.Page > 1 {
+		viewModel.PrevURL = u.shareURL(r, link, url.Values{"page": {strconv.Itoa(viewModel.Page - 1)}})
+	}
+	if viewModel.Page < viewModel.Pages {
+		viewModel.NextURL = u.shareURL(r, link, url.Values{"page": {strconv.Itoa(viewModel.Page + 1)}})
+	}
+
+	start := (viewModel.Page - 1) * listPageSize
+	end := start + listPageSize
+	if end > len(records) {
+		end = len(records)
+	}
+	viewModel.Table = tablulate(records[start:end], func(data map[string]interface{}) { u.redact(share.Dataset, data) })
+
+	u.render(w, viewModel, "static/snapshot.html")
+}
+
+//shareURL returns the absolute URL of path, a page of share links, with query and, for a database of a
+//UIHandler serving several, the database it is for
+func (u *router) shareURL(r *http.Request, path string, query url.Values) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if len(u.database) > 0 {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("db", u.database)
+	}
+
+	link := scheme + "://" + r.Host + path
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+	return link
+}
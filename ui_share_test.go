@@ -0,0 +1,90 @@
+package gitdb_test
+
+import (
+	"encoding/csv"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestShareLink(t *testing.T) {
+	cfg := getConfig()
+	cfg.ShareKey = "s3cret-share-key"
+	cfg.UIUsers = []gitdb.UIUser{{Name: "ada", Password: "s3cret"}}
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	insert(getTestMessageWithId(1), true)
+	insert(getTestMessageWithId(2), true)
+
+	if _, err := testDb.ShareLink("Message", "", 0); err == nil {
+		t.Error("want: error for a link that never expires")
+	}
+	if _, err := testDb.ShareLink("Message", "nosuchcommit", time.Hour); err == nil {
+		t.Error("want: error for an unknown commit")
+	}
+	if _, err := testDb.ShareLink("Unknown", "", time.Hour); err == nil {
+		t.Error("want: error for an unknown dataset")
+	}
+
+	link, err := testDb.ShareLink("Message", "", time.Hour)
+	if err != nil {
+		t.Fatalf("ShareLink failed: %s", err)
+	}
+	expired, _ := testDb.ShareLink("Message", "", time.Nanosecond)
+	//the snapshot is of the commit the link was made at
+	insert(getTestMessageWithId(3), true)
+
+	handler, err := gitdb.UIHandler(testDb)
+	if err != nil {
+		t.Fatalf("gitdb.UIHandler failed: %s", err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("request for %s failed: %s", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, page := get(link)
+	if status != http.StatusOK || !strings.Contains(page, "Message/b0/2") || strings.Contains(page, "Message/b0/3") {
+		t.Errorf("want: Message at the commit shared without signing in, got: %d %s", status, page)
+	}
+	status, body := get(link + "?format=csv")
+	lines, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	if status != http.StatusOK || err != nil || len(lines) != 3 {
+		t.Errorf("want: header and 2 messages exported as CSV, got: %d %s", status, body)
+	}
+
+	if status, _ := get(link[:len(link)-2] + "xx"); status != http.StatusForbidden {
+		t.Errorf("want: 403 for a tampered link, got: %d", status)
+	}
+	if status, _ := get(expired); status != http.StatusForbidden {
+		t.Errorf("want: 403 for an expired link, got: %d", status)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/share/Message", strings.NewReader(url.Values{"ttl": {"24h0m0s"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("ada", "s3cret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("share request failed: %s", err)
+	}
+	defer resp.Body.Close()
+	shared, _ := ioutil.ReadAll(resp.Body)
+	if !strings.Contains(string(shared), `value="`+server.URL+`/snapshot/`) {
+		t.Errorf("want: link made in the UI, got: %s", shared)
+	}
+}
@@ -1,21 +0,0 @@
-package gitdb
-// Code generated by gitdb embed-ui on Tue, 07 Apr 2020 14:46:09 BST; DO NOT EDIT.
-
-func init() {
-	//Embed Files
-	
-	getFs().embed("static/css/app.css", "Ym9keSB7cGFkZGluZzogMDttYXJnaW46IDA7Zm9udC1mYW1pbHk6IEFyaWFsLCBIZWx2ZXRpY2EsIHNhbnMtc2VyaWY7fWRpdiB7Ym94LXNpemluZzogYm9yZGVyLWJveDt9aDEge3BhZGRpbmc6IDA7bWFyZ2luOiAwO21hcmdpbi1ib3R0b206IDMwcHg7fWgxIGEge3RleHQtZGVjb3JhdGlvbjogbm9uZTtjb2xvcjogZGFya3NlYWdyZWVuO30uc2lkZWJhciB7ZmxvYXQ6IGxlZnQ7d2lkdGg6IDIwJTtoZWlnaHQ6IDgwMHB4O2JhY2tncm91bmQtY29sb3I6ICNlZWU7Ym9yZGVyLXJpZ2h0OiAxcHggc29saWQgI2RkZDtwYWRkaW5nOiAxMHB4O30uY29udGVudCB7cGFkZGluZzogMzBweDtwYWRkaW5nLXRvcDogMTBweDtmbG9hdDogbGVmdDt3aWR0aDogODAlO2hlaWdodDogODAwcHg7fS5uYXYge2xpc3Qtc3R5bGU6IG5vbmU7bWFyZ2luOiAwO3BhZGRpbmc6IDB9Lm5hdiBsaSB7Y29sb3I6ICMwMDA7fS5uYXYgYSB7Y29sb3I6ICMwMDA7dGV4dC1kZWNvcmF0aW9uOiBub25lO2Rpc3BsYXk6IGJsb2NrO3BhZGRpbmctdG9wOiAxMHB4O3BhZGRpbmctYm90dG9tOiA1cHg7cGFkZGluZy1sZWZ0OiA1cHg7Ym9yZGVyLWJvdHRvbTogMXB4IHNvbGlkICNkZGQ7fS5uYXYgYTpob3ZlciB7YmFja2dyb3VuZC1jb2xvcjogI2RkZDt9dGFibGUgdHI6aG92ZXIgdGQge2N1cnNvcjogcG9pbnRlcjtiYWNrZ3JvdW5kLWNvbG9yOiAjY2NjO310YWJsZSB0aCB7YmFja2dyb3VuZC1jb2xvcjogZGFya3NlYWdyZWVuO2NvbG9yOiAjZmZmO3RleHQtYWxpZ246IGxlZnQ7fXRhYmxlIHt3aWR0aDogMTAwJTsvKiBib3JkZXI6IDFweCBzb2xpZCAjMDAwOyAqL2JvcmRlci1zcGFjaW5nOiAwcHg7fXRhYmxlIHRkLHRhYmxlIHRoIHtwYWRkaW5nOiAxMHB4O2JvcmRlci1ib3R0b206IDFweCBzb2xpZCAjZGRkO31wcmUge2JhY2tncm91bmQtY29sb3I6ICMyMjI7Y29sb3I6ICNmZmY7cGFkZGluZzogMTBweDtmb250LXNpemU6IDE0cHg7d2lkdGg6IDgwMHB4O292ZXJmbG93OiBoaWRkZW47fXRleHRhcmVhIHtkaXNwbGF5OiBibG9jazt9Lmxpc3RXaW5kb3cge3dpZHRoOiAxMDAlO292ZXJmbG93LXg6IHNjcm9sbDt9")
-	
-	getFs().embed("static/errors.html", "PGh0bWw+PGhlYWQ+PC9oZWFkPjxsaW5rIHJlbD0ic3R5bGVzaGVldCIgaHJlZj0iL2Nzcy9hcHAuY3NzIj48Ym9keT57e3RlbXBsYXRlICJzaWRlYmFyIiAkfX08ZGl2IGNsYXNzPSJjb250ZW50Ij48aDE+e3suVGl0bGV9fTwvaDE+e3tpZiAuRGF0YVNldC5CYWRCbG9ja3N9fTxoMj5CYWQgQmxvY2tzPC9oMj48dWw+e3tyYW5nZSAka2V5LCAkdmFsdWUgOj0gLkRhdGFTZXQuQmFkQmxvY2tzfX08bGk+PGEgaHJlZj0iL2VkaXQve3sgJHZhbHVlIH19Ij57eyAkdmFsdWUgfX08L2E+PC9saT57e2VuZH19PC91bD57e2VuZH19IHt7aWYgLkRhdGFTZXQuQmFkUmVjb3Jkc319PGgyPkJhZCBSZWNvcmRzPC9oMj48dWw+e3tyYW5nZSAka2V5LCAkdmFsdWUgOj0gLkRhdGFTZXQuQmFkUmVjb3Jkc319PGxpPjxhIGhyZWY9IiMiPnt7ICR2YWx1ZSB9fTwvYT48L2xpPnt7ZW5kfX08L3VsPnt7ZW5kfX08L2Rpdj48L2JvZHk+PC9odG1sPg==")
-	
-	getFs().embed("static/index.html", "PGh0bWw+PGhlYWQ+PC9oZWFkPjxsaW5rIHJlbD0ic3R5bGVzaGVldCIgaHJlZj0iL2Nzcy9hcHAuY3NzIj48c2NyaXB0IHNyYz0iL2pzL2FwcC5qcyI+PC9zY3JpcHQ+PGJvZHk+e3t0ZW1wbGF0ZSAic2lkZWJhciIgJH19PGRpdiBjbGFzcz0iY29udGVudCI+PGgxPnt7LlRpdGxlfX08L2gxPjx0YWJsZT48dHI+PHRoPkRhdGFzZXQ8L3RoPjx0aD5Oby4gb2YgYmxvY2tzPC90aD48dGg+Tm8uIG9mIHJlY29yZHM8L3RoPjx0aD5TaXplPC90aD48dGg+RXJyb3JzPC90aD48dGg+SW5kZXhlczwvdGg+PHRoPkxhc3QgTW9kaWZpZWQ8L3RoPjwvdHI+e3tyYW5nZSAka2V5LCAkdmFsdWUgOj0gLkRhdGFTZXRzfX08dHIgY2xhc3M9ImRhdGFzZXRSb3ciIGRhdGEtdmlldz0iL2xpc3Qve3sgJHZhbHVlLk5hbWUgfX0iPjx0ZD57eyAkdmFsdWUuTmFtZSB9fTwvdGQ+PHRkPnt7ICR2YWx1ZS5CbG9ja0NvdW50IH19PC90ZD48dGQ+e3sgJHZhbHVlLlJlY29yZENvdW50IH19PC90ZD48dGQ+e3sgJHZhbHVlLkh1bWFuU2l6ZSB9fTwvdGQ+PHRkPjxhIGhyZWY9Ii9lcnJvcnMve3sgJHZhbHVlLk5hbWUgfX0iPnt7ICR2YWx1ZS5CYWRCbG9ja3NDb3VudCB9fSBibG9jayhzKSAvIHt7ICR2YWx1ZS5CYWRSZWNvcmRzQ291bnQgfX0gcmVjb3JkKHMpPC9hPjwvdGQ+PHRkPjx1bD57e3JhbmdlICRpbmRleE5hbWUgOj0gJHZhbHVlLkluZGV4ZXN9fTxsaT57eyAkaW5kZXhOYW1lIH19PC9saT57e2VuZH19PC91bD48L3RkPjx0ZD57eyAkdmFsdWUuTGFzdE1vZGlmaWVkRGF0ZSB9fTwvdGQ+PC90cj57e2VuZH19PC90YWJsZT48L2Rpdj48L2JvZHk+PC9odG1sPg==")
-	
-	getFs().embed("static/js/app.js", "d2luZG93LmFkZEV2ZW50TGlzdGVuZXIoJ2xvYWQnLCAoZXZlbnQpID0+IHttYWtlRGF0YXNldFJvd3NDbGlja2FibGUoKTttYWtlUmVjb3JkUm93c0NsaWNrYWJsZSgpO30pO2Z1bmN0aW9uIG1ha2VEYXRhc2V0Um93c0NsaWNrYWJsZSgpIHtkb2N1bWVudC5xdWVyeVNlbGVjdG9yQWxsKCcuZGF0YXNldFJvdycpLmZvckVhY2gocm93ID0+IHtyb3cuYWRkRXZlbnRMaXN0ZW5lcignY2xpY2snLCBldmVudCA9PiB7d2luZG93LmxvY2F0aW9uID0gcm93LmRhdGFzZXQudmlld30pO30pfWZ1bmN0aW9uIG1ha2VSZWNvcmRSb3dzQ2xpY2thYmxlKCkge2RvY3VtZW50LnF1ZXJ5U2VsZWN0b3JBbGwoJy5yZWNvcmRSb3cnKS5mb3JFYWNoKHJvdyA9PiB7cm93LmFkZEV2ZW50TGlzdGVuZXIoJ2NsaWNrJywgZXZlbnQgPT4ge3dpbmRvdy5sb2NhdGlvbiA9IHJvdy5kYXRhc2V0LnZpZXd9KTt9KX0=")
-	
-	getFs().embed("static/list.html", "PGh0bWw+PGhlYWQ+PC9oZWFkPjxsaW5rIHJlbD0ic3R5bGVzaGVldCIgaHJlZj0iL2Nzcy9hcHAuY3NzIj48c2NyaXB0IHNyYz0iL2pzL2FwcC5qcyI+PC9zY3JpcHQ+PGJvZHk+e3t0ZW1wbGF0ZSAic2lkZWJhciIgJH19PGRpdiBjbGFzcz0iY29udGVudCI+PGgxPnt7LkRhdGFTZXQuTmFtZX19PC9oMT48ZGl2PjxzcGFuPnt7LkRhdGFTZXQuQmxvY2tDb3VudH19IGJsb2Nrczwvc3Bhbj4gPHNwYW4+e3suRGF0YVNldC5IdW1hblNpemV9fTwvc3Bhbj48L2Rpdj48ZGl2IGNsYXNzPSJsaXN0V2luZG93Ij48dGFibGU+PHRyPnt7cmFuZ2UgJGtleSwgJHZhbHVlIDo9IC5UYWJsZS5IZWFkZXJzfX08dGg+e3sgJHZhbHVlIH19PC90aD57e2VuZH19PC90cj57e3JhbmdlICRrZXksICR2YWx1ZSA6PSAuVGFibGUuUm93c319PHRyIGNsYXNzPSJyZWNvcmRSb3ciIGRhdGEtdmlldz0iL3ZpZXcve3skLkRhdGFTZXQuTmFtZX19L2IwL3J7eyAka2V5IH19Ij57e3JhbmdlICRrLCAkdiA6PSAkdmFsdWV9fSB7e2lmIGVxICRrIDB9fTx0ZD57eyAkdiB9fTwvdGQ+e3tlbHNlfX08dGQ+e3sgJHYgfX08L3RkPnt7ZW5kfX0ge3tlbmR9fTx0cj57e2VuZH19PC90YWJsZT48L2Rpdj48L2Rpdj48L2JvZHk+PC9odG1sPg==")
-	
-	getFs().embed("static/sidebar.html", "e3tkZWZpbmUgInNpZGViYXIifX08ZGl2IGNsYXNzPSJzaWRlYmFyIj48aDE+PGEgaHJlZj0iLyI+R2l0REI8L2E+PC9oMT48c3Ryb25nPkRhdGEgU2V0czwvc3Ryb25nPjx1bCBjbGFzcz0ibmF2Ij57e3JhbmdlICRrZXksICR2YWx1ZSA6PSAuRGF0YVNldHN9fTxsaT48YSBocmVmPSIvbGlzdC97eyAkdmFsdWUuTmFtZSB9fSI+e3sgJHZhbHVlLk5hbWUgfX08L2E+PC9saT57e2VuZH19PC91bD48L2Rpdj57e2VuZH19")
-	
-	getFs().embed("static/view.html", "PGh0bWw+PGhlYWQ+PC9oZWFkPjxsaW5rIHJlbD0ic3R5bGVzaGVldCIgaHJlZj0iL2Nzcy9hcHAuY3NzIj48Ym9keT57e3RlbXBsYXRlICJzaWRlYmFyIiAkfX08ZGl2IGNsYXNzPSJjb250ZW50Ij48aDE+e3suRGF0YVNldC5OYW1lfX08L2gxPjxkaXY+PHNwYW4+e3suRGF0YVNldC5CbG9ja0NvdW50fX0gYmxvY2tzPC9zcGFuPiA8c3Bhbj57ey5CbG9jay5IdW1hblNpemV9fS97ey5EYXRhU2V0Lkh1bWFuU2l6ZX19PC9zcGFuPjwvZGl2PjxhIGhyZWY9Ii92aWV3L3t7LkRhdGFTZXQuTmFtZX19L3t7LlBhZ2VyLlByZXZCbG9ja1VSSX19Ij5QcmV2IEJsb2NrPC9hPiB8IDxhIGhyZWY9Ii92aWV3L3t7LkRhdGFTZXQuTmFtZX19L3t7LlBhZ2VyLk5leHRCbG9ja1VSSX19Ij5OZXh0IEJsb2NrPC9hPjxwcmU+e3suQ29udGVudH19PC9wcmU+PGEgaHJlZj0iL3ZpZXcve3suRGF0YVNldC5OYW1lfX0ve3suUGFnZXIuUHJldlJlY29yZFVSSX19Ij5QcmV2IFJlY29yZDwvYT4gfCA8YSBocmVmPSIvdmlldy97ey5EYXRhU2V0Lk5hbWV9fS97ey5QYWdlci5OZXh0UmVjb3JkVVJJfX0iPk5leHQgUmVjb3JkPC9hPjwvZGl2PjwvYm9keT48L2h0bWw+")
-	
-}
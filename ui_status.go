@@ -0,0 +1,115 @@
+package gitdb
+
+import (
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+//remoteSyncStatus is the sync status of a remote reported by /status
+type remoteSyncStatus struct {
+	Name      string     `json:"name"`
+	URL       string     `json:"url"`
+	LastPull  *time.Time `json:"lastPull,omitempty"`
+	LastPush  *time.Time `json:"lastPush,omitempty"`
+	LastError string     `json:"lastError,omitempty"`
+}
+
+//datasetStatus is the health of a dataset reported by /status
+type datasetStatus struct {
+	Name       string `json:"name"`
+	Blocks     int    `json:"blocks"`
+	Records    int    `json:"records"`
+	BadBlocks  int    `json:"badBlocks"`
+	BadRecords int    `json:"badRecords"`
+}
+
+//nodeStatus is the response of /status
+type nodeStatus struct {
+	Status        string              `json:"status"`
+	ReadOnly      bool                `json:"readOnly"`
+	PendingPushes int                 `json:"pendingPushes"`
+	Remotes       []*remoteSyncStatus `json:"remotes"`
+	Datasets      []*datasetStatus    `json:"datasets"`
+	BadBlocks     int                 `json:"badBlocks"`
+	BadRecords    int                 `json:"badRecords"`
+	//RepoSize is the size in bytes of the git directory of the database
+	RepoSize int64 `json:"repoSize"`
+}
+
+//statusEndpoints maps the paths orchestrators and dashboards monitor a node with to their handlers
+func (u *router) statusEndpoints() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/healthz": u.healthz,
+		"/status":  u.status,
+	}
+}
+
+//healthz reports whether the node is up and ready to serve requests. It doesn't need a login
+func (u *router) healthz(w http.ResponseWriter, r *http.Request) {
+	if !u.ready() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+//status reports the sync status of the node, the health of the datasets the request can read and the size of the repository
+func (u *router) status(w http.ResponseWriter, r *http.Request) {
+	status := &nodeStatus{
+		Status:        "ok",
+		ReadOnly:      u.db.config.readOnly,
+		PendingPushes: u.db.PendingPushes(),
+		Remotes:       []*remoteSyncStatus{},
+		Datasets:      []*datasetStatus{},
+		RepoSize:      dirSize(filepath.Join(u.db.dbDir(), ".git")),
+	}
+	code := http.StatusOK
+	if !u.ready() {
+		status.Status = "unavailable"
+		code = http.StatusServiceUnavailable
+	}
+
+	for _, remote := range u.db.Remotes() {
+		sync := &remoteSyncStatus{
+			Name:     remote.Name,
+			URL:      remote.URL,
+			LastPull: statusTime(remote.LastPull),
+			LastPush: statusTime(remote.LastPush),
+		}
+		if remote.LastError != nil {
+			sync.LastError = remote.LastError.Error()
+		}
+		status.Remotes = append(status.Remotes, sync)
+	}
+
+	for _, ds := range u.readable(u.role(r)) {
+		health := &datasetStatus{
+			Name:       ds.Name(),
+			Blocks:     ds.BlockCount(),
+			Records:    ds.RecordCount(),
+			BadBlocks:  ds.BadBlocksCount(),
+			BadRecords: ds.BadRecordsCount(),
+		}
+		status.BadBlocks += health.BadBlocks
+		status.BadRecords += health.BadRecords
+		status.Datasets = append(status.Datasets, health)
+	}
+
+	writeJSON(w, code, status)
+}
+
+//ready reports whether the connection of the node is open
+func (u *router) ready() bool {
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+	return !u.db.closed
+}
+
+//statusTime returns nil for the zero time so it is left out of /status
+func statusTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
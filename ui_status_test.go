@@ -0,0 +1,79 @@
+package gitdb_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestStatus(t *testing.T) {
+	cfg := getConfig()
+	cfg.EnableUI = true
+	cfg.UIPort = 4138
+	cfg.UIUsers = []gitdb.UIUser{{Name: "ada", Password: "s3cret"}}
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	insert(getTestMessageWithId(1), false)
+
+	resp, err := http.Get("http://localhost:4138/healthz")
+	if err != nil {
+		t.Fatalf("health check failed: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("want: /healthz open without sign in, got: %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get("http://localhost:4138/status")
+	if err != nil {
+		t.Fatalf("status request failed: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("want: 401 without sign in, got: %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost:4138/status", nil)
+	req.SetBasicAuth("ada", "s3cret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("status request failed: %s", err)
+	}
+	defer resp.Body.Close()
+
+	var status struct {
+		Status        string
+		PendingPushes int
+		RepoSize      int64
+		Datasets      []struct {
+			Name      string
+			Records   int
+			BadBlocks int
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode status: %s", err)
+	}
+	if status.Status != "ok" || status.RepoSize <= 0 {
+		t.Errorf("want: ok status with the repository size, got: %+v", status)
+	}
+	if status.PendingPushes != testDb.PendingPushes() {
+		t.Errorf("want: %d pending pushes, got: %d", testDb.PendingPushes(), status.PendingPushes)
+	}
+
+	found := false
+	for _, ds := range status.Datasets {
+		if ds.Name == "Message" {
+			found = true
+			if ds.Records != 1 || ds.BadBlocks != 0 {
+				t.Errorf("want: 1 record and no bad blocks in Message, got: %+v", ds)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("want: Message in datasets, got: %+v", status.Datasets)
+	}
+}
@@ -1,8 +1,14 @@
 package gitdb_test
 
 import (
+	"io/ioutil"
 	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
 	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
 )
 
 func TestServer(t *testing.T) {
@@ -44,3 +50,147 @@ func request(method, url string) *http.Request {
 	req, _ := http.NewRequest(method, url, nil)
 	return req
 }
+
+func TestServerLogin(t *testing.T) {
+	cfg := getConfig()
+	cfg.EnableUI = true
+	cfg.UIPort = 4122
+	cfg.Roles = map[string]gitdb.Role{"reporting": {"Message": gitdb.PermRead}}
+	cfg.UIUsers = []gitdb.UIUser{{Name: "ada", Password: "s3cret"}, {Name: "bob", Password: "hunter2", Role: "reporting"}}
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	insert(getTestMessageWithId(1), false)
+	testDb.Insert(&Credential{Username: "ada", Password: "pass", Token: "tok"})
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	get := func(path string) (int, string) {
+		resp, err := client.Get("http://localhost:4122" + path)
+		if err != nil {
+			t.Fatalf("GitDB UI Server request failed: %s", err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if _, body := get("/list/Message"); !strings.Contains(body, `name="password"`) {
+		t.Errorf("want: login page, got: %s", body)
+	}
+
+	resp, err := client.PostForm("http://localhost:4122/login", url.Values{"name": {"ada"}, "password": {"wrong"}})
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("want: %d, got: %v (%v)", http.StatusUnauthorized, resp, err)
+	}
+	resp.Body.Close()
+
+	//bob only sees the datasets of the reporting role
+	resp, err = client.PostForm("http://localhost:4122/login", url.Values{"name": {"bob"}, "password": {"hunter2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if _, body := get("/"); !strings.Contains(body, "/list/Message") || strings.Contains(body, "/list/Credential") {
+		t.Errorf("want: only Message, got: %s", body)
+	}
+	if _, body := get("/list/Credential"); !strings.Contains(body, "does not exist") {
+		t.Errorf("want: Credential hidden, got: %s", body)
+	}
+
+	if status, _ := get("/logout"); status != http.StatusMethodNotAllowed {
+		t.Errorf("want: %d for a logout link, got: %d", http.StatusMethodNotAllowed, status)
+	}
+	resp, err = client.PostForm("http://localhost:4122/logout", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if _, body := get("/"); !strings.Contains(body, `name="password"`) {
+		t.Errorf("want: login page after logout, got: %s", body)
+	}
+
+	//basic auth works without a session
+	req := request(http.MethodGet, "http://localhost:4122/list/Credential")
+	req.SetBasicAuth("ada", "s3cret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "[REDACTED]") {
+		t.Errorf("want: Credential list, got: %s", body)
+	}
+
+	//failed sign ins are throttled, basic auth included
+	for i := 0; i < 5; i++ {
+		resp, err = client.PostForm("http://localhost:4122/login", url.Values{"name": {"ada"}, "password": {"wrong"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	resp, err = client.PostForm("http://localhost:4122/login", url.Values{"name": {"ada"}, "password": {"s3cret"}})
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("want: %d, got: %v (%v)", http.StatusTooManyRequests, resp, err)
+	}
+	resp.Body.Close()
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("want: %d for basic auth, got: %v (%v)", http.StatusTooManyRequests, resp, err)
+	}
+	resp.Body.Close()
+}
+
+func TestServerList(t *testing.T) {
+	cfg := getConfig()
+	cfg.EnableUI = true
+	cfg.UIPort = 4132
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	for i := 1; i <= 55; i++ {
+		m := getTestMessageWithId(i)
+		if i%5 == 0 {
+			m.From = "carol@example.com"
+		}
+		insert(m, false)
+	}
+
+	get := func(path string) string {
+		resp, err := http.Get("http://localhost:4132" + path)
+		if err != nil {
+			t.Fatalf("GitDB UI Server request failed: %s", err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	body := get("/list/Message")
+	if got := strings.Count(body, `class="recordRow"`); got != 50 || !strings.Contains(body, "Page 1 of 2") {
+		t.Errorf("want: first 50 of 2 pages, got: %d rows", got)
+	}
+	if got := strings.Count(get("/list/Message?page=2"), `class="recordRow"`); got != 5 {
+		t.Errorf("want: 5 rows on page 2, got: %d", got)
+	}
+
+	body = get("/list/Message?q=CAROL&in=From")
+	if got := strings.Count(body, `class="recordRow"`); got != 11 || !strings.Contains(body, "11 records") {
+		t.Errorf("want: 11 rows from carol, got: %d", got)
+	}
+
+	body = get("/list/Message?sort=From&desc=1")
+	first := strings.Index(body, `class="recordRow"`)
+	if first < 0 || !strings.Contains(body[first:first+500], "carol@example.com") {
+		t.Errorf("want: carol first sorting by From descending, got: %s", body)
+	}
+	if !strings.Contains(body, `href="/list/Message?sort=From"`) {
+		t.Errorf("want: link sorting by From ascending, got: %s", body)
+	}
+
+	if body := get("/record/Message/b0/5"); !strings.Contains(body, "carol@example.com") {
+		t.Errorf("want: Message/b0/5, got: %s", body)
+	}
+}
@@ -12,30 +12,56 @@ import (
 //table represents a tabular view
 type table struct {
 	Headers []string
-	Rows    [][]string
+	Rows    []*tableRow
 }
 
-//tablulate returns a tabular representation of a Block
-func tablulate(b *db.Block) *table {
+//tableRow is a record in a table
+type tableRow struct {
+	ID    string
+	Cells []*tableCell
+}
+
+//tableCell is a value in a table. Value is cut short to fit in its column, Full is all of it
+type tableCell struct {
+	Value string
+	Full  string
+}
+
+//tablulate returns a tabular representation of records with the data of each record passed through redact
+func tablulate(records []*db.Record, redact func(map[string]interface{})) *table {
 	t := &table{}
-	var jsonMap map[string]interface{}
+	headers := map[string]interface{}{}
+	var rows []map[string]interface{}
+	var ids []string
 
-	for i, record := range b.Records() {
+	for _, record := range records {
+		var jsonMap map[string]interface{}
 		if err := record.Hydrate(&jsonMap); err != nil {
 			log.Error(err.Error())
 			continue
 		}
+		redact(jsonMap)
 
-		var row []string
-		if i == 0 {
-			t.Headers = sortHeaderFields(jsonMap)
+		for key := range jsonMap {
+			headers[key] = nil
 		}
+		rows = append(rows, jsonMap)
+		ids = append(ids, record.ID())
+	}
+
+	t.Headers = sortHeaderFields(headers)
+	for i, jsonMap := range rows {
+		row := &tableRow{ID: ids[i]}
 		for _, key := range t.Headers {
-			val := fmt.Sprintf("%v", jsonMap[key])
-			if len(val) > 40 {
-				val = val[0:40]
+			cell := &tableCell{}
+			if val, ok := jsonMap[key]; ok {
+				cell.Full = fmt.Sprintf("%v", val)
+			}
+			cell.Value = cell.Full
+			if len(cell.Value) > 40 {
+				cell.Value = cell.Value[0:40]
 			}
-			row = append(row, val)
+			row.Cells = append(row.Cells, cell)
 		}
 
 		t.Rows = append(t.Rows, row)
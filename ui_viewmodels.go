@@ -1,10 +1,23 @@
 package gitdb
 
-import "github.com/gogitdb/gitdb/v2/internal/db"
+import (
+	"time"
+
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
 
 type baseViewModel struct {
 	Title    string
 	DataSets []*db.Dataset
+	//Databases are the databases the switcher of the sidebar switches between
+	Databases []*uiDatabase
+	//Base is the path the UI is served under, prefixed to its links
+	Base string
+}
+
+//base returns the baseViewModel of a view model so render can fill in what every page shows
+func (b *baseViewModel) base() *baseViewModel {
+	return b
 }
 
 type overviewViewModel struct {
@@ -17,15 +30,131 @@ type viewDataSetViewModel struct {
 	Block   *db.Block
 	Pager   *pager
 	Content string
+	//RecordID is the id of the record shown
+	RecordID string
+	Editable bool
 }
 
 type listDataSetViewModel struct {
 	baseViewModel
 	DataSet *db.Dataset
+	Meta    *DatasetMeta
+	Table   *table
+	Columns []*listColumn
+	//Indexes are the indexed fields the list can be searched by
+	Indexes []string
+	Query   listQuery
+	Total   int
+	Pages   int
+	PrevURL string
+	NextURL string
+	//New links to the forms creating records when they can be created
+	New []*newLink
+	//Shareable is whether share links of the dataset can be made
+	Shareable bool
+}
+
+type newLink struct {
+	Label string
+	URL   string
+}
+
+type dashboardViewModel struct {
+	baseViewModel
+	//Days is how many days back the charts go
+	Days int
+	Rows []*dashboardRow
+	//Activity is the commits of each day, Commits is their total
+	Activity       []*activityColumn
+	Commits        int
+	ActivityWidth  int
+	ActivityHeight int
+}
+
+type consoleViewModel struct {
+	baseViewModel
+	Query string
+	Error string
+	//DataSet is the dataset queried, Total how many of its records match the query
+	DataSet string
 	Table   *table
+	Total   int
+	//Indexes are the indexes of DataSet the query can filter and order by
+	Indexes []string
 }
 
 type errorsViewModel struct {
 	baseViewModel
 	DataSet *db.Dataset
+	//BadBlocks are the names of the blocks of DataSet that can't be read e.g b0 and BadRecords the ids of its records
+	BadBlocks  []string
+	BadRecords []string
+	//Repairable is whether the request can repair them
+	Repairable bool
+}
+
+type loginViewModel struct {
+	baseViewModel
+	Error string
+}
+
+type editViewModel struct {
+	baseViewModel
+	DataSet string
+	//ID is the id of the record edited, empty for a new one
+	ID     string
+	Action string
+	Fields []*formField
+	Errors []string
+}
+
+type historyViewModel struct {
+	baseViewModel
+	DataSet    string
+	ID         string
+	Versions   []*historyVersion
+	Selected   *historyVersion
+	Diff       []*diffLine
+	Revertible bool
+}
+
+type repairViewModel struct {
+	baseViewModel
+	DataSet string
+	//Block is the id of the block repaired e.g Message/b0 and ID the id of the record repaired, empty for the whole block
+	Block string
+	ID    string
+	//Content is the block or record as stored, decrypted if it can be, and Problem why it can't be read
+	Content string
+	Problem string
+	//Error is why the last repair failed
+	Error    string
+	Versions []*historyVersion
+}
+
+type shareViewModel struct {
+	baseViewModel
+	DataSet  string
+	Commit   string
+	Expiries []*shareExpiry
+	//Link is the share link made, which stops working at Expires
+	Link    string
+	Expires time.Time
+	Error   string
+}
+
+type snapshotViewModel struct {
+	baseViewModel
+	DataSet string
+	//Commit is the abbreviated hash of the commit the snapshot is of
+	Commit  string
+	Expires time.Time
+	Table   *table
+	Total   int
+	Page    int
+	Pages   int
+	PrevURL string
+	NextURL string
+	CSVURL  string
+	JSONURL string
 }
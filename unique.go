@@ -0,0 +1,44 @@
+package gitdb
+
+import (
+	"fmt"
+)
+
+//ErrUniqueViolation is returned when an insert would duplicate the value of a unique index
+type ErrUniqueViolation struct {
+	Dataset string
+	Index   string
+	Value   interface{}
+	//ID is the id of the record that already has Value
+	ID string
+	//redacted hides Value from the error message
+	redacted bool
+}
+
+func (e *ErrUniqueViolation) Error() string {
+	var value interface{} = e.Value
+	if e.redacted {
+		value = redactedValue
+	}
+	return fmt.Sprintf("%s.%s must be unique: %v already used by %s", e.Dataset, e.Index, value, e.ID)
+}
+
+//Unique marks indexes whose values must not be shared by two records in the dataset
+func (a *Schema) Unique(indexes ...string) *Schema {
+	a.unique = append(a.unique, indexes...)
+	return a
+}
+
+//checkUnique returns *ErrUniqueViolation if another record in the dataset has
+//the same value as id for any of the schema's unique indexes
+func (g *gitdb) checkUnique(schema *Schema, id string) error {
+	for _, name := range schema.unique {
+		value := fmt.Sprint(schema.indexes[name])
+		for recordID, iv := range g.index(schema.name(), name) {
+			if recordID != id && fmt.Sprint(iv.Value) == value {
+				return &ErrUniqueViolation{Dataset: schema.name(), Index: name, Value: schema.indexes[name], ID: recordID, redacted: schema.isRedacted(name)}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package gitdb_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+type Account struct {
+	testModel
+	AccountId int
+	Email     string
+}
+
+func (a *Account) GetSchema() *gitdb.Schema {
+	indexes := map[string]interface{}{"Email": a.Email}
+	return gitdb.NewSchema("Account", "b0", fmt.Sprintf("%d", a.AccountId), indexes).Unique("Email")
+}
+
+func TestUniqueIndex(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	a1 := &Account{AccountId: 1, Email: "alice@example.com"}
+	if err := testDb.Insert(a1); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+
+	//updating a record keeps its own value
+	if err := testDb.Insert(a1); err != nil {
+		t.Errorf("testDb.Insert failed to update record: %s", err)
+	}
+
+	a2 := &Account{AccountId: 2, Email: "alice@example.com"}
+	err := testDb.Insert(a2)
+	var violation *gitdb.ErrUniqueViolation
+	if !errors.As(err, &violation) {
+		t.Fatalf("want: *gitdb.ErrUniqueViolation, got: %v", err)
+	}
+	if violation.Index != "Email" || violation.ID != gitdb.ID(a1) {
+		t.Errorf("want: Email already used by %s, got: %s", gitdb.ID(a1), violation)
+	}
+
+	//value is free again once the record holding it is deleted
+	if err := testDb.Delete(gitdb.ID(a1)); err != nil {
+		t.Fatalf("testDb.Delete failed: %s", err)
+	}
+	if err := testDb.Insert(a2); err != nil {
+		t.Errorf("testDb.Insert failed: %s", err)
+	}
+}
+
+func TestUniqueMustBeIndexed(t *testing.T) {
+	schema := gitdb.NewSchema("Account", "b0", "1", map[string]interface{}{}).Unique("Email")
+	if err := schema.Validate(); err == nil {
+		t.Error("schema.Validate should fail for a unique index that is not defined")
+	}
+}
+
+type Customer struct {
+	testModel
+	CustomerId int
+	Email      string
+}
+
+func (c *Customer) GetSchema() *gitdb.Schema {
+	indexes := map[string]interface{}{"Email": c.Email}
+	return gitdb.NewSchema("Customer", "b0", fmt.Sprintf("%d", c.CustomerId), indexes).
+		Collate("Email", gitdb.CollateFoldCase|gitdb.CollateTrim|gitdb.CollateNormalize).
+		Unique("Email")
+}
+
+func TestCollatedIndex(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	c := &Customer{CustomerId: 1, Email: " Adé@Example.com  "}
+	if err := testDb.Insert(c); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+
+	for _, query := range []string{"ade@example.com", "ADE@EXAMPLE.COM ", "Adé@example.com"} {
+		sp := &gitdb.SearchParam{Index: "Email", Value: query}
+		results, err := testDb.Search("Customer", []*gitdb.SearchParam{sp}, gitdb.SearchEquals)
+		if err != nil {
+			t.Fatalf("testDb.Search failed: %s", err)
+		}
+		if len(results) != 1 {
+			t.Errorf("%q: want: 1 result, got: %d", query, len(results))
+		}
+	}
+
+	results, err := testDb.SearchWhere("Customer", "Email", gitdb.Eq("ADÉ@example.com"))
+	if err != nil {
+		t.Fatalf("testDb.SearchWhere failed: %s", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("want: 1 result, got: %d", len(results))
+	}
+
+	var violation *gitdb.ErrUniqueViolation
+	if err := testDb.Insert(&Customer{CustomerId: 2, Email: "ade@example.com"}); !errors.As(err, &violation) {
+		t.Errorf("want: *gitdb.ErrUniqueViolation for collated duplicate, got: %v", err)
+	}
+}
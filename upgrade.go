@@ -0,0 +1,134 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/bouggo/log"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//UpgradeFormat rewrites records stored in the v1 layout, the JSON of the model on its own, to the
+//RecVersion layout and commits them. v1 records are read as they are so upgrading is optional, but
+//upgraded records carry their indexes and revision. Indexes are taken from the Config.Factory or registered
+//model of each dataset; without one records are upgraded with no indexes
+func (g *gitdb) UpgradeFormat() error {
+	if err := g.writable(); err != nil {
+		return err
+	}
+
+	g.commitMu.Lock()
+	defer g.commitMu.Unlock()
+
+	datasets, err := g.datasetNames()
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+	for _, dataset := range datasets {
+		blockFiles, err := g.blockFiles(dataset)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		for _, blockFile := range blockFiles {
+			dataBlock, err := g.loadBlock(blockFile)
+			if err != nil {
+				return err
+			}
+
+			upgraded, err := g.upgradeBlock(dataset, dataBlock)
+			if err != nil {
+				return err
+			}
+			if len(upgraded) == 0 {
+				continue
+			}
+
+			if err := g.writeBlock(blockFile, dataBlock); err != nil {
+				return err
+			}
+			g.updateIndexes(dataBlock)
+			for _, id := range upgraded {
+				g.reads.forget(id)
+			}
+			ids = append(ids, upgraded...)
+		}
+	}
+
+	if err := g.flushIndex(); err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	g.commit.Add(1)
+	g.events <- newWriteEvent(fmt.Sprintf("Upgrading %d records to %s", len(ids), RecVersion), ".", true, nil, "upgrade", ids...)
+	g.waitForCommit()
+	log.Info(fmt.Sprintf("Upgraded %d records to %s", len(ids), RecVersion))
+
+	return nil
+}
+
+//upgradeBlock rewrites the v1 records of dataBlock to RecVersion and returns their ids
+func (g *gitdb) upgradeBlock(dataset string, dataBlock *db.Block) ([]string, error) {
+	var key string
+	if g.config.Cipher == nil {
+		key, _ = g.encryptionKey(dataset)
+	}
+
+	var ids []string
+	for _, record := range dataBlock.Records() {
+		if record.Version() != "v1" {
+			continue
+		}
+
+		var stored string
+		if m := g.config.factory(dataset); m != nil {
+			if err := record.Hydrate(m); err != nil {
+				return nil, err
+			}
+
+			schema := schemaOf(m)
+			if err := g.blindIndexes(schema, schema.indexes); err != nil {
+				return nil, err
+			}
+
+			recordStr, err := g.encodeRecord(&model{Version: RecVersion, Indexes: schema.indexes, Data: m})
+			if err != nil {
+				return nil, err
+			}
+			stored = recordStr
+		} else {
+			var data json.RawMessage
+			if err := record.Hydrate(&data); err != nil {
+				return nil, err
+			}
+
+			b, err := json.Marshal(struct {
+				Version string
+				Indexes map[string]interface{}
+				Data    json.RawMessage
+			}{RecVersion, map[string]interface{}{}, data})
+			if err != nil {
+				return nil, err
+			}
+
+			//records stay encrypted if they were
+			stored = string(b)
+			if !json.Valid([]byte(record.Data())) {
+				if stored, err = g.encrypt(dataset, key, stored); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		dataBlock.Add(record.ID(), stored)
+		ids = append(ids, record.ID())
+	}
+	return ids, nil
+}
@@ -0,0 +1,72 @@
+package gitdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestUpgradeFormat(t *testing.T) {
+	cfg := getReadTestConfig("v1")
+	cfg.DbPath = dbPath
+	cfg.EncryptionKey = getConfig().EncryptionKey
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	//put a block of v1 records in the database
+	testDb.Close()
+	v1Block, err := ioutil.ReadFile("./testdata/v1/data/data/Message/b0.json")
+	if err != nil {
+		t.Fatalf("failed to read v1 block: %s", err)
+	}
+	blockFile := filepath.Join(dbPath, "data", "Message", "b0.json")
+	if err := os.MkdirAll(filepath.Dir(blockFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(blockFile, v1Block, 0644); err != nil {
+		t.Fatal(err)
+	}
+	testDb = getDbConn(t, cfg)
+
+	//v1 records are read as they are
+	m := getTestMessageWithId(0)
+	result := &Message{}
+	if err := testDb.Get(gitdb.ID(m), result); err != nil || result.Body != m.Body {
+		t.Errorf("want: %s, got: %s (%v)", m.Body, result.Body, err)
+	}
+
+	if err := testDb.UpgradeFormat(); err != nil {
+		t.Fatalf("testDb.UpgradeFormat failed: %s", err)
+	}
+	if got := gitOutput(t, "log", "-1", "--format=%s"); !strings.HasPrefix(got, "Upgrading 10 records to "+gitdb.RecVersion) {
+		t.Errorf("want: upgrade commit, got: %s", got)
+	}
+
+	records, err := testDb.Fetch("Message")
+	if err != nil || len(records) != 10 {
+		t.Fatalf("want: 10 records, got: %d (%v)", len(records), err)
+	}
+	for _, record := range records {
+		if record.Version() != gitdb.RecVersion || record.Indexes()["From"] != m.From {
+			t.Errorf("want: %s record indexed by From, got: %s %v", gitdb.RecVersion, record.Version(), record.Indexes())
+		}
+	}
+
+	result = &Message{}
+	if err := testDb.Get(gitdb.ID(m), result); err != nil || result.Body != m.Body {
+		t.Errorf("want: %s, got: %s (%v)", m.Body, result.Body, err)
+	}
+
+	//there is nothing left to upgrade
+	commits := gitOutput(t, "rev-list", "--count", "HEAD")
+	if err := testDb.UpgradeFormat(); err != nil {
+		t.Fatalf("testDb.UpgradeFormat failed: %s", err)
+	}
+	if got := gitOutput(t, "rev-list", "--count", "HEAD"); got != commits {
+		t.Errorf("want: %s commits, got: %s", commits, got)
+	}
+}
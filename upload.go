@@ -22,7 +22,7 @@ type UploadModel struct {
 
 //GetSchema implements Model.GetSchema
 func (u *UploadModel) GetSchema() *Schema {
-	name := "Upload"
+	name := uploadModelDataset
 	return newSchema(
 		name,
 		// AutoBlock(u.db.dbDir(), name, BlockByCount, 1000),
@@ -49,10 +49,15 @@ func (u *UploadModel) ShouldEncrypt() bool { return false }
 
 const uploadDataset = "Bucket"
 
+//uploadModelDataset is the dataset UploadModel records are written to
+const uploadModelDataset = "Upload"
+
 //Upload provides API for managing file uploads
 type Upload struct {
 	db    *gitdb
 	model *UploadModel
+	//denied is returned by New and Replace when the connection may not write uploads e.g with WithRole
+	denied error
 }
 
 //Get returns an upload by id
@@ -88,6 +93,13 @@ func (u *Upload) Replace(bucket, file string) error {
 }
 
 func (u *Upload) upload(bucket, file string) error {
+	if u.denied != nil {
+		return u.denied
+	}
+	if err := u.db.writable(); err != nil {
+		return err
+	}
+
 	src, err2 := os.Open(file)
 	if err2 != nil {
 		return err2
@@ -0,0 +1,402 @@
+package gitdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	netmail "net/mail"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//FieldError is a field of a model that breaks a rule of its gitdb tag
+type FieldError struct {
+	//Field is the name of the field, with the names of the structs it is nested in e.g Address.City
+	Field string
+	//Rule is the rule the field breaks e.g max=64
+	Rule    string
+	Message string
+}
+
+//ErrValidation is returned by Insert when fields of a model break the rules of their gitdb tags
+type ErrValidation struct {
+	Dataset string
+	Fields  []FieldError
+}
+
+func (e *ErrValidation) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Message
+	}
+	return fmt.Sprintf("%s is not valid: %s", e.Dataset, strings.Join(msgs, ", "))
+}
+
+//ErrIndexFields is returned by Insert when indexes of a model are missing or hold values that can't be
+//indexed e.g a nil pointer or a struct. Each FieldError names the index and the rule it breaks: required or type
+type ErrIndexFields struct {
+	Dataset string
+	Fields  []FieldError
+}
+
+func (e *ErrIndexFields) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Message
+	}
+	return fmt.Sprintf("%s has invalid indexes: %s", e.Dataset, strings.Join(msgs, ", "))
+}
+
+//As lets errors.As read e as ValidationErrors
+func (e *ErrIndexFields) As(target interface{}) bool {
+	return asValidationErrors(e.Fields, target)
+}
+
+//As lets errors.As read e as ValidationErrors
+func (e *ErrValidation) As(target interface{}) bool {
+	return asValidationErrors(e.Fields, target)
+}
+
+func asValidationErrors(fields []FieldError, target interface{}) bool {
+	t, ok := target.(*ValidationErrors)
+	if !ok {
+		return false
+	}
+	*t = ValidationErrors{}
+	for _, f := range fields {
+		t.Add(f.Field, f.Message)
+	}
+	return true
+}
+
+//ValidationErrors maps the fields of a model that are not valid to what is wrong with them. Insert fails with an
+//error errors.As reads as ValidationErrors whenever a model isn't valid: *ErrValidation, *ErrIndexFields or
+//ValidationErrors returned by the Validate of the model for checks of its own
+type ValidationErrors map[string][]string
+
+//Add adds msg to the messages of field
+func (e ValidationErrors) Add(field string, msg string) {
+	e[field] = append(e[field], msg)
+}
+
+//Err returns e, or nil if it has no fields, so Validate can end with return errs.Err()
+func (e ValidationErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e ValidationErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	msgs := make([]string, len(fields))
+	for i, field := range fields {
+		msgs[i] = field + ": " + strings.Join(e[field], ", ")
+	}
+	return strings.Join(msgs, "; ")
+}
+
+//JSON returns e as a JSON object of the messages of each field e.g {"Email":["Email must be an email address"]}
+func (e ValidationErrors) JSON() []byte {
+	b, _ := json.Marshal(map[string][]string(e))
+	return b
+}
+
+//WriteJSON writes e to w as a 422 Unprocessable Entity response with the body {"errors": e}
+func (e ValidationErrors) WriteJSON(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	return json.NewEncoder(w).Encode(map[string]interface{}{"errors": map[string][]string(e)})
+}
+
+//fieldRule is a rule of a gitdb tag. arg is the value after = e.g 64 in max=64
+type fieldRule struct {
+	name string
+	arg  string
+	n    float64
+}
+
+//taggedField is a field of a struct with a gitdb tag or with fields of its own that may have one
+type taggedField struct {
+	index  int
+	name   string
+	rules  []fieldRule
+	nested bool
+}
+
+//tagCache holds the tagged fields of each struct type
+var tagCache sync.Map
+
+//validateFields checks the fields of m against the rules of their gitdb tags e.g
+//
+//	Email string `gitdb:"required,max=64,email"`
+func validateFields(dataset string, m Model) error {
+	v := reflect.ValueOf(m)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs []FieldError
+	if err := checkStruct(v, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return &ErrValidation{Dataset: dataset, Fields: errs}
+	}
+	return nil
+}
+
+func checkStruct(v reflect.Value, prefix string, errs *[]FieldError) error {
+	fields, err := taggedFields(v.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		fv := v.Field(f.index)
+		name := prefix + f.name
+		for _, rule := range f.rules {
+			if msg := rule.check(fv); msg != "" {
+				*errs = append(*errs, FieldError{Field: name, Rule: rule.String(), Message: name + " " + msg})
+			}
+		}
+
+		if !f.nested {
+			continue
+		}
+		for fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct {
+			//fields of embedded structs are named as if they were fields of v
+			nestedPrefix := name + "."
+			if v.Type().Field(f.index).Anonymous {
+				nestedPrefix = prefix
+			}
+			if err := checkStruct(fv, nestedPrefix, errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//taggedFields returns the fields of struct type t that have rules or may have fields with rules
+func taggedFields(t reflect.Type) ([]taggedField, error) {
+	if cached, ok := tagCache.Load(t); ok {
+		return cached.([]taggedField), nil
+	}
+
+	var fields []taggedField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		rules, err := parseRules(sf.Tag.Get("gitdb"))
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s has an invalid gitdb tag: %s", t.Name(), sf.Name, err)
+		}
+
+		ft := sf.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		nested := ft.Kind() == reflect.Struct && ft.PkgPath() != "time"
+		if len(rules) > 0 || nested {
+			fields = append(fields, taggedField{index: i, name: sf.Name, rules: rules, nested: nested})
+		}
+	}
+
+	tagCache.Store(t, fields)
+	return fields, nil
+}
+
+func parseRules(tag string) ([]fieldRule, error) {
+	//transient fields are not stored so they have no rules
+	if tag == "" || tag == "-" {
+		return nil, nil
+	}
+
+	var rules []fieldRule
+	for _, r := range strings.Split(tag, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+
+		rule := fieldRule{name: r}
+		if i := strings.Index(r, "="); i >= 0 {
+			rule.name, rule.arg = r[:i], r[i+1:]
+		}
+
+		switch rule.name {
+		case "required", "email":
+			if rule.arg != "" {
+				return nil, fmt.Errorf("%s takes no value", rule.name)
+			}
+		case "min", "max":
+			n, err := strconv.ParseFloat(rule.arg, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a number", rule.name)
+			}
+			rule.n = n
+		case "oneof":
+			if rule.arg == "" {
+				return nil, fmt.Errorf("oneof needs values separated by spaces")
+			}
+		default:
+			return nil, fmt.Errorf("unknown rule %s", rule.name)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (r fieldRule) String() string {
+	if r.arg == "" {
+		return r.name
+	}
+	return r.name + "=" + r.arg
+}
+
+//check returns why v breaks r or an empty string if it doesn't. Other than required, rules
+//are not checked against zero values so optional fields can be left empty
+func (r fieldRule) check(v reflect.Value) string {
+	if r.name == "required" {
+		if v.IsZero() {
+			return "is required"
+		}
+		return ""
+	}
+	if v.IsZero() {
+		return ""
+	}
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	switch r.name {
+	case "min", "max":
+		size, isLen := ruleSize(v)
+		if r.name == "min" && size < r.n {
+			if isLen {
+				return fmt.Sprintf("must be at least %s long", r.arg)
+			}
+			return fmt.Sprintf("must be at least %s", r.arg)
+		}
+		if r.name == "max" && size > r.n {
+			if isLen {
+				return fmt.Sprintf("must be at most %s long", r.arg)
+			}
+			return fmt.Sprintf("must be at most %s", r.arg)
+		}
+	case "email":
+		if addr, err := netmail.ParseAddress(fmt.Sprint(v.Interface())); err != nil || addr.Name != "" {
+			return "must be an email address"
+		}
+	case "oneof":
+		value := fmt.Sprint(v.Interface())
+		for _, allowed := range strings.Fields(r.arg) {
+			if value == allowed {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(strings.Fields(r.arg), ", ")
+	}
+	return ""
+}
+
+//ruleSize returns what min and max compare against: the length of strings, slices and maps,
+//and the value of numbers
+func ruleSize(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(len([]rune(v.String()))), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false
+	}
+	return 0, false
+}
+
+//RequireIndexes makes inserts fail with *ErrIndexFields when any of the indexes is empty, including
+//path indexes whose path leads nowhere
+func (a *Schema) RequireIndexes(indexes ...string) *Schema {
+	a.required = append(a.required, indexes...)
+	return a
+}
+
+//validateIndexes checks every index of schema has a value that is stored and searched as itself:
+//a string, number, bool, time or a type that marshals itself to JSON. Index values are written to the
+//index files as JSON and matched as text, so nil and structured values would index junk.
+//Path indexes may be nil as the path can lead nowhere unless they are required
+func validateIndexes(schema *Schema) error {
+	required := map[string]bool{}
+	for _, name := range schema.required {
+		if _, ok := schema.indexes[name]; !ok {
+			return fmt.Errorf("required index %s is not an index of %s", name, schema.name())
+		}
+		required[name] = true
+	}
+
+	names := make([]string, 0, len(schema.indexes))
+	for name := range schema.indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []FieldError
+	for _, name := range names {
+		v := indirect(reflect.ValueOf(schema.indexes[name]))
+		switch {
+		case !v.IsValid():
+			if required[name] || !isPath(name) {
+				errs = append(errs, FieldError{Field: name, Rule: "required", Message: "index " + name + " is missing"})
+			}
+		case !indexable(v.Type()):
+			errs = append(errs, FieldError{Field: name, Rule: "type", Message: fmt.Sprintf("index %s is %s, not a string, number, bool or time", name, v.Type())})
+		case required[name] && v.IsZero():
+			errs = append(errs, FieldError{Field: name, Rule: "required", Message: "index " + name + " is empty"})
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ErrIndexFields{Dataset: schema.name(), Fields: errs}
+	}
+	return nil
+}
+
+func indexable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool:
+		return true
+	}
+	if isNumber(t.Kind()) || t == timeType {
+		return true
+	}
+	return t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType)
+}
@@ -0,0 +1,180 @@
+package gitdb_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+type Contact struct {
+	testModel
+	ContactId int
+	Name      string `gitdb:"required,max=16"`
+	Email     string `gitdb:"required,email"`
+	Age       int    `gitdb:"min=18"`
+	Status    string `gitdb:"oneof=active inactive"`
+}
+
+func (c *Contact) GetSchema() *gitdb.Schema {
+	return gitdb.NewSchema("Contact", "b0", fmt.Sprintf("%d", c.ContactId), map[string]interface{}{})
+}
+
+func (c *Contact) Validate() error {
+	if c.Name == "root" {
+		return errors.New("Name is reserved")
+	}
+	return nil
+}
+
+func TestValidationTags(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	c := &Contact{ContactId: 1, Name: "a name that is much too long", Email: "alice", Age: 12, Status: "away"}
+	err := testDb.Insert(c)
+	var invalid *gitdb.ErrValidation
+	if !errors.As(err, &invalid) {
+		t.Fatalf("want: *gitdb.ErrValidation, got: %v", err)
+	}
+
+	want := []string{"Name max=16", "Email email", "Age min=18", "Status oneof=active inactive"}
+	if len(invalid.Fields) != len(want) {
+		t.Fatalf("want: %d field errors, got: %v", len(want), invalid)
+	}
+	for i, f := range invalid.Fields {
+		if got := f.Field + " " + f.Rule; got != want[i] {
+			t.Errorf("want: %s, got: %s", want[i], got)
+		}
+	}
+
+	//optional fields can be left empty
+	c = &Contact{ContactId: 1, Name: "alice", Email: "alice@example.com"}
+	if err := testDb.Insert(c); err != nil {
+		t.Errorf("testDb.Insert failed: %s", err)
+	}
+
+	//the model's own Validate still runs
+	c.Name = "root"
+	if err := testDb.Insert(c); err == nil || errors.As(err, &invalid) {
+		t.Errorf("want: Validate error, got: %v", err)
+	}
+}
+
+type BadTag struct {
+	Contact
+	Nickname string `gitdb:"maximum=3"`
+}
+
+func TestValidationTagInvalid(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	b := &BadTag{Contact: Contact{ContactId: 2, Name: "bob", Email: "bob@example.com"}}
+	if err := testDb.Insert(b); err == nil {
+		t.Errorf("testDb.Insert should fail for an unknown rule")
+	}
+}
+
+type Parcel struct {
+	testModel
+	ParcelId int
+	Courier  *string
+	Size     struct{ Width, Height int }
+	Customer struct{ Email string }
+}
+
+func (p *Parcel) GetSchema() *gitdb.Schema {
+	return gitdb.NewSchema("Parcel", "b0", fmt.Sprintf("%d", p.ParcelId), map[string]interface{}{"Courier": p.Courier, "Size": p.Size}).
+		Index("Customer.Email").
+		RequireIndexes("Customer.Email")
+}
+
+func TestValidationIndexes(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	p := &Parcel{ParcelId: 1}
+	err := testDb.Insert(p)
+	var invalid *gitdb.ErrIndexFields
+	if !errors.As(err, &invalid) {
+		t.Fatalf("want: *gitdb.ErrIndexFields, got: %v", err)
+	}
+
+	want := []string{"Courier required", "Customer.Email required", "Size type"}
+	if len(invalid.Fields) != len(want) {
+		t.Fatalf("want: %d index errors, got: %v", len(want), invalid)
+	}
+	for i, f := range invalid.Fields {
+		if got := f.Field + " " + f.Rule; got != want[i] {
+			t.Errorf("want: %s, got: %s", want[i], got)
+		}
+	}
+}
+
+type Signup struct {
+	testModel
+	SignupId int
+	Password string
+	Confirm  string
+}
+
+func (s *Signup) GetSchema() *gitdb.Schema {
+	return gitdb.NewSchema("Signup", "b0", fmt.Sprintf("%d", s.SignupId), map[string]interface{}{})
+}
+
+func (s *Signup) Validate() error {
+	errs := gitdb.ValidationErrors{}
+	if len(s.Password) < 8 {
+		errs.Add("Password", "is too short")
+	}
+	if s.Confirm != s.Password {
+		errs.Add("Confirm", "does not match Password")
+	}
+	return errs.Err()
+}
+
+func TestValidationErrors(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	//errors returned by Validate
+	err := testDb.Insert(&Signup{SignupId: 1, Password: "short", Confirm: "typo"})
+	var errs gitdb.ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("want: gitdb.ValidationErrors, got: %v", err)
+	}
+	if len(errs) != 2 || errs["Password"][0] != "is too short" || errs["Confirm"][0] != "does not match Password" {
+		t.Errorf("want: Password and Confirm errors, got: %v", errs)
+	}
+	if err := testDb.Insert(&Signup{SignupId: 1, Password: "long enough", Confirm: "long enough"}); err != nil {
+		t.Errorf("testDb.Insert failed: %s", err)
+	}
+
+	//errors of gitdb tags
+	err = testDb.Insert(&Contact{ContactId: 1, Name: "alice", Email: "alice"})
+	if !errors.As(err, &errs) {
+		t.Fatalf("want: gitdb.ValidationErrors, got: %v", err)
+	}
+	if len(errs) != 1 || len(errs["Email"]) != 1 {
+		t.Errorf("want: an Email error, got: %v", errs)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := errs.WriteJSON(rec); err != nil {
+		t.Fatalf("errs.WriteJSON failed: %s", err)
+	}
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("want: %d, got: %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+	var body struct {
+		Errors map[string][]string `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Errors["Email"][0] != errs["Email"][0] {
+		t.Errorf("want: the Email error, got: %s (%v)", rec.Body, err)
+	}
+}
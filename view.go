@@ -0,0 +1,205 @@
+package gitdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bouggo/log"
+	"github.com/gogitdb/gitdb/v2/internal/db"
+)
+
+//CreateView materializes the records of q.Dataset matching q into the dataset name. Records keep
+//their block and record ids so name can be read with Get, Fetch and Search like any dataset. The view
+//is refreshed block by block as records of q.Dataset are written or deleted through this database
+func (g *gitdb) CreateView(name string, q *Query) error {
+	if err := g.writable(); err != nil {
+		return err
+	}
+
+	if len(name) == 0 || strings.Contains(name, "/") {
+		return errors.New("Invalid view name: " + name)
+	}
+
+	if name == q.Dataset {
+		return errors.New("a view can not be created over itself")
+	}
+
+	if _, ok := g.meta().Views[name]; ok {
+		return fmt.Errorf("view %s already exists", name)
+	}
+
+	if _, err := os.Stat(g.datasetPath(name)); err == nil {
+		return fmt.Errorf("dataset %s already exists", name)
+	}
+
+	g.commitMu.Lock()
+	defer g.commitMu.Unlock()
+
+	blockFiles, err := g.blockFiles(q.Dataset)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	g.meta().Views[name] = q
+	g.saveMeta()
+
+	if err := os.MkdirAll(g.datasetPath(name), 0755); err != nil {
+		return err
+	}
+
+	var ids []string
+	for _, blockFile := range blockFiles {
+		dataBlock, err := g.loadBlock(blockFile)
+		if err != nil {
+			return err
+		}
+
+		changed, err := g.materialize(name, q, dataBlock)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, changed...)
+	}
+
+	if err := g.flushIndex(); err != nil {
+		log.Error(err.Error())
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	g.commit.Add(1)
+	g.events <- newWriteEvent("Creating view "+name+" of "+q.Dataset, g.datasetPath(name), g.autoCommit, nil, "view", ids...)
+	g.waitForCommit()
+
+	return nil
+}
+
+//refreshViews brings the views of dataBlock's dataset in line with it and, if commit is set, commits each view that
+//changed as user
+func (g *gitdb) refreshViews(dataBlock *db.Block, user *User, commit bool) {
+	dataset := dataBlock.Dataset().Name()
+	for name, q := range g.meta().Views {
+		if q.Dataset != dataset {
+			continue
+		}
+
+		g.cacheMu.Lock()
+		ids, err := g.materialize(name, q, dataBlock)
+		if err == nil && len(ids) > 0 {
+			g.commit.Add(1)
+			g.events <- newWriteEvent("Refreshing view "+name+" of "+dataset, g.datasetPath(name), commit, user, "view", ids...)
+		}
+		g.cacheMu.Unlock()
+		if err != nil {
+			log.Error(fmt.Sprintf("Failed to refresh view %s: %s", name, err))
+			continue
+		}
+
+		if len(ids) == 0 {
+			continue
+		}
+		if commit {
+			g.waitForCommit()
+		}
+
+		//views can be created over views
+		g.cacheMu.Lock()
+		viewBlock, err := g.loadBlock(g.blockFilePath(name, dataBlock.Name()))
+		g.cacheMu.Unlock()
+		if err == nil {
+			g.refreshViews(viewBlock, user, commit)
+		}
+	}
+
+	g.cacheMu.Lock()
+	defer g.cacheMu.Unlock()
+	if err := g.flushIndex(); err != nil {
+		log.Error(err.Error())
+	}
+}
+
+//materialize writes the records of dataBlock matching q to the same block of view name
+//and returns the ids of the view records added, changed or removed
+func (g *gitdb) materialize(name string, q *Query, dataBlock *db.Block) ([]string, error) {
+	viewBlockFile := g.blockFilePath(name, dataBlock.Name())
+	viewBlock, err := g.loadBlock(viewBlockFile)
+	if err != nil {
+		return nil, err
+	}
+
+	want := map[string]string{}
+	for _, record := range dataBlock.Records() {
+		if g.viewMatch(q, record.ID()) {
+			_, block, recordID, err := ParseID(record.ID())
+			if err != nil {
+				return nil, err
+			}
+			want[name+"/"+block+"/"+recordID] = record.Data()
+		}
+	}
+
+	var changed []string
+	for _, record := range viewBlock.Records() {
+		if _, ok := want[record.ID()]; !ok {
+			viewBlock.Delete(record.ID())
+			changed = append(changed, record.ID())
+		}
+	}
+
+	for id, data := range want {
+		if record, err := viewBlock.Get(id); err == nil && record.Data() == data {
+			continue
+		}
+		viewBlock.Add(id, data)
+		changed = append(changed, id)
+	}
+
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	if viewBlock.Len() == 0 {
+		release := g.blockLocks.lock(viewBlockFile)
+		err := os.Remove(viewBlockFile)
+		release()
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	} else {
+		if err := os.MkdirAll(g.datasetPath(name), 0755); err != nil {
+			return nil, err
+		}
+		if err := g.writeBlock(viewBlockFile, viewBlock); err != nil {
+			return nil, err
+		}
+	}
+
+	g.updateIndexes(viewBlock)
+	return changed, nil
+}
+
+//viewMatch reports whether record id of q.Dataset matches any SearchParam of q.
+//A Query without SearchParams matches every record
+func (g *gitdb) viewMatch(q *Query, id string) bool {
+	if len(q.Params) == 0 {
+		return true
+	}
+
+	for _, param := range q.Params {
+		iv, ok := g.index(q.Dataset, param.Index)[id]
+		if !ok {
+			continue
+		}
+
+		queryValue := strings.ToLower(g.collateQuery(q.Dataset, param.Index, param.Value))
+		if searchMatch(iv.Value, queryValue, q.Mode) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,78 @@
+package gitdb_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func viewIDs(t *testing.T, view string) []string {
+	t.Helper()
+	records, err := testDb.Fetch(view)
+	if err != nil {
+		t.Fatalf("testDb.Fetch(%s) failed: %s", view, err)
+	}
+
+	var ids []string
+	for _, r := range records {
+		ids = append(ids, r.ID())
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestCreateView(t *testing.T) {
+	teardown := setup(t, nil)
+	defer teardown(t)
+
+	bookings := []*Booking{
+		{BookingId: 1, RoomId: "101", CheckInDate: "2024-06-03"},
+		{BookingId: 2, RoomId: "102", CheckInDate: "2024-06-01"},
+		{BookingId: 3, RoomId: "101", CheckInDate: "2024-06-02"},
+	}
+	for _, b := range bookings {
+		if err := testDb.Insert(b); err != nil {
+			t.Fatalf("testDb.Insert failed: %s", err)
+		}
+	}
+
+	q := &gitdb.Query{
+		Dataset: "Booking",
+		Params:  []*gitdb.SearchParam{{Index: "RoomId", Value: "101"}},
+		Mode:    gitdb.SearchEquals,
+	}
+	if err := testDb.CreateView("Room101", q); err != nil {
+		t.Fatalf("testDb.CreateView failed: %s", err)
+	}
+
+	if got := viewIDs(t, "Room101"); len(got) != 2 || got[0] != "Room101/b0/1" || got[1] != "Room101/b0/3" {
+		t.Errorf("want: [Room101/b0/1 Room101/b0/3], got: %v", got)
+	}
+
+	//records moving in and out of the view refresh it
+	bookings[1].RoomId = "101"
+	if err := testDb.Insert(bookings[1]); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+	if err := testDb.Delete(gitdb.ID(bookings[0])); err != nil {
+		t.Fatalf("testDb.Delete failed: %s", err)
+	}
+
+	if got := viewIDs(t, "Room101"); len(got) != 2 || got[0] != "Room101/b0/2" || got[1] != "Room101/b0/3" {
+		t.Errorf("want: [Room101/b0/2 Room101/b0/3], got: %v", got)
+	}
+
+	//the view is searchable like any dataset
+	results, err := testDb.Search("Room101", []*gitdb.SearchParam{{Index: "CheckInDate", Value: "2024-06-02"}}, gitdb.SearchEquals)
+	if err != nil {
+		t.Fatalf("testDb.Search failed: %s", err)
+	}
+	if len(results) != 1 || results[0].ID() != "Room101/b0/3" {
+		t.Errorf("want: [Room101/b0/3], got: %d result(s)", len(results))
+	}
+
+	if err := testDb.CreateView("Room101", q); err == nil {
+		t.Error("creating an existing view should fail")
+	}
+}
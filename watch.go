@@ -0,0 +1,61 @@
+package gitdb
+
+//Op is the kind of change made to a record
+type Op string
+
+const (
+	//OpInsert is a record that was added
+	OpInsert Op = "insert"
+	//OpUpdate is a record whose data changed
+	OpUpdate Op = "update"
+	//OpDelete is a record that was removed
+	OpDelete Op = "delete"
+	//OpShred is a record erased from history with Shred
+	OpShred Op = "shred"
+)
+
+//ChangeHandler is called with the ids of records in a dataset that changed with op
+type ChangeHandler func(ids []string, op Op)
+
+//OnChange registers handler to be called when a sync pulls changes to dataset made by other nodes
+func (g *gitdb) OnChange(dataset string, handler ChangeHandler) {
+	g.watchMu.Lock()
+	defer g.watchMu.Unlock()
+
+	if g.watchers == nil {
+		g.watchers = map[string][]ChangeHandler{}
+	}
+	g.watchers[dataset] = append(g.watchers[dataset], handler)
+}
+
+//notifyWatchers calls the handlers registered for the datasets in changes
+func (g *gitdb) notifyWatchers(changes []recordChange) {
+	g.watchMu.RLock()
+	defer g.watchMu.RUnlock()
+
+	if len(g.watchers) == 0 {
+		return
+	}
+
+	//group ids by dataset and op preserving the order they changed in
+	grouped := map[string]map[Op][]string{}
+	for _, c := range changes {
+		if _, ok := g.watchers[c.dataset]; !ok {
+			continue
+		}
+		if grouped[c.dataset] == nil {
+			grouped[c.dataset] = map[Op][]string{}
+		}
+		grouped[c.dataset][c.op] = append(grouped[c.dataset][c.op], c.id)
+	}
+
+	for dataset, ops := range grouped {
+		for _, op := range []Op{OpInsert, OpUpdate, OpDelete} {
+			if ids, ok := ops[op]; ok {
+				for _, handler := range g.watchers[dataset] {
+					handler(ids, op)
+				}
+			}
+		}
+	}
+}
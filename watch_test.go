@@ -0,0 +1,68 @@
+package gitdb_test
+
+import (
+	"testing"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestOnChange(t *testing.T) {
+	cfg := getConfig()
+	cfg.SyncMode = gitdb.SyncManual
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	//a second node syncing with the same online remote
+	nodeCfg := gitdb.NewConfig(testData + "/node")
+	nodeCfg.ConnectionName = "node"
+	nodeCfg.OnlineRemote = fakeRemote
+	nodeCfg.EncryptionKey = cfg.EncryptionKey
+	nodeCfg.SyncMode = gitdb.SyncManual
+	node, err := gitdb.Open(nodeCfg)
+	if err != nil {
+		t.Fatalf("gitdb.Open failed: %s", err)
+	}
+	defer node.Close()
+
+	got := map[gitdb.Op][]string{}
+	node.OnChange("Message", func(ids []string, op gitdb.Op) {
+		got[op] = append(got[op], ids...)
+	})
+
+	m1, m2 := getTestMessageWithId(1), getTestMessageWithId(2)
+	if err := testDb.InsertMany([]gitdb.Model{m1, m2}); err != nil {
+		t.Fatalf("testDb.InsertMany failed: %s", err)
+	}
+	if err := testDb.Sync(); err != nil {
+		t.Fatalf("testDb.Sync failed: %s", err)
+	}
+	if err := node.Sync(); err != nil {
+		t.Fatalf("node.Sync failed: %s", err)
+	}
+
+	if len(got[gitdb.OpInsert]) != 2 {
+		t.Errorf("want: 2 inserted records, got: %v", got[gitdb.OpInsert])
+	}
+
+	m1.Body = "Changed"
+	if err := insert(m1, false); err != nil {
+		t.Fatalf("insert failed: %s", err)
+	}
+	if err := testDb.Delete(gitdb.ID(m2)); err != nil {
+		t.Fatalf("testDb.Delete failed: %s", err)
+	}
+	if err := testDb.Sync(); err != nil {
+		t.Fatalf("testDb.Sync failed: %s", err)
+	}
+	if err := node.Sync(); err != nil {
+		t.Fatalf("node.Sync failed: %s", err)
+	}
+
+	if ids := got[gitdb.OpUpdate]; len(ids) != 1 || ids[0] != gitdb.ID(m1) {
+		t.Errorf("want: [%s] updated, got: %v", gitdb.ID(m1), ids)
+	}
+
+	if ids := got[gitdb.OpDelete]; len(ids) != 1 || ids[0] != gitdb.ID(m2) {
+		t.Errorf("want: [%s] deleted, got: %v", gitdb.ID(m2), ids)
+	}
+}
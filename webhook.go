@@ -0,0 +1,210 @@
+package gitdb
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bouggo/log"
+)
+
+const defaultWebhookAttempts = 4
+const defaultWebhookBackoff = time.Second
+const webhookTimeout = time.Second * 10
+
+//WebhookSignatureHeader holds the hex HMAC-SHA256 of the body of a webhook request keyed with Webhook.Secret
+const WebhookSignatureHeader = "X-Gitdb-Signature"
+
+//Webhook is a URL notified of the changes made to the database
+type Webhook struct {
+	//URL is the http(s) endpoint payloads are POSTed to
+	URL string
+	//Secret signs payloads in the X-Gitdb-Signature header when set
+	Secret string
+	//Events are the kinds of change the webhook is notified of. Defaults to EventAll
+	Events EventKind
+	//Datasets limits the webhook to changes of these datasets. Empty is every dataset
+	Datasets []string
+	//MaxAttempts is how many times a payload is POSTed before it is dropped. Defaults to 4
+	MaxAttempts int
+	//RetryBackoff is how long the first retry waits, doubling after each. Defaults to 1s
+	RetryBackoff time.Duration
+}
+
+//WebhookPayload is the JSON body POSTed to a Webhook
+type WebhookPayload struct {
+	Event   string   `json:"event"`
+	Dataset string   `json:"dataset"`
+	Op      Op       `json:"op"`
+	IDs     []string `json:"ids"`
+	//SHA is the commit the change is in, or the commit a sync brought the database to
+	SHA string `json:"sha"`
+}
+
+type webhookDelivery struct {
+	hook    *Webhook
+	kind    EventKind
+	payload *WebhookPayload
+}
+
+//String returns the name of kind used in webhook payloads
+func (kind EventKind) String() string {
+	switch kind {
+	case EventInsert:
+		return "insert"
+	case EventUpdate:
+		return "update"
+	case EventDelete:
+		return "delete"
+	case EventSync:
+		return "sync"
+	}
+	return fmt.Sprintf("EventKind(%d)", int(kind))
+}
+
+func (w *Webhook) watches(dataset string) bool {
+	if len(w.Datasets) == 0 {
+		return true
+	}
+	for _, d := range w.Datasets {
+		if d == dataset {
+			return true
+		}
+	}
+	return false
+}
+
+//sign returns the signature of body sent in WebhookSignatureHeader
+func (w *Webhook) sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(w.Secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+//startWebhooks subscribes Config.Webhooks to the changes they are for and starts delivering them
+func (g *gitdb) startWebhooks() {
+	if len(g.config.Webhooks) == 0 {
+		return
+	}
+
+	queue := make(chan *webhookDelivery, 1024)
+	for _, hook := range g.config.Webhooks {
+		hook := hook
+		kinds := hook.Events
+		if kinds == 0 {
+			kinds = EventAll
+		}
+		g.Subscribe(kinds, func(e Event) {
+			if !hook.watches(e.Dataset) {
+				return
+			}
+			payload := &WebhookPayload{Event: e.Kind.String(), Dataset: e.Dataset, Op: e.Op, IDs: []string{e.ID}, SHA: e.SHA}
+			select {
+			case queue <- &webhookDelivery{hook: hook, kind: e.Kind, payload: payload}:
+			default:
+				log.Error("webhook queue is full, dropping " + e.Kind.String() + " of " + e.ID + " for " + hook.URL)
+			}
+		})
+	}
+
+	go g.dispatchWebhooks(queue)
+}
+
+//dispatchWebhooks delivers the payloads of queue until the connection is closed. Changes queued
+//together for the same webhook, dataset and commit are delivered in one payload
+func (g *gitdb) dispatchWebhooks(queue chan *webhookDelivery) {
+	log.Test("starting webhook dispatcher")
+	for {
+		var pending []*webhookDelivery
+		select {
+		case <-g.shutdown:
+			log.Test("shutting down webhook dispatcher")
+			return
+		case d := <-queue:
+			pending = append(pending, d)
+		}
+
+	drain:
+		for {
+			select {
+			case d := <-queue:
+				last := pending[len(pending)-1]
+				if last.hook == d.hook && last.kind == d.kind && last.payload.Dataset == d.payload.Dataset &&
+					last.payload.Op == d.payload.Op && last.payload.SHA == d.payload.SHA {
+					last.payload.IDs = append(last.payload.IDs, d.payload.IDs...)
+					continue
+				}
+				pending = append(pending, d)
+			default:
+				break drain
+			}
+		}
+
+		for _, d := range pending {
+			if err := g.deliverWebhook(d); err != nil {
+				log.Error("webhook " + d.hook.URL + " failed: " + err.Error())
+			}
+		}
+	}
+}
+
+//deliverWebhook POSTs the payload of d retrying with backoff until it is accepted or Webhook.MaxAttempts is reached
+func (g *gitdb) deliverWebhook(d *webhookDelivery) error {
+	body, err := json.Marshal(d.payload)
+	if err != nil {
+		return err
+	}
+
+	attempts := d.hook.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultWebhookAttempts
+	}
+	backoff := d.hook.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultWebhookBackoff
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+	for attempt := 1; ; attempt++ {
+		err = postWebhook(client, d.hook, body)
+		if err == nil || attempt >= attempts {
+			return err
+		}
+
+		log.Test(fmt.Sprintf("webhook %s attempt %d failed: %s", d.hook.URL, attempt, err))
+		select {
+		case <-g.shutdown:
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func postWebhook(client *http.Client, hook *Webhook, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gitdb")
+	if len(hook.Secret) > 0 {
+		req.Header.Set(WebhookSignatureHeader, hook.sign(body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
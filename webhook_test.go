@@ -0,0 +1,88 @@
+package gitdb_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gogitdb/gitdb/v2"
+)
+
+func TestWebhooks(t *testing.T) {
+	var requests int32
+	payloads := make(chan *gitdb.WebhookPayload, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//the first request fails to check it is retried
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(body)
+		if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.Header.Get(gitdb.WebhookSignatureHeader) != want {
+			t.Errorf("want signature: %s, got: %s", want, r.Header.Get(gitdb.WebhookSignatureHeader))
+		}
+
+		payload := &gitdb.WebhookPayload{}
+		if err := json.Unmarshal(body, payload); err != nil {
+			t.Errorf("json.Unmarshal failed: %s", err)
+		}
+		payloads <- payload
+	}))
+	defer server.Close()
+
+	cfg := getConfig()
+	cfg.Webhooks = []*gitdb.Webhook{{
+		URL:          server.URL,
+		Secret:       "s3cret",
+		Events:       gitdb.EventInsert | gitdb.EventDelete,
+		RetryBackoff: time.Millisecond * 10,
+	}}
+	teardown := setup(t, cfg)
+	defer teardown(t)
+
+	m := getTestMessageWithId(1)
+	id := gitdb.ID(m)
+	if err := testDb.Insert(m); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+	//updates are not one of the events of the webhook
+	if err := testDb.Insert(m); err != nil {
+		t.Fatalf("testDb.Insert failed: %s", err)
+	}
+	if err := testDb.Delete(id); err != nil {
+		t.Fatalf("testDb.Delete failed: %s", err)
+	}
+
+	for _, want := range []string{"insert", "delete"} {
+		select {
+		case p := <-payloads:
+			if p.Event != want || p.Dataset != "Message" || len(p.IDs) != 1 || p.IDs[0] != id || p.SHA == "" {
+				t.Errorf("want: %s of %s, got: %+v", want, id, p)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatalf("timed out waiting for %s webhook", want)
+		}
+	}
+
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Errorf("want: 3 requests, got: %d", got)
+	}
+}
+
+func TestWebhooksConfig(t *testing.T) {
+	cfg := getConfig()
+	cfg.Webhooks = []*gitdb.Webhook{{URL: "ftp://hooks.example.com"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("cfg.Validate should fail for a webhook URL that is not http(s)")
+	}
+}
@@ -6,99 +6,360 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/bouggo/log"
-	"github.com/gogitdb/gitdb/v2/internal/crypto"
 	"github.com/gogitdb/gitdb/v2/internal/db"
 )
 
+//opNone is the change writeRecord makes when an Update changed nothing
+const opNone Op = ""
+
 func (g *gitdb) Insert(mo Model) error {
+	return g.insert(mo, nil)
+}
+
+func (g *gitdb) insert(mo Model, user *User) error {
+	return g.insertRevision(mo, anyRevision, user)
+}
+
+//InsertRevision inserts or updates mo only if the stored record is at revision, or doesn't exist yet when revision
+//is 0, and returns *ErrStaleRecord otherwise. Read the revision with Revision before changing the record so a
+//change made by someone else in the meantime isn't overwritten
+func (g *gitdb) InsertRevision(mo Model, revision int) error {
+	return g.insertRevision(mo, revision, nil)
+}
+
+func (g *gitdb) insertRevision(mo Model, revision int, user *User) error {
+	if err := g.writable(); err != nil {
+		return err
+	}
+
+	m, err := g.prepareInsert(mo)
+	if err != nil {
+		return err
+	}
+	if m.expected == anyRevision {
+		m.expected = revision
+	}
+
+	//hooks and validation run concurrently, writes wait their turn in the queue of the dataset
+	defer g.writeQueue.enter(g.config.WriteConcurrency, m.GetSchema().name())()
+	return g.write(m, user)
+}
+
+//Update writes mo over its stored record only if its fields changed, so saving a model nothing was changed on
+//doesn't make a commit. The fields that changed are recorded in the Fields of the CommitInfo of the commit.
+//UpdatedAt, which TimeStampedModel sets on every write, is not counted as a change
+func (g *gitdb) Update(mo Model) error {
+	return g.update(mo, nil)
+}
+
+func (g *gitdb) update(mo Model, user *User) error {
+	if err := g.writable(); err != nil {
+		return err
+	}
+
+	m, err := g.prepareInsert(mo)
+	if err != nil {
+		return err
+	}
+	m.dirty = true
+
+	defer g.writeQueue.enter(g.config.WriteConcurrency, m.GetSchema().name())()
+	return g.write(m, user)
+}
+
+//dirtyFields returns the fields of m that differ from the stored record old, leaving out UpdatedAt
+func dirtyFields(old *db.Record, m Model) []string {
+	//both sides are decoded into maps so nested objects and numbers are compared the same way
+	var after map[string]interface{}
+	if err := json.Unmarshal([]byte(modelData(m)), &after); err != nil {
+		log.Error(err.Error())
+	}
+	b, _ := json.Marshal(after)
+
+	var fields []string
+	for _, field := range changedFields(recordData(old), string(b)) {
+		if field != "UpdatedAt" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
 
+//prepareInsert wraps mo and runs its hooks and validation ahead of writing it
+func (g *gitdb) prepareInsert(mo Model) (*model, error) {
 	m := wrap(mo)
 	if err := m.BeforeInsert(); err != nil {
-		return fmt.Errorf("Model.BeforeInsert failed: %s", err)
+		return nil, fmt.Errorf("Model.BeforeInsert failed: %s", err)
+	}
+
+	if err := g.beforeUpdate(mo); err != nil {
+		return nil, err
+	}
+
+	schema := m.GetSchema()
+	generated, err := g.assignID(schema, mo)
+	if err != nil {
+		return nil, err
+	}
+	//a generated id must not overwrite a record e.g when the sequence of another node is behind
+	if generated {
+		m.expected = 0
+	}
+
+	if err := applyDefaults(schema, mo); err != nil {
+		return nil, err
+	}
+	//the schema is built again now ids and defaults are set so its indexes see them
+	if schema.autoID != nil || len(schema.defaults) > 0 || len(schema.computed) > 0 {
+		schema = m.GetSchema()
+	}
+	m.Indexes = schema.indexes
+
+	//gitdb tags are checked before the model's own Validate so it can rely on them
+	if err := validateFields(schema.name(), mo); err != nil {
+		return nil, err
 	}
 
 	if err := m.Validate(); err != nil {
-		return fmt.Errorf("Model is not valid: %s", err)
+		return nil, fmt.Errorf("Model is not valid: %w", err)
 	}
 
-	if err := m.GetSchema().Validate(); err != nil {
-		return err
+	if err := schema.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := validateIndexes(schema); err != nil {
+		return nil, err
 	}
 
-	return g.write(m)
+	if err := validateTransientIndexes(schema, mo); err != nil {
+		return nil, err
+	}
+
+	typeName, err := g.typeName(schema.name(), mo)
+	if err != nil {
+		return nil, err
+	}
+	m.Type = typeName
+	m.schema = schema
+
+	return m, nil
 }
 
 func (g *gitdb) InsertMany(models []Model) error {
-	tx := g.StartTransaction("InsertMany")
+	return g.insertMany(models, nil)
+}
+
+func (g *gitdb) insertMany(models []Model, user *User) error {
+	tx := g.startTransaction("InsertMany", user)
 	var model Model
 	for _, model = range models {
 		//create a new variable to pass to function to avoid
 		//passing pointer which will end up inserting the same
 		//model multiple times
 		m := model
-		f := func() error { return g.Insert(m) }
+		f := func() error { return g.insert(m, user) }
 		tx.AddOperation(f)
 	}
 	return tx.Commit()
 }
 
-func (g *gitdb) write(m Model) error {
+func (g *gitdb) write(m Model, user *User) error {
+	g.commitMu.RLock()
+	op, before, stored, err := g.writeRecord(m, user)
+	g.commitMu.RUnlock()
+	if err != nil || op == opNone {
+		return err
+	}
+
+	g.audit(op, ID(m), before, modelData(m), storedEncrypted(stored), user)
+	g.publishWrite(op, ID(m))
+	return afterWrite(op, m)
+}
+
+//writeRecord writes m to its block and commits it. It returns the change made, the data of the record
+//before it and the record as stored for the audit log, or opNone if an Update changed nothing. Callers must hold commitMu
+func (g *gitdb) writeRecord(m Model, user *User) (op Op, before string, stored string, err error) {
+	if err := g.sparseWritable(m.GetSchema().name()); err != nil {
+		return "", "", "", err
+	}
 
 	if _, err := os.Stat(g.fullPath(m)); err != nil {
 		err := os.MkdirAll(g.fullPath(m), 0755)
 		if err != nil {
-			return fmt.Errorf("failed to make dir %s: %w", g.fullPath(m), err)
+			return "", "", "", fmt.Errorf("failed to make dir %s: %w", g.fullPath(m), err)
 		}
 	}
 
 	schema := m.GetSchema()
-	blockFilePath := g.blockFilePath(schema.name(), schema.block)
-	dataBlock, err := g.loadBlock(blockFilePath)
-	if err != nil {
-		return err
+	if wrapped, ok := m.(*model); ok && len(schema.blind) > 0 {
+		if err := g.blindIndexes(schema, wrapped.Indexes); err != nil {
+			return "", "", "", err
+		}
+		for index := range schema.blind {
+			schema.indexes[index] = wrapped.Indexes[index]
+		}
 	}
 
-	log.Test(fmt.Sprintf("Size of block before write - %d", dataBlock.Len()))
+	wrapped, _ := m.(*model)
+	if schema.name() == auditDataset && (wrapped == nil || !wrapped.audit) {
+		return "", "", "", ErrAuditReadOnly
+	}
 
-	//...append new record to block
-	newRecordBytes, err := json.Marshal(m)
+	if len(schema.unique) > 0 || (wrapped != nil && wrapped.expected != anyRevision) {
+		//hold until the record is indexed so concurrent inserts can't both pass the checks
+		g.uniqueMu.Lock()
+		defer g.uniqueMu.Unlock()
+	}
+
+	op, before, stored, dataBlock, err := g.stageRecord(m, schema, user)
+	if err != nil || op == opNone {
+		return op, before, stored, err
+	}
+
+	//the writes of other datasets are staged while this one is committed
+	commit := g.autoCommits(m)
+	if commit {
+		g.waitForCommit()
+	}
+	g.refreshViews(dataBlock, user, commit)
+	return op, before, stored, nil
+}
+
+//autoCommits reports whether the write of m is committed as it's made rather than along with others by a
+//transaction or writeBatch
+func (g *gitdb) autoCommits(m Model) bool {
+	wrapped, ok := m.(*model)
+	return g.autoCommit && !(ok && wrapped.batched)
+}
+
+//stageRecord adds m to its block, writes the block and sends it to be committed, returning the block along with
+//what writeRecord returns. The cached blocks and indexes are held until the indexes are flushed
+func (g *gitdb) stageRecord(m Model, schema *Schema, user *User) (op Op, before string, stored string, dataBlock *db.Block, err error) {
+	g.cacheMu.Lock()
+	defer g.cacheMu.Unlock()
+
+	wrapped, _ := m.(*model)
+	checkRevision := wrapped != nil && wrapped.expected != anyRevision
+	if len(schema.unique) > 0 || checkRevision {
+		if err := g.checkUnique(schema, ID(m)); err != nil {
+			return "", "", "", nil, err
+		}
+	}
+
+	if err := g.checkRefs(schema, ID(m)); err != nil {
+		return "", "", "", nil, err
+	}
+	g.registerSchema(schema)
+
+	blockFilePath := g.blockFilePath(schema.name(), schema.block)
+	dataBlock, err = g.loadBlock(blockFilePath)
 	if err != nil {
-		return err
+		return "", "", "", nil, err
+	}
+
+	//records of the block written at an earlier version of the dataset are migrated along with m
+	if _, err := g.migrateBlock(schema.name(), dataBlock); err != nil {
+		return "", "", "", nil, err
 	}
 
+	log.Test(fmt.Sprintf("Size of block before write - %d", dataBlock.Len()))
+
 	mID := ID(m)
 
 	//construct a commit message
+	op = OpInsert
 	commitMsg := "Inserting " + mID + " into " + schema.blockID()
-	if _, err := dataBlock.Get(mID); err == nil {
+	revision := 0
+	var fields []string
+	old, _ := dataBlock.Get(mID)
+	if old != nil {
+		op = OpUpdate
 		commitMsg = "Updating " + mID + " in " + schema.blockID()
+		if g.config.Audit {
+			before = recordData(old)
+		}
+		revision = old.Revision()
+	}
+
+	if wrapped != nil && wrapped.dirty {
+		if old == nil {
+			return "", "", "", nil, fmt.Errorf("Record %s not found in %s", mID, schema.name())
+		}
+		if fields = dirtyFields(old, m); len(fields) == 0 {
+			log.Test("no change to " + mID + ", skipping write")
+			return opNone, "", "", nil, nil
+		}
+	}
+
+	commitOp := string(op)
+	if wrapped != nil && len(wrapped.revert) > 0 {
+		commitOp = "revert"
+		commitMsg = "Reverting " + mID + " to " + wrapped.revert
+	}
+
+	if wrapped != nil {
+		if checkRevision && wrapped.expected != revision {
+			return "", "", "", nil, &ErrStaleRecord{ID: mID, Expected: wrapped.expected, Revision: revision}
+		}
+		wrapped.Revision = revision + 1
+		wrapped.SchemaVersion = g.schemaVersion(schema.name())
 	}
 
-	newRecordStr := string(newRecordBytes)
-	//encrypt data if need be
-	if m.ShouldEncrypt() {
-		newRecordStr = crypto.Encrypt(g.config.EncryptionKey, newRecordStr)
+	//...append new record to block
+	newRecordStr, err := g.encodeRecord(m)
+	if err != nil {
+		return "", "", "", nil, err
 	}
 
 	dataBlock.Add(mID, newRecordStr)
 
 	g.events <- newWriteBeforeEvent("...", mID)
+	if err := g.stageBlock(blockFilePath, dataBlock, user, g.autoCommits(m), commitOp, mID, commitMsg, fields...); err != nil {
+		return "", "", "", nil, err
+	}
+
+	return op, before, newRecordStr, dataBlock, nil
+}
+
+//commitBlock writes dataBlock to disk, commits the change op made to fields of record id as user and updates the indexes
+func (g *gitdb) commitBlock(blockFilePath string, dataBlock *db.Block, user *User, op string, id string, commitMsg string, fields ...string) error {
+	g.cacheMu.Lock()
+	err := g.stageBlock(blockFilePath, dataBlock, user, g.autoCommit, op, id, commitMsg, fields...)
+	g.cacheMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	//block here until write has been committed
+	g.waitForCommit()
+	g.refreshViews(dataBlock, user, g.autoCommit)
+
+	return nil
+}
+
+//stageBlock writes dataBlock to disk, sends the change op made to fields of record id as user to the event loop,
+//to be committed if commit is set, and updates the indexes without waiting for the commit. Callers must hold cacheMu
+func (g *gitdb) stageBlock(blockFilePath string, dataBlock *db.Block, user *User, commit bool, op string, id string, commitMsg string, fields ...string) error {
 	if err := g.writeBlock(blockFilePath, dataBlock); err != nil {
 		return err
 	}
 
-	log.Info(fmt.Sprintf("autoCommit: %v", g.autoCommit))
+	log.Info(fmt.Sprintf("autoCommit: %v", commit))
 
 	g.commit.Add(1)
-	g.events <- newWriteEvent(commitMsg, blockFilePath, g.autoCommit)
+	e := newWriteEvent(commitMsg, blockFilePath, commit, user, op, id)
+	e.Fields = fields
+	g.events <- e
 	log.Test("sent write event to loop")
 	g.updateIndexes(dataBlock)
-
-	//block here until write has been committed
-	g.waitForCommit()
-
+	g.reads.forget(id)
+	if err := g.flushIndex(); err != nil {
+		log.Error(err.Error())
+	}
 	return nil
 }
 
@@ -118,54 +379,207 @@ func (g *gitdb) writeBlock(blockFile string, block *db.Block) error {
 		return fmtErr
 	}
 
-	return ioutil.WriteFile(blockFile, blockBytes, 0744)
+	if err := g.scanBlock(block, blockBytes); err != nil {
+		//the cached block holds the changes that weren't written so it's read again
+		delete(g.loadedBlocks, blockFile)
+		return err
+	}
+
+	//keep readers, and read-only connections of other processes, out until the whole block is on disk
+	release := g.lockWorktree(false)
+	unlock := g.blockLocks.lock(blockFile)
+	err := g.writeFileAtomic(blockFile, blockBytes, 0744)
+	unlock()
+	release()
+	if err != nil {
+		return err
+	}
+
+	g.recordFile(blockFile, blockBytes)
+	return nil
+}
+
+//writeFileAtomic writes data to file through a temporary file renamed over it so readers that don't take the lock
+//of file, or the working tree, see either its old or its new content and never a part written file
+func (g *gitdb) writeFileAtomic(file string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(g.tmpDir(), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(g.tmpDir(), filepath.Base(file))
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
 }
 
 func (g *gitdb) Delete(id string) error {
-	return g.dodelete(id, false)
+	return g.dodelete(id, false, nil)
 }
 
 func (g *gitdb) DeleteOrFail(id string) error {
-	return g.dodelete(id, true)
+	return g.dodelete(id, true, nil)
+}
+
+func (g *gitdb) dodelete(id string, failNotFound bool, user *User) error {
+	dataset, _, _, err := ParseID(id)
+	if err != nil {
+		return err
+	}
+	if dataset == auditDataset {
+		return ErrAuditReadOnly
+	}
+	if err := g.sparseWritable(dataset); err != nil {
+		return err
+	}
+	return g.deleteQueued(dataset, id, failNotFound, user)
 }
 
-func (g *gitdb) dodelete(id string, failNotFound bool) error {
+//deleteQueued deletes id of dataset in its turn of the write queue. The turns of the datasets the delete can
+//cascade to are taken along with it
+func (g *gitdb) deleteQueued(dataset string, id string, failNotFound bool, user *User) error {
+	defer g.writeQueue.enter(g.config.WriteConcurrency, g.cascadesTo(dataset)...)()
+	return g.deleteCascade(id, failNotFound, user, map[string]bool{})
+}
+
+//deleteCascade deletes id and, through CascadeRef, the records referencing it. The records deleted through a
+//CascadeRef are committed along with id, or not at all if deleting one of them fails. deleting holds the records
+//being deleted so cyclic references don't recurse forever
+func (g *gitdb) deleteCascade(id string, failNotFound bool, user *User, deleting map[string]bool) error {
+	if err := g.writable(); err != nil {
+		return err
+	}
+
+	cascading := len(deleting) > 0
+	var manifest map[string]string
+	if !cascading {
+		manifest = g.snapshotManifest()
+	}
+
+	err := g.deleteRecord(id, failNotFound, user, deleting, !cascading && g.autoCommit)
+	//a transaction reverts its own changes
+	if err != nil && !cascading && len(deleting) > 1 && g.autoCommit {
+		if err := g.revertUncommitted(manifest); err != nil {
+			log.Error(err.Error())
+		}
+	}
+	return err
+}
 
+//deleteRecord deletes id after the records referencing it through a CascadeRef and commits the deletes if commit
+func (g *gitdb) deleteRecord(id string, failNotFound bool, user *User, deleting map[string]bool, commit bool) error {
 	dataset, block, _, err := ParseID(id)
 	if err != nil {
 		return err
 	}
 
+	hooked, err := g.beforeDelete(id)
+	if err != nil {
+		return err
+	}
+
+	deleting[id] = true
+	if err := g.enforceRefs(id, user, deleting); err != nil {
+		return err
+	}
+
+	g.commitMu.RLock()
 	blockFilePath := g.blockFilePath(dataset, block)
-	err = g.delByID(id, dataset, blockFilePath, failNotFound)
+	//read before cacheMu is taken as reads wait for it
+	var before *db.Record
+	if g.config.Audit {
+		before, _ = g.doget(id)
+	}
+	g.cacheMu.Lock()
+	deleted, err := g.delByID(id, dataset, blockFilePath, failNotFound)
+	g.reads.forget(id)
 
 	if err == nil {
 		log.Test("sending delete event to loop")
 		g.commit.Add(1)
-		g.events <- newDeleteEvent("Deleting "+id+" in "+blockFilePath, blockFilePath, g.autoCommit)
+		g.events <- newDeleteEvent("Deleting "+id+" in "+blockFilePath, blockFilePath, commit, user, id)
+	}
+	g.cacheMu.Unlock()
+
+	if err == nil {
 		g.waitForCommit()
+		g.cacheMu.Lock()
+		dataBlock, loadErr := g.loadBlock(blockFilePath)
+		g.cacheMu.Unlock()
+		if loadErr == nil {
+			g.refreshViews(dataBlock, user, g.autoCommit)
+		}
+	}
+	g.commitMu.RUnlock()
+
+	if err == nil && before != nil {
+		g.audit(OpDelete, id, recordData(before), "", storedEncrypted(before.Data()), user)
+	}
+	if err == nil && deleted {
+		g.publishWrite(OpDelete, id)
+	}
+	if err == nil && hooked != nil {
+		return afterWrite(OpDelete, hooked)
 	}
+	return err
+}
+
+//revertUncommitted discards the uncommitted changes of a transaction or cascade that failed part way, puts back
+//the manifest returned by snapshotManifest before it and rebuilds the cached blocks, indexes and bloom filters
+func (g *gitdb) revertUncommitted(manifest map[string]string) error {
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
 
+	err := g.gitUndo()
+	g.restoreManifest(manifest)
+	g.events <- newUndoEvent()
+	if err := g.reindex(); err != nil {
+		log.Error(err.Error())
+	}
 	return err
 }
 
-func (g *gitdb) delByID(id string, dataset string, blockFile string, failIfNotFound bool) error {
+//delByID removes record id from blockFile and reports whether it was there
+func (g *gitdb) delByID(id string, dataset string, blockFile string, failIfNotFound bool) (bool, error) {
 
 	if _, err := os.Stat(blockFile); err != nil {
 		if failIfNotFound {
-			return errors.New("Could not delete [" + id + "]: record does not exist")
+			return false, errors.New("Could not delete [" + id + "]: record does not exist")
 		}
-		return nil
+		return false, nil
+	}
+
+	//delete from the cached block so later writes to the block don't restore the record
+	dataBlock, err := g.loadBlock(blockFile)
+	if err != nil {
+		return false, err
 	}
 
-	dataBlock := db.LoadBlock(blockFile, g.config.EncryptionKey)
 	if err := dataBlock.Delete(id); err != nil {
 		if failIfNotFound {
-			return errors.New("Could not delete [" + id + "]: record does not exist")
+			return false, errors.New("Could not delete [" + id + "]: record does not exist")
 		}
-		return nil
+		return false, nil
 	}
 
 	//write undeleted records back to block file
-	return g.writeBlock(blockFile, dataBlock)
+	if err := g.writeBlock(blockFile, dataBlock); err != nil {
+		return false, err
+	}
+
+	g.updateIndexes(dataBlock)
+	return true, g.flushIndex()
 }
@@ -0,0 +1,105 @@
+package gitdb
+
+import (
+	"sort"
+	"sync"
+)
+
+//writeQueue lines up writes by dataset so writes to a dataset happen one at a time while writes to
+//different datasets run side by side, up to Config.WriteConcurrency of them. A delete that cascades
+//holds the turns of the datasets it can cascade to
+type writeQueue struct {
+	mu       sync.Mutex
+	slots    chan bool
+	datasets map[string]*datasetQueue
+}
+
+//datasetQueue holds the turn of a dataset and the number of writes waiting for or holding it
+type datasetQueue struct {
+	turn  chan bool
+	depth int
+}
+
+//enter blocks until it is the turn of a write to datasets and returns the func that ends the turn.
+//concurrency limits how many writes run at once, zero or less means no limit
+func (q *writeQueue) enter(concurrency int, datasets ...string) func() {
+	//turns are taken in the same order by every write so two writes taking more than one can't wait on each other
+	datasets = uniqueSorted(datasets)
+
+	q.mu.Lock()
+	if q.datasets == nil {
+		q.datasets = map[string]*datasetQueue{}
+	}
+	if q.slots == nil && concurrency > 0 {
+		q.slots = make(chan bool, concurrency)
+	}
+	queues := make([]*datasetQueue, len(datasets))
+	for i, dataset := range datasets {
+		dq, ok := q.datasets[dataset]
+		if !ok {
+			dq = &datasetQueue{turn: make(chan bool, 1)}
+			q.datasets[dataset] = dq
+		}
+		dq.depth++
+		queues[i] = dq
+	}
+	slots := q.slots
+	q.mu.Unlock()
+
+	//take the turns before a slot so writes queued on a busy dataset don't hold slots
+	for _, dq := range queues {
+		dq.turn <- true
+	}
+	if slots != nil {
+		slots <- true
+	}
+
+	return func() {
+		if slots != nil {
+			<-slots
+		}
+		for _, dq := range queues {
+			<-dq.turn
+		}
+
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		for i, dq := range queues {
+			dq.depth--
+			if dq.depth == 0 {
+				delete(q.datasets, datasets[i])
+			}
+		}
+	}
+}
+
+//uniqueSorted returns the distinct values of s in order
+func uniqueSorted(s []string) []string {
+	seen := map[string]bool{}
+	var values []string
+	for _, v := range s {
+		if !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+//depths returns the number of writes waiting for or holding the turn of each dataset
+func (q *writeQueue) depths() map[string]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	depths := make(map[string]int, len(q.datasets))
+	for dataset, dq := range q.datasets {
+		depths[dataset] = dq.depth
+	}
+	return depths
+}
+
+//PendingWrites returns the number of writes queued or in progress by dataset.
+//Datasets without writes are left out
+func (g *gitdb) PendingWrites() map[string]int {
+	return g.writeQueue.depths()
+}